@@ -0,0 +1,277 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// This command reads coordinates line by line from a file or stdin, converts them from
+// a source into a target coordinate system and writes delimiter separated values to stdout.
+// Every row holds the input coordinate followed by the columns of the converted coordinate.
+// Lines which fail to convert are reported to stderr by their line number and skipped,
+// the remaining lines keep being converted. Empty lines and lines starting with '#' are ignored.
+//
+// Usage of ./cartconv [flags] [file]
+//
+//	-from="latlong": specify the source coordinate system, "auto" to detect it line by line
+//	-to="utm": specify the target coordinate system
+//	-delimiter=",": specify the column delimiter, "tab" for tab separated values
+//	-axisorder="lat,long": specify the order of the columns of latitude and longitude, "long,lat" for longitude first
+//	-columns="": read a table with a header and convert the coordinate of the named columns, eg. "lat,long"
+//
+// Given columns, the input is read as a delimiter separated table, whose first row names the columns. The values
+// of the named columns are joined in the given order to the coordinate of a row, and the converted coordinate is
+// appended to the row as a column named like the target coordinate system, which has to be a coordinate system of
+// package convert. The other columns are written unchanged, a row which fails to convert keeps an empty result.
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"github.com/the42/cartconvert/cartconvert"
+	"github.com/the42/cartconvert/cartconvert/convert"
+	"github.com/the42/cartconvert/cartconvert/parse"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// A source coordinate system parses a line into a WGS84 latitude / longitude coordinate
+type source func(line string, delimiter rune) (*cartconvert.PolarCoord, error)
+
+// A target coordinate system converts a WGS84 latitude / longitude coordinate into the columns of a row
+type target func(gc *cartconvert.PolarCoord) ([]string, error)
+
+// latlong reads latitude and longitude separated by the delimiter, each like parseBearing, or in any of the
+// notations of cartconvert.ADegMinSecToPolar, like "N47 30.25 E016 20.17"
+func latlong(line string, delimiter rune) (*cartconvert.PolarCoord, error) {
+	if fields := strings.Split(line, string(delimiter)); len(fields) == 2 {
+		lat, err := parseBearing(fields[0])
+		if err == nil {
+			long, err := parseBearing(fields[1])
+			if err == nil {
+				return &cartconvert.PolarCoord{Latitude: lat, Longitude: long, El: cartconvert.WGS84Ellipsoid}, nil
+			}
+		}
+	}
+
+	gc, err := cartconvert.ADegMinSecToPolar(line, false)
+	if err != nil {
+		return nil, err
+	}
+	return gc.ToWGS84()
+}
+
+// normalise replaces the delimiter by blanks, so the columns of a line form the literal of a coordinate
+func normalise(line string, delimiter rune) string {
+	return strings.Replace(line, string(delimiter), " ", -1)
+}
+
+var sources = map[string]source{}
+
+// The EPSG code of WGS84, the SRID of the points written by the targets ewkt and ewkb
+const wgs84SRID = 4326
+
+// The order in which the targets latlong and dms write the columns of latitude and longitude
+var axisorder = cartconvert.AxisLatLong
+
+var targets = map[string]target{
+	"latlong": func(gc *cartconvert.PolarCoord) ([]string, error) {
+		lat, long := strconv.FormatFloat(gc.Latitude, 'f', -1, 64), strconv.FormatFloat(gc.Longitude, 'f', -1, 64)
+		if axisorder == cartconvert.AxisLongLat {
+			return []string{long, lat}, nil
+		}
+		return []string{lat, long}, nil
+	},
+	"dms": func(gc *cartconvert.PolarCoord) ([]string, error) {
+		first, second := cartconvert.LatLongToStringOrder(gc, cartconvert.LLFdms, axisorder)
+		return []string{first, second}, nil
+	},
+	// WGS84 points to be loaded into PostGIS, as EWKT and hex encoded EWKB
+	"ewkt": func(gc *cartconvert.PolarCoord) ([]string, error) {
+		return []string{cartconvert.LatLongToEWKT(gc, wgs84SRID)}, nil
+	},
+	"ewkb": func(gc *cartconvert.PolarCoord) ([]string, error) {
+		return []string{strings.ToUpper(hex.EncodeToString(cartconvert.LatLongToEWKB(gc, wgs84SRID)))}, nil
+	},
+}
+
+// Every coordinate system of package convert is a source and a target of a single column, except for latitude
+// and longitude, which are read separated by the delimiter and written as two columns. The source auto detects
+// the coordinate system of every line by package parse and converts the coordinate parsed.
+func init() {
+	for _, system := range convert.Systems() {
+		system := system
+		if _, ok := targets[system]; !ok {
+			targets[system] = func(gc *cartconvert.PolarCoord) ([]string, error) {
+				out, err := convert.Format(gc, system)
+				if err != nil {
+					return nil, err
+				}
+				return []string{out}, nil
+			}
+		}
+		sources[system] = func(line string, delimiter rune) (*cartconvert.PolarCoord, error) {
+			return convert.LatLong(normalise(line, delimiter), system)
+		}
+	}
+	sources["latlong"] = latlong
+
+	sources[convert.Auto] = func(line string, delimiter rune) (*cartconvert.PolarCoord, error) {
+		coord, _, err := parse.ParseCoordinate(normalise(line, delimiter))
+		if err != nil {
+			return nil, err
+		}
+		return coord.ToWGS84()
+	}
+}
+
+// parseBearing parses a latitude or longitude in decimal degrees, with or without the degree mark, or in degrees,
+// minutes and seconds like ADegMMSSToNum
+func parseBearing(bearing string) (float64, error) {
+	bearing = strings.TrimSpace(bearing)
+	if deg, err := strconv.ParseFloat(bearing, 64); err == nil {
+		return deg, nil
+	}
+	if deg, err := cartconvert.ADegCommaToNum(bearing); err == nil {
+		return deg, nil
+	}
+	return cartconvert.ADegMMSSToNum(bearing)
+}
+
+func keys(m interface{}) string {
+	var names []string
+	switch m := m.(type) {
+	case map[string]source:
+		for key := range m {
+			names = append(names, key)
+		}
+	case map[string]target:
+		for key := range m {
+			names = append(names, key)
+		}
+	}
+	sort.Strings(names)
+	return strings.Join(names, " ")
+}
+
+// convertLines converts every line of r and writes the rows to w. Errors of single lines are written to errw.
+// Returns the number of lines which failed to convert.
+func convertLines(r io.Reader, w *csv.Writer, errw io.Writer, from source, to target, delimiter rune) (failed int, err error) {
+
+	scanner := bufio.NewScanner(r)
+	for lines := 1; scanner.Scan(); lines++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		gc, err := from(line, delimiter)
+		var columns []string
+		if err == nil {
+			columns, err = to(gc)
+		}
+		if err != nil {
+			fmt.Fprintf(errw, "cartconv: error on line %d: %s\n", lines, err)
+			failed++
+			continue
+		}
+
+		if err := w.Write(append([]string{line}, columns...)); err != nil {
+			return failed, err
+		}
+	}
+	w.Flush()
+
+	if err := scanner.Err(); err != nil {
+		return failed, err
+	}
+	return failed, w.Error()
+}
+
+func main() {
+
+	var fromspec, tospec, delimiterspec, axisorderspec, columnsspec string
+
+	flag.StringVar(&fromspec, "from", "latlong", "specify the source coordinate system. Possible values are: "+keys(sources))
+	flag.StringVar(&tospec, "to", "utm", "specify the target coordinate system. Possible values are: "+keys(targets))
+	flag.StringVar(&delimiterspec, "delimiter", ",", `specify the column delimiter, "tab" for tab separated values`)
+	flag.StringVar(&axisorderspec, "axisorder", "lat,long", `specify the order of the columns of latitude and longitude, "long,lat" for longitude first`)
+	flag.StringVar(&columnsspec, "columns", "", `read a table with a header and convert the coordinate of the named columns, eg. "lat,long"`)
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s [flags] [file]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	from, ok := sources[strings.ToLower(fromspec)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unrecognized source coordinate system: '%s'\n", fromspec)
+		flag.Usage()
+		os.Exit(2)
+	}
+	to, ok := targets[strings.ToLower(tospec)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unrecognized target coordinate system: '%s'\n", tospec)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if delimiterspec == "tab" || delimiterspec == `\t` {
+		delimiterspec = "\t"
+	}
+	delimiter, size := utf8.DecodeRuneInString(delimiterspec)
+	if size == 0 || size != len(delimiterspec) {
+		fmt.Fprintf(os.Stderr, "The delimiter has to be a single character, got: '%s'\n", delimiterspec)
+		os.Exit(2)
+	}
+
+	order, err := cartconvert.ParseAxisOrder(axisorderspec)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unrecognized axis order: '%s'\n", axisorderspec)
+		flag.Usage()
+		os.Exit(2)
+	}
+	axisorder = order
+
+	in := io.Reader(os.Stdin)
+	switch flag.NArg() {
+	case 0:
+	case 1:
+		file, err := os.Open(flag.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cartconv: %s\n", err)
+			os.Exit(1)
+		}
+		defer file.Close()
+		in = file
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	w := csv.NewWriter(os.Stdout)
+	w.Comma = delimiter
+
+	var failed int
+	if columnsspec != "" {
+		r := csv.NewReader(in)
+		r.Comma = delimiter
+		mapping := convert.CSVMapping{From: fromspec, Columns: strings.Split(columnsspec, ","), To: []string{tospec}}
+		failed, err = convert.ConvertCSV(r, w, mapping, func(re convert.RowError) {
+			fmt.Fprintf(os.Stderr, "cartconv: error on %s\n", re)
+		})
+	} else {
+		failed, err = convertLines(in, w, os.Stderr, from, to, delimiter)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cartconv: %s\n", err)
+		os.Exit(1)
+	}
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,237 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// This package provides functions to deal with conversion of coordinates of Austria Lambert, the Lambert
+// conformal conic projection of the Datum Austria (MGI) used by the Austrian topographic maps (ÖK) at small scales.
+//
+// Austria Lambert is a Lambert Conformal Conic projection of the Bessel ellipsoid of the MGI with the standard
+// parallels 46° and 49° and the origin at 13°20'E 47°30'N, which is mapped to easting 400000 m and northing
+// 400000 m. Unlike the Bundesmeldenetz, which needs three meridian stripes, the projection covers all of Austria.
+//
+// For further info see https://epsg.io/31287
+package austrialambert
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/the42/cartconvert/cartconvert"
+	"strconv"
+	"strings"
+)
+
+// Geographic extent of Austria Lambert, the territory of Austria
+var Extent = &cartconvert.LatLongExtent{MinLat: 46.3, MaxLat: 49.1, MinLong: 9.5, MaxLong: 17.2}
+
+// A Lambert Conformal Conic projection: standard parallels, latitude and longitude of the origin in decimal degrees,
+// false easting and northing in meters
+type lambertProjection struct {
+	lat1, lat2                  float64
+	latO, longO                 float64
+	falseEasting, falseNorthing float64
+}
+
+// The parameters of Austria Lambert as defined by EPSG:31287
+var austriaLambert = lambertProjection{lat1: 49, lat2: 46, latO: 47.5, longO: 13 + 20.0/60, falseEasting: 400000, falseNorthing: 400000}
+
+// Returns easting and northing in meters of latitude lat and longitude long on the ellipsoid el
+func (p lambertProjection) project(lat, long float64, el *cartconvert.Ellipsoid) (easting, northing float64) {
+	gp := cartconvert.DirectLambertConformalConic(&cartconvert.PolarCoord{Latitude: lat, Longitude: long, El: el},
+		p.lat1, p.lat2, p.latO, p.longO, p.falseEasting, p.falseNorthing)
+	return gp.X, gp.Y
+}
+
+// Returns latitude and longitude on the ellipsoid el of easting and northing in meters, the inverse of project
+func (p lambertProjection) unproject(easting, northing float64, el *cartconvert.Ellipsoid) (lat, long float64) {
+	gc := cartconvert.InverseLambertConformalConic(&cartconvert.GeoPoint{X: easting, Y: northing, El: el},
+		p.lat1, p.lat2, p.latO, p.longO, p.falseEasting, p.falseNorthing)
+	return gc.Latitude, gc.Longitude
+}
+
+// The EPSG code of MGI / Austria Lambert. The Lambert Conformal Conic projection is not implemented by
+// cartconvert.CRS, so the system is not registered.
+const EPSG = 31287
+
+// An Austria Lambert coordinate is specified by easting and northing in meters. RelHeight is the orthometric
+// height above sea level in meters.
+type ALCoord struct {
+	Easting   float64                `json:"easting" xml:"easting"`
+	Northing  float64                `json:"northing" xml:"northing"`
+	RelHeight float64                `json:"relheight" xml:"relheight"`
+	El        *cartconvert.Ellipsoid `json:"ellipsoid,omitempty" xml:"ellipsoid,omitempty"`
+	// Approximate positional error in meters introduced by the datum shift of the conversion from WGS84, the
+	// accuracy of the helmert parameter set used. Zero for coordinates not converted from WGS84.
+	Accuracy float64 `json:"accuracy,omitempty" xml:"accuracy,omitempty"`
+}
+
+// Canonical representation of an Austria Lambert coordinate, easting and northing to the centimeter, like
+// "625844.87 483218.81"
+func (coord *ALCoord) String() string {
+	return cartconvert.FormatTrimmed(coord.Easting, 2) + " " + cartconvert.FormatTrimmed(coord.Northing, 2)
+}
+
+// Implements cartconvert.Coordinate, the system of an Austria Lambert coordinate is "austrialambert"
+func (coord *ALCoord) System() string {
+	return "austrialambert"
+}
+
+// Implements cartconvert.Coordinate, see ALToWGS84LatLong
+func (coord *ALCoord) ToWGS84() (*cartconvert.PolarCoord, error) {
+	return ALToWGS84LatLong(coord)
+}
+
+// Representation of an Austria Lambert coordinate with precision decimal places of easting and northing, see
+// cartconvert.FormatFixed
+func (coord *ALCoord) Format(precision int) string {
+	return fmt.Sprintf("%s %s", cartconvert.FormatFixed(coord.Easting, precision), cartconvert.FormatFixed(coord.Northing, precision))
+}
+
+// Returns a copy of the Austria Lambert coordinate with easting and northing snapped to the nearest multiple of
+// spacingMeters, see cartconvert.SnapToGrid
+func (coord *ALCoord) Snap(spacingMeters float64) *ALCoord {
+	snapped := *coord
+	snapped.Easting, snapped.Northing = cartconvert.SnapToGrid(coord.Easting, spacingMeters), cartconvert.SnapToGrid(coord.Northing, spacingMeters)
+	return &snapped
+}
+
+// Implements json.Marshaler, writing the coordinates in fixed-point notation
+func (coord *ALCoord) MarshalJSON() ([]byte, error) {
+	return cartconvert.MarshalFixedJSON(coord)
+}
+
+// Implements xml.Marshaler, writing the coordinates in fixed-point notation
+func (coord *ALCoord) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return cartconvert.MarshalFixedXML(e, start, coord)
+}
+
+// Validates that the Austria Lambert coordinate lies within Extent, the territory of Austria. Returns the error
+// of ALToWGS84LatLong, or a cartconvert.ExtentError, if easting and northing transform to a location outside of
+// Extent.
+func (coord *ALCoord) Valid() error {
+	gc, err := ALToWGS84LatLong(coord)
+	if err != nil {
+		return err
+	}
+	return Extent.Validate(gc, "Austria Lambert", coord.String())
+}
+
+// Returns the EPSG code of Austria Lambert
+func (coord *ALCoord) EPSG() int {
+	return EPSG
+}
+
+// Parses a string representation of an Austria Lambert coordinate, easting and northing in meters separated by
+// blanks, like
+//
+//	625844.87 483218.81
+//
+// The reference ellipsoid of an Austria Lambert coordinate will always be set to the Bessel ellipsoid of the MGI.
+//
+// returns cartconvert.ErrSyntax if the literal doesn't consist of easting and northing
+func AALToStruct(alcoord string) (*ALCoord, error) {
+
+	fields := strings.Fields(alcoord)
+	if len(fields) != 2 {
+		return nil, cartconvert.ErrSyntax
+	}
+
+	easting, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return nil, err
+	}
+	northing, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return nil, err
+	}
+	if err = cartconvert.CheckFinite(easting, northing); err != nil {
+		return nil, err
+	}
+	return NewALCoord(easting, northing, 0), nil
+}
+
+// Returns the helmert parameter set used for the datum shift between WGS84 and MGI. If set is nil,
+// the default parameter set for WGS84 to MGI is returned. Returns cartconvert.ErrRange if the
+// parameter set does not transform from WGS84 to MGI.
+func helmertParameterSet(set *cartconvert.HelmertParameterSet) (*cartconvert.HelmertParameterSet, error) {
+	if set == nil {
+		return cartconvert.DefaultHelmertParameterSet(cartconvert.DatumWGS84, cartconvert.DatumMGI)
+	}
+	if set.From != cartconvert.DatumWGS84 || set.To != cartconvert.DatumMGI {
+		return nil, cartconvert.ErrRange
+	}
+	return set, nil
+}
+
+// Transform an Austria Lambert coordinate value to a WGS84 based latitude and longitude coordinate.
+//
+// The orthometric height RelHeight is returned unchanged as Height of the latitude / longitude coordinate.
+func ALToWGS84LatLong(coord *ALCoord) (*cartconvert.PolarCoord, error) {
+	return ALToWGS84LatLongHelmert(coord, nil)
+}
+
+// Transform an Austria Lambert coordinate value to a WGS84 based latitude and longitude coordinate using the
+// helmert parameter set for the datum shift. If set is nil, the default parameter set from WGS84 to MGI is used.
+// Function returns cartconvert.ErrRange, if the parameter set does not transform from WGS84 to MGI.
+func ALToWGS84LatLongHelmert(coord *ALCoord, set *cartconvert.HelmertParameterSet) (*cartconvert.PolarCoord, error) {
+
+	hp, err := helmertParameterSet(set)
+	if err != nil {
+		return nil, err
+	}
+	if err = cartconvert.CheckFinite(coord.Easting, coord.Northing); err != nil {
+		return nil, err
+	}
+
+	lat, long := austriaLambert.unproject(coord.Easting, coord.Northing, cartconvert.Bessel1841MGIEllipsoid)
+
+	cart := cartconvert.PolarToCartesian(&cartconvert.PolarCoord{Latitude: lat, Longitude: long, El: cartconvert.Bessel1841MGIEllipsoid})
+	pt := hp.InverseTransform(&cartconvert.Point3D{X: cart.X, Y: cart.Y, Z: cart.Z})
+
+	polar := cartconvert.CartesianToPolar(&cartconvert.CartPoint{X: pt.X, Y: pt.Y, Z: pt.Z, El: cartconvert.WGS84Ellipsoid})
+	// the datum is shifted on the surface of the ellipsoid, the orthometric height keeps its value
+	polar.Height = coord.RelHeight
+	return polar, nil
+}
+
+// Transform a latitude / longitude coordinate into an Austria Lambert coordinate.
+//
+// The Height of the latitude / longitude coordinate is taken as orthometric height and returned unchanged
+// as RelHeight of the Austria Lambert coordinate. The latitude and longitude are taken on the reference ellipsoid
+// of gc, or on the WGS84Ellipsoid if gc has no reference ellipsoid set.
+func WGS84LatLongToAL(gc *cartconvert.PolarCoord) (*ALCoord, error) {
+	return WGS84LatLongToALHelmert(gc, nil)
+}
+
+// Transform a latitude / longitude coordinate into an Austria Lambert coordinate using the helmert parameter set
+// for the datum shift. If set is nil, the default parameter set from WGS84 to MGI is used.
+// Function returns cartconvert.ErrRange, if the parameter set does not transform from WGS84 to MGI.
+func WGS84LatLongToALHelmert(gc *cartconvert.PolarCoord, set *cartconvert.HelmertParameterSet) (*ALCoord, error) {
+
+	hp, err := helmertParameterSet(set)
+	if err != nil {
+		return nil, err
+	}
+	if err = gc.CheckFinite(); err != nil {
+		return nil, err
+	}
+
+	el := gc.El
+	if el == nil {
+		el = cartconvert.WGS84Ellipsoid
+	}
+
+	// The height is orthometric, not ellipsoidal, so the datum is shifted on the surface of the ellipsoid
+	cart := cartconvert.PolarToCartesian(&cartconvert.PolarCoord{Latitude: gc.Latitude, Longitude: gc.Longitude, El: el})
+	pt := hp.Transform(&cartconvert.Point3D{X: cart.X, Y: cart.Y, Z: cart.Z})
+	polar := cartconvert.CartesianToPolar(&cartconvert.CartPoint{X: pt.X, Y: pt.Y, Z: pt.Z, El: cartconvert.Bessel1841MGIEllipsoid})
+
+	easting, northing := austriaLambert.project(polar.Latitude, polar.Longitude, cartconvert.Bessel1841MGIEllipsoid)
+
+	coord := NewALCoord(easting, northing, gc.Height)
+	coord.Accuracy = hp.Accuracy
+	return coord, nil
+}
+
+func NewALCoord(easting, northing, relheight float64) *ALCoord {
+	return &ALCoord{Easting: easting, Northing: northing, RelHeight: relheight, El: cartconvert.Bessel1841MGIEllipsoid}
+}
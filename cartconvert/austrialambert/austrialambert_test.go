@@ -0,0 +1,209 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// Automated tests for the cartconvert/austrialambert package
+package austrialambert
+
+import (
+	"fmt"
+	"github.com/the42/cartconvert/cartconvert"
+	"math"
+	"testing"
+)
+
+// ## AALToStruct
+func TestAALToStruct(t *testing.T) {
+	out, err := AALToStruct(" 625844.87  483218.81 ")
+	if err != nil || fmt.Sprintf("%.2f %.2f", out.Easting, out.Northing) != "625844.87 483218.81" {
+		t.Errorf("AALToStruct: expected 625844.87 483218.81, got %v (%v)", out, err)
+	}
+	if out != nil && out.El != cartconvert.Bessel1841MGIEllipsoid {
+		t.Errorf("AALToStruct: expected the Bessel ellipsoid of the MGI, got %v", out.El)
+	}
+	if _, err = AALToStruct("625844.87"); err != cartconvert.ErrSyntax {
+		t.Errorf("AALToStruct: expected ErrSyntax, got %v", err)
+	}
+	if _, err = AALToStruct("E625844 483218"); err == nil {
+		t.Error("AALToStruct: expected error on malformed easting")
+	}
+}
+
+// ## String, Format
+func TestALCoordString(t *testing.T) {
+	coord := NewALCoord(625844.874, 483218.8105, 0)
+	if s := coord.String(); s != "625844.87 483218.81" {
+		t.Errorf("ALCoord.String: expected 625844.87 483218.81, got %s", s)
+	}
+	if s := coord.Format(3); s != "625844.874 483218.811" {
+		t.Errorf("ALCoord.Format: expected 625844.874 483218.811, got %s", s)
+	}
+}
+
+// ## WGS84LatLongToAL, ALToWGS84LatLong
+type wGS84LatLongToALTest struct {
+	gc                *cartconvert.PolarCoord
+	easting, northing float64
+}
+
+// Regression values of the datum shift from WGS84 by the default helmert parameter set followed by the projection,
+// which is verified by TestProject against the published worked example and by TestOrigin against the definition
+// of EPSG:31287
+var wGS84LatLongToALTests = []wGS84LatLongToALTest{
+	// Stephansdom, Vienna
+	{&cartconvert.PolarCoord{Latitude: 48.208493, Longitude: 16.373118, El: cartconvert.WGS84Ellipsoid}, 625844.874, 483218.811},
+	// Innsbruck
+	{&cartconvert.PolarCoord{Latitude: 47.269212, Longitude: 11.404102, El: cartconvert.WGS84Ellipsoid}, 254107.959, 376198.413},
+	// Graz
+	{&cartconvert.PolarCoord{Latitude: 47.070714, Longitude: 15.439504, El: cartconvert.WGS84Ellipsoid}, 559943.444, 354504.386},
+	// Bregenz, at the western border
+	{&cartconvert.PolarCoord{Latitude: 47.503, Longitude: 9.747, El: cartconvert.WGS84Ellipsoid}, 130030.012, 406597.758},
+}
+
+func TestWGS84LatLongToAL(t *testing.T) {
+	for index, test := range wGS84LatLongToALTests {
+		out, err := WGS84LatLongToAL(test.gc)
+		if err != nil {
+			t.Fatalf("WGS84LatLongToAL [%d]: %s", index, err)
+		}
+		if math.Abs(out.Easting-test.easting) > 0.01 || math.Abs(out.Northing-test.northing) > 0.01 {
+			t.Errorf("WGS84LatLongToAL [%d]: expected %.3f %.3f, got %.3f %.3f", index, test.easting, test.northing, out.Easting, out.Northing)
+		}
+		if out.Accuracy != 1.5 || out.El != cartconvert.Bessel1841MGIEllipsoid {
+			t.Errorf("WGS84LatLongToAL [%d]: expected an accuracy of 1.5 m on the Bessel ellipsoid of the MGI, got %f m on %v", index, out.Accuracy, out.El)
+		}
+
+		back, err := ALToWGS84LatLong(out)
+		if err != nil || !back.Equal(test.gc, 0.001) {
+			t.Errorf("ALToWGS84LatLong [%d]: expected %s, got %s (%v)", index, test.gc, back, err)
+		}
+	}
+}
+
+// ## project, unproject
+// The worked example of the Lambert Conic Conformal (2SP) projection of the EPSG guidance note 7-2 (IOGP Publication
+// 373-7-2, Coordinate Conversions and Transformations including Formulas): the zone Texas South Central of SPCS27,
+// on the Clarke 1866 ellipsoid of NAD27 in US survey feet. Austria Lambert applies the same method, EPSG:9802.
+const usSurveyFoot = 1200.0 / 3937
+
+// Returns decimal degrees of degrees and minutes
+func dm(deg, min float64) float64 {
+	return deg + min/60
+}
+
+func TestProject(t *testing.T) {
+	texas := lambertProjection{lat1: dm(28, 23), lat2: dm(30, 17), latO: dm(27, 50), longO: -dm(99, 0),
+		falseEasting: 2000000 * usSurveyFoot, falseNorthing: 0}
+	lat, long := dm(28, 30), -96.0
+	easting, northing := 2963503.91*usSurveyFoot, 254759.80*usSurveyFoot
+
+	// the coordinates are published to the hundredth of a foot
+	if e, n := texas.project(lat, long, cartconvert.Clarke1866Ellipsoid); math.Abs(e-easting) > 0.01*usSurveyFoot || math.Abs(n-northing) > 0.01*usSurveyFoot {
+		t.Errorf("project: expected %.3f %.3f, got %.3f %.3f", easting, northing, e, n)
+	}
+	if la, lo := texas.unproject(easting, northing, cartconvert.Clarke1866Ellipsoid); math.Abs(la-lat) > 1e-6 || math.Abs(lo-long) > 1e-6 {
+		t.Errorf("unproject: expected %.8f %.8f, got %.8f %.8f", lat, long, la, lo)
+	}
+}
+
+// ## Origin
+// The origin of EPSG:31287 at 13°20'E 47°30'N is mapped to the false easting and northing of 400000 m
+func TestOrigin(t *testing.T) {
+	easting, northing := austriaLambert.project(47.5, dm(13, 20), cartconvert.Bessel1841MGIEllipsoid)
+	if math.Abs(easting-400000) > 1e-6 || math.Abs(northing-400000) > 1e-6 {
+		t.Errorf("Origin: expected 400000 400000, got %f %f", easting, northing)
+	}
+}
+
+// ## Standard parallels
+// The scale is true along the standard parallels 46° and 49° of EPSG:31287: a short arc of the parallel keeps its
+// length on the Bessel ellipsoid of the MGI, whose semi-major axis is 6377397.155 m
+func TestStandardParallels(t *testing.T) {
+	el := cartconvert.Bessel1841MGIEllipsoid
+	const a, delta = 6377397.155, 0.001
+
+	for _, lat := range []float64{46, 49} {
+		phi := lat * math.Pi / 180
+		nu := a / math.Sqrt(1-el.EccentricitySquared()*math.Sin(phi)*math.Sin(phi))
+		arc := nu * math.Cos(phi) * 2 * delta * math.Pi / 180
+
+		e1, n1 := austriaLambert.project(lat, austriaLambert.longO-delta, el)
+		e2, n2 := austriaLambert.project(lat, austriaLambert.longO+delta, el)
+		if scale := math.Hypot(e2-e1, n2-n1) / arc; math.Abs(scale-1) > 1e-6 {
+			t.Errorf("Standard parallel %.0f: expected a scale of 1, got %.9f", lat, scale)
+		}
+	}
+}
+
+// ## Height
+func TestHeight(t *testing.T) {
+	out, err := WGS84LatLongToAL(&cartconvert.PolarCoord{Latitude: 47.269212, Longitude: 11.404102, Height: 574, El: cartconvert.WGS84Ellipsoid})
+	if err != nil || out.RelHeight != 574 {
+		t.Errorf("WGS84LatLongToAL: expected the orthometric height 574, got %v (%v)", out, err)
+	}
+	back, err := ALToWGS84LatLong(out)
+	if err != nil || back.Height != 574 {
+		t.Errorf("ALToWGS84LatLong: expected the orthometric height 574, got %v (%v)", back, err)
+	}
+}
+
+// ## Helmert
+func TestHelmert(t *testing.T) {
+	gc := wGS84LatLongToALTests[0].gc
+
+	set := &cartconvert.HelmertParameterSet{Name: "WGS84toMGI", From: cartconvert.DatumWGS84, To: cartconvert.DatumMGI, Accuracy: 3,
+		HelmertTransform: cartconvert.HelmertWGS84ToMGI}
+	out, err := WGS84LatLongToALHelmert(gc, set)
+	if err != nil || out.Accuracy != 3 {
+		t.Errorf("WGS84LatLongToALHelmert: expected an accuracy of 3 m, got %v (%v)", out, err)
+	}
+
+	wrong := &cartconvert.HelmertParameterSet{Name: "wrong", From: cartconvert.DatumWGS84, To: cartconvert.DatumOSGB36,
+		HelmertTransform: cartconvert.HelmertWGS84ToMGI}
+	if _, err := WGS84LatLongToALHelmert(gc, wrong); err != cartconvert.ErrRange {
+		t.Errorf("WGS84LatLongToALHelmert: expected ErrRange, got %v", err)
+	}
+	if _, err := ALToWGS84LatLongHelmert(NewALCoord(625845, 483219, 0), wrong); err != cartconvert.ErrRange {
+		t.Errorf("ALToWGS84LatLongHelmert: expected ErrRange, got %v", err)
+	}
+}
+
+// ## Valid
+func TestValid(t *testing.T) {
+	if err := NewALCoord(625845, 483219, 0).Valid(); err != nil {
+		t.Errorf("Valid: expected a valid Austria Lambert coordinate, got %v", err)
+	}
+	if _, ok := NewALCoord(625845, 700000, 0).Valid().(cartconvert.ExtentError); !ok {
+		t.Error("Valid: expected an ExtentError for an Austria Lambert coordinate north of Austria")
+	}
+}
+
+// ## EPSG
+func TestEPSG(t *testing.T) {
+	if code := NewALCoord(400000, 400000, 0).EPSG(); code != 31287 {
+		t.Errorf("EPSG: expected 31287, got %d", code)
+	}
+}
+
+// ## NaN and infinity
+func TestNonFinite(t *testing.T) {
+	for _, value := range []float64{math.NaN(), math.Inf(1)} {
+		if _, err := AALToStruct(fmt.Sprintf("625844.87 %v", value)); err != cartconvert.ErrNotFinite {
+			t.Errorf("AALToStruct (%v): expected ErrNotFinite, got %v", value, err)
+		}
+		if _, err := ALToWGS84LatLong(NewALCoord(value, 483219, 0)); err != cartconvert.ErrNotFinite {
+			t.Errorf("ALToWGS84LatLong (%v): expected ErrNotFinite, got %v", value, err)
+		}
+		if _, err := WGS84LatLongToAL(&cartconvert.PolarCoord{Latitude: value, Longitude: 16.37}); err != cartconvert.ErrNotFinite {
+			t.Errorf("WGS84LatLongToAL (%v): expected ErrNotFinite, got %v", value, err)
+		}
+	}
+}
+
+// ## Snap
+func TestSnap(t *testing.T) {
+	out := NewALCoord(625844.874, 483218.811, 171).Snap(100)
+	if out.Easting != 625800 || out.Northing != 483200 || out.RelHeight != 171 {
+		t.Errorf("ALCoord.Snap: expected 625800 483200, got %s", out)
+	}
+}
@@ -16,8 +16,10 @@
 package bmn
 
 import (
+	"encoding/xml"
 	"fmt"
 	"github.com/the42/cartconvert/cartconvert"
+	"math"
 	"strconv"
 	"strings"
 )
@@ -49,116 +51,317 @@ func (bm BMNMeridian) String() (rep string) {
 	return
 }
 
+// Geographic extent of the Bundesmeldenetz, the territory of Austria
+var Extent = &cartconvert.LatLongExtent{MinLat: 46.3, MaxLat: 49.1, MinLong: 9.5, MaxLong: 17.2}
+
 // A BMN coordinate is specified by right-value (easting), height-value (northing)
-// and the meridian stripe, 28°, 31° or 34° West of Hierro
+// and the meridian stripe, 28°, 31° or 34° West of Hierro. RelHeight is the orthometric height
+// above sea level in meters.
 type BMNCoord struct {
-	Right, Height, RelHeight float64
-	Meridian                 BMNMeridian
-	El                       *cartconvert.Ellipsoid
+	Right     float64                `json:"right" xml:"right"`
+	Height    float64                `json:"height" xml:"height"`
+	RelHeight float64                `json:"relheight" xml:"relheight"`
+	Meridian  BMNMeridian            `json:"meridian" xml:"meridian"`
+	El        *cartconvert.Ellipsoid `json:"ellipsoid,omitempty" xml:"ellipsoid,omitempty"`
+	// Approximate positional error in meters introduced by the datum shift of the conversion from WGS84, the
+	// accuracy of the helmert parameter set used. Zero for coordinates not converted from WGS84.
+	Accuracy float64 `json:"accuracy,omitempty" xml:"accuracy,omitempty"`
+}
+
+// Canonical representation of a BMN-value, right- and height-value to the meter
+func (bc *BMNCoord) String() string {
+	return bc.Format(0)
 }
 
-// Canonical representation of a BMN-value
-func (bc *BMNCoord) String() (fs string) {
+// Implements cartconvert.Coordinate, the system of a BMN-value is "bmn"
+func (bc *BMNCoord) System() string {
+	return "bmn"
+}
 
-	fs = bc.Meridian.String()
-	var next float64
+// Implements cartconvert.Coordinate, see BMNToWGS84LatLong
+func (bc *BMNCoord) ToWGS84() (*cartconvert.PolarCoord, error) {
+	return BMNToWGS84LatLong(bc)
+}
 
-	for i := 0; i < 2; i++ {
-		fs += " "
-		switch i {
-		case 0:
-			next = bc.Right
-		case 1:
-			next = bc.Height
-		}
+// Representation of a BMN-value with precision decimal places of right- and height-value, see
+// cartconvert.FormatFixed
+func (bc *BMNCoord) Format(precision int) string {
+	return bc.Meridian.String() + " " + cartconvert.FormatFixed(bc.Right, precision) + " " + cartconvert.FormatFixed(bc.Height, precision)
+}
+
+// Returns a copy of the BMN coordinate with right and height snapped to the nearest multiple of spacingMeters, see
+// cartconvert.SnapToGrid. The meridian is kept.
+func (bc *BMNCoord) Snap(spacingMeters float64) *BMNCoord {
+	snapped := *bc
+	snapped.Right, snapped.Height = cartconvert.SnapToGrid(bc.Right, spacingMeters), cartconvert.SnapToGrid(bc.Height, spacingMeters)
+	return &snapped
+}
+
+// Implements json.Marshaler, writing the coordinates in fixed-point notation
+func (bc *BMNCoord) MarshalJSON() ([]byte, error) {
+	return cartconvert.MarshalFixedJSON(bc)
+}
+
+// Implements xml.Marshaler, writing the coordinates in fixed-point notation
+func (bc *BMNCoord) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return cartconvert.MarshalFixedXML(e, start, bc)
+}
+
+// Validates that the BMN coordinate lies within Extent, the territory of Austria. Returns the error of
+// BMNToWGS84LatLong, if the meridian stripe is not set, or a cartconvert.ExtentError, if right- and height-value
+// transform to a location outside of Extent.
+func (bc *BMNCoord) Valid() error {
+	gc, err := BMNToWGS84LatLong(bc)
+	if err != nil {
+		return err
+	}
+	return Extent.Validate(gc, "BMN", bc.String())
+}
+
+// EPSG codes of the meridian stripes M28, M31 and M34 of the Bundesmeldenetz, MGI / Austria GK M28 to M34
+const (
+	EPSGM28 = 31257
+	EPSGM31 = 31258
+	EPSGM34 = 31259
+)
+
+// Returns the EPSG code of the meridian stripe of the BMN coordinate, 0 if the meridian stripe is not set
+func (bc *BMNCoord) EPSG() int {
+	switch bc.Meridian {
+	case BMNM28:
+		return EPSGM28
+	case BMNM31:
+		return EPSGM31
+	case BMNM34:
+		return EPSGM34
+	}
+	return 0
+}
 
-		tmp := fmt.Sprintf("%.0f", next)
-		n := len(tmp)
-		for n > 0 && tmp[n-1] == '0' {
-			n--
+// The meridian stripes are registered as coordinate reference systems of the MGI
+func init() {
+	for _, meridian := range []BMNMeridian{BMNM28, BMNM31, BMNM34} {
+		long0, fe, _ := BMNMeridianParameters(meridian)
+		crs := &cartconvert.CRS{EPSG: (&BMNCoord{Meridian: meridian}).EPSG(), Name: "MGI / Austria GK " + meridian.String(),
+			Datum: cartconvert.DatumMGI, El: cartconvert.Bessel1841MGIEllipsoid, Method: cartconvert.MethodTransverseMercator,
+			LongO: long0, Scale: 1, FalseEasting: fe, FalseNorthing: -5000000}
+		if err := cartconvert.RegisterCRS(crs); err != nil {
+			panic(err)
 		}
-		if n > 0 && tmp[n-1] == '.' {
-			n--
+	}
+}
+
+// Errors returned by ABMNToStruct. All of them wrap cartconvert.ErrSyntax.
+var (
+	ErrTokenCount      = fmt.Errorf("%w: expected meridian, right and height value", cartconvert.ErrSyntax)
+	ErrUnknownMeridian = fmt.Errorf("%w: unknown meridian", cartconvert.ErrSyntax)
+	ErrInvalidEasting  = fmt.Errorf("%w: invalid easting (right value)", cartconvert.ErrSyntax)
+	ErrInvalidNorthing = fmt.Errorf("%w: invalid northing (height value)", cartconvert.ErrSyntax)
+)
+
+// Reports whether s is an unsigned decimal number of digits with an optional fraction, like 592269 or 272290.5.
+// Signs, exponents, hexadecimal numbers, underscores, Inf and NaN, all accepted by strconv, are rejected.
+func isDecimal(s string) bool {
+	digits, point := 0, false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] >= '0' && s[i] <= '9':
+			digits++
+		case s[i] == '.' && !point:
+			point = true
+		default:
+			return false
 		}
-		fs = fs + tmp[:n]
 	}
-	return
+	return digits > 0
 }
 
 // Parses a string representation of a BMN-Coordinate into a struct holding a BMN coordinate value.
-// The reference ellipsoid of BMN coordinates is always the Bessel ellipsoid.
+// The literal consists of exactly three tokens separated by white space: the meridian M28, M31 or M34, the right
+// and the height value, as unsigned decimal numbers. The reference ellipsoid of BMN coordinates is always the
+// Bessel ellipsoid.
+//
+// returns ErrTokenCount if the literal doesn't consist of three tokens
+// returns ErrUnknownMeridian if the first token is no meridian of the BMN
+// returns ErrInvalidEasting or ErrInvalidNorthing if the right or height value is no decimal number
 func ABMNToStruct(bmncoord string) (*BMNCoord, error) {
 
-	compact := strings.ToUpper(strings.TrimSpace(bmncoord))
-	var rights, heights string
-	var meridian BMNMeridian
-	var right, height float64
-	var err error
-
-L1:
-	for i, index := 0, 0; i < 3; i++ {
-		index = strings.Index(compact, " ")
-		if index == -1 {
-			index = len(compact)
-		}
-		switch i {
-		case 0:
-			switch compact[:index] {
-			case "M28":
-				meridian = BMNM28
-			case "M31":
-				meridian = BMNM31
-			case "M34":
-				meridian = BMNM34
-			default:
-				err = cartconvert.ErrSyntax
-				break L1
-			}
-		case 1:
-			rights = compact[:index]
-		case 2:
-			heights = compact[:index]
-			break L1
-		}
-		compact = compact[index+len(" "):]
-		compact = strings.TrimLeft(compact, " ")
+	tokens := strings.Fields(strings.ToUpper(bmncoord))
+	if len(tokens) != 3 {
+		return nil, ErrTokenCount
 	}
 
-	if err == nil {
-
-		right, err = strconv.ParseFloat(rights, 64)
-		if err == nil {
+	var meridian BMNMeridian
+	switch tokens[0] {
+	case "M28":
+		meridian = BMNM28
+	case "M31":
+		meridian = BMNM31
+	case "M34":
+		meridian = BMNM34
+	default:
+		return nil, ErrUnknownMeridian
+	}
 
-			height, err = strconv.ParseFloat(heights, 64)
-			if err == nil {
+	if !isDecimal(tokens[1]) {
+		return nil, ErrInvalidEasting
+	}
+	right, err := strconv.ParseFloat(tokens[1], 64)
+	if err != nil {
+		return nil, ErrInvalidEasting
+	}
 
-				return &BMNCoord{Right: right, Height: height, Meridian: meridian, El: cartconvert.Bessel1841MGIEllipsoid}, nil
-			}
-		}
+	if !isDecimal(tokens[2]) {
+		return nil, ErrInvalidNorthing
+	}
+	height, err := strconv.ParseFloat(tokens[2], 64)
+	if err != nil {
+		return nil, ErrInvalidNorthing
 	}
 
-	return nil, err
+	return &BMNCoord{Right: right, Height: height, Meridian: meridian, El: cartconvert.Bessel1841MGIEllipsoid}, nil
 }
 
-// Transform a BMN coordinate value to a WGS84 based latitude and longitude coordinate. Function returns
-// cartconvert.ErrRange, if the meridian stripe of the bmn-coordinate is not set
-func BMNToWGS84LatLong(bmncoord *BMNCoord) (*cartconvert.PolarCoord, error) {
+// Half width of a meridian stripe of the Bundesmeldenetz east and west of its central meridian, in degrees
+const stripeHalfWidth = 1.5
 
-	var long0, fe float64
+// Central meridians of the meridian stripes, 28°, 31° and 34° east of Hierro, in degrees east of Greenwich
+const (
+	m28CentralMeridian = 10.0 + 20.0/60.0
+	m31CentralMeridian = 13.0 + 20.0/60.0
+	m34CentralMeridian = 16.0 + 20.0/60.0
+)
+
+// Longitude breakpoints of the meridian stripes on MGI, in degrees east of Greenwich. Every stripe spans from its
+// western breakpoint, inclusive, to the western breakpoint of the next stripe, exclusive. The breakpoints of
+// neighbouring stripes coincide, so there are neither gaps nor overlaps: M28 spans 8°50' to 11°50', M31 11°50'
+// to 14°50' and M34 14°50' to 17°50', inclusive.
+const (
+	m28West = m28CentralMeridian - stripeHalfWidth
+	m31West = m31CentralMeridian - stripeHalfWidth
+	m34West = m34CentralMeridian - stripeHalfWidth
+	m34East = m34CentralMeridian + stripeHalfWidth
+)
 
-	switch bmncoord.Meridian {
+// Returns the meridian stripe of a longitude on MGI, or BMNZoneDet, if the longitude lies outside of all stripes.
+// A longitude on WGS84 needs to be shifted to MGI first, as done by WGS84LatLongToBMN, since the datum shift moves
+// the longitude by several arc seconds and may thus cross the boundary of two stripes.
+func BMNMeridianFor(long float64) BMNMeridian {
+	switch {
+	case long < m28West || long > m34East:
+		return BMNZoneDet
+	case long < m31West:
+		return BMNM28
+	case long < m34West:
+		return BMNM31
+	}
+	return BMNM34
+}
+
+// Returns the longitude of the central meridian on MGI and the false easting of a meridian stripe.
+// Returns cartconvert.ErrRange, if the meridian stripe is not one of M28, M31 or M34.
+func BMNMeridianParameters(meridian BMNMeridian) (long0, fe float64, err error) {
+	switch meridian {
 	case BMNM28:
-		long0 = 10.0 + 20.0/60.0
+		long0 = m28CentralMeridian
 		fe = 150000
 	case BMNM31:
-		long0 = 13.0 + 20.0/60.0
+		long0 = m31CentralMeridian
 		fe = 450000
 	case BMNM34:
-		long0 = 16.0 + 20.0/60.0
+		long0 = m34CentralMeridian
 		fe = 750000
 	default:
+		err = cartconvert.ErrRange
+	}
+	return
+}
+
+// Returns the western and eastern breakpoint of longitude on MGI of a meridian stripe. As by BMNMeridianFor, the
+// eastern breakpoint of M28 and M31 belongs to the next stripe, the one of M34 to M34 itself. Returns cartconvert.ErrRange, if the meridian stripe is not one of M28, M31 or M34.
+func BMNMeridianLongitudes(meridian BMNMeridian) (west, east float64, err error) {
+	switch meridian {
+	case BMNM28:
+		west, east = m28West, m31West
+	case BMNM31:
+		west, east = m31West, m34West
+	case BMNM34:
+		west, east = m34West, m34East
+	default:
+		err = cartconvert.ErrRange
+	}
+	return
+}
+
+// Returns the area covered by the meridian stripe as closed ring of WGS84 coordinates, as by
+// cartconvert.LatLongExtent.Polygon. The stripe is bounded on MGI by the longitudes of BMNMeridianLongitudes
+// and the latitudes of Extent, every edge divided into segments parts. The boundary is projected into the
+// right- and height-values of the stripe and inverse-projected to WGS84 by BMNToWGS84LatLong, so the edges
+// follow the datum shift. Function returns cartconvert.ErrRange, if the meridian stripe is not set or
+// segments is less than 1.
+func BMNMeridianPolygon(meridian BMNMeridian, segments int) ([]*cartconvert.PolarCoord, error) {
+
+	west, east, err := BMNMeridianLongitudes(meridian)
+	if err != nil {
+		return nil, err
+	}
+	long0, fe, _ := BMNMeridianParameters(meridian)
+
+	points, err := (&cartconvert.LatLongExtent{MinLat: Extent.MinLat, MaxLat: Extent.MaxLat, MinLong: west, MaxLong: east}).Polygon(segments)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, gc := range points {
+		gc.El = cartconvert.Bessel1841MGIEllipsoid
+		gp := cartconvert.DirectTransverseMercator(gc, 0, long0, 1, fe, -5000000)
+		if points[i], err = BMNToWGS84LatLong(&BMNCoord{Right: gp.X, Height: gp.Y, Meridian: meridian, El: cartconvert.Bessel1841MGIEllipsoid}); err != nil {
+			return nil, err
+		}
+	}
+	return points, nil
+}
+
+// Returns the helmert parameter set used for the datum shift between WGS84 and MGI. If set is nil,
+// the default parameter set for WGS84 to MGI is returned. Returns cartconvert.ErrRange if the
+// parameter set does not transform from WGS84 to MGI.
+func helmertParameterSet(set *cartconvert.HelmertParameterSet) (*cartconvert.HelmertParameterSet, error) {
+	if set == nil {
+		return cartconvert.DefaultHelmertParameterSet(cartconvert.DatumWGS84, cartconvert.DatumMGI)
+	}
+	if set.From != cartconvert.DatumWGS84 || set.To != cartconvert.DatumMGI {
 		return nil, cartconvert.ErrRange
 	}
+	return set, nil
+}
+
+// Transform a BMN coordinate value to a WGS84 based latitude and longitude coordinate. Function returns
+// cartconvert.ErrRange, if the meridian stripe of the bmn-coordinate is not set.
+//
+// The orthometric height RelHeight is returned unchanged as Height of the latitude / longitude coordinate.
+// Converting it to a height above the WGS84 ellipsoid requires a geoid model, which is not part of this package.
+func BMNToWGS84LatLong(bmncoord *BMNCoord) (*cartconvert.PolarCoord, error) {
+	return BMNToWGS84LatLongHelmert(bmncoord, nil)
+}
+
+// Transform a BMN coordinate value to a WGS84 based latitude and longitude coordinate using the helmert
+// parameter set for the datum shift. If set is nil, the default parameter set from WGS84 to MGI is used.
+// Function returns cartconvert.ErrRange, if the meridian stripe of the bmn-coordinate is not set or
+// the parameter set does not transform from WGS84 to MGI.
+func BMNToWGS84LatLongHelmert(bmncoord *BMNCoord, set *cartconvert.HelmertParameterSet) (*cartconvert.PolarCoord, error) {
+
+	hp, err := helmertParameterSet(set)
+	if err != nil {
+		return nil, err
+	}
+
+	long0, fe, err := BMNMeridianParameters(bmncoord.Meridian)
+	if err != nil {
+		return nil, err
+	}
+	if err = cartconvert.CheckFinite(bmncoord.Right, bmncoord.Height); err != nil {
+		return nil, err
+	}
 
 	gc := cartconvert.InverseTransverseMercator(
 		&cartconvert.GeoPoint{Y: bmncoord.Height, X: bmncoord.Right, El: bmncoord.El},
@@ -169,64 +372,215 @@ func BMNToWGS84LatLong(bmncoord *BMNCoord) (*cartconvert.PolarCoord, error) {
 		-5000000)
 
 	cart := cartconvert.PolarToCartesian(gc)
-	pt := cartconvert.HelmertWGS84ToMGI.InverseTransform(&cartconvert.Point3D{X: cart.X, Y: cart.Y, Z: cart.Z})
+	pt := hp.InverseTransform(&cartconvert.Point3D{X: cart.X, Y: cart.Y, Z: cart.Z})
 
-	return cartconvert.CartesianToPolar(&cartconvert.CartPoint{X: pt.X, Y: pt.Y, Z: pt.Z, El: cartconvert.WGS84Ellipsoid}), nil
+	polar := cartconvert.CartesianToPolar(&cartconvert.CartPoint{X: pt.X, Y: pt.Y, Z: pt.Z, El: cartconvert.WGS84Ellipsoid})
+	// the datum is shifted on the surface of the ellipsoid, the orthometric height keeps its value
+	polar.Height = bmncoord.RelHeight
+	return polar, nil
 }
 
 // Transform a latitude / longitude coordinate datum into a BMN coordinate. Function returns
 // cartconvert.ErrRange, if the meridian stripe of the bmn-coordinate is not set.
 //
-// Important: The reference ellipsoid of the originating coordinate system will be assumed
-// to be the WGS84Ellipsoid and will be set thereupon, regardless of the actually set reference ellipsoid.
+// The Height of the latitude / longitude coordinate is taken as orthometric height and returned unchanged
+// as RelHeight of the BMN coordinate.
+//
+// The latitude and longitude are taken on the reference ellipsoid of gc, eg. the GRS80Ellipsoid of ETRS89
+// coordinates, or on the WGS84Ellipsoid if gc has no reference ellipsoid set. gc is not modified.
 func WGS84LatLongToBMN(gc *cartconvert.PolarCoord, meridian BMNMeridian) (*BMNCoord, error) {
+	return WGS84LatLongToBMNHelmert(gc, meridian, nil)
+}
 
-	var long0, fe float64
+// Transform a latitude / longitude coordinate datum into a BMN coordinate using the helmert parameter set
+// for the datum shift. If set is nil, the default parameter set from WGS84 to MGI is used.
+// Function returns cartconvert.ErrRange, if the meridian stripe of the bmn-coordinate is not set or
+// the parameter set does not transform from WGS84 to MGI.
+func WGS84LatLongToBMNHelmert(gc *cartconvert.PolarCoord, meridian BMNMeridian, set *cartconvert.HelmertParameterSet) (*BMNCoord, error) {
 
-	// This sets the Ellipsoid to WGS84, regardless of the actual value set
-	gc.El = cartconvert.WGS84Ellipsoid
+	hp, err := helmertParameterSet(set)
+	if err != nil {
+		return nil, err
+	}
 
-	cart := cartconvert.PolarToCartesian(gc)
-	pt := cartconvert.HelmertWGS84ToMGI.Transform(&cartconvert.Point3D{X: cart.X, Y: cart.Y, Z: cart.Z})
-	polar := cartconvert.CartesianToPolar(&cartconvert.CartPoint{X: pt.X, Y: pt.Y, Z: pt.Z, El: cartconvert.Bessel1841MGIEllipsoid})
+	right, height, meridian, err := wgs84LatLongToBMN(gc, meridian, hp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BMNCoord{Meridian: meridian, Height: height, Right: right, RelHeight: gc.Height, El: cartconvert.Bessel1841MGIEllipsoid, Accuracy: hp.Accuracy}, nil
+}
+
+// Does the work of WGS84LatLongToBMNHelmert without allocating the resulting BMN coordinate or any intermediate
+// coordinate. Returns the right- and height-value together with the meridian stripe, which is determined if
+// meridian is BMNZoneDet.
+func wgs84LatLongToBMN(gc *cartconvert.PolarCoord, meridian BMNMeridian, hp *cartconvert.HelmertParameterSet) (right, height float64, _ BMNMeridian, err error) {
+
+	if err = gc.CheckFinite(); err != nil {
+		return
+	}
+
+	el := gc.El
+	if el == nil {
+		el = cartconvert.WGS84Ellipsoid
+	}
+
+	// The height is orthometric, not ellipsoidal, so the datum is shifted on the surface of the ellipsoid
+	var cart cartconvert.CartPoint
+	cartconvert.PolarToCartesianInto(&cartconvert.PolarCoord{Latitude: gc.Latitude, Longitude: gc.Longitude, El: el}, &cart)
+	pt := cartconvert.Point3D{X: cart.X, Y: cart.Y, Z: cart.Z}
+	hp.TransformInto(&pt, &pt)
+	var polar cartconvert.PolarCoord
+	cartconvert.CartesianToPolarInto(&cartconvert.CartPoint{X: pt.X, Y: pt.Y, Z: pt.Z, El: cartconvert.Bessel1841MGIEllipsoid}, &polar)
 
 	// Determine meridian stripe based on longitude
 	if meridian == BMNZoneDet {
-		switch {
-		case 11.0+0.5/6*10 >= polar.Longitude && polar.Longitude >= 8.0+0.5/6*10:
-			meridian = BMNM28
-		case 14.0+0.5/6*10 >= polar.Longitude && polar.Longitude >= 11.0+0.5/6*10:
-			meridian = BMNM31
-		case 17.0+0.5/6*10 >= polar.Longitude && polar.Longitude >= 14.0+0.5/6*10:
-			meridian = BMNM34
-		}
+		meridian = BMNMeridianFor(polar.Longitude)
 	}
 
-	switch meridian {
-	case BMNM28:
-		long0 = 10.0 + 20.0/60.0
-		fe = 150000
-	case BMNM31:
-		long0 = 13.0 + 20.0/60.0
-		fe = 450000
-	case BMNM34:
-		long0 = 16.0 + 20.0/60.0
-		fe = 750000
-	default:
-		return nil, cartconvert.ErrRange
+	long0, fe, err := BMNMeridianParameters(meridian)
+	if err != nil {
+		return 0, 0, meridian, err
 	}
 
-	gp := cartconvert.DirectTransverseMercator(
-		polar,
+	var gp cartconvert.GeoPoint
+	cartconvert.DirectTransverseMercatorInto(
+		&polar,
 		0,
 		long0,
 		1,
 		fe,
-		-5000000)
+		-5000000,
+		&gp)
 
-	return &BMNCoord{Meridian: meridian, Height: gp.Y, Right: gp.X, El: gp.El}, nil
+	return gp.X, gp.Y, meridian, nil
+}
+
+// Transform interleaved WGS84 based latitude / longitude pairs into interleaved BMN right- / height-value pairs
+// of the meridian stripe. If meridian is BMNZoneDet, the meridian stripe is determined for every point separately.
+// Unlike looping over WGS84LatLongToBMN, neither an input nor a result struct is allocated per point and the
+// default helmert parameter set is looked up only once, which makes this the preferred form for numeric
+// pipelines and callers from cgo.
+//
+// The returned slice eastingNorthing has the same length as latlon. Errors are reported in errs, which is nil
+// if all points were transformed, otherwise errs holds one entry per point, nil for every transformed point.
+// The values of a point which could not be transformed are set to NaN.
+// If latlon has an odd number of values, no point is transformed and errs holds the single error cartconvert.ErrSyntax.
+func WGS84LatLongToBMNFlat(latlon []float64, meridian BMNMeridian) (eastingNorthing []float64, errs []error) {
+
+	if len(latlon)%2 != 0 {
+		return nil, []error{cartconvert.ErrSyntax}
+	}
+
+	hp, err := helmertParameterSet(nil)
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	eastingNorthing = make([]float64, len(latlon))
+	gc := &cartconvert.PolarCoord{El: cartconvert.WGS84Ellipsoid}
+
+	for i := 0; i < len(latlon); i += 2 {
+		gc.Latitude, gc.Longitude = latlon[i], latlon[i+1]
+
+		right, height, _, err := wgs84LatLongToBMN(gc, meridian, hp)
+		if err != nil {
+			if errs == nil {
+				errs = make([]error, len(latlon)/2)
+			}
+			errs[i/2] = err
+			right, height = math.NaN(), math.NaN()
+		}
+		eastingNorthing[i], eastingNorthing[i+1] = right, height
+	}
+	return
 }
 
 func NewBMNCoord(Meridian BMNMeridian, Right, Height, RelHeight float64) *BMNCoord {
 	return &BMNCoord{Right: Right, Height: Height, RelHeight: RelHeight, Meridian: Meridian, El: cartconvert.Bessel1841MGIEllipsoid}
 }
+
+// Adds the metric offsets dE (easting) and dN (northing) to a BMN coordinate and returns the resulting BMN
+// coordinate together with its WGS84 based latitude and longitude.
+//
+// Function returns cartconvert.ErrRange, if the meridian stripe of the bmn-coordinate is not set or
+// if the resulting coordinate lies outside of the meridian stripe, that is farther than 1°30' east or west
+// of the central meridian.
+func OffsetGrid(bc *BMNCoord, dE, dN float64) (*BMNCoord, *cartconvert.PolarCoord, error) {
+
+	long0, fe, err := BMNMeridianParameters(bc.Meridian)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = cartconvert.CheckFinite(bc.Right, bc.Height, dE, dN); err != nil {
+		return nil, nil, err
+	}
+
+	offset := &BMNCoord{Right: bc.Right + dE, Height: bc.Height + dN, RelHeight: bc.RelHeight, Meridian: bc.Meridian, El: bc.El}
+
+	gc := cartconvert.InverseTransverseMercator(
+		&cartconvert.GeoPoint{Y: offset.Height, X: offset.Right, El: offset.El},
+		0,
+		long0,
+		1,
+		fe,
+		-5000000)
+
+	if math.Abs(gc.Longitude-long0) > stripeHalfWidth {
+		return nil, nil, cartconvert.ErrRange
+	}
+
+	latlong, err := BMNToWGS84LatLong(offset)
+	if err != nil {
+		return nil, nil, err
+	}
+	return offset, latlong, nil
+}
+
+// Number of bits used by the cell key to encode the cell index of easting and northing respectively
+const cellKeyIndexBits = 31
+
+// Returns the integer key of the grid cell of size resolution x resolution meters containing the BMN coordinate,
+// to be used for spatial bucketing. The key packs the meridian stripe and the indices of the snapped easting and
+// northing, which renders it free of collisions for all BMN coordinates of non-negative easting and northing
+// smaller than resolution * 2^31. Decode a key with CellKeyToBMN.
+//
+// Function returns cartconvert.ErrRange, if resolution is not positive, the meridian stripe of the BMN coordinate
+// is not set or if the coordinate can not be encoded at the given resolution.
+func (bc *BMNCoord) CellKey(resolution float64) (uint64, error) {
+
+	if !(resolution > 0) {
+		return 0, cartconvert.ErrRange
+	}
+	if _, _, err := BMNMeridianParameters(bc.Meridian); err != nil {
+		return 0, err
+	}
+
+	const maxIndex = 1 << cellKeyIndexBits
+	east, north := math.Floor(bc.Right/resolution), math.Floor(bc.Height/resolution)
+	if !(east >= 0 && east < maxIndex && north >= 0 && north < maxIndex) {
+		return 0, cartconvert.ErrRange
+	}
+
+	return uint64(bc.Meridian)<<(2*cellKeyIndexBits) | uint64(east)<<cellKeyIndexBits | uint64(north), nil
+}
+
+// Decodes a key computed by CellKey at the same resolution into the BMN coordinate of the south-west corner
+// of the grid cell.
+//
+// Function returns cartconvert.ErrRange, if resolution is not positive or the key does not denote a meridian stripe.
+func CellKeyToBMN(key uint64, resolution float64) (*BMNCoord, error) {
+
+	if !(resolution > 0) {
+		return nil, cartconvert.ErrRange
+	}
+
+	const indexMask = 1<<cellKeyIndexBits - 1
+	meridian := BMNMeridian(key >> (2 * cellKeyIndexBits))
+	if _, _, err := BMNMeridianParameters(meridian); err != nil {
+		return nil, err
+	}
+
+	east, north := float64(key>>cellKeyIndexBits&indexMask), float64(key&indexMask)
+	return NewBMNCoord(meridian, east*resolution, north*resolution, 0), nil
+}
@@ -6,8 +6,11 @@
 package bmn
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/the42/cartconvert/cartconvert"
+	"math"
 	"testing"
 )
 
@@ -45,6 +48,141 @@ func TestBMNStringToStruct(t *testing.T) {
 	}
 }
 
+// ## ABMNToStruct, malformed literals
+type aBMNToStructErrorTest struct {
+	in  string
+	err error
+}
+
+var aBMNToStructErrorTests = []aBMNToStructErrorTest{
+	{"", ErrTokenCount},
+	{"M31", ErrTokenCount},
+	{"M31 592269", ErrTokenCount},
+	{"M31 592269 272290 0", ErrTokenCount},
+	{"M31 592269 272290 junk", ErrTokenCount},
+	{"M 31 592269 272290", ErrTokenCount},
+	{"M99 592269 272290", ErrUnknownMeridian},
+	{"M31X 592269 272290", ErrUnknownMeridian},
+	{"M31, 592269 272290", ErrUnknownMeridian},
+	{"31 592269 272290", ErrUnknownMeridian},
+	{"M31 5.92269E5 272290", ErrInvalidEasting},
+	{"M31 -592269 272290", ErrInvalidEasting},
+	{"M31 +592269 272290", ErrInvalidEasting},
+	{"M31 0x90A0D 272290", ErrInvalidEasting},
+	{"M31 592_269 272290", ErrInvalidEasting},
+	{"M31 592269.0.0 272290", ErrInvalidEasting},
+	{"M31 . 272290", ErrInvalidEasting},
+	{"M31 INF 272290", ErrInvalidEasting},
+	{"M31 592269 NAN", ErrInvalidNorthing},
+	{"M31 592269 2.7229e5", ErrInvalidNorthing},
+	{"M31 592269 272290m", ErrInvalidNorthing},
+}
+
+func TestABMNToStructErrors(t *testing.T) {
+	for _, test := range aBMNToStructErrorTests {
+		out, err := ABMNToStruct(test.in)
+
+		if err != test.err || out != nil {
+			t.Errorf("ABMNToStruct %q: expected error %v, got %v", test.in, test.err, err)
+		}
+		if !errors.Is(err, cartconvert.ErrSyntax) {
+			t.Errorf("ABMNToStruct %q: expected the error to wrap ErrSyntax, got %v", test.in, err)
+		}
+	}
+}
+
+// Literals accepted with surrounding and repeated white space, in lower case and with fractions
+var aBMNToStructLaxTests = []string{
+	"  M31 592269 272290  ",
+	"m31\t592269   272290",
+	"M31 592269. 272290.0",
+	"M31 0592269 272290.",
+}
+
+func TestABMNToStructLax(t *testing.T) {
+	for _, in := range aBMNToStructLaxTests {
+		out, err := ABMNToStruct(in)
+
+		if err != nil {
+			t.Errorf("ABMNToStruct %q: %v", in, err)
+			continue
+		}
+		if out.Meridian != BMNM31 || out.Right != 592269 || out.Height != 272290 {
+			t.Errorf("ABMNToStruct %q: expected M31 592269 272290, got %s", in, out)
+		}
+	}
+}
+
+// Every literal is either rejected with an error wrapping ErrSyntax or yields a coordinate, which reads back the
+// same from its string representation
+func FuzzABMNToStruct(f *testing.F) {
+	for _, test := range bMNStringToStructTests {
+		f.Add(test.in)
+	}
+	for _, test := range aBMNToStructErrorTests {
+		f.Add(test.in)
+	}
+	for _, in := range aBMNToStructLaxTests {
+		f.Add(in)
+	}
+
+	f.Fuzz(func(t *testing.T, in string) {
+		out, err := ABMNToStruct(in)
+		if err != nil {
+			if out != nil || !errors.Is(err, cartconvert.ErrSyntax) {
+				t.Fatalf("ABMNToStruct %q: unexpected result %v, %v", in, out, err)
+			}
+			return
+		}
+		if math.IsInf(out.Right, 0) || math.IsInf(out.Height, 0) || out.Right < 0 || out.Height < 0 {
+			t.Fatalf("ABMNToStruct %q: accepted the value %s", in, out)
+		}
+
+		again, err := ABMNToStruct(out.String())
+		if err != nil {
+			t.Fatalf("ABMNToStruct %q: can't read back %q: %v", in, out.String(), err)
+		}
+		if again.Meridian != out.Meridian {
+			t.Fatalf("ABMNToStruct %q: read back %s as %s", in, out, again)
+		}
+	})
+}
+
+// ## Format
+type formatTest struct {
+	in        *BMNCoord
+	precision int
+	out       string
+}
+
+var formatTests = []formatTest{
+	{NewBMNCoord(BMNM31, 592270, 272290, 0), 0, "M31 592270 272290"},
+	{NewBMNCoord(BMNM31, 592269.5, 272289.996, 0), 2, "M31 592269.50 272290.00"},
+	{NewBMNCoord(BMNM28, 99999.95, 272289.4, 0), 0, "M28 100000 272289"},
+	{NewBMNCoord(BMNM34, 592269.125, 272290, 0), -1, "M34 592269.125 272290"},
+}
+
+func TestFormat(t *testing.T) {
+	for index, test := range formatTests {
+		if out := test.in.Format(test.precision); out != test.out {
+			t.Errorf("Format [%d]: expected %s, got %s", index, test.out, out)
+		}
+	}
+
+	// String keeps the meters
+	if out := NewBMNCoord(BMNM34, 592269.5, 272289.4, 0).String(); out != "M34 592270 272289" {
+		t.Errorf("String: expected M34 592270 272289, got %s", out)
+	}
+}
+
+// ## MarshalJSON
+func TestMarshalJSON(t *testing.T) {
+	out, err := json.Marshal(NewBMNCoord(BMNM34, 592269.5, 272290, 0))
+	if expected := `{"right":592269.5,"height":272290,"relheight":0,"meridian":3,"ellipsoid":"Bessel1841MGI"}`; err != nil || string(out) != expected {
+		t.Errorf("MarshalJSON: expected %s, got %s (%v)", expected, out, err)
+	}
+}
+
 // ## BMNToWGS84LatLong
 type bMNToWGS84LatLongTest struct {
 	in  *BMNCoord
@@ -105,13 +243,13 @@ var wGS84LatLongToBMNTests = []wGS84LatLongToBMNTest{
 		wGS84LatLongToBMNParam{
 			gc:       &cartconvert.PolarCoord{Latitude: 47.570299, Longitude: 14.236188, El: cartconvert.WGS84Ellipsoid},
 			meridian: BMNM34},
-		NewBMNCoord(BMNM34, 592269, 272290.05, 0),
+		NewBMNCoord(BMNM34, 592272, 272289.38, 0),
 	},
 	{
 		wGS84LatLongToBMNParam{
 			gc:       &cartconvert.PolarCoord{Latitude: 48.507001, Longitude: 15.698748, El: cartconvert.WGS84Ellipsoid},
 			meridian: BMNZoneDet},
-		NewBMNCoord(BMNM34, 703168, 374510, 0),
+		NewBMNCoord(BMNM34, 703171, 374509.35, 0),
 	},
 }
 
@@ -123,3 +261,526 @@ func TestWGS84LatLongToBMN(t *testing.T) {
 		}
 	}
 }
+
+// ## RelHeight
+var relHeightTests = []*BMNCoord{
+	NewBMNCoord(BMNM28, 592270.0, 272290, 0),
+	NewBMNCoord(BMNM34, 592269, 272290, 1234.5),
+	NewBMNCoord(BMNM34, 703168, 374510, 3798),
+	NewBMNCoord(BMNM31, 450000, 215000, -2.25),
+}
+
+func TestRelHeightRoundTrip(t *testing.T) {
+	for index, test := range relHeightTests {
+		gc, err := BMNToWGS84LatLong(test)
+		if err != nil {
+			t.Fatalf("BMNToWGS84LatLong [%d]: %s", index, err)
+		}
+		if gc.Height != test.RelHeight {
+			t.Errorf("BMNToWGS84LatLong [%d]: expected height %g, got %g", index, test.RelHeight, gc.Height)
+		}
+
+		out, err := WGS84LatLongToBMN(gc, test.Meridian)
+		if err != nil {
+			t.Fatalf("WGS84LatLongToBMN [%d]: %s", index, err)
+		}
+		if out.RelHeight != test.RelHeight {
+			t.Errorf("WGS84LatLongToBMN [%d]: expected height %g, got %g", index, test.RelHeight, out.RelHeight)
+		}
+		if math.Abs(out.Right-test.Right) > 0.001 || math.Abs(out.Height-test.Height) > 0.001 {
+			t.Errorf("WGS84LatLongToBMN [%d]: expected %.2f %.2f, got %.2f %.2f", index, test.Right, test.Height, out.Right, out.Height)
+		}
+	}
+}
+
+// ## BMNMeridianFor
+type bMNMeridianForTest struct {
+	long     float64
+	meridian BMNMeridian
+}
+
+var bMNMeridianForTests = []bMNMeridianForTest{
+	{8.0 + 50.0/60.0 - 1e-9, BMNZoneDet},
+	{8.0 + 50.0/60.0, BMNM28},
+	{10.0 + 20.0/60.0, BMNM28},
+	{11.0 + 50.0/60.0 - 1e-9, BMNM28},
+	{11.0 + 50.0/60.0, BMNM31},
+	{13.0 + 20.0/60.0, BMNM31},
+	{14.0 + 50.0/60.0 - 1e-9, BMNM31},
+	{14.0 + 50.0/60.0, BMNM34},
+	{16.0 + 20.0/60.0, BMNM34},
+	{17.0 + 50.0/60.0, BMNM34},
+	{17.0 + 50.0/60.0 + 1e-9, BMNZoneDet},
+}
+
+func TestBMNMeridianFor(t *testing.T) {
+	for index, test := range bMNMeridianForTests {
+		if meridian := BMNMeridianFor(test.long); meridian != test.meridian {
+			t.Errorf("BMNMeridianFor [%d]: expected %s for %.10f, got %s", index, test.meridian, test.long, meridian)
+		}
+	}
+}
+
+// ## BMNMeridianParameters, BMNMeridianLongitudes
+type bMNMeridianParametersTest struct {
+	meridian   BMNMeridian
+	long0, fe  float64
+	west, east float64
+	err        error
+}
+
+var bMNMeridianParametersTests = []bMNMeridianParametersTest{
+	{BMNM28, 10.0 + 20.0/60.0, 150000, 8.0 + 50.0/60.0, 11.0 + 50.0/60.0, nil},
+	{BMNM31, 13.0 + 20.0/60.0, 450000, 11.0 + 50.0/60.0, 14.0 + 50.0/60.0, nil},
+	{BMNM34, 16.0 + 20.0/60.0, 750000, 14.0 + 50.0/60.0, 17.0 + 50.0/60.0, nil},
+	{BMNZoneDet, 0, 0, 0, 0, cartconvert.ErrRange},
+}
+
+func TestBMNMeridianParameters(t *testing.T) {
+	for index, test := range bMNMeridianParametersTests {
+		long0, fe, err := BMNMeridianParameters(test.meridian)
+		if err != test.err || math.Abs(long0-test.long0) > 1e-9 || fe != test.fe {
+			t.Errorf("BMNMeridianParameters [%d]: expected %f %f (%v), got %f %f (%v)", index, test.long0, test.fe, test.err, long0, fe, err)
+		}
+
+		west, east, err := BMNMeridianLongitudes(test.meridian)
+		if err != test.err || math.Abs(west-test.west) > 1e-9 || math.Abs(east-test.east) > 1e-9 {
+			t.Errorf("BMNMeridianLongitudes [%d]: expected %f %f (%v), got %f %f (%v)", index, test.west, test.east, test.err, west, east, err)
+		}
+		if err == nil && (BMNMeridianFor(west) != test.meridian || BMNMeridianFor(long0) != test.meridian) {
+			t.Errorf("BMNMeridianLongitudes [%d]: expected the western boundary %f to lie in %s", index, west, test.meridian)
+		}
+	}
+}
+
+// ## BMNMeridianPolygon
+func TestBMNMeridianPolygon(t *testing.T) {
+	points, err := BMNMeridianPolygon(BMNM31, 8)
+	if err != nil || len(points) != 33 {
+		t.Fatalf("BMNMeridianPolygon: expected 33 coordinates, got %d (%v)", len(points), err)
+	}
+	if !points[0].Equal(points[32], 0.001) {
+		t.Errorf("BMNMeridianPolygon: expected a closed ring, got %s and %s", points[0], points[32])
+	}
+
+	// the boundary is shifted to WGS84, the southern edge follows the parallel of Extent eastwards
+	for index, gc := range points {
+		if gc.El != cartconvert.WGS84Ellipsoid {
+			t.Errorf("BMNMeridianPolygon [%d]: expected a WGS84 coordinate, got %v", index, gc.El)
+		}
+		if index > 0 && index <= 8 && (math.Abs(gc.Latitude-Extent.MinLat) > 0.01 || gc.Longitude <= points[index-1].Longitude) {
+			t.Errorf("BMNMeridianPolygon [%d]: expected the southern edge eastwards of %s, got %s", index, points[index-1], gc)
+		}
+	}
+	if math.Abs(points[8].Longitude-(14.0+50.0/60.0)) > 0.01 || math.Abs(points[8].Latitude-Extent.MinLat) > 0.01 {
+		t.Errorf("BMNMeridianPolygon: expected the south-eastern corner of M31, got %s", points[8])
+	}
+
+	if _, err = BMNMeridianPolygon(BMNZoneDet, 8); err != cartconvert.ErrRange {
+		t.Errorf("BMNMeridianPolygon: expected ErrRange for an unset meridian stripe, got %v", err)
+	}
+	if _, err = BMNMeridianPolygon(BMNM28, 0); err != cartconvert.ErrRange {
+		t.Errorf("BMNMeridianPolygon: expected ErrRange for no segments, got %v", err)
+	}
+}
+
+func TestWGS84LatLongToBMNStripes(t *testing.T) {
+	// every longitude of Austria selects a stripe
+	for long := Extent.MinLong; long <= Extent.MaxLong; long += 0.01 {
+		gc := &cartconvert.PolarCoord{Latitude: 47.5, Longitude: long}
+		if bc, err := WGS84LatLongToBMN(gc, BMNZoneDet); err != nil {
+			t.Fatalf("WGS84LatLongToBMN: expected a meridian stripe for longitude %f, got %s (%v)", long, bc, err)
+		}
+	}
+
+	// the boundary of M31 and M34, on either side in MGI
+	for _, test := range []bMNMeridianForTest{{14.8, BMNM31}, {14.9, BMNM34}} {
+		bc, err := WGS84LatLongToBMN(&cartconvert.PolarCoord{Latitude: 47.5, Longitude: test.long}, BMNZoneDet)
+		if err != nil || bc.Meridian != test.meridian {
+			t.Errorf("WGS84LatLongToBMN: expected %s for longitude %f, got %s (%v)", test.meridian, test.long, bc, err)
+		}
+	}
+}
+
+// ## WGS84LatLongToBMNHelmert
+func TestWGS84LatLongToBMNHelmert(t *testing.T) {
+	for index, test := range wGS84LatLongToBMNTests {
+		out, err := WGS84LatLongToBMNHelmert(test.in.gc, test.in.meridian, nil)
+		if err != nil || !bmnequal(test.out, out) {
+			t.Errorf("WGS84LatLongToBMNHelmert [%d]: expected %s, got %s (%v)", index, test.out, out, err)
+		}
+	}
+
+	// a parameter set derived from the default one, shifted by 10 m in dx, moves the point by the horizontal
+	// component of the shift, which is about 7.6 m in Austria
+	dx, dy, dz, dM, drx, dry, drz := cartconvert.HelmertWGS84ToMGI.Parameters()
+	shifted := &cartconvert.HelmertParameterSet{Name: "MGI_shifted", From: cartconvert.DatumWGS84, To: cartconvert.DatumMGI,
+		HelmertTransform: cartconvert.NewHelmertTransformer(dx+10, dy, dz, dM, drx, dry, drz, "WGS84toMGI_shifted")}
+	def, _ := WGS84LatLongToBMNHelmert(wGS84LatLongToBMNTests[0].in.gc, BMNM34, nil)
+	out, err := WGS84LatLongToBMNHelmert(wGS84LatLongToBMNTests[0].in.gc, BMNM34, shifted)
+	if d := math.Hypot(out.Right-def.Right, out.Height-def.Height); err != nil || d < 7 || d > 8 {
+		t.Errorf("WGS84LatLongToBMNHelmert: expected a shift of about 7.6 m by the derived parameter set, got %f (%v)", d, err)
+	}
+	if def.Accuracy != 1.5 || out.Accuracy != 0 {
+		t.Errorf("WGS84LatLongToBMNHelmert: expected the accuracy 1.5 of the default parameter set and none of the derived one, got %g and %g", def.Accuracy, out.Accuracy)
+	}
+	back, err := BMNToWGS84LatLongHelmert(out, shifted)
+	if err != nil || math.Abs(back.Latitude-wGS84LatLongToBMNTests[0].in.gc.Latitude) > 1e-7 || math.Abs(back.Longitude-wGS84LatLongToBMNTests[0].in.gc.Longitude) > 1e-7 {
+		t.Errorf("BMNToWGS84LatLongHelmert: expected the round trip by the derived parameter set, got %v (%v)", back, err)
+	}
+
+	osgb, _ := cartconvert.DefaultHelmertParameterSet(cartconvert.DatumWGS84, cartconvert.DatumOSGB36)
+	if _, err := WGS84LatLongToBMNHelmert(wGS84LatLongToBMNTests[0].in.gc, BMNM34, osgb); err != cartconvert.ErrRange {
+		t.Errorf("WGS84LatLongToBMNHelmert: expected ErrRange for a parameter set of another datum, got %v", err)
+	}
+
+	if _, err := BMNToWGS84LatLongHelmert(NewBMNCoord(BMNM34, 592269, 272290, 0), osgb); err != cartconvert.ErrRange {
+		t.Errorf("BMNToWGS84LatLongHelmert: expected ErrRange for a parameter set of another datum, got %v", err)
+	}
+}
+
+// ## WGS84LatLongToBMNFlat
+func TestWGS84LatLongToBMNFlat(t *testing.T) {
+	var latlon []float64
+	for _, test := range wGS84LatLongToBMNTests {
+		latlon = append(latlon, test.in.gc.Latitude, test.in.gc.Longitude)
+	}
+
+	out, errs := WGS84LatLongToBMNFlat(latlon, BMNM34)
+	if errs != nil {
+		t.Fatalf("WGS84LatLongToBMNFlat: expected no errors, got %v", errs)
+	}
+
+	for index, test := range wGS84LatLongToBMNTests {
+		bc := NewBMNCoord(BMNM34, out[2*index], out[2*index+1], 0)
+		if !bmnequal(test.out, bc) {
+			t.Errorf("WGS84LatLongToBMNFlat [%d]: expected %s, got %s", index, test.out, bc)
+		}
+	}
+
+	// the second point lies outside of every meridian stripe
+	out, errs = WGS84LatLongToBMNFlat([]float64{48.507001, 15.698748, 48.2, 25.0}, BMNZoneDet)
+	if len(errs) != 2 || errs[0] != nil || errs[1] != cartconvert.ErrRange {
+		t.Errorf("WGS84LatLongToBMNFlat: expected ErrRange for the second point, got %v", errs)
+	}
+	if !math.IsNaN(out[2]) || !math.IsNaN(out[3]) {
+		t.Errorf("WGS84LatLongToBMNFlat: expected NaN for the second point, got %f %f", out[2], out[3])
+	}
+
+	if _, errs = WGS84LatLongToBMNFlat([]float64{48.507001}, BMNM34); len(errs) != 1 || errs[0] != cartconvert.ErrSyntax {
+		t.Errorf("WGS84LatLongToBMNFlat: expected ErrSyntax for an odd number of values, got %v", errs)
+	}
+}
+
+const benchmarkPoints = 1000
+
+func benchmarkLatLon() []float64 {
+	latlon := make([]float64, 2*benchmarkPoints)
+	for i := 0; i < len(latlon); i += 2 {
+		latlon[i], latlon[i+1] = 47.0+float64(i)/(2*benchmarkPoints), 15.0+float64(i)/(2*benchmarkPoints)
+	}
+	return latlon
+}
+
+// ## NaN and infinity
+func TestNonFinite(t *testing.T) {
+	for _, value := range []float64{math.NaN(), math.Inf(1)} {
+		if _, err := WGS84LatLongToBMN(&cartconvert.PolarCoord{Latitude: value, Longitude: 14}, BMNM31); err != cartconvert.ErrNotFinite {
+			t.Errorf("WGS84LatLongToBMN (%v): expected ErrNotFinite, got %v", value, err)
+		}
+		if _, err := BMNToWGS84LatLong(NewBMNCoord(BMNM31, value, 270000, 0)); err != cartconvert.ErrNotFinite {
+			t.Errorf("BMNToWGS84LatLong (%v): expected ErrNotFinite, got %v", value, err)
+		}
+		if _, _, err := OffsetGrid(NewBMNCoord(BMNM31, 450000, 270000, 0), 0, value); err != cartconvert.ErrNotFinite {
+			t.Errorf("OffsetGrid (%v): expected ErrNotFinite, got %v", value, err)
+		}
+		eastingNorthing, errs := WGS84LatLongToBMNFlat([]float64{47.5, value}, BMNM31)
+		if len(errs) != 1 || errs[0] != cartconvert.ErrNotFinite || !math.IsNaN(eastingNorthing[0]) {
+			t.Errorf("WGS84LatLongToBMNFlat (%v): expected ErrNotFinite, got %v", value, errs)
+		}
+	}
+}
+
+func BenchmarkWGS84LatLongToBMNFlat(b *testing.B) {
+	latlon := benchmarkLatLon()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		WGS84LatLongToBMNFlat(latlon, BMNM34)
+	}
+}
+
+func BenchmarkWGS84LatLongToBMN(b *testing.B) {
+	latlon := benchmarkLatLon()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		out := make([]*BMNCoord, 0, benchmarkPoints)
+		for j := 0; j < len(latlon); j += 2 {
+			bc, _ := WGS84LatLongToBMN(&cartconvert.PolarCoord{Latitude: latlon[j], Longitude: latlon[j+1]}, BMNM34)
+			out = append(out, bc)
+		}
+	}
+}
+
+// ## OffsetGrid
+type offsetGridTest struct {
+	in     *BMNCoord
+	dE, dN float64
+	out    *BMNCoord
+	err    error
+}
+
+var offsetGridTests = []offsetGridTest{
+	{NewBMNCoord(BMNM34, 703168, 374510, 0), 1000, -500, NewBMNCoord(BMNM34, 704168, 374010, 0), nil},
+	{NewBMNCoord(BMNM34, 703168, 374510, 0), -250000, 0, nil, cartconvert.ErrRange},
+	{NewBMNCoord(BMNZoneDet, 703168, 374510, 0), 1000, 0, nil, cartconvert.ErrRange},
+}
+
+func TestOffsetGrid(t *testing.T) {
+	for index, test := range offsetGridTests {
+		out, latlong, err := OffsetGrid(test.in, test.dE, test.dN)
+
+		if err != test.err {
+			t.Errorf("OffsetGrid [%d]: expected error %v, got %v", index, test.err, err)
+			continue
+		}
+
+		if test.out == nil {
+			continue
+		}
+
+		if !bmnequal(test.out, out) {
+			t.Errorf("OffsetGrid [%d]: expected %s, got %s", index, test.out, out)
+		}
+
+		expected, _ := BMNToWGS84LatLong(test.out)
+		if !latlongequal(expected, latlong) {
+			t.Errorf("OffsetGrid [%d]: expected %s, got %s", index, expected, latlong)
+		}
+	}
+}
+
+// ## CellKey
+type cellKeyTest struct {
+	in         *BMNCoord
+	resolution float64
+	out        *BMNCoord
+	err        error
+}
+
+var cellKeyTests = []cellKeyTest{
+	{NewBMNCoord(BMNM34, 703168.4, 374510.9, 0), 1, NewBMNCoord(BMNM34, 703168, 374510, 0), nil},
+	{NewBMNCoord(BMNM34, 703168.4, 374510.9, 0), 100, NewBMNCoord(BMNM34, 703100, 374500, 0), nil},
+	{NewBMNCoord(BMNM28, 150000, 250000, 0), 0.5, NewBMNCoord(BMNM28, 150000, 250000, 0), nil},
+	{NewBMNCoord(BMNM31, 450000, 0, 0), 1000, NewBMNCoord(BMNM31, 450000, 0, 0), nil},
+	{NewBMNCoord(BMNM31, 450000, 270000, 0), 0, nil, cartconvert.ErrRange},
+	{NewBMNCoord(BMNM31, -1, 270000, 0), 1, nil, cartconvert.ErrRange},
+	{NewBMNCoord(BMNM31, 450000, 270000, 0), 0.0001, nil, cartconvert.ErrRange},
+	{NewBMNCoord(BMNZoneDet, 450000, 270000, 0), 1, nil, cartconvert.ErrRange},
+}
+
+func TestCellKey(t *testing.T) {
+	keys := make(map[uint64]*BMNCoord)
+
+	for index, test := range cellKeyTests {
+		key, err := test.in.CellKey(test.resolution)
+
+		if err != test.err {
+			t.Errorf("CellKey [%d]: expected error %v, got %v", index, test.err, err)
+			continue
+		}
+
+		if test.out == nil {
+			continue
+		}
+
+		if other, ok := keys[key]; ok {
+			t.Errorf("CellKey [%d]: key %d of %s collides with %s", index, key, test.in, other)
+		}
+		keys[key] = test.in
+
+		out, err := CellKeyToBMN(key, test.resolution)
+		if err != nil || !bmnequal(test.out, out) {
+			t.Errorf("CellKeyToBMN [%d]: expected %s, got %s (%v)", index, test.out, out, err)
+		}
+	}
+
+	// adjacent cells of all meridian stripes yield distinct keys
+	for _, meridian := range []BMNMeridian{BMNM28, BMNM31, BMNM34} {
+		for _, offset := range [][2]float64{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+			key, err := NewBMNCoord(meridian, 450000+offset[0], 270000+offset[1], 0).CellKey(1)
+			if err != nil {
+				t.Fatalf("CellKey: %s", err)
+			}
+			if _, ok := keys[key]; ok {
+				t.Errorf("CellKey: key %d of %s %v collides", key, meridian, offset)
+			}
+			keys[key] = nil
+		}
+	}
+
+	if _, err := CellKeyToBMN(12345, 1); err != cartconvert.ErrRange {
+		t.Errorf("CellKeyToBMN: expected ErrRange for a key without meridian stripe, got %v", err)
+	}
+}
+
+type validTest struct {
+	in    *BMNCoord
+	valid bool
+}
+
+var validTests = []validTest{
+	{NewBMNCoord(BMNM34, 592269, 272290, 0), true},
+	{NewBMNCoord(BMNM28, 150000, 250000, 0), true},
+	// east of Austria
+	{NewBMNCoord(BMNM34, 1200000, 272290, 0), false},
+	// north of Austria
+	{NewBMNCoord(BMNM31, 450000, 600000, 0), false},
+}
+
+func TestValid(t *testing.T) {
+	for index, test := range validTests {
+		err := test.in.Valid()
+		if _, ok := err.(cartconvert.ExtentError); (err == nil) != test.valid || (err != nil && !ok) {
+			t.Errorf("Valid [%d]: expected valid %t for %s, got %v", index, test.valid, test.in, err)
+		}
+	}
+
+	if err := NewBMNCoord(BMNZoneDet, 592269, 272290, 0).Valid(); err != cartconvert.ErrRange {
+		t.Errorf("Valid: expected ErrRange without meridian stripe, got %v", err)
+	}
+}
+
+// ## RoundTrip
+// The positional error in meters of a latitude / longitude coordinate converted into a BMN coordinate and back
+type roundTripTest struct {
+	gc        *cartconvert.PolarCoord
+	threshold float64
+}
+
+// The helmert transformation is inverted exactly, the round trip is limited by the transverse mercator projection
+var roundTripTests = []roundTripTest{
+	{&cartconvert.PolarCoord{Latitude: 47.570299, Longitude: 14.236188, El: cartconvert.WGS84Ellipsoid}, 0.01},
+	{&cartconvert.PolarCoord{Latitude: 48.2082, Longitude: 16.3738, El: cartconvert.WGS84Ellipsoid}, 0.01},
+	{&cartconvert.PolarCoord{Latitude: 47.2692, Longitude: 9.6, El: cartconvert.WGS84Ellipsoid}, 0.01}, // Vorarlberg
+	{&cartconvert.PolarCoord{Latitude: 46.62, Longitude: 13.85, El: cartconvert.WGS84Ellipsoid}, 0.01},
+}
+
+func TestRoundTrip(t *testing.T) {
+	for index, test := range roundTripTests {
+		bmncoord, err := WGS84LatLongToBMN(test.gc, BMNZoneDet)
+		if err != nil {
+			t.Fatalf("WGS84LatLongToBMN [%d]: %s", index, err)
+		}
+		out, err := BMNToWGS84LatLong(bmncoord)
+		if err != nil {
+			t.Fatalf("BMNToWGS84LatLong [%d]: %s", index, err)
+		}
+		if d := cartconvert.Haversine(test.gc, out, 0); d > test.threshold {
+			t.Errorf("RoundTrip [%d]: expected a positional error below %g m, got %.3f m", index, test.threshold, d)
+		}
+	}
+}
+
+// ## Reference ellipsoid of the latitude / longitude coordinate
+func TestWGS84LatLongToBMNEllipsoid(t *testing.T) {
+	latlong := func(el *cartconvert.Ellipsoid) *cartconvert.PolarCoord {
+		return &cartconvert.PolarCoord{Latitude: 47.570299, Longitude: 14.236188, El: el}
+	}
+
+	wgs84, err := WGS84LatLongToBMN(latlong(cartconvert.WGS84Ellipsoid), BMNM31)
+	if err != nil {
+		t.Fatalf("WGS84LatLongToBMN: %s", err)
+	}
+
+	// a coordinate without reference ellipsoid is taken on WGS84
+	out, err := WGS84LatLongToBMN(latlong(nil), BMNM31)
+	if err != nil || out.Right != wgs84.Right || out.Height != wgs84.Height {
+		t.Errorf("WGS84LatLongToBMN: expected %s without reference ellipsoid, got %v (%v)", wgs84, out, err)
+	}
+
+	// GRS80 differs from WGS84 by a tenth of a millimeter of the semi-minor axis only, yet is not replaced
+	gc := latlong(cartconvert.GRS80Ellipsoid)
+	out, err = WGS84LatLongToBMN(gc, BMNM31)
+	if gc.El != cartconvert.GRS80Ellipsoid {
+		t.Errorf("WGS84LatLongToBMN: expected the GRS80 ellipsoid of the input to be kept, got %s", gc.El.CommonName)
+	}
+	if err != nil || (out.Right == wgs84.Right && out.Height == wgs84.Height) ||
+		math.Abs(out.Right-wgs84.Right) > 0.001 || math.Abs(out.Height-wgs84.Height) > 0.001 {
+		t.Errorf("WGS84LatLongToBMN: expected GRS80 to differ from %s by less than a millimeter, got %v (%v)", wgs84, out, err)
+	}
+
+	// on the Bessel ellipsoid the same latitude and longitude lie hundreds of meters apart
+	out, err = WGS84LatLongToBMN(latlong(cartconvert.Bessel1841Ellipsoid), BMNM31)
+	if err != nil || math.Hypot(out.Right-wgs84.Right, out.Height-wgs84.Height) < 1 {
+		t.Errorf("WGS84LatLongToBMN: expected Bessel to differ from %s, got %v (%v)", wgs84, out, err)
+	}
+}
+
+// ## EPSG
+func TestEPSG(t *testing.T) {
+	for _, meridian := range []BMNMeridian{BMNM28, BMNM31, BMNM34} {
+		gc := &cartconvert.PolarCoord{Latitude: 47.57, Longitude: 14.0075, El: cartconvert.WGS84Ellipsoid}
+		bmnval, err := WGS84LatLongToBMN(gc, meridian)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the registered coordinate reference system projects on MGI, which differs from WGS84 by less than 0.01°
+		crs, err := cartconvert.ByEPSG(bmnval.EPSG())
+		if err != nil {
+			t.Fatalf("ByEPSG: expected the CRS of %s, got %v", meridian, err)
+		}
+		out, err := crs.InverseProject(&cartconvert.GeoPoint{X: bmnval.Right, Y: bmnval.Height})
+		if err != nil || math.Abs(out.Latitude-47.57) > 0.01 || math.Abs(out.Longitude-14.0075) > 0.01 {
+			t.Errorf("CRS.InverseProject: expected %s of %s near 47.57 14.0075, got %v (%v)", crs.Name, bmnval, out, err)
+		}
+	}
+
+	if out := (&BMNCoord{}).EPSG(); out != 0 {
+		t.Errorf("EPSG: expected 0 without a meridian stripe, got %d", out)
+	}
+}
+
+// ## WKT
+func TestWKT(t *testing.T) {
+	crs, err := cartconvert.ByEPSG(EPSGM31)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `PROJCS["MGI / Austria GK M31",GEOGCS["MGI",DATUM["MGI",SPHEROID["Bessel1841MGI",6377397.155,299.152843417]],` +
+		`PRIMEM["Greenwich",0],UNIT["degree",0.0174532925199433],AUTHORITY["EPSG","4312"]],PROJECTION["Transverse_Mercator"],` +
+		`PARAMETER["latitude_of_origin",0],PARAMETER["central_meridian",13.3333333333],PARAMETER["scale_factor",1],` +
+		`PARAMETER["false_easting",450000],PARAMETER["false_northing",-5000000],UNIT["metre",1],AUTHORITY["EPSG","31258"]]`
+	if out := crs.WKT(); out != expected {
+		t.Errorf("CRS.WKT: expected %s, got %s", expected, out)
+	}
+}
+
+// ## Proj4
+func TestProj4(t *testing.T) {
+	crs, err := cartconvert.ByEPSG(EPSGM31)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "+proj=tmerc +lat_0=0 +lon_0=13.3333333333 +k=1 +x_0=450000 +y_0=-5000000 +a=6377397.155 +b=6356078.965" +
+		" +towgs84=577.326,90.129,463.919,-5.1366,-1.4742,-5.297,2.4232 +units=m +no_defs"
+	if out := crs.Proj4(); out != expected {
+		t.Errorf("CRS.Proj4: expected %s, got %s", expected, out)
+	}
+}
+
+// ## Snap
+func TestSnap(t *testing.T) {
+	bc := &BMNCoord{Meridian: BMNM34, Right: 592269.4, Height: 272250, RelHeight: 540, El: cartconvert.Bessel1841MGIEllipsoid}
+	out := bc.Snap(100)
+	if out.Right != 592300 || out.Height != 272200 || out.Meridian != BMNM34 || out.RelHeight != 540 {
+		t.Errorf("BMNCoord.Snap: expected M34 592300 272200 at a height of 540, got %s at %f", out, out.RelHeight)
+	}
+	if bc.Right != 592269.4 {
+		t.Errorf("BMNCoord.Snap: expected the coordinate unchanged, got %s", bc)
+	}
+}
@@ -8,16 +8,35 @@ package cartconvert
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
 	"errors"
 	"fmt"
+	"io"
 	"math"
+	"math/rand"
+	"reflect"
+	"runtime"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Cartography Errors
 var ErrRange = errors.New("value out of range")
 var ErrSyntax = errors.New("invalid syntax")
+var ErrNotFound = errors.New("not found")
+var ErrDuplicate = errors.New("already registered")
+var ErrUnsupported = errors.New("unsupported operation")
+var ErrAmbiguous = errors.New("ambiguous value")
+var ErrConvergence = errors.New("iteration does not converge")
+
+// ErrNotFinite is returned for NaN or infinite coordinates, see CheckFinite. It wraps ErrRange.
+var ErrNotFinite = fmt.Errorf("%w: not a finite number", ErrRange)
 
 // A CartographyError is yielded when a literal can not be parsed as a bearing specifier.
 // In this case the following values may be set and carry the meaning:
@@ -32,25 +51,142 @@ func (ce CartographyError) Error() string {
 	return fmt.Sprintf("unable to parse fragment \"%s\". Partial value: %f. The additional error was: %s", ce.Coord, ce.Val, ce.Err.Error())
 }
 
+// Unwrap returns the inherited error, so errors.Is finds eg. ErrSyntax
+func (ce CartographyError) Unwrap() error {
+	return ce.Err
+}
+
 // Set of common ellipsoidal models regularly found in cartography
 var (
-	Bessel1841MGIEllipsoid = NewEllipsoid(6377397.155, 6356078.965, "Bessel1841MGI")
-	Bessel1841Ellipsoid    = NewEllipsoid(6377397.155, 6356078.962822, "Bessel1841")
-	GRS80Ellipsoid         = NewEllipsoid(6378137, 6356752.31414, "GRS80")
-	WGS84Ellipsoid         = NewEllipsoid(6378137, 6356752.31425, "WGS84")
-	Airy1830Ellipsoid      = NewEllipsoid(6377563.396, 6356256.909, "Airy1830")
-	DefaultEllipsoid       = WGS84Ellipsoid
+	Bessel1841MGIEllipsoid     = NewEllipsoid(6377397.155, 6356078.965, "Bessel1841MGI")
+	Bessel1841Ellipsoid        = NewEllipsoid(6377397.155, 6356078.962822, "Bessel1841")
+	GRS80Ellipsoid             = NewEllipsoid(6378137, 6356752.31414, "GRS80")
+	WGS84Ellipsoid             = NewEllipsoid(6378137, 6356752.31425, "WGS84")
+	Airy1830Ellipsoid          = NewEllipsoid(6377563.396, 6356256.909, "Airy1830")
+	Airy1830ModEllipsoid       = NewEllipsoid(6377340.189, 6356034.447938, "Airy1830Modified")
+	Clarke1866Ellipsoid        = NewEllipsoid(6378206.4, 6356583.8, "Clarke1866")
+	International1924Ellipsoid = NewEllipsoid(6378388, 6356911.946, "International1924")
+	DefaultEllipsoid           = WGS84Ellipsoid
 )
 
 type Ellipsoid struct {
 	a, b       float64
 	CommonName string
+	epsg       int
+
+	// the parameters derived from the axes, computed once on first use by derived
+	derivedOnce sync.Once
+	params      ellipsoidParameters
+}
+
+// The parameters derived from the axes of an ellipsoid, used by the formulas of the conversions. As the axes of
+// an ellipsoid never change, they are computed once instead of on every conversion.
+type ellipsoidParameters struct {
+	f   float64 // flattening
+	esq float64 // square of the first eccentricity
+	e   float64 // first eccentricity
+	ep2 float64 // square of the second eccentricity
+	n   float64 // third flattening
+
+	// the radius of the rectifying sphere and the coefficients h1 to h4 of the series of Krüger of the direct and
+	// inverse transverse mercator projection, see DirectTransverseMercator. tmSO holds the coefficients of the
+	// direct series as evaluated for the meridian arc of the latitude of origin by the inverse projection.
+	tmB             float64
+	tmH, tmSO, tmHi [4]float64
+}
+
+// Returns the parameters derived from the axes of el, computing them on the first call. Safe for concurrent use.
+func (el *Ellipsoid) derived() *ellipsoidParameters {
+	el.derivedOnce.Do(func() {
+		p := &el.params
+		p.f = 1 - el.b/el.a
+		p.esq = (el.a*el.a - el.b*el.b) / (el.a * el.a)
+		p.e = math.Sqrt(2.0*p.f - p.f*p.f)
+		p.ep2 = (el.a*el.a - el.b*el.b) / (el.b * el.b)
+
+		n := p.f / (2.0 - p.f)
+		p.n = n
+		p.tmB = (el.a / (1 + n)) * (1 + n*n/4.0 + n*n*n*n/64.0)
+		p.tmH = [4]float64{
+			n/2.0 - (2.0/3.0)*(n*n) + (5.0/16.0)*(n*n*n) + (41.0/180.0)*(n*n*n*n),
+			(13.0/48.0)*(n*n) - (3.0/5.0)*(n*n*n) + (557.0/1440.0)*(n*n*n*n),
+			(61.0/240.0)*(n*n*n) - (103.0/140.0)*(n*n*n*n),
+			(49561.0 / 161280.0) * (n * n * n * n),
+		}
+		p.tmSO = [4]float64{
+			n/2.0 - (2.0/3.0)*n*n + (5.0/16.0)*n*n*n + (41.0/180.0)*n*n*n*n,
+			(13.0/48.0)*n*n - (3.0/5.0)*n*n*n + (557.0/1440.0)*n*n*n*n,
+			(61.0/240.0)*n*n*n - (103.0/140.0)*n*n*n*n,
+			(49561.0 / 161280.0) * n * n * n * n,
+		}
+		p.tmHi = [4]float64{
+			n/2.0 - (2.0/3.0)*n*n + (37.0/96.0)*n*n*n - (1.0/360.0)*n*n*n*n,
+			(1.0/48.0)*n*n + (1.0/15.0)*n*n*n - (437.0/1440.0)*n*n*n*n,
+			(17.0/480.0)*n*n*n - (37.0/840.0)*n*n*n*n,
+			(4397.0 / 161280.0) * n * n * n * n,
+		}
+	})
+	return &el.params
+}
+
+// Returns the flattening (a-b)/a of the ellipsoid
+func (el *Ellipsoid) Flattening() float64 {
+	return el.derived().f
+}
+
+// Returns the first eccentricity of the ellipsoid
+func (el *Ellipsoid) Eccentricity() float64 {
+	return el.derived().e
+}
+
+// Returns the square of the first eccentricity (a²-b²)/a² of the ellipsoid
+func (el *Ellipsoid) EccentricitySquared() float64 {
+	return el.derived().esq
+}
+
+// Returns the square of the second eccentricity (a²-b²)/b² of the ellipsoid
+func (el *Ellipsoid) SecondEccentricitySquared() float64 {
+	return el.derived().ep2
+}
+
+// Returns the third flattening (a-b)/(a+b) of the ellipsoid
+func (el *Ellipsoid) ThirdFlattening() float64 {
+	return el.derived().n
+}
+
+// Returns the EPSG code of the ellipsoid, 0 if it has none
+func (el *Ellipsoid) EPSG() int {
+	return el.epsg
+}
+
+// Implements json.Marshaler, writing the ellipsoid as its common name, as the coordinates referring to it do not
+// need its axes
+func (el *Ellipsoid) MarshalJSON() ([]byte, error) {
+	return json.Marshal(el.CommonName)
+}
+
+// Implements xml.Marshaler, writing the ellipsoid as its common name like MarshalJSON
+func (el *Ellipsoid) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(el.CommonName, start)
 }
 
 // Holds latitude, longitude and ellipsoidal height, relative to El, the reference ellipsoid
 type PolarCoord struct {
-	Latitude, Longitude, Height float64
-	El                          *Ellipsoid
+	Latitude  float64    `json:"latitude" xml:"latitude"`
+	Longitude float64    `json:"longitude" xml:"longitude"`
+	Height    float64    `json:"height" xml:"height"`
+	El        *Ellipsoid `json:"ellipsoid,omitempty" xml:"ellipsoid,omitempty"`
+}
+
+// A coordinate of any of the supported coordinate systems. System returns the short name of the system, like "utm" or
+// "bmn", under which package convert registers it. ToWGS84 converts the coordinate to a WGS84 latitude / longitude
+// with the default parameters of its system. EPSG returns the EPSG code of the coordinate reference system of the
+// coordinate, 0 if it has none. The concrete types keep their specific methods, a type switch recovers them.
+type Coordinate interface {
+	String() string
+	ToWGS84() (*PolarCoord, error)
+	System() string
+	EPSG() int
 }
 
 // specifier for the string representation of a polar coordinate
@@ -72,17 +208,74 @@ func (spec LatLongFormat) String() string {
 	return "#unknown"
 }
 
-func f64toa(val float64, prec int) string {
+// Formats val in fixed-point notation with prec decimal places, rounded to nearest. A negative prec uses the smallest
+// number of decimal places necessary to represent val exactly. Values which round to zero are formatted without a
+// sign, so -0.001 formats as 0.00 with two decimal places.
+func FormatFixed(val float64, prec int) string {
+
+	sval := strconv.FormatFloat(val, 'f', prec, 64)
+	if sval[0] == '-' && strings.Trim(sval[1:], "0.") == "" {
+		return sval[1:]
+	}
+	return sval
+}
+
+// Formats val like FormatFixed, but with the trailing zeros of the decimal places removed, down to an integer.
+// Zeros of the integer part are kept, so 272290 formats as 272290 with any number of decimal places.
+func FormatTrimmed(val float64, prec int) string {
+
+	sval := FormatFixed(val, prec)
+	if strings.IndexByte(sval, '.') < 0 {
+		return sval
+	}
+	return strings.TrimSuffix(strings.TrimRight(sval, "0"), ".")
+}
+
+// The character separating the integer part of a number from its decimal places in literals formatted for humans.
+// Machine readable formats like JSON and XML always use the DecimalPoint. The zero value is taken as DecimalPoint.
+type DecimalMark byte
+
+const (
+	DecimalPoint DecimalMark = '.'
+	DecimalComma DecimalMark = ','
+)
+
+// Parses a decimal mark given as "." or "point", "," or "comma", case insensitive. An empty spec yields the
+// DecimalPoint. Returns ErrSyntax for any other spec.
+func ParseDecimalMark(spec string) (DecimalMark, error) {
+	switch strings.ToLower(strings.TrimSpace(spec)) {
+	case "", ".", "point":
+		return DecimalPoint, nil
+	case ",", "comma":
+		return DecimalComma, nil
+	}
+	return DecimalPoint, ErrSyntax
+}
 
-	sval := fmt.Sprintf("%.*f", prec, val)
-	n := len(sval)
-	for n > 0 && sval[n-1] == '0' {
-		n--
+// Replaces the decimal points of the numbers in literal by mark. Only a point between two digits is taken as
+// decimal point, so "33T 594807.5 5339087.5" becomes "33T 594807,5 5339087,5" with the DecimalComma, while
+// other points are left unchanged.
+func (mark DecimalMark) Localize(literal string) string {
+	if mark == 0 || mark == DecimalPoint || strings.IndexByte(literal, '.') < 0 {
+		return literal
 	}
-	if n > 0 && sval[n-1] == '.' {
-		n--
+	b := []byte(literal)
+	for i := 1; i < len(b)-1; i++ {
+		if b[i] == '.' && '0' <= b[i-1] && b[i-1] <= '9' && '0' <= b[i+1] && b[i+1] <= '9' {
+			b[i] = byte(mark)
+		}
 	}
-	return sval[:n]
+	return string(b)
+}
+
+// Formats val like FormatFixed, with mark as decimal mark
+func (mark DecimalMark) FormatFixed(val float64, prec int) string {
+	return mark.Localize(FormatFixed(val, prec))
+}
+
+// Formats val like FormatTrimmed, with mark as decimal mark
+func (mark DecimalMark) FormatTrimmed(val float64, prec int) string {
+	return mark.Localize(FormatTrimmed(val, prec))
 }
 
 func LatLongToString(pc *PolarCoord, format LatLongFormat) (string, string) {
@@ -92,22 +285,13 @@ func LatLongToString(pc *PolarCoord, format LatLongFormat) (string, string) {
 
 	switch format {
 	case LLFdeg:
-		latitude = f64toa(pc.Latitude, 6)
-		longitude = f64toa(pc.Longitude, 6)
+		latitude = FormatTrimmed(pc.Latitude, 6)
+		longitude = FormatTrimmed(pc.Longitude, 6)
 
 	case LLFdms:
-		lat, latrem = math.Modf(pc.Latitude)
-
-		if lat < 0 {
-			lat *= -1
-			latrem *= -1
-		}
-
-		long, longrem = math.Modf(pc.Longitude)
-		if long < 0 {
-			long *= -1
-			longrem *= -1
-		}
+		// the main direction carries the sign, also of a bearing of less than one degree
+		lat, latrem = math.Modf(math.Abs(pc.Latitude))
+		long, longrem = math.Modf(math.Abs(pc.Longitude))
 
 		latmin, latrem = math.Modf(latrem / 100 * 6000)
 		longmin, longrem = math.Modf(longrem / 100 * 6000)
@@ -126,7 +310,7 @@ func LatLongToString(pc *PolarCoord, format LatLongFormat) (string, string) {
 			latitude += fmt.Sprintf("%d'", int(latmin))
 		}
 		if latsec != 0.0 {
-			latitude += fmt.Sprintf("%s''", f64toa(latsec, 2))
+			latitude += fmt.Sprintf("%s''", FormatTrimmed(latsec, 2))
 		}
 
 		if pc.Longitude < 0 {
@@ -139,942 +323,4445 @@ func LatLongToString(pc *PolarCoord, format LatLongFormat) (string, string) {
 			longitude += fmt.Sprintf("%d'", int(longmin))
 		}
 		if longsec != 0.0 {
-			longitude += fmt.Sprintf("%s''", f64toa(longsec, 2))
+			longitude += fmt.Sprintf("%s''", FormatTrimmed(longsec, 2))
 		}
 	}
 	return latitude, longitude
 }
 
+// Formats latitude and longitude of gc in degrees, minutes and seconds with prec decimal places of the seconds,
+// followed by the main direction, like 47°30′15.2″N and 9°05′03.0″E. Minutes and seconds are padded to two digits.
+// The bearing is rounded as a whole, so seconds rounding up to 60 carry over into the minutes and degrees instead of
+// being written as 60″. A negative prec is taken as 0.
+func FormatDMS(gc *PolarCoord, prec int) (lat, lon string) {
+	return formatDMS(gc.Latitude, prec, 'N', 'S'), formatDMS(gc.Longitude, prec, 'E', 'W')
+}
+
+func formatDMS(bearing float64, prec int, positive, negative rune) string {
+
+	if prec < 0 {
+		prec = 0
+	}
+
+	direction := positive
+	if bearing < 0 {
+		direction = negative
+		bearing = -bearing
+	}
+
+	// the bearing in units of the last decimal place of the seconds
+	unit := math.Pow10(prec)
+	units := math.Round(bearing * 3600 * unit)
+	if units == 0 {
+		direction = positive
+	}
+
+	deg := math.Floor(units / (3600 * unit))
+	units -= deg * 3600 * unit
+	min := math.Floor(units / (60 * unit))
+	sec := (units - min*60*unit) / unit
+
+	width := 2
+	if prec > 0 {
+		width += prec + 1
+	}
+	return fmt.Sprintf("%d°%02d′%0*.*f″%c", int(deg), int(min), width, prec, sec, direction)
+}
+
+// Formats the bearings of pc like LatLongToString, in the axis order order: the latitude first for AxisLatLong,
+// the longitude first for AxisLongLat.
+func LatLongToStringOrder(pc *PolarCoord, format LatLongFormat, order AxisOrder) (first, second string) {
+	lat, long := LatLongToString(pc, format)
+	if order == AxisLongLat {
+		return long, lat
+	}
+	return lat, long
+}
+
 // Canonical representation of a lat/long bearing
 func (pc *PolarCoord) String() string {
-	lat, long := LatLongToString(pc, LLFdeg)
-	return "lat: " + lat + "°, long: " + long + "°"
+	return pc.StringOrder(AxisLatLong)
 }
 
-// A generic representation of easting (right, Y) and northing (Height,X) of a 2D projection
-// relative to Ellipsoid El. The height H at Point X,Y is above defining ellipsoid
-type GeoPoint struct {
-	X, Y, H float64
-	El      *Ellipsoid
+// Implements Coordinate, the system of a lat/long bearing is "latlong"
+func (pc *PolarCoord) System() string {
+	return "latlong"
 }
 
-// A generic Cartesian, geocentric point. For ease of conversion between polar and Cartesian
-// coordinates, the ellipsis might be included
-type CartPoint struct {
-	X, Y, Z float64
-	El      *Ellipsoid
+// Implements Coordinate, the EPSG code of WGS84 for a bearing on the WGS84Ellipsoid or without reference ellipsoid,
+// otherwise 0, as the ellipsoid alone does not determine the datum
+func (pc *PolarCoord) EPSG() int {
+	if pc.El == nil || pc.El == WGS84Ellipsoid {
+		return EPSGWGS84
+	}
+	return 0
 }
 
-func degtorad(deg float64) float64 {
-	return math.Pi * deg / 180
+// Implements Coordinate, returning a copy of the bearing on the WGS84Ellipsoid. A bearing without reference ellipsoid
+// is taken as WGS84, one on the GRS80Ellipsoid as identical within a meter. Returns ErrUnsupported for any other
+// ellipsoid, which requires a datum shift.
+func (pc *PolarCoord) ToWGS84() (*PolarCoord, error) {
+	if pc.El != nil && pc.El != WGS84Ellipsoid && pc.El != GRS80Ellipsoid {
+		return nil, ErrUnsupported
+	}
+	gc := *pc
+	gc.El = WGS84Ellipsoid
+	return &gc, nil
 }
 
-func radtodeg(rad float64) float64 {
-	return 180 * rad / math.Pi
+// Canonical representation of a lat/long bearing in the axis order order, like "long: 16.37°, lat: 48.2°"
+func (pc *PolarCoord) StringOrder(order AxisOrder) string {
+	lat, long := LatLongToString(pc, LLFdeg)
+	if order == AxisLongLat {
+		return "long: " + long + "°, lat: " + lat + "°"
+	}
+	return "lat: " + lat + "°, long: " + long + "°"
 }
 
-func removeblank(input string) string {
-	var accu string
-	for _, token := range input {
-		switch token {
-		case ' ':
+// ## JSON serialization
+
+// Number of decimal places of floating point values in the JSON and XML representation of coordinates.
+// The default of -1 uses the smallest number of decimal places necessary to represent the value exactly.
+var JSONPrecision = -1
+
+// Returns the JSON encoding of the struct v, or a pointer thereto, like json.Marshal does, but always writes
+// floating point fields in fixed-point notation with JSONPrecision decimal places instead of switching to the
+// exponent form for very small and very large magnitudes, which some JSON consumers can not parse. Fields of other
+// types are encoded by json.Marshal. The json struct tags name, "-" and omitempty are honored. The fields of an
+// embedded struct of an exported type are promoted to the fields of v, as json.Marshal does; a field of v hides a
+// promoted field of the same name.
+//
+// The package and its subpackages use MarshalFixedJSON to implement json.Marshaler for their coordinate types.
+func MarshalFixedJSON(v interface{}) ([]byte, error) {
+
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return nil, &json.UnsupportedTypeError{Type: rv.Type()}
+	}
+
+	buf := new(bytes.Buffer)
+	buf.WriteByte('{')
+
+	for _, field := range dominantJSONFields(fixedJSONFields(rv, 0, false, nil)) {
+		if field.omit {
 			continue
+		}
+		fv := field.value
+
+		var value []byte
+		switch fv.Kind() {
+		case reflect.Float32, reflect.Float64:
+			f := fv.Float()
+			if math.IsNaN(f) || math.IsInf(f, 0) {
+				return nil, &json.UnsupportedValueError{Value: fv, Str: strconv.FormatFloat(f, 'g', -1, 64)}
+			}
+			value = []byte(strconv.FormatFloat(f, 'f', JSONPrecision, 64))
 		default:
-			accu += string(token)
+			var err error
+			if value, err = json.Marshal(fv.Interface()); err != nil {
+				return nil, err
+			}
+		}
+
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
 		}
+		key, _ := json.Marshal(field.name)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
 	}
-	return accu
-}
 
-// The function accepts a string representing a bearing in degree, minute and second.
-// Minute and second are booth optional and the second may contain fractions.
-// The bearing may be prepended by the literal 'N', 'E', 'S', 'W', representing the
-// four main directions. 'S' and 'W' denotes negative bearing. Instead of the main directions,
-// the signs '+' or '-' may be used.
-//
-// [N|E|S|W|+|-]ddd°[dd'[dd'']]
-func ADegMMSSToNum(DegMMSS string) (float64, error) {
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
 
-	var accu string
-	var i, position int
-	var token rune
-	var tf, degf float64
-	var err error
+// A field of a struct encoded by MarshalFixedJSON. depth is the level of embedding of the field, 0 for a field of
+// the struct itself, tagged is true if the field is named by its json tag. A field to omit, as it is empty and
+// tagged omitempty or promoted from a nil pointer, still hides promoted fields of the same name.
+type fixedJSONField struct {
+	name   string
+	value  reflect.Value
+	depth  int
+	tagged bool
+	omit   bool
+}
 
-	degree := strings.ToUpper(removeblank(DegMMSS))
-	slen := len(degree)
+// fixedJSONFields appends the fields of the struct rv to fields, in the order of json.Marshal, descending into
+// embedded structs. omit is true for the fields of a struct embedded by a nil pointer.
+func fixedJSONFields(rv reflect.Value, depth int, omit bool, fields []fixedJSONField) []fixedJSONField {
 
-	negate := false
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
 
-	// parse the degree
-L2:
-	for i, token = range degree {
-		switch token {
-		case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
-			accu += string(token)
-		case 'S', 'W', '-':
-			negate = true
-		case 'N', 'E', '+':
+		name, omitempty := "", false
+		if tag := field.Tag.Get("json"); tag == "-" {
 			continue
-		case '°':
-			degf, err = strconv.ParseFloat(accu, 64)
-
-			if err != nil {
-				return degf, err
+		} else if len(tag) > 0 {
+			options := strings.Split(tag, ",")
+			name = options[0]
+			for _, option := range options[1:] {
+				omitempty = omitempty || option == "omitempty"
 			}
-
-			i += len("°")
-			degree = degree[i:]
-			position += i
-			accu = ""
-			break L2
-		default:
-			return 0, CartographyError{Val: degf, Index: i, Coord: degree, Err: ErrSyntax}
 		}
-	}
-
-L4:
-	// parse the minute
-	for i, token = range degree {
-		switch token {
-		case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
-			accu += string(token)
-		case '\'':
-			tf, err = strconv.ParseFloat(accu, 64)
 
-			if err != nil {
-				return tf, err
+		fv := rv.Field(i)
+		if field.Anonymous && name == "" && field.PkgPath == "" {
+			embeddedomit := omit
+			if fv.Kind() == reflect.Ptr && fv.Type().Elem().Kind() == reflect.Struct {
+				if fv.IsNil() {
+					fv, embeddedomit = reflect.Zero(fv.Type().Elem()), true
+				} else {
+					fv = fv.Elem()
+				}
+			}
+			if fv.Kind() == reflect.Struct {
+				fields = fixedJSONFields(fv, depth+1, embeddedomit, fields)
+				continue
 			}
+		}
+		if field.PkgPath != "" {
+			continue
+		}
 
-			degf += tf / 60.0
-			i += len("'")
-			degree = degree[i:]
-			position += i
-			accu = ""
-			break L4
-		default:
-			return 0, CartographyError{Val: degf, Index: i, Coord: degree, Err: ErrSyntax}
+		entry := fixedJSONField{name: name, value: fv, depth: depth, tagged: name != "", omit: omit || omitempty && fv.IsZero()}
+		if !entry.tagged {
+			entry.name = field.Name
 		}
+		fields = append(fields, entry)
 	}
+	return fields
+}
 
-	// parse the second
-L6:
-	for i, token = range degree {
-		switch token {
-		case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9', '.':
-			accu += string(token)
-		case '\'':
-			tf, err = strconv.ParseFloat(accu, 64)
-
-			if err != nil {
-				return tf, err
+// dominantJSONFields resolves fields of the same name as json.Marshal does: the field of the least depth of
+// embedding wins, among several of the same depth the only one named by a tag. Fields of a name which remains
+// ambiguous are left out.
+func dominantJSONFields(fields []fixedJSONField) []fixedJSONField {
+
+	dominant := make([]fixedJSONField, 0, len(fields))
+	for i, field := range fields {
+		wins := true
+		for j, other := range fields {
+			if i == j || other.name != field.name {
+				continue
 			}
-
-			degf += tf / (60.0 * 60.0)
-			i += len("'")
-			degree = degree[i:]
-			position += i
-
-			if !(position < slen && degree[0] == '\'') {
-				return 0, CartographyError{Val: degf, Index: i, Coord: degree, Err: ErrSyntax}
+			if other.depth < field.depth || other.depth == field.depth && (other.tagged || !field.tagged) {
+				wins = false
+				break
 			}
-			break L6
-		default:
-			return 0, CartographyError{Val: degf, Index: i, Coord: degree, Err: ErrSyntax}
+		}
+		if wins {
+			dominant = append(dominant, field)
 		}
 	}
+	return dominant
+}
 
-	if negate {
-		degf = -degf
-	}
+// Implements json.Marshaler, writing the coordinates in fixed-point notation
+func (pc *PolarCoord) MarshalJSON() ([]byte, error) {
+	return MarshalFixedJSON(pc)
+}
 
-	return degf, nil
+// Implements json.Marshaler, writing the coordinates in fixed-point notation
+func (gp *GeoPoint) MarshalJSON() ([]byte, error) {
+	return MarshalFixedJSON(gp)
 }
 
-// The function accepts a string literal representing a bearing
-// denoted as decimal degrees. The suffix is optional.
-// The literal value must end with the degree mark '°'
-// The bearing may be prepended by the literal 'N', 'E', 'S', 'W', representing the
-// four main directions. 'S' and 'W' denotes negative bearing. Instead of the main directions,
-// the signs '+' or '-' may be used.
+// Implements json.Marshaler, writing the coordinates in fixed-point notation
+func (cp *CartPoint) MarshalJSON() ([]byte, error) {
+	return MarshalFixedJSON(cp)
+}
+
+// Implements json.Marshaler, writing the coordinates in fixed-point notation
+func (pt *Point3D) MarshalJSON() ([]byte, error) {
+	return MarshalFixedJSON(pt)
+}
+
+// Implements json.Marshaler, writing the coordinates in fixed-point notation
+func (utm *UTMCoord) MarshalJSON() ([]byte, error) {
+	return MarshalFixedJSON(utm)
+}
+
+// ## XML serialization
+
+// Writes the XML encoding of the struct v, or a pointer thereto, as element start, like xml.Encoder does, with
+// floating point fields in fixed-point notation with JSONPrecision decimal places, so the XML representation of a
+// coordinate carries the same digits as its JSON representation. Fields of other types are encoded by the encoder.
+// The xml struct tags name, "-" and omitempty are honored.
 //
-// [N|E|S|W|+|-]ddd[.suffix]°
-func ADegCommaToNum(DegComma string) (float64, error) {
+// The package and its subpackages use MarshalFixedXML to implement xml.Marshaler for their coordinate types.
+func MarshalFixedXML(e *xml.Encoder, start xml.StartElement, v interface{}) error {
 
-	var accu string
-	var i int
-	var token rune
-	var degf, tf float64
-	var err error
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return &xml.UnsupportedTypeError{Type: rv.Type()}
+	}
 
-	degree := strings.ToUpper(removeblank(DegComma))
-	negate := false
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
 
-	// parse the degree
-L2:
-	for i, token = range degree {
-		switch token {
-		case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
-			accu += string(token)
-		case 'S', 'W', '-':
-			negate = true
-		case 'N', 'E', '+':
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		// XMLName names the element, which is given by start
+		field := t.Field(i)
+		if field.PkgPath != "" || field.Name == "XMLName" {
 			continue
-		case '.', '°':
-			degf, err = strconv.ParseFloat(accu, 64)
+		}
 
-			if err != nil {
-				return degf, err
+		name, omitempty := field.Name, false
+		if tag := field.Tag.Get("xml"); tag == "-" {
+			continue
+		} else if len(tag) > 0 {
+			options := strings.Split(tag, ",")
+			if len(options[0]) > 0 {
+				name = options[0]
 			}
+			for _, option := range options[1:] {
+				omitempty = omitempty || option == "omitempty"
+			}
+		}
 
-			degree = degree[i+len(string(token)):]
-			accu = ""
-			break L2
+		fv := rv.Field(i)
+		if omitempty && fv.IsZero() {
+			continue
+		}
+
+		element := xml.StartElement{Name: xml.Name{Local: name}}
+		var err error
+		switch fv.Kind() {
+		case reflect.Float32, reflect.Float64:
+			err = e.EncodeElement(strconv.FormatFloat(fv.Float(), 'f', JSONPrecision, 64), element)
 		default:
-			return 0, CartographyError{Val: degf, Index: i, Coord: degree, Err: ErrSyntax}
+			err = e.EncodeElement(fv.Interface(), element)
+		}
+		if err != nil {
+			return err
 		}
 	}
-L4:
-	// parse the suffix
-	for i, token = range degree {
-		switch token {
-		case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
-			accu += string(token)
-		case '°':
-			tf, err = strconv.ParseFloat("0."+accu, 64)
+
+	return e.EncodeToken(start.End())
+}
+
+// Implements xml.Marshaler, writing the coordinates in fixed-point notation
+func (pc *PolarCoord) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return MarshalFixedXML(e, start, pc)
+}
+
+// Implements xml.Marshaler, writing the coordinates in fixed-point notation
+func (gp *GeoPoint) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return MarshalFixedXML(e, start, gp)
+}
+
+// Implements xml.Marshaler, writing the coordinates in fixed-point notation
+func (cp *CartPoint) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return MarshalFixedXML(e, start, cp)
+}
+
+// Implements xml.Marshaler, writing the coordinates in fixed-point notation
+func (pt *Point3D) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return MarshalFixedXML(e, start, pt)
+}
+
+// Implements xml.Marshaler, writing the coordinates in fixed-point notation
+func (utm *UTMCoord) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return MarshalFixedXML(e, start, utm)
+}
+
+// ## Axis order
+
+// Order of the axes of a geographic coordinate reference system
+type AxisOrder byte
+
+const (
+	AxisLatLong AxisOrder = iota // first axis is the latitude, second axis is the longitude
+	AxisLongLat                  // first axis is the longitude, second axis is the latitude
+)
+
+func (order AxisOrder) String() string {
+	switch order {
+	case AxisLatLong:
+		return "lat,long"
+	case AxisLongLat:
+		return "long,lat"
+	}
+	return "#unknown"
+}
+
+// Parses the axis order spec, "lat,long" or "long,lat". The axes may also be named lat and lon, and the comma may
+// be omitted, as in "lonlat". Case and blanks around the axes are ignored.
+// Returns ErrSyntax if spec is none of these.
+func ParseAxisOrder(spec string) (AxisOrder, error) {
+	axes := strings.Split(strings.ToLower(spec), ",")
+	for i := range axes {
+		axes[i] = strings.TrimSpace(axes[i])
+	}
+
+	switch strings.Join(axes, "") {
+	case "latlong", "latlon":
+		return AxisLatLong, nil
+	case "longlat", "lonlat":
+		return AxisLongLat, nil
+	}
+	return AxisLatLong, ErrSyntax
+}
+
+// Authoritative axis order of the geographic coordinate reference systems supported by this package.
+// As defined by the EPSG registry, all of them state the latitude first, although many tools send the
+// longitude first.
+var epsgAxisOrder = map[int]AxisOrder{
+	4326: AxisLatLong, // WGS84
+	4258: AxisLatLong, // ETRS89
+	4312: AxisLatLong, // MGI
+	4277: AxisLatLong, // OSGB36
+	4149: AxisLatLong, // CH1903
+	4150: AxisLatLong, // CH1903+
+}
+
+// Returns the axis order of the geographic coordinate reference system denoted by its EPSG code.
+// Returns ErrNotFound if the EPSG code is not one of a supported geographic coordinate reference system.
+func AxisOrderByEPSG(epsg int) (AxisOrder, error) {
+	if order, ok := epsgAxisOrder[epsg]; ok {
+		return order, nil
+	}
+	return AxisLatLong, ErrNotFound
+}
+
+// Identifier of the geographic coordinate reference system of WGS84 with the longitude first, as used by GeoJSON
+const CRS84 = "OGC:CRS84"
+
+// splitCRS splits the identifier of a coordinate reference system, "authority:code" like EPSG:4326 or an OGC URN
+// like urn:ogc:def:crs:OGC:1.3:CRS84, into its upper case authority and code
+func splitCRS(crs string) (authority, code string) {
+	parts := strings.Split(strings.ToUpper(strings.TrimSpace(crs)), ":")
+	switch {
+	case len(parts) == 2:
+		return parts[0], parts[1]
+	case len(parts) == 7 && strings.Join(parts[:4], ":") == "URN:OGC:DEF:CRS":
+		// the version of the URN may be empty
+		return parts[4], parts[6]
+	}
+	return "", ""
+}
+
+// Returns WGS84 latitude and longitude of a pair of values given in the authoritative axis order of the
+// geographic coordinate reference system crs, identified like EPSG:4326 or OGC:CRS84 or by its OGC URN.
+// Returns ErrUnsupported if crs is no geographic coordinate reference system of the datum WGS84, as values of
+// another datum would need a datum shift.
+func NormalizeAxisOrder(values [2]float64, crs string) (lat, long float64, err error) {
+	if err = CheckFinite(values[0], values[1]); err != nil {
+		return 0, 0, err
+	}
+
+	var order AxisOrder
+	switch authority, code := splitCRS(crs); {
+	case authority == "OGC" && code == "CRS84":
+		order = AxisLongLat
+	case authority == "EPSG":
+		epsg, err := strconv.Atoi(code)
+		if err != nil {
+			return 0, 0, ErrUnsupported
+		}
+		if system, err := ByEPSG(epsg); err != nil || system.Projected() || system.Datum != DatumWGS84 {
+			return 0, 0, ErrUnsupported
+		}
+		if order, err = AxisOrderByEPSG(epsg); err != nil {
+			return 0, 0, ErrUnsupported
+		}
+	default:
+		return 0, 0, ErrUnsupported
+	}
+
+	if order == AxisLongLat {
+		return values[1], values[0], nil
+	}
+	return values[0], values[1], nil
+}
+
+// ## Extents
+
+// A geographic extent in decimal degrees, bounding the area in which a coordinate system is defined
+type LatLongExtent struct {
+	MinLat, MaxLat, MinLong, MaxLong float64
+}
+
+// Extents of the whole globe, of the UTM system, which excludes the polar regions, and of the two zones of UPS
+// covering them, which overlap UTM by 30'
+var (
+	WorldExtent    = &LatLongExtent{MinLat: -90, MaxLat: 90, MinLong: -180, MaxLong: 180}
+	UTMExtent      = &LatLongExtent{MinLat: -80, MaxLat: 84, MinLong: -180, MaxLong: 180}
+	UPSNorthExtent = &LatLongExtent{MinLat: 83.5, MaxLat: 90, MinLong: -180, MaxLong: 180}
+	UPSSouthExtent = &LatLongExtent{MinLat: -90, MaxLat: -79.5, MinLong: -180, MaxLong: 180}
+)
+
+// Returns true, if the coordinate lies within the extent, bounds included
+func (extent *LatLongExtent) Contains(gc *PolarCoord) bool {
+	return gc.Latitude >= extent.MinLat && gc.Latitude <= extent.MaxLat &&
+		gc.Longitude >= extent.MinLong && gc.Longitude <= extent.MaxLong
+}
+
+// An ExtentError is yielded when a coordinate lies outside of the extent of its coordinate system.
+// In this case the following values are set and carry the meaning:
+type ExtentError struct {
+	System   string         // Name of the coordinate system
+	Coord    string         // The coordinate in its canonical representation
+	Location *PolarCoord    // The latitude / longitude the coordinate transforms to
+	Extent   *LatLongExtent // The extent of the coordinate system
+}
+
+func (ee ExtentError) Error() string {
+	return fmt.Sprintf("%s coordinate \"%s\" transforms to latitude %f, longitude %f, which lies outside of the extent latitude %g to %g, longitude %g to %g",
+		ee.System, ee.Coord, ee.Location.Latitude, ee.Location.Longitude, ee.Extent.MinLat, ee.Extent.MaxLat, ee.Extent.MinLong, ee.Extent.MaxLong)
+}
+
+// Returns an ExtentError, if the location gc of the coordinate coord of the coordinate system named system lies
+// outside of the extent. Otherwise returns nil.
+func (extent *LatLongExtent) Validate(gc *PolarCoord, system, coord string) error {
+	if !extent.Contains(gc) {
+		return ExtentError{System: system, Coord: coord, Location: gc, Extent: extent}
+	}
+	return nil
+}
+
+// Detects and corrects latitude and longitude given in the wrong order. If the coordinate lat / long lies
+// outside of extent but the swapped coordinate long / lat lies within, the swapped values are returned and
+// swapped is set. Otherwise lat and long are returned unchanged.
+//
+// The function does not guess: if both orderings lie within extent, lat and long are returned unchanged together
+// with ErrAmbiguous, which is meant as a warning. If neither ordering lies within extent, ErrRange is returned.
+func AutoCorrectSwap(lat, long float64, extent *LatLongExtent) (clat, clong float64, swapped bool, err error) {
+
+	if err = CheckFinite(lat, long); err != nil {
+		return lat, long, false, err
+	}
+
+	given := extent.Contains(&PolarCoord{Latitude: lat, Longitude: long})
+	reversed := extent.Contains(&PolarCoord{Latitude: long, Longitude: lat})
+
+	switch {
+	case given && reversed && lat != long:
+		return lat, long, false, ErrAmbiguous
+	case given:
+		return lat, long, false, nil
+	case reversed:
+		return long, lat, true, nil
+	}
+	return lat, long, false, ErrRange
+}
+
+// Returns the i-th of n values evenly spaced from min to max, bounds included. A single value lies in the middle.
+func lattice(min, max float64, i, n int) float64 {
+	if n == 1 {
+		return (min + max) / 2
+	}
+	return min + float64(i)*(max-min)/float64(n-1)
+}
+
+// Generates a regular lattice of rows by cols coordinates covering extent. The coordinates are ordered by rows from south
+// to north, every row from west to east, and refer to the DefaultEllipsoid. The outer rows and columns lie on the bounds
+// of the extent; a single row or column lies in its middle.
+//
+// The lattice is regular in latitude and longitude, unless projected is set. Then the lattice is regular in a transverse
+// mercator projection centered on the extent, which yields evenly spaced coordinates on the map. The projected lattice
+// spans the bounding rectangle of the projected extent, so its corner coordinates may lie outside of extent.
+//
+// Returns ErrRange if rows or cols is less than 1 or the extent is empty.
+func SampleGrid(extent *LatLongExtent, rows, cols int, projected bool) ([]*PolarCoord, error) {
+
+	if rows < 1 || cols < 1 || extent.MinLat > extent.MaxLat || extent.MinLong > extent.MaxLong {
+		return nil, ErrRange
+	}
+
+	points := make([]*PolarCoord, 0, rows*cols)
+
+	if !projected {
+		for row := 0; row < rows; row++ {
+			for col := 0; col < cols; col++ {
+				points = append(points, &PolarCoord{
+					Latitude:  lattice(extent.MinLat, extent.MaxLat, row, rows),
+					Longitude: lattice(extent.MinLong, extent.MaxLong, col, cols),
+					El:        DefaultEllipsoid})
+			}
+		}
+		return points, nil
+	}
+
+	longO := (extent.MinLong + extent.MaxLong) / 2
+
+	// the edges of the extent are curved in the projection, sample them for the bounding rectangle
+	const edgeSamples = 32
+	x0, y0, x1, y1 := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for i := 0; i <= edgeSamples; i++ {
+		lat, long := lattice(extent.MinLat, extent.MaxLat, i, edgeSamples+1), lattice(extent.MinLong, extent.MaxLong, i, edgeSamples+1)
+		for _, gc := range []*PolarCoord{
+			{Latitude: lat, Longitude: extent.MinLong, El: DefaultEllipsoid},
+			{Latitude: lat, Longitude: extent.MaxLong, El: DefaultEllipsoid},
+			{Latitude: extent.MinLat, Longitude: long, El: DefaultEllipsoid},
+			{Latitude: extent.MaxLat, Longitude: long, El: DefaultEllipsoid},
+		} {
+			gp := DirectTransverseMercator(gc, 0, longO, 1, 0, 0)
+			x0, x1 = math.Min(x0, gp.X), math.Max(x1, gp.X)
+			y0, y1 = math.Min(y0, gp.Y), math.Max(y1, gp.Y)
+		}
+	}
+
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			gp := &GeoPoint{X: lattice(x0, x1, col, cols), Y: lattice(y0, y1, row, rows), El: DefaultEllipsoid}
+			points = append(points, InverseTransverseMercator(gp, 0, longO, 1, 0, 0))
+		}
+	}
+	return points, nil
+}
+
+// Traces the boundary of extent as closed ring of coordinates, counterclockwise from the south-western corner, the
+// first coordinate repeated as last one, as required for exterior rings by GeoJSON. Every edge is divided into segments
+// parts, so the meridians and parallels bounding the extent curve correctly when drawn in a projection; a segments of 1
+// yields the four corners only. The coordinates refer to the DefaultEllipsoid.
+//
+// Returns ErrRange if segments is less than 1 or the extent is empty.
+func (extent *LatLongExtent) Polygon(segments int) ([]*PolarCoord, error) {
+
+	if segments < 1 || extent.MinLat > extent.MaxLat || extent.MinLong > extent.MaxLong {
+		return nil, ErrRange
+	}
+
+	n := segments + 1
+	points := make([]*PolarCoord, 0, 4*segments+1)
+	edge := func(lat0, long0, lat1, long1 float64) {
+		for i := 0; i < segments; i++ {
+			points = append(points, &PolarCoord{Latitude: lattice(lat0, lat1, i, n), Longitude: lattice(long0, long1, i, n), El: DefaultEllipsoid})
+		}
+	}
+	edge(extent.MinLat, extent.MinLong, extent.MinLat, extent.MaxLong)
+	edge(extent.MinLat, extent.MaxLong, extent.MaxLat, extent.MaxLong)
+	edge(extent.MaxLat, extent.MaxLong, extent.MaxLat, extent.MinLong)
+	edge(extent.MaxLat, extent.MinLong, extent.MinLat, extent.MinLong)
+
+	closing := *points[0]
+	return append(points, &closing), nil
+}
+
+// A generic representation of easting (right, Y) and northing (Height,X) of a 2D projection
+// relative to Ellipsoid El. The height H at Point X,Y is above defining ellipsoid
+type GeoPoint struct {
+	X  float64    `json:"x" xml:"x"`
+	Y  float64    `json:"y" xml:"y"`
+	H  float64    `json:"h" xml:"h"`
+	El *Ellipsoid `json:"ellipsoid,omitempty" xml:"ellipsoid,omitempty"`
+}
+
+// A generic Cartesian, geocentric point. For ease of conversion between polar and Cartesian
+// coordinates, the ellipsis might be included
+type CartPoint struct {
+	X  float64    `json:"x" xml:"x"`
+	Y  float64    `json:"y" xml:"y"`
+	Z  float64    `json:"z" xml:"z"`
+	El *Ellipsoid `json:"ellipsoid,omitempty" xml:"ellipsoid,omitempty"`
+}
+
+func degtorad(deg float64) float64 {
+	return math.Pi * deg / 180
+}
+
+func radtodeg(rad float64) float64 {
+	return 180 * rad / math.Pi
+}
+
+// Returns a polar coordinate from latitude and longitude given in radians. The coordinate
+// is stored in decimal degrees like every polar coordinate of this package.
+// If the reference ellipsoid is nil, the DefaultEllipsoid will be set in the resulting polar coordinate.
+func NewPolarRadians(latRad, lonRad float64, el *Ellipsoid) *PolarCoord {
+	if el == nil {
+		el = DefaultEllipsoid
+	}
+	return &PolarCoord{Latitude: radtodeg(latRad), Longitude: radtodeg(lonRad), El: el}
+}
+
+// Latitude of the polar coordinate in radians
+func (pc *PolarCoord) LatRadians() float64 {
+	return degtorad(pc.Latitude)
+}
+
+// Longitude of the polar coordinate in radians
+func (pc *PolarCoord) LonRadians() float64 {
+	return degtorad(pc.Longitude)
+}
+
+// Returns ErrNotFinite if latitude or longitude of the polar coordinate is NaN or infinite
+func (pc *PolarCoord) CheckFinite() error {
+	return CheckFinite(pc.Latitude, pc.Longitude)
+}
+
+// Reports whether the polar coordinate and other lie within toleranceMeters of each other, measured by the geodesic
+// distance of Vincenty on their reference ellipsoid, rather than by comparing latitude and longitude, which differ by
+// floating-point noise after a round trip through a projection. The height is not compared.
+//
+// Coordinates of different reference ellipsoids are never equal, even if they denote the same place, as their
+// latitude and longitude refer to different datums; a nil ellipsoid is taken as the DefaultEllipsoid. Coordinates
+// which are not finite, nearly antipodal coordinates, for which Vincenty doesn't converge, and a negative or NaN
+// tolerance don't compare equal either.
+func (pc *PolarCoord) Equal(other *PolarCoord, toleranceMeters float64) bool {
+	distance, _, _, err := Vincenty(pc, other)
+	return err == nil && distance <= toleranceMeters
+}
+
+// A reference point of a coordinate system, as returned by the function Examples of its subpackage, for tests and
+// documentation: the literal Input, as read by the parser of the subpackage, denotes the WGS84 location LatLong
+// within Tolerance meters. The tolerance covers the accuracy of the transformation to WGS84 and, for systems of
+// cells like grid references or locators, the distance of the reference point to the center of its cell.
+type Example struct {
+	Name      string      // the reference point, like "Old observatory, Bern"
+	Input     string      // literal of the coordinate system
+	LatLong   *PolarCoord // on the WGS84 ellipsoid
+	Tolerance float64     // meters
+}
+
+// Reports whether gc, read from the input of the example and converted to latitude / longitude, lies within the
+// tolerance of the example. The reference ellipsoid of gc is taken to be WGS84, so results on the GRS80 ellipsoid
+// of ETRS89 compare within the sub-meter difference of both datums.
+func (ex Example) Matches(gc *PolarCoord) bool {
+	if gc == nil {
+		return false
+	}
+	wgs84 := *gc
+	wgs84.El = ex.LatLong.El
+	return wgs84.Equal(ex.LatLong, ex.Tolerance)
+}
+
+// Returns ErrNotFinite if any of values is NaN or infinite. Such values slip in from corrupted data and would
+// silently turn every result computed from them into NaN, so the conversion functions reject them by CheckFinite.
+func CheckFinite(values ...float64) error {
+	for _, value := range values {
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return ErrNotFinite
+		}
+	}
+	return nil
+}
+
+// Rounds val to the nearest multiple of spacing, halfway values to the even multiple, so snapping many values to a
+// grid doesn't shift them on average. Negative values are rounded alike, -250 snaps to -200 at a spacing of 100. A
+// spacing which is not positive and finite returns val unchanged.
+func SnapToGrid(val, spacing float64) float64 {
+	if !(spacing > 0) || math.IsInf(spacing, 1) {
+		return val
+	}
+	return math.RoundToEven(val/spacing) * spacing
+}
+
+func removeblank(input string) string {
+	var accu string
+	for _, token := range input {
+		switch token {
+		case ' ':
+			continue
+		default:
+			accu += string(token)
+		}
+	}
+	return accu
+}
+
+// The function accepts a string representing a bearing in degree, minute and second.
+// Minute and second are booth optional and the second may contain fractions.
+// The bearing may be prepended by the literal 'N', 'E', 'S', 'W', representing the
+// four main directions. 'S' and 'W' denotes negative bearing. Instead of the main directions,
+// the signs '+' or '-' may be used.
+//
+// [N|E|S|W|+|-]ddd°[dd'[dd'']]
+//
+// A literal without the degree mark, like a bare integer, or digits without the mark of minutes or seconds are
+// rejected with ErrSyntax.
+func ADegMMSSToNum(DegMMSS string) (float64, error) {
+
+	var accu string
+	var i, position int
+	var token rune
+	var tf, degf float64
+	var err error
+
+	degree := strings.ToUpper(removeblank(DegMMSS))
+	slen := len(degree)
+
+	// without the degree mark, the literal is no bearing of this notation, like a bare integer
+	if !strings.ContainsRune(degree, '°') {
+		return 0, CartographyError{Coord: degree, Err: ErrSyntax}
+	}
+
+	negate := false
+
+	// parse the degree
+L2:
+	for i, token = range degree {
+		switch token {
+		case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+			accu += string(token)
+		case 'S', 'W', '-':
+			negate = true
+		case 'N', 'E', '+':
+			continue
+		case '°':
+			degf, err = strconv.ParseFloat(accu, 64)
+
+			if err != nil {
+				return degf, err
+			}
+
+			i += len("°")
+			degree = degree[i:]
+			position += i
+			accu = ""
+			break L2
+		default:
+			return 0, CartographyError{Val: degf, Index: i, Coord: degree, Err: ErrSyntax}
+		}
+	}
+
+L4:
+	// parse the minute
+	for i, token = range degree {
+		switch token {
+		case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+			accu += string(token)
+		case '\'':
+			tf, err = strconv.ParseFloat(accu, 64)
+
+			if err != nil {
+				return tf, err
+			}
+
+			degf += tf / 60.0
+			i += len("'")
+			degree = degree[i:]
+			position += i
+			accu = ""
+			break L4
+		default:
+			return 0, CartographyError{Val: degf, Index: i, Coord: degree, Err: ErrSyntax}
+		}
+	}
+
+	// parse the second
+L6:
+	for i, token = range degree {
+		switch token {
+		case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9', '.':
+			accu += string(token)
+		case '\'':
+			tf, err = strconv.ParseFloat(accu, 64)
 
 			if err != nil {
 				return tf, err
 			}
 
-			degf += tf
-			accu = ""
-			break L4
-		default:
-			return 0, CartographyError{Val: degf, Index: i, Coord: degree, Err: ErrSyntax}
-		}
+			degf += tf / (60.0 * 60.0)
+			i += len("'")
+			degree = degree[i:]
+			position += i
+
+			if !(position < slen && degree[0] == '\'') {
+				return 0, CartographyError{Val: degf, Index: i, Coord: degree, Err: ErrSyntax}
+			}
+			accu = ""
+			break L6
+		default:
+			return 0, CartographyError{Val: degf, Index: i, Coord: degree, Err: ErrSyntax}
+		}
+	}
+
+	// digits not terminated by the mark of minutes or seconds
+	if accu != "" {
+		return 0, CartographyError{Val: degf, Index: i, Coord: degree, Err: ErrSyntax}
+	}
+
+	if negate {
+		degf = -degf
+	}
+
+	return degf, nil
+}
+
+// The function accepts a string literal representing a bearing
+// denoted as decimal degrees. The suffix is optional.
+// The literal value must end with the degree mark '°'
+// The bearing may be prepended by the literal 'N', 'E', 'S', 'W', representing the
+// four main directions. 'S' and 'W' denotes negative bearing. Instead of the main directions,
+// the signs '+' or '-' may be used.
+//
+// [N|E|S|W|+|-]ddd[.suffix]°
+func ADegCommaToNum(DegComma string) (float64, error) {
+
+	var accu string
+	var i int
+	var token rune
+	var degf, tf float64
+	var err error
+
+	degree := strings.ToUpper(removeblank(DegComma))
+	negate := false
+
+	// parse the degree
+L2:
+	for i, token = range degree {
+		switch token {
+		case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+			accu += string(token)
+		case 'S', 'W', '-':
+			negate = true
+		case 'N', 'E', '+':
+			continue
+		case '.', '°':
+			degf, err = strconv.ParseFloat(accu, 64)
+
+			if err != nil {
+				return degf, err
+			}
+
+			degree = degree[i+len(string(token)):]
+			accu = ""
+			break L2
+		default:
+			return 0, CartographyError{Val: degf, Index: i, Coord: degree, Err: ErrSyntax}
+		}
+	}
+L4:
+	// parse the suffix
+	for i, token = range degree {
+		switch token {
+		case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+			accu += string(token)
+		case '°':
+			tf, err = strconv.ParseFloat("0."+accu, 64)
+
+			if err != nil {
+				return tf, err
+			}
+
+			degf += tf
+			accu = ""
+			break L4
+		default:
+			return 0, CartographyError{Val: degf, Index: i, Coord: degree, Err: ErrSyntax}
+		}
+	}
+
+	if len(accu) > 0 {
+		return 0, CartographyError{Val: degf, Index: i, Coord: degree, Err: ErrSyntax}
+	}
+
+	if negate {
+		degf = -degf
+	}
+
+	return degf, nil
+}
+
+// ## Polar to Cartesian coordinate conversion and vice-versa
+
+// Function accepts two bearing datum as Deg°MM'SS'' (typically northing and easting)
+// and height at bearing relative to reference ellipsoid and returns a polar coordinate type.
+// If the reference ellipsoid is nil, the DefaultEllipsoid will be set in the resulting polar coordinate.
+func ADegMMSSToPolar(Northing, Easting string, Height float64, El *Ellipsoid) (*PolarCoord, error) {
+
+	northing, err := ADegMMSSToNum(Northing)
+
+	if err == nil {
+		easting, err := ADegMMSSToNum(Easting)
+
+		if err == nil {
+
+			el := El
+			if el == nil {
+				el = DefaultEllipsoid
+			}
+
+			return &PolarCoord{Latitude: northing, Longitude: easting, Height: Height, El: el}, nil
+		}
+	}
+	return nil, err
+}
+
+// A token of a latitude / longitude literal
+type dmsToken struct {
+	kind  rune    // '0' for a number, 'N', 'E', 'S' or 'W' for a main direction, '+' or '-' for a sign
+	value float64 // the value of a number
+	frac  bool    // set if the number has decimal fractions
+	unit  int     // the unit a number is marked with, 0 for degree, 1 for minute, 2 for second, -1 if unmarked
+	index int     // position of the token within the literal
+}
+
+// Splits a latitude / longitude literal into numbers, main directions and signs. Unit marks are attached to the
+// preceding number, blanks, commas and semicolons separate tokens.
+func dmsTokenize(literal string) ([]dmsToken, error) {
+
+	var tokens []dmsToken
+	runes := []rune(strings.ToUpper(literal))
+
+	for i := 0; i < len(runes); i++ {
+		unit := -1
+
+		switch token := runes[i]; token {
+		case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9', '.':
+			start := i
+			for i+1 < len(runes) && (runes[i+1] >= '0' && runes[i+1] <= '9' || runes[i+1] == '.') {
+				i++
+			}
+			number := string(runes[start : i+1])
+			value, err := strconv.ParseFloat(number, 64)
+			if err != nil {
+				return nil, CartographyError{Index: start, Coord: number, Err: ErrSyntax}
+			}
+			tokens = append(tokens, dmsToken{kind: '0', value: value, frac: strings.Contains(number, "."), unit: -1, index: start})
+			continue
+		case 'N', 'E', 'S', 'W', '+', '-':
+			tokens = append(tokens, dmsToken{kind: token, index: i})
+			continue
+		case ' ', '\t', ',', ';':
+			continue
+		case '°', 'º', 'D':
+			unit = 0
+		case '\'', '′', '’':
+			unit = 1
+			// two apostrophes denote the second
+			if token == '\'' && i+1 < len(runes) && runes[i+1] == '\'' {
+				unit = 2
+				i++
+			}
+		case '"', '″', '”':
+			unit = 2
+		default:
+			return nil, CartographyError{Index: i, Coord: string(runes[i:]), Err: ErrSyntax}
+		}
+
+		// a unit mark has to follow an unmarked number
+		last := len(tokens) - 1
+		if last < 0 || tokens[last].kind != '0' || tokens[last].unit >= 0 {
+			return nil, CartographyError{Index: i, Coord: string(runes[i:]), Err: ErrSyntax}
+		}
+		tokens[last].unit = unit
+	}
+	return tokens, nil
+}
+
+// Returns the value in decimal degrees of a bearing given by up to three numbers for degree, minute and second,
+// preceded or followed by a main direction or preceded by a sign. For a main direction, axis is set to 'N' for
+// N and S, and to 'E' for E and W.
+func dmsBearing(tokens []dmsToken, literal string) (value float64, axis rune, err error) {
+
+	syntaxerr := func(index int) error {
+		return CartographyError{Val: value, Index: index, Coord: literal, Err: ErrSyntax}
+	}
+
+	if len(tokens) == 0 {
+		return 0, 0, syntaxerr(len(literal))
+	}
+
+	var negate bool
+	var parts [3]float64
+	var position, numbers int
+	for i, token := range tokens {
+		if token.kind != '0' {
+			// the direction or the sign precedes or follows the numbers, only one of them is allowed
+			if (i != 0 && i != len(tokens)-1) || len(tokens) == 1 || (i != 0 && tokens[0].kind != '0') ||
+				(i != 0 && (token.kind == '+' || token.kind == '-')) {
+				return 0, 0, syntaxerr(token.index)
+			}
+			switch token.kind {
+			case 'N', 'S':
+				axis = 'N'
+			case 'E', 'W':
+				axis = 'E'
+			}
+			negate = token.kind == 'S' || token.kind == 'W' || token.kind == '-'
+			continue
+		}
+
+		if token.unit >= 0 {
+			if token.unit < position {
+				return 0, 0, syntaxerr(token.index)
+			}
+			position = token.unit
+		}
+		// only the last number may have decimal fractions
+		if position > 2 || (numbers > 0 && tokens[i-1].kind == '0' && tokens[i-1].frac) {
+			return 0, 0, syntaxerr(token.index)
+		}
+		if position > 0 && token.value >= 60 {
+			return 0, 0, CartographyError{Val: value, Index: token.index, Coord: literal, Err: ErrRange}
+		}
+		parts[position] = token.value
+		value = parts[0] + parts[1]/60 + parts[2]/3600
+		position++
+		numbers++
+	}
+
+	if numbers == 0 {
+		return 0, 0, syntaxerr(tokens[0].index)
+	}
+	if negate {
+		value = -value
+	}
+	return value, axis, nil
+}
+
+// Returns the index of the token at which the second bearing of a latitude / longitude literal starts. With main
+// directions, the bearings are split by the directions, which either precede or follow both bearings. Otherwise,
+// the second bearing starts at the second of two numbers marked as degree. Lacking those, the numbers are split
+// evenly. Returns ErrSyntax if the main directions don't delimit two bearings and ErrAmbiguous for an odd
+// count of numbers.
+func dmsSplit(tokens []dmsToken) (int, error) {
+
+	var directions, degrees, numbers []int
+	for i, token := range tokens {
+		switch token.kind {
+		case 'N', 'E', 'S', 'W':
+			directions = append(directions, i)
+		case '0':
+			numbers = append(numbers, i)
+			if token.unit == 0 {
+				degrees = append(degrees, i)
+			}
+		}
+	}
+
+	var split int
+	switch {
+	case len(directions) > 0:
+		switch {
+		case len(directions) != 2:
+			return 0, ErrSyntax
+		case directions[0] == 0:
+			return directions[1], nil
+		case directions[1] == len(tokens)-1:
+			return directions[0] + 1, nil
+		}
+		return 0, ErrSyntax
+	case len(degrees) == 2:
+		split = degrees[1]
+	case len(numbers) == 0:
+		return 0, ErrSyntax
+	case len(numbers)%2 != 0:
+		return 0, ErrAmbiguous
+	default:
+		split = numbers[len(numbers)/2]
+	}
+
+	// a sign belongs to the following number
+	if tokens[split-1].kind == '+' || tokens[split-1].kind == '-' {
+		split--
+	}
+	return split, nil
+}
+
+// Parses a single bearing in the notations of ADegMinSecToPolar, like 47°30'15"N or -16 20.17, into decimal degrees.
+//
+// Returns a CartographyError with ErrSyntax if the literal is malformed and with ErrRange if minute or second are
+// not below 60.
+func ADegMinSecToNum(bearing string) (float64, error) {
+
+	tokens, err := dmsTokenize(bearing)
+	if err != nil {
+		return 0, err
+	}
+	value, _, err := dmsBearing(tokens, bearing)
+	return value, err
+}
+
+// Parses a latitude / longitude literal given in one of the common notations into a polar coordinate, like
+//
+//	47°30'15"N 16°20'10"E
+//	N47 30.25 E016 20.17
+//	47.504167, 16.336111
+//	-33.86 151.2
+//
+// Every bearing consists of degree and the optional minute and second, given as numbers separated by blanks or
+// marked by the units °, ' and " or ''. Only the last number of a bearing may have decimal fractions. A bearing may
+// be preceded or followed by one of the main directions 'N', 'E', 'S', 'W', or preceded by the signs '+' and '-'.
+// 'S', 'W' and '-' denote a negative bearing. The bearings may be separated by blanks, a comma or a semicolon.
+//
+// If the main directions are given, they determine which bearing is the latitude. Otherwise the latitude is given
+// first, unless longFirst is set. The reference ellipsoid of the coordinate is the DefaultEllipsoid.
+//
+// Returns a CartographyError with ErrSyntax if the literal is malformed, with ErrAmbiguous if the literal
+// can not be split unambiguously into latitude and longitude, like "47 30 16", and with ErrRange if minute or
+// second are not below 60 or latitude and longitude exceed 90, respectively 180 degrees.
+func ADegMinSecToPolar(latlong string, longFirst bool) (*PolarCoord, error) {
+
+	tokens, err := dmsTokenize(latlong)
+	if err != nil {
+		return nil, err
+	}
+
+	split, err := dmsSplit(tokens)
+	if err != nil {
+		return nil, CartographyError{Coord: latlong, Err: err}
+	}
+
+	first, axis1, err := dmsBearing(tokens[:split], latlong)
+	if err != nil {
+		return nil, err
+	}
+	second, axis2, err := dmsBearing(tokens[split:], latlong)
+	if err != nil {
+		return nil, err
+	}
+
+	lat, long := first, second
+	switch {
+	case axis1 != axis2 && axis1 != 0 && axis2 != 0:
+		if axis1 == 'E' {
+			lat, long = second, first
+		}
+	case axis1 != 0 || axis2 != 0:
+		// both bearings of the same direction, or only one of them with a direction
+		return nil, CartographyError{Coord: latlong, Err: ErrSyntax}
+	case longFirst:
+		lat, long = second, first
+	}
+
+	if math.Abs(lat) > 90 {
+		return nil, CartographyError{Val: lat, Coord: latlong, Err: ErrRange}
+	}
+	if math.Abs(long) > 180 {
+		return nil, CartographyError{Val: long, Coord: latlong, Err: ErrRange}
+	}
+	return &PolarCoord{Latitude: lat, Longitude: long, El: DefaultEllipsoid}, nil
+}
+
+// Convert polar coordinates to Cartesian. The polar coordinates must be in decimal degrees.
+// The reference ellipsoid is copied verbatim to the result.
+// Inspired by http://www.movable-type.co.uk/scripts/latlong-convert-coords.html
+//
+// The coordinate is not checked to be finite, a NaN or infinite coordinate yields NaN, see PolarToCartesianChecked.
+func PolarToCartesian(gc *PolarCoord) *CartPoint {
+	var p CartPoint
+	PolarToCartesianInto(gc, &p)
+	return &p
+}
+
+// Convert polar coordinates to Cartesian like PolarToCartesian. Function returns ErrNotFinite, if latitude,
+// longitude or height of gc is NaN or infinite.
+func PolarToCartesianChecked(gc *PolarCoord) (*CartPoint, error) {
+	if err := CheckFinite(gc.Latitude, gc.Longitude, gc.Height); err != nil {
+		return nil, err
+	}
+	return PolarToCartesian(gc), nil
+}
+
+// Convert polar coordinates to Cartesian like PolarToCartesian, storing the result in p instead of allocating it.
+// Meant for tight loops of conversions, where p may be reused.
+func PolarToCartesianInto(gc *PolarCoord, p *CartPoint) {
+
+	el := gc.El
+
+	lat := degtorad(gc.Latitude)
+	long := degtorad(gc.Longitude)
+
+	esq := el.derived().esq
+
+	u := el.a / math.Sqrt(1-esq*math.Pow(math.Sin(lat), 2))
+
+	p.X = (gc.Height + u) * math.Cos(lat) * math.Cos(long)
+	p.Y = (gc.Height + u) * math.Cos(lat) * math.Sin(long)
+	p.Z = ((1-esq)*u + gc.Height) * math.Sin(lat)
+
+	p.El = el
+}
+
+// Convert Cartesian coordinates to polar.
+// The reference ellipsoid is copied verbatim to the result.
+// The resulting polar coordinates are in decimal degrees.
+// Inspired by http://www.movable-type.co.uk/scripts/latlong-convert-coords.html
+func CartesianToPolar(pt *CartPoint) *PolarCoord {
+	var gc PolarCoord
+	CartesianToPolarInto(pt, &gc)
+	return &gc
+}
+
+// Convert Cartesian coordinates to polar like CartesianToPolar, storing the result in gc instead of allocating it.
+// Meant for tight loops of conversions, where gc may be reused.
+func CartesianToPolarInto(pt *CartPoint, gc *PolarCoord) {
+
+	el := pt.El
+
+	esq := el.derived().esq
+	p := math.Hypot(pt.X, pt.Y)
+
+	lat := math.Atan2(pt.Z, p*(1-esq))
+	lat0 := 2.0 * math.Pi
+
+	precision := 4.0 / el.a
+	var v float64
+	for math.Abs(lat-lat0) > precision {
+		v = el.a / math.Sqrt(1-esq*math.Pow(math.Sin(lat), 2))
+
+		lat0 = lat
+		lat = math.Atan2(pt.Z+esq*v*math.Sin(lat), p)
+	}
+
+	// at the poles p/cos(lat) degenerates, where the height is derived from Z instead
+	if math.Abs(lat) < degtorad(89) {
+		gc.Height = p/math.Cos(lat) - v
+	} else {
+		v = el.a / math.Sqrt(1-esq*math.Pow(math.Sin(lat), 2))
+		gc.Height = pt.Z/math.Sin(lat) - v*(1-esq)
+	}
+	gc.Latitude = radtodeg(lat)
+	gc.Longitude = radtodeg(math.Atan2(pt.Y, pt.X))
+
+	gc.El = el
+}
+
+// ## Earth-centered, earth-fixed coordinates
+
+// Convert a WGS84 based latitude / longitude coordinate into earth-centered, earth-fixed (ECEF) coordinates,
+// as reported by GNSS receivers. X points from the earth's center to latitude 0° and longitude 0°, Y to
+// latitude 0° and longitude 90°E and Z to the north pole; all in meters. The height of gc is the height
+// above the WGS84 ellipsoid in meters.
+//
+// Important: The reference ellipsoid of gc will be assumed to be the WGS84Ellipsoid,
+// regardless of the actually set reference ellipsoid.
+func WGS84LatLongToECEF(gc *PolarCoord) *CartPoint {
+
+	p := *gc
+	p.El = WGS84Ellipsoid
+	return PolarToCartesian(&p)
+}
+
+// Convert earth-centered, earth-fixed (ECEF) coordinates in meters into a WGS84 based latitude / longitude
+// coordinate, with the height above the WGS84 ellipsoid in meters. This is the inverse of WGS84LatLongToECEF.
+//
+// Important: The reference ellipsoid of cp will be assumed to be the WGS84Ellipsoid,
+// regardless of the actually set reference ellipsoid.
+func ECEFToWGS84LatLong(cp *CartPoint) *PolarCoord {
+
+	p := *cp
+	p.El = WGS84Ellipsoid
+	return CartesianToPolar(&p)
+}
+
+// ## Local tangent plane coordinates
+
+// Rotates a geocentric Cartesian offset into the East-North-Up frame of the local tangent plane at
+// latitude lat and longitude long, or back, if inverse is set
+func enuRotation(d Point3D, lat, long float64, inverse bool) Point3D {
+
+	slat, clat := math.Sincos(degtorad(lat))
+	slong, clong := math.Sincos(degtorad(long))
+
+	if inverse {
+		return Point3D{
+			X: -slong*d.X - slat*clong*d.Y + clat*clong*d.Z,
+			Y: clong*d.X - slat*slong*d.Y + clat*slong*d.Z,
+			Z: clat*d.Y + slat*d.Z}
+	}
+	return Point3D{
+		X: -slong*d.X + clong*d.Y,
+		Y: -slat*clong*d.X - slat*slong*d.Y + clat*d.Z,
+		Z: clat*clong*d.X + clat*slong*d.Y + slat*d.Z}
+}
+
+// Convert local East-North-Up coordinates relative to the origin into a WGS84 based latitude / longitude
+// coordinate. X of enu is the distance east, Y the distance north and Z the distance up from the origin
+// in meters, measured in the plane tangent to the WGS84 ellipsoid at the origin.
+//
+// Important: The reference ellipsoid of the origin will be assumed to be the WGS84Ellipsoid,
+// regardless of the actually set reference ellipsoid.
+func ENUToWGS84(enu Point3D, origin *PolarCoord) *PolarCoord {
+
+	o := *origin
+	o.El = WGS84Ellipsoid
+	ocart := PolarToCartesian(&o)
+
+	d := enuRotation(enu, o.Latitude, o.Longitude, true)
+	return CartesianToPolar(&CartPoint{X: ocart.X + d.X, Y: ocart.Y + d.Y, Z: ocart.Z + d.Z, El: WGS84Ellipsoid})
+}
+
+// Convert a WGS84 based latitude / longitude coordinate into local East-North-Up coordinates relative to
+// the origin. This is the inverse of ENUToWGS84.
+//
+// Important: The reference ellipsoid of gc and the origin will be assumed to be the WGS84Ellipsoid,
+// regardless of the actually set reference ellipsoid.
+func WGS84ToENU(gc, origin *PolarCoord) Point3D {
+
+	o, p := *origin, *gc
+	o.El, p.El = WGS84Ellipsoid, WGS84Ellipsoid
+	ocart, pcart := PolarToCartesian(&o), PolarToCartesian(&p)
+
+	return enuRotation(Point3D{X: pcart.X - ocart.X, Y: pcart.Y - ocart.Y, Z: pcart.Z - ocart.Z}, o.Latitude, o.Longitude, false)
+}
+
+// Convert local North-East-Down coordinates relative to the origin into a WGS84 based latitude / longitude
+// coordinate. X of ned is the distance north, Y the distance east and Z the distance down from the origin
+// in meters, measured in the plane tangent to the WGS84 ellipsoid at the origin. The NED frame is the ENU
+// frame with the first two axes swapped and the third one negated, eg. a point 100m north, 20m west and
+// 5m above the origin is
+//
+//	NED: Point3D{X: 100, Y: -20, Z: -5}
+//	ENU: Point3D{X: -20, Y: 100, Z: 5}
+//
+// Important: The reference ellipsoid of the origin will be assumed to be the WGS84Ellipsoid,
+// regardless of the actually set reference ellipsoid.
+func NEDToWGS84(ned Point3D, origin *PolarCoord) *PolarCoord {
+	return ENUToWGS84(Point3D{X: ned.Y, Y: ned.X, Z: -ned.Z}, origin)
+}
+
+// Convert a WGS84 based latitude / longitude coordinate into local North-East-Down coordinates relative to
+// the origin. This is the inverse of NEDToWGS84.
+//
+// Important: The reference ellipsoid of gc and the origin will be assumed to be the WGS84Ellipsoid,
+// regardless of the actually set reference ellipsoid.
+func WGS84ToNED(gc, origin *PolarCoord) Point3D {
+	enu := WGS84ToENU(gc, origin)
+	return Point3D{X: enu.Y, Y: enu.X, Z: -enu.Z}
+}
+
+// ## Transverse Mercator Projection
+
+// Direct transverse mercator projection: Projection of an ellipsoid onto the surface of
+// of a cylinder. Also known as Gauss-Krüger projection. Input parameters:
+//
+//	gc *PolarCoord: Latitude and Longitude or point to be projected; in decimal degrees
+//	latO, longO: Shifted origin of latitude and longitude in decimal degrees
+//	fe, fn: False easting and northing respectively in meters
+//	scale: Projection scaling; Dimensionless, typically 1 or little bellow
+//
+// The northing is the scaled distance along the central meridian from the latitude of origin latO plus fn, so it
+// is negative south of latO without a false northing. The projection knows no hemispheres: a system like UTM,
+// which keeps the northings of the southern hemisphere positive, passes the false northing of the hemisphere of gc,
+// see Hemisphere. Negative false northings like the -5000000 of the BMN are part of the definition of a system and
+// shift the northing alike.
+//
+// This algorithm uses the algorithm described by Redfearn
+// http://en.wikipedia.org/wiki/Transverse_Mercator:_Redfearn_series
+//
+// Taken from "OGP Publication 373-7-2 – Surveying and Positioning Guidance Note number 7, part 2 – November 2010",
+// pp. 48 - 51
+func DirectTransverseMercator(gc *PolarCoord, latO, longO, scale, fe, fn float64) *GeoPoint {
+	var pt GeoPoint
+	directTransverseMercator(gc, latO, longO, scale, fe, fn, &pt)
+	return &pt
+}
+
+// Direct transverse mercator projection like DirectTransverseMercator, storing the result in pt instead of
+// allocating it. Meant for tight loops of conversions, where pt may be reused.
+func DirectTransverseMercatorInto(gc *PolarCoord, latO, longO, scale, fe, fn float64, pt *GeoPoint) {
+	directTransverseMercator(gc, latO, longO, scale, fe, fn, pt)
+}
+
+// Direct transverse mercator projection like DirectTransverseMercator, which additionally returns the grid
+// convergence and the point scale factor at gc:
+//
+//	convergence: Angle from true north to grid north in decimal degrees, positive clockwise. It is positive east
+//	  of the central meridian longO on the northern hemisphere, and negative west of it. The grid bearing of a
+//	  direction is its true bearing minus convergence.
+//	k: Ratio of a short distance on the map to the distance on the ellipsoid, including scale
+//
+// The factors are computed by the series of Krüger, as given by Karney, "Transverse Mercator with an accuracy of
+// a few nanometers", J. Geodesy 85(8), 2011, pp. 475 - 485, equations 10 and 11
+func DirectTransverseMercatorFactors(gc *PolarCoord, latO, longO, scale, fe, fn float64) (pt *GeoPoint, convergence, k float64) {
+	pt = &GeoPoint{}
+	convergence, k = directTransverseMercator(gc, latO, longO, scale, fe, fn, pt)
+	return
+}
+
+// Does the work of DirectTransverseMercatorFactors, storing the projected point in pt
+func directTransverseMercator(gc *PolarCoord, latO, longO, scale, fe, fn float64, pt *GeoPoint) (convergence, k float64) {
+
+	*pt = GeoPoint{}
+
+	el := gc.El
+
+	latOrad := degtorad(latO)
+	longOrad := degtorad(longO)
+
+	latrad := degtorad(gc.Latitude)
+	longrad := degtorad(gc.Longitude)
+
+	params := el.derived()
+	esq := params.e
+	B := params.tmB
+	h1, h2, h3, h4 := params.tmH[0], params.tmH[1], params.tmH[2], params.tmH[3]
+
+	var SO float64
+
+	if latOrad != 0.0 {
+		QO := math.Asinh(math.Tan(latOrad)) - (esq * math.Atanh(esq*math.Sin(latOrad)))
+		bO := math.Atan(math.Sinh(QO))
+		xiO0 := bO // math.Asin(math.Sin(bO))
+
+		xiO1 := h1 * math.Sin(2.0*xiO0)
+		xiO2 := h2 * math.Sin(4.0*xiO0)
+		xiO3 := h3 * math.Sin(6.0*xiO0)
+		xiO4 := h4 * math.Sin(8.0*xiO0)
+
+		xiO := xiO0 + xiO1 + xiO2 + xiO3 + xiO4
+
+		SO = B * xiO
+	}
+
+	Q := math.Asinh(math.Tan(latrad)) - (esq * math.Atanh(esq*math.Sin(latrad)))
+	b := math.Atan(math.Sinh(Q))
+
+	eta0 := math.Atanh(math.Cos(b) * math.Sin(longrad-longOrad))
+	xi0 := math.Asin(math.Sin(b) * math.Cosh(eta0))
+
+	xi1 := h1 * math.Sin(2*xi0) * math.Cosh(2*eta0)
+	xi2 := h2 * math.Sin(4*xi0) * math.Cosh(4*eta0)
+	xi3 := h3 * math.Sin(6*xi0) * math.Cosh(6*eta0)
+	xi4 := h4 * math.Sin(8*xi0) * math.Cosh(8*eta0)
+	xi := xi0 + xi1 + xi2 + xi3 + xi4
+
+	eta1 := h1 * math.Cos(2*xi0) * math.Sinh(2*eta0)
+	eta2 := h2 * math.Cos(4*xi0) * math.Sinh(4*eta0)
+	eta3 := h3 * math.Cos(6*xi0) * math.Sinh(6*eta0)
+	eta4 := h4 * math.Cos(8*xi0) * math.Sinh(8*eta0)
+	eta := eta0 + eta1 + eta2 + eta3 + eta4
+
+	pt.X = fe + scale*B*eta
+	pt.Y = fn + scale*(B*xi-SO)
+
+	pt.El = el
+
+	// derivatives of the series of xi and eta
+	p := 1 + 2*h1*math.Cos(2*xi0)*math.Cosh(2*eta0) + 4*h2*math.Cos(4*xi0)*math.Cosh(4*eta0) +
+		6*h3*math.Cos(6*xi0)*math.Cosh(6*eta0) + 8*h4*math.Cos(8*xi0)*math.Cosh(8*eta0)
+	q := 2*h1*math.Sin(2*xi0)*math.Sinh(2*eta0) + 4*h2*math.Sin(4*xi0)*math.Sinh(4*eta0) +
+		6*h3*math.Sin(6*xi0)*math.Sinh(6*eta0) + 8*h4*math.Sin(8*xi0)*math.Sinh(8*eta0)
+
+	dlong := longrad - longOrad
+	convergence = radtodeg(math.Atan2(math.Sin(b)*math.Sin(dlong), math.Cos(dlong)) + math.Atan2(q, p))
+
+	tanb := math.Tan(b)
+	tanlat := math.Tan(latrad)
+	k = scale * (B / el.a) * math.Sqrt(1+(1-esq*esq)*tanlat*tanlat) * math.Hypot(p, q) /
+		math.Sqrt(tanb*tanb+math.Cos(dlong)*math.Cos(dlong))
+
+	return
+}
+
+// Inverse transverse mercator projection: Projection of an cylinder onto the surface of
+// of an ellipsoid. Also known as reverse Gauss-Krüger projection. Input parameters:
+//
+//	pt *GeoPoint: Easting (X) and Northing (Y) of map point to be projected; in meters
+//	latO, longO: Shifted origin of latitude and longitude in decimal degrees
+//	fe, fn: False easting and northing respectively in meters
+//	scale: Projection scaling; Dimensionless, typically 1 or little bellow
+//
+// fn has to be the false northing pt was projected with by DirectTransverseMercator. The northing does not tell
+// the hemisphere, the northing 6245000 of UTM is 33.9°S with the false northing of the southern hemisphere and
+// 56.3°N without, so a system like UTM passes the false northing of the hemisphere given by its zone, see Hemisphere.
+//
+// This algorithm uses the algorithm described by Redfearn
+// http://en.wikipedia.org/wiki/Transverse_Mercator:_Redfearn_series
+//
+// Taken from "OGP Publication 373-7-2 – Surveying and Positioning Guidance Note number 7, part 2 – November 2010",
+// pp. 48 - 51
+//
+// More accurate, iterative but slower algorithmic implementation
+func InverseTransverseMercator(pt *GeoPoint, latO, longO, scale, fe, fn float64) *PolarCoord {
+	var gc PolarCoord
+	InverseTransverseMercatorInto(pt, latO, longO, scale, fe, fn, &gc)
+	return &gc
+}
+
+// Inverse transverse mercator projection like InverseTransverseMercator, storing the result in gc instead of
+// allocating it. Meant for tight loops of conversions, where gc may be reused.
+func InverseTransverseMercatorInto(pt *GeoPoint, latO, longO, scale, fe, fn float64, gc *PolarCoord) {
+
+	*gc = PolarCoord{}
+
+	el := pt.El
+
+	latOrad := degtorad(latO)
+	longOrad := degtorad(longO)
+
+	params := el.derived()
+	esq := params.e
+	B := params.tmB
+
+	var SO float64
+
+	if latOrad != 0.0 {
+
+		h1, h2, h3, h4 := params.tmSO[0], params.tmSO[1], params.tmSO[2], params.tmSO[3]
+
+		QO := math.Asinh(math.Tan(latOrad)) - (esq * math.Atanh(esq*math.Sin(latOrad)))
+		bO := math.Atan(math.Sinh(QO))
+		xiO0 := bO // math.Asin(math.Sin(bO))
+
+		xiO1 := h1 * math.Sin(2.0*xiO0)
+		xiO2 := h2 * math.Sin(4.0*xiO0)
+		xiO3 := h3 * math.Sin(6.0*xiO0)
+		xiO4 := h4 * math.Sin(8.0*xiO0)
+
+		xiO := xiO0 + xiO1 + xiO2 + xiO3 + xiO4
+
+		SO = B * xiO
+	}
+
+	h1i, h2i, h3i, h4i := params.tmHi[0], params.tmHi[1], params.tmHi[2], params.tmHi[3]
+
+	etai := (pt.X - fe) / (B * scale)
+	xii := ((pt.Y - fn) + scale*SO) / (B * scale)
+
+	xi1i := h1i * math.Sin(2*xii) * math.Cosh(2*etai)
+	xi2i := h2i * math.Sin(4*xii) * math.Cosh(4*etai)
+	xi3i := h3i * math.Sin(6*xii) * math.Cosh(6*etai)
+	xi4i := h4i * math.Sin(8*xii) * math.Cosh(8*etai)
+
+	eta1i := h1i * math.Cos(2*xii) * math.Sinh(2*etai)
+	eta2i := h2i * math.Cos(4*xii) * math.Sinh(4*etai)
+	eta3i := h3i * math.Cos(6*xii) * math.Sinh(6*etai)
+	eta4i := h4i * math.Cos(8*xii) * math.Sinh(8*etai)
+
+	xi0i := xii - (xi1i + xi2i + xi3i + xi4i)
+	eta0i := etai - (eta1i + eta2i + eta3i + eta4i)
+
+	bi := math.Asin(math.Sin(xi0i) / math.Cosh(eta0i))
+
+	Qi := math.Asinh(math.Tan(bi))
+	Qiiold := Qi + (esq * math.Atanh(esq*math.Tanh(Qi)))
+	Qii := Qi + (esq * math.Atanh(esq*math.Tanh(Qiiold)))
+
+	for math.Abs(Qiiold-Qii) > 1e-12 {
+		Qiiold = Qii
+		Qii = Qi + (esq * math.Atanh(esq*math.Tanh(Qiiold)))
+	}
+
+	gc.Latitude = radtodeg(math.Atan(math.Sinh(Qii)))
+	gc.Longitude = radtodeg(longOrad + math.Asin(math.Tanh(eta0i)/math.Cos(bi)))
+
+	gc.El = el
+}
+
+// ## Lambert Conformal Conic Projection
+
+// Returns the constants n, aF and rho0 of the Lambert conformal conic projection on the ellipsoid el with the
+// standard parallels lat1, lat2 and the latitude of origin latO, all in radians, together with the first
+// eccentricity e of el
+func lambertConstants(el *Ellipsoid, lat1, lat2, latO float64) (n, aF, rho0, e float64) {
+
+	e = el.derived().e
+
+	m1, t1 := lambertM(lat1, e), lambertT(lat1, e)
+	if lat1 == lat2 {
+		n = math.Sin(lat1)
+	} else {
+		n = (math.Log(m1) - math.Log(lambertM(lat2, e))) / (math.Log(t1) - math.Log(lambertT(lat2, e)))
+	}
+	aF = el.a * m1 / (n * math.Pow(t1, n))
+	rho0 = aF * math.Pow(lambertT(latO, e), n)
+	return
+}
+
+func lambertM(lat, e float64) float64 {
+	esinlat := e * math.Sin(lat)
+	return math.Cos(lat) / math.Sqrt(1-esinlat*esinlat)
+}
+
+func lambertT(lat, e float64) float64 {
+	esinlat := e * math.Sin(lat)
+	return math.Tan(math.Pi/4-lat/2) / math.Pow((1-esinlat)/(1+esinlat), e/2)
+}
+
+// Direct Lambert conformal conic projection: Projection of an ellipsoid onto the surface of a cone
+// intersecting the ellipsoid at two standard parallels. Input parameters:
+//
+//	gc *PolarCoord: Latitude and Longitude or point to be projected; in decimal degrees
+//	lat1, lat2: Standard parallels in decimal degrees. If they coincide, the cone touches the ellipsoid at this parallel
+//	latO, longO: Latitude and longitude of the origin in decimal degrees
+//	fe, fn: False easting and northing respectively in meters
+//
+// Taken from Snyder, "Map Projections - A Working Manual", USGS Professional Paper 1395, 1987, pp. 107 - 109
+func DirectLambertConformalConic(gc *PolarCoord, lat1, lat2, latO, longO, fe, fn float64) *GeoPoint {
+
+	el := gc.El
+	n, aF, rho0, e := lambertConstants(el, degtorad(lat1), degtorad(lat2), degtorad(latO))
+
+	rho := aF * math.Pow(lambertT(degtorad(gc.Latitude), e), n)
+	theta := n * math.Remainder(degtorad(gc.Longitude)-degtorad(longO), 2*math.Pi)
+
+	return &GeoPoint{X: fe + rho*math.Sin(theta), Y: fn + rho0 - rho*math.Cos(theta), El: el}
+}
+
+// Inverse Lambert conformal conic projection: Projection of a cone onto the surface of an ellipsoid.
+// Input parameters:
+//
+//	pt *GeoPoint: Easting(X) and Northing(Y) of map point to be projected; in meters
+//	lat1, lat2: Standard parallels in decimal degrees. If they coincide, the cone touches the ellipsoid at this parallel
+//	latO, longO: Latitude and longitude of the origin in decimal degrees
+//	fe, fn: False easting and northing respectively in meters
+//
+// Taken from Snyder, "Map Projections - A Working Manual", USGS Professional Paper 1395, 1987, pp. 107 - 109
+func InverseLambertConformalConic(pt *GeoPoint, lat1, lat2, latO, longO, fe, fn float64) *PolarCoord {
+
+	el := pt.El
+	n, aF, rho0, e := lambertConstants(el, degtorad(lat1), degtorad(lat2), degtorad(latO))
+
+	x, y := pt.X-fe, rho0-(pt.Y-fn)
+	if n < 0 {
+		x, y = -x, -y
+	}
+	rho := math.Copysign(math.Hypot(x, y), n)
+	theta := math.Atan2(x, y)
+	t := math.Pow(rho/aF, 1/n)
+
+	// iterate the latitude from the conformal latitude
+	lat := math.Pi/2 - 2*math.Atan(t)
+	for i := 0; i < 20; i++ {
+		esinlat := e * math.Sin(lat)
+		next := math.Pi/2 - 2*math.Atan(t*math.Pow((1-esinlat)/(1+esinlat), e/2))
+		if math.Abs(next-lat) < 1e-14 {
+			lat = next
+			break
+		}
+		lat = next
+	}
+
+	return &PolarCoord{Latitude: radtodeg(lat), Longitude: radtodeg(theta/n + degtorad(longO)), El: el}
+}
+
+// ## Polar Stereographic Projection
+
+// Returns the first eccentricity e of el and the factor relating the distance from the pole to the constant t of
+// the polar stereographic projection with the scale factor scale at the pole
+func polarStereographicConstants(el *Ellipsoid, scale float64) (e, f float64) {
+	e = el.derived().e
+	f = 2 * el.a * scale / math.Sqrt(math.Pow(1+e, 1+e)*math.Pow(1-e, 1-e))
+	return
+}
+
+// Direct polar stereographic projection: Projection of an ellipsoid onto the plane touching it at a pole.
+// Input parameters:
+//
+//	gc *PolarCoord: Latitude and Longitude or point to be projected; in decimal degrees
+//	north: Projection onto the plane at the north pole if true, at the south pole otherwise
+//	longO: Longitude of origin in decimal degrees, pointing from the pole to grid south at the north pole and to
+//	       grid north at the south pole
+//	scale: Scale factor at the pole
+//	fe, fn: False easting and northing of the pole respectively in meters
+//
+// Taken from "OGP Publication 373-7-2 – Surveying and Positioning Guidance Note number 7, part 2 – November 2010",
+// Polar Stereographic (Variant A)
+func DirectPolarStereographic(gc *PolarCoord, north bool, longO, scale, fe, fn float64) *GeoPoint {
+
+	el := gc.El
+	e, f := polarStereographicConstants(el, scale)
+
+	// the south pole is taken as the north pole of the mirrored ellipsoid
+	lat := degtorad(gc.Latitude)
+	if !north {
+		lat = -lat
+	}
+	rho := f * lambertT(lat, e)
+	theta := degtorad(gc.Longitude - longO)
+
+	pt := &GeoPoint{X: fe + rho*math.Sin(theta), Y: fn + rho*math.Cos(theta), El: el}
+	if north {
+		pt.Y = fn - rho*math.Cos(theta)
+	}
+	return pt
+}
+
+// Inverse polar stereographic projection: Projection of the plane touching an ellipsoid at a pole onto the
+// surface of the ellipsoid. The input parameters are the ones of DirectPolarStereographic, pt being Easting(X)
+// and Northing(Y) of map point to be projected in meters.
+//
+// Taken from "OGP Publication 373-7-2 – Surveying and Positioning Guidance Note number 7, part 2 – November 2010",
+// Polar Stereographic (Variant A)
+func InversePolarStereographic(pt *GeoPoint, north bool, longO, scale, fe, fn float64) *PolarCoord {
+
+	el := pt.El
+	e, f := polarStereographicConstants(el, scale)
+
+	x, y := pt.X-fe, pt.Y-fn
+	if north {
+		y = -y
+	}
+	chi := math.Pi/2 - 2*math.Atan(math.Hypot(x, y)/f)
+
+	// series of the latitude from the conformal latitude chi
+	e2 := e * e
+	e4, e6, e8 := e2*e2, e2*e2*e2, e2*e2*e2*e2
+	lat := chi + (e2/2+5*e4/24+e6/12+13*e8/360)*math.Sin(2*chi) +
+		(7*e4/48+29*e6/240+811*e8/11520)*math.Sin(4*chi) +
+		(7*e6/120+81*e8/1120)*math.Sin(6*chi) +
+		(4279*e8/161280)*math.Sin(8*chi)
+	if !north {
+		lat = -lat
+	}
+
+	// at the pole itself, atan2 yields the longitude of origin
+	return &PolarCoord{Latitude: radtodeg(lat), Longitude: longO + radtodeg(math.Atan2(x, y)), El: el}
+}
+
+// ## Web Mercator Projection
+
+// The latitude in decimal degrees at which the Web Mercator projection ends, making the map a square
+const WebMercatorMaxLatitude = 85.05112877980659
+
+// Projects a WGS84 latitude / longitude coordinate to Web Mercator (EPSG:3857), the projection of tile maps on the
+// web. Following the convention of web maps, the spherical formulas are applied on the semi-major axis of the
+// WGS84Ellipsoid, which is not conformal on the ellipsoid. The latitude is clamped to the range of
+// -WebMercatorMaxLatitude to WebMercatorMaxLatitude. The result carries easting (X) and northing (Y) in meters.
+//
+// Important: The reference ellipsoid of gc will be assumed to be the WGS84Ellipsoid, regardless of the actually
+// set reference ellipsoid.
+func WGS84LatLongToWebMercator(gc *PolarCoord) *GeoPoint {
+
+	r := WGS84Ellipsoid.a
+	lat := math.Max(-WebMercatorMaxLatitude, math.Min(WebMercatorMaxLatitude, gc.Latitude))
+
+	return &GeoPoint{
+		X:  r * degtorad(gc.Longitude),
+		Y:  r * math.Log(math.Tan(math.Pi/4+degtorad(lat)/2)),
+		El: WGS84Ellipsoid}
+}
+
+// Transforms a Web Mercator (EPSG:3857) easting (X) and northing (Y) in meters into a WGS84 latitude / longitude
+// coordinate. This is the inverse of WGS84LatLongToWebMercator.
+func WebMercatorToWGS84LatLong(pt *GeoPoint) *PolarCoord {
+
+	r := WGS84Ellipsoid.a
+	return &PolarCoord{
+		Latitude:  radtodeg(math.Atan(math.Sinh(pt.Y / r))),
+		Longitude: radtodeg(pt.X / r),
+		El:        WGS84Ellipsoid}
+}
+
+// Returns the column tileX from west to east and the row tileY from north to south of the map tile at zoom level
+// zoom containing the Web Mercator easting x and northing y in meters, following the tile scheme of web maps,
+// which divides the map into 2^zoom by 2^zoom tiles. Coordinates beyond the map are assigned to the tiles at its
+// edge. A zoom level below 0 is taken as 0, above 30 as 30.
+func WebMercatorToTile(x, y float64, zoom int) (tileX, tileY int) {
+
+	if zoom < 0 {
+		zoom = 0
+	} else if zoom > 30 {
+		zoom = 30
+	}
+
+	extent := math.Pi * WGS84Ellipsoid.a
+	n := float64(int(1) << uint(zoom))
+
+	tile := func(offset float64) int {
+		return int(math.Max(0, math.Min(n-1, math.Floor(offset/(2*extent)*n))))
+	}
+	return tile(x + extent), tile(extent - y)
+}
+
+// ## UTM coordinate functions for parsing and conversion
+
+// A UTM coordinate defined by Northin, Easting and relative origin by Zone
+// The reference ellipsoid is typically the GRS80Ellipsoid or the WGS84Ellipsoid
+// A zone of a single letter denotes a coordinate of UPS, see LatLongToUPS
+type UTMCoord struct {
+	Northing float64    `json:"northing" xml:"northing"`
+	Easting  float64    `json:"easting" xml:"easting"`
+	Zone     string     `json:"zone" xml:"zone"`
+	El       *Ellipsoid `json:"ellipsoid,omitempty" xml:"ellipsoid,omitempty"`
+}
+
+// Canonical representation of an UTM coordinate
+func (utm *UTMCoord) String() string {
+	return fmt.Sprintf("%s %.0f %.0f", utm.Zone, utm.Easting, utm.Northing)
+}
+
+// Representation of an UTM coordinate with precision decimal places of easting and northing, see FormatFixed
+func (utm *UTMCoord) Format(precision int) string {
+	return utm.Zone + " " + FormatFixed(utm.Easting, precision) + " " + FormatFixed(utm.Northing, precision)
+}
+
+// Returns a copy of the UTM coordinate with easting and northing snapped to the nearest multiple of spacingMeters,
+// see SnapToGrid. The zone is kept.
+func (utm *UTMCoord) Snap(spacingMeters float64) *UTMCoord {
+	snapped := *utm
+	snapped.Easting, snapped.Northing = SnapToGrid(utm.Easting, spacingMeters), SnapToGrid(utm.Northing, spacingMeters)
+	return &snapped
+}
+
+// Validates that the UTM coordinate lies within UTMExtent, or a UPS coordinate within UPSNorthExtent or
+// UPSSouthExtent. Returns the error of UTMToLatLong, if the zone is invalid, or an ExtentError, if easting and
+// northing transform to a location outside of the extent.
+func (utm *UTMCoord) Valid() error {
+	gc, err := UTMToLatLong(utm)
+	if err != nil {
+		return err
+	}
+	switch {
+	case !isUPSZone(utm.Zone):
+		return UTMExtent.Validate(gc, "UTM", utm.String())
+	case gc.Latitude > 0:
+		return UPSNorthExtent.Validate(gc, "UPS", utm.String())
+	}
+	return UPSSouthExtent.Validate(gc, "UPS", utm.String())
+}
+
+// Latitude bands of UTM from 80° S to 84° N. Bands C to M lie on the southern hemisphere.
+const utmBands = "CDEFGHJKLMNPQRSTUVWX"
+
+// Returns the EPSG code of the coordinate reference system of the UTM coordinate: a zone of ETRS89, if the
+// reference ellipsoid is the GRS80Ellipsoid and the zone is one used in Europe, otherwise a zone of WGS84.
+// UPS coordinates return UPS North or UPS South of WGS84. Returns 0 if the zone is invalid.
+func (utm *UTMCoord) EPSG() int {
+	if isUPSZone(utm.Zone) {
+		if strings.ToUpper(utm.Zone) >= "Y" {
+			return EPSGWGS84UPSNorth
+		}
+		return EPSGWGS84UPSSouth
+	}
+	zone, band, err := parseUTMZone(utm.Zone)
+	if err != nil {
+		return 0
+	}
+	switch {
+	case utm.El == GRS80Ellipsoid && band >= 'N' && zone >= 28 && zone <= 38:
+		return EPSGETRS89UTM + int(zone)
+	case band >= 'N':
+		return EPSGWGS84UTMNorth + int(zone)
+	}
+	return EPSGWGS84UTMSouth + int(zone)
+}
+
+// Parses a UTM zone specifier of zone number and latitude band, like "33T", and returns the zone number
+// and the latitude band in upper case. Returns ErrSyntax if the specifier is not of this form and ErrRange
+// if the zone number is not within 1 to 60 or the latitude band does not exist.
+func parseUTMZone(zone string) (uint, byte, error) {
+
+	if len(zone) < 2 {
+		return 0, 0, ErrSyntax
+	}
+
+	zonenumber, err := strconv.ParseUint(zone[:len(zone)-1], 10, 0)
+	if err != nil {
+		return 0, 0, ErrSyntax
+	}
+
+	band := strings.ToUpper(zone[len(zone)-1:])[0]
+	if zonenumber < 1 || zonenumber > 60 || strings.IndexByte(utmBands, band) < 0 {
+		return 0, 0, ErrRange
+	}
+	return uint(zonenumber), band, nil
+}
+
+// This function parses a string UTM coordinate literal of the format
+//
+//	"ZONE EASTING NORTHING"
+//
+// Zone is the UTM meridian zone specifier and must be specified in the unambiguous
+// way of zone number and latitude band. Easting and northing are specified as decimal meters.
+// A zone of UPS, one of the letters A, B, Y and Z, denotes a UPS coordinate.
+// If the reference ellipsoid is nil, the DefaultEllipsoid is assumed.
+//
+// returns ErrSyntax if the literal doesn't consist of zone, easting and northing separated by blanks
+// returns ErrRange if the zone number is not within 1 to 60 or the latitude band does not exist
+func AUTMToStruct(utmcoord string, el *Ellipsoid) (*UTMCoord, error) {
+
+	fields := strings.Fields(utmcoord)
+	if len(fields) != 3 {
+		return nil, ErrSyntax
+	}
+
+	var zone string
+	if isUPSZone(fields[0]) {
+		zone = strings.ToUpper(fields[0])
+	} else {
+		zonenumber, band, err := parseUTMZone(fields[0])
+		if err != nil {
+			return nil, err
+		}
+		zone = fmt.Sprintf("%d%c", zonenumber, band)
+	}
+
+	east, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return nil, err
+	}
+
+	north, err := strconv.ParseFloat(fields[2], 64)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = CheckFinite(east, north); err != nil {
+		return nil, err
+	}
+
+	if el == nil {
+		el = DefaultEllipsoid
+	}
+	return &UTMCoord{Northing: north, Easting: east, Zone: zone, El: el}, nil
+}
+
+// Convert from UTM 2D projection to 3D polar. If the UTM coordinates do not contain a
+// reference ellipsoid, the WGS84Ellipsoid is assumed and copied to the resulting polar coordinates.
+// UPS coordinates are converted by UPSToLatLong.
+// Function returns the error of parseUTMZone, if the zone is invalid.
+//
+// Inspired by http://www.gpsy.com/gpsinfo/geotoutm/gantz/LatLong-UTMconversion.cpp.txt
+func UTMToLatLong(coord *UTMCoord) (*PolarCoord, error) {
+
+	if err := CheckFinite(coord.Easting, coord.Northing); err != nil {
+		return nil, err
+	}
+
+	if isUPSZone(coord.Zone) {
+		return UPSToLatLong(coord)
+	}
+
+	zonenumber, band, err := parseUTMZone(coord.Zone)
+	if err != nil {
+		return nil, err
+	}
+
+	pt := &GeoPoint{Y: coord.Northing, X: coord.Easting, El: coord.El}
+
+	if pt.El == nil {
+		pt.El = DefaultEllipsoid
+	}
+
+	gc := InverseTransverseMercator(pt, 0, utmCentralMeridian(zonenumber), 0.9996, utmFalseEasting,
+		UTMBandHemisphere(band).UTMFalseNorthing())
+
+	return gc, nil
+}
+
+// Normalizes a longitude into the interval [-180°, 180°). The longitude 180° is therefore mapped to -180°.
+func normalizeLongitude(long float64) float64 {
+	long = math.Mod(long+180, 360)
+	if long < 0 {
+		long += 360
+	}
+	return long - 180
+}
+
+// Returns the UTM meridian zone number of a latitude / longitude coordinate, honouring the exceptions
+// of southern Norway (zone 32V) and Svalbard (zones 31X, 33X, 35X and 37X).
+//
+// Zones span 6° of longitude, zone 1 starting at -180°. A coordinate located exactly on the boundary
+// of two zones falls into the eastern zone, the one starting at this boundary. The dateline
+// at ±180° therefore falls into zone 1, as does every longitude which normalizes to -180°.
+// The same rule applies to the boundaries of the exceptional zones.
+func UTMZone(gc *PolarCoord) uint {
+
+	long := normalizeLongitude(gc.Longitude)
+	zonenumber := uint(math.Floor((long+180)/6)) + 1
+
+	if gc.Latitude >= 56.0 && gc.Latitude < 64.0 && long >= 3.0 && long < 12.0 {
+		zonenumber = 32
+	}
+
+	if gc.Latitude >= 72.0 && gc.Latitude < 84.0 {
+		switch {
+		case long >= 0.0 && long < 9.0:
+			zonenumber = 31
+		case long >= 9.0 && long < 21.0:
+			zonenumber = 33
+		case long >= 21.0 && long < 33.0:
+			zonenumber = 35
+		case long >= 33.0 && long < 42.0:
+			zonenumber = 37
+		}
+	}
+
+	return zonenumber
+}
+
+// False easting of every UTM meridian zone, the easting of its central meridian
+const utmFalseEasting = 500000
+
+// The hemisphere of a transverse mercator projection like UTM, whose false northing differs between the hemispheres
+type Hemisphere byte
+
+const (
+	HemisphereNorth Hemisphere = iota
+	HemisphereSouth
+)
+
+// False northing of UTM on the southern hemisphere, so the northings of the southern hemisphere are positive and
+// decrease from 10000000 m at the equator. UTM on the northern hemisphere has no false northing.
+const UTMFalseNorthingSouth = 10000000
+
+// Returns the hemisphere of latitude. The equator belongs to the northern hemisphere, as the latitude bands of UTM.
+func HemisphereOf(latitude float64) Hemisphere {
+	if latitude < 0 {
+		return HemisphereSouth
+	}
+	return HemisphereNorth
+}
+
+// Returns the false northing of UTM on the hemisphere, 0 on the northern and UTMFalseNorthingSouth on the southern
+// hemisphere, to be passed as fn to DirectTransverseMercator and InverseTransverseMercator
+func (h Hemisphere) UTMFalseNorthing() float64 {
+	if h == HemisphereSouth {
+		return UTMFalseNorthingSouth
+	}
+	return 0
+}
+
+func (h Hemisphere) String() string {
+	if h == HemisphereSouth {
+		return "S"
+	}
+	return "N"
+}
+
+// Returns the hemisphere of a latitude band of UTM or MGRS, the southern one for the bands C to M
+func UTMBandHemisphere(band byte) Hemisphere {
+	if band < 'N' {
+		return HemisphereSouth
+	}
+	return HemisphereNorth
+}
+
+// Returns the longitude of the central meridian of the UTM meridian zone zonenumber
+func utmCentralMeridian(zonenumber uint) float64 {
+	return (float64(zonenumber)-1)*6 - 180 + 3
+}
+
+// Returns the longitude of the central meridian and the false easting of the UTM meridian zone zonenumber.
+// Function returns ErrRange, if zonenumber is not within 1 to 60.
+func UTMZoneParameters(zonenumber uint) (long0, fe float64, err error) {
+	if zonenumber < 1 || zonenumber > 60 {
+		return 0, 0, ErrRange
+	}
+	return utmCentralMeridian(zonenumber), utmFalseEasting, nil
+}
+
+// Returns the western and eastern boundary of longitude of the UTM meridian zone zonenumber. As by UTMZone,
+// the western boundary belongs to the zone and the eastern boundary to the next zone. The exceptional zones
+// of southern Norway and Svalbard are not taken into account. Function returns ErrRange, if zonenumber is not
+// within 1 to 60.
+func UTMZoneLongitudes(zonenumber uint) (west, east float64, err error) {
+	if zonenumber < 1 || zonenumber > 60 {
+		return 0, 0, ErrRange
+	}
+	long0 := utmCentralMeridian(zonenumber)
+	return long0 - 3, long0 + 3, nil
+}
+
+// Returns the area covered by the UTM meridian zone zonenumber as closed ring of coordinates, as by
+// LatLongExtent.Polygon, bounded by the longitudes of UTMZoneLongitudes and the latitudes of UTMExtent. The
+// bounds of a UTM zone are meridians and parallels of WGS84, the datum of UTM, so the boundary is traced in
+// latitude and longitude and every edge is divided into segments parts. Function returns ErrRange, if
+// zonenumber is not within 1 to 60 or segments is less than 1.
+func UTMZonePolygon(zonenumber uint, segments int) ([]*PolarCoord, error) {
+	west, east, err := UTMZoneLongitudes(zonenumber)
+	if err != nil {
+		return nil, err
+	}
+	return (&LatLongExtent{MinLat: UTMExtent.MinLat, MaxLat: UTMExtent.MaxLat, MinLong: west, MaxLong: east}).Polygon(segments)
+}
+
+// Convert from 3D polar to UTM 2D projection. If the polar coordinates do not contain a
+// reference ellipsoid, the WGS84Ellipsoid is assumed and copied to the resulting UTM coordinates.
+// The zone is selected by UTMZone.
+//
+// Inspired by http://www.gpsy.com/gpsinfo/geotoutm/gantz/LatLong-UTMconversion.cpp.txt
+//
+// The coordinate is not checked to be finite, a NaN or infinite coordinate yields a meaningless UTM coordinate, see
+// LatLongToUTMChecked.
+func LatLongToUTM(gcin *PolarCoord) *UTMCoord {
+	return latLongToUTMZone(gcin, UTMZone(gcin))
+}
+
+// Convert from 3D polar to UTM 2D projection like LatLongToUTM. Function returns ErrNotFinite, if latitude or
+// longitude of gc is NaN or infinite.
+func LatLongToUTMChecked(gc *PolarCoord) (*UTMCoord, error) {
+	if err := gc.CheckFinite(); err != nil {
+		return nil, err
+	}
+	return LatLongToUTM(gc), nil
+}
+
+// Convert from 3D polar to UTM 2D projection in the meridian zone zonenumber, regardless of the zone
+// the coordinate would naturally fall into. National grids based on UTM often extend a single zone
+// across their territory. Function returns ErrRange if zonenumber is not within 1 to 60.
+// If the polar coordinates do not contain a reference ellipsoid, the DefaultEllipsoid is assumed.
+func LatLongToUTMZone(gc *PolarCoord, zonenumber uint) (*UTMCoord, error) {
+	if zonenumber < 1 || zonenumber > 60 {
+		return nil, ErrRange
+	}
+	if err := gc.CheckFinite(); err != nil {
+		return nil, err
+	}
+	return latLongToUTMZone(gc, zonenumber), nil
+}
+
+// Project a polar coordinate into the UTM meridian zone zonenumber, regardless of the zone the
+// coordinate would naturally fall into.
+func latLongToUTMZone(gcin *PolarCoord, zonenumber uint) *UTMCoord {
+
+	var utm UTMCoord
+
+	gc := *gcin
+	if gc.El == nil {
+		gc.El = DefaultEllipsoid
+	}
+
+	// Measure the longitude relative to the central meridian of the zone, so points beyond the
+	// dateline project correctly into zones 1 and 60.
+	longO := utmCentralMeridian(zonenumber)
+	gc.Longitude = longO + normalizeLongitude(gc.Longitude-longO)
+
+	pt := DirectTransverseMercator(&gc, 0, longO, 0.9996, utmFalseEasting, HemisphereOf(gc.Latitude).UTMFalseNorthing())
+
+	utm.Zone = strconv.FormatUint(uint64(zonenumber), 10) + string(utmLetterDesignator(gc.Latitude))
+	utm.Northing = pt.Y
+	utm.Easting = pt.X
+
+	utm.El = pt.El
+
+	return &utm
+}
+
+// This routine determines the correct UTM letter designator for the given latitude
+// returns 'Z' if latitude is outside the UTM limits of 84N to 80S
+func utmLetterDesignator(Lat float64) (LetterDesignator byte) {
+
+	switch {
+	case 84 >= Lat && Lat >= 72:
+		LetterDesignator = 'X'
+	case 72 > Lat && Lat >= 64:
+		LetterDesignator = 'W'
+	case 64 > Lat && Lat >= 56:
+		LetterDesignator = 'V'
+	case 56 > Lat && Lat >= 48:
+		LetterDesignator = 'U'
+	case 48 > Lat && Lat >= 40:
+		LetterDesignator = 'T'
+	case 40 > Lat && Lat >= 32:
+		LetterDesignator = 'S'
+	case 32 > Lat && Lat >= 24:
+		LetterDesignator = 'R'
+	case 24 > Lat && Lat >= 16:
+		LetterDesignator = 'Q'
+	case 16 > Lat && Lat >= 8:
+		LetterDesignator = 'P'
+	case 8 > Lat && Lat >= 0:
+		LetterDesignator = 'N'
+	case 0 > Lat && Lat >= -8:
+		LetterDesignator = 'M'
+	case -8 > Lat && Lat >= -16:
+		LetterDesignator = 'L'
+	case -16 > Lat && Lat >= -24:
+		LetterDesignator = 'K'
+	case -24 > Lat && Lat >= -32:
+		LetterDesignator = 'J'
+	case -32 > Lat && Lat >= -40:
+		LetterDesignator = 'H'
+	case -40 > Lat && Lat >= -48:
+		LetterDesignator = 'G'
+	case -48 > Lat && Lat >= -56:
+		LetterDesignator = 'F'
+	case -56 > Lat && Lat >= -64:
+		LetterDesignator = 'E'
+	case -64 > Lat && Lat >= -72:
+		LetterDesignator = 'D'
+	case -72 > Lat && Lat >= -80:
+		LetterDesignator = 'C'
+	default:
+		LetterDesignator = 'Z' //  error flag to show that the Latitude is outside the UTM limits
+	}
+	return
+}
+
+// ## UPS coordinate functions
+
+// The Universal Polar Stereographic system (UPS) maps the polar regions not covered by UTM, north of 84°N and
+// south of 80°S. UPS coordinates are represented by UTMCoord, the zone being a single letter: A and B around the
+// south pole, Y and Z around the north pole, A and Y west of the meridian of Greenwich, B and Z east of it.
+const (
+	upsScale         = 0.994
+	upsFalseEasting  = 2000000
+	upsFalseNorthing = 2000000
+)
+
+// Returns true, if zone is one of the UPS zones A, B, Y and Z
+func isUPSZone(zone string) bool {
+	return len(zone) == 1 && strings.IndexByte("ABYZ", strings.ToUpper(zone)[0]) >= 0
+}
+
+// Returns true, if the coordinate is one of UPS instead of UTM
+func (utm *UTMCoord) IsUPS() bool {
+	return isUPSZone(utm.Zone)
+}
+
+// Implements Coordinate, the system is "ups" for a coordinate of UPS, otherwise "utm"
+func (utm *UTMCoord) System() string {
+	if utm.IsUPS() {
+		return "ups"
+	}
+	return "utm"
+}
+
+// Implements Coordinate, see UTMToLatLong. Returns ErrUnsupported for a reference ellipsoid other than the
+// WGS84Ellipsoid or the GRS80Ellipsoid, like the one of ED50, see PolarCoord.ToWGS84.
+func (utm *UTMCoord) ToWGS84() (*PolarCoord, error) {
+	gc, err := UTMToLatLong(utm)
+	if err != nil {
+		return nil, err
+	}
+	return gc.ToWGS84()
+}
+
+// Convert from 3D polar to UPS 2D projection, at the pole of the hemisphere of the coordinate. If the polar
+// coordinates do not contain a reference ellipsoid, the DefaultEllipsoid is assumed and copied to the resulting
+// UPS coordinates. Coordinates of the whole hemisphere are projected, regardless of UPSNorthExtent and
+// UPSSouthExtent.
+func LatLongToUPS(gcin *PolarCoord) *UTMCoord {
+
+	gc := *gcin
+	if gc.El == nil {
+		gc.El = DefaultEllipsoid
+	}
+
+	north := gc.Latitude >= 0
+	pt := DirectPolarStereographic(&gc, north, 0, upsScale, upsFalseEasting, upsFalseNorthing)
+
+	west := normalizeLongitude(gc.Longitude) < 0
+	zone := map[[2]bool]string{{true, true}: "Y", {true, false}: "Z", {false, true}: "A", {false, false}: "B"}[[2]bool{north, west}]
+
+	return &UTMCoord{Northing: pt.Y, Easting: pt.X, Zone: zone, El: gc.El}
+}
+
+// Convert from UPS 2D projection to 3D polar. If the UPS coordinates do not contain a reference ellipsoid, the
+// DefaultEllipsoid is assumed and copied to the resulting polar coordinates. Function returns ErrRange, if the
+// zone is not one of the UPS zones A, B, Y and Z.
+func UPSToLatLong(coord *UTMCoord) (*PolarCoord, error) {
+
+	if !isUPSZone(coord.Zone) {
+		return nil, ErrRange
+	}
+	if err := CheckFinite(coord.Easting, coord.Northing); err != nil {
+		return nil, err
+	}
+
+	pt := &GeoPoint{X: coord.Easting, Y: coord.Northing, El: coord.El}
+	if pt.El == nil {
+		pt.El = DefaultEllipsoid
+	}
+
+	north := strings.ToUpper(coord.Zone) >= "Y"
+	return InversePolarStereographic(pt, north, 0, upsScale, upsFalseEasting, upsFalseNorthing), nil
+}
+
+// Convert from 3D polar to UTM 2D projection within UTMExtent, and to UPS in the polar regions beyond. The zone of
+// UTM is selected by UTMZone, the one of UPS by the hemisphere and the side of the meridian of Greenwich.
+func LatLongToUTMUPS(gc *PolarCoord) *UTMCoord {
+	if gc.Latitude >= UTMExtent.MinLat && gc.Latitude <= UTMExtent.MaxLat {
+		return LatLongToUTM(gc)
+	}
+	return LatLongToUPS(gc)
+}
+
+// ## UTM grid cells
+
+// Returns the identifier of the square grid cell of resolution meters edge length which contains the
+// UTM coordinate. The identifier is the canonical representation of the south-west corner of the cell,
+// eg. "33T 425000 5268000" for a resolution of 1000 meters. Returns ErrRange if resolution is not positive.
+func UTMGridCell(coord *UTMCoord, resolution float64) (string, error) {
+	if !(resolution > 0) {
+		return "", ErrRange
+	}
+	if err := CheckFinite(coord.Easting, coord.Northing); err != nil {
+		return "", err
+	}
+
+	corner := &UTMCoord{
+		Northing: math.Floor(coord.Northing/resolution) * resolution,
+		Easting:  math.Floor(coord.Easting/resolution) * resolution,
+		Zone:     coord.Zone}
+	return corner.String(), nil
+}
+
+// Groups UTM coordinates by the grid cell of resolution meters edge length they lie within. The result maps
+// the identifier of a cell as returned by UTMGridCell to the coordinates within this cell, in the order they
+// appear in coords. Nil coordinates, eg. of failed conversions, are skipped.
+// Returns ErrRange if resolution is not positive.
+func GroupUTMByGridCell(coords []*UTMCoord, resolution float64) (map[string][]*UTMCoord, error) {
+	if !(resolution > 0) {
+		return nil, ErrRange
+	}
+
+	cells := make(map[string][]*UTMCoord)
+	for _, coord := range coords {
+		if coord == nil {
+			continue
+		}
+		cell, _ := UTMGridCell(coord, resolution)
+		cells[cell] = append(cells[cell], coord)
+	}
+	return cells, nil
+}
+
+// ## Spanish UTM on ETRS89
+
+// Extent of the Spanish territory, divided into the peninsula including the Balearic Islands,
+// Ceuta and Melilla and the Canary Islands. Values are in decimal degrees.
+const (
+	spainMinLat, spainMaxLat   = 35.0, 44.0
+	spainMinLong, spainMaxLong = -9.5, 4.5
+
+	canariesMinLat, canariesMaxLat   = 27.5, 29.5
+	canariesMinLong, canariesMaxLong = -18.5, -13.0
+)
+
+// Returns the UTM meridian zone used by the Spanish national cartography for a latitude / longitude
+// coordinate. The peninsula and the Balearic Islands fall into the zones 29, 30 and 31 according to
+// the regular 6° zone rule. The Canary Islands are, as defined by the IGN, entirely mapped in zone 28,
+// although the western part of El Hierro would regularly fall into zone 27.
+//
+// Returns ErrRange if the coordinate lies outside of Spain.
+func SpanishUTMZone(gc *PolarCoord) (uint, error) {
+
+	if err := gc.CheckFinite(); err != nil {
+		return 0, err
+	}
+
+	switch {
+	case gc.Latitude >= canariesMinLat && gc.Latitude <= canariesMaxLat &&
+		gc.Longitude >= canariesMinLong && gc.Longitude <= canariesMaxLong:
+		return 28, nil
+	case gc.Latitude >= spainMinLat && gc.Latitude <= spainMaxLat &&
+		gc.Longitude >= spainMinLong && gc.Longitude <= spainMaxLong:
+		return UTMZone(gc), nil
+	}
+	return 0, ErrRange
+}
+
+// Transform a latitude / longitude coordinate into an UTM coordinate of the Spanish national
+// cartography, selecting the zone by SpanishUTMZone. Function returns ErrRange, if the coordinate
+// lies outside of Spain.
+//
+// The datum of the Spanish UTM grid is ETRS89, which is treated as identical to WGS84. No datum shift
+// is applied, the reference ellipsoid of the resulting UTM coordinate is the GRS80Ellipsoid.
+func LatLongToSpanishUTM(gc *PolarCoord) (*UTMCoord, error) {
+
+	zonenumber, err := SpanishUTMZone(gc)
+	if err != nil {
+		return nil, err
+	}
+
+	etrs := *gc
+	etrs.El = GRS80Ellipsoid
+
+	return latLongToUTMZone(&etrs, zonenumber), nil
+}
+
+// Transform a Spanish UTM coordinate on ETRS89 into latitude and longitude. Function returns ErrRange,
+// if the zone of the UTM coordinate is not one of the zones 28 to 31 used in Spain.
+//
+// The reference ellipsoid is always the GRS80Ellipsoid, regardless of the actually set reference ellipsoid.
+func SpanishUTMToLatLong(coord *UTMCoord) (*PolarCoord, error) {
+
+	if err := CheckFinite(coord.Easting, coord.Northing); err != nil {
+		return nil, err
+	}
+
+	zonelength := len(coord.Zone)
+	if zonelength < 2 {
+		return nil, ErrSyntax
+	}
+
+	zonenumber, err := strconv.ParseUint(coord.Zone[:zonelength-1], 10, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if zonenumber < 28 || zonenumber > 31 {
+		return nil, ErrRange
+	}
+
+	etrs := *coord
+	etrs.El = GRS80Ellipsoid
+
+	return UTMToLatLong(&etrs)
+}
+
+// ## Norwegian NTM on EUREF89
+
+// Extent of mainland Norway, in which the NTM zones are defined. Values are in decimal degrees.
+var NTMExtent = &LatLongExtent{MinLat: 57.5, MaxLat: 71.5, MinLong: 4.0, MaxLong: 31.5}
+
+// The NTM zones 5 to 30 are transverse mercator projections of bands one degree wide. The central meridian
+// of a zone lies half a degree east of its number, eg. at 10.5° E for zone 10.
+const (
+	NTMMinZone = 5
+	NTMMaxZone = 30
+
+	// EPSG code of the NTM zones, offset by the number of the zone, eg. 5110 for "ETRS89 / NTM zone 10"
+	EPSGEUREF89NTM = 5100
+
+	ntmLatO, ntmScale                 = 58, 1
+	ntmFalseEasting, ntmFalseNorthing = 100000, 1000000
+)
+
+// A coordinate of the Norwegian NTM (Norsk Transversal Mercator) grid, used for cadastral surveys.
+// The reference ellipsoid is the GRS80Ellipsoid of EUREF89.
+type NTMCoord struct {
+	Northing float64    `json:"northing" xml:"northing"`
+	Easting  float64    `json:"easting" xml:"easting"`
+	Zone     uint       `json:"zone" xml:"zone"`
+	El       *Ellipsoid `json:"ellipsoid,omitempty" xml:"ellipsoid,omitempty"`
+}
+
+// Canonical representation of an NTM coordinate, with a resolution of millimeters
+func (ntm *NTMCoord) String() string {
+	return fmt.Sprintf("NTM%d %.3f %.3f", ntm.Zone, ntm.Easting, ntm.Northing)
+}
+
+// Implements Coordinate, the system of an NTM coordinate is "ntm"
+func (ntm *NTMCoord) System() string {
+	return "ntm"
+}
+
+// Implements Coordinate, the EPSG code of the zone of EUREF89 NTM, 0 if the zone is invalid
+func (ntm *NTMCoord) EPSG() int {
+	if ntm.Zone < NTMMinZone || ntm.Zone > NTMMaxZone {
+		return 0
+	}
+	return EPSGEUREF89NTM + int(ntm.Zone)
+}
+
+// Implements Coordinate, see NTMToLatLong. The GRS80Ellipsoid of EUREF89 is taken as WGS84.
+func (ntm *NTMCoord) ToWGS84() (*PolarCoord, error) {
+	gc, err := NTMToLatLong(ntm)
+	if err != nil {
+		return nil, err
+	}
+	return gc.ToWGS84()
+}
+
+// Representation of an NTM coordinate with precision decimal places of easting and northing, see FormatFixed
+func (ntm *NTMCoord) Format(precision int) string {
+	return fmt.Sprintf("NTM%d %s %s", ntm.Zone, FormatFixed(ntm.Easting, precision), FormatFixed(ntm.Northing, precision))
+}
+
+// Returns a copy of the NTM coordinate with easting and northing snapped to the nearest multiple of spacingMeters,
+// see SnapToGrid
+func (ntm *NTMCoord) Snap(spacingMeters float64) *NTMCoord {
+	snapped := *ntm
+	snapped.Easting, snapped.Northing = SnapToGrid(ntm.Easting, spacingMeters), SnapToGrid(ntm.Northing, spacingMeters)
+	return &snapped
+}
+
+// Returns the NTM zone of a latitude / longitude coordinate, which is the number of the degree of longitude.
+// Mainland Norway west of 5° E belongs to zone 5, east of 31° E to zone 30.
+//
+// Returns ErrRange if the coordinate lies outside of the NTMExtent.
+func NTMZone(gc *PolarCoord) (uint, error) {
+
+	if err := gc.CheckFinite(); err != nil {
+		return 0, err
+	}
+	if !NTMExtent.Contains(gc) {
+		return 0, ErrRange
+	}
+
+	zone := uint(math.Floor(gc.Longitude))
+	switch {
+	case zone < NTMMinZone:
+		zone = NTMMinZone
+	case zone > NTMMaxZone:
+		zone = NTMMaxZone
+	}
+	return zone, nil
+}
+
+// Transform a latitude / longitude coordinate into an NTM coordinate, selecting the zone by NTMZone.
+// Function returns ErrRange, if the coordinate lies outside of the NTMExtent.
+//
+// EUREF89 is treated as identical to WGS84. No datum shift is applied, the reference ellipsoid of the
+// resulting NTM coordinate is the GRS80Ellipsoid.
+func LatLongToNTM(gc *PolarCoord) (*NTMCoord, error) {
+
+	zone, err := NTMZone(gc)
+	if err != nil {
+		return nil, err
+	}
+	return LatLongToNTMZone(gc, zone)
+}
+
+// Transform a latitude / longitude coordinate into an NTM coordinate of the given zone, which may differ
+// from the zone selected by NTMZone, eg. to map the area of a project in a single zone. Function returns
+// ErrRange, if the zone is not one of the zones 5 to 30 or the coordinate lies outside of the NTMExtent.
+func LatLongToNTMZone(gc *PolarCoord, zone uint) (*NTMCoord, error) {
+
+	if err := gc.CheckFinite(); err != nil {
+		return nil, err
+	}
+	if zone < NTMMinZone || zone > NTMMaxZone || !NTMExtent.Contains(gc) {
+		return nil, ErrRange
+	}
+
+	etrs := *gc
+	etrs.El = GRS80Ellipsoid
+
+	gp := DirectTransverseMercator(&etrs, ntmLatO, float64(zone)+0.5, ntmScale, ntmFalseEasting, ntmFalseNorthing)
+	return &NTMCoord{Easting: gp.X, Northing: gp.Y, Zone: zone, El: GRS80Ellipsoid}, nil
+}
+
+// Transform an NTM coordinate into latitude and longitude. Function returns ErrRange, if the zone is not one
+// of the zones 5 to 30.
+//
+// The reference ellipsoid is always the GRS80Ellipsoid, regardless of the actually set reference ellipsoid.
+func NTMToLatLong(coord *NTMCoord) (*PolarCoord, error) {
+
+	if coord.Zone < NTMMinZone || coord.Zone > NTMMaxZone {
+		return nil, ErrRange
+	}
+	if err := CheckFinite(coord.Easting, coord.Northing); err != nil {
+		return nil, err
+	}
+
+	gp := &GeoPoint{X: coord.Easting, Y: coord.Northing, El: GRS80Ellipsoid}
+	return InverseTransverseMercator(gp, ntmLatO, float64(coord.Zone)+0.5, ntmScale, ntmFalseEasting, ntmFalseNorthing), nil
+}
+
+// Base32 codeset for geohash as described in http://en.wikipedia.org/wiki/Geohash
+var Base32GeohashCode = []byte("0123456789bcdefghjkmnpqrstuvwxyz")
+
+// The following functions deal with geohash encoding & decoding as described in http://en.wikipedia.org/wiki/Geohash
+// Inspiration taken from
+// - http://code.google.com/p/geospatialweb/source/browse/trunk/geohash/src/Geohash.java
+// - http://blog.dixo.net/downloads/geohash-php-class/
+// - https://github.com/kungfoo/geohsh-java/blob/master/src/main/java/ch/hsr/geohash/GeoHash.java
+// - https://github.com/broady/gogeohash/blob/master/geohash.go
+
+// Returns the latitude and longitude ranges of the cell of a geohash-encoded string, halving them bit by bit.
+// Returns ErrRange if the string contains a character which is not within the geohash code set.
+func geoHashRanges(geohash string) (latrange, longrange [2]float64, err error) {
+
+	latrange = [2]float64{-90, 90}
+	longrange = [2]float64{-180, 180}
+
+	bytehash := []byte(geohash)
+	even := true
+
+	for _, r := range bytehash {
+		i := bytes.IndexByte(Base32GeohashCode, r)
+		if i < 0 {
+			return latrange, longrange, ErrRange
+		}
+		for j := 16; j != 0; j >>= 1 {
+			var index int
+			if i&j == 0 {
+				index = 1
+			} else {
+				index = 0
+			}
+
+			if even {
+				longrange[index] = (longrange[0] + longrange[1]) / 2.0
+			} else {
+				latrange[index] = (latrange[0] + latrange[1]) / 2.0
+			}
+			even = !even
+		}
+	}
+	return latrange, longrange, nil
+}
+
+// Return latitude & longitude from a geohash-encoded string.
+// If the reference ellipsoid is nil, the default Ellipsoid will be returned.
+// If the string is not a geohash, err will be set to ERRRANGE.
+func GeoHashToLatLong(geohash string, el *Ellipsoid) (*PolarCoord, error) {
+
+	latrange, longrange, err := geoHashRanges(geohash)
+	if err != nil {
+		return nil, err
+	}
+
+	errlat := (latrange[1] - latrange[0]) / 2
+	errlong := (longrange[1] - longrange[0]) / 2
+
+	if el == nil {
+		el = DefaultEllipsoid
+	}
+
+	return &PolarCoord{
+			Latitude:  round((latrange[0]+latrange[1])/2.0, int(math.Max(1.0, -round(math.Log10(errlat), 0))-1)),
+			Longitude: round((longrange[0]+longrange[1])/2.0, int(math.Max(1.0, -round(math.Log10(errlong), 0))-1)),
+			El:        el},
+		nil
+}
+
+// Return the south-western and north-eastern corner of the cell of a geohash-encoded string.
+// If the reference ellipsoid is nil, the default Ellipsoid will be returned.
+// If the string is not a geohash, err will be set to ERRRANGE.
+func GeoHashToBBox(geohash string, el *Ellipsoid) (sw, ne *PolarCoord, err error) {
+
+	latrange, longrange, err := geoHashRanges(geohash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if el == nil {
+		el = DefaultEllipsoid
+	}
+	return &PolarCoord{Latitude: latrange[0], Longitude: longrange[0], El: el}, &PolarCoord{Latitude: latrange[1], Longitude: longrange[1], El: el}, nil
+}
+
+// a general purpose round
+func round(x float64, prec int) float64 {
+
+	var rounder float64
+	pow := math.Pow(10, float64(prec))
+	intermed := x * pow
+	_, frac := math.Modf(intermed)
+	x = .5
+	if frac < 0.0 {
+		x = -.5
+	}
+	if frac >= x {
+		rounder = math.Ceil(intermed)
+	} else {
+		rounder = math.Floor(intermed)
+	}
+
+	return rounder / pow
+}
+
+// Returns precision of number.
+// precision of 42 is 0.5
+// precision of 42.4 is 0.05
+// precision of 42.41 is 0.005 etc
+func precision(val float64) float64 {
+	str := strconv.FormatFloat(val, 'f', -1, 64)
+	pos := strings.IndexRune(str, '.')
+	if pos == -1 {
+		return 0.5
+	}
+
+	pos = len(str) - pos - 1
+	return math.Pow(10, -float64(pos)) / 2
+}
+
+// Return a geohased representation of a latitude & longitude bearing point.
+//
+// The coordinate is not checked to be finite, a NaN or infinite coordinate yields a meaningless geohash, see
+// LatLongToGeoHashChecked.
+func LatLongToGeoHash(pc *PolarCoord) string {
+
+	// the suffix of a float64 may not be accurately representable as a string value due to
+	// IEEE bit representation. Geohash-Encoding supports only 6 digits remainder either
+	preclat := precision(pc.Latitude)
+	preclong := precision(pc.Longitude)
+
+	bits := byte(1)
+	for laterr, longerr := 45.0, 90.0; laterr > preclat || longerr > preclong; {
+		longerr /= 2.0
+		laterr /= 2.0
+		bits++
+	}
+
+	// precision is for latitude and longitude so multiply by two
+	bits *= 2
+
+	// assure that once bits is not a multiple of five it will be rounded towards the next integer
+	bits = (bits-1)/5 + 1
+
+	return LatLongToGeoHashBits(pc, bits)
+}
+
+// Return a geohased representation of a latitude & longitude bearing point like LatLongToGeoHash. Function returns
+// ErrNotFinite, if latitude or longitude of pc is NaN or infinite.
+func LatLongToGeoHashChecked(pc *PolarCoord) (string, error) {
+	if err := pc.CheckFinite(); err != nil {
+		return "", err
+	}
+	return LatLongToGeoHash(pc), nil
+}
+
+// Return a geohased representation of a latitude & longitude bearing point using bits precision.
+// If bits is 0 or larger than 30, it is set to a maximum of 30 bits
+func LatLongToGeoHashBits(pc *PolarCoord, precision byte) string {
+
+	if precision == 0 || precision > 30 {
+		precision = 30
+	}
+	latrange := [2]float64{-90, 90}
+	longrange := [2]float64{-180, 180}
+
+	even := true
+	bit := 0
+	n := 0
+	var mid float64
+	var geohash string
+
+	for byte(len(geohash)) < precision {
+		n <<= 1
+
+		if even {
+			mid = (longrange[0] + longrange[1]) / 2
+			if pc.Longitude >= mid {
+				longrange[0] = mid
+				n ^= 1
+			} else {
+				longrange[1] = mid
+				n ^= 0
+			}
+		} else {
+			mid = (latrange[0] + latrange[1]) / 2
+			if pc.Latitude >= mid {
+				latrange[0] = mid
+				n ^= 1
+			} else {
+				latrange[1] = mid
+				n ^= 0
+			}
+		}
+
+		if bit == 4 {
+			geohash += string(Base32GeohashCode[n])
+			bit = 0
+			n = 0
+		} else {
+			bit++
+		}
+		even = !even
+	}
+	return geohash
+}
+
+// HELMERT Transformation - http://en.wikipedia.org/wiki/Helmert_transformation
+
+// Returns a new ellipsoid by the given major axis a and major axis b in meters.
+func NewEllipsoid(a, b float64, CommonName string) *Ellipsoid {
+	return &Ellipsoid{a: a, b: b, CommonName: CommonName}
+}
+
+// ## Ellipsoid registry
+
+// Registry of reference ellipsoids by name
+var (
+	ellipsoidRegistryLock sync.RWMutex
+	ellipsoids            = map[string]*Ellipsoid{}
+)
+
+// Register a reference ellipsoid by name, so it can be looked up by EllipsoidByName, eg. when given as parameter
+// of a request. Returns ErrSyntax if name is empty or el is nil and ErrDuplicate if the name is already registered.
+func RegisterEllipsoid(name string, el *Ellipsoid) error {
+
+	if name == "" || el == nil {
+		return ErrSyntax
+	}
+
+	ellipsoidRegistryLock.Lock()
+	defer ellipsoidRegistryLock.Unlock()
+
+	if _, ok := ellipsoids[name]; ok {
+		return ErrDuplicate
 	}
+	ellipsoids[name] = el
+	return nil
+}
 
-	if len(accu) > 0 {
-		return 0, CartographyError{Val: degf, Index: i, Coord: degree, Err: ErrSyntax}
+// Returns the reference ellipsoid registered by name. Returns ErrNotFound if no ellipsoid of that name is registered.
+func EllipsoidByName(name string) (*Ellipsoid, error) {
+
+	ellipsoidRegistryLock.RLock()
+	defer ellipsoidRegistryLock.RUnlock()
+
+	if el, ok := ellipsoids[name]; ok {
+		return el, nil
 	}
+	return nil, ErrNotFound
+}
 
-	if negate {
-		degf = -degf
+// Returns the names of the registered reference ellipsoids in alphabetical order
+func EllipsoidNames() []string {
+
+	ellipsoidRegistryLock.RLock()
+	defer ellipsoidRegistryLock.RUnlock()
+
+	names := make([]string, 0, len(ellipsoids))
+	for name := range ellipsoids {
+		names = append(names, name)
 	}
+	sort.Strings(names)
+	return names
+}
 
-	return degf, nil
+// The reference ellipsoids shipped with this package are registered by their common name
+func init() {
+	for _, el := range []*Ellipsoid{Bessel1841MGIEllipsoid, Bessel1841Ellipsoid, GRS80Ellipsoid, WGS84Ellipsoid,
+		Airy1830Ellipsoid, Airy1830ModEllipsoid, Clarke1866Ellipsoid, International1924Ellipsoid} {
+		if err := RegisterEllipsoid(el.CommonName, el); err != nil {
+			panic(err)
+		}
+	}
+
+	// the variant of the Bessel ellipsoid used by the MGI has no EPSG code of its own
+	Bessel1841Ellipsoid.epsg = 7004
+	GRS80Ellipsoid.epsg = 7019
+	WGS84Ellipsoid.epsg = 7030
+	Airy1830Ellipsoid.epsg = 7001
+	Airy1830ModEllipsoid.epsg = 7002
+	Clarke1866Ellipsoid.epsg = 7008
+	International1924Ellipsoid.epsg = 7022
 }
 
-// ## Polar to Cartesian coordinate conversion and vice-versa
+// ## Coordinate reference systems
 
-// Function accepts two bearing datum as Deg°MM'SS'' (typically northing and easting)
-// and height at bearing relative to reference ellipsoid and returns a polar coordinate type.
-// If the reference ellipsoid is nil, the DefaultEllipsoid will be set in the resulting polar coordinate.
-func ADegMMSSToPolar(Northing, Easting string, Height float64, El *Ellipsoid) (*PolarCoord, error) {
+// Registry of coordinate reference systems by EPSG code
+var (
+	crsRegistryLock sync.RWMutex
+	crss            = map[int]*CRS{}
+)
 
-	northing, err := ADegMMSSToNum(Northing)
+// Register a coordinate reference system by its EPSG code, so it can be looked up by ByEPSG. The packages of
+// the coordinate systems based on the Transverse Mercator projection register their systems when imported.
+// Returns ErrSyntax if crs is nil or its EPSG code is not positive and ErrDuplicate if the EPSG code is already
+// registered.
+func RegisterCRS(crs *CRS) error {
 
-	if err == nil {
-		easting, err := ADegMMSSToNum(Easting)
+	if crs == nil || crs.EPSG <= 0 {
+		return ErrSyntax
+	}
 
-		if err == nil {
+	crsRegistryLock.Lock()
+	defer crsRegistryLock.Unlock()
 
-			el := El
-			if el == nil {
-				el = DefaultEllipsoid
-			}
+	if _, ok := crss[crs.EPSG]; ok {
+		return ErrDuplicate
+	}
+	crss[crs.EPSG] = crs
+	return nil
+}
 
-			return &PolarCoord{Latitude: northing, Longitude: easting, Height: Height, El: el}, nil
+// Returns the coordinate reference system registered by its EPSG code. Returns ErrNotFound if no system of
+// that code is registered.
+func ByEPSG(code int) (*CRS, error) {
+
+	crsRegistryLock.RLock()
+	defer crsRegistryLock.RUnlock()
+
+	if crs, ok := crss[code]; ok {
+		return crs, nil
+	}
+	return nil, ErrNotFound
+}
+
+// Returns the EPSG codes of the registered coordinate reference systems in ascending order
+func EPSGCodes() []int {
+
+	crsRegistryLock.RLock()
+	defer crsRegistryLock.RUnlock()
+
+	codes := make([]int, 0, len(crss))
+	for code := range crss {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	return codes
+}
+
+// EPSG codes of WGS84 latitude / longitude, of the UTM zones on WGS84, on the northern and the southern hemisphere, and of the zones used
+// with ETRS89 and its predecessor ED50 in Europe. The polar stereographic projections of UPS North and UPS South are not implemented by
+// CRS, so they are not registered.
+const (
+	EPSGWGS84         = 4326
+	EPSGWGS84UTMNorth = 32600
+	EPSGWGS84UTMSouth = 32700
+	EPSGETRS89UTM     = 25800
+	EPSGED50UTM       = 23000
+	EPSGWGS84UPSNorth = 32661
+	EPSGWGS84UPSSouth = 32761
+)
+
+// The geographic coordinate reference systems of the datums of this package and the zones of UTM
+func init() {
+	for _, crs := range []*CRS{
+		{EPSG: EPSGWGS84, Name: "WGS 84", Datum: DatumWGS84, El: WGS84Ellipsoid},
+		{EPSG: 4258, Name: "ETRS89", El: GRS80Ellipsoid},
+		{EPSG: 4312, Name: "MGI", Datum: DatumMGI, El: Bessel1841MGIEllipsoid},
+		{EPSG: 4277, Name: "OSGB 1936", Datum: DatumOSGB36, El: Airy1830Ellipsoid},
+		{EPSG: 4149, Name: "CH1903", Datum: DatumLV03, El: Bessel1841Ellipsoid},
+		{EPSG: 4314, Name: "DHDN", Datum: DatumDHDN, El: Bessel1841Ellipsoid},
+		{EPSG: 4300, Name: "TM75", Datum: DatumTM75, El: Airy1830ModEllipsoid},
+		{EPSG: 4267, Name: "NAD27", Datum: DatumNAD27, El: Clarke1866Ellipsoid},
+		{EPSG: 4230, Name: "ED50", Datum: DatumED50, El: International1924Ellipsoid},
+	} {
+		if err := RegisterCRS(crs); err != nil {
+			panic(err)
 		}
 	}
-	return nil, err
+
+	utm := func(epsg int, name, datum string, el *Ellipsoid, zone int, fn float64) *CRS {
+		return &CRS{EPSG: epsg, Name: fmt.Sprintf(name, zone), Datum: datum, El: el, Method: MethodTransverseMercator,
+			LongO: utmCentralMeridian(uint(zone)), Scale: 0.9996, FalseEasting: utmFalseEasting, FalseNorthing: fn}
+	}
+	for zone := 1; zone <= 60; zone++ {
+		RegisterCRS(utm(EPSGWGS84UTMNorth+zone, "WGS 84 / UTM zone %dN", DatumWGS84, WGS84Ellipsoid, zone, 0))
+		RegisterCRS(utm(EPSGWGS84UTMSouth+zone, "WGS 84 / UTM zone %dS", DatumWGS84, WGS84Ellipsoid, zone, UTMFalseNorthingSouth))
+	}
+	for zone := 28; zone <= 38; zone++ {
+		RegisterCRS(utm(EPSGETRS89UTM+zone, "ETRS89 / UTM zone %dN", "", GRS80Ellipsoid, zone, 0))
+		RegisterCRS(utm(EPSGED50UTM+zone, "ED50 / UTM zone %dN", DatumED50, International1924Ellipsoid, zone, 0))
+	}
 }
 
-// Convert polar coordinates to Cartesian. The polar coordinates must be in decimal degrees.
-// The reference ellipsoid is copied verbatim to the result.
-// Inspired by http://www.movable-type.co.uk/scripts/latlong-convert-coords.html
-func PolarToCartesian(gc *PolarCoord) *CartPoint {
+// ## Helmert transformation
 
-	var p CartPoint
+// The parameters of a 7 parameter helmert transformation between two 3D datums: three translations,
+// three rotations and a scale correction. The rotations follow the position vector convention.
+// Create instances by calling NewHelmertTransformer or FitHelmert.
+type HelmertTransform struct {
+	dx, dy, dz, dM, drx, dry, drz float64
+	datum                         string
+}
 
-	el := gc.El
+// A generic Cartesian point to represent a 3D datum; used by the helmert-transformation
+type Point3D struct {
+	X float64 `json:"x" xml:"x"`
+	Y float64 `json:"y" xml:"y"`
+	Z float64 `json:"z" xml:"z"`
+}
 
-	lat := degtorad(gc.Latitude)
-	long := degtorad(gc.Longitude)
+// A set of 3D datum transformations for the helmert transformation
+var (
+	// http://de.wikipedia.org/wiki/Datum_Austria
+	HelmertWGS84ToMGI    = NewHelmertTransformer(-577.326, -90.129, -463.919, -2.4232, 5.1366, 1.4742, 5.2970, "WGS84toMGI")
+	HelmertWGS84ToOSGB36 = NewHelmertTransformer(-446.448, 125.157, -542.060, 20.4894, -0.1502, -0.2470, -0.8421, "WGS84toOSGB36")
+	// EPSG:1777, DHDN to WGS 84 (2), reversed
+	HelmertWGS84ToDHDN = NewHelmertTransformer(-598.1, -73.7, -418.2, -6.7, -0.202, -0.045, 2.455, "WGS84toDHDN")
+	// Ordnance Survey Ireland, ETRS89 to Ireland 1975
+	HelmertWGS84ToTM75 = NewHelmertTransformer(-482.530, 130.596, -564.557, -8.150, 1.042, 0.214, 0.631, "WGS84toTM75")
+	// "Granit87" parameters
+	HelmertLV03ToWGS84Granit87 = NewHelmertTransformer(660.077, 13.551, 369.3444, 5.66, 2.2356, 1.6047, 2.6451, "LV03toWGS84")
+)
 
-	esq := (el.a*el.a - el.b*el.b) / (el.a * el.a)
+// Names of the geodetic datums between which helmert parameter sets are registered
+const (
+	DatumWGS84  = "WGS84"
+	DatumMGI    = "MGI"
+	DatumOSGB36 = "OSGB36"
+	DatumLV03   = "LV03"
+	DatumDHDN   = "DHDN"
+	DatumTM75   = "TM75"
+	DatumNAD27  = "NAD27"
+	DatumED50   = "ED50"
+)
 
-	u := el.a / math.Sqrt(1-esq*math.Pow(math.Sin(lat), 2))
+// A named set of helmert parameters, transforming Cartesian coordinates from datum From into datum To.
+// Published parameters for the same pair of datums vary by source, area and accuracy, so there may be
+// several parameter sets for one pair of datums.
+//
+// Accuracy is the approximate positional accuracy of the transformation in meters as stated by the
+// source of the parameters. An accuracy of 0 means the accuracy is unknown. Extent is the area the parameters
+// were determined for, nil if they apply everywhere, like a mean solution for a whole datum.
+type HelmertParameterSet struct {
+	Name     string
+	From, To string
+	Accuracy float64
+	Source   string
+	Extent   *LatLongExtent
+	*HelmertTransform
+}
 
-	p.X = (gc.Height + u) * math.Cos(lat) * math.Cos(long)
-	p.Y = (gc.Height + u) * math.Cos(lat) * math.Sin(long)
-	p.Z = ((1-esq)*u + gc.Height) * math.Sin(lat)
+// Registry of helmert parameter sets by name and the name of the default parameter set per pair of datums
+var (
+	helmertRegistryLock         sync.RWMutex
+	helmertParameterSets        = map[string]*HelmertParameterSet{}
+	defaultHelmertParameterSets = map[string]string{}
+)
 
-	p.El = el
+func datumpair(from, to string) string {
+	return from + "->" + to
+}
 
-	return &p
+// Register a helmert parameter set by its name. If isdefault is true, the parameter set becomes the default
+// for transformations between its pair of datums. The first parameter set registered for a pair of datums
+// becomes the default, unless another parameter set is registered as default.
+//
+// Returns ErrSyntax if the parameter set has no name or datums and ErrDuplicate if a parameter set of the
+// same name is already registered.
+func RegisterHelmertParameterSet(set *HelmertParameterSet, isdefault bool) error {
+
+	if set.Name == "" || set.From == "" || set.To == "" || set.HelmertTransform == nil {
+		return ErrSyntax
+	}
+
+	helmertRegistryLock.Lock()
+	defer helmertRegistryLock.Unlock()
+
+	if _, ok := helmertParameterSets[set.Name]; ok {
+		return ErrDuplicate
+	}
+	helmertParameterSets[set.Name] = set
+
+	pair := datumpair(set.From, set.To)
+	if _, ok := defaultHelmertParameterSets[pair]; isdefault || !ok {
+		defaultHelmertParameterSets[pair] = set.Name
+	}
+	return nil
 }
 
-// Convert Cartesian coordinates to polar.
-// The reference ellipsoid is copied verbatim to the result.
-// The resulting polar coordinates are in decimal degrees.
-// Inspired by http://www.movable-type.co.uk/scripts/latlong-convert-coords.html
-func CartesianToPolar(pt *CartPoint) *PolarCoord {
+// Returns the helmert parameter set registered by name. Returns ErrNotFound if no such parameter set is registered.
+func HelmertParameterSetByName(name string) (*HelmertParameterSet, error) {
 
-	var gc PolarCoord
+	helmertRegistryLock.RLock()
+	defer helmertRegistryLock.RUnlock()
 
-	el := pt.El
+	if set, ok := helmertParameterSets[name]; ok {
+		return set, nil
+	}
+	return nil, ErrNotFound
+}
 
-	esq := (el.a*el.a - el.b*el.b) / (el.a * el.a)
-	p := math.Hypot(pt.X, pt.Y)
+// Returns the default helmert parameter set for the transformation from datum from to datum to.
+// Returns ErrNotFound if there is no parameter set registered for this pair of datums.
+func DefaultHelmertParameterSet(from, to string) (*HelmertParameterSet, error) {
 
-	lat := math.Atan2(pt.Z, p*(1-esq))
-	lat0 := 2.0 * math.Pi
+	helmertRegistryLock.RLock()
+	defer helmertRegistryLock.RUnlock()
 
-	precision := 4.0 / el.a
-	var v float64
-	for math.Abs(lat-lat0) > precision {
-		v = el.a / math.Sqrt(1-esq*math.Pow(math.Sin(lat), 2))
+	if name, ok := defaultHelmertParameterSets[datumpair(from, to)]; ok {
+		return helmertParameterSets[name], nil
+	}
+	return nil, ErrNotFound
+}
 
-		lat0 = lat
-		lat = math.Atan2(pt.Z+esq*v*math.Sin(lat), p)
+// Returns true, if the parameter set is the default for its pair of datums
+func (set *HelmertParameterSet) IsDefault() bool {
+
+	helmertRegistryLock.RLock()
+	defer helmertRegistryLock.RUnlock()
+
+	return defaultHelmertParameterSets[datumpair(set.From, set.To)] == set.Name
+}
+
+// Returns all registered helmert parameter sets transforming from datum from to datum to, sorted by name.
+// An empty string for from or to matches every datum.
+func HelmertParameterSets(from, to string) []*HelmertParameterSet {
+
+	helmertRegistryLock.RLock()
+	defer helmertRegistryLock.RUnlock()
+
+	var sets []*HelmertParameterSet
+	for _, set := range helmertParameterSets {
+		if (from == "" || from == set.From) && (to == "" || to == set.To) {
+			sets = append(sets, set)
+		}
 	}
+	sort.Slice(sets, func(i, j int) bool { return sets[i].Name < sets[j].Name })
+	return sets
+}
 
-	gc.Height = p/math.Cos(lat) - v
-	gc.Latitude = radtodeg(lat)
-	gc.Longitude = radtodeg(math.Atan2(pt.Y, pt.X))
+// Returns the helmert parameter set best suited to transform the location gc from datum from to datum to: the most
+// accurate of the registered parameter sets between the datums whose Extent contains gc or which apply everywhere.
+// Parameter sets of unknown accuracy rank last, among parameter sets of equal accuracy the default one is preferred,
+// then the first by name. If no parameter set applies at gc, the default parameter set is returned. gc may be given
+// in either datum, the shift between datums is far smaller than the extent of any parameter set.
+//
+// This lets regional parameter sets be registered as they become available, each improving the accuracy within its
+// extent only, while the default parameter set keeps covering the rest of the datum.
+//
+// Returns ErrNotFound if there is no parameter set registered for this pair of datums and ErrNotFinite if
+// latitude or longitude of gc is not finite.
+func SelectHelmertParameterSet(from, to string, gc *PolarCoord) (*HelmertParameterSet, error) {
 
-	gc.El = el
+	if err := gc.CheckFinite(); err != nil {
+		return nil, err
+	}
+	if from == "" || to == "" {
+		return nil, ErrNotFound
+	}
 
-	return &gc
+	// ranks parameter sets of unknown accuracy last
+	rank := func(set *HelmertParameterSet) float64 {
+		if set.Accuracy <= 0 {
+			return math.Inf(1)
+		}
+		return set.Accuracy
+	}
+
+	var best *HelmertParameterSet
+	for _, set := range HelmertParameterSets(from, to) {
+		if set.Extent != nil && !set.Extent.Contains(gc) {
+			continue
+		}
+		if best == nil || rank(set) < rank(best) || (rank(set) == rank(best) && set.IsDefault() && !best.IsDefault()) {
+			best = set
+		}
+	}
+	if best != nil {
+		return best, nil
+	}
+	return DefaultHelmertParameterSet(from, to)
 }
 
-// ## Transverse Mercator Projection
+// The helmert parameter sets shipped with this package. The default parameter set of every datum pair is
+// the one used by the national subpackages.
+func init() {
+	for _, set := range []struct {
+		*HelmertParameterSet
+		isdefault bool
+	}{
+		{&HelmertParameterSet{Name: "MGI_7param_BEV", From: DatumWGS84, To: DatumMGI, Accuracy: 1.5,
+			Source: "http://de.wikipedia.org/wiki/Datum_Austria", HelmertTransform: HelmertWGS84ToMGI}, true},
+		{&HelmertParameterSet{Name: "OSGB36_7param_OS", From: DatumWGS84, To: DatumOSGB36, Accuracy: 5,
+			Source: "http://gps.ordnancesurvey.co.uk/etrs89geo_natgrid.asp", HelmertTransform: HelmertWGS84ToOSGB36}, true},
+		{&HelmertParameterSet{Name: "OSGB36_3param_NIMA", From: DatumWGS84, To: DatumOSGB36, Accuracy: 20,
+			Source: "NIMA TR8350.2, Great Britain mean solution", HelmertTransform: NewHelmertTransformer(-375, 111, -431, 0, 0, 0, 0, "WGS84toOSGB36")}, false},
+		{&HelmertParameterSet{Name: "LV03_3param_swisstopo", From: DatumLV03, To: DatumWGS84, Accuracy: 1,
+			Source: "swisstopo, approximate shift LV03 to WGS84", HelmertTransform: NewHelmertTransformer(674.374, 15.056, 405.346, 0, 0, 0, 0, "LV03toWGS84")}, true},
+		{&HelmertParameterSet{Name: "LV03_7param_Granit87", From: DatumLV03, To: DatumWGS84,
+			Source: "swisstopo, Granit87", HelmertTransform: HelmertLV03ToWGS84Granit87}, false},
+		{&HelmertParameterSet{Name: "DHDN_7param_EPSG1777", From: DatumWGS84, To: DatumDHDN, Accuracy: 3,
+			Source: "EPSG:1777, DHDN to WGS 84 (2), reversed", HelmertTransform: HelmertWGS84ToDHDN}, true},
+		{&HelmertParameterSet{Name: "TM75_7param_OSi", From: DatumWGS84, To: DatumTM75, Accuracy: 1,
+			Source: "Ordnance Survey Ireland, ETRS89 to Ireland 1975", HelmertTransform: HelmertWGS84ToTM75}, true},
+		{&HelmertParameterSet{Name: "NAD27_3param_NIMA_CONUS", From: DatumNAD27, To: DatumWGS84, Accuracy: 10,
+			Source: "NIMA TR8350.2, NAD27 mean solution for CONUS", HelmertTransform: NewHelmertTransformer(-8, 160, 176, 0, 0, 0, 0, "NAD27toWGS84")}, true},
+		{&HelmertParameterSet{Name: "ED50_3param_NIMA", From: DatumED50, To: DatumWGS84, Accuracy: 10,
+			Source: "NIMA TR8350.2, ED50 mean solution for Western Europe", HelmertTransform: NewHelmertTransformer(-87, -98, -121, 0, 0, 0, 0, "ED50toWGS84")}, true},
+	} {
+		if err := RegisterHelmertParameterSet(set.HelmertParameterSet, set.isdefault); err != nil {
+			panic(err)
+		}
+	}
+}
 
-// Direct transverse mercator projection: Projection of an ellipsoid onto the surface of
-// of a cylinder. Also known as Gauss-Krüger projection. Input parameters:
+// An entry of a file of helmert parameter sets read by LoadHelmertParameterSets. The names of the parameters state
+// their units, so values given in another unit are caught as unknown key rather than silently misread.
+type helmertParameterSetEntry struct {
+	Name, From, To     string
+	Accuracy           float64
+	Source             string
+	Extent             *LatLongExtent
+	Default            bool
+	TranslationMeters  []float64 // dx, dy, dz
+	RotationArcSeconds []float64 // drx, dry, drz in the position vector convention, none for a translation
+	ScalePPM           float64
+}
+
+// A HelmertParameterSetError is yielded by LoadHelmertParameterSets for a malformed entry of a file of helmert
+// parameter sets
+type HelmertParameterSetError struct {
+	Index int    // index of the entry in the file, counted from 0
+	Name  string // name of the parameter set, empty if it has none
+	Err   error  // the error of the entry, wrapping ErrSyntax, ErrRange or ErrDuplicate, or a HelmertError
+}
+
+func (hpe HelmertParameterSetError) Error() string {
+	return fmt.Sprintf("helmert parameter set %d \"%s\": %s", hpe.Index, hpe.Name, hpe.Err)
+}
+
+func (hpe HelmertParameterSetError) Unwrap() error {
+	return hpe.Err
+}
+
+// Returns the helmert parameter set of the entry, or the error of the first of its values which is malformed
+func (entry *helmertParameterSetEntry) parameterSet() (*HelmertParameterSet, error) {
+
+	switch {
+	case entry.Name == "" || entry.From == "" || entry.To == "":
+		return nil, fmt.Errorf("%w: Name, From and To are required", ErrSyntax)
+	case entry.From == entry.To:
+		return nil, fmt.Errorf("%w: From and To are both the datum %s", ErrSyntax, entry.From)
+	case len(entry.TranslationMeters) != 3:
+		return nil, fmt.Errorf("%w: TranslationMeters has to be dx, dy and dz, got %d values", ErrSyntax, len(entry.TranslationMeters))
+	case len(entry.RotationArcSeconds) != 0 && len(entry.RotationArcSeconds) != 3:
+		return nil, fmt.Errorf("%w: RotationArcSeconds has to be drx, dry and drz, got %d values", ErrSyntax, len(entry.RotationArcSeconds))
+	case entry.Accuracy < 0:
+		return nil, fmt.Errorf("%w: Accuracy %g is negative", ErrRange, entry.Accuracy)
+	}
+
+	if extent := entry.Extent; extent != nil {
+		if !(extent.MinLat >= -90 && extent.MinLat < extent.MaxLat && extent.MaxLat <= 90 &&
+			extent.MinLong >= -180 && extent.MinLong < extent.MaxLong && extent.MaxLong <= 180) {
+			return nil, fmt.Errorf("%w: Extent latitude %g to %g, longitude %g to %g is empty or exceeds the globe",
+				ErrRange, extent.MinLat, extent.MaxLat, extent.MinLong, extent.MaxLong)
+		}
+	}
+
+	rotation := [3]float64{}
+	copy(rotation[:], entry.RotationArcSeconds)
+	dx, dy, dz := entry.TranslationMeters[0], entry.TranslationMeters[1], entry.TranslationMeters[2]
+	transform, err := NewHelmertTransform(dx, dy, dz, entry.ScalePPM, rotation[0], rotation[1], rotation[2], entry.From+"to"+entry.To)
+	if err != nil {
+		return nil, err
+	}
+	return &HelmertParameterSet{Name: entry.Name, From: entry.From, To: entry.To, Accuracy: entry.Accuracy,
+		Source: entry.Source, Extent: entry.Extent, HelmertTransform: transform}, nil
+}
+
+// Loads helmert parameter sets from the JSON file r and registers them, so regional datum shifts can be added without
+// changing the package. The file is a list of parameter sets like
 //
-//	gc *PolarCoord: Latitude and Longitude or point to be projected; in decimal degrees
-//	latO, longO: Shifted origin of latitude and longitude in decimal degrees
-//	fe, fn: False easting and northing respectively in meters
-//	scale: Projection scaling; Dimensionless, typically 1 or little bellow
+//	[{"Name": "MGI_7param_regional", "From": "WGS84", "To": "MGI", "Accuracy": 0.5, "Source": "...",
+//	  "Extent": {"MinLat": 46.3, "MaxLat": 49.1, "MinLong": 9.5, "MaxLong": 17.2}, "Default": false,
+//	  "TranslationMeters": [-577.3, -90.1, -463.9], "RotationArcSeconds": [5.137, 1.474, 5.297], "ScalePPM": -2.423}]
 //
-// This algorithm uses the algorithm described by Redfearn
-// http://en.wikipedia.org/wiki/Transverse_Mercator:_Redfearn_series
+// The translations are given in meters, the rotations in arc seconds, following the position vector convention, and
+// the scale correction in parts per million, like the parameters of NewHelmertTransformer. RotationArcSeconds and
+// ScalePPM may be omitted for a translation. Extent is the area the parameter set applies to, see
+// SelectHelmertParameterSet, and Default makes it the default parameter set of its pair of datums, see
+// RegisterHelmertParameterSet.
 //
-// Taken from "OGP Publication 373-7-2 – Surveying and Positioning Guidance Note number 7, part 2 – November 2010",
-// pp. 48 - 51
-func DirectTransverseMercator(gc *PolarCoord, latO, longO, scale, fe, fn float64) *GeoPoint {
+// Every entry is checked before any is registered, so a malformed file registers none of its parameter sets. Returns
+// the error of the JSON decoder if the file is no such list or has unknown keys, or a HelmertParameterSetError for
+// the first malformed entry: one without name or datums, implausible parameters, which typically result from values
+// in the wrong unit, see NewHelmertTransform, an empty extent or a name already registered.
+func LoadHelmertParameterSets(r io.Reader) ([]*HelmertParameterSet, error) {
+
+	var entries []helmertParameterSetEntry
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&entries); err != nil {
+		return nil, err
+	}
 
-	var pt GeoPoint
+	sets := make([]*HelmertParameterSet, len(entries))
+	names := map[string]bool{}
+	for index := range entries {
+		entry := &entries[index]
+		set, err := entry.parameterSet()
+		if err == nil {
+			if _, registered := HelmertParameterSetByName(entry.Name); names[entry.Name] || registered == nil {
+				err = fmt.Errorf("%w: the name is already in use", ErrDuplicate)
+			}
+		}
+		if err != nil {
+			return nil, HelmertParameterSetError{Index: index, Name: entry.Name, Err: err}
+		}
+		names[entry.Name] = true
+		sets[index] = set
+	}
 
-	el := gc.El
+	for index, set := range sets {
+		if err := RegisterHelmertParameterSet(set, entries[index].Default); err != nil {
+			return sets[:index], HelmertParameterSetError{Index: index, Name: set.Name, Err: err}
+		}
+	}
+	return sets, nil
+}
 
-	latOrad := degtorad(latO)
-	longOrad := degtorad(longO)
+// Method to perform the Helmert transformation on a generic 3D datum and return a new datum.
+// Instances of helmert transformations might be created by calls to NewHelmertTransformer
+//
+// Inspired by http://www.movable-type.co.uk/scripts/latlong-convert-coords.html
+func (hp *HelmertTransform) Transform(ip *Point3D) *Point3D {
+	var tp Point3D
+	hp.TransformInto(ip, &tp)
+	return &tp
+}
 
-	latrad := degtorad(gc.Latitude)
-	longrad := degtorad(gc.Longitude)
+// Performs the helmert transformation like Transform, storing the result in tp instead of allocating it.
+// tp may be ip itself, which gets transformed in place then.
+func (hp *HelmertTransform) TransformInto(ip, tp *Point3D) {
 
-	f := 1 - el.b/el.a
-	esq := math.Sqrt(2.0*f - f*f)
+	s := 1 + hp.dM/1e6
 
-	n := f / (2.0 - f)
-	B := (el.a / (1 + n)) * (1 + n*n/4.0 + n*n*n*n/64.0)
+	rx := degtorad(hp.drx / 3600)
+	ry := degtorad(hp.dry / 3600)
+	rz := degtorad(hp.drz / 3600)
 
-	h1 := n/2.0 - (2.0/3.0)*(n*n) + (5.0/16.0)*(n*n*n) + (41.0/180.0)*(n*n*n*n)
-	h2 := (13.0/48.0)*(n*n) - (3.0/5.0)*(n*n*n) + (557.0/1440.0)*(n*n*n*n)
-	h3 := (61.0/240.0)*(n*n*n) - (103.0/140.0)*(n*n*n*n)
-	h4 := (49561.0 / 161280.0) * (n * n * n * n)
+	x, y, z := ip.X, ip.Y, ip.Z
+	tp.X = hp.dx + s*x - rz*y + ry*z
+	tp.Y = hp.dy + rz*x + s*y - rx*z
+	tp.Z = hp.dz - ry*x + rx*y + s*z
+}
 
-	var SO float64
+// Method to perform the inverse helmert transformation on a generic 3D datum and return a new datum.
+// The inverse is computed exactly by solving the linear equations of the transformation, not by inverting
+// the signs of the helmert parameters, which is accurate to a few millimeters only.
+// Instances of helmert transformations might be created by calls to NewHelmertTransformer
+func (hp *HelmertTransform) InverseTransform(pt *Point3D) *Point3D {
 
-	if latOrad != 0.0 {
-		QO := math.Asinh(math.Tan(latOrad)) - (esq * math.Atanh(esq*math.Sin(latOrad)))
-		bO := math.Atan(math.Sinh(QO))
-		xiO0 := bO // math.Asin(math.Sin(bO))
+	s := 1 + hp.dM/1e6
 
-		xiO1 := h1 * math.Sin(2.0*xiO0)
-		xiO2 := h2 * math.Sin(4.0*xiO0)
-		xiO3 := h3 * math.Sin(6.0*xiO0)
-		xiO4 := h4 * math.Sin(8.0*xiO0)
+	rx := degtorad(hp.drx / 3600)
+	ry := degtorad(hp.dry / 3600)
+	rz := degtorad(hp.drz / 3600)
 
-		xiO := xiO0 + xiO1 + xiO2 + xiO3 + xiO4
+	// the rotation and scale of Transform
+	m := [3][3]float64{
+		{s, -rz, ry},
+		{rz, s, -rx},
+		{-ry, rx, s},
+	}
+	b := [3]float64{pt.X - hp.dx, pt.Y - hp.dy, pt.Z - hp.dz}
+
+	// Cramer's rule
+	det3 := func(m [3][3]float64) float64 {
+		return m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) - m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) + m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+	}
+	det := det3(m)
+
+	var x [3]float64
+	for col := range x {
+		mc := m
+		for row := range b {
+			mc[row][col] = b[row]
+		}
+		x[col] = det3(mc) / det
+	}
+
+	return &Point3D{X: x[0], Y: x[1], Z: x[2]}
+}
+
+// Returns a canoncial representation of the helmert parameters
+func (tp *HelmertTransform) String() string {
+	return fmt.Sprintf("Helmert[%s](dx,dy,dz,dM,drx, dry,drz): (%f, %f, %f, %f, %f, %f, %f)", tp.datum, tp.dx, tp.dy, tp.dz, tp.dM, tp.drx, tp.dry, tp.drz)
+}
+
+// Returns the helmert parameters in the units of NewHelmertTransformer: translations in meters, the scale
+// correction in ppm and rotations in arc seconds. A derived parameter set may be created by passing the
+// modified parameters to NewHelmertTransformer.
+func (tp *HelmertTransform) Parameters() (dx, dy, dz, dM, drx, dry, drz float64) {
+	return tp.dx, tp.dy, tp.dz, tp.dM, tp.drx, tp.dry, tp.drz
+}
+
+// Returns the name of the transformation as given to NewHelmertTransformer, eg. "WGS84toMGI"
+func (tp *HelmertTransform) Datum() string {
+	return tp.datum
+}
+
+// Get the well known text (WKT) for the helmert transformation as defined in
+// http://www.geoapi.org/2.0/javadoc/org/opengis/referencing/doc-files/WKT.html
+func (tp *HelmertTransform) WellKnownString() string {
+	return fmt.Sprintf("TOWGS84[\"%f\", \"%f\", \"%f\", \"%f\", \"%f\", \"%f\", \"%f\"]", tp.dx, tp.dy, tp.dz, tp.dM, tp.drx, tp.dry, tp.drz)
+}
+
+// Create a new instance of helmert parameters.
+//
+//	dx, dy, dz: delta of coordinate origin in meters
+//	drx, dry, drz: rotation of the coordinate axes in arc seconds
+//	dM:  scale correction to be made to the position vector in the source coordinate reference
+//		system, in parts of per million
+//
+// Attention: Unlike the other functions dealing with bearings in this package, the angular helmert
+// parameters have to be specified in arc seconds, not in degrees or rad. The parameters are not checked,
+// use NewHelmertTransform to reject implausible parameters.
+func NewHelmertTransformer(dx, dy, dz, dM, drx, dry, drz float64, datum string) *HelmertTransform {
+	return &HelmertTransform{dx: dx, dy: dy, dz: dz, dM: dM, drx: drx, dry: dry, drz: drz, datum: datum}
+}
 
-		SO = B * xiO
+// Create a new instance of helmert parameters like NewHelmertTransformer and validate them by Validate.
+// Returns a HelmertError if the parameters are implausible.
+func NewHelmertTransform(dx, dy, dz, dM, drx, dry, drz float64, datum string) (*HelmertTransform, error) {
+	hp := NewHelmertTransformer(dx, dy, dz, dM, drx, dry, drz, datum)
+	if err := hp.Validate(); err != nil {
+		return nil, err
 	}
+	return hp, nil
+}
 
-	Q := math.Asinh(math.Tan(latrad)) - (esq * math.Atanh(esq*math.Sin(latrad)))
-	b := math.Atan(math.Sinh(Q))
-
-	eta0 := math.Atanh(math.Cos(b) * math.Sin(longrad-longOrad))
-	xi0 := math.Asin(math.Sin(b) * math.Cosh(eta0))
+// Limits of plausible helmert parameters checked by Validate. Published datum shifts stay well within
+// these limits, parameters beyond them typically result from mixing up units, like passing rotations
+// in milliarc seconds instead of arc seconds, or the scale factor in ppm instead of the scale correction.
+// The limits may be changed to accommodate unusual transformations.
+var (
+	HelmertMaxTranslation = 2000.0 // maximum absolute translation dx, dy, dz in meters
+	HelmertMaxScale       = 1000.0 // maximum absolute scale correction dM in ppm
+	HelmertMaxRotation    = 60.0   // maximum absolute rotation drx, dry, drz in arc seconds
+)
 
-	xi1 := h1 * math.Sin(2*xi0) * math.Cosh(2*eta0)
-	xi2 := h2 * math.Sin(4*xi0) * math.Cosh(4*eta0)
-	xi3 := h3 * math.Sin(6*xi0) * math.Cosh(6*eta0)
-	xi4 := h4 * math.Sin(8*xi0) * math.Cosh(8*eta0)
-	xi := xi0 + xi1 + xi2 + xi3 + xi4
+// A HelmertError is yielded when a helmert parameter exceeds its plausible limit or the parameters
+// do not describe an invertible transformation
+type HelmertError struct {
+	Parameter    string  // name of the parameter, eg. "drx"; "matrix" if the transformation is not invertible
+	Value, Limit float64 // value of the parameter and its limit
+}
 
-	eta1 := h1 * math.Cos(2*xi0) * math.Sinh(2*eta0)
-	eta2 := h2 * math.Cos(4*xi0) * math.Sinh(4*eta0)
-	eta3 := h3 * math.Cos(6*xi0) * math.Sinh(6*eta0)
-	eta4 := h4 * math.Cos(8*xi0) * math.Sinh(8*eta0)
-	eta := eta0 + eta1 + eta2 + eta3 + eta4
+func (he HelmertError) Error() string {
+	if he.Parameter == "matrix" {
+		return fmt.Sprintf("helmert transformation is not invertible, determinant: %g", he.Value)
+	}
+	return fmt.Sprintf("helmert parameter %s: %g exceeds the plausible limit of %g", he.Parameter, he.Value, he.Limit)
+}
 
-	pt.X = fe + scale*B*eta
-	pt.Y = fn + scale*(B*xi-SO)
+// Checks the helmert parameters for plausibility: The absolute translations, scale correction and rotations
+// must not exceed HelmertMaxTranslation, HelmertMaxScale and HelmertMaxRotation and the transformation
+// must be invertible. Returns a HelmertError for the first parameter violating its limit.
+func (hp *HelmertTransform) Validate() error {
+
+	for _, p := range []struct {
+		name         string
+		value, limit float64
+	}{
+		{"dx", hp.dx, HelmertMaxTranslation},
+		{"dy", hp.dy, HelmertMaxTranslation},
+		{"dz", hp.dz, HelmertMaxTranslation},
+		{"dM", hp.dM, HelmertMaxScale},
+		{"drx", hp.drx, HelmertMaxRotation},
+		{"dry", hp.dry, HelmertMaxRotation},
+		{"drz", hp.drz, HelmertMaxRotation},
+	} {
+		if !(math.Abs(p.value) <= p.limit) {
+			return HelmertError{Parameter: p.name, Value: p.value, Limit: p.limit}
+		}
+	}
 
-	pt.El = el
+	// the columns of the linear part of the transformation are the images of the unit vectors
+	origin := hp.Transform(&Point3D{})
+	var m [3]*Point3D
+	for i, unit := range []Point3D{{X: 1}, {Y: 1}, {Z: 1}} {
+		pt := hp.Transform(&unit)
+		m[i] = &Point3D{X: pt.X - origin.X, Y: pt.Y - origin.Y, Z: pt.Z - origin.Z}
+	}
 
-	return &pt
+	det := m[0].X*(m[1].Y*m[2].Z-m[2].Y*m[1].Z) - m[1].X*(m[0].Y*m[2].Z-m[2].Y*m[0].Z) + m[2].X*(m[0].Y*m[1].Z-m[1].Y*m[0].Z)
+	if math.Abs(det) < 1e-6 {
+		return HelmertError{Parameter: "matrix", Value: det}
+	}
+	return nil
 }
 
-// Inverse transverse mercator projection: Projection of an cylinder onto the surface of
-// of an ellipsoid. Also known as reverse Gauss-Krüger projection. Input parameters:
-//
-//	pt *GeoPoint: Easting(Y) and Northing(X) of map point to be projected; in meters
-//	latO, longO: Shifted origin of latitude and longitude in decimal degrees
-//	fe, fn: False easting and northing respectively in meters
-//	scale: Projection scaling; Dimensionless, typically 1 or little bellow
-//
-// This algorithm uses the algorithm described by Redfearn
-// http://en.wikipedia.org/wiki/Transverse_Mercator:_Redfearn_series
+// ## Molodensky transformation
+
+// Transforms the latitude / longitude coordinate gc from the ellipsoid from to the ellipsoid to, which are shifted
+// by the translation dX, dY, dZ in meters, by the abridged Molodensky formulae. The formulae apply the datum shift
+// directly to latitude, longitude and height, without the conversion into and from cartesian coordinates a helmert
+// transformation requires. If from is nil, the reference ellipsoid of gc is used.
 //
-// Taken from "OGP Publication 373-7-2 – Surveying and Positioning Guidance Note number 7, part 2 – November 2010",
-// pp. 48 - 51
+// The abridged formulae neglect terms of higher order and rotations and scale, so they are only suited for datum
+// shifts given by a translation. The error of the approximation is in the order of a meter for translations of
+// some hundred meters, compared to the rigorous transformation of the cartesian coordinates. If the parameters
+// of a 7-parameter helmert transformation are available, the helmert transformation is more accurate.
 //
-// More accurate, iterative but slower algorithmic implementation
-func InverseTransverseMercator(pt *GeoPoint, latO, longO, scale, fe, fn float64) *PolarCoord {
+// Reference: NIMA TR8350.2, Department of Defense World Geodetic System 1984, Appendix D
+func MolodenskyTransform(gc *PolarCoord, from, to *Ellipsoid, dX, dY, dZ float64) *PolarCoord {
 
-	var gc PolarCoord
+	if from == nil {
+		from = gc.El
+		if from == nil {
+			from = DefaultEllipsoid
+		}
+	}
 
-	el := pt.El
+	f := (from.a - from.b) / from.a
+	e2 := f * (2 - f)
+	da := to.a - from.a
+	df := (to.a-to.b)/to.a - f
 
-	latOrad := degtorad(latO)
-	longOrad := degtorad(longO)
+	lat, long := gc.LatRadians(), gc.LonRadians()
+	sinlat, coslat := math.Sincos(lat)
+	sinlong, coslong := math.Sincos(long)
 
-	f := 1 - el.b/el.a
-	esq := math.Sqrt(2.0*f - f*f)
+	// radii of curvature in the meridian and in the prime vertical
+	w := 1 - e2*sinlat*sinlat
+	M := from.a * (1 - e2) / math.Pow(w, 1.5)
+	N := from.a / math.Sqrt(w)
 
-	n := f / (2.0 - f)
-	B := (el.a / (1 + n)) * (1 + n*n/4.0 + n*n*n*n/64.0)
+	adfda := from.a*df + f*da
+	dlat := (-dX*sinlat*coslong - dY*sinlat*sinlong + dZ*coslat + adfda*math.Sin(2*lat)) / M
+	dlong := (-dX*sinlong + dY*coslong) / (N * coslat)
+	dh := dX*coslat*coslong + dY*coslat*sinlong + dZ*sinlat + adfda*sinlat*sinlat - da
 
-	var SO float64
+	return &PolarCoord{
+		Latitude:  radtodeg(lat + dlat),
+		Longitude: radtodeg(long + dlong),
+		Height:    gc.Height + dh,
+		El:        to}
+}
 
-	if latOrad != 0.0 {
+// ## Centroid
 
-		h1 := n/2.0 - (2.0/3.0)*n*n + (5.0/16.0)*n*n*n + (41.0/180.0)*n*n*n*n
-		h2 := (13.0/48.0)*n*n - (3.0/5.0)*n*n*n + (557.0/1440.0)*n*n*n*n
-		h3 := (61.0/240.0)*n*n*n - (103.0/140.0)*n*n*n*n
-		h4 := (49561.0 / 161280.0) * n * n * n * n
+// Returns the spherical centroid of a set of latitude / longitude coordinates. The centroid is computed
+// by averaging the 3D unit vectors of the coordinates and projecting the mean vector back onto the sphere,
+// which, unlike averaging latitudes and longitudes, holds near the poles and across the antimeridian.
+// The height of the centroid is the mean height of the coordinates.
+//
+// All coordinates have to share the same reference ellipsoid, which is set for the centroid. A nil ellipsoid
+// is taken as the DefaultEllipsoid. The function returns ErrRange if points is empty, if the coordinates differ
+// in their reference ellipsoid or if the centroid is undefined, as for two antipodal points.
+func Centroid(points []*PolarCoord) (*PolarCoord, error) {
 
-		QO := math.Asinh(math.Tan(latOrad)) - (esq * math.Atanh(esq*math.Sin(latOrad)))
-		bO := math.Atan(math.Sinh(QO))
-		xiO0 := bO // math.Asin(math.Sin(bO))
+	if len(points) == 0 {
+		return nil, ErrRange
+	}
 
-		xiO1 := h1 * math.Sin(2.0*xiO0)
-		xiO2 := h2 * math.Sin(4.0*xiO0)
-		xiO3 := h3 * math.Sin(6.0*xiO0)
-		xiO4 := h4 * math.Sin(8.0*xiO0)
+	var x, y, z, height float64
+	var el *Ellipsoid
 
-		xiO := xiO0 + xiO1 + xiO2 + xiO3 + xiO4
+	for i, pc := range points {
+		pel := pc.El
+		if pel == nil {
+			pel = DefaultEllipsoid
+		}
+		if i == 0 {
+			el = pel
+		} else if pel != el {
+			return nil, ErrRange
+		}
+		if err := pc.CheckFinite(); err != nil {
+			return nil, err
+		}
 
-		SO = B * xiO
+		lat, long := pc.LatRadians(), pc.LonRadians()
+		x += math.Cos(lat) * math.Cos(long)
+		y += math.Cos(lat) * math.Sin(long)
+		z += math.Sin(lat)
+		height += pc.Height
 	}
 
-	h1i := n/2.0 - (2.0/3.0)*n*n + (37.0/96.0)*n*n*n - (1.0/360.0)*n*n*n*n
-	h2i := (1.0/48.0)*n*n + (1.0/15.0)*n*n*n - (437.0/1440.0)*n*n*n*n
-	h3i := (17.0/480.0)*n*n*n - (37.0/840.0)*n*n*n*n
-	h4i := (4397.0 / 161280.0) * n * n * n * n
-
-	etai := (pt.X - fe) / (B * scale)
-	xii := ((pt.Y - fn) + scale*SO) / (B * scale)
+	n := float64(len(points))
+	x, y, z = x/n, y/n, z/n
 
-	xi1i := h1i * math.Sin(2*xii) * math.Cosh(2*etai)
-	xi2i := h2i * math.Sin(4*xii) * math.Cosh(4*etai)
-	xi3i := h3i * math.Sin(6*xii) * math.Cosh(6*etai)
-	xi4i := h4i * math.Sin(8*xii) * math.Cosh(8*etai)
+	// the mean vector degenerates to the origin if the points are evenly spread around the sphere
+	hyp := math.Hypot(x, y)
+	if math.Hypot(hyp, z) < 1e-12 {
+		return nil, ErrRange
+	}
 
-	eta1i := h1i * math.Cos(2*xii) * math.Sinh(2*etai)
-	eta2i := h2i * math.Cos(4*xii) * math.Sinh(4*etai)
-	eta3i := h3i * math.Cos(6*xii) * math.Sinh(6*etai)
-	eta4i := h4i * math.Cos(8*xii) * math.Sinh(8*etai)
+	centroid := &PolarCoord{Latitude: radtodeg(math.Atan2(z, hyp)), Height: height / n, El: el}
+	// the longitude of a pole is arbitrary, don't return the noise of the summation
+	if hyp >= 1e-12 {
+		centroid.Longitude = radtodeg(math.Atan2(y, x))
+	}
+	return centroid, nil
+}
 
-	xi0i := xii - (xi1i + xi2i + xi3i + xi4i)
-	eta0i := etai - (eta1i + eta2i + eta3i + eta4i)
+// ## Bearings
 
-	bi := math.Asin(math.Sin(xi0i) / math.Cosh(eta0i))
+// A MagneticModel computes the magnetic declination, the angle between true north and magnetic north,
+// of a location at a given date, such as an implementation of the World Magnetic Model (WMM). The package
+// does not provide a model, as the coefficient tables are large and need to be updated every few years.
+type MagneticModel interface {
+	// Returns the magnetic declination in decimal degrees, positive if magnetic north lies east of true north.
+	// Returns ErrRange if the location or date is outside of the validity of the model.
+	Declination(gc *PolarCoord, date time.Time) (float64, error)
+}
 
-	Qi := math.Asinh(math.Tan(bi))
-	Qiiold := Qi + (esq * math.Atanh(esq*math.Tanh(Qi)))
-	Qii := Qi + (esq * math.Atanh(esq*math.Tanh(Qiiold)))
+// The magnetic model used by the bearing functions to report magnetic bearings. No model is configured by default,
+// in which case magnetic bearings are not available.
+var DefaultMagneticModel MagneticModel
 
-	for math.Abs(Qiiold-Qii) > 1e-12 {
-		Qiiold = Qii
-		Qii = Qi + (esq * math.Atanh(esq*math.Tanh(Qiiold)))
+// Returns bearing, given in decimal degrees, in the range [0, 360)
+func normalizeBearing(bearing float64) float64 {
+	bearing = math.Mod(bearing, 360)
+	if bearing < 0 {
+		bearing += 360
 	}
+	return bearing
+}
 
-	gc.Latitude = radtodeg(math.Atan(math.Sinh(Qii)))
-	gc.Longitude = radtodeg(longOrad + math.Asin(math.Tanh(eta0i)/math.Cos(bi)))
+// Returns the initial great circle bearing from one coordinate to another in decimal degrees relative to true north,
+// in the range [0, 360). The bearing is computed on a sphere; the result is undefined if both coordinates coincide.
+func InitialBearing(from, to *PolarCoord) float64 {
 
-	gc.El = el
+	lat1, lat2 := from.LatRadians(), to.LatRadians()
+	dlong := to.LonRadians() - from.LonRadians()
 
-	return &gc
+	y := math.Sin(dlong) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(dlong)
+
+	return normalizeBearing(radtodeg(math.Atan2(y, x)))
 }
 
-// ## UTM coordinate functions for parsing and conversion
+// Converts a bearing relative to true north at coordinate gc into a bearing relative to magnetic north,
+// using the declination of the DefaultMagneticModel at date. Returns ErrUnsupported if no model is configured.
+func TrueToMagneticBearing(bearing float64, gc *PolarCoord, date time.Time) (float64, error) {
 
-// A UTM coordinate defined by Northin, Easting and relative origin by Zone
-// The reference ellipsoid is typically the GRS80Ellipsoid or the WGS84Ellipsoid
-type UTMCoord struct {
-	Northing, Easting float64
-	Zone              string
-	El                *Ellipsoid
+	if DefaultMagneticModel == nil {
+		return 0, ErrUnsupported
+	}
+	if err := CheckFinite(bearing, gc.Latitude, gc.Longitude); err != nil {
+		return 0, err
+	}
+
+	declination, err := DefaultMagneticModel.Declination(gc, date)
+	if err != nil {
+		return 0, err
+	}
+	return normalizeBearing(bearing - declination), nil
 }
 
-// Canonical representation of an UTM coordinate
-func (utm *UTMCoord) String() string {
-	return fmt.Sprintf("%s %.0f %.0f", utm.Zone, utm.Easting, utm.Northing)
+// Returns the initial great circle bearing from one coordinate to another in decimal degrees relative to magnetic north
+// at the starting coordinate and date. Returns ErrUnsupported if no DefaultMagneticModel is configured.
+func InitialMagneticBearing(from, to *PolarCoord, date time.Time) (float64, error) {
+	return TrueToMagneticBearing(InitialBearing(from, to), from, date)
 }
 
-// This function parses a string UTM coordinate literal of the format
-//
-//	"ZONE EASTING NORTHING"
-//
-// Zone is the UTM meridian zone specifier and must be specified in the unambiguous
-// way of zone number and latitude band. Easting and northing are specified as decimal meters.
-// If the reference ellipsoid is nil, the DefaultEllipsoid is assumed.
-func AUTMToStruct(utmcoord string, el *Ellipsoid) (*UTMCoord, error) {
+// The number of points of the compass rose labelling a bearing formatted by FormatBearingPoints
+type CompassPoints int
 
-	var zone, northing, easting string
-	var north, east float64
-	var err error
+const (
+	Compass8  CompassPoints = 8  // cardinal and intercardinal directions, like "NE"
+	Compass16 CompassPoints = 16 // with the secondary intercardinal directions, like "NNE"
+)
 
-	compact := strings.TrimSpace(utmcoord)
+var compassLabels = [16]string{"N", "NNE", "NE", "ENE", "E", "ESE", "SE", "SSE", "S", "SSW", "SW", "WSW", "W", "WNW", "NW", "NNW"}
 
-L1:
-	for i, index := 0, 0; i < 3; i++ {
-		index = strings.Index(compact, " ")
-		if index == -1 {
-			index = len(compact)
-		}
-		switch i {
-		case 0:
-			zone = compact[:index]
-		case 1:
-			easting = compact[:index]
-		case 2:
-			northing = compact[:index]
-			break L1
-		}
-		compact = compact[index+len(" "):]
-		compact = strings.TrimLeft(compact, " ")
-	}
+// Formats a bearing in decimal degrees as compass heading to the degree, labelled by the nearest of the 8 points of
+// the compass rose, like "045° NE", see FormatBearingPoints
+func FormatBearing(deg float64) string {
+	return FormatBearingPoints(deg, 0, Compass8)
+}
 
-	north, err = strconv.ParseFloat(northing, 64)
+// Formats a bearing in decimal degrees as compass heading with prec decimal places, normalized to the range [0, 360)
+// and padded to three digits of degrees, followed by the label of the nearest of the given points of the compass rose,
+// like "337.5° NNW". The label is chosen by the heading as formatted, so a heading half-way between two points is
+// labelled by the point clockwise of it: 337.5° is N on the compass rose of 8 points. A negative prec is taken as 0.
+// A bearing which is not finite is formatted without label.
+func FormatBearingPoints(deg float64, prec int, points CompassPoints) string {
 
-	if err != nil {
-		return nil, err
+	if prec < 0 {
+		prec = 0
+	}
+	if err := CheckFinite(deg); err != nil {
+		return FormatFixed(deg, prec) + "°"
+	}
+	if points != Compass16 {
+		points = Compass8
 	}
 
-	east, err = strconv.ParseFloat(easting, 64)
+	scale := math.Pow(10, float64(prec))
+	heading := normalizeBearing(math.Round(normalizeBearing(deg)*scale) / scale)
+	if heading == 0 {
+		// -0 from normalizing a negative multiple of 360
+		heading = 0
+	}
 
-	if err != nil {
-		return nil, err
+	width := 3
+	if prec > 0 {
+		width += prec + 1
 	}
+	sector := 360 / float64(points)
+	point := int(math.Floor(heading/sector+0.5)) % int(points)
+	return fmt.Sprintf("%0*.*f° %s", width, prec, heading, compassLabels[point*16/int(points)])
+}
 
+// Returns the mean radius (2a + b) / 3 of ellipsoid el, or of the DefaultEllipsoid if el is nil
+func meanRadius(el *Ellipsoid) float64 {
 	if el == nil {
 		el = DefaultEllipsoid
 	}
-	return &UTMCoord{Northing: north, Easting: east, Zone: zone, El: el}, nil
+	return (2*el.a + el.b) / 3
 }
 
-// Convert from UTM 2D projection to 3D polar. If the UTM coordinates do not contain a
-// reference ellipsoid, the WGS84Ellipsoid is assumed and copied to the resulting polar coordinates.
-//
-// Inspired by http://www.gpsy.com/gpsinfo/geotoutm/gantz/LatLong-UTMconversion.cpp.txt
-func UTMToLatLong(coord *UTMCoord) (*PolarCoord, error) {
+// Returns the central angle in radians between two coordinates on a sphere, using the haversine formula
+func angularDistance(from, to *PolarCoord) float64 {
 
-	zonelength := len(coord.Zone)
-	utmLetter := int8(coord.Zone[zonelength-1:][0])
-	zonenumber, err := strconv.ParseUint(coord.Zone[:zonelength-1], 10, 0)
+	lat1, lat2 := from.LatRadians(), to.LatRadians()
+	dlat, dlong := lat2-lat1, to.LonRadians()-from.LonRadians()
 
-	if err != nil {
-		return nil, err
-	}
+	h := math.Sin(dlat/2)*math.Sin(dlat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dlong/2)*math.Sin(dlong/2)
+	return 2 * math.Asin(math.Sqrt(math.Min(h, 1)))
+}
 
-	pt := &GeoPoint{Y: coord.Northing, X: coord.Easting, El: coord.El}
+// Returns the signed distance in meters of point from the path along the great circle from pathStart to pathEnd.
+// The distance is positive if point lies right of the path, seen from pathStart in the direction of pathEnd,
+// and negative if it lies left of the path. If the foot of the perpendicular from point lies beyond either end of
+// the path, the distance to the nearer end of the path is returned instead, signed the same way. The distance is
+// computed on a sphere of the mean radius of the reference ellipsoid of point.
+func CrossTrackDistance(point, pathStart, pathEnd *PolarCoord) float64 {
+
+	d13 := angularDistance(pathStart, point)
+	theta13, theta12 := degtorad(InitialBearing(pathStart, point)), degtorad(InitialBearing(pathStart, pathEnd))
+	dxt := math.Asin(math.Sin(d13) * math.Sin(theta13-theta12))
+
+	// the foot of the perpendicular lies beyond an end of the path, if the angle at this end between the path
+	// and point exceeds a right angle
+	theta23, theta21 := degtorad(InitialBearing(pathEnd, point)), degtorad(InitialBearing(pathEnd, pathStart))
+	switch {
+	case math.Cos(theta13-theta12) < 0:
+		dxt = math.Copysign(d13, dxt)
+	case math.Cos(theta23-theta21) < 0:
+		dxt = math.Copysign(angularDistance(pathEnd, point), dxt)
+	}
+	return dxt * meanRadius(point.El)
+}
 
-	if utmLetter-'N' < 0 {
-		pt.Y -= 10000000.0
+// Returns the great circle distance in meters between two coordinates on a sphere of radius meters, using the haversine
+// formula. If radius is 0, the mean radius of the WGS84Ellipsoid is used. The spherical distance deviates from the
+// geodesic on the ellipsoid by up to 0.5%, but is considerably cheaper to compute than Vincenty.
+// Coordinates on either side of the antimeridian are joined the short way round, so 179°E to 179°W spans 2°.
+func Haversine(a, b *PolarCoord, radius float64) float64 {
+	if radius == 0 {
+		radius = meanRadius(WGS84Ellipsoid)
 	}
+	return angularDistance(a, b) * radius
+}
 
-	if pt.El == nil {
-		pt.El = DefaultEllipsoid
+// Returns the point at fraction of the way from a to b along the great circle, which is a at 0 and b at 1.
+// Fractions outside of [0, 1] extend the great circle beyond a or b. The height is interpolated linearly and
+// the reference ellipsoid is the one of a. A copy of a is returned if both coordinates coincide; the result is
+// undefined for antipodal coordinates, which are joined by infinitely many great circles. Like Haversine, the great
+// circle joins the coordinates the short way round, crossing the antimeridian if that is shorter; the longitude
+// of the result is in the range (-180, 180].
+func IntermediatePoint(a, b *PolarCoord, fraction float64) *PolarCoord {
+
+	d := angularDistance(a, b)
+	switch {
+	case d == 0 || fraction == 0:
+		return &PolarCoord{Latitude: a.Latitude, Longitude: a.Longitude, Height: a.Height, El: a.El}
+	case fraction == 1:
+		return &PolarCoord{Latitude: b.Latitude, Longitude: b.Longitude, Height: b.Height, El: a.El}
 	}
 
-	gc := InverseTransverseMercator(pt, 0, (float64(zonenumber)-1)*6-180+3, 0.9996, 500000, 0)
+	fa, fb := math.Sin((1-fraction)*d)/math.Sin(d), math.Sin(fraction*d)/math.Sin(d)
 
-	return gc, nil
+	sinlat1, coslat1 := math.Sincos(a.LatRadians())
+	sinlat2, coslat2 := math.Sincos(b.LatRadians())
+	sinlong1, coslong1 := math.Sincos(a.LonRadians())
+	sinlong2, coslong2 := math.Sincos(b.LonRadians())
+
+	x := fa*coslat1*coslong1 + fb*coslat2*coslong2
+	y := fa*coslat1*sinlong1 + fb*coslat2*sinlong2
+	z := fa*sinlat1 + fb*sinlat2
+
+	return &PolarCoord{Latitude: radtodeg(math.Atan2(z, math.Hypot(x, y))), Longitude: radtodeg(math.Atan2(y, x)),
+		Height: a.Height + fraction*(b.Height-a.Height), El: a.El}
 }
 
-// Convert from 3D polar to UTM 2D projection. If the polar coordinates do not contain a
-// reference ellipsoid, the WGS84Ellipsoid is assumed and copied to the resulting UTM coordinates.
-//
-// Inspired by http://www.gpsy.com/gpsinfo/geotoutm/gantz/LatLong-UTMconversion.cpp.txt
-func LatLongToUTM(gcin *PolarCoord) *UTMCoord {
+// Returns the point halfway between a and b along the great circle, see IntermediatePoint
+func Midpoint(a, b *PolarCoord) *PolarCoord {
+	return IntermediatePoint(a, b, 0.5)
+}
 
-	var utm UTMCoord
+// The maximum number of iterations of the inverse Vincenty formulae
+const vincentyMaxIterations = 200
 
-	gc := *gcin // Make a copy as we might set the ellipsoid and we will not alter the input values
-	zonenumber := uint((gc.Longitude+180)/6) + 1
+// Returns the distance in meters on the reference ellipsoid between two coordinates, with the initial bearing at a and the
+// final bearing at b in decimal degrees relative to true north, in the range [0, 360). The geodesic is computed by the
+// inverse formulae of Vincenty, which are accurate to less than a millimeter. Both bearings are 0 if the coordinates coincide.
+//
+// Both coordinates have to share the same reference ellipsoid; a nil ellipsoid is taken as the DefaultEllipsoid.
+// The function returns ErrRange if the coordinates differ in their reference ellipsoid and ErrConvergence if the
+// iteration fails to converge, which happens for nearly antipodal points.
+func Vincenty(a, b *PolarCoord) (distance, initialBearing, finalBearing float64, err error) {
 
-	if gc.Latitude >= 56.0 && gc.Latitude < 64.0 && gc.Longitude >= 3.0 && gc.Longitude < 12.0 {
-		zonenumber = 32
+	el, elb := a.El, b.El
+	if el == nil {
+		el = DefaultEllipsoid
+	}
+	if elb == nil {
+		elb = DefaultEllipsoid
+	}
+	if el != elb {
+		return 0, 0, 0, ErrRange
+	}
+	if err = CheckFinite(a.Latitude, a.Longitude, b.Latitude, b.Longitude); err != nil {
+		return 0, 0, 0, err
 	}
 
-	if gc.Latitude >= 72.0 && gc.Latitude < 84.0 {
-		switch {
-		case gc.Longitude >= 0.0 && gc.Longitude < 9.0:
-			zonenumber = 31
-		case gc.Longitude >= 9.0 && gc.Longitude < 21.0:
-			zonenumber = 33
-		case gc.Longitude >= 21.0 && gc.Longitude < 33.0:
-			zonenumber = 35
-		case gc.Longitude >= 33.0 && gc.Longitude < 42.0:
-			zonenumber = 37
+	f := el.derived().f
+	// the difference of longitude the short way round, across the antimeridian if that is shorter
+	L := math.Remainder(b.LonRadians()-a.LonRadians(), 2*math.Pi)
+
+	// reduced latitudes
+	U1, U2 := math.Atan((1-f)*math.Tan(a.LatRadians())), math.Atan((1-f)*math.Tan(b.LatRadians()))
+	sinU1, cosU1 := math.Sincos(U1)
+	sinU2, cosU2 := math.Sincos(U2)
+
+	var sinLambda, cosLambda, sinSigma, cosSigma, sigma, cos2Alpha, cos2SigmaM float64
+
+	lambda, iteration := L, 0
+	for ; iteration < vincentyMaxIterations; iteration++ {
+		sinLambda, cosLambda = math.Sincos(lambda)
+		sinSigma = math.Hypot(cosU2*sinLambda, cosU1*sinU2-sinU1*cosU2*cosLambda)
+		if sinSigma == 0 {
+			return 0, 0, 0, nil
+		}
+		cosSigma = sinU1*sinU2 + cosU1*cosU2*cosLambda
+		sigma = math.Atan2(sinSigma, cosSigma)
+
+		sinAlpha := cosU1 * cosU2 * sinLambda / sinSigma
+		cos2Alpha = 1 - sinAlpha*sinAlpha
+		// on the equator, cos2Alpha is 0 and cos2SigmaM undefined
+		cos2SigmaM = 0
+		if cos2Alpha != 0 {
+			cos2SigmaM = cosSigma - 2*sinU1*sinU2/cos2Alpha
 		}
-	}
 
-	if gc.El == nil {
-		gc.El = DefaultEllipsoid
+		C := f / 16 * cos2Alpha * (4 + f*(4-3*cos2Alpha))
+		previous := lambda
+		lambda = L + (1-C)*f*sinAlpha*(sigma+C*sinSigma*(cos2SigmaM+C*cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)))
+		if math.Abs(lambda-previous) < 1e-12 {
+			break
+		}
+	}
+	if iteration == vincentyMaxIterations {
+		return 0, 0, 0, ErrConvergence
 	}
 
-	pt := DirectTransverseMercator(&gc, 0, (float64(zonenumber)-1)*6-180+3, 0.9996, 500000, 0)
+	u2 := cos2Alpha * el.derived().ep2
+	A := 1 + u2/16384*(4096+u2*(-768+u2*(320-175*u2)))
+	B := u2 / 1024 * (256 + u2*(-128+u2*(74-47*u2)))
+	deltaSigma := B * sinSigma * (cos2SigmaM + B/4*(cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)-
+		B/6*cos2SigmaM*(-3+4*sinSigma*sinSigma)*(-3+4*cos2SigmaM*cos2SigmaM)))
 
-	utm.Zone = strconv.FormatUint(uint64(zonenumber), 10) + string(utmLetterDesignator(gc.Latitude))
-	utm.Northing = pt.Y
-	utm.Easting = pt.X
+	distance = el.b * A * (sigma - deltaSigma)
+	initialBearing = normalizeBearing(radtodeg(math.Atan2(cosU2*sinLambda, cosU1*sinU2-sinU1*cosU2*cosLambda)))
+	finalBearing = normalizeBearing(radtodeg(math.Atan2(cosU1*sinLambda, -sinU1*cosU2+cosU1*sinU2*cosLambda)))
+	return
+}
 
-	if gc.Latitude < 0 {
-		utm.Northing += 10000000
+// Returns the coordinate reached from start by travelling distanceMeters along the geodesic of the initial bearing
+// bearingDeg, given in decimal degrees relative to true north. The geodesic is computed by the direct formulae of
+// Vincenty on the reference ellipsoid of start, or the DefaultEllipsoid if it is nil, which are accurate to less
+// than a millimeter. Negative distances travel in the opposite direction. The height of start is retained.
+func Destination(start *PolarCoord, bearingDeg, distanceMeters float64) *PolarCoord {
+
+	el := start.El
+	if el == nil {
+		el = DefaultEllipsoid
 	}
 
-	utm.El = pt.El
+	f := el.derived().f
+	sinAlpha1, cosAlpha1 := math.Sincos(degtorad(bearingDeg))
 
-	return &utm
-}
+	// reduced latitude of start
+	tanU1 := (1 - f) * math.Tan(start.LatRadians())
+	cosU1 := 1 / math.Sqrt(1+tanU1*tanU1)
+	sinU1 := tanU1 * cosU1
 
-// This routine determines the correct UTM letter designator for the given latitude
-// returns 'Z' if latitude is outside the UTM limits of 84N to 80S
-func utmLetterDesignator(Lat float64) (LetterDesignator byte) {
+	// angular distance on the sphere from the equator to start
+	sigma1 := math.Atan2(tanU1, cosAlpha1)
+	sinAlpha := cosU1 * sinAlpha1
+	cos2Alpha := 1 - sinAlpha*sinAlpha
 
-	switch {
-	case 84 >= Lat && Lat >= 72:
-		LetterDesignator = 'X'
-	case 72 > Lat && Lat >= 64:
-		LetterDesignator = 'W'
-	case 64 > Lat && Lat >= 56:
-		LetterDesignator = 'V'
-	case 56 > Lat && Lat >= 48:
-		LetterDesignator = 'U'
-	case 48 > Lat && Lat >= 40:
-		LetterDesignator = 'T'
-	case 40 > Lat && Lat >= 32:
-		LetterDesignator = 'S'
-	case 32 > Lat && Lat >= 24:
-		LetterDesignator = 'R'
-	case 24 > Lat && Lat >= 16:
-		LetterDesignator = 'Q'
-	case 16 > Lat && Lat >= 8:
-		LetterDesignator = 'P'
-	case 8 > Lat && Lat >= 0:
-		LetterDesignator = 'N'
-	case 0 > Lat && Lat >= -8:
-		LetterDesignator = 'M'
-	case -8 > Lat && Lat >= -16:
-		LetterDesignator = 'L'
-	case -16 > Lat && Lat >= -24:
-		LetterDesignator = 'K'
-	case -24 > Lat && Lat >= -32:
-		LetterDesignator = 'J'
-	case -32 > Lat && Lat >= -40:
-		LetterDesignator = 'H'
-	case -40 > Lat && Lat >= -48:
-		LetterDesignator = 'G'
-	case -48 > Lat && Lat >= -56:
-		LetterDesignator = 'F'
-	case -56 > Lat && Lat >= -64:
-		LetterDesignator = 'E'
-	case -64 > Lat && Lat >= -72:
-		LetterDesignator = 'D'
-	case -72 > Lat && Lat >= -80:
-		LetterDesignator = 'C'
-	default:
-		LetterDesignator = 'Z' //  error flag to show that the Latitude is outside the UTM limits
+	u2 := cos2Alpha * el.derived().ep2
+	A := 1 + u2/16384*(4096+u2*(-768+u2*(320-175*u2)))
+	B := u2 / 1024 * (256 + u2*(-128+u2*(74-47*u2)))
+
+	var sinSigma, cosSigma, cos2SigmaM float64
+
+	sigma := distanceMeters / (el.b * A)
+	for iteration := 0; iteration < vincentyMaxIterations; iteration++ {
+		cos2SigmaM = math.Cos(2*sigma1 + sigma)
+		sinSigma, cosSigma = math.Sincos(sigma)
+		deltaSigma := B * sinSigma * (cos2SigmaM + B/4*(cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)-
+			B/6*cos2SigmaM*(-3+4*sinSigma*sinSigma)*(-3+4*cos2SigmaM*cos2SigmaM)))
+
+		previous := sigma
+		sigma = distanceMeters/(el.b*A) + deltaSigma
+		if math.Abs(sigma-previous) < 1e-12 {
+			break
+		}
 	}
-	return
-}
+	sinSigma, cosSigma = math.Sincos(sigma)
+	cos2SigmaM = math.Cos(2*sigma1 + sigma)
 
-// Base32 codeset for geohash as described in http://en.wikipedia.org/wiki/Geohash
-var Base32GeohashCode = []byte("0123456789bcdefghjkmnpqrstuvwxyz")
+	x := sinU1*sinSigma - cosU1*cosSigma*cosAlpha1
+	lat := math.Atan2(sinU1*cosSigma+cosU1*sinSigma*cosAlpha1, (1-f)*math.Hypot(sinAlpha, x))
+	lambda := math.Atan2(sinSigma*sinAlpha1, cosU1*cosSigma-sinU1*sinSigma*cosAlpha1)
+	C := f / 16 * cos2Alpha * (4 + f*(4-3*cos2Alpha))
+	L := lambda - (1-C)*f*sinAlpha*(sigma+C*sinSigma*(cos2SigmaM+C*cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)))
 
-// The following functions deal with geohash encoding & decoding as described in http://en.wikipedia.org/wiki/Geohash
-// Inspiration taken from
-// - http://code.google.com/p/geospatialweb/source/browse/trunk/geohash/src/Geohash.java
-// - http://blog.dixo.net/downloads/geohash-php-class/
-// - https://github.com/kungfoo/geohsh-java/blob/master/src/main/java/ch/hsr/geohash/GeoHash.java
-// - https://github.com/broady/gogeohash/blob/master/geohash.go
+	return &PolarCoord{Latitude: radtodeg(lat), Longitude: normalizeLongitude(radtodeg(start.LonRadians() + L)),
+		Height: start.Height, El: start.El}
+}
 
-// Return latitude & longitude from a geohash-encoded string.
-// If the reference ellipsoid is nil, the default Ellipsoid will be returned.
-// If the string is not a geohash, err will be set to ERRRANGE.
-func GeoHashToLatLong(geohash string, el *Ellipsoid) (*PolarCoord, error) {
+// Returns a closed ring of points coordinates around center, each radiusMeters away from it along the geodesic,
+// eg. to draw a circle on a map or to approximate a buffer around a coordinate by a polygon. The coordinates are
+// spaced evenly by their initial bearing from center, starting due north and proceeding clockwise; the ring is
+// closed by repeating the first coordinate, so it has points+1 coordinates. Like Destination, the coordinates share
+// the height and the reference ellipsoid of center.
+//
+// Each bearing is computed from its index rather than accumulated, so high point counts don't drift, and the
+// direct formulae of Vincenty stay accurate for radii down to fractions of a millimeter. The function returns nil
+// if points is less than 3, or if center or radiusMeters are not finite.
+func Ring(center *PolarCoord, radiusMeters float64, points int) []*PolarCoord {
 
-	latrange := [2]float64{-90, 90}
-	longrange := [2]float64{-180, 180}
+	if points < 3 || center.CheckFinite() != nil || CheckFinite(radiusMeters) != nil {
+		return nil
+	}
 
-	errlat := latrange[1]
-	errlong := longrange[1]
+	ring := make([]*PolarCoord, points+1)
+	for i := 0; i < points; i++ {
+		ring[i] = Destination(center, 360*float64(i)/float64(points), radiusMeters)
+	}
+	first := *ring[0]
+	ring[points] = &first
+	return ring
+}
 
-	bytehash := []byte(geohash)
-	even := true
+// ## Polygon area
 
-	for _, r := range bytehash {
-		i := bytes.IndexByte(Base32GeohashCode, r)
-		if i < 0 {
-			return nil, ErrRange
-		}
-		for j := 16; j != 0; j >>= 1 {
-			var index int
-			if i&j == 0 {
-				index = 1
-			} else {
-				index = 0
-			}
+// Returns the function q of the authalic latitude for latitude lat, given in radians, on an ellipsoid of
+// first eccentricity e. For a sphere, e is 0 and q is 2 sin(lat).
+func authalicQ(lat, e float64) float64 {
+	sinlat := math.Sin(lat)
+	if e == 0 {
+		return 2 * sinlat
+	}
+	esinlat := e * sinlat
+	return (1 - e*e) * (sinlat/(1-esinlat*esinlat) - math.Log((1-esinlat)/(1+esinlat))/(2*e))
+}
 
-			if even {
-				longrange[index] = (longrange[0] + longrange[1]) / 2.0
-				errlong /= 2
-			} else {
-				latrange[index] = (latrange[0] + latrange[1]) / 2.0
-				errlat /= 2
-			}
-			even = !even
-		}
+// Returns the area in square meters and the perimeter in meters of the polygon on the reference ellipsoid with
+// the vertices points. The ring is closed automatically, if the first and the last vertex differ. The orientation
+// of the ring doesn't matter; the area is the smaller of the two regions the ring divides the ellipsoid into.
+//
+// The area is computed as the spherical excess of the polygon mapped onto the authalic sphere, which has the
+// surface of the ellipsoid. The mapping preserves areas, yet the edges of the polygon map to great circles instead
+// of geodesics, which deviates from the geodesic area by far less than a square meter for polygons of a few
+// kilometers. Polygons enclosing a pole are not supported. The perimeter is the sum of the geodesic distances
+// by Vincenty.
+//
+// All vertices have to share the same reference ellipsoid; a nil ellipsoid is taken as the DefaultEllipsoid.
+// The function returns ErrRange if the polygon has fewer than 3 vertices or the vertices differ in their reference
+// ellipsoid, and ErrConvergence if the distance between two adjacent vertices can not be computed.
+func PolygonArea(points []*PolarCoord) (area, perimeter float64, err error) {
+
+	if n := len(points); n > 1 && points[0].Latitude == points[n-1].Latitude && points[0].Longitude == points[n-1].Longitude {
+		points = points[:n-1]
+	}
+	if len(points) < 3 {
+		return 0, 0, ErrRange
 	}
 
+	el := points[0].El
 	if el == nil {
 		el = DefaultEllipsoid
 	}
 
-	return &PolarCoord{
-			Latitude:  round((latrange[0]+latrange[1])/2.0, int(math.Max(1.0, -round(math.Log10(errlat), 0))-1)),
-			Longitude: round((longrange[0]+longrange[1])/2.0, int(math.Max(1.0, -round(math.Log10(errlong), 0))-1)),
-			El:        el},
-		nil
-}
+	e := el.derived().e
+	qp := authalicQ(math.Pi/2, e)
 
-// a general purpose round
-func round(x float64, prec int) float64 {
+	var excess float64
+	for i, from := range points {
+		to := points[(i+1)%len(points)]
 
-	var rounder float64
-	pow := math.Pow(10, float64(prec))
-	intermed := x * pow
-	_, frac := math.Modf(intermed)
-	x = .5
-	if frac < 0.0 {
-		x = -.5
+		distance, _, _, err := Vincenty(from, to)
+		if err != nil {
+			return 0, 0, err
+		}
+		perimeter += distance
+
+		// the excess of the edge, between the edge and the equator, of the authalic latitudes
+		beta1, beta2 := math.Asin(authalicQ(from.LatRadians(), e)/qp), math.Asin(authalicQ(to.LatRadians(), e)/qp)
+		dlong := math.Remainder(to.LonRadians()-from.LonRadians(), 2*math.Pi)
+		t1, t2 := math.Tan(beta1/2), math.Tan(beta2/2)
+		excess += 2 * math.Atan2(math.Tan(dlong/2)*(t1+t2), 1+t1*t2)
 	}
-	if frac >= x {
-		rounder = math.Ceil(intermed)
-	} else {
-		rounder = math.Floor(intermed)
+
+	excess = math.Abs(excess)
+	if excess > 2*math.Pi {
+		excess = 4*math.Pi - excess
 	}
 
-	return rounder / pow
+	// the squared radius of the authalic sphere
+	rq2 := el.a * el.a * qp / 2
+	return excess * rq2, perimeter, nil
 }
 
-// Returns precision of number.
-// precision of 42 is 0.5
-// precision of 42.4 is 0.05
-// precision of 42.41 is 0.005 etc
-func precision(val float64) float64 {
-	str := strconv.FormatFloat(val, 'f', -1, 64)
-	pos := strings.IndexRune(str, '.')
-	if pos == -1 {
-		return 0.5
-	}
+// ## Bounding circle
 
-	pos = len(str) - pos - 1
-	return math.Pow(10, -float64(pos)) / 2
+// A spherical cap, given by the unit vector of its center and its angular radius in radians
+type sphericalCap struct {
+	center Point3D
+	radius float64
 }
 
-// Return a geohased representation of a latitude & longitude bearing point.
-func LatLongToGeoHash(pc *PolarCoord) string {
+func unitVector(pc *PolarCoord) Point3D {
+	lat, long := pc.LatRadians(), pc.LonRadians()
+	return Point3D{X: math.Cos(lat) * math.Cos(long), Y: math.Cos(lat) * math.Sin(long), Z: math.Sin(lat)}
+}
 
-	// the suffix of a float64 may not be accurately representable as a string value due to
-	// IEEE bit representation. Geohash-Encoding supports only 6 digits remainder either
-	preclat := precision(pc.Latitude)
-	preclong := precision(pc.Longitude)
+func dot(u, v Point3D) float64 {
+	return u.X*v.X + u.Y*v.Y + u.Z*v.Z
+}
 
-	bits := byte(1)
-	for laterr, longerr := 45.0, 90.0; laterr > preclat || longerr > preclong; {
-		longerr /= 2.0
-		laterr /= 2.0
-		bits++
+func cross(u, v Point3D) Point3D {
+	return Point3D{X: u.Y*v.Z - u.Z*v.Y, Y: u.Z*v.X - u.X*v.Z, Z: u.X*v.Y - u.Y*v.X}
+}
+
+// Returns u scaled to unit length, or false if u is too short to have a direction
+func normalize(u Point3D) (Point3D, bool) {
+	l := math.Sqrt(dot(u, u))
+	if l < 1e-12 {
+		return u, false
 	}
+	return Point3D{X: u.X / l, Y: u.Y / l, Z: u.Z / l}, true
+}
 
-	// precision is for latitude and longitude so multiply by two
-	bits *= 2
+// Returns the angle in radians between the unit vectors u and v, which unlike the arc cosine of the
+// dot product is accurate for small angles
+func angle(u, v Point3D) float64 {
+	c := cross(u, v)
+	return math.Atan2(math.Sqrt(dot(c, c)), dot(u, v))
+}
 
-	// assure that once bits is not a multiple of five it will be rounded towards the next integer
-	bits = (bits-1)/5 + 1
+func (sc *sphericalCap) contains(u Point3D) bool {
+	return angle(sc.center, u) <= sc.radius+1e-12
+}
 
-	return LatLongToGeoHashBits(pc, bits)
+// Returns the smallest cap with u and v on its boundary, or false for antipodal points
+func capFrom2(u, v Point3D) (sphericalCap, bool) {
+	center, ok := normalize(Point3D{X: u.X + v.X, Y: u.Y + v.Y, Z: u.Z + v.Z})
+	return sphericalCap{center, angle(center, u)}, ok
 }
 
-// Return a geohased representation of a latitude & longitude bearing point using bits precision.
-// If bits is 0 or larger than 30, it is set to a maximum of 30 bits
-func LatLongToGeoHashBits(pc *PolarCoord, precision byte) string {
+// Returns the cap with u, v and w on its boundary which does not exceed a hemisphere. Coinciding points
+// don't determine a cap; the largest cap of two of the points is returned instead.
+func capFrom3(u, v, w Point3D) (sphericalCap, bool) {
+	normal, ok := normalize(cross(Point3D{X: v.X - u.X, Y: v.Y - u.Y, Z: v.Z - u.Z}, Point3D{X: w.X - u.X, Y: w.Y - u.Y, Z: w.Z - u.Z}))
+	if ok {
+		if dot(normal, u) < 0 {
+			normal = Point3D{X: -normal.X, Y: -normal.Y, Z: -normal.Z}
+		}
+		return sphericalCap{normal, angle(normal, u)}, true
+	}
 
-	if precision == 0 || precision > 30 {
-		precision = 30
+	var largest sphericalCap
+	for _, pair := range [][2]Point3D{{u, v}, {u, w}, {v, w}} {
+		sc, ok := capFrom2(pair[0], pair[1])
+		if !ok {
+			return sc, false
+		}
+		if sc.radius > largest.radius {
+			largest = sc
+		}
 	}
-	latrange := [2]float64{-90, 90}
-	longrange := [2]float64{-180, 180}
+	return largest, true
+}
 
-	even := true
-	bit := 0
-	n := 0
-	var mid float64
-	var geohash string
+// Returns the bounding circle, the smallest circle enclosing a set of latitude / longitude coordinates,
+// as its center and radius in meters. The circle is a spherical cap, computed by Welzl's algorithm on the
+// unit sphere; the radius is measured along the surface of a sphere of the mean radius of the reference
+// ellipsoid. A single coordinate yields itself as center and a radius of 0, two coordinates the midpoint
+// of the great circle segment between them. The center lies on the ellipsoid, its height is 0.
+//
+// The restrictions on the coordinates are those of Centroid. In addition, the coordinates have to lie
+// within a hemisphere, as there is no unique smallest circle otherwise; the function returns ErrRange if they don't.
+func BoundingCircle(points []*PolarCoord) (center *PolarCoord, radius float64, err error) {
 
-	for byte(len(geohash)) < precision {
-		n <<= 1
+	if len(points) == 0 {
+		return nil, 0, ErrRange
+	}
 
-		if even {
-			mid = (longrange[0] + longrange[1]) / 2
-			if pc.Longitude >= mid {
-				longrange[0] = mid
-				n ^= 1
-			} else {
-				longrange[1] = mid
-				n ^= 0
+	el := points[0].El
+	if el == nil {
+		el = DefaultEllipsoid
+	}
+
+	vectors := make([]Point3D, len(points))
+	for i, pc := range points {
+		pel := pc.El
+		if pel == nil {
+			pel = DefaultEllipsoid
+		}
+		if pel != el {
+			return nil, 0, ErrRange
+		}
+		if err := pc.CheckFinite(); err != nil {
+			return nil, 0, err
+		}
+		vectors[i] = unitVector(pc)
+	}
+
+	// the expected linear run time of the algorithm depends on a random order of the points.
+	// A fixed seed keeps the result reproducible.
+	rnd := rand.New(rand.NewSource(1))
+	for i := len(vectors) - 1; i > 0; i-- {
+		j := rnd.Intn(i + 1)
+		vectors[i], vectors[j] = vectors[j], vectors[i]
+	}
+
+	var ok bool
+	sc := sphericalCap{vectors[0], 0}
+	for i := 1; i < len(vectors); i++ {
+		if sc.contains(vectors[i]) {
+			continue
+		}
+		sc = sphericalCap{vectors[i], 0}
+		for j := 0; j < i; j++ {
+			if sc.contains(vectors[j]) {
+				continue
 			}
-		} else {
-			mid = (latrange[0] + latrange[1]) / 2
-			if pc.Latitude >= mid {
-				latrange[0] = mid
-				n ^= 1
-			} else {
-				latrange[1] = mid
-				n ^= 0
+			if sc, ok = capFrom2(vectors[i], vectors[j]); !ok {
+				return nil, 0, ErrRange
+			}
+			for k := 0; k < j; k++ {
+				if sc.contains(vectors[k]) {
+					continue
+				}
+				if sc, ok = capFrom3(vectors[i], vectors[j], vectors[k]); !ok {
+					return nil, 0, ErrRange
+				}
 			}
 		}
+	}
 
-		if bit == 4 {
-			geohash += string(Base32GeohashCode[n])
-			bit = 0
-			n = 0
-		} else {
-			bit++
+	// points not within a hemisphere leave the invariants of the algorithm unsatisfied
+	for _, u := range vectors {
+		if !sc.contains(u) {
+			return nil, 0, ErrRange
 		}
-		even = !even
 	}
-	return geohash
+
+	hyp := math.Hypot(sc.center.X, sc.center.Y)
+	center = &PolarCoord{Latitude: radtodeg(math.Atan2(sc.center.Z, hyp)), El: el}
+	if hyp >= 1e-12 {
+		center.Longitude = radtodeg(math.Atan2(sc.center.Y, sc.center.X))
+	}
+	return center, sc.radius * meanRadius(el), nil
 }
 
-// HELMERT Transformation - http://en.wikipedia.org/wiki/Helmert_transformation
+// ## Grid addresses
 
-// Returns a new ellipsoid by the given major axis a and major axis b in meters.
-func NewEllipsoid(a, b float64, CommonName string) *Ellipsoid {
-	return &Ellipsoid{a: a, b: b, CommonName: CommonName}
+// A GridAddressProvider translates between latitude / longitude coordinates and the addresses of a
+// grid addressing system, such as the three word addresses of what3words. The package does not provide
+// an implementation, as the addressing systems are typically proprietary and accessed by an online API.
+type GridAddressProvider interface {
+	// Returns the address of the grid cell containing gc
+	Encode(gc *PolarCoord) (string, error)
+	// Returns the center of the grid cell denoted by address. Returns ErrNotFound if the address is unknown.
+	Decode(address string) (*PolarCoord, error)
 }
 
-// ## Helmert transformation
+// The grid address provider used to complement conversion results with an address. No provider is configured by default.
+var DefaultGridAddressProvider GridAddressProvider
 
-type transformer struct {
-	dx, dy, dz, dM, drx, dry, drz float64
-	datum                         string
+// ## Reverse geocoding
+
+// A Geocoder returns the name of the place at a latitude / longitude coordinate, like a town or a street address.
+// The package does not provide an implementation, integrators attach an online service like Nominatim or a local
+// gazetteer.
+type Geocoder interface {
+	// Returns the name of the place at gc, empty if the place is unknown
+	ReverseGeocode(gc *PolarCoord) (string, error)
 }
 
-// A generic Cartesian point to represent a 3D datum; used by the helmert-transformation
-type Point3D struct {
-	X, Y, Z float64
+// A Geocoder which knows no places. It is the default and returns an empty name for every coordinate.
+type NopGeocoder struct{}
+
+func (NopGeocoder) ReverseGeocode(gc *PolarCoord) (string, error) {
+	return "", nil
 }
 
-// A set of 3D datum transformations for the helmert transformation
-var (
-	// http://de.wikipedia.org/wiki/Datum_Austria
-	HelmertWGS84ToMGI    = NewHelmertTransformer(-577.326, -90.129, -463.919, -2.4232, 5.1366, 1.4742, 5.2970, "WGS84toMGI")
-	HelmertWGS84ToOSGB36 = NewHelmertTransformer(-446.448, 125.157, -542.060, 20.4894, -0.1502, -0.2470, -0.8421, "WGS84toOSGB36")
-	// "Granit87" parameters
-	HelmertLV03ToWGS84Granit87 = NewHelmertTransformer(660.077, 13.551, 369.3444, 5.66, 2.2356, 1.6047, 2.6451, "LV03toWGS84")
-)
+// The geocoder used to complement conversion results with the name of their place
+var DefaultGeocoder Geocoder = NopGeocoder{}
 
-// Method to perform the Helmert transformation on a generic 3D datum and return a new datum.
-// Instances of helmert transformations might be created by calls to NewHelmertTransformer
+// ## Helmert parameter estimation
+
+// A pair of matched Cartesian coordinates of the same point in two datums, used as control point
+// for the estimation of helmert parameters
+type ControlPoint struct {
+	From, To Point3D
+}
+
+// Minimum number of control points to estimate the 7 helmert parameters from
+const minControlPoints = 3
+
+// Least-squares fits the 7 helmert parameters transforming the From coordinates of the control points into
+// their To coordinates. The transformation is fitted to exactly the model applied by Transform, rotations
+// and scale of the result are thus in arc seconds and ppm like the parameters of NewHelmertTransformer.
 //
-// Inspired by http://www.movable-type.co.uk/scripts/latlong-convert-coords.html
-func (hp *transformer) Transform(ip *Point3D) *Point3D {
+// The residuals hold the distance in meters between the transformed From coordinate and the To
+// coordinate for every control point, in the order of controlPairs. Large residuals indicate outliers.
+//
+// Function returns ErrRange if there are less than three control points or if the control points
+// do not determine the parameters, eg. because they are collinear.
+func FitHelmert(controlPairs []ControlPoint) (*HelmertTransform, []float64, error) {
 
-	var tp Point3D
+	if len(controlPairs) < minControlPoints {
+		return nil, nil, ErrRange
+	}
 
-	s := 1 + hp.dM/1e6
+	// Transform is linear in its parameters, so column j of the design matrix is the change of the
+	// transformed point caused by a unit change of parameter j
+	unit := [7]*HelmertTransform{
+		{dx: 1}, {dy: 1}, {dz: 1}, {dM: 1}, {drx: 1}, {dry: 1}, {drz: 1},
+	}
+	identity := &HelmertTransform{}
 
-	rx := degtorad(hp.drx / 3600)
-	ry := degtorad(hp.dry / 3600)
-	rz := degtorad(hp.drz / 3600)
+	rows := 3 * len(controlPairs)
+	a := make([][7]float64, rows)
+	b := make([]float64, rows)
+
+	for i, cp := range controlPairs {
+		base := identity.Transform(&cp.From)
+		for j, hp := range unit {
+			pt := hp.Transform(&cp.From)
+			a[3*i][j], a[3*i+1][j], a[3*i+2][j] = pt.X-base.X, pt.Y-base.Y, pt.Z-base.Z
+		}
+		b[3*i], b[3*i+1], b[3*i+2] = cp.To.X-base.X, cp.To.Y-base.Y, cp.To.Z-base.Z
+	}
 
-	tp.X = hp.dx + s*ip.X - rz*ip.Y + ry*ip.Z
-	tp.Y = hp.dy + rx*ip.X + s*ip.Y - rx*ip.Z
-	tp.Z = hp.dz - ry*ip.X + rx*ip.Y + s*ip.Z
+	x, err := leastSquares(a, b)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	return &tp
+	hp := &HelmertTransform{dx: x[0], dy: x[1], dz: x[2], dM: x[3], drx: x[4], dry: x[5], drz: x[6], datum: "fitted"}
+
+	residuals := make([]float64, len(controlPairs))
+	for i, cp := range controlPairs {
+		pt := hp.Transform(&cp.From)
+		residuals[i] = math.Sqrt((pt.X-cp.To.X)*(pt.X-cp.To.X) + (pt.Y-cp.To.Y)*(pt.Y-cp.To.Y) + (pt.Z-cp.To.Z)*(pt.Z-cp.To.Z))
+	}
+
+	return hp, residuals, nil
 }
 
-// Method to perform the inverse helmert transformation on a generic 3D datum and return a new datum.
-// The inverse might be easily computed by inverting all helmert parameters. The inversion is moderately
-// accurate if the points of the orginal datum (x0, y0, z0) do not substantially diverge.
-// Instances of helmert transformations might be created by calls to NewHelmertTransformer
-func (hp *transformer) InverseTransform(pt *Point3D) *Point3D {
+// Solves the over-determined linear system a x = b in the least squares sense by a householder QR
+// decomposition, which unlike the normal equations does not square the condition of the system.
+// a and b are overwritten. Returns ErrRange if a does not have full column rank.
+func leastSquares(a [][7]float64, b []float64) ([7]float64, error) {
+
+	var x [7]float64
+	var norm float64
+	for _, row := range a {
+		for _, val := range row {
+			norm = math.Max(norm, math.Abs(val))
+		}
+	}
+
+	for k := 0; k < 7; k++ {
+		var alpha float64
+		for i := k; i < len(a); i++ {
+			alpha = math.Hypot(alpha, a[i][k])
+		}
+		if alpha <= 1e-8*norm {
+			return x, ErrRange
+		}
+		if a[k][k] > 0 {
+			alpha = -alpha
+		}
 
-	var ihp transformer
+		// householder vector v = a[k:][k] - alpha e_k, stored in place of column k
+		a[k][k] -= alpha
+		var vv float64
+		for i := k; i < len(a); i++ {
+			vv += a[i][k] * a[i][k]
+		}
+
+		for j := k + 1; j < 7; j++ {
+			var dot float64
+			for i := k; i < len(a); i++ {
+				dot += a[i][k] * a[i][j]
+			}
+			for i := k; i < len(a); i++ {
+				a[i][j] -= 2 * dot / vv * a[i][k]
+			}
+		}
+
+		var dot float64
+		for i := k; i < len(a); i++ {
+			dot += a[i][k] * b[i]
+		}
+		for i := k; i < len(a); i++ {
+			b[i] -= 2 * dot / vv * a[i][k]
+		}
 
-	ihp.dM = -1 * hp.dM
-	ihp.drx = -1 * hp.drx
-	ihp.dry = -1 * hp.dry
-	ihp.drz = -1 * hp.drz
-	ihp.dx = -1 * hp.dx
-	ihp.dy = -1 * hp.dy
-	ihp.dz = -1 * hp.dz
+		a[k][k] = alpha
+	}
 
-	return ihp.Transform(pt)
+	// back substitution of the upper triangular system R x = Q^T b
+	for k := 6; k >= 0; k-- {
+		x[k] = b[k]
+		for j := k + 1; j < 7; j++ {
+			x[k] -= a[k][j] * x[j]
+		}
+		x[k] /= a[k][k]
+	}
+	return x, nil
 }
 
-// Returns a canoncial representation of the helmert parameters
-func (tp *transformer) String() string {
-	return fmt.Sprintf("Helmert[%s](dx,dy,dz,dM,drx, dry,drz): (%f, %f, %f, %f, %f, %f, %f)", tp.datum, tp.dx, tp.dy, tp.dz, tp.dM, tp.drx, tp.dry, tp.drz)
+// ## Batch conversion
+
+// Result of a single conversion within a batch. Either Coord carries the converted coordinate
+// or Err the reason why the conversion failed. Index is the position of the converted coordinate
+// within the input of the batch.
+type Result struct {
+	Index int
+	Coord *PolarCoord
+	Err   error
 }
 
-// Get the well known text (WKT) for the helmert transformation as defined in
-// http://www.geoapi.org/2.0/javadoc/org/opengis/referencing/doc-files/WKT.html
-func (tp *transformer) WellKnownString() string {
-	return fmt.Sprintf("TOWGS84[\"%f\", \"%f\", \"%f\", \"%f\", \"%f\", \"%f\", \"%f\"]", tp.dx, tp.dy, tp.dz, tp.dM, tp.drx, tp.dry, tp.drz)
+// Number of conversions performed between two checks for cancelation of a batch
+const batchCancelInterval = 64
+
+// Converts every coordinate of points by fn. Errors of single conversions are recorded in the
+// respective result and do not abort the batch.
+//
+// The conversions are performed concurrently, fn must therefore be safe for concurrent use.
+// Regardless of the order in which the conversions complete, the results are returned in the
+// order of points: result i carries the Index i and the conversion of points[i].
+//
+// The context is checked for cancelation every batchCancelInterval points. If the context is
+// canceled or its deadline passes, the function returns the results processed so far together
+// with the error of the context. The results processed so far are the results of the leading
+// points, so their order and indices hold as well.
+func ConvertBatch(ctx context.Context, points []*PolarCoord, fn func(*PolarCoord) (*PolarCoord, error)) ([]Result, error) {
+
+	results := make([]Result, len(points))
+	done, err := ProcessBatch(ctx, len(points), func(i int) {
+		coord, err := fn(points[i])
+		results[i] = Result{Index: i, Coord: coord, Err: err}
+	})
+	return results[:done], err
 }
 
-// Create a new instance of helmert parameters.
+// Calls fn for every index of a batch of n items, as ConvertBatch does for the conversion of a point, for batches
+// of other items than points. fn must be safe for concurrent use and stores its results by index itself.
 //
-//	dx, dy, dz: delta of coordinate origin in meters
-//	drx, dry, drz: delta of coordinate bearing in rads
-//	dM:  scale correction to be made to the position vector in the source coordinate reference
-//		system, in parts of per million
+// The context is checked for cancelation every batchCancelInterval items. If the context is canceled or its
+// deadline passes, the function returns the number of leading items processed so far together with the error of
+// the context, otherwise n and nil.
+func ProcessBatch(ctx context.Context, n int, fn func(i int)) (int, error) {
+
+	workers := runtime.GOMAXPROCS(0)
+
+	for start := 0; start < n; start += batchCancelInterval {
+		if err := ctx.Err(); err != nil {
+			return start, err
+		}
+
+		end := start + batchCancelInterval
+		if end > n {
+			end = n
+		}
+
+		// every worker processes a stride of the chunk, fn stores the results at their index,
+		// which keeps the order independent of the completion of the items
+		var wg sync.WaitGroup
+		for w := 0; w < workers && start+w < end; w++ {
+			wg.Add(1)
+			go func(first int) {
+				defer wg.Done()
+				for i := first; i < end; i += workers {
+					fn(i)
+				}
+			}(start + w)
+		}
+		wg.Wait()
+	}
+	return n, nil
+}
+
+// Converts every coordinate of points by fn on at most workers goroutines, GOMAXPROCS if workers
+// is less than 1. Errors of single conversions are recorded in the respective result and do not
+// abort the conversion of the remaining points. As by ConvertBatch, fn must be safe for concurrent
+// use and result i carries the Index i and the conversion of points[i].
 //
-// Attention: Unlike the other functions dealing with bearings and coordinates in this package,
-// the angular helmert parameters have to be specified in rad [-pi;pi]
-func NewHelmertTransformer(dx, dy, dz, dM, drx, dry, drz float64, datum string) *transformer {
-	return &transformer{dx: dx, dy: dy, dz: dz, dM: dM, drx: drx, dry: dry, drz: drz, datum: datum}
+// Unlike ConvertBatch, the points are handed out one by one to the next idle worker, so conversions
+// taking uneven time keep every worker busy.
+func ConvertAll(points []*PolarCoord, fn func(*PolarCoord) (*PolarCoord, error), workers int) []Result {
+
+	results := make([]Result, len(points))
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(points) {
+		workers = len(points)
+	}
+
+	// the index of the next point to convert; every worker stores its results at their index
+	var next int64 = -1
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := int(atomic.AddInt64(&next, 1)); i < len(points); i = int(atomic.AddInt64(&next, 1)) {
+				coord, err := fn(points[i])
+				results[i] = Result{Index: i, Coord: coord, Err: err}
+			}
+		}()
+	}
+	wg.Wait()
+	return results
 }
@@ -6,9 +6,21 @@
 package cartconvert
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"fmt"
 	"math"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 // ## TestDirectTransverseMercator
@@ -52,6 +64,35 @@ func TestDirectTransverseMercator(t *testing.T) {
 	}
 }
 
+// ## DirectTransverseMercatorFactors
+type directTransverseMercatorFactorsTest struct {
+	in             directtransversemercatorParam
+	convergence, k float64
+}
+
+// Hand-computed by the series of Snyder, "Map Projections - A Working Manual", p. 61, for the scale factor and
+// of the Ordnance Survey, "A guide to coordinate systems in Great Britain", for the convergence
+var directTransverseMercatorFactorsTests = []directTransverseMercatorFactorsTest{
+	// east and west of the central meridian of UTM zone 32
+	{directtransversemercatorParam{&PolarCoord{Latitude: 48, Longitude: 11, El: WGS84Ellipsoid}, 0, 9, 0.9996, 500000, 0}, 1.486562408, 0.9998734899},
+	{directtransversemercatorParam{&PolarCoord{Latitude: 48, Longitude: 7, El: WGS84Ellipsoid}, 0, 9, 0.9996, 500000, 0}, -1.486562408, 0.9998734899},
+	// Cape Town, southern hemisphere east of the central meridian of UTM zone 33
+	{directtransversemercatorParam{&PolarCoord{Latitude: -33.9, Longitude: 18.4, El: WGS84Ellipsoid}, 0, 15, 0.9996, 500000, 10000000}, -1.897889417, 1.0008189031},
+	// on the equator and on the central meridian
+	{directtransversemercatorParam{&PolarCoord{Latitude: 0, Longitude: 12, El: WGS84Ellipsoid}, 0, 9, 0.9996, 500000, 0}, 0, 1.0009810614},
+	{directtransversemercatorParam{&PolarCoord{Latitude: 60, Longitude: 9, El: WGS84Ellipsoid}, 0, 9, 0.9996, 500000, 0}, 0, 0.9996},
+}
+
+func TestDirectTransverseMercatorFactors(t *testing.T) {
+	for cnt, test := range directTransverseMercatorFactorsTests {
+		_, convergence, k := DirectTransverseMercatorFactors(test.in.pc, test.in.lat0, test.in.long0, test.in.scale, test.in.fe, test.in.fn)
+		if math.Abs(convergence-test.convergence) > 1e-7 || math.Abs(k-test.k) > 1e-9 {
+			t.Errorf("DirectTransverseMercatorFactors [%d]: expected convergence %.9f and scale factor %.10f, got %.9f and %.10f",
+				cnt, test.convergence, test.k, convergence, k)
+		}
+	}
+}
+
 // ## InverseTransverseMercator
 type inversetransversemercatorParam struct {
 	pt                         *GeoPoint
@@ -93,6 +134,206 @@ func TestInverseTransverseMercator(t *testing.T) {
 	}
 }
 
+// ## Hemisphere
+type hemisphereTest struct {
+	gc         *PolarCoord
+	hemisphere Hemisphere
+	utm        string
+}
+
+var hemisphereTests = []hemisphereTest{
+	// Vienna, Cape Town and Sydney
+	{&PolarCoord{Latitude: 48.208493, Longitude: 16.373118, El: WGS84Ellipsoid}, HemisphereNorth, "33U 602014 5340385"},
+	{&PolarCoord{Latitude: -33.9, Longitude: 18.4, El: WGS84Ellipsoid}, HemisphereSouth, "34H 259583 6245888"},
+	{&PolarCoord{Latitude: -33.9, Longitude: 151.2, El: WGS84Ellipsoid}, HemisphereSouth, "56H 333569 6247473"},
+	// the equator belongs to the northern hemisphere
+	{&PolarCoord{Latitude: 0, Longitude: 15, El: WGS84Ellipsoid}, HemisphereNorth, "33N 500000 0"},
+}
+
+func TestHemisphere(t *testing.T) {
+	for cnt, test := range hemisphereTests {
+		if h := HemisphereOf(test.gc.Latitude); h != test.hemisphere {
+			t.Errorf("HemisphereOf [%d]: expected %s, got %s", cnt, test.hemisphere, h)
+		}
+
+		// projecting by the core functions with the false northing of the hemisphere yields UTM
+		utm := LatLongToUTM(test.gc)
+		if utm.String() != test.utm {
+			t.Errorf("LatLongToUTM [%d]: expected %s, got %s", cnt, test.utm, utm)
+		}
+		zone, band, _ := parseUTMZone(utm.Zone)
+		if h := UTMBandHemisphere(band); h != test.hemisphere {
+			t.Errorf("UTMBandHemisphere [%d]: expected %s for the band %c, got %s", cnt, test.hemisphere, band, h)
+		}
+		fn := test.hemisphere.UTMFalseNorthing()
+		pt := DirectTransverseMercator(test.gc, 0, utmCentralMeridian(zone), 0.9996, utmFalseEasting, fn)
+		if math.Abs(pt.X-utm.Easting) > 1e-6 || math.Abs(pt.Y-utm.Northing) > 1e-6 {
+			t.Errorf("DirectTransverseMercator [%d]: expected %f %f, got %f %f", cnt, utm.Easting, utm.Northing, pt.X, pt.Y)
+		}
+		if out := InverseTransverseMercator(pt, 0, utmCentralMeridian(zone), 0.9996, utmFalseEasting, fn); !out.Equal(test.gc, 0.001) {
+			t.Errorf("InverseTransverseMercator [%d]: expected %s, got %s", cnt, test.gc, out)
+		}
+	}
+
+	if HemisphereNorth.UTMFalseNorthing() != 0 || HemisphereSouth.UTMFalseNorthing() != 10000000 {
+		t.Error("UTMFalseNorthing: expected 0 on the northern and 10000000 on the southern hemisphere")
+	}
+
+	// the northing doesn't tell the hemisphere, the false northing has to
+	pt := &GeoPoint{X: 500000, Y: 6245000, El: WGS84Ellipsoid}
+	north := InverseTransverseMercator(pt, 0, 15, 0.9996, 500000, HemisphereNorth.UTMFalseNorthing())
+	south := InverseTransverseMercator(pt, 0, 15, 0.9996, 500000, HemisphereSouth.UTMFalseNorthing())
+	if math.Abs(north.Latitude-56.35) > 0.01 || math.Abs(south.Latitude+33.94) > 0.01 {
+		t.Errorf("InverseTransverseMercator: expected 56.35° on the northern and -33.94° on the southern hemisphere, got %f° and %f°",
+			north.Latitude, south.Latitude)
+	}
+}
+
+// ## LambertConformalConic
+type lambertConformalConicParam struct {
+	lat1, lat2, latO, longO, fe, fn float64
+}
+
+type lambertConformalConicTest struct {
+	gc    *PolarCoord
+	param lambertConformalConicParam
+	pt    *GeoPoint
+}
+
+// US survey foot in meters
+const usft = 1200.0 / 3937
+
+var lambertConformalConicTests = []lambertConformalConicTest{
+	// Snyder, "Map Projections - A Working Manual", p. 296
+	{
+		&PolarCoord{Latitude: 35, Longitude: -75, El: Clarke1866Ellipsoid},
+		lambertConformalConicParam{33, 45, 23, -96, 0, 0},
+		&GeoPoint{X: 1894410.9, Y: 1564649.5},
+	},
+	// EPSG Guidance Note 7-2, NAD27 / Texas South Central
+	{
+		&PolarCoord{Latitude: 28.5, Longitude: -96, El: Clarke1866Ellipsoid},
+		lambertConformalConicParam{28 + 23.0/60, 30 + 17.0/60, 27 + 50.0/60, -99, 2000000 * usft, 0},
+		&GeoPoint{X: 2963503.91 * usft, Y: 254759.80 * usft},
+	},
+	// a single standard parallel on the southern hemisphere
+	{
+		&PolarCoord{Latitude: -36, Longitude: 146, El: GRS80Ellipsoid},
+		lambertConformalConicParam{-37, -37, -37, 145, 2500000, 2500000},
+		nil,
+	},
+}
+
+func TestLambertConformalConic(t *testing.T) {
+	for index, test := range lambertConformalConicTests {
+		p := test.param
+		pt := DirectLambertConformalConic(test.gc, p.lat1, p.lat2, p.latO, p.longO, p.fe, p.fn)
+
+		if test.pt != nil && (math.Abs(test.pt.X-pt.X) > 0.05 || math.Abs(test.pt.Y-pt.Y) > 0.05) {
+			t.Errorf("DirectLambertConformalConic [%d]: expected %.2f %.2f, got %.2f %.2f", index, test.pt.X, test.pt.Y, pt.X, pt.Y)
+		}
+		if pt.El != test.gc.El {
+			t.Errorf("DirectLambertConformalConic [%d]: expected the ellipsoid of the coordinate", index)
+		}
+
+		gc := InverseLambertConformalConic(pt, p.lat1, p.lat2, p.latO, p.longO, p.fe, p.fn)
+		if math.Abs(test.gc.Latitude-gc.Latitude) > 1e-9 || math.Abs(test.gc.Longitude-gc.Longitude) > 1e-9 || gc.El != test.gc.El {
+			t.Errorf("InverseLambertConformalConic [%d]: expected %s, got %s", index, test.gc, gc)
+		}
+	}
+
+	// the origin yields false easting and northing
+	pt := DirectLambertConformalConic(&PolarCoord{Latitude: 23, Longitude: -96, El: Clarke1866Ellipsoid}, 33, 45, 23, -96, 100, 200)
+	if math.Abs(pt.X-100) > 1e-6 || math.Abs(pt.Y-200) > 1e-6 {
+		t.Errorf("DirectLambertConformalConic: expected the false origin 100 200, got %f %f", pt.X, pt.Y)
+	}
+}
+
+// ## PolarStereographic
+func TestPolarStereographic(t *testing.T) {
+	// EPSG Guidance Note 7-2, WGS 84 / UPS North
+	gc := &PolarCoord{Latitude: 73, Longitude: 44, El: WGS84Ellipsoid}
+	pt := DirectPolarStereographic(gc, true, 0, 0.994, 2000000, 2000000)
+	if math.Abs(pt.X-3320416.75) > 0.01 || math.Abs(pt.Y-632668.43) > 0.01 || pt.El != gc.El {
+		t.Errorf("DirectPolarStereographic: expected 3320416.75 632668.43, got %.2f %.2f", pt.X, pt.Y)
+	}
+	back := InversePolarStereographic(pt, true, 0, 0.994, 2000000, 2000000)
+	if math.Abs(back.Latitude-gc.Latitude) > 1e-9 || math.Abs(back.Longitude-gc.Longitude) > 1e-9 || back.El != gc.El {
+		t.Errorf("InversePolarStereographic: expected %s, got %s", gc, back)
+	}
+
+	// the south pole mirrors the north pole
+	gc = &PolarCoord{Latitude: -73, Longitude: 44, El: WGS84Ellipsoid}
+	pt = DirectPolarStereographic(gc, false, 0, 0.994, 2000000, 2000000)
+	if math.Abs(pt.X-3320416.75) > 0.01 || math.Abs(pt.Y-(4000000-632668.43)) > 0.01 {
+		t.Errorf("DirectPolarStereographic: expected 3320416.75 %.2f, got %.2f %.2f", 4000000-632668.43, pt.X, pt.Y)
+	}
+	back = InversePolarStereographic(pt, false, 0, 0.994, 2000000, 2000000)
+	if math.Abs(back.Latitude-gc.Latitude) > 1e-9 || math.Abs(back.Longitude-gc.Longitude) > 1e-9 {
+		t.Errorf("InversePolarStereographic: expected %s, got %s", gc, back)
+	}
+}
+
+// ## WebMercator
+type webMercatorTest struct {
+	gc *PolarCoord
+	pt *GeoPoint
+}
+
+var webMercatorTests = []webMercatorTest{
+	{&PolarCoord{Latitude: 0, Longitude: 0}, &GeoPoint{X: 0, Y: 0}},
+	// EPSG Guidance Note 7-2, Popular Visualisation Pseudo Mercator
+	{&PolarCoord{Latitude: 24 + 22.0/60 + 54.433/3600, Longitude: -100 - 20.0/60}, &GeoPoint{X: -11169055.58, Y: 2800000.00}},
+	{&PolarCoord{Latitude: WebMercatorMaxLatitude, Longitude: 180}, &GeoPoint{X: 20037508.34, Y: 20037508.34}},
+	{&PolarCoord{Latitude: -33.86, Longitude: 151.2}, &GeoPoint{X: 16831507.01, Y: -4010018.90}},
+}
+
+func TestWebMercator(t *testing.T) {
+	for index, test := range webMercatorTests {
+		pt := WGS84LatLongToWebMercator(test.gc)
+		if math.Abs(test.pt.X-pt.X) > 0.01 || math.Abs(test.pt.Y-pt.Y) > 0.01 || pt.El != WGS84Ellipsoid {
+			t.Errorf("WGS84LatLongToWebMercator [%d]: expected %.2f %.2f, got %.2f %.2f", index, test.pt.X, test.pt.Y, pt.X, pt.Y)
+		}
+
+		gc := WebMercatorToWGS84LatLong(pt)
+		if !latlongequal(test.gc, gc) || gc.El != WGS84Ellipsoid {
+			t.Errorf("WebMercatorToWGS84LatLong [%d]: expected %s, got %s", index, test.gc, gc)
+		}
+	}
+
+	// the poles are clamped to the edge of the map
+	if pt := WGS84LatLongToWebMercator(&PolarCoord{Latitude: -90, Longitude: 0}); math.Abs(pt.Y+20037508.34) > 0.01 {
+		t.Errorf("WGS84LatLongToWebMercator: expected the southern edge of the map for the south pole, got %.2f", pt.Y)
+	}
+}
+
+type webMercatorToTileTest struct {
+	gc           *PolarCoord
+	zoom         int
+	tileX, tileY int
+}
+
+var webMercatorToTileTests = []webMercatorToTileTest{
+	{&PolarCoord{Latitude: 48.2082, Longitude: 16.3738}, 10, 558, 355},
+	{&PolarCoord{Latitude: 48.2082, Longitude: 16.3738}, 0, 0, 0},
+	{&PolarCoord{Latitude: 48.2082, Longitude: 16.3738}, -1, 0, 0},
+	{&PolarCoord{Latitude: 0, Longitude: 0}, 1, 1, 1},
+	{&PolarCoord{Latitude: -33.86, Longitude: 151.2}, 12, 3768, 2457},
+	// the edges of the map
+	{&PolarCoord{Latitude: 90, Longitude: 180}, 3, 7, 0},
+	{&PolarCoord{Latitude: -90, Longitude: -180}, 3, 0, 7},
+}
+
+func TestWebMercatorToTile(t *testing.T) {
+	for index, test := range webMercatorToTileTests {
+		pt := WGS84LatLongToWebMercator(test.gc)
+		tileX, tileY := WebMercatorToTile(pt.X, pt.Y, test.zoom)
+		if tileX != test.tileX || tileY != test.tileY {
+			t.Errorf("WebMercatorToTile [%d]: expected %d/%d, got %d/%d", index, test.tileX, test.tileY, tileX, tileY)
+		}
+	}
+}
+
 // ## ADegMMSSToNum
 type degMMSSToNumTest struct {
 	in  string
@@ -131,6 +372,13 @@ func TestDegMMSSToNum(t *testing.T) {
 			t.Errorf("ADegMMSSToNum [%d]: expected %f, got %f", index, test.out, out)
 		}
 	}
+
+	// a bare integer lacks the degree mark, digits lack the mark of minutes or seconds
+	for _, in := range []string{"70", "-70", "", "14°30", "14°30'27"} {
+		if _, err := ADegMMSSToNum(in); err == nil {
+			t.Errorf("ADegMMSSToNum: expected an error for %q", in)
+		}
+	}
 }
 
 // ## ADegCommaToNum
@@ -192,6 +440,101 @@ func TestLatLongToUTM(t *testing.T) {
 	}
 }
 
+// ## UTMZone
+type uTMZoneTest struct {
+	in   *PolarCoord
+	zone uint
+}
+
+var uTMZoneTests = []uTMZoneTest{
+	// the dateline normalizes to -180, the western boundary of zone 1, regardless of its sign
+	{&PolarCoord{Latitude: 0, Longitude: 180}, 1},
+	{&PolarCoord{Latitude: 0, Longitude: -180}, 1},
+	// west of the dateline lies zone 60, also for longitudes normalizing to less than 180
+	{&PolarCoord{Latitude: 0, Longitude: 179.999999}, 60},
+	{&PolarCoord{Latitude: 0, Longitude: -180.5}, 60},
+	{&PolarCoord{Latitude: 0, Longitude: 540}, 1},
+	{&PolarCoord{Latitude: 60, Longitude: 3}, 32},
+	{&PolarCoord{Latitude: 60, Longitude: 2.999999}, 31},
+	{&PolarCoord{Latitude: 60, Longitude: 12}, 33},
+	{&PolarCoord{Latitude: 64, Longitude: 3}, 31},
+	{&PolarCoord{Latitude: 78, Longitude: 0}, 31},
+	{&PolarCoord{Latitude: 78, Longitude: 9}, 33},
+	{&PolarCoord{Latitude: 78, Longitude: 21}, 35},
+	{&PolarCoord{Latitude: 78, Longitude: 33}, 37},
+	{&PolarCoord{Latitude: 78, Longitude: 42}, 38},
+}
+
+func TestUTMZone(t *testing.T) {
+	for index, test := range uTMZoneTests {
+		if zone := UTMZone(test.in); zone != test.zone {
+			t.Errorf("UTMZone [%d]: expected zone %d for longitude %f, got %d", index, test.zone, test.in.Longitude, zone)
+		}
+	}
+
+	// every zone boundary falls into the zone starting there
+	for zone := uint(1); zone <= 60; zone++ {
+		long := float64(zone-1)*6 - 180
+		if out := UTMZone(&PolarCoord{Latitude: 0, Longitude: long}); out != zone {
+			t.Errorf("UTMZone: expected zone %d for the boundary longitude %f, got %d", zone, long, out)
+		}
+	}
+}
+
+// ## UTMZoneParameters, UTMZoneLongitudes
+type uTMZoneParametersTest struct {
+	zone       uint
+	long0, fe  float64
+	west, east float64
+	err        error
+}
+
+var uTMZoneParametersTests = []uTMZoneParametersTest{
+	{1, -177, 500000, -180, -174, nil},
+	{31, 3, 500000, 0, 6, nil},
+	{33, 15, 500000, 12, 18, nil},
+	{60, 177, 500000, 174, 180, nil},
+	{0, 0, 0, 0, 0, ErrRange},
+	{61, 0, 0, 0, 0, ErrRange},
+}
+
+func TestUTMZoneParameters(t *testing.T) {
+	for index, test := range uTMZoneParametersTests {
+		long0, fe, err := UTMZoneParameters(test.zone)
+		if err != test.err || long0 != test.long0 || fe != test.fe {
+			t.Errorf("UTMZoneParameters [%d]: expected %f %f (%v), got %f %f (%v)", index, test.long0, test.fe, test.err, long0, fe, err)
+		}
+
+		west, east, err := UTMZoneLongitudes(test.zone)
+		if err != test.err || west != test.west || east != test.east {
+			t.Errorf("UTMZoneLongitudes [%d]: expected %f %f (%v), got %f %f (%v)", index, test.west, test.east, test.err, west, east, err)
+		}
+		if err == nil && UTMZone(&PolarCoord{Latitude: 0, Longitude: west}) != test.zone {
+			t.Errorf("UTMZoneLongitudes [%d]: expected the western boundary %f to lie in zone %d", index, west, test.zone)
+		}
+	}
+}
+
+func TestLatLongToUTMDateline(t *testing.T) {
+	east := LatLongToUTM(&PolarCoord{Latitude: -17.5, Longitude: 180})
+	west := LatLongToUTM(&PolarCoord{Latitude: -17.5, Longitude: -180})
+
+	if !utmabrequal(east, west) || east.Zone != "1K" {
+		t.Errorf("LatLongToUTM: expected the dateline in zone 1K regardless of its sign, got %s and %s", east, west)
+	}
+
+	// beyond the dateline, longitude -180.5 normalizes to 179.5, which lies in zone 60 east of its central meridian 177
+	beyond := LatLongToUTM(&PolarCoord{Latitude: -17.5, Longitude: -180.5})
+	if beyond.Zone != "60K" || beyond.Easting <= 500000 {
+		t.Errorf("LatLongToUTM: expected longitude -180.5 east of the central meridian of zone 60K, got %s", beyond)
+	}
+
+	latlong, err := UTMToLatLong(east)
+	if err != nil || !floatequal(latlong.Longitude, -180) {
+		t.Errorf("UTMToLatLong: expected longitude -180, got %s (%v)", latlong, err)
+	}
+}
+
 // ## AUTMToStruct
 type aUTMToStructTestParam struct {
 	utmcoord string
@@ -235,6 +578,38 @@ func TestAUTMToStruct(t *testing.T) {
 	}
 }
 
+var aUTMToStructErrorTests = []struct {
+	in  string
+	err error
+}{
+	{"bogus", ErrSyntax},
+	{"", ErrSyntax},
+	{"17T 630084.31", ErrSyntax},
+	{"17T 630084.31 4833438.548 12", ErrSyntax},
+	{"T 630084.31 4833438.548", ErrSyntax},
+	{"XYT 630084.31 4833438.548", ErrSyntax},
+	{"61T 630084.31 4833438.548", ErrRange},
+	{"0T 630084.31 4833438.548", ErrRange},
+	{"17I 630084.31 4833438.548", ErrRange},
+	{"17Y 630084.31 4833438.548", ErrRange},
+}
+
+func TestAUTMToStructErrors(t *testing.T) {
+	for index, test := range aUTMToStructErrorTests {
+		if _, err := AUTMToStruct(test.in, nil); err != test.err {
+			t.Errorf("AUTMToStruct [%d] '%s': expected error %v, got %v", index, test.in, test.err, err)
+		}
+	}
+
+	// the latitude band is normalized to upper case
+	if out, err := AUTMToStruct("17t 630084.31 4833438.548", nil); err != nil || out.Zone != "17T" {
+		t.Errorf("AUTMToStruct: expected zone 17T, got %v (%v)", out, err)
+	}
+	if _, err := AUTMToStruct("17T abc 4833438.548", nil); err == nil {
+		t.Errorf("AUTMToStruct: expected error for a malformed easting")
+	}
+}
+
 // ## UTMToLatLong
 type uTMToLatLongTest struct {
 	in  *UTMCoord
@@ -271,6 +646,209 @@ func TestUTMToLatLong(t *testing.T) {
 			t.Error("UTMToLatLong")
 		}
 	}
+
+	for _, zone := range []string{"", "T", "61T", "17Z"} {
+		if _, err := UTMToLatLong(&UTMCoord{Zone: zone, Easting: 630084.31, Northing: 4833438.548}); err == nil {
+			t.Errorf("UTMToLatLong: expected error for zone '%s'", zone)
+		}
+	}
+}
+
+// ## UTMRoundTrip
+// The positional error in meters of a latitude / longitude coordinate converted into an UTM coordinate and back, as the
+// transverse mercator projection is inverted to the millimeter
+var uTMRoundTripTests = []*PolarCoord{
+	{Latitude: 47.570299, Longitude: 14.236188},
+	{Latitude: -33.8688, Longitude: 151.2093},
+	{Latitude: 83.5, Longitude: -30.1},
+	{Latitude: -79.9, Longitude: 0.1},
+}
+
+func TestUTMRoundTrip(t *testing.T) {
+	for index, test := range uTMRoundTripTests {
+		out, err := UTMToLatLong(LatLongToUTM(&PolarCoord{Latitude: test.Latitude, Longitude: test.Longitude, El: WGS84Ellipsoid}))
+		if err != nil {
+			t.Fatalf("UTMRoundTrip [%d]: %s", index, err)
+		}
+		if !test.Equal(out, 0.001) {
+			t.Errorf("UTMRoundTrip [%d]: expected %s within 0.001 m, got %s", index, test, out)
+		}
+	}
+}
+
+// ## LatLongToUPS, UPSToLatLong
+type upsTest struct {
+	gc  *PolarCoord
+	ups string
+}
+
+var upsTests = []upsTest{
+	// EPSG Guidance Note 7-2, WGS 84 / UPS North
+	{&PolarCoord{Latitude: 73, Longitude: 44, El: WGS84Ellipsoid}, "Z 3320416.75 632668.43"},
+	// the poles
+	{&PolarCoord{Latitude: 90, Longitude: 0, El: WGS84Ellipsoid}, "Z 2000000.00 2000000.00"},
+	{&PolarCoord{Latitude: -90, Longitude: 0, El: WGS84Ellipsoid}, "B 2000000.00 2000000.00"},
+	// computed by the closed formulas of Snyder, "Map Projections - A Working Manual", p. 160
+	{&PolarCoord{Latitude: 87, Longitude: -62.3, El: WGS84Ellipsoid}, "Y 1705036.00 1845140.44"},
+	{&PolarCoord{Latitude: -85, Longitude: 139.27, El: WGS84Ellipsoid}, "B 2362433.32 1579078.39"},
+}
+
+func TestUPS(t *testing.T) {
+	for index, test := range upsTests {
+		ups := LatLongToUPS(test.gc)
+		if s := ups.Format(2); s != test.ups {
+			t.Errorf("LatLongToUPS [%d]: expected %s, got %s", index, test.ups, s)
+		}
+
+		parsed, err := AUTMToStruct(strings.ToLower(test.ups), WGS84Ellipsoid)
+		if err != nil {
+			t.Errorf("AUTMToStruct [%d]: %v", index, err)
+			continue
+		}
+		gc, err := UTMToLatLong(parsed)
+		if err != nil {
+			t.Errorf("UTMToLatLong [%d]: %v", index, err)
+			continue
+		}
+		if math.Abs(gc.Latitude-test.gc.Latitude) > 1e-6 || (math.Abs(gc.Latitude) < 90 && math.Abs(gc.Longitude-test.gc.Longitude) > 1e-6) {
+			t.Errorf("UTMToLatLong [%d]: expected %s, got %s", index, test.gc, gc)
+		}
+	}
+
+	if _, err := UPSToLatLong(&UTMCoord{Zone: "33U", Easting: 2000000, Northing: 2000000}); err != ErrRange {
+		t.Errorf("UPSToLatLong: expected ErrRange for a UTM zone, got %v", err)
+	}
+}
+
+// ## LatLongToUTMUPS
+func TestLatLongToUTMUPS(t *testing.T) {
+	for _, test := range []struct {
+		lat, long float64
+		zone      string
+	}{{84, 10, "32X"}, {84.1, 10, "Z"}, {-80, -10, "29C"}, {-80.1, -10, "A"}} {
+		if utm := LatLongToUTMUPS(&PolarCoord{Latitude: test.lat, Longitude: test.long}); utm.Zone != test.zone {
+			t.Errorf("LatLongToUTMUPS: expected zone %s for %g %g, got %s", test.zone, test.lat, test.long, utm.Zone)
+		}
+	}
+}
+
+// ## LatLongToSpanishUTM
+var latLongToSpanishUTMTests = []aLatLongToUTMTest{
+	{ // Madrid, Puerta del Sol
+		in:  &PolarCoord{Latitude: 40.416944, Longitude: -3.703611},
+		out: &UTMCoord{Zone: "30T", Easting: 440307.0, Northing: 4474273.0},
+	},
+	{ // Palma de Mallorca
+		in:  &PolarCoord{Latitude: 39.5696, Longitude: 2.6502},
+		out: &UTMCoord{Zone: "31S", Easting: 469955.0, Northing: 4380047.0},
+	},
+	{ // Santiago de Compostela
+		in:  &PolarCoord{Latitude: 42.88, Longitude: -8.545},
+		out: &UTMCoord{Zone: "29T", Easting: 537158.0, Northing: 4747590.0},
+	},
+	{ // Teide, Tenerife
+		in:  &PolarCoord{Latitude: 28.272361, Longitude: -16.6425},
+		out: &UTMCoord{Zone: "28R", Easting: 338901.0, Northing: 3128468.0},
+	},
+	{ // El Hierro, west of 18°W: still mapped in zone 28
+		in:  &PolarCoord{Latitude: 27.713, Longitude: -18.14},
+		out: &UTMCoord{Zone: "28R", Easting: 190365.0, Northing: 3069359.0},
+	},
+}
+
+func TestLatLongToSpanishUTM(t *testing.T) {
+	for index, test := range latLongToSpanishUTMTests {
+		out, err := LatLongToSpanishUTM(test.in)
+
+		if err != nil {
+			t.Errorf("LatLongToSpanishUTM [%d]: %s", index, err)
+			continue
+		}
+
+		if !utmabrequal(test.out, out) {
+			t.Errorf("LatLongToSpanishUTM [%d]: expected %s, got %s", index, test.out, out)
+		}
+
+		back, err := SpanishUTMToLatLong(out)
+		if err != nil {
+			t.Errorf("SpanishUTMToLatLong [%d]: %s", index, err)
+			continue
+		}
+
+		if !latlongequal(test.in, back) {
+			t.Errorf("SpanishUTMToLatLong [%d]: expected %s, got %s", index, test.in, back)
+		}
+	}
+}
+
+func TestSpanishUTMRange(t *testing.T) {
+	if _, err := LatLongToSpanishUTM(&PolarCoord{Latitude: 48.2, Longitude: 16.37}); err != ErrRange {
+		t.Errorf("LatLongToSpanishUTM: expected ErrRange for a coordinate outside of Spain, got %v", err)
+	}
+
+	if _, err := SpanishUTMToLatLong(&UTMCoord{Zone: "33T", Easting: 500000, Northing: 5300000}); err != ErrRange {
+		t.Errorf("SpanishUTMToLatLong: expected ErrRange for zone 33, got %v", err)
+	}
+}
+
+// ## LatLongToNTM
+type latLongToNTMTest struct {
+	in  *PolarCoord
+	out *NTMCoord
+	err error
+}
+
+// The projected coordinates agree to the millimeter with the Krüger series of sixth order (EPSG Guidance Note 7-2,
+// method 9807) for the parameters of EPSG:5105 to EPSG:5130, computed independently of this package.
+var latLongToNTMTests = []latLongToNTMTest{
+	// Oslo
+	{&PolarCoord{Latitude: 59.9139, Longitude: 10.7522}, &NTMCoord{Zone: 10, Easting: 114109.289, Northing: 1213224.482}, nil},
+	// Trondheim
+	{&PolarCoord{Latitude: 63.4305, Longitude: 10.3951}, &NTMCoord{Zone: 10, Easting: 94762.851, Northing: 1605091.896}, nil},
+	// Tromsø
+	{&PolarCoord{Latitude: 69.6492, Longitude: 18.9553}, &NTMCoord{Zone: 18, Easting: 117678.096, Northing: 2298628.735}, nil},
+	// Bergen at 5.32° E, in zone 5 west of its central meridian 5.5° E
+	{&PolarCoord{Latitude: 60.3913, Longitude: 5.3221}, &NTMCoord{Zone: 5, Easting: 90190.648, Northing: 1266400.313}, nil},
+	// Kirkenes
+	{&PolarCoord{Latitude: 69.7271, Longitude: 30.0456}, &NTMCoord{Zone: 30, Easting: 82421.484, Northing: 2307318.640}, nil},
+	// Copenhagen
+	{&PolarCoord{Latitude: 55.676, Longitude: 12.568}, nil, ErrRange},
+}
+
+func TestLatLongToNTM(t *testing.T) {
+	for index, test := range latLongToNTMTests {
+		out, err := LatLongToNTM(test.in)
+
+		if err != test.err {
+			t.Errorf("LatLongToNTM [%d]: expected error %v, got %v", index, test.err, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		if out.String() != test.out.String() || out.El != GRS80Ellipsoid {
+			t.Errorf("LatLongToNTM [%d]: expected %s, got %s", index, test.out, out)
+		}
+
+		latlong, err := NTMToLatLong(out)
+		if err != nil || !latlongequal(test.in, latlong) {
+			t.Errorf("NTMToLatLong [%d]: expected %s, got %s (%v)", index, test.in, latlong, err)
+		}
+	}
+
+	// Oslo in the neighboring zone
+	if out, err := LatLongToNTMZone(latLongToNTMTests[0].in, 11); err != nil || out.Zone != 11 || out.Easting > ntmFalseEasting {
+		t.Errorf("LatLongToNTMZone: expected a coordinate west of the central meridian of zone 11, got %s (%v)", out, err)
+	}
+	for _, zone := range []uint{4, 31} {
+		if _, err := LatLongToNTMZone(latLongToNTMTests[0].in, zone); err != ErrRange {
+			t.Errorf("LatLongToNTMZone: expected ErrRange for zone %d, got %v", zone, err)
+		}
+		if _, err := NTMToLatLong(&NTMCoord{Zone: zone, Easting: 100000, Northing: 1200000}); err != ErrRange {
+			t.Errorf("NTMToLatLong: expected ErrRange for zone %d, got %v", zone, err)
+		}
+	}
 }
 
 // ## ADegMMSSToPolar
@@ -310,77 +888,1150 @@ func TestADegMMSSToPolar(t *testing.T) {
 	}
 }
 
-// ## PolarToCartesian
-type polarToCartesianTest struct {
-	in  *PolarCoord
-	out *CartPoint
+// ## ADegMinSecToNum
+type aDegMinSecToNumTest struct {
+	in  string
+	out float64
+	err error
 }
 
-var polarToCartesianTests = []polarToCartesianTest{
-	{
-		&PolarCoord{Latitude: 47.567, Longitude: 14.243, El: WGS84Ellipsoid},
-		&CartPoint{Y: 1060748.224300, X: 4178845.984047, Z: 4684527.101880},
-	},
+var aDegMinSecToNumTests = []aDegMinSecToNumTest{
+	{`47°30'15"N`, 47.504167, nil},
+	{"W016 20.17", -16.336167, nil},
+	{"-16 20 10", -16.336111, nil},
+	{"47.57°", 47.57, nil},
+	{"14°0'27''", 14.0075, nil},
+	{"47 30 15 10", 0, ErrSyntax},
+	{"N", 0, ErrSyntax},
+	{"47 60", 0, ErrRange},
 }
 
-func cartequal(cp1, cp2 *CartPoint) bool {
-	p1 := fmt.Sprintf("%f %f %f", cp1.Y, cp1.X, cp1.Z)
-	p2 := fmt.Sprintf("%f %f %f", cp2.Y, cp2.X, cp2.Z)
+func TestADegMinSecToNum(t *testing.T) {
+	for index, test := range aDegMinSecToNumTests {
+		out, err := ADegMinSecToNum(test.in)
 
-	return p1 == p2
-}
+		if test.err != nil {
+			if ce, ok := err.(CartographyError); !ok || ce.Err != test.err {
+				t.Errorf("ADegMinSecToNum [%d]: expected error %v for %q, got %v", index, test.err, test.in, err)
+			}
+			continue
+		}
 
-func TestPolarToCartesian(t *testing.T) {
-	for index, test := range polarToCartesianTests {
-		out := PolarToCartesian(test.in)
-		if !cartequal(test.out, out) {
-			t.Errorf("PolarToCartesian [%d]: expected (%f %f %f), got (%f %f %f)",
-				index, test.out.Y, test.out.X, test.out.Z, out.Y, out.X, out.Z)
+		if err != nil || !floatequal(test.out, out) {
+			t.Errorf("ADegMinSecToNum [%d]: expected %f for %q, got %f (%v)", index, test.out, test.in, out, err)
 		}
 	}
 }
 
-// ## CartesianToPolar
-type cartesianToPolarTest struct {
-	in  *CartPoint
-	out *PolarCoord
+// ## ADegMinSecToPolar
+type aDegMinSecToPolarTest struct {
+	in        string
+	longFirst bool
+	out       *PolarCoord
+	err       error
 }
 
-var cartesianToPolarTests = []cartesianToPolarTest{
-	{
-		&CartPoint{Y: 1060748.224300, X: 4178845.984047, Z: 4684527.101880, El: WGS84Ellipsoid},
-		&PolarCoord{Latitude: 47.567, Longitude: 14.243},
-	},
+var aDegMinSecToPolarTests = []aDegMinSecToPolarTest{
+	{`47°30'15"N 16°20'10"E`, false, &PolarCoord{Latitude: 47.504167, Longitude: 16.336111}, nil},
+	{`47°30′15″N, 16°20′10″E`, false, &PolarCoord{Latitude: 47.504167, Longitude: 16.336111}, nil},
+	{"N47 30.25 E016 20.17", false, &PolarCoord{Latitude: 47.504167, Longitude: 16.336167}, nil},
+	{"S33° 55' 21.6'' E18° 25' 0.08''", false, &PolarCoord{Latitude: -33.922667, Longitude: 18.416689}, nil},
+	{"47.504167, 16.336111", false, &PolarCoord{Latitude: 47.504167, Longitude: 16.336111}, nil},
+	{"-33.86 151.2", false, &PolarCoord{Latitude: -33.86, Longitude: 151.2}, nil},
+	{"151.2;-33.86", true, &PolarCoord{Latitude: -33.86, Longitude: 151.2}, nil},
+	{"47 30 16 20", false, &PolarCoord{Latitude: 47.5, Longitude: 16.333333}, nil},
+	{"-79°23.5' 43°38.5'", true, &PolarCoord{Latitude: 43.641667, Longitude: -79.391667}, nil},
+	// the main directions take precedence over the order
+	{"16d20'W 47d30'S", false, &PolarCoord{Latitude: -47.5, Longitude: -16.333333}, nil},
+	{"16d20'W 47d30'S", true, &PolarCoord{Latitude: -47.5, Longitude: -16.333333}, nil},
+	{"47 30 16", false, nil, ErrAmbiguous},
+	{"47.5", false, nil, ErrAmbiguous},
+	{"N47 N16", false, nil, ErrSyntax},
+	{"N47 16", false, nil, ErrSyntax},
+	{"47.5 30 16 20", false, nil, ErrSyntax},
+	{"47°30'' 16°20'", false, &PolarCoord{Latitude: 47.008333, Longitude: 16.333333}, nil},
+	{"30' 47° 16° 20'", false, nil, ErrSyntax},
+	{"47x 16", false, nil, ErrSyntax},
+	{"", false, nil, ErrSyntax},
+	{"47 75 16 20", false, nil, ErrRange},
+	{"95 16", false, nil, ErrRange},
+	{"45 190", false, nil, ErrRange},
 }
 
-func TestCartesianToPolar(t *testing.T) {
-	for index, test := range cartesianToPolarTests {
-		out := CartesianToPolar(test.in)
-		if !latlongequal(test.out, out) {
-			t.Errorf("CartesianToPolar [%d]: expected (%f %f), got (%f %f)",
-				index, test.out.Latitude, test.out.Longitude, out.Latitude, out.Longitude)
+func TestADegMinSecToPolar(t *testing.T) {
+	for index, test := range aDegMinSecToPolarTests {
+		out, err := ADegMinSecToPolar(test.in, test.longFirst)
+
+		if test.err != nil {
+			if ce, ok := err.(CartographyError); !ok || ce.Err != test.err {
+				t.Errorf("ADegMinSecToPolar [%d]: expected error %v for %q, got %v", index, test.err, test.in, err)
+			}
+			continue
+		}
+
+		if err != nil || !latlongequal(test.out, out) || out.El != DefaultEllipsoid {
+			t.Errorf("ADegMinSecToPolar [%d]: expected %s for %q, got %s (%v)", index, test.out, test.in, out, err)
 		}
 	}
 }
 
-// ## Helmert
-type helmertTest struct {
-	in  *Point3D
-	out *Point3D
+// ## NewPolarRadians
+type newPolarRadiansTest struct {
+	latrad, lonrad float64
+	out            *PolarCoord
 }
 
-var helmertTests = []helmertTest{
-	{
-		&Point3D{Y: 4178845.984047, X: 1060748.2243, Z: 4684527.10188},
-		&Point3D{Y: 4178655.486121, X: 1060094.493596, Z: 4684148.315584},
-	},
+var newPolarRadiansTests = []newPolarRadiansTest{
+	{0, 0, &PolarCoord{Latitude: 0, Longitude: 0}},
+	{math.Pi / 4, -math.Pi / 2, &PolarCoord{Latitude: 45, Longitude: -90}},
+	{-math.Pi / 6, math.Pi, &PolarCoord{Latitude: -30, Longitude: 180}},
 }
 
-func point3dequal(p1, p2 *Point3D) bool {
-	pt1 := fmt.Sprintf("%f %f %f", p1.X, p1.Y, p1.Z)
-	pt2 := fmt.Sprintf("%f %f %f", p2.X, p2.Y, p2.Z)
+func TestNewPolarRadians(t *testing.T) {
+	for index, test := range newPolarRadiansTests {
+		out := NewPolarRadians(test.latrad, test.lonrad, nil)
 
-	return pt1 == pt2
+		if !polarequal(test.out, out) {
+			t.Errorf("NewPolarRadians [%d]: expected %s, got %s", index, test.out, out)
+		}
+
+		if out.El != DefaultEllipsoid {
+			t.Errorf("NewPolarRadians [%d]: expected the DefaultEllipsoid", index)
+		}
+
+		if !floatequal(test.latrad, out.LatRadians()) || !floatequal(test.lonrad, out.LonRadians()) {
+			t.Errorf("LatRadians/LonRadians [%d]: expected (%f, %f), got (%f, %f)", index, test.latrad, test.lonrad, out.LatRadians(), out.LonRadians())
+		}
+	}
+}
+
+// ## CheckFinite
+var nonFiniteValues = []float64{math.NaN(), math.Inf(1), math.Inf(-1)}
+
+func TestCheckFinite(t *testing.T) {
+	if err := CheckFinite(48.2, -16.37, 0, math.MaxFloat64); err != nil {
+		t.Errorf("CheckFinite: expected no error for finite values, got %v", err)
+	}
+	if err := CheckFinite(); err != nil {
+		t.Errorf("CheckFinite: expected no error for no values, got %v", err)
+	}
+	for _, value := range nonFiniteValues {
+		if err := CheckFinite(48.2, value); err != ErrNotFinite {
+			t.Errorf("CheckFinite (%v): expected ErrNotFinite, got %v", value, err)
+		}
+		if err := (&PolarCoord{Latitude: 48.2, Longitude: value}).CheckFinite(); err != ErrNotFinite {
+			t.Errorf("PolarCoord.CheckFinite (%v): expected ErrNotFinite, got %v", value, err)
+		}
+	}
+	if !errors.Is(ErrNotFinite, ErrRange) {
+		t.Errorf("ErrNotFinite: expected to wrap ErrRange")
+	}
+}
+
+// Every conversion has to reject a NaN or infinite coordinate instead of returning NaN
+func TestNonFiniteConversions(t *testing.T) {
+	for _, value := range nonFiniteValues {
+		valid := &PolarCoord{Latitude: 48.2, Longitude: 16.37, El: WGS84Ellipsoid}
+		lat := &PolarCoord{Latitude: value, Longitude: 16.37, El: WGS84Ellipsoid}
+		long := &PolarCoord{Latitude: 48.2, Longitude: value, El: WGS84Ellipsoid}
+
+		conversions := []struct {
+			name string
+			fn   func() error
+		}{
+			{"AUTMToStruct", func() error {
+				_, err := AUTMToStruct("33T "+strconv.FormatFloat(value, 'f', -1, 64)+" 5268987", nil)
+				return err
+			}},
+			{"UTMToLatLong", func() error {
+				_, err := UTMToLatLong(&UTMCoord{Zone: "33T", Easting: value, Northing: 5268987})
+				return err
+			}},
+			{"UPSToLatLong", func() error {
+				_, err := UPSToLatLong(&UTMCoord{Zone: "Z", Easting: 2000000, Northing: value})
+				return err
+			}},
+			{"LatLongToUTMZone", func() error { _, err := LatLongToUTMZone(lat, 33); return err }},
+			{"LatLongToUTMChecked", func() error { _, err := LatLongToUTMChecked(long); return err }},
+			{"LatLongToGeoHashChecked", func() error { _, err := LatLongToGeoHashChecked(lat); return err }},
+			{"PolarToCartesianChecked", func() error {
+				_, err := PolarToCartesianChecked(&PolarCoord{Latitude: 48.2, Longitude: 16.37, Height: value, El: WGS84Ellipsoid})
+				return err
+			}},
+			{"UTMGridCell", func() error {
+				_, err := UTMGridCell(&UTMCoord{Zone: "33T", Easting: 425351, Northing: value}, 1000)
+				return err
+			}},
+			{"SpanishUTMZone", func() error { _, err := SpanishUTMZone(long); return err }},
+			{"SpanishUTMToLatLong", func() error {
+				_, err := SpanishUTMToLatLong(&UTMCoord{Zone: "30S", Easting: value, Northing: 4474000})
+				return err
+			}},
+			{"NTMZone", func() error { _, err := NTMZone(long); return err }},
+			{"LatLongToNTMZone", func() error { _, err := LatLongToNTMZone(lat, 10); return err }},
+			{"NTMToLatLong", func() error {
+				_, err := NTMToLatLong(&NTMCoord{Zone: 10, Easting: value, Northing: 1000000})
+				return err
+			}},
+			{"NormalizeAxisOrder", func() error { _, _, err := NormalizeAxisOrder([2]float64{value, 16.37}, "EPSG:4326"); return err }},
+			{"AutoCorrectSwap", func() error { _, _, _, err := AutoCorrectSwap(48.2, value, WorldExtent); return err }},
+			{"Centroid", func() error { _, err := Centroid([]*PolarCoord{valid, lat}); return err }},
+			{"Vincenty", func() error { _, _, _, err := Vincenty(valid, long); return err }},
+			{"PolygonArea", func() error {
+				_, _, err := PolygonArea([]*PolarCoord{valid, {Latitude: 48.3, Longitude: 16.37}, lat})
+				return err
+			}},
+			{"BoundingCircle", func() error { _, _, err := BoundingCircle([]*PolarCoord{valid, long}); return err }},
+		}
+
+		for _, conversion := range conversions {
+			if err := conversion.fn(); err != ErrNotFinite {
+				t.Errorf("%s (%v): expected ErrNotFinite, got %v", conversion.name, value, err)
+			}
+		}
+	}
+}
+
+// ## PolarCoord.Equal
+type polarCoordEqualTest struct {
+	a, b      *PolarCoord
+	tolerance float64
+	equal     bool
+}
+
+var polarCoordEqualTests = []polarCoordEqualTest{
+	{&PolarCoord{Latitude: 48.2082, Longitude: 16.3738, El: WGS84Ellipsoid}, &PolarCoord{Latitude: 48.2082, Longitude: 16.3738, El: WGS84Ellipsoid}, 0, true},
+	// 1e-8 degrees of latitude are about 1.1 millimeters
+	{&PolarCoord{Latitude: 48.2082, Longitude: 16.3738, El: WGS84Ellipsoid}, &PolarCoord{Latitude: 48.20820001, Longitude: 16.3738, El: WGS84Ellipsoid}, 0.002, true},
+	{&PolarCoord{Latitude: 48.2082, Longitude: 16.3738, El: WGS84Ellipsoid}, &PolarCoord{Latitude: 48.20820001, Longitude: 16.3738, El: WGS84Ellipsoid}, 0.001, false},
+	// the height is not compared, a nil ellipsoid is the DefaultEllipsoid
+	{&PolarCoord{Latitude: 48.2082, Longitude: 16.3738, Height: 200, El: WGS84Ellipsoid}, &PolarCoord{Latitude: 48.2082, Longitude: 16.3738}, 0, true},
+	// across the antimeridian
+	{&PolarCoord{Latitude: 0, Longitude: 180, El: WGS84Ellipsoid}, &PolarCoord{Latitude: 0, Longitude: -179.99999999, El: WGS84Ellipsoid}, 0.002, true},
+	// different reference ellipsoids, non-finite coordinates, nearly antipodal coordinates and invalid tolerances
+	{&PolarCoord{Latitude: 48.2082, Longitude: 16.3738, El: WGS84Ellipsoid}, &PolarCoord{Latitude: 48.2082, Longitude: 16.3738, El: GRS80Ellipsoid}, 1000, false},
+	{&PolarCoord{Latitude: math.NaN(), Longitude: 16.3738, El: WGS84Ellipsoid}, &PolarCoord{Latitude: math.NaN(), Longitude: 16.3738, El: WGS84Ellipsoid}, 1000, false},
+	{&PolarCoord{Latitude: 0, Longitude: 0, El: WGS84Ellipsoid}, &PolarCoord{Latitude: 0.5, Longitude: 179.7, El: WGS84Ellipsoid}, math.Inf(1), false},
+	{&PolarCoord{Latitude: 48.2082, Longitude: 16.3738, El: WGS84Ellipsoid}, &PolarCoord{Latitude: 48.2082, Longitude: 16.3738, El: WGS84Ellipsoid}, -1, false},
+	{&PolarCoord{Latitude: 48.2082, Longitude: 16.3738, El: WGS84Ellipsoid}, &PolarCoord{Latitude: 48.2082, Longitude: 16.3738, El: WGS84Ellipsoid}, math.NaN(), false},
+}
+
+func TestPolarCoordEqual(t *testing.T) {
+	for index, test := range polarCoordEqualTests {
+		if equal := test.a.Equal(test.b, test.tolerance); equal != test.equal {
+			t.Errorf("PolarCoord.Equal [%d]: expected %t for %s and %s within %f m, got %t", index, test.equal, test.a, test.b, test.tolerance, equal)
+		}
+		if equal := test.b.Equal(test.a, test.tolerance); equal != test.equal {
+			t.Errorf("PolarCoord.Equal [%d]: expected %t for %s and %s within %f m, got %t", index, test.equal, test.b, test.a, test.tolerance, equal)
+		}
+	}
+}
+
+// ## SnapToGrid
+type snapToGridTest struct {
+	val, spacing, out float64
+}
+
+var snapToGridTests = []snapToGridTest{
+	{592269.4, 100, 592300},
+	{272249.9, 100, 272200},
+	// halfway values are rounded to the even multiple
+	{250, 100, 200},
+	{350, 100, 400},
+	{-250, 100, -200},
+	{-350, 100, -400},
+	{-1234.5, 10, -1230},
+	{0.75, 0.5, 1},
+	// a spacing which is not positive and finite leaves the value unchanged
+	{1234.5, 0, 1234.5},
+	{1234.5, -10, 1234.5},
+	{1234.5, math.Inf(1), 1234.5},
+	{1234.5, math.NaN(), 1234.5},
+}
+
+func TestSnapToGrid(t *testing.T) {
+	for cnt, test := range snapToGridTests {
+		if out := SnapToGrid(test.val, test.spacing); out != test.out {
+			t.Errorf("SnapToGrid [%d]: expected %v, got %v", cnt, test.out, out)
+		}
+	}
+
+	// snapping halfway values doesn't shift them on average
+	var sum float64
+	for val := 50.0; val < 100000; val += 100 {
+		sum += SnapToGrid(val, 100) - val
+	}
+	if sum != 0 {
+		t.Errorf("SnapToGrid: expected no bias of halfway values, got a sum of %v", sum)
+	}
+
+	utm := &UTMCoord{Zone: "33T", Easting: 425351.2, Northing: 5268986.5, El: WGS84Ellipsoid}
+	if out := utm.Snap(100); out.String() != "33T 425400 5269000" || out.El != WGS84Ellipsoid || utm.Easting != 425351.2 {
+		t.Errorf("UTMCoord.Snap: expected 33T 425400 5269000 leaving the coordinate unchanged, got %s and %s", out, utm)
+	}
+	ntm := &NTMCoord{Zone: 10, Easting: 100049.9995, Northing: 1200000.0005}
+	if out := ntm.Snap(0.001); out.String() != "NTM10 100050.000 1200000.000" {
+		t.Errorf("NTMCoord.Snap: expected NTM10 100050.000 1200000.000, got %s", out)
+	}
+}
+
+// ## FormatFixed
+type formatFixedTest struct {
+	val          float64
+	prec         int
+	fixed, trimd string
+}
+
+var formatFixedTests = []formatFixedTest{
+	{272290, 0, "272290", "272290"},
+	{272290, 2, "272290.00", "272290"},
+	{235.5, 6, "235.500000", "235.5"},
+	{-235.5, 2, "-235.50", "-235.5"},
+	{1.25, -1, "1.25", "1.25"},
+	// rounding up across the decimal point
+	{9.996, 2, "10.00", "10"},
+	{-9.996, 2, "-10.00", "-10"},
+	{99.5, 0, "100", "100"},
+	{0.0999, 2, "0.10", "0.1"},
+	// values rounding to zero lose their sign
+	{-0.001, 2, "0.00", "0"},
+	{-0.4, 0, "0", "0"},
+	{math.Copysign(0, -1), 3, "0.000", "0"},
+}
+
+func TestFormatFixed(t *testing.T) {
+	for index, test := range formatFixedTests {
+		if out := FormatFixed(test.val, test.prec); out != test.fixed {
+			t.Errorf("FormatFixed [%d]: expected %s, got %s", index, test.fixed, out)
+		}
+		if out := FormatTrimmed(test.val, test.prec); out != test.trimd {
+			t.Errorf("FormatTrimmed [%d]: expected %s, got %s", index, test.trimd, out)
+		}
+	}
+}
+
+// ## DecimalMark
+func TestDecimalMark(t *testing.T) {
+	for _, test := range []struct {
+		spec string
+		mark DecimalMark
+		err  error
+	}{
+		{"", DecimalPoint, nil},
+		{".", DecimalPoint, nil},
+		{"Point", DecimalPoint, nil},
+		{",", DecimalComma, nil},
+		{" comma ", DecimalComma, nil},
+		{";", DecimalPoint, ErrSyntax},
+	} {
+		if mark, err := ParseDecimalMark(test.spec); mark != test.mark || err != test.err {
+			t.Errorf("ParseDecimalMark \"%s\": expected %q (%v), got %q (%v)", test.spec, test.mark, test.err, mark, err)
+		}
+	}
+
+	for literal, expected := range map[string]string{
+		"33T 594807.52 5339087.5": "33T 594807,52 5339087,5",
+		"N 48°12'30.25''":         "N 48°12'30,25''",
+		"-16.5":                   "-16,5",
+		"272290":                  "272290",
+		"v1. .5 5.":               "v1. .5 5.",
+	} {
+		if out := DecimalComma.Localize(literal); out != expected {
+			t.Errorf("DecimalMark.Localize: expected %s, got %s", expected, out)
+		}
+		if out := DecimalPoint.Localize(literal); out != literal {
+			t.Errorf("DecimalMark.Localize: expected %s unchanged with the decimal point, got %s", literal, out)
+		}
+	}
+
+	if out := DecimalComma.FormatFixed(-235.5, 2); out != "-235,50" {
+		t.Errorf("DecimalMark.FormatFixed: expected -235,50, got %s", out)
+	}
+	if out := DecimalComma.FormatTrimmed(9.996, 2); out != "10" {
+		t.Errorf("DecimalMark.FormatTrimmed: expected 10, got %s", out)
+	}
+}
+
+func TestUTMFormat(t *testing.T) {
+	utm := &UTMCoord{Zone: "33T", Easting: 425351.4951, Northing: 5268987.004}
+	if out := utm.Format(2); out != "33T 425351.50 5268987.00" {
+		t.Errorf("UTMCoord.Format: expected 33T 425351.50 5268987.00, got %s", out)
+	}
+	if out := utm.Format(0); out != utm.String() {
+		t.Errorf("UTMCoord.Format: expected %s, got %s", utm.String(), out)
+	}
+}
+
+// ## MarshalFixedJSON
+type marshalFixedJSONTest struct {
+	in        interface{}
+	precision int
+	out       string
+}
+
+var marshalFixedJSONTests = []marshalFixedJSONTest{
+	{&PolarCoord{Latitude: 1e-7, Longitude: -5e-10, Height: 1e22, El: WGS84Ellipsoid}, -1,
+		`{"latitude":0.0000001,"longitude":-0.0000000005,"height":10000000000000000000000,"ellipsoid":"WGS84"}`},
+	{&GeoPoint{X: 425351.5, Y: 5268987.25, El: Bessel1841MGIEllipsoid}, 2, `{"x":425351.50,"y":5268987.25,"h":0.00,"ellipsoid":"Bessel1841MGI"}`},
+	{&CartPoint{X: 4160000, Y: 1055000, Z: 4683000}, 0, `{"x":4160000,"y":1055000,"z":4683000}`},
+	{&UTMCoord{Northing: 5268987.123456, Easting: 425351.5, Zone: "33T"}, 2,
+		`{"northing":5268987.12,"easting":425351.50,"zone":"33T"}`},
+	{&Point3D{X: 1e21, Y: -1e-21, Z: 0}, 0, `{"x":1000000000000000000000,"y":-0,"z":0}`},
+	{&struct {
+		A float64 `json:"a"`
+		B float64 `json:",omitempty"`
+		C float64 `json:"-"`
+		d float64
+	}{A: 2.5e-8, C: 1, d: 1}, -1, `{"a":0.000000025}`},
+	// the fields of embedded structs are promoted, hidden by the fields of the same name of the embedding struct
+	{&struct {
+		*GeoPoint
+		Point3D
+		Zone string  `json:"zone"`
+		Z    float64 `json:"z,omitempty"`
+	}{GeoPoint: &GeoPoint{X: 1.5e-7, Y: 2, El: WGS84Ellipsoid}, Point3D: Point3D{X: 1, Y: 2, Z: 3}, Zone: "33T"}, -1,
+		`{"h":0,"ellipsoid":"WGS84","zone":"33T"}`},
+	// an embedded struct named by its tag is no embedded one, the fields of a nil embedded struct are left out
+	{&struct {
+		*UTMCoord `json:"utm"`
+		*CartPoint
+	}{UTMCoord: &UTMCoord{Northing: 1e22, Easting: 425351.5, Zone: "33T"}}, 0,
+		`{"utm":{"northing":10000000000000000000000,"easting":425352,"zone":"33T"}}`},
+}
+
+// a number in exponent form
+var exponent = regexp.MustCompile(`[0-9][eE][-+]?[0-9]`)
+
+func TestMarshalFixedJSON(t *testing.T) {
+	defer func(precision int) { JSONPrecision = precision }(JSONPrecision)
+
+	for index, test := range marshalFixedJSONTests {
+		JSONPrecision = test.precision
+		out, err := json.Marshal(test.in)
+		if _, ok := test.in.(json.Marshaler); !ok {
+			out, err = MarshalFixedJSON(test.in)
+		}
+
+		if err != nil || string(out) != test.out {
+			t.Errorf("MarshalFixedJSON [%d]: expected %s, got %s (%v)", index, test.out, out, err)
+		}
+		if exponent.Match(out) {
+			t.Errorf("MarshalFixedJSON [%d]: output contains an exponent: %s", index, out)
+		}
+	}
+
+	if _, err := json.Marshal(&GeoPoint{X: math.NaN()}); err == nil {
+		t.Errorf("MarshalFixedJSON: expected an error for NaN")
+	}
+}
+
+// ## MarshalFixedXML
+var marshalFixedXMLTests = []marshalFixedJSONTest{
+	{&PolarCoord{Latitude: 1e-7, Longitude: -5e-10, Height: 1e22, El: WGS84Ellipsoid}, -1,
+		`<PolarCoord><latitude>0.0000001</latitude><longitude>-0.0000000005</longitude><height>10000000000000000000000</height><ellipsoid>WGS84</ellipsoid></PolarCoord>`},
+	{&UTMCoord{Northing: 5268987.123456, Easting: 425351.5, Zone: "33T"}, 2,
+		`<UTMCoord><northing>5268987.12</northing><easting>425351.50</easting><zone>33T</zone></UTMCoord>`},
+	{&Point3D{X: 1e21, Y: -1e-21, Z: 0}, 0, `<Point3D><x>1000000000000000000000</x><y>-0</y><z>0</z></Point3D>`},
+	{&struct {
+		XMLName xml.Name `xml:"s"`
+		A       float64  `xml:"a"`
+		B       float64  `xml:",omitempty"`
+		C       float64  `xml:"-"`
+		d       float64
+	}{A: 2.5e-8, C: 1, d: 1}, -1, `<s><a>0.000000025</a></s>`},
+}
+
+func TestMarshalFixedXML(t *testing.T) {
+	defer func(precision int) { JSONPrecision = precision }(JSONPrecision)
+
+	for index, test := range marshalFixedXMLTests {
+		JSONPrecision = test.precision
+		out, err := xml.Marshal(test.in)
+		if _, ok := test.in.(xml.Marshaler); !ok {
+			buf := new(bytes.Buffer)
+			enc := xml.NewEncoder(buf)
+			if err = MarshalFixedXML(enc, xml.StartElement{Name: xml.Name{Local: "s"}}, test.in); err == nil {
+				err = enc.Flush()
+			}
+			out = buf.Bytes()
+		}
+
+		if err != nil || string(out) != test.out {
+			t.Errorf("MarshalFixedXML [%d]: expected %s, got %s (%v)", index, test.out, out, err)
+		}
+		if exponent.Match(out) {
+			t.Errorf("MarshalFixedXML [%d]: output contains an exponent: %s", index, out)
+		}
+	}
+
+	// the digits of XML and JSON agree
+	JSONPrecision = -1
+	gc := &PolarCoord{Latitude: 47.570299123456789, Longitude: 14.236188, El: WGS84Ellipsoid}
+	jsonout, _ := json.Marshal(gc)
+	xmlout, _ := xml.Marshal(gc)
+	if !strings.Contains(string(jsonout), `"latitude":47.57029912345679`) || !strings.Contains(string(xmlout), `<latitude>47.57029912345679</latitude>`) {
+		t.Errorf("MarshalFixedXML: expected the same digits as JSON, got %s and %s", xmlout, jsonout)
+	}
+}
+
+// ## ParseAxisOrder
+type parseAxisOrderTest struct {
+	spec  string
+	order AxisOrder
+	err   error
+}
+
+var parseAxisOrderTests = []parseAxisOrderTest{
+	{"lat,long", AxisLatLong, nil},
+	{"lat,lon", AxisLatLong, nil},
+	{"LatLong", AxisLatLong, nil},
+	{"long,lat", AxisLongLat, nil},
+	{" lon , lat ", AxisLongLat, nil},
+	{"lonlat", AxisLongLat, nil},
+	{"", AxisLatLong, ErrSyntax},
+	{"lat", AxisLatLong, ErrSyntax},
+	{"lat,long,lat", AxisLatLong, ErrSyntax},
+	{"x,y", AxisLatLong, ErrSyntax},
+}
+
+func TestParseAxisOrder(t *testing.T) {
+	for _, test := range parseAxisOrderTests {
+		order, err := ParseAxisOrder(test.spec)
+
+		if err != test.err || order != test.order {
+			t.Errorf("ParseAxisOrder %q: expected %s, %v, got %s, %v", test.spec, test.order, test.err, order, err)
+		}
+	}
+}
+
+// ## LatLongToStringOrder
+func TestLatLongToStringOrder(t *testing.T) {
+	pc := &PolarCoord{Latitude: 48.5, Longitude: -16.25}
+
+	if first, second := LatLongToStringOrder(pc, LLFdeg, AxisLatLong); first != "48.5" || second != "-16.25" {
+		t.Errorf("LatLongToStringOrder: expected 48.5 -16.25, got %s %s", first, second)
+	}
+	if first, second := LatLongToStringOrder(pc, LLFdms, AxisLongLat); first != "W 16°15'" || second != "N 48°30'" {
+		t.Errorf("LatLongToStringOrder: expected W 16°15' N 48°30', got %s %s", first, second)
+	}
+	if out := pc.StringOrder(AxisLongLat); out != "long: -16.25°, lat: 48.5°" {
+		t.Errorf("StringOrder: expected long: -16.25°, lat: 48.5°, got %s", out)
+	}
+	if out := pc.String(); out != "lat: 48.5°, long: -16.25°" {
+		t.Errorf("String: expected lat: 48.5°, long: -16.25°, got %s", out)
+	}
+}
+
+// ## NormalizeAxisOrder
+type normalizeAxisOrderTest struct {
+	values    [2]float64
+	crs       string
+	lat, long float64
+	err       error
+}
+
+var normalizeAxisOrderTests = []normalizeAxisOrderTest{
+	{[2]float64{48.2, 16.37}, "EPSG:4326", 48.2, 16.37, nil},
+	{[2]float64{48.2, 16.37}, "urn:ogc:def:crs:EPSG::4326", 48.2, 16.37, nil},
+	{[2]float64{16.37, 48.2}, CRS84, 48.2, 16.37, nil},
+	{[2]float64{16.37, 48.2}, "urn:ogc:def:crs:OGC:1.3:CRS84", 48.2, 16.37, nil},
+	// geographic, but not of the datum WGS84
+	{[2]float64{51.5, -0.12}, "EPSG:4277", 0, 0, ErrUnsupported},
+	// projected, of the datum WGS84 and unknown
+	{[2]float64{425351, 5268987}, "EPSG:32633", 0, 0, ErrUnsupported},
+	{[2]float64{16.37, 48.2}, "EPSG:3857", 0, 0, ErrUnsupported},
+	{[2]float64{48.2, 16.37}, "4326", 0, 0, ErrUnsupported},
+}
+
+func TestNormalizeAxisOrder(t *testing.T) {
+	for index, test := range normalizeAxisOrderTests {
+		lat, long, err := NormalizeAxisOrder(test.values, test.crs)
+
+		if err != test.err {
+			t.Errorf("NormalizeAxisOrder [%d]: expected error %v, got %v", index, test.err, err)
+		}
+
+		if !floatequal(test.lat, lat) || !floatequal(test.long, long) {
+			t.Errorf("NormalizeAxisOrder [%d]: expected (%f, %f), got (%f, %f)", index, test.lat, test.long, lat, long)
+		}
+	}
+}
+
+// ## AutoCorrectSwap
+type autoCorrectSwapTest struct {
+	lat, long   float64
+	extent      *LatLongExtent
+	clat, clong float64
+	swapped     bool
+	err         error
+}
+
+var austria = &LatLongExtent{MinLat: 46.3, MaxLat: 49.1, MinLong: 9.5, MaxLong: 17.2}
+
+var autoCorrectSwapTests = []autoCorrectSwapTest{
+	{48.2, 16.37, austria, 48.2, 16.37, false, nil},
+	{16.37, 48.2, austria, 48.2, 16.37, true, nil},
+	// Sydney given as long / lat is only valid in the swapped order
+	{151.2, -33.86, WorldExtent, -33.86, 151.2, true, nil},
+	{48.2, 16.37, WorldExtent, 48.2, 16.37, false, ErrAmbiguous},
+	{10, 10, WorldExtent, 10, 10, false, nil},
+	{-33.86, 151.2, austria, -33.86, 151.2, false, ErrRange},
+	{84.5, 10, UTMExtent, 10, 84.5, true, nil},
+}
+
+func TestAutoCorrectSwap(t *testing.T) {
+	for index, test := range autoCorrectSwapTests {
+		lat, long, swapped, err := AutoCorrectSwap(test.lat, test.long, test.extent)
+
+		if err != test.err || swapped != test.swapped || !floatequal(test.clat, lat) || !floatequal(test.clong, long) {
+			t.Errorf("AutoCorrectSwap [%d]: expected (%f, %f, %t, %v), got (%f, %f, %t, %v)", index,
+				test.clat, test.clong, test.swapped, test.err, lat, long, swapped, err)
+		}
+	}
+}
+
+// ## LatLongExtent.Validate
+func TestExtentValidate(t *testing.T) {
+	if err := austria.Validate(&PolarCoord{Latitude: 48.2, Longitude: 16.37}, "test", "a"); err != nil {
+		t.Errorf("Validate: expected no error within the extent, got %v", err)
+	}
+
+	gc := &PolarCoord{Latitude: -33.86, Longitude: 151.2}
+	err := austria.Validate(gc, "test", "b")
+	if ee, ok := err.(ExtentError); !ok || ee.System != "test" || ee.Coord != "b" || ee.Location != gc || ee.Extent != austria {
+		t.Errorf("Validate: expected an ExtentError, got %#v", err)
+	}
+}
+
+type utmValidTest struct {
+	in    *UTMCoord
+	valid bool
+}
+
+var utmValidTests = []utmValidTest{
+	{&UTMCoord{Zone: "33U", Easting: 601000, Northing: 5340000}, true},
+	{&UTMCoord{Zone: "56H", Easting: 334000, Northing: 6252000}, true},
+	// beyond the north pole
+	{&UTMCoord{Zone: "33X", Easting: 500000, Northing: 9990000}, false},
+	// UPS around the poles and too far from them
+	{&UTMCoord{Zone: "Z", Easting: 2000000, Northing: 2000000}, true},
+	{&UTMCoord{Zone: "A", Easting: 1900000, Northing: 2000000}, true},
+	{&UTMCoord{Zone: "Z", Easting: 3320416.75, Northing: 632668.43}, false},
+}
+
+func TestUTMValid(t *testing.T) {
+	for index, test := range utmValidTests {
+		err := test.in.Valid()
+		if _, ok := err.(ExtentError); (err == nil) != test.valid || (err != nil && !ok) {
+			t.Errorf("Valid [%d]: expected valid %t for %s, got %v", index, test.valid, test.in, err)
+		}
+	}
+
+	if err := (&UTMCoord{Zone: "61U", Easting: 601000, Northing: 5340000}).Valid(); err == nil {
+		t.Error("Valid: expected an error for an invalid zone")
+	}
+}
+
+// ## SampleGrid
+func TestSampleGrid(t *testing.T) {
+	extent := &LatLongExtent{MinLat: 46, MaxLat: 49, MinLong: 9, MaxLong: 17}
+
+	points, err := SampleGrid(extent, 4, 3, false)
+	if err != nil || len(points) != 12 {
+		t.Fatalf("SampleGrid: expected 12 coordinates, got %d (%v)", len(points), err)
+	}
+	for index, expected := range map[int]*PolarCoord{
+		0:  {Latitude: 46, Longitude: 9},
+		1:  {Latitude: 46, Longitude: 13},
+		5:  {Latitude: 47, Longitude: 17},
+		11: {Latitude: 49, Longitude: 17},
+	} {
+		if !latlongequal(expected, points[index]) || points[index].El != DefaultEllipsoid {
+			t.Errorf("SampleGrid [%d]: expected %s, got %s", index, expected, points[index])
+		}
+	}
+
+	if points, err = SampleGrid(extent, 1, 1, false); err != nil || !latlongequal(points[0], &PolarCoord{Latitude: 47.5, Longitude: 13}) {
+		t.Errorf("SampleGrid: expected the middle of the extent for a single coordinate, got %v (%v)", points, err)
+	}
+
+	// the projected lattice is evenly spaced on the map, but not in longitude
+	points, err = SampleGrid(extent, 3, 5, true)
+	if err != nil || len(points) != 15 {
+		t.Fatalf("SampleGrid: expected 15 projected coordinates, got %d (%v)", len(points), err)
+	}
+	var dx []float64
+	for _, gc := range points[10:] {
+		dx = append(dx, DirectTransverseMercator(gc, 0, 13, 1, 0, 0).X)
+	}
+	for i := 2; i < len(dx); i++ {
+		if math.Abs((dx[i]-dx[i-1])-(dx[1]-dx[0])) > 1e-3 {
+			t.Errorf("SampleGrid: expected evenly spaced eastings, got %v", dx)
+		}
+	}
+	if !latlongequal(points[7], &PolarCoord{Latitude: points[7].Latitude, Longitude: 13}) || floatequal(points[11].Longitude-points[10].Longitude, 2) {
+		t.Errorf("SampleGrid: expected a lattice regular in the projection, got %s, %s, %s", points[7], points[10], points[11])
+	}
+
+	for _, test := range []struct {
+		extent     *LatLongExtent
+		rows, cols int
+	}{
+		{extent, 0, 3},
+		{extent, 3, -1},
+		{&LatLongExtent{MinLat: 49, MaxLat: 46, MinLong: 9, MaxLong: 17}, 3, 3},
+	} {
+		if _, err := SampleGrid(test.extent, test.rows, test.cols, false); err != ErrRange {
+			t.Errorf("SampleGrid: expected ErrRange for %d by %d coordinates in %v, got %v", test.rows, test.cols, test.extent, err)
+		}
+	}
+}
+
+// ## LatLongExtent.Polygon, UTMZonePolygon
+func TestPolygon(t *testing.T) {
+	extent := &LatLongExtent{MinLat: 46, MaxLat: 49, MinLong: 9, MaxLong: 17}
+
+	points, err := extent.Polygon(1)
+	if err != nil || len(points) != 5 {
+		t.Fatalf("Polygon: expected the four corners and the closing coordinate, got %d (%v)", len(points), err)
+	}
+	for index, expected := range []*PolarCoord{{Latitude: 46, Longitude: 9}, {Latitude: 46, Longitude: 17},
+		{Latitude: 49, Longitude: 17}, {Latitude: 49, Longitude: 9}, {Latitude: 46, Longitude: 9}} {
+		if !latlongequal(points[index], expected) {
+			t.Errorf("Polygon [%d]: expected %s, got %s", index, expected, points[index])
+		}
+	}
+	if points[0] == points[4] {
+		t.Error("Polygon: expected the closing coordinate to be a copy of the first one")
+	}
+
+	if points, err = extent.Polygon(4); err != nil || len(points) != 17 {
+		t.Fatalf("Polygon: expected 17 coordinates, got %d (%v)", len(points), err)
+	}
+	if !latlongequal(points[1], &PolarCoord{Latitude: 46, Longitude: 11}) || !latlongequal(points[6], &PolarCoord{Latitude: 47.5, Longitude: 17}) {
+		t.Errorf("Polygon: expected densified edges, got %s, %s", points[1], points[6])
+	}
+
+	if _, err = extent.Polygon(0); err != ErrRange {
+		t.Errorf("Polygon: expected ErrRange for no segments, got %v", err)
+	}
+	if _, err = (&LatLongExtent{MinLat: 49, MaxLat: 46, MinLong: 9, MaxLong: 17}).Polygon(1); err != ErrRange {
+		t.Errorf("Polygon: expected ErrRange for an empty extent, got %v", err)
+	}
+
+	points, err = UTMZonePolygon(33, 2)
+	if err != nil || len(points) != 9 {
+		t.Fatalf("UTMZonePolygon: expected 9 coordinates, got %d (%v)", len(points), err)
+	}
+	if !latlongequal(points[0], &PolarCoord{Latitude: -80, Longitude: 12}) || !latlongequal(points[4], &PolarCoord{Latitude: 84, Longitude: 18}) ||
+		!latlongequal(points[3], &PolarCoord{Latitude: 2, Longitude: 18}) {
+		t.Errorf("UTMZonePolygon: expected the bounds of zone 33, got %s, %s, %s", points[0], points[3], points[4])
+	}
+	if _, err = UTMZonePolygon(61, 2); err != ErrRange {
+		t.Errorf("UTMZonePolygon: expected ErrRange for zone 61, got %v", err)
+	}
+}
+
+// ## PolarToCartesian
+type polarToCartesianTest struct {
+	in  *PolarCoord
+	out *CartPoint
+}
+
+var polarToCartesianTests = []polarToCartesianTest{
+	{
+		&PolarCoord{Latitude: 47.567, Longitude: 14.243, El: WGS84Ellipsoid},
+		&CartPoint{Y: 1060748.224300, X: 4178845.984047, Z: 4684527.101880},
+	},
+}
+
+func cartequal(cp1, cp2 *CartPoint) bool {
+	p1 := fmt.Sprintf("%f %f %f", cp1.Y, cp1.X, cp1.Z)
+	p2 := fmt.Sprintf("%f %f %f", cp2.Y, cp2.X, cp2.Z)
+
+	return p1 == p2
+}
+
+func TestPolarToCartesian(t *testing.T) {
+	for index, test := range polarToCartesianTests {
+		out := PolarToCartesian(test.in)
+		if !cartequal(test.out, out) {
+			t.Errorf("PolarToCartesian [%d]: expected (%f %f %f), got (%f %f %f)",
+				index, test.out.Y, test.out.X, test.out.Z, out.Y, out.X, out.Z)
+		}
+	}
+}
+
+// ## CartesianToPolar
+type cartesianToPolarTest struct {
+	in  *CartPoint
+	out *PolarCoord
+}
+
+var cartesianToPolarTests = []cartesianToPolarTest{
+	{
+		&CartPoint{Y: 1060748.224300, X: 4178845.984047, Z: 4684527.101880, El: WGS84Ellipsoid},
+		&PolarCoord{Latitude: 47.567, Longitude: 14.243},
+	},
+}
+
+func TestCartesianToPolar(t *testing.T) {
+	for index, test := range cartesianToPolarTests {
+		out := CartesianToPolar(test.in)
+		if !latlongequal(test.out, out) {
+			t.Errorf("CartesianToPolar [%d]: expected (%f %f), got (%f %f)",
+				index, test.out.Latitude, test.out.Longitude, out.Latitude, out.Longitude)
+		}
+	}
+}
+
+// ## WGS84LatLongToECEF
+type eCEFTest struct {
+	gc *PolarCoord
+	cp *CartPoint
+}
+
+var eCEFTests = []eCEFTest{
+	{
+		&PolarCoord{Latitude: 47.567, Longitude: 14.243, El: WGS84Ellipsoid},
+		&CartPoint{X: 4178845.984047, Y: 1060748.224300, Z: 4684527.101880},
+	},
+	// the reference ellipsoid is assumed to be WGS84
+	{
+		&PolarCoord{Latitude: 47.567, Longitude: 14.243, El: Bessel1841Ellipsoid},
+		&CartPoint{X: 4178845.984047, Y: 1060748.224300, Z: 4684527.101880},
+	},
+	{
+		&PolarCoord{Latitude: 0, Longitude: 0, Height: 100, El: WGS84Ellipsoid},
+		&CartPoint{X: 6378237, Y: 0, Z: 0},
+	},
+	{
+		&PolarCoord{Latitude: 0, Longitude: 90, El: WGS84Ellipsoid},
+		&CartPoint{X: 0, Y: 6378137, Z: 0},
+	},
+	{
+		&PolarCoord{Latitude: 90, Longitude: 0, Height: 1000, El: WGS84Ellipsoid},
+		&CartPoint{X: 0, Y: 0, Z: 6357752.314245},
+	},
+	{
+		&PolarCoord{Latitude: -90, Longitude: 0, Height: -50, El: WGS84Ellipsoid},
+		&CartPoint{X: 0, Y: 0, Z: -6356702.314245},
+	},
+}
+
+func TestWGS84LatLongToECEF(t *testing.T) {
+	for index, test := range eCEFTests {
+		out := WGS84LatLongToECEF(test.gc)
+		if math.Abs(out.X-test.cp.X) > 1e-5 || math.Abs(out.Y-test.cp.Y) > 1e-5 || math.Abs(out.Z-test.cp.Z) > 1e-5 {
+			t.Errorf("WGS84LatLongToECEF [%d]: expected (%f %f %f), got (%f %f %f)",
+				index, test.cp.X, test.cp.Y, test.cp.Z, out.X, out.Y, out.Z)
+		}
+		if out.El != WGS84Ellipsoid {
+			t.Errorf("WGS84LatLongToECEF [%d]: expected the WGS84 ellipsoid, got %s", index, out.El.CommonName)
+		}
+	}
+}
+
+func TestECEFToWGS84LatLong(t *testing.T) {
+	for index, test := range eCEFTests {
+		out := ECEFToWGS84LatLong(test.cp)
+		if math.Abs(out.Latitude-test.gc.Latitude) > 1e-9 || math.Abs(out.Longitude-test.gc.Longitude) > 1e-9 || math.Abs(out.Height-test.gc.Height) > 1e-4 {
+			t.Errorf("ECEFToWGS84LatLong [%d]: expected (%f %f %f), got (%f %f %f)",
+				index, test.gc.Latitude, test.gc.Longitude, test.gc.Height, out.Latitude, out.Longitude, out.Height)
+		}
+		if out.El != WGS84Ellipsoid {
+			t.Errorf("ECEFToWGS84LatLong [%d]: expected the WGS84 ellipsoid, got %s", index, out.El.CommonName)
+		}
+	}
+}
+
+// ## ENUToWGS84
+type eNUTest struct {
+	enu    Point3D
+	origin *PolarCoord
+	out    *PolarCoord
+}
+
+var eNUTests = []eNUTest{
+	{Point3D{}, &PolarCoord{Latitude: 47.57, Longitude: 14.0075, Height: 500}, &PolarCoord{Latitude: 47.57, Longitude: 14.0075, Height: 500}},
+	// straight up
+	{Point3D{Z: 1000}, &PolarCoord{Latitude: 47.57, Longitude: 14.0075, Height: 500}, &PolarCoord{Latitude: 47.57, Longitude: 14.0075, Height: 1500}},
+	// one arc minute along the meridian at the equator is approximately a nautical mile
+	{Point3D{Y: 1842.9}, &PolarCoord{}, &PolarCoord{Latitude: 1.0 / 60, Height: 0.27}},
+	{Point3D{X: 1855.325}, &PolarCoord{}, &PolarCoord{Longitude: 1.0 / 60, Height: 0.27}},
+}
+
+func TestENUToWGS84(t *testing.T) {
+	for index, test := range eNUTests {
+		out := ENUToWGS84(test.enu, test.origin)
+
+		if !floatequal(test.out.Latitude, out.Latitude) || !floatequal(test.out.Longitude, out.Longitude) ||
+			fmt.Sprintf("%.2f", test.out.Height) != fmt.Sprintf("%.2f", out.Height) {
+			t.Errorf("ENUToWGS84 [%d]: expected %s height %f, got %s height %f", index, test.out, test.out.Height, out, out.Height)
+		}
+
+		enu := WGS84ToENU(out, test.origin)
+		if !helmertfuzzyequal(&test.enu, &enu) || math.Abs(test.enu.Z-enu.Z) > 0.001 {
+			t.Errorf("WGS84ToENU [%d]: expected %v, got %v", index, test.enu, enu)
+		}
+	}
+}
+
+// ## NEDToWGS84
+func TestNEDToWGS84(t *testing.T) {
+	origin := &PolarCoord{Latitude: 47.57, Longitude: 14.0075, Height: 500}
+
+	for index, test := range []struct{ ned, enu Point3D }{
+		{Point3D{X: 100, Y: -20, Z: -5}, Point3D{X: -20, Y: 100, Z: 5}},
+		{Point3D{Z: 250}, Point3D{Z: -250}},
+	} {
+		out := NEDToWGS84(test.ned, origin)
+		expected := ENUToWGS84(test.enu, origin)
+		if *out != *expected {
+			t.Errorf("NEDToWGS84 [%d]: expected %s, got %s", index, expected, out)
+		}
+
+		ned := WGS84ToNED(out, origin)
+		if !helmertfuzzyequal(&test.ned, &ned) || math.Abs(test.ned.Z-ned.Z) > 0.001 {
+			t.Errorf("WGS84ToNED [%d]: expected %v, got %v", index, test.ned, ned)
+		}
+	}
+
+	// down is below the origin
+	if below := NEDToWGS84(Point3D{Z: 250}, origin); fmt.Sprintf("%.3f", below.Height) != "250.000" {
+		t.Errorf("NEDToWGS84: expected height 250, got %f", below.Height)
+	}
+}
+
+// ## Ellipsoid registry
+func TestEllipsoidByName(t *testing.T) {
+	for _, el := range []*Ellipsoid{WGS84Ellipsoid, GRS80Ellipsoid, Bessel1841Ellipsoid, Airy1830Ellipsoid, Clarke1866Ellipsoid, International1924Ellipsoid} {
+		if out, err := EllipsoidByName(el.CommonName); err != nil || out != el {
+			t.Errorf("EllipsoidByName: expected %s, got %v (%v)", el.CommonName, out, err)
+		}
+	}
+
+	if _, err := EllipsoidByName("Everest1830"); err != ErrNotFound {
+		t.Errorf("EllipsoidByName: expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestRegisterEllipsoid(t *testing.T) {
+	el := NewEllipsoid(6377276.345, 6356075.413, "Everest1830")
+	if err := RegisterEllipsoid("TEST_Everest1830", el); err != nil {
+		t.Fatalf("RegisterEllipsoid: %s", err)
+	}
+
+	if err := RegisterEllipsoid("TEST_Everest1830", el); err != ErrDuplicate {
+		t.Errorf("RegisterEllipsoid: expected ErrDuplicate, got %v", err)
+	}
+
+	if err := RegisterEllipsoid("TEST_nil", nil); err != ErrSyntax {
+		t.Errorf("RegisterEllipsoid: expected ErrSyntax, got %v", err)
+	}
+
+	if out, err := EllipsoidByName("TEST_Everest1830"); err != nil || out != el {
+		t.Errorf("EllipsoidByName: expected %v, got %v (%v)", el, out, err)
+	}
+
+	found := false
+	names := EllipsoidNames()
+	for index, name := range names {
+		if index > 0 && names[index-1] >= name {
+			t.Errorf("EllipsoidNames: expected names in alphabetical order, got %v", names)
+		}
+		found = found || name == "TEST_Everest1830"
+	}
+	if !found {
+		t.Errorf("EllipsoidNames: expected TEST_Everest1830 to be listed, got %v", names)
+	}
+}
+
+// ## Ellipsoid derived parameters
+type ellipsoidParametersTest struct {
+	el                     *Ellipsoid
+	invf, esq, ep2, thirdf float64
+}
+
+var ellipsoidParametersTests = []ellipsoidParametersTest{
+	// derived from the axes as defined by the package, the semi-minor axes being rounded
+	{WGS84Ellipsoid, 298.257223630, 0.00669437998863, 0.00673949674075, 0.00167922038600},
+	{GRS80Ellipsoid, 298.257222096, 0.00669438002301, 0.00673949677559, 0.00167922039466},
+	{Bessel1841Ellipsoid, 299.152812853, 0.00667437223061, 0.00671921879797, 0.00167418480082},
+}
+
+func TestEllipsoidParameters(t *testing.T) {
+	for index, test := range ellipsoidParametersTests {
+		el := test.el
+		if invf := 1 / el.Flattening(); math.Abs(invf-test.invf) > 1e-6 {
+			t.Errorf("Flattening [%d]: expected 1/%f, got 1/%f", index, test.invf, invf)
+		}
+		if esq := el.EccentricitySquared(); math.Abs(esq-test.esq) > 1e-12 {
+			t.Errorf("EccentricitySquared [%d]: expected %.14f, got %.14f", index, test.esq, esq)
+		}
+		if e := el.Eccentricity(); math.Abs(e*e-test.esq) > 1e-12 {
+			t.Errorf("Eccentricity [%d]: expected %.14f, got %.14f", index, math.Sqrt(test.esq), e)
+		}
+		if ep2 := el.SecondEccentricitySquared(); math.Abs(ep2-test.ep2) > 1e-12 {
+			t.Errorf("SecondEccentricitySquared [%d]: expected %.14f, got %.14f", index, test.ep2, ep2)
+		}
+		if n := el.ThirdFlattening(); math.Abs(n-test.thirdf) > 1e-12 {
+			t.Errorf("ThirdFlattening [%d]: expected %.14f, got %.14f", index, test.thirdf, n)
+		}
+	}
+}
+
+// The parameters of an ellipsoid are computed on first use, which may happen concurrently. Run with -race.
+func TestEllipsoidParametersConcurrent(t *testing.T) {
+	el := NewEllipsoid(6377276.345, 6356075.413, "Everest1830")
+	want := 1 - el.b/el.a
+
+	var wg sync.WaitGroup
+	errs := make(chan float64, 16)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pc := &PolarCoord{Latitude: 27.988, Longitude: 86.925, El: el}
+			back := CartesianToPolar(PolarToCartesian(pc))
+			if f := el.Flattening(); f != want {
+				errs <- f
+			}
+			if math.Abs(back.Latitude-pc.Latitude) > 1e-9 {
+				errs <- back.Latitude
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for value := range errs {
+		t.Errorf("Ellipsoid concurrent use: expected flattening %v and latitude 27.988, got %v", want, value)
+	}
+}
+
+// ## CRS registry
+type byEPSGTest struct {
+	code      int
+	name      string
+	el        *Ellipsoid
+	projected bool
+	err       error
+}
+
+var byEPSGTests = []byEPSGTest{
+	{4326, "WGS 84", WGS84Ellipsoid, false, nil},
+	{4258, "ETRS89", GRS80Ellipsoid, false, nil},
+	{4277, "OSGB 1936", Airy1830Ellipsoid, false, nil},
+	{32633, "WGS 84 / UTM zone 33N", WGS84Ellipsoid, true, nil},
+	{32760, "WGS 84 / UTM zone 60S", WGS84Ellipsoid, true, nil},
+	{25830, "ETRS89 / UTM zone 30N", GRS80Ellipsoid, true, nil},
+	{4230, "ED50", International1924Ellipsoid, false, nil},
+	{23031, "ED50 / UTM zone 31N", International1924Ellipsoid, true, nil},
+	{32661, "", nil, false, ErrNotFound},
+	{0, "", nil, false, ErrNotFound},
+}
+
+func TestByEPSG(t *testing.T) {
+	// the zones of UTM project like LatLongToUTM
+	gc := &PolarCoord{Latitude: 47.57, Longitude: 14.0075, El: WGS84Ellipsoid}
+	utm := LatLongToUTM(gc)
+	if crs, err := ByEPSG(utm.EPSG()); err != nil {
+		t.Errorf("ByEPSG: expected the CRS of %s, got %v", utm, err)
+	} else if gp, _ := crs.Project(gc); math.Abs(gp.X-utm.Easting) > 1e-6 || math.Abs(gp.Y-utm.Northing) > 1e-6 {
+		t.Errorf("CRS.Project: expected %s, got %f %f", utm, gp.X, gp.Y)
+	}
+
+	for index, test := range byEPSGTests {
+		crs, err := ByEPSG(test.code)
+		if err != test.err {
+			t.Errorf("ByEPSG [%d]: expected error %v, got %v", index, test.err, err)
+			continue
+		}
+		if err == nil && (crs.EPSG != test.code || crs.Name != test.name || crs.El != test.el || crs.Projected() != test.projected) {
+			t.Errorf("ByEPSG [%d]: expected %s, got %v", index, test.name, crs)
+		}
+	}
+
+	if WGS84Ellipsoid.EPSG() != 7030 || GRS80Ellipsoid.EPSG() != 7019 || Bessel1841MGIEllipsoid.EPSG() != 0 {
+		t.Errorf("Ellipsoid.EPSG: expected 7030, 7019 and 0, got %d, %d and %d", WGS84Ellipsoid.EPSG(), GRS80Ellipsoid.EPSG(), Bessel1841MGIEllipsoid.EPSG())
+	}
+}
+
+func TestRegisterCRS(t *testing.T) {
+	crs := &CRS{EPSG: 999999, Name: "TEST_CRS", Datum: DatumWGS84, El: WGS84Ellipsoid}
+	if err := RegisterCRS(crs); err != nil {
+		t.Fatalf("RegisterCRS: %s", err)
+	}
+
+	if err := RegisterCRS(crs); err != ErrDuplicate {
+		t.Errorf("RegisterCRS: expected ErrDuplicate, got %v", err)
+	}
+	if err := RegisterCRS(&CRS{Name: "TEST_no code"}); err != ErrSyntax {
+		t.Errorf("RegisterCRS: expected ErrSyntax, got %v", err)
+	}
+	if err := RegisterCRS(nil); err != ErrSyntax {
+		t.Errorf("RegisterCRS: expected ErrSyntax, got %v", err)
+	}
+
+	if out, err := ByEPSG(999999); err != nil || out != crs {
+		t.Errorf("ByEPSG: expected %v, got %v (%v)", crs, out, err)
+	}
+
+	codes := EPSGCodes()
+	for index := 1; index < len(codes); index++ {
+		if codes[index-1] >= codes[index] {
+			t.Fatalf("EPSGCodes: expected codes in ascending order, got %v", codes)
+		}
+	}
+	if codes[len(codes)-1] != 999999 {
+		t.Errorf("EPSGCodes: expected 999999 to be listed last, got %d", codes[len(codes)-1])
+	}
+}
+
+type utmEPSGTest struct {
+	utm  *UTMCoord
+	epsg int
+}
+
+var utmEPSGTests = []utmEPSGTest{
+	{&UTMCoord{Zone: "33T", El: WGS84Ellipsoid}, 32633},
+	{&UTMCoord{Zone: "33t", El: WGS84Ellipsoid}, 32633},
+	{&UTMCoord{Zone: "56H", El: WGS84Ellipsoid}, 32756},
+	{&UTMCoord{Zone: "30T", El: GRS80Ellipsoid}, 25830},
+	// ETRS89 has no zones beyond Europe
+	{&UTMCoord{Zone: "56H", El: GRS80Ellipsoid}, 32756},
+	{&UTMCoord{Zone: "61T", El: WGS84Ellipsoid}, 0},
+	{&UTMCoord{Zone: "Y", El: WGS84Ellipsoid}, 32661},
+	{&UTMCoord{Zone: "B", El: WGS84Ellipsoid}, 32761},
+}
+
+func TestUTMEPSG(t *testing.T) {
+	for index, test := range utmEPSGTests {
+		if out := test.utm.EPSG(); out != test.epsg {
+			t.Errorf("UTMCoord.EPSG [%d]: expected %d, got %d", index, test.epsg, out)
+		}
+	}
+}
+
+// ## Helmert
+type helmertTest struct {
+	in  *Point3D
+	out *Point3D
+}
+
+var helmertTests = []helmertTest{
+	{
+		&Point3D{Y: 4178845.984047, X: 1060748.2243, Z: 4684527.10188},
+		&Point3D{Y: 4178656.311003, X: 1060094.493596, Z: 4684148.315584},
+	},
+}
+
+func point3dequal(p1, p2 *Point3D) bool {
+	pt1 := fmt.Sprintf("%f %f %f", p1.X, p1.Y, p1.Z)
+	pt2 := fmt.Sprintf("%f %f %f", p2.X, p2.Y, p2.Z)
+
+	return pt1 == pt2
 }
 
 // the inversion of a helmert is not totaly accurate; let's assume an accuracy of 2 meters
@@ -389,91 +2040,1479 @@ func helmertfuzzyequal(p1, p2 *Point3D) bool {
 	return math.Sqrt(math.Pow(p1.X-p2.X, 2)+math.Pow(p1.Y-p2.Y, 2)+math.Pow(p1.Z-p2.Z, 2)) < 2.0
 }
 
-func TestHelmertTransform(t *testing.T) {
-	for index, test := range helmertTests {
-		out := HelmertWGS84ToMGI.Transform(test.in)
-		if !point3dequal(test.out, out) {
-			t.Errorf("HelmertTransform [%d]: expected (%f %f %f), got (%f %f %f)",
-				index, test.out.X, test.out.Y, test.out.Z, out.X, out.Y, out.Z)
+func TestHelmertTransform(t *testing.T) {
+	for index, test := range helmertTests {
+		out := HelmertWGS84ToMGI.Transform(test.in)
+		if !point3dequal(test.out, out) {
+			t.Errorf("HelmertTransform [%d]: expected (%f %f %f), got (%f %f %f)",
+				index, test.out.X, test.out.Y, test.out.Z, out.X, out.Y, out.Z)
+		}
+	}
+}
+
+func TestHelmertParameters(t *testing.T) {
+	dx, dy, dz, dM, drx, dry, drz := HelmertWGS84ToMGI.Parameters()
+	if dx != -577.326 || dy != -90.129 || dz != -463.919 || dM != -2.4232 || drx != 5.1366 || dry != 1.4742 || drz != 5.2970 {
+		t.Errorf("HelmertParameters: expected the parameters of WGS84toMGI, got (%f, %f, %f, %f, %f, %f, %f)", dx, dy, dz, dM, drx, dry, drz)
+	}
+	if datum := HelmertWGS84ToMGI.Datum(); datum != "WGS84toMGI" {
+		t.Errorf("HelmertParameters: expected WGS84toMGI, got %s", datum)
+	}
+
+	// a transformation created from the parameters is equal to the original one
+	hp := NewHelmertTransformer(dx, dy, dz, dM, drx, dry, drz, HelmertWGS84ToMGI.Datum())
+	if hp.String() != HelmertWGS84ToMGI.String() {
+		t.Errorf("HelmertParameters: expected %s, got %s", HelmertWGS84ToMGI, hp)
+	}
+}
+
+func TestHelmertInverseTransform(t *testing.T) {
+	for index, test := range helmertTests {
+		out := HelmertWGS84ToMGI.InverseTransform(test.out)
+		if !helmertfuzzyequal(test.in, out) {
+			t.Errorf("HelmertInverseTransform [%d]: expected (%f %f %f), got (%f %f %f)",
+				index, test.in.X, test.in.Y, test.in.Z, out.X, out.Y, out.Z)
+		}
+	}
+}
+
+// Worked example of the Ordnance Survey, "A guide to coordinate systems in Great Britain", section 6.6
+func TestHelmertOSGB36(t *testing.T) {
+	etrs89 := &Point3D{X: 3790644.900, Y: -110149.210, Z: 5111482.970}
+	osgb36 := &Point3D{X: 3790269.549, Y: -110038.064, Z: 5111050.261}
+
+	out := HelmertWGS84ToOSGB36.Transform(etrs89)
+	if fmt.Sprintf("%.3f %.3f %.3f", out.X, out.Y, out.Z) != fmt.Sprintf("%.3f %.3f %.3f", osgb36.X, osgb36.Y, osgb36.Z) {
+		t.Errorf("HelmertTransform: expected (%.3f %.3f %.3f), got (%.3f %.3f %.3f)", osgb36.X, osgb36.Y, osgb36.Z, out.X, out.Y, out.Z)
+	}
+
+	// the inverse transformation is exact
+	back := HelmertWGS84ToOSGB36.InverseTransform(out)
+	if !point3dequal(etrs89, back) {
+		t.Errorf("HelmertInverseTransform: expected (%f %f %f), got (%f %f %f)", etrs89.X, etrs89.Y, etrs89.Z, back.X, back.Y, back.Z)
+	}
+}
+
+// ## HelmertParameterSet registry
+func TestHelmertParameterSets(t *testing.T) {
+	sets := HelmertParameterSets(DatumWGS84, DatumOSGB36)
+	if len(sets) != 2 || sets[0].Name != "OSGB36_3param_NIMA" || sets[1].Name != "OSGB36_7param_OS" {
+		t.Fatalf("HelmertParameterSets: unexpected parameter sets %v", sets)
+	}
+
+	def, err := DefaultHelmertParameterSet(DatumWGS84, DatumOSGB36)
+	if err != nil || def != sets[1] || !def.IsDefault() || sets[0].IsDefault() {
+		t.Errorf("DefaultHelmertParameterSet: expected %s, got %v (%v)", sets[1].Name, def, err)
+	}
+
+	if len(HelmertParameterSets("", "")) < len(sets) {
+		t.Errorf("HelmertParameterSets: expected an empty datum to match every parameter set")
+	}
+
+	if _, err := DefaultHelmertParameterSet(DatumMGI, DatumOSGB36); err != ErrNotFound {
+		t.Errorf("DefaultHelmertParameterSet: expected ErrNotFound, got %v", err)
+	}
+}
+
+// Meades Ranch, Kansas, the origin of NAD27, and its NAD83 position by NADCON, which is taken as WGS84
+func TestNAD27ToWGS84(t *testing.T) {
+	set, err := DefaultHelmertParameterSet(DatumNAD27, DatumWGS84)
+	if err != nil {
+		t.Fatalf("DefaultHelmertParameterSet: %s", err)
+	}
+
+	nad27 := &PolarCoord{Latitude: 39 + 13/60.0 + 26.686/3600, Longitude: -(98 + 32/60.0 + 30.506/3600), El: Clarke1866Ellipsoid}
+	expected := &PolarCoord{Latitude: 39 + 13/60.0 + 26.7122/3600, Longitude: -(98 + 32/60.0 + 31.7454/3600), El: WGS84Ellipsoid}
+
+	pt := PolarToCartesian(nad27)
+	p3d := set.Transform(&Point3D{X: pt.X, Y: pt.Y, Z: pt.Z})
+	out := CartesianToPolar(&CartPoint{X: p3d.X, Y: p3d.Y, Z: p3d.Z, El: WGS84Ellipsoid})
+
+	// the mean shift of the conterminous United States is accurate to several meters
+	if d := Haversine(expected, out, 0); d > set.Accuracy {
+		t.Errorf("NAD27 to WGS84: expected %s within %.0f m, got %s, %.1f m off", expected, set.Accuracy, out, d)
+	}
+}
+
+// The example point of the abridged Molodensky transformation in the North Sea of EPSG Guidance Note 7-2, given in
+// ED50 / UTM zone 31N, and its WGS84 position
+func TestED50ToWGS84(t *testing.T) {
+	set, err := DefaultHelmertParameterSet(DatumED50, DatumWGS84)
+	if err != nil {
+		t.Fatalf("DefaultHelmertParameterSet: %s", err)
+	}
+	crs, err := ByEPSG(EPSGED50UTM + 31)
+	if err != nil {
+		t.Fatalf("ByEPSG: %s", err)
+	}
+
+	ed50, err := crs.InverseProject(&GeoPoint{X: 442774.221, Y: 5962877.651, El: International1924Ellipsoid})
+	if err != nil {
+		t.Fatalf("CRS.InverseProject: %s", err)
+	}
+	expected := &PolarCoord{Latitude: 53 + 48/60.0 + 33.82/3600, Longitude: 2 + 7/60.0 + 46.38/3600, El: WGS84Ellipsoid}
+
+	pt := PolarToCartesian(ed50)
+	p3d := set.Transform(&Point3D{X: pt.X, Y: pt.Y, Z: pt.Z})
+	out := CartesianToPolar(&CartPoint{X: p3d.X, Y: p3d.Y, Z: p3d.Z, El: WGS84Ellipsoid})
+
+	// a single shift for the whole of Western Europe is accurate to several meters only
+	if d := Haversine(expected, out, 0); d > set.Accuracy {
+		t.Errorf("ED50 to WGS84: expected %s within %.0f m, got %s, %.1f m off", expected, set.Accuracy, out, d)
+	}
+}
+
+func TestRegisterHelmertParameterSet(t *testing.T) {
+	set := &HelmertParameterSet{Name: "TEST_3param", From: "TESTFROM", To: "TESTTO", HelmertTransform: NewHelmertTransformer(1, 2, 3, 0, 0, 0, 0, "test")}
+	if err := RegisterHelmertParameterSet(set, false); err != nil {
+		t.Fatalf("RegisterHelmertParameterSet: %s", err)
+	}
+
+	if err := RegisterHelmertParameterSet(set, false); err != ErrDuplicate {
+		t.Errorf("RegisterHelmertParameterSet: expected ErrDuplicate, got %v", err)
+	}
+
+	if err := RegisterHelmertParameterSet(&HelmertParameterSet{Name: "TEST_noparams"}, false); err != ErrSyntax {
+		t.Errorf("RegisterHelmertParameterSet: expected ErrSyntax, got %v", err)
+	}
+
+	second := &HelmertParameterSet{Name: "TEST_7param", From: "TESTFROM", To: "TESTTO", HelmertTransform: NewHelmertTransformer(1, 2, 3, 1, 1, 1, 1, "test")}
+	if err := RegisterHelmertParameterSet(second, true); err != nil {
+		t.Fatalf("RegisterHelmertParameterSet: %s", err)
+	}
+
+	if out, _ := DefaultHelmertParameterSet("TESTFROM", "TESTTO"); out != second {
+		t.Errorf("DefaultHelmertParameterSet: expected TEST_7param to become the default, got %v", out)
+	}
+
+	if out, err := HelmertParameterSetByName("TEST_3param"); err != nil || out != set {
+		t.Errorf("HelmertParameterSetByName: expected %v, got %v (%v)", set, out, err)
+	}
+
+	if _, err := HelmertParameterSetByName("TEST_unknown"); err != ErrNotFound {
+		t.Errorf("HelmertParameterSetByName: expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestSelectHelmertParameterSet(t *testing.T) {
+	params := NewHelmertTransformer(1, 2, 3, 0, 0, 0, 0, "test")
+	global := &HelmertParameterSet{Name: "SELECT_global", From: "SELECTFROM", To: "SELECTTO", Accuracy: 10, HelmertTransform: params}
+	country := &HelmertParameterSet{Name: "SELECT_country", From: "SELECTFROM", To: "SELECTTO", Accuracy: 1,
+		Extent: &LatLongExtent{MinLat: 46, MaxLat: 49, MinLong: 9, MaxLong: 17}, HelmertTransform: params}
+	city := &HelmertParameterSet{Name: "SELECT_city", From: "SELECTFROM", To: "SELECTTO", Accuracy: 0.5,
+		Extent: &LatLongExtent{MinLat: 48, MaxLat: 48.5, MinLong: 16, MaxLong: 16.8}, HelmertTransform: params}
+	unknown := &HelmertParameterSet{Name: "SELECT_unknown", From: "SELECTFROM", To: "SELECTTO",
+		Extent: &LatLongExtent{MinLat: 0, MaxLat: 10, MinLong: 0, MaxLong: 10}, HelmertTransform: params}
+	for _, set := range []*HelmertParameterSet{global, country, city, unknown} {
+		if err := RegisterHelmertParameterSet(set, set == global); err != nil {
+			t.Fatalf("RegisterHelmertParameterSet: %s", err)
+		}
+	}
+
+	for index, test := range []struct {
+		gc  *PolarCoord
+		set *HelmertParameterSet
+	}{
+		{&PolarCoord{Latitude: 48.2, Longitude: 16.37}, city},
+		{&PolarCoord{Latitude: 47.27, Longitude: 11.4}, country},
+		// bounds included
+		{&PolarCoord{Latitude: 49, Longitude: 9}, country},
+		{&PolarCoord{Latitude: 51.5, Longitude: -0.1}, global},
+		// parameter sets of unknown accuracy rank last
+		{&PolarCoord{Latitude: 5, Longitude: 5}, global},
+	} {
+		if set, err := SelectHelmertParameterSet("SELECTFROM", "SELECTTO", test.gc); err != nil || set != test.set {
+			t.Errorf("SelectHelmertParameterSet [%d]: expected %s, got %v (%v)", index, test.set.Name, set, err)
+		}
+	}
+
+	// the more accurate of the shipped parameter sets, regardless of the default
+	if set, err := SelectHelmertParameterSet(DatumWGS84, DatumOSGB36, &PolarCoord{Latitude: 51.5, Longitude: -0.1}); err != nil || set.Name != "OSGB36_7param_OS" {
+		t.Errorf("SelectHelmertParameterSet: expected OSGB36_7param_OS, got %v (%v)", set, err)
+	}
+	if _, err := SelectHelmertParameterSet(DatumMGI, DatumOSGB36, &PolarCoord{Latitude: 48.2, Longitude: 16.37}); err != ErrNotFound {
+		t.Errorf("SelectHelmertParameterSet: expected ErrNotFound, got %v", err)
+	}
+	if _, err := SelectHelmertParameterSet("", DatumMGI, &PolarCoord{Latitude: 48.2, Longitude: 16.37}); err != ErrNotFound {
+		t.Errorf("SelectHelmertParameterSet: expected ErrNotFound for an empty datum, got %v", err)
+	}
+	if _, err := SelectHelmertParameterSet(DatumWGS84, DatumMGI, &PolarCoord{Latitude: math.NaN(), Longitude: 16.37}); err != ErrNotFinite {
+		t.Errorf("SelectHelmertParameterSet: expected ErrNotFinite, got %v", err)
+	}
+}
+
+// ## LoadHelmertParameterSets
+func TestLoadHelmertParameterSets(t *testing.T) {
+	sets, err := LoadHelmertParameterSets(strings.NewReader(`[
+		{"Name": "LOAD_7param", "From": "LOADFROM", "To": "LOADTO", "Accuracy": 0.5, "Source": "test",
+		 "Extent": {"MinLat": 46, "MaxLat": 49, "MinLong": 9, "MaxLong": 17},
+		 "TranslationMeters": [-577.326, -90.129, -463.919], "RotationArcSeconds": [5.1366, 1.4742, 5.2970], "ScalePPM": -2.4232},
+		{"Name": "LOAD_3param", "From": "LOADFROM", "To": "LOADTO", "Accuracy": 5, "Default": true,
+		 "TranslationMeters": [-577, -90, -464]}
+	]`))
+	if err != nil || len(sets) != 2 {
+		t.Fatalf("LoadHelmertParameterSets: expected 2 parameter sets, got %v (%v)", sets, err)
+	}
+	if set, err := SelectHelmertParameterSet("LOADFROM", "LOADTO", &PolarCoord{Latitude: 48.2, Longitude: 16.37}); err != nil || set != sets[0] {
+		t.Errorf("SelectHelmertParameterSet: expected LOAD_7param, got %v (%v)", set, err)
+	}
+	if set, err := DefaultHelmertParameterSet("LOADFROM", "LOADTO"); err != nil || set != sets[1] {
+		t.Errorf("DefaultHelmertParameterSet: expected LOAD_3param, got %v (%v)", set, err)
+	}
+
+	pt := &Point3D{X: 4194423, Y: 1045913, Z: 4682318}
+	if out, expected := sets[0].Transform(pt), HelmertWGS84ToMGI.Transform(pt); math.Abs(out.X-expected.X) > 1e-6 ||
+		math.Abs(out.Y-expected.Y) > 1e-6 || math.Abs(out.Z-expected.Z) > 1e-6 {
+		t.Errorf("LoadHelmertParameterSets: expected the parameters of HelmertWGS84ToMGI, got %v instead of %v", out, expected)
+	}
+
+	for index, test := range []struct {
+		file  string
+		index int
+		err   error
+	}{
+		{`[{"Name": "LOAD_noto", "From": "LOADFROM", "TranslationMeters": [1, 2, 3]}]`, 0, ErrSyntax},
+		{`[{"Name": "LOAD_same", "From": "LOADFROM", "To": "LOADFROM", "TranslationMeters": [1, 2, 3]}]`, 0, ErrSyntax},
+		{`[{"Name": "LOAD_two", "From": "LOADFROM", "To": "LOADTO", "TranslationMeters": [1, 2]}]`, 0, ErrSyntax},
+		{`[{"Name": "LOAD_rot", "From": "LOADFROM", "To": "LOADTO", "TranslationMeters": [1, 2, 3], "RotationArcSeconds": [1]}]`, 0, ErrSyntax},
+		{`[{"Name": "LOAD_acc", "From": "LOADFROM", "To": "LOADTO", "Accuracy": -1, "TranslationMeters": [1, 2, 3]}]`, 0, ErrRange},
+		{`[{"Name": "LOAD_ext", "From": "LOADFROM", "To": "LOADTO", "TranslationMeters": [1, 2, 3],
+		   "Extent": {"MinLat": 49, "MaxLat": 46, "MinLong": 9, "MaxLong": 17}}]`, 0, ErrRange},
+		{`[{"Name": "LOAD_glob", "From": "LOADFROM", "To": "LOADTO", "TranslationMeters": [1, 2, 3],
+		   "Extent": {"MinLat": 46, "MaxLat": 95, "MinLong": 9, "MaxLong": 17}}]`, 0, ErrRange},
+		{`[{"Name": "LOAD_ok", "From": "LOADFROM", "To": "LOADTO", "TranslationMeters": [1, 2, 3]},
+		   {"Name": "LOAD_ok", "From": "LOADFROM", "To": "LOADTO", "TranslationMeters": [1, 2, 3]}]`, 1, ErrDuplicate},
+		{`[{"Name": "LOAD_7param", "From": "LOADFROM", "To": "LOADTO", "TranslationMeters": [1, 2, 3]}]`, 0, ErrDuplicate},
+	} {
+		_, err := LoadHelmertParameterSets(strings.NewReader(test.file))
+		var hpe HelmertParameterSetError
+		if !errors.As(err, &hpe) || hpe.Index != test.index || !errors.Is(err, test.err) {
+			t.Errorf("LoadHelmertParameterSets [%d]: expected %v at entry %d, got %v", index, test.err, test.index, err)
+		}
+	}
+
+	// rotations given in radians instead of arc seconds
+	_, err = LoadHelmertParameterSets(strings.NewReader(`[{"Name": "LOAD_rad", "From": "LOADFROM", "To": "LOADTO",
+		"TranslationMeters": [1, 2, 3], "RotationArcSeconds": [1000, 0, 0]}]`))
+	var he HelmertError
+	if !errors.As(err, &he) || he.Parameter != "drx" {
+		t.Errorf("LoadHelmertParameterSets: expected a HelmertError of drx, got %v", err)
+	}
+	if _, err := LoadHelmertParameterSets(strings.NewReader(`[{"Name": "LOAD_unit", "From": "LOADFROM", "To": "LOADTO",
+		"TranslationMeters": [1, 2, 3], "RotationRadians": [0, 0, 0]}]`)); err == nil {
+		t.Error("LoadHelmertParameterSets: expected an error for an unknown key")
+	}
+	if _, err := LoadHelmertParameterSets(strings.NewReader(`{"Name": "LOAD_list"}`)); err == nil {
+		t.Error("LoadHelmertParameterSets: expected an error for a file which is no list")
+	}
+
+	// a malformed file registers none of its parameter sets
+	if _, err := HelmertParameterSetByName("LOAD_ok"); err != ErrNotFound {
+		t.Errorf("HelmertParameterSetByName: expected ErrNotFound for a parameter set of a malformed file, got %v", err)
+	}
+}
+
+// ## GeoHashToLatLong
+type geoHashToLatLongTest struct {
+	in  string
+	out *PolarCoord
+}
+
+var geoHashToLatLongTests = []geoHashToLatLongTest{
+	{"ezs42",
+		&PolarCoord{Latitude: 42.6, Longitude: -5.6},
+	},
+	{"u4pruydqqvj",
+		&PolarCoord{Latitude: 57.64911, Longitude: 10.40744},
+	},
+	{"vn0p",
+		&PolarCoord{Latitude: 80.0, Longitude: 45.0},
+	},
+	{"60p0",
+		&PolarCoord{Latitude: -45.0, Longitude: -80.0},
+	},
+}
+
+func TestGeoHashToLatLong(t *testing.T) {
+	for index, test := range geoHashToLatLongTests {
+		out, err := GeoHashToLatLong(test.in, nil)
+
+		if err != nil {
+			t.Error(err)
+		}
+
+		if !latlongequal(test.out, out) {
+			t.Errorf("GeoHashToLatLong [%d]: expected (%f, %f), got (%f, %f)", index, test.out.Latitude, test.out.Longitude, out.Latitude, out.Longitude)
+		}
+	}
+}
+
+// LatLongToGeoHash - uses the inverted input of TestGeoHashToLatLong
+func TestLatLongToGeoHash(t *testing.T) {
+	for index, test := range geoHashToLatLongTests {
+		out := LatLongToGeoHash(test.out)
+
+		if test.in != out {
+			t.Errorf("LatLongToGeoHash [%d]: expected %s, got %s", index, test.in, out)
+		}
+	}
+}
+
+// ## GeoHashToBBox
+type geoHashToBBoxTest struct {
+	in     string
+	sw, ne *PolarCoord
+	err    error
+}
+
+var geoHashToBBoxTests = []geoHashToBBoxTest{
+	{"ezs42", &PolarCoord{Latitude: 42.5830078125, Longitude: -5.625}, &PolarCoord{Latitude: 42.626953125, Longitude: -5.5810546875}, nil},
+	{"u", &PolarCoord{Latitude: 45, Longitude: 0}, &PolarCoord{Latitude: 90, Longitude: 45}, nil},
+	// the empty geohash is the whole world
+	{"", &PolarCoord{Latitude: -90, Longitude: -180}, &PolarCoord{Latitude: 90, Longitude: 180}, nil},
+	{"ezs4a", nil, nil, ErrRange},
+}
+
+func TestGeoHashToBBox(t *testing.T) {
+	for index, test := range geoHashToBBoxTests {
+		sw, ne, err := GeoHashToBBox(test.in, nil)
+
+		if err != test.err {
+			t.Errorf("GeoHashToBBox [%d]: expected error %v, got %v", index, test.err, err)
+			continue
+		}
+		if test.err != nil {
+			continue
+		}
+		if sw.Latitude != test.sw.Latitude || sw.Longitude != test.sw.Longitude || ne.Latitude != test.ne.Latitude || ne.Longitude != test.ne.Longitude {
+			t.Errorf("GeoHashToBBox [%d]: expected (%f, %f) (%f, %f), got (%f, %f) (%f, %f)", index,
+				test.sw.Latitude, test.sw.Longitude, test.ne.Latitude, test.ne.Longitude, sw.Latitude, sw.Longitude, ne.Latitude, ne.Longitude)
+		}
+	}
+}
+
+// ## LatLongToString
+type latlongToStringTest struct {
+	in        *PolarCoord
+	lat, long string
+}
+
+var latlongToStringTests = []latlongToStringTest{
+	{&PolarCoord{Latitude: 140.0, Longitude: 40.0}, "N 140°", "E 40°"},
+	{&PolarCoord{Latitude: -140.0, Longitude: -40.0}, "S 140°", "W 40°"},
+	{&PolarCoord{Latitude: 140.5, Longitude: -40.5}, "N 140°30'", "W 40°30'"},
+	{&PolarCoord{Latitude: 140.005, Longitude: -40.505}, "N 140°0'18''", "W 40°30'18''"},
+	// bearings of less than one degree keep the sign out of minutes and seconds
+	{&PolarCoord{Latitude: -0.5, Longitude: -0.1246}, "S 0°30'", "W 0°7'28.56''"},
+}
+
+func TestLatLongToString(t *testing.T) {
+	for index, test := range latlongToStringTests {
+		lat, long := LatLongToString(test.in, LLFdms)
+
+		if !(test.lat == lat && test.long == long) {
+			t.Errorf("LatLongToString [%d]: expected %s, %s, got %s, %s", index, test.lat, test.long, lat, long)
+		}
+	}
+}
+
+// ## FormatDMS
+type formatDMSTest struct {
+	in        *PolarCoord
+	prec      int
+	lat, long string
+}
+
+var formatDMSTests = []formatDMSTest{
+	{&PolarCoord{Latitude: 47.504222, Longitude: 9.084167}, 1, "47°30′15.2″N", "9°05′03.0″E"},
+	{&PolarCoord{Latitude: -33.925, Longitude: -70.6}, 0, "33°55′30″S", "70°36′00″W"},
+	{&PolarCoord{Latitude: 12.3456789, Longitude: 0}, 3, "12°20′44.444″N", "0°00′00.000″E"},
+	// seconds rounding up to 60 carry over into the minutes and degrees
+	{&PolarCoord{Latitude: 47.999999, Longitude: -10.49999999}, 2, "48°00′00.00″N", "10°30′00.00″W"},
+	{&PolarCoord{Latitude: 16.1666666, Longitude: 179.9999999}, 1, "16°10′00.0″N", "180°00′00.0″E"},
+	// bearings rounding to zero carry no negative direction, negative decimal places are taken as 0
+	{&PolarCoord{Latitude: -0.0000001, Longitude: -0.4}, -1, "0°00′00″N", "0°24′00″W"},
+}
+
+func TestFormatDMS(t *testing.T) {
+	for index, test := range formatDMSTests {
+		lat, long := FormatDMS(test.in, test.prec)
+
+		if !(test.lat == lat && test.long == long) {
+			t.Errorf("FormatDMS [%d]: expected %s, %s, got %s, %s", index, test.lat, test.long, lat, long)
+		}
+	}
+
+	// the bearings parse back within the precision of the seconds
+	gc := &PolarCoord{Latitude: -47.504222, Longitude: 9.084167}
+	lat, long := FormatDMS(gc, 2)
+	for _, bearing := range []struct {
+		literal  string
+		expected float64
+	}{{lat, gc.Latitude}, {long, gc.Longitude}} {
+		if val, err := ADegMinSecToNum(bearing.literal); err != nil || math.Abs(val-bearing.expected) > 0.005/3600 {
+			t.Errorf("FormatDMS: expected %s to parse as %f, got %f (%v)", bearing.literal, bearing.expected, val, err)
+		}
+	}
+}
+
+// ## GroupUTMByGridCell
+type uTMGridCellTest struct {
+	in         *UTMCoord
+	resolution float64
+	cell       string
+}
+
+var uTMGridCellTests = []uTMGridCellTest{
+	{&UTMCoord{Zone: "33T", Easting: 425351.16, Northing: 5268986.55}, 1000, "33T 425000 5268000"},
+	{&UTMCoord{Zone: "33T", Easting: 425999.99, Northing: 5268000}, 1000, "33T 425000 5268000"},
+	{&UTMCoord{Zone: "33T", Easting: 425351.16, Northing: 5268986.55}, 100000, "33T 400000 5200000"},
+	{&UTMCoord{Zone: "32T", Easting: 425351.16, Northing: 5268986.55}, 1000, "32T 425000 5268000"},
+}
+
+func TestGroupUTMByGridCell(t *testing.T) {
+	coords := make([]*UTMCoord, 0, len(uTMGridCellTests)+1)
+
+	for index, test := range uTMGridCellTests {
+		cell, err := UTMGridCell(test.in, test.resolution)
+		if err != nil || cell != test.cell {
+			t.Errorf("UTMGridCell [%d]: expected %s, got %s (%v)", index, test.cell, cell, err)
+		}
+		coords = append(coords, test.in)
+	}
+	coords = append(coords, nil)
+
+	cells, err := GroupUTMByGridCell(coords, 1000)
+	if err != nil {
+		t.Fatalf("GroupUTMByGridCell: %s", err)
+	}
+
+	if len(cells) != 2 {
+		t.Errorf("GroupUTMByGridCell: expected 2 cells, got %d", len(cells))
+	}
+
+	if group := cells["33T 425000 5268000"]; len(group) != 3 || group[0] != coords[0] || group[1] != coords[1] || group[2] != coords[2] {
+		t.Errorf("GroupUTMByGridCell: expected the first three coordinates in cell 33T 425000 5268000, got %v", group)
+	}
+
+	if _, err := GroupUTMByGridCell(coords, 0); err != ErrRange {
+		t.Errorf("GroupUTMByGridCell: expected ErrRange for resolution 0, got %v", err)
+	}
+}
+
+// ## MolodenskyTransform
+type molodenskyTransformTest struct {
+	in  *PolarCoord
+	out *PolarCoord
+}
+
+// NAD27 on the Clarke 1866 ellipsoid to WGS84 by the mean shift of the conterminous United States,
+// dX = -8 m, dY = 160 m, dZ = 176 m, as given in NIMA TR8350.2
+var molodenskyTransformTests = []molodenskyTransformTest{
+	{&PolarCoord{Latitude: 40, Longitude: -100, El: Clarke1866Ellipsoid},
+		&PolarCoord{Latitude: 40.000008215, Longitude: -100.000417609, Height: -35.412}},
+	{&PolarCoord{Latitude: 30.5, Longitude: -85.25, Height: 100, El: Clarke1866Ellipsoid},
+		&PolarCoord{Latitude: 30.500223923, Longitude: -85.249945034, Height: 59.483}},
+	{&PolarCoord{Latitude: 47.5, Longitude: -122.3, El: Clarke1866Ellipsoid},
+		&PolarCoord{Latitude: 47.499806184, Longitude: -122.301224465, Height: -18.646}},
+}
+
+func TestMolodenskyTransform(t *testing.T) {
+	for index, test := range molodenskyTransformTests {
+		out := MolodenskyTransform(test.in, nil, WGS84Ellipsoid, -8, 160, 176)
+		if math.Abs(out.Latitude-test.out.Latitude) > 1e-8 || math.Abs(out.Longitude-test.out.Longitude) > 1e-8 ||
+			math.Abs(out.Height-test.out.Height) > 1e-3 || out.El != WGS84Ellipsoid {
+			t.Errorf("MolodenskyTransform [%d]: expected %s %.3f, got %s %.3f", index, test.out, test.out.Height, out, out.Height)
+		}
+
+		// the abridged formulae agree with the rigorous shift of the cartesian coordinates within a meter
+		cart := PolarToCartesian(test.in)
+		rigorous := CartesianToPolar(&CartPoint{X: cart.X - 8, Y: cart.Y + 160, Z: cart.Z + 176, El: WGS84Ellipsoid})
+		if d := angularDistance(out, rigorous) * meanRadius(WGS84Ellipsoid); d > 1 || math.Abs(out.Height-rigorous.Height) > 1 {
+			t.Errorf("MolodenskyTransform [%d]: deviates by %.3f m from the rigorous transformation %s", index, d, rigorous)
+		}
+	}
+}
+
+// ## Centroid
+type centroidTest struct {
+	in  []*PolarCoord
+	out *PolarCoord
+	err error
+}
+
+var centroidTests = []centroidTest{
+	{
+		[]*PolarCoord{{Latitude: 47.5, Longitude: 14.0, Height: 100}},
+		&PolarCoord{Latitude: 47.5, Longitude: 14.0, Height: 100, El: DefaultEllipsoid},
+		nil,
+	},
+	{ // across the antimeridian
+		[]*PolarCoord{{Latitude: 10, Longitude: 179}, {Latitude: 10, Longitude: -179}},
+		&PolarCoord{Latitude: 10.001493, Longitude: 180, El: DefaultEllipsoid},
+		nil,
+	},
+	{ // around the north pole
+		[]*PolarCoord{{Latitude: 89, Longitude: 0}, {Latitude: 89, Longitude: 90}, {Latitude: 89, Longitude: 180}, {Latitude: 89, Longitude: -90}},
+		&PolarCoord{Latitude: 90, Longitude: 0, El: DefaultEllipsoid},
+		nil,
+	},
+	{
+		[]*PolarCoord{{Latitude: 47.5, Longitude: 14.0, El: WGS84Ellipsoid}, {Latitude: 47.5, Longitude: 14.0, El: Bessel1841Ellipsoid}},
+		nil,
+		ErrRange,
+	},
+	{
+		[]*PolarCoord{{Latitude: 0, Longitude: 0}, {Latitude: 0, Longitude: 180}},
+		nil,
+		ErrRange,
+	},
+	{nil, nil, ErrRange},
+}
+
+func TestCentroid(t *testing.T) {
+	for index, test := range centroidTests {
+		out, err := Centroid(test.in)
+
+		if err != test.err {
+			t.Errorf("Centroid [%d]: expected error %v, got %v", index, test.err, err)
+			continue
+		}
+
+		if test.out == nil {
+			continue
+		}
+
+		if !floatequal(test.out.Latitude, out.Latitude) || !floatequal(math.Abs(test.out.Longitude), math.Abs(out.Longitude)) ||
+			!floatequal(test.out.Height, out.Height) || test.out.El != out.El {
+			t.Errorf("Centroid [%d]: expected %s, got %s", index, test.out, out)
+		}
+	}
+}
+
+// ## InitialBearing
+type bearingTest struct {
+	from, to *PolarCoord
+	bearing  float64
+}
+
+var bearingTests = []bearingTest{
+	{&PolarCoord{Latitude: 0, Longitude: 0}, &PolarCoord{Latitude: 1, Longitude: 0}, 0},
+	{&PolarCoord{Latitude: 0, Longitude: 0}, &PolarCoord{Latitude: 0, Longitude: 1}, 90},
+	{&PolarCoord{Latitude: 0, Longitude: 0}, &PolarCoord{Latitude: -1, Longitude: 0}, 180},
+	{&PolarCoord{Latitude: 0, Longitude: 0}, &PolarCoord{Latitude: 0, Longitude: -1}, 270},
+	{&PolarCoord{Latitude: 0, Longitude: 179.5}, &PolarCoord{Latitude: 0, Longitude: -179.5}, 90},
+	// Vienna to Graz
+	{&PolarCoord{Latitude: 48.208, Longitude: 16.373}, &PolarCoord{Latitude: 47.071, Longitude: 15.439}, 209.310301},
+}
+
+// a magnetic model of constant declination
+type constantDeclination float64
+
+func (cd constantDeclination) Declination(gc *PolarCoord, date time.Time) (float64, error) {
+	if date.Year() < 2020 {
+		return 0, ErrRange
+	}
+	return float64(cd), nil
+}
+
+func TestInitialBearing(t *testing.T) {
+	for index, test := range bearingTests {
+		if out := InitialBearing(test.from, test.to); !floatequal(test.bearing, out) {
+			t.Errorf("InitialBearing [%d]: expected %f, got %f", index, test.bearing, out)
+		}
+	}
+
+	defer func(mm MagneticModel) { DefaultMagneticModel = mm }(DefaultMagneticModel)
+
+	from, to := bearingTests[1].from, bearingTests[1].to
+	date := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	DefaultMagneticModel = nil
+	if _, err := InitialMagneticBearing(from, to, date); err != ErrUnsupported {
+		t.Errorf("InitialMagneticBearing: expected ErrUnsupported without a model, got %v", err)
+	}
+
+	DefaultMagneticModel = constantDeclination(95.5)
+	if out, err := InitialMagneticBearing(from, to, date); err != nil || !floatequal(354.5, out) {
+		t.Errorf("InitialMagneticBearing: expected 354.500000, got %f (%v)", out, err)
+	}
+	if _, err := InitialMagneticBearing(from, to, time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)); err != ErrRange {
+		t.Errorf("InitialMagneticBearing: expected the error of the model, got %v", err)
+	}
+}
+
+// ## FormatBearing, FormatBearingPoints
+type formatBearingTest struct {
+	deg     float64
+	prec    int
+	points  CompassPoints
+	heading string
+}
+
+var formatBearingTests = []formatBearingTest{
+	{45, 0, Compass8, "045° NE"},
+	{0, 0, Compass8, "000° N"},
+	{-90, 0, Compass8, "270° W"},
+	{-720, 0, Compass8, "000° N"},
+	{540, 0, Compass8, "180° S"},
+	// half-way between two points is labelled by the point clockwise of it
+	{337.5, 1, Compass8, "337.5° N"},
+	{337.49, 2, Compass8, "337.49° NW"},
+	{337.5, 1, Compass16, "337.5° NNW"},
+	{348.75, 2, Compass16, "348.75° N"},
+	{348.74, 2, Compass16, "348.74° NNW"},
+	// the label follows the heading as rounded
+	{22.49, 0, Compass8, "022° N"},
+	{22.5, 0, Compass8, "023° NE"},
+	{359.6, 0, Compass8, "000° N"},
+	{359.96, 1, Compass16, "000.0° N"},
+	{209.310301, 1, Compass16, "209.3° SSW"},
+	{101.25, 2, Compass16, "101.25° ESE"},
+	{5.25, -1, Compass16, "005° N"},
+	{math.NaN(), 0, Compass8, "NaN°"},
+}
+
+func TestFormatBearing(t *testing.T) {
+	for index, test := range formatBearingTests {
+		if out := FormatBearingPoints(test.deg, test.prec, test.points); out != test.heading {
+			t.Errorf("FormatBearingPoints [%d]: expected %s, got %s", index, test.heading, out)
+		}
+	}
+	if out := FormatBearing(InitialBearing(bearingTests[5].from, bearingTests[5].to)); out != "209° SW" {
+		t.Errorf("FormatBearing: expected 209° SW, got %s", out)
+	}
+}
+
+// ## CrossTrackDistance
+type crossTrackDistanceTest struct {
+	point, start, end *PolarCoord
+	distance          float64
+}
+
+var crossTrackDistanceTests = []crossTrackDistanceTest{
+	// along the equator to the east, one degree north is left of the path
+	{&PolarCoord{Latitude: 1, Longitude: 5}, &PolarCoord{Latitude: 0, Longitude: 0}, &PolarCoord{Latitude: 0, Longitude: 10}, -111195.079735},
+	{&PolarCoord{Latitude: -1, Longitude: 5}, &PolarCoord{Latitude: 0, Longitude: 0}, &PolarCoord{Latitude: 0, Longitude: 10}, 111195.079735},
+	{&PolarCoord{Latitude: 0, Longitude: 3}, &PolarCoord{Latitude: 0, Longitude: 0}, &PolarCoord{Latitude: 0, Longitude: 10}, 0},
+	// beyond the end of the path, the distance is measured to the end, signed by the side of the path
+	{&PolarCoord{Latitude: 1, Longitude: 20}, &PolarCoord{Latitude: 0, Longitude: 0}, &PolarCoord{Latitude: 0, Longitude: 10}, -1117440.430958},
+	{&PolarCoord{Latitude: -1, Longitude: -1}, &PolarCoord{Latitude: 0, Longitude: 0}, &PolarCoord{Latitude: 0, Longitude: 10}, 157249.597769},
+	// before the start on the extension of the great circle, five degrees of the equator from the start
+	{&PolarCoord{Latitude: 0, Longitude: -5}, &PolarCoord{Latitude: 0, Longitude: 0}, &PolarCoord{Latitude: 0, Longitude: 10}, 555975.398673},
+	// along a meridian to the north, one degree east is right of the path
+	{&PolarCoord{Latitude: 5, Longitude: 1}, &PolarCoord{Latitude: 0, Longitude: 0}, &PolarCoord{Latitude: 10, Longitude: 0}, 110771.906161},
+	// reversing the path flips the sign
+	{&PolarCoord{Latitude: 5, Longitude: 1}, &PolarCoord{Latitude: 10, Longitude: 0}, &PolarCoord{Latitude: 0, Longitude: 0}, -110771.906161},
+}
+
+func TestCrossTrackDistance(t *testing.T) {
+	for index, test := range crossTrackDistanceTests {
+		if out := CrossTrackDistance(test.point, test.start, test.end); math.Abs(out-test.distance) > 1e-3 {
+			t.Errorf("CrossTrackDistance [%d]: expected %f, got %f", index, test.distance, out)
+		}
+	}
+}
+
+// ## Haversine
+type haversineTest struct {
+	a, b             *PolarCoord
+	radius, distance float64
+}
+
+var haversineTests = []haversineTest{
+	// London to Paris
+	{&PolarCoord{Latitude: 51.5074, Longitude: -0.1278}, &PolarCoord{Latitude: 48.8566, Longitude: 2.3522}, 0, 343556.533},
+	{&PolarCoord{Latitude: 51.5074, Longitude: -0.1278}, &PolarCoord{Latitude: 48.8566, Longitude: 2.3522}, 6371000, 343556.060},
+	// half the circumference
+	{&PolarCoord{Latitude: 0, Longitude: 0}, &PolarCoord{Latitude: 0, Longitude: 180}, 0, 20015114.352},
+	{&PolarCoord{Latitude: 47.57, Longitude: 14.0075}, &PolarCoord{Latitude: 47.57, Longitude: 14.0075}, 0, 0},
+	// across the antimeridian, 2° along the equator either way
+	{&PolarCoord{Latitude: 0, Longitude: 179}, &PolarCoord{Latitude: 0, Longitude: -179}, 0, 222390.159},
+	{&PolarCoord{Latitude: 0, Longitude: -179}, &PolarCoord{Latitude: 0, Longitude: 179}, 0, 222390.159},
+	{&PolarCoord{Latitude: 0, Longitude: 1}, &PolarCoord{Latitude: 0, Longitude: -1}, 0, 222390.159},
+}
+
+func TestHaversine(t *testing.T) {
+	for index, test := range haversineTests {
+		if out := Haversine(test.a, test.b, test.radius); math.Abs(out-test.distance) > 1e-3 {
+			t.Errorf("Haversine [%d]: expected %f, got %f", index, test.distance, out)
+		}
+	}
+}
+
+// ## IntermediatePoint
+type intermediatePointTest struct {
+	a, b     *PolarCoord
+	fraction float64
+	out      *PolarCoord
+}
+
+var intermediatePointTests = []intermediatePointTest{
+	// London to Paris
+	{&PolarCoord{Latitude: 51.5074, Longitude: -0.1278}, &PolarCoord{Latitude: 48.8566, Longitude: 2.3522}, 0.5, &PolarCoord{Latitude: 50.188595, Longitude: 1.146618}},
+	{&PolarCoord{Latitude: 51.5074, Longitude: -0.1278}, &PolarCoord{Latitude: 48.8566, Longitude: 2.3522}, 0.25, &PolarCoord{Latitude: 50.849732, Longitude: 0.518417}},
+	{&PolarCoord{Latitude: 51.5074, Longitude: -0.1278}, &PolarCoord{Latitude: 48.8566, Longitude: 2.3522}, 0, &PolarCoord{Latitude: 51.5074, Longitude: -0.1278}},
+	{&PolarCoord{Latitude: 51.5074, Longitude: -0.1278}, &PolarCoord{Latitude: 48.8566, Longitude: 2.3522}, 1, &PolarCoord{Latitude: 48.8566, Longitude: 2.3522}},
+	// the great circle from New York to London leads north of the parallels
+	{&PolarCoord{Latitude: 40.6413, Longitude: -73.7781}, &PolarCoord{Latitude: 51.4700, Longitude: -0.4543}, 0.5, &PolarCoord{Latitude: 52.216674, Longitude: -41.302671}},
+	// along the equator and across the antimeridian
+	{&PolarCoord{Latitude: 0, Longitude: 0}, &PolarCoord{Latitude: 0, Longitude: 90}, 0.5, &PolarCoord{Latitude: 0, Longitude: 45}},
+	{&PolarCoord{Latitude: 0, Longitude: 170}, &PolarCoord{Latitude: 0, Longitude: -170}, 0.5, &PolarCoord{Latitude: 0, Longitude: 180}},
+	{&PolarCoord{Latitude: 0, Longitude: 179}, &PolarCoord{Latitude: 0, Longitude: -179}, 0.25, &PolarCoord{Latitude: 0, Longitude: 179.5}},
+	{&PolarCoord{Latitude: 0, Longitude: 179}, &PolarCoord{Latitude: 0, Longitude: -179}, 0.75, &PolarCoord{Latitude: 0, Longitude: -179.5}},
+	{&PolarCoord{Latitude: 0, Longitude: -179}, &PolarCoord{Latitude: 0, Longitude: 179}, 0.25, &PolarCoord{Latitude: 0, Longitude: -179.5}},
+	// halfway from Tokyo to San Francisco the great circle has crossed the antimeridian, north of both
+	{&PolarCoord{Latitude: 35.5494, Longitude: 139.7798}, &PolarCoord{Latitude: 37.6213, Longitude: -122.379}, 0.5, &PolarCoord{Latitude: 48.479305, Longitude: -172.181734}},
+	// the height is interpolated linearly
+	{&PolarCoord{Latitude: 0, Longitude: 0, Height: 100}, &PolarCoord{Latitude: 10, Longitude: 0, Height: 200}, 0.5, &PolarCoord{Latitude: 5, Longitude: 0, Height: 150}},
+	// coincident coordinates
+	{&PolarCoord{Latitude: 47.57, Longitude: 14.0075, Height: 10}, &PolarCoord{Latitude: 47.57, Longitude: 14.0075, Height: 20}, 0.5, &PolarCoord{Latitude: 47.57, Longitude: 14.0075, Height: 10}},
+}
+
+func TestIntermediatePoint(t *testing.T) {
+	for index, test := range intermediatePointTests {
+		out := IntermediatePoint(test.a, test.b, test.fraction)
+		if !floatequal(test.out.Latitude, out.Latitude) || !floatequal(test.out.Longitude, out.Longitude) || !floatequal(test.out.Height, out.Height) {
+			t.Errorf("IntermediatePoint [%d]: expected %s, got %s", index, test.out, out)
 		}
+		if out == test.a || out == test.b {
+			t.Errorf("IntermediatePoint [%d]: expected a copy, got an input coordinate", index)
+		}
+	}
+
+	test := intermediatePointTests[0]
+	if out := Midpoint(test.a, test.b); !floatequal(test.out.Latitude, out.Latitude) || !floatequal(test.out.Longitude, out.Longitude) {
+		t.Errorf("Midpoint: expected %s, got %s", test.out, out)
 	}
 }
 
-func TestHelmertInverseTransform(t *testing.T) {
-	for index, test := range helmertTests {
-		out := HelmertWGS84ToMGI.InverseTransform(test.out)
-		if !helmertfuzzyequal(test.in, out) {
-			t.Errorf("HelmertInverseTransform [%d]: expected (%f %f %f), got (%f %f %f)",
-				index, test.in.X, test.in.Y, test.in.Z, out.X, out.Y, out.Z)
+// ## Vincenty
+type vincentyTest struct {
+	a, b                                   *PolarCoord
+	distance, initialBearing, finalBearing float64
+	err                                    error
+}
+
+var vincentyTests = []vincentyTest{
+	// Flinders Peak to Buninyong, the worked example of Geoscience Australia
+	{&PolarCoord{Latitude: -(37 + 57.0/60 + 3.72030/3600), Longitude: 144 + 25.0/60 + 29.52440/3600, El: GRS80Ellipsoid},
+		&PolarCoord{Latitude: -(37 + 39.0/60 + 10.15610/3600), Longitude: 143 + 55.0/60 + 35.38390/3600, El: GRS80Ellipsoid},
+		54972.271, 306 + 52.0/60 + 5.37/3600, 307 + 10.0/60 + 25.07/3600, nil},
+	// along the equator and along a meridian
+	{&PolarCoord{Latitude: 0, Longitude: 0, El: WGS84Ellipsoid}, &PolarCoord{Latitude: 0, Longitude: 10, El: WGS84Ellipsoid}, 1113194.908, 90, 90, nil},
+	{&PolarCoord{Latitude: 0, Longitude: 0, El: WGS84Ellipsoid}, &PolarCoord{Latitude: 10, Longitude: 0, El: WGS84Ellipsoid}, 1105854.833, 0, 0, nil},
+	{&PolarCoord{Latitude: 48.2082, Longitude: 16.3738, El: WGS84Ellipsoid}, &PolarCoord{Latitude: 47.2608, Longitude: 11.3933, El: WGS84Ellipsoid}, 388060.447, 256.106208, 252.419279, nil},
+	// across the antimeridian, 2° along the equator either way
+	{&PolarCoord{Latitude: 0, Longitude: 179, El: WGS84Ellipsoid}, &PolarCoord{Latitude: 0, Longitude: -179, El: WGS84Ellipsoid}, 222638.982, 90, 90, nil},
+	{&PolarCoord{Latitude: 0, Longitude: -179, El: WGS84Ellipsoid}, &PolarCoord{Latitude: 0, Longitude: 179, El: WGS84Ellipsoid}, 222638.982, 270, 270, nil},
+	// coincident coordinates
+	{&PolarCoord{Latitude: 47.57, Longitude: 14.0075, El: WGS84Ellipsoid}, &PolarCoord{Latitude: 47.57, Longitude: 14.0075, El: WGS84Ellipsoid}, 0, 0, 0, nil},
+	// nearly antipodal coordinates
+	{&PolarCoord{Latitude: 0, Longitude: 0, El: WGS84Ellipsoid}, &PolarCoord{Latitude: 0.5, Longitude: 179.7, El: WGS84Ellipsoid}, 0, 0, 0, ErrConvergence},
+	// differing ellipsoids
+	{&PolarCoord{Latitude: 0, Longitude: 0, El: WGS84Ellipsoid}, &PolarCoord{Latitude: 0, Longitude: 10, El: Bessel1841Ellipsoid}, 0, 0, 0, ErrRange},
+}
+
+func TestVincenty(t *testing.T) {
+	for index, test := range vincentyTests {
+		distance, initialBearing, finalBearing, err := Vincenty(test.a, test.b)
+		if err != test.err || math.Abs(distance-test.distance) > 1e-3 ||
+			math.Abs(initialBearing-test.initialBearing) > 1e-5 || math.Abs(finalBearing-test.finalBearing) > 1e-5 {
+			t.Errorf("Vincenty [%d]: expected %f %f %f %v, got %f %f %f %v", index,
+				test.distance, test.initialBearing, test.finalBearing, test.err, distance, initialBearing, finalBearing, err)
 		}
 	}
 }
 
-// ## GeoHashToLatLong
-type geoHashToLatLongTest struct {
-	in  string
-	out *PolarCoord
+// ## Destination
+type destinationTest struct {
+	start             *PolarCoord
+	bearing, distance float64
+	out               *PolarCoord
 }
 
-var geoHashToLatLongTests = []geoHashToLatLongTest{
-	{"ezs42",
-		&PolarCoord{Latitude: 42.6, Longitude: -5.6},
-	},
-	{"u4pruydqqvj",
-		&PolarCoord{Latitude: 57.64911, Longitude: 10.40744},
-	},
-	{"vn0p",
-		&PolarCoord{Latitude: 80.0, Longitude: 45.0},
-	},
-	{"60p0",
-		&PolarCoord{Latitude: -45.0, Longitude: -80.0},
-	},
+var destinationTests = []destinationTest{
+	// Flinders Peak to Buninyong, the worked example of Geoscience Australia
+	{&PolarCoord{Latitude: -(37 + 57.0/60 + 3.72030/3600), Longitude: 144 + 25.0/60 + 29.52440/3600, El: GRS80Ellipsoid},
+		306 + 52.0/60 + 5.37/3600, 54972.271,
+		&PolarCoord{Latitude: -(37 + 39.0/60 + 10.15610/3600), Longitude: 143 + 55.0/60 + 35.38390/3600, El: GRS80Ellipsoid}},
+	// along the equator and along a meridian
+	{&PolarCoord{Latitude: 0, Longitude: 0, El: WGS84Ellipsoid}, 90, 1113194.908, &PolarCoord{Latitude: 0, Longitude: 10, El: WGS84Ellipsoid}},
+	{&PolarCoord{Latitude: 0, Longitude: 0, El: WGS84Ellipsoid}, 0, 1105854.833, &PolarCoord{Latitude: 10, Longitude: 0, El: WGS84Ellipsoid}},
+	{&PolarCoord{Latitude: 0, Longitude: 0, El: WGS84Ellipsoid}, 180, -1105854.833, &PolarCoord{Latitude: 10, Longitude: 0, El: WGS84Ellipsoid}},
+	// across the antimeridian
+	{&PolarCoord{Latitude: 0, Longitude: 175, El: WGS84Ellipsoid}, 90, 1113194.908, &PolarCoord{Latitude: 0, Longitude: -175, El: WGS84Ellipsoid}},
+	// Vienna to Innsbruck, the height is retained
+	{&PolarCoord{Latitude: 48.2082, Longitude: 16.3738, Height: 200, El: WGS84Ellipsoid}, 256.1062084851, 388060.447377,
+		&PolarCoord{Latitude: 47.2608, Longitude: 11.3933, Height: 200, El: WGS84Ellipsoid}},
+	{&PolarCoord{Latitude: 47.57, Longitude: 14.0075, El: WGS84Ellipsoid}, 45, 0, &PolarCoord{Latitude: 47.57, Longitude: 14.0075, El: WGS84Ellipsoid}},
 }
 
-func TestGeoHashToLatLong(t *testing.T) {
-	for index, test := range geoHashToLatLongTests {
-		out, err := GeoHashToLatLong(test.in, nil)
+func TestDestination(t *testing.T) {
+	for index, test := range destinationTests {
+		out := Destination(test.start, test.bearing, test.distance)
+		// 1e-8 degrees are about a millimeter
+		if math.Abs(out.Latitude-test.out.Latitude) > 1e-8 || math.Abs(out.Longitude-test.out.Longitude) > 1e-8 ||
+			out.Height != test.out.Height || out.El != test.out.El {
+			t.Errorf("Destination [%d]: expected %.9f %.9f, got %.9f %.9f", index,
+				test.out.Latitude, test.out.Longitude, out.Latitude, out.Longitude)
+		}
+	}
 
-		if err != nil {
-			t.Error(err)
+	// travelling back the geodesic from its destination returns to the start
+	start := &PolarCoord{Latitude: 48.2082, Longitude: 16.3738, El: WGS84Ellipsoid}
+	to := Destination(start, 30, 5000000)
+	distance, _, finalBearing, err := Vincenty(start, to)
+	back := Destination(to, finalBearing+180, distance)
+	if err != nil || math.Abs(distance-5000000) > 1e-3 || !start.Equal(back, 1e-3) {
+		t.Errorf("Destination: expected to return to %s, got %s (%v)", start, back, err)
+	}
+}
+
+// ## Ring
+type ringTest struct {
+	center *PolarCoord
+	radius float64
+	points int
+}
+
+var ringTests = []ringTest{
+	{&PolarCoord{Latitude: 48.2082, Longitude: 16.3738, Height: 200, El: WGS84Ellipsoid}, 1000, 8},
+	{&PolarCoord{Latitude: 48.2082, Longitude: 16.3738, El: Bessel1841Ellipsoid}, 0.01, 36},
+	// around a pole and across the antimeridian
+	{&PolarCoord{Latitude: 90, Longitude: 0, El: WGS84Ellipsoid}, 50000, 12},
+	{&PolarCoord{Latitude: -12.5, Longitude: 179.99, El: WGS84Ellipsoid}, 25000, 5},
+}
+
+func TestRing(t *testing.T) {
+	for index, test := range ringTests {
+		ring := Ring(test.center, test.radius, test.points)
+
+		if len(ring) != test.points+1 || *ring[0] != *ring[test.points] {
+			t.Errorf("Ring [%d]: expected a closed ring of %d coordinates, got %d", index, test.points+1, len(ring))
+			continue
+		}
+		for i, pc := range ring[:test.points] {
+			distance, bearing, _, err := Vincenty(test.center, pc)
+			// the bearing is undefined at the pole, and the inverse bearing by Vincenty imprecise at centimeters
+			bearingDiff := math.Abs(math.Remainder(bearing-360*float64(i)/float64(test.points), 360))
+			if err != nil || math.Abs(distance-test.radius) > 1e-6+test.radius*1e-8 ||
+				(test.center.Latitude != 90 && test.radius >= 1 && bearingDiff > 1e-6) || pc.Height != test.center.Height || pc.El != test.center.El {
+				t.Errorf("Ring [%d]: expected coordinate %d at %f m, bearing %f, got %f m, bearing %f (%v)", index, i,
+					test.radius, 360*float64(i)/float64(test.points), distance, bearing, err)
+			}
 		}
+	}
 
-		if !latlongequal(test.out, out) {
-			t.Errorf("GeoHashToLatLong [%d]: expected (%f, %f), got (%f, %f)", index, test.out.Latitude, test.out.Longitude, out.Latitude, out.Longitude)
+	// many coordinates approximate the area of the circle
+	center := &PolarCoord{Latitude: 48.2082, Longitude: 16.3738, El: WGS84Ellipsoid}
+	if area, _, err := PolygonArea(Ring(center, 1000, 100000)); err != nil || math.Abs(area-math.Pi*1e6) > 1 {
+		t.Errorf("Ring: expected an area of %f, got %f (%v)", math.Pi*1e6, area, err)
+	}
+
+	for _, ring := range [][]*PolarCoord{Ring(center, 1000, 2), Ring(center, math.NaN(), 8), Ring(center, math.Inf(1), 8),
+		Ring(&PolarCoord{Latitude: math.NaN(), Longitude: 16}, 1000, 8)} {
+		if ring != nil {
+			t.Errorf("Ring: expected nil, got %d coordinates", len(ring))
 		}
 	}
 }
 
-// LatLongToGeoHash - uses the inverted input of TestGeoHashToLatLong
-func TestLatLongToGeoHash(t *testing.T) {
-	for index, test := range geoHashToLatLongTests {
-		out := LatLongToGeoHash(test.out)
+// ## PolygonArea
+type polygonAreaTest struct {
+	in              []*PolarCoord
+	area, perimeter float64
+	err             error
+}
 
-		if test.in != out {
-			t.Errorf("LatLongToGeoHash [%d]: expected %s, got %s", index, test.in, out)
+var polygonAreaTests = []polygonAreaTest{
+	// an octant of the ellipsoid, bounded by the equator and two meridians
+	{[]*PolarCoord{{Latitude: 0, Longitude: 0}, {Latitude: 0, Longitude: 90}, {Latitude: 90, Longitude: 0}}, 63758202715511, 30022685.630022, nil},
+	// the closed ring in reverse orientation
+	{[]*PolarCoord{{Latitude: 90, Longitude: 0}, {Latitude: 0, Longitude: 90}, {Latitude: 0, Longitude: 0}, {Latitude: 90, Longitude: 0}}, 63758202715511, 30022685.630022, nil},
+	{[]*PolarCoord{{Latitude: 48, Longitude: 16}, {Latitude: 48, Longitude: 16.01}, {Latitude: 48.01, Longitude: 16.01}, {Latitude: 48.01, Longitude: 16}}, 829682.256, 3716.171233, nil},
+	// across the antimeridian
+	{[]*PolarCoord{{Latitude: -1, Longitude: 179.5}, {Latitude: -1, Longitude: -179.5}, {Latitude: 1, Longitude: -179.5}, {Latitude: 1, Longitude: 179.5}}, 24617552513.793, 664902.852911, nil},
+	{nil, 0, 0, ErrRange},
+	{[]*PolarCoord{{Latitude: 48, Longitude: 16}, {Latitude: 48, Longitude: 17}, {Latitude: 48, Longitude: 16}}, 0, 0, ErrRange},
+	{[]*PolarCoord{{Latitude: 48, Longitude: 16, El: WGS84Ellipsoid}, {Latitude: 48, Longitude: 17, El: Bessel1841Ellipsoid}, {Latitude: 49, Longitude: 16, El: WGS84Ellipsoid}}, 0, 0, ErrRange},
+}
+
+func TestPolygonArea(t *testing.T) {
+	for index, test := range polygonAreaTests {
+		area, perimeter, err := PolygonArea(test.in)
+
+		if err != test.err {
+			t.Errorf("PolygonArea [%d]: expected error %v, got %v", index, test.err, err)
+			continue
+		}
+
+		if math.Abs(test.area-area) > 1e-3+test.area*1e-12 || math.Abs(test.perimeter-perimeter) > 1e-3 {
+			t.Errorf("PolygonArea [%d]: expected area %f, perimeter %f, got area %f, perimeter %f", index, test.area, test.perimeter, area, perimeter)
 		}
 	}
 }
 
-// ## LatLongToString
-type latlongToStringTest struct {
-	in        *PolarCoord
-	lat, long string
+// ## BoundingCircle
+type boundingCircleTest struct {
+	in     []*PolarCoord
+	center *PolarCoord
+	radius float64
+	err    error
 }
 
-var latlongToStringTests = []latlongToStringTest{
-	{&PolarCoord{Latitude: 140.0, Longitude: 40.0}, "N 140°", "E 40°"},
-	{&PolarCoord{Latitude: -140.0, Longitude: -40.0}, "S 140°", "W 40°"},
-	{&PolarCoord{Latitude: 140.5, Longitude: -40.5}, "N 140°30'", "W 40°30'"},
-	{&PolarCoord{Latitude: 140.005, Longitude: -40.505}, "N 140°0'18''", "W 40°30'18''"},
+var boundingCircleTests = []boundingCircleTest{
+	{[]*PolarCoord{{Latitude: 47.5, Longitude: 14.0}}, &PolarCoord{Latitude: 47.5, Longitude: 14.0}, 0, nil},
+	{[]*PolarCoord{{Latitude: 0, Longitude: 0}, {Latitude: 0, Longitude: 2}}, &PolarCoord{Latitude: 0, Longitude: 1}, 111195.079735, nil},
+	// the inner point doesn't contribute to the circle
+	{[]*PolarCoord{{Latitude: 0, Longitude: -1}, {Latitude: 0.5, Longitude: 0}, {Latitude: 0, Longitude: 1}}, &PolarCoord{Latitude: 0, Longitude: 0}, 111195.079735, nil},
+	// around the north pole
+	{[]*PolarCoord{{Latitude: 80, Longitude: 0}, {Latitude: 80, Longitude: 120}, {Latitude: 80, Longitude: -120}}, &PolarCoord{Latitude: 90, Longitude: 0}, 1111950.797346, nil},
+	// across the antimeridian
+	{[]*PolarCoord{{Latitude: 10, Longitude: 179}, {Latitude: 10, Longitude: -179}, {Latitude: -10, Longitude: 179}, {Latitude: -10, Longitude: -179}, {Latitude: 0, Longitude: 180}},
+		&PolarCoord{Latitude: 0, Longitude: 180}, 1117440.430958, nil},
+	{nil, nil, 0, ErrRange},
+	{[]*PolarCoord{{Latitude: 47.5, Longitude: 14.0, El: WGS84Ellipsoid}, {Latitude: 47.5, Longitude: 14.0, El: Bessel1841Ellipsoid}}, nil, 0, ErrRange},
+	{[]*PolarCoord{{Latitude: 0, Longitude: 0}, {Latitude: 0, Longitude: 180}}, nil, 0, ErrRange},
+	// not within a hemisphere
+	{[]*PolarCoord{{Latitude: 0, Longitude: 0}, {Latitude: 0, Longitude: 120}, {Latitude: 0, Longitude: -120}, {Latitude: 45, Longitude: 0}, {Latitude: -45, Longitude: 0}}, nil, 0, ErrRange},
 }
 
-func TestLatLongToString(t *testing.T) {
-	for index, test := range latlongToStringTests {
-		lat, long := LatLongToString(test.in, LLFdms)
+func TestBoundingCircle(t *testing.T) {
+	for index, test := range boundingCircleTests {
+		center, radius, err := BoundingCircle(test.in)
 
-		if !(test.lat == lat && test.long == long) {
-			t.Errorf("LatLongToString [%d]: expected %s, %s, got %s, %s", index, test.lat, test.long, lat, long)
+		if err != test.err {
+			t.Errorf("BoundingCircle [%d]: expected error %v, got %v", index, test.err, err)
+			continue
+		}
+
+		if test.center == nil {
+			continue
+		}
+
+		if !floatequal(test.center.Latitude, center.Latitude) || !floatequal(math.Abs(test.center.Longitude), math.Abs(center.Longitude)) ||
+			math.Abs(test.radius-radius) > 1e-3 || center.El != DefaultEllipsoid {
+			t.Errorf("BoundingCircle [%d]: expected %s, radius %f, got %s, radius %f", index, test.center, test.radius, center, radius)
+		}
+	}
+}
+
+// ## Validate
+type helmertValidateTest struct {
+	hp        *HelmertTransform
+	parameter string // "" if the parameters are valid
+}
+
+var helmertValidateTests = []helmertValidateTest{
+	{HelmertWGS84ToMGI, ""},
+	{HelmertWGS84ToOSGB36, ""},
+	{HelmertLV03ToWGS84Granit87, ""},
+	{NewHelmertTransformer(-577.326, -90.129, -463.919, -2.4232, 5.1366, 1.4742, 5.2970, "test"), ""},
+	// a rotation about the z axis beyond 60 arc seconds, while rotations in degrees about x and y are too small to
+	// be detected
+	{NewHelmertTransformer(-577.326, -90.129, -463.919, -2.4232, 0.00143, 0.00041, 150, "test"), "drz"},
+	// the scale factor in ppm instead of the scale correction
+	{NewHelmertTransformer(-577.326, -90.129, -463.919, 1.0000024232e6, 5.1366, 1.4742, 5.2970, "test"), "dM"},
+	{NewHelmertTransformer(-577326, -90.129, -463.919, -2.4232, 5.1366, 1.4742, 5.2970, "test"), "dx"},
+	{NewHelmertTransformer(0, 0, math.NaN(), 0, 0, 0, 0, "test"), "dz"},
+}
+
+func TestHelmertValidate(t *testing.T) {
+	for index, test := range helmertValidateTests {
+		err := test.hp.Validate()
+
+		if test.parameter == "" {
+			if err != nil {
+				t.Errorf("Validate [%d]: expected no error, got %v", index, err)
+			}
+			continue
+		}
+
+		if he, ok := err.(HelmertError); !ok || he.Parameter != test.parameter {
+			t.Errorf("Validate [%d]: expected error for parameter %s, got %v", index, test.parameter, err)
+		}
+	}
+
+	// the limits may be changed
+	defer func(limit float64) { HelmertMaxRotation = limit }(HelmertMaxRotation)
+	HelmertMaxRotation = 200
+	if _, err := NewHelmertTransform(0, 0, 0, 0, 0, 0, 150, "test"); err != nil {
+		t.Errorf("NewHelmertTransform: expected no error for a rotation within the raised limit, got %v", err)
+	}
+
+	// a scale correction of -1e6 ppm collapses every point onto the origin
+	HelmertMaxScale = 2e6
+	defer func() { HelmertMaxScale = 1000 }()
+	if _, err := NewHelmertTransform(0, 0, 0, -1e6, 0, 0, 0, "test"); err == nil || err.(HelmertError).Parameter != "matrix" {
+		t.Errorf("NewHelmertTransform: expected a non-invertible transformation, got %v", err)
+	}
+}
+
+// ## FitHelmert
+// control points on a grid spanning Austria
+func controlPoints(hp *HelmertTransform) []ControlPoint {
+	var cps []ControlPoint
+	for lat := 46.5; lat < 49; lat += 0.75 {
+		for long := 9.5; long < 18; long += 2.5 {
+			cart := PolarToCartesian(&PolarCoord{Latitude: lat, Longitude: long, Height: 100 * lat, El: WGS84Ellipsoid})
+			from := Point3D{X: cart.X, Y: cart.Y, Z: cart.Z}
+			cps = append(cps, ControlPoint{From: from, To: *hp.Transform(&from)})
+		}
+	}
+	return cps
+}
+
+func TestFitHelmert(t *testing.T) {
+	hp, residuals, err := FitHelmert(controlPoints(HelmertWGS84ToMGI))
+	if err != nil {
+		t.Fatalf("FitHelmert: %s", err)
+	}
+
+	expected := fmt.Sprintf("%.3f %.3f %.3f %.3f %.3f %.3f %.3f", HelmertWGS84ToMGI.dx, HelmertWGS84ToMGI.dy, HelmertWGS84ToMGI.dz,
+		HelmertWGS84ToMGI.dM, HelmertWGS84ToMGI.drx, HelmertWGS84ToMGI.dry, HelmertWGS84ToMGI.drz)
+	fitted := fmt.Sprintf("%.3f %.3f %.3f %.3f %.3f %.3f %.3f", hp.dx, hp.dy, hp.dz, hp.dM, hp.drx, hp.dry, hp.drz)
+	if expected != fitted {
+		t.Errorf("FitHelmert: expected %s, got %s", expected, fitted)
+	}
+
+	for index, residual := range residuals {
+		if residual > 0.001 {
+			t.Errorf("FitHelmert [%d]: expected a residual below 1mm, got %f", index, residual)
+		}
+	}
+
+	// an outlier yields the largest residual
+	cps := controlPoints(HelmertWGS84ToMGI)
+	cps[2].To.X += 10
+	_, residuals, err = FitHelmert(cps)
+	if err != nil {
+		t.Fatalf("FitHelmert: %s", err)
+	}
+	for index, residual := range residuals {
+		if index != 2 && residual >= residuals[2] {
+			t.Errorf("FitHelmert [%d]: expected the residual %f to be below the residual of the outlier %f", index, residual, residuals[2])
+		}
+	}
+}
+
+func TestFitHelmertErrors(t *testing.T) {
+	if _, _, err := FitHelmert(controlPoints(HelmertWGS84ToMGI)[:2]); err != ErrRange {
+		t.Errorf("FitHelmert: expected ErrRange for two control points, got %v", err)
+	}
+
+	var collinear []ControlPoint
+	for i := 0; i < 4; i++ {
+		from := Point3D{X: 4000000 + float64(i)*10000, Y: 1000000 + float64(i)*20000, Z: 4700000 - float64(i)*5000}
+		collinear = append(collinear, ControlPoint{From: from, To: *HelmertWGS84ToMGI.Transform(&from)})
+	}
+	if _, _, err := FitHelmert(collinear); err != ErrRange {
+		t.Errorf("FitHelmert: expected ErrRange for collinear control points, got %v", err)
+	}
+}
+
+// ## ConvertBatch
+func TestConvertBatch(t *testing.T) {
+	points := []*PolarCoord{
+		{Latitude: 47.567, Longitude: 14.243, El: WGS84Ellipsoid},
+		{Latitude: -33.922667, Longitude: 18.416689, El: WGS84Ellipsoid},
+		nil,
+	}
+
+	tocart := func(pc *PolarCoord) (*PolarCoord, error) {
+		if pc == nil {
+			return nil, ErrRange
+		}
+		return CartesianToPolar(PolarToCartesian(pc)), nil
+	}
+
+	out, err := ConvertBatch(context.Background(), points, tocart)
+	if err != nil {
+		t.Fatalf("ConvertBatch: %s", err)
+	}
+
+	if len(out) != len(points) {
+		t.Fatalf("ConvertBatch: expected %d results, got %d", len(points), len(out))
+	}
+
+	for index := 0; index < 2; index++ {
+		if out[index].Err != nil || !latlongequal(points[index], out[index].Coord) {
+			t.Errorf("ConvertBatch [%d]: expected %s, got %s (%v)", index, points[index], out[index].Coord, out[index].Err)
+		}
+	}
+
+	if out[2].Err != ErrRange {
+		t.Errorf("ConvertBatch [2]: expected ErrRange, got %v", out[2].Err)
+	}
+}
+
+func TestConvertBatchCancel(t *testing.T) {
+	points := make([]*PolarCoord, 10*batchCancelInterval)
+	for index := range points {
+		points[index] = &PolarCoord{Latitude: 47, Longitude: 14, El: WGS84Ellipsoid}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls int32
+
+	out, err := ConvertBatch(ctx, points, func(pc *PolarCoord) (*PolarCoord, error) {
+		if atomic.AddInt32(&calls, 1) == batchCancelInterval+1 {
+			cancel()
+		}
+		return pc, nil
+	})
+
+	if err != context.Canceled {
+		t.Errorf("ConvertBatch: expected context.Canceled, got %v", err)
+	}
+
+	if len(out) != 2*batchCancelInterval {
+		t.Errorf("ConvertBatch: expected %d partial results, got %d", 2*batchCancelInterval, len(out))
+	}
+
+	for index, result := range out {
+		if result.Index != index || result.Coord != points[index] {
+			t.Errorf("ConvertBatch [%d]: expected partial result of point %d, got point %d", index, index, result.Index)
+		}
+	}
+}
+
+func TestConvertBatchOrder(t *testing.T) {
+	// the points are shuffled and every conversion takes a random time to complete,
+	// so the conversions complete out of order
+	rnd := rand.New(rand.NewSource(42))
+	points := make([]*PolarCoord, 5*batchCancelInterval+7)
+	for index, perm := range rnd.Perm(len(points)) {
+		points[index] = &PolarCoord{Latitude: float64(perm%180) - 90, Longitude: float64(perm%360) - 180, Height: float64(perm), El: WGS84Ellipsoid}
+	}
+
+	delays := make([]time.Duration, len(points))
+	for index := range delays {
+		delays[index] = time.Duration(rnd.Intn(100)) * time.Microsecond
+	}
+
+	out, err := ConvertBatch(context.Background(), points, func(pc *PolarCoord) (*PolarCoord, error) {
+		time.Sleep(delays[int(pc.Height)])
+		if int(pc.Height)%3 == 0 {
+			return nil, ErrRange
+		}
+		return &PolarCoord{Latitude: pc.Latitude, Longitude: pc.Longitude, Height: pc.Height, El: pc.El}, nil
+	})
+	if err != nil {
+		t.Fatalf("ConvertBatch: %s", err)
+	}
+
+	if len(out) != len(points) {
+		t.Fatalf("ConvertBatch: expected %d results, got %d", len(points), len(out))
+	}
+
+	for index, result := range out {
+		if result.Index != index {
+			t.Errorf("ConvertBatch [%d]: expected index %d, got %d", index, index, result.Index)
+		}
+
+		if int(points[index].Height)%3 == 0 {
+			if result.Err != ErrRange || result.Coord != nil {
+				t.Errorf("ConvertBatch [%d]: expected ErrRange, got %s (%v)", index, result.Coord, result.Err)
+			}
+			continue
+		}
+
+		if result.Err != nil || result.Coord.Height != points[index].Height {
+			t.Errorf("ConvertBatch [%d]: expected the conversion of point %.0f, got %s (%v)", index, points[index].Height, result.Coord, result.Err)
+		}
+	}
+}
+
+// ## ProcessBatch
+func TestProcessBatch(t *testing.T) {
+	n := 3*batchCancelInterval + 5
+	calls := make([]int32, n)
+
+	done, err := ProcessBatch(context.Background(), n, func(i int) { atomic.AddInt32(&calls[i], 1) })
+	if done != n || err != nil {
+		t.Fatalf("ProcessBatch: expected %d items processed, got %d (%v)", n, done, err)
+	}
+	for index, count := range calls {
+		if count != 1 {
+			t.Errorf("ProcessBatch [%d]: expected one call, got %d", index, count)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if done, err := ProcessBatch(ctx, n, func(i int) { t.Errorf("ProcessBatch: unexpected call of item %d", i) }); done != 0 || err != context.Canceled {
+		t.Errorf("ProcessBatch: expected no item processed and context.Canceled, got %d (%v)", done, err)
+	}
+}
+
+// ## ConvertAll
+func TestConvertAll(t *testing.T) {
+	// every conversion takes a random time to complete, so the conversions complete out of order
+	rnd := rand.New(rand.NewSource(42))
+	points := make([]*PolarCoord, 1000)
+	delays := make([]time.Duration, len(points))
+	for index := range points {
+		points[index] = &PolarCoord{Latitude: float64(index%180) - 90, Longitude: float64(index%360) - 180, Height: float64(index), El: WGS84Ellipsoid}
+		delays[index] = time.Duration(rnd.Intn(50)) * time.Microsecond
+	}
+
+	for _, workers := range []int{0, 1, 3, 16, 2000} {
+		var running, maxrunning int32
+		out := ConvertAll(points, func(pc *PolarCoord) (*PolarCoord, error) {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				max := atomic.LoadInt32(&maxrunning)
+				if n <= max || atomic.CompareAndSwapInt32(&maxrunning, max, n) {
+					break
+				}
+			}
+			defer atomic.AddInt32(&running, -1)
+
+			time.Sleep(delays[int(pc.Height)])
+			if int(pc.Height)%7 == 0 {
+				return nil, ErrRange
+			}
+			return &PolarCoord{Latitude: pc.Latitude, Longitude: pc.Longitude, Height: pc.Height, El: pc.El}, nil
+		}, workers)
+
+		if workers > 0 && int(maxrunning) > workers {
+			t.Errorf("ConvertAll [workers %d]: expected at most %d concurrent conversions, got %d", workers, workers, maxrunning)
+		}
+
+		if len(out) != len(points) {
+			t.Fatalf("ConvertAll [workers %d]: expected %d results, got %d", workers, len(points), len(out))
+		}
+
+		for index, result := range out {
+			if result.Index != index {
+				t.Errorf("ConvertAll [workers %d, %d]: expected index %d, got %d", workers, index, index, result.Index)
+			}
+
+			if index%7 == 0 {
+				if result.Err != ErrRange || result.Coord != nil {
+					t.Errorf("ConvertAll [workers %d, %d]: expected ErrRange, got %s (%v)", workers, index, result.Coord, result.Err)
+				}
+				continue
+			}
+
+			if result.Err != nil || result.Coord.Height != points[index].Height {
+				t.Errorf("ConvertAll [workers %d, %d]: expected the conversion of point %d, got %s (%v)", workers, index, index, result.Coord, result.Err)
+			}
+		}
+	}
+
+	if out := ConvertAll(nil, func(pc *PolarCoord) (*PolarCoord, error) { return pc, nil }, 4); len(out) != 0 {
+		t.Errorf("ConvertAll: expected no results for no points, got %d", len(out))
+	}
+}
+
+// ## Geocoder
+func TestNopGeocoder(t *testing.T) {
+	if _, ok := DefaultGeocoder.(NopGeocoder); !ok {
+		t.Errorf("DefaultGeocoder: expected NopGeocoder, got %T", DefaultGeocoder)
+	}
+
+	name, err := NopGeocoder{}.ReverseGeocode(&PolarCoord{Latitude: 48.2, Longitude: 16.37, El: WGS84Ellipsoid})
+	if name != "" || err != nil {
+		t.Errorf("NopGeocoder: expected no name, got '%s' (%v)", name, err)
+	}
+}
+
+// the number of conversions of BenchmarkConvertAll, each a round trip to cartesian coordinates
+const benchmarkConvertAllPoints = 10000
+
+func BenchmarkConvertAll(b *testing.B) {
+	points := make([]*PolarCoord, benchmarkConvertAllPoints)
+	for index := range points {
+		points[index] = &PolarCoord{Latitude: 47 + float64(index)/benchmarkConvertAllPoints, Longitude: 15 + float64(index)/benchmarkConvertAllPoints, El: WGS84Ellipsoid}
+	}
+	tocart := func(pc *PolarCoord) (*PolarCoord, error) {
+		return CartesianToPolar(PolarToCartesian(pc)), nil
+	}
+
+	// the time per conversion of all points should drop as workers are added, up to the number of cores
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				ConvertAll(points, tocart, workers)
+			}
+		})
+	}
+}
+
+// ## Allocation-free variants
+func TestIntoVariants(t *testing.T) {
+	gc := &PolarCoord{Latitude: 47.57, Longitude: 14.0075, Height: 500, El: WGS84Ellipsoid}
+
+	var cart CartPoint
+	PolarToCartesianInto(gc, &cart)
+	if expected := PolarToCartesian(gc); cart != *expected {
+		t.Errorf("PolarToCartesianInto: expected %v, got %v", expected, cart)
+	}
+
+	var polar PolarCoord
+	CartesianToPolarInto(&cart, &polar)
+	if expected := CartesianToPolar(&cart); polar != *expected {
+		t.Errorf("CartesianToPolarInto: expected %v, got %v", expected, polar)
+	}
+
+	pt := Point3D{X: cart.X, Y: cart.Y, Z: cart.Z}
+	expected3d := HelmertWGS84ToMGI.Transform(&pt)
+	HelmertWGS84ToMGI.TransformInto(&pt, &pt)
+	if pt != *expected3d {
+		t.Errorf("HelmertTransform.TransformInto: expected %v in place, got %v", expected3d, pt)
+	}
+
+	var gp GeoPoint
+	DirectTransverseMercatorInto(gc, 0, 15, 0.9996, 500000, 0, &gp)
+	if expected := DirectTransverseMercator(gc, 0, 15, 0.9996, 500000, 0); gp != *expected {
+		t.Errorf("DirectTransverseMercatorInto: expected %v, got %v", expected, gp)
+	}
+
+	// a reused result must not keep the height of the previous coordinate
+	InverseTransverseMercatorInto(&gp, 0, 15, 0.9996, 500000, 0, &polar)
+	if expected := InverseTransverseMercator(&gp, 0, 15, 0.9996, 500000, 0); polar != *expected {
+		t.Errorf("InverseTransverseMercatorInto: expected %v, got %v", expected, polar)
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		PolarToCartesianInto(gc, &cart)
+		HelmertWGS84ToMGI.TransformInto(&pt, &pt)
+		CartesianToPolarInto(&cart, &polar)
+		DirectTransverseMercatorInto(&polar, 0, 15, 0.9996, 500000, 0, &gp)
+		InverseTransverseMercatorInto(&gp, 0, 15, 0.9996, 500000, 0, &polar)
+	})
+	if allocs != 0 {
+		t.Errorf("Into variants: expected no allocations, got %.0f", allocs)
+	}
+}
+
+// keeps the results of the benchmarks, so the compiler can't optimize the conversions away
+var benchmarkResult interface{}
+
+func BenchmarkPolarToCartesian(b *testing.B) {
+	gc := &PolarCoord{Latitude: 47.57, Longitude: 14.0075, El: WGS84Ellipsoid}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchmarkResult = PolarToCartesian(gc)
+	}
+}
+
+func BenchmarkPolarToCartesianInto(b *testing.B) {
+	gc := &PolarCoord{Latitude: 47.57, Longitude: 14.0075, El: WGS84Ellipsoid}
+	var cart CartPoint
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		PolarToCartesianInto(gc, &cart)
+	}
+}
+
+func BenchmarkCartesianToPolar(b *testing.B) {
+	cart := PolarToCartesian(&PolarCoord{Latitude: 47.57, Longitude: 14.0075, El: WGS84Ellipsoid})
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchmarkResult = CartesianToPolar(cart)
+	}
+}
+
+func BenchmarkCartesianToPolarInto(b *testing.B) {
+	cart := PolarToCartesian(&PolarCoord{Latitude: 47.57, Longitude: 14.0075, El: WGS84Ellipsoid})
+	var gc PolarCoord
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		CartesianToPolarInto(cart, &gc)
+	}
+}
+
+func BenchmarkDirectTransverseMercator(b *testing.B) {
+	gc := &PolarCoord{Latitude: 47.57, Longitude: 14.0075, El: WGS84Ellipsoid}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchmarkResult = DirectTransverseMercator(gc, 0, 15, 0.9996, 500000, 0)
+	}
+}
+
+func BenchmarkDirectTransverseMercatorInto(b *testing.B) {
+	gc := &PolarCoord{Latitude: 47.57, Longitude: 14.0075, El: WGS84Ellipsoid}
+	var gp GeoPoint
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		DirectTransverseMercatorInto(gc, 0, 15, 0.9996, 500000, 0, &gp)
+	}
+}
+
+func BenchmarkInverseTransverseMercator(b *testing.B) {
+	gp := DirectTransverseMercator(&PolarCoord{Latitude: 47.57, Longitude: 14.0075, El: WGS84Ellipsoid}, 0, 15, 0.9996, 500000, 0)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchmarkResult = InverseTransverseMercator(gp, 0, 15, 0.9996, 500000, 0)
+	}
+}
+
+// A tight loop of the conversion of WGS84 to BMN and back, every step of which depends on the parameters derived
+// from the axes of the ellipsoids
+func BenchmarkConversionLoop(b *testing.B) {
+	gc := PolarCoord{Latitude: 47.57, Longitude: 14.0075, El: WGS84Ellipsoid}
+	var cart CartPoint
+	var pt Point3D
+	var gp GeoPoint
+	var polar PolarCoord
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		PolarToCartesianInto(&gc, &cart)
+		pt = Point3D{X: cart.X, Y: cart.Y, Z: cart.Z}
+		HelmertWGS84ToMGI.TransformInto(&pt, &pt)
+		cart = CartPoint{X: pt.X, Y: pt.Y, Z: pt.Z, El: Bessel1841MGIEllipsoid}
+		CartesianToPolarInto(&cart, &polar)
+		DirectTransverseMercatorInto(&polar, 0, 13+20.0/60, 1, 450000, -5000000, &gp)
+		InverseTransverseMercatorInto(&gp, 0, 13+20.0/60, 1, 450000, -5000000, &polar)
+	}
+}
+
+func BenchmarkHelmertTransform(b *testing.B) {
+	pt := &Point3D{X: 4194423, Y: 1045913, Z: 4682318}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchmarkResult = HelmertWGS84ToMGI.Transform(pt)
+	}
+}
+
+// ## Coordinate
+func TestCoordinate(t *testing.T) {
+	gc := &PolarCoord{Latitude: 48.208493, Longitude: 16.373118, El: WGS84Ellipsoid}
+	ntm, err := LatLongToNTM(&PolarCoord{Latitude: 59.91, Longitude: 10.75, El: GRS80Ellipsoid})
+	if err != nil {
+		t.Fatalf("LatLongToNTM: %s", err)
+	}
+	for _, test := range []struct {
+		coord  Coordinate
+		system string
+		epsg   int
+		gc     *PolarCoord
+	}{
+		{gc, "latlong", EPSGWGS84, gc},
+		{&PolarCoord{Latitude: 48.208493, Longitude: 16.373118}, "latlong", EPSGWGS84, gc},
+		{LatLongToUTM(gc), "utm", EPSGWGS84UTMNorth + 33, gc},
+		{LatLongToUPS(&PolarCoord{Latitude: 85, Longitude: 20, El: WGS84Ellipsoid}), "ups", EPSGWGS84UPSNorth, &PolarCoord{Latitude: 85, Longitude: 20}},
+		{ntm, "ntm", 5110, &PolarCoord{Latitude: 59.91, Longitude: 10.75}},
+	} {
+		out, err := test.coord.ToWGS84()
+		if err != nil || !out.Equal(test.gc, 0.001) || out.El != WGS84Ellipsoid || test.coord.System() != test.system || test.coord.EPSG() != test.epsg {
+			t.Errorf("Coordinate %s: expected %s on %s, EPSG %d, got %s on %s, EPSG %d (%v)", test.coord, test.gc, test.system, test.epsg, out, test.coord.System(), test.coord.EPSG(), err)
 		}
 	}
+
+	if out, _ := gc.ToWGS84(); out == gc {
+		t.Error("PolarCoord.ToWGS84: expected a copy of the bearing")
+	}
+	mgi := &PolarCoord{Latitude: 48.2, Longitude: 16.37, El: Bessel1841MGIEllipsoid}
+	if _, err := mgi.ToWGS84(); err != ErrUnsupported || mgi.EPSG() != 0 {
+		t.Errorf("PolarCoord.ToWGS84: expected ErrUnsupported on MGI without EPSG code, got %v, EPSG %d", err, mgi.EPSG())
+	}
+	if _, err := (&UTMCoord{Zone: "31U", Easting: 442774, Northing: 5962878, El: International1924Ellipsoid}).ToWGS84(); err != ErrUnsupported {
+		t.Errorf("UTMCoord.ToWGS84: expected ErrUnsupported on ED50, got %v", err)
+	}
 }
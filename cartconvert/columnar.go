@@ -0,0 +1,131 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// This file provides a compact binary columnar format for bulk conversion results.
+package cartconvert
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"math"
+)
+
+// ## Columnar format
+
+// The columnar format stores the results of a bulk conversion as columns of eastings and northings,
+// without framing of the single records. All values are little endian. The layout is
+//
+//	offset   size     content
+//	0        4        magic "CCOL"
+//	4        2        version, 1
+//	6        2        flags, reserved, 0
+//	8        4        number of points n
+//	12       4        reserved, 0
+//	16       8n       eastings as IEEE 754 float64
+//	16+8n    8n       northings as IEEE 754 float64
+//	16+16n   (n+7)/8  error bitmap, bit i%8 of byte i/8 is set if point i failed
+//
+// The columns of floats start at offsets which are a multiple of 8, so they can be mapped into memory
+// directly. The easting and northing of a failed point are NaN.
+const (
+	columnarMagic      = "CCOL"
+	columnarVersion    = 1
+	columnarHeaderSize = 16
+)
+
+// Writes eastingNorthing, the interleaved eastings and northings of a bulk conversion, together with the
+// errors per point in the columnar format to w. errs is either nil, if all points converted successfully,
+// or carries one entry per point, as returned by bmn.WGS84LatLongToBMNFlat.
+//
+// Returns ErrSyntax, if eastingNorthing has odd length or the length of errs does not match the number of points.
+func WriteColumnar(w io.Writer, eastingNorthing []float64, errs []error) error {
+
+	if len(eastingNorthing)%2 != 0 {
+		return ErrSyntax
+	}
+	n := len(eastingNorthing) / 2
+	if errs != nil && len(errs) != n {
+		return ErrSyntax
+	}
+
+	bw := bufio.NewWriter(w)
+
+	var header [columnarHeaderSize]byte
+	copy(header[:], columnarMagic)
+	binary.LittleEndian.PutUint16(header[4:], columnarVersion)
+	binary.LittleEndian.PutUint32(header[8:], uint32(n))
+	bw.Write(header[:])
+
+	failed := func(i int) bool { return errs != nil && errs[i] != nil }
+
+	var buf [8]byte
+	for column := 0; column < 2; column++ {
+		for i := 0; i < n; i++ {
+			value := eastingNorthing[2*i+column]
+			if failed(i) {
+				value = math.NaN()
+			}
+			binary.LittleEndian.PutUint64(buf[:], math.Float64bits(value))
+			bw.Write(buf[:])
+		}
+	}
+
+	bitmap := make([]byte, (n+7)/8)
+	for i := 0; i < n; i++ {
+		if failed(i) {
+			bitmap[i/8] |= 1 << uint(i%8)
+		}
+	}
+	bw.Write(bitmap)
+
+	return bw.Flush()
+}
+
+// Reads results written by WriteColumnar from r. failed is true for every point which failed to convert.
+//
+// Returns ErrSyntax, if the data is not in the columnar format or truncated, ErrUnsupported for an unknown version,
+// or the error of r.
+func ReadColumnar(r io.Reader) (eastings, northings []float64, failed []bool, err error) {
+
+	var header [columnarHeaderSize]byte
+	if _, err = io.ReadFull(r, header[:]); err != nil {
+		return nil, nil, nil, columnarReadError(err)
+	}
+	if string(header[:4]) != columnarMagic {
+		return nil, nil, nil, ErrSyntax
+	}
+	if binary.LittleEndian.Uint16(header[4:]) != columnarVersion {
+		return nil, nil, nil, ErrUnsupported
+	}
+	n := int(binary.LittleEndian.Uint32(header[8:]))
+
+	// the number of points of the header is not trusted: the data is read as far as it is given, so a forged number
+	// of points allocates no more than the length of the input
+	size := 16*int64(n) + (int64(n)+7)/8
+	data, err := ioutil.ReadAll(io.LimitReader(r, size))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if int64(len(data)) < size {
+		return nil, nil, nil, ErrSyntax
+	}
+
+	eastings, northings, failed = make([]float64, n), make([]float64, n), make([]bool, n)
+	for i := 0; i < n; i++ {
+		eastings[i] = math.Float64frombits(binary.LittleEndian.Uint64(data[8*i:]))
+		northings[i] = math.Float64frombits(binary.LittleEndian.Uint64(data[8*(n+i):]))
+		failed[i] = data[16*n+i/8]&(1<<uint(i%8)) != 0
+	}
+	return eastings, northings, failed, nil
+}
+
+// Maps the errors of truncated data to ErrSyntax
+func columnarReadError(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrSyntax
+	}
+	return err
+}
@@ -0,0 +1,127 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// Automated tests for the columnar format of the cartconvert package
+package cartconvert
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+// ## WriteColumnar
+func TestColumnar(t *testing.T) {
+	// nine points, so the error bitmap spans two bytes
+	en := []float64{703168, 374510, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+	errs := make([]error, 9)
+	errs[1], errs[8] = ErrRange, ErrRange
+
+	for _, e := range [][]error{errs, nil} {
+		buf := new(bytes.Buffer)
+		if err := WriteColumnar(buf, en, e); err != nil {
+			t.Fatalf("WriteColumnar: %s", err)
+		}
+		if buf.Len() != columnarHeaderSize+16*9+2 {
+			t.Errorf("WriteColumnar: expected %d bytes, got %d", columnarHeaderSize+16*9+2, buf.Len())
+		}
+
+		eastings, northings, failed, err := ReadColumnar(buf)
+		if err != nil {
+			t.Fatalf("ReadColumnar: %s", err)
+		}
+
+		for i := range eastings {
+			if isfailed := e != nil && e[i] != nil; failed[i] != isfailed {
+				t.Errorf("ReadColumnar [%d]: expected failed %t, got %t", i, isfailed, failed[i])
+			} else if isfailed {
+				if !math.IsNaN(eastings[i]) || !math.IsNaN(northings[i]) {
+					t.Errorf("ReadColumnar [%d]: expected NaN for a failed point, got %f %f", i, eastings[i], northings[i])
+				}
+			} else if eastings[i] != en[2*i] || northings[i] != en[2*i+1] {
+				t.Errorf("ReadColumnar [%d]: expected %f %f, got %f %f", i, en[2*i], en[2*i+1], eastings[i], northings[i])
+			}
+		}
+	}
+
+	if err := WriteColumnar(new(bytes.Buffer), en[:3], nil); err != ErrSyntax {
+		t.Errorf("WriteColumnar: expected ErrSyntax for odd length, got %v", err)
+	}
+	if err := WriteColumnar(new(bytes.Buffer), en, errs[:2]); err != ErrSyntax {
+		t.Errorf("WriteColumnar: expected ErrSyntax for a mismatch of errors, got %v", err)
+	}
+}
+
+func TestReadColumnarErrors(t *testing.T) {
+	valid := new(bytes.Buffer)
+	WriteColumnar(valid, []float64{1, 2, 3, 4}, nil)
+	data := valid.Bytes()
+
+	version := append([]byte(nil), data...)
+	version[4] = 2
+
+	for index, test := range []struct {
+		data []byte
+		err  error
+	}{
+		{nil, ErrSyntax},
+		{[]byte("CCOL"), ErrSyntax},
+		{append([]byte("XCOL"), data[4:]...), ErrSyntax},
+		{data[:len(data)-1], ErrSyntax},
+		{version, ErrUnsupported},
+		// a header announcing 2^32-1 points, followed by the data of two
+		{append(append([]byte(nil), data[:8]...), append([]byte{0xff, 0xff, 0xff, 0xff}, data[12:]...)...), ErrSyntax},
+	} {
+		if _, _, _, err := ReadColumnar(bytes.NewReader(test.data)); err != test.err {
+			t.Errorf("ReadColumnar [%d]: expected %v, got %v", index, test.err, err)
+		}
+	}
+}
+
+const benchmarkColumnarPoints = 10000
+
+func benchmarkColumnarData() []float64 {
+	en := make([]float64, 2*benchmarkColumnarPoints)
+	for i := range en {
+		en[i] = 500000 + float64(i)*1.37
+	}
+	return en
+}
+
+func BenchmarkReadColumnar(b *testing.B) {
+	buf := new(bytes.Buffer)
+	WriteColumnar(buf, benchmarkColumnarData(), nil)
+	data := buf.Bytes()
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		ReadColumnar(bytes.NewReader(data))
+	}
+}
+
+// the same results as array of JSON objects, for comparison with BenchmarkReadColumnar
+func BenchmarkReadColumnarJSON(b *testing.B) {
+	type result struct {
+		Easting, Northing float64
+		Error             string `json:",omitempty"`
+	}
+
+	en := benchmarkColumnarData()
+	results := make([]result, benchmarkColumnarPoints)
+	for i := range results {
+		results[i] = result{Easting: en[2*i], Northing: en[2*i+1]}
+	}
+	data, _ := json.Marshal(results)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var out []result
+		json.Unmarshal(data, &out)
+	}
+}
@@ -0,0 +1,385 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// This package converts coordinate literals between any of the coordinate systems supported by cartconvert and
+// its subpackages, given by their names. Every coordinate system is registered with a function converting its
+// literal to WGS84 latitude / longitude and a function converting WGS84 latitude / longitude to its literal, so a
+// conversion pivots through WGS84:
+//
+//	out, err := convert.Convert("M34 592269 272290", "bmn", "utm")
+//
+// The built-in coordinate systems are
+//
+//	latlong, dms, utm, ups, geohash, bmn, osgb36, gk, irishgrid, itm, lv03, lv95, rd, lambert93, austrialambert, mgrs, maidenhead, olc
+//
+// The source coordinate system "auto" detects the coordinate system of the literal by package parse.
+package convert
+
+import (
+	"fmt"
+	"github.com/the42/cartconvert/cartconvert"
+	"github.com/the42/cartconvert/cartconvert/austrialambert"
+	"github.com/the42/cartconvert/cartconvert/bmn"
+	"github.com/the42/cartconvert/cartconvert/dutchrd"
+	"github.com/the42/cartconvert/cartconvert/gausskrueger"
+	"github.com/the42/cartconvert/cartconvert/irishgrid"
+	"github.com/the42/cartconvert/cartconvert/lambert93"
+	"github.com/the42/cartconvert/cartconvert/lv03p"
+	"github.com/the42/cartconvert/cartconvert/maidenhead"
+	"github.com/the42/cartconvert/cartconvert/mgrs"
+	"github.com/the42/cartconvert/cartconvert/olc"
+	"github.com/the42/cartconvert/cartconvert/osgb36"
+	"github.com/the42/cartconvert/cartconvert/parse"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// The name of the source coordinate system detecting the coordinate system of a literal
+const Auto = "auto"
+
+// Converts WGS84 latitude / longitude to the literal of a coordinate of a coordinate system. Returns an error,
+// if the coordinate lies outside of the coordinate system.
+type FromWGS84 func(gc *cartconvert.PolarCoord) (string, error)
+
+// The error returned if a coordinate system is not registered
+type UnknownSystemError struct {
+	System string
+}
+
+func (use UnknownSystemError) Error() string {
+	return fmt.Sprintf("unknown coordinate system \"%s\", known are %s", use.System, strings.Join(Systems(), ", "))
+}
+
+// The error returned if the literal Input is not a coordinate of the source coordinate system, Err being the
+// error of its parser, like cartconvert.ErrSyntax
+type ParseError struct {
+	System string
+	Input  string
+	Err    error
+}
+
+func (pe ParseError) Error() string {
+	return fmt.Sprintf("\"%s\" is not a coordinate of %s: %s", pe.Input, pe.System, pe.Err)
+}
+
+// Unwrap returns the error of the parser
+func (pe ParseError) Unwrap() error {
+	return pe.Err
+}
+
+// The error returned if the WGS84 latitude / longitude Coord can not be converted to the target coordinate
+// system, Err being the error of the conversion, like cartconvert.ErrRange for a coordinate outside of the
+// coordinate system
+type FormatError struct {
+	System string
+	Coord  *cartconvert.PolarCoord
+	Err    error
+}
+
+func (fe FormatError) Error() string {
+	return fmt.Sprintf("%s can not be converted to %s: %s", fe.Coord, fe.System, fe.Err)
+}
+
+// Unwrap returns the error of the conversion
+func (fe FormatError) Unwrap() error {
+	return fe.Err
+}
+
+type system struct {
+	parse parse.Parser
+	from  FromWGS84
+}
+
+var (
+	registryMu sync.RWMutex
+	names      []string
+	systems    = make(map[string]system)
+)
+
+// Register adds a coordinate system of the given name, whose literals are parsed by parser into coordinates
+// converting themselves to WGS84, and which is converted from WGS84 by from. Returns cartconvert.ErrSyntax if the
+// name is empty or one of the functions is nil and cartconvert.ErrDuplicate if a coordinate system of the name is
+// already registered.
+func Register(name string, parser parse.Parser, from FromWGS84) error {
+	if name == "" || name == Auto || parser == nil || from == nil {
+		return cartconvert.ErrSyntax
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, ok := systems[name]; ok {
+		return cartconvert.ErrDuplicate
+	}
+	systems[name] = system{parse: parser, from: from}
+	names = append(names, name)
+	return nil
+}
+
+// Systems returns the names of the registered coordinate systems in the order of their registration.
+func Systems() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	return append([]string(nil), names...)
+}
+
+// lookup returns the coordinate system of the name, case insensitive
+func lookup(name string) (system, error) {
+	registryMu.RLock()
+	sys, ok := systems[strings.ToLower(name)]
+	registryMu.RUnlock()
+
+	if !ok {
+		return system{}, UnknownSystemError{System: name}
+	}
+	return sys, nil
+}
+
+// Parse parses the literal coord of the coordinate system fromSystem. The coordinate system "auto" detects the
+// coordinate system of the literal and returns the coordinate parsed by package parse.
+//
+// returns UnknownSystemError if fromSystem is not registered
+// returns ParseError if coord is not a coordinate of fromSystem, or the parse.NoMatchError for "auto"
+func Parse(coord, fromSystem string) (cartconvert.Coordinate, error) {
+	c, _, err := parseCoordinate(coord, fromSystem)
+	return c, err
+}
+
+// parseCoordinate parses coord like Parse and returns the name of its coordinate system, the detected one for "auto"
+func parseCoordinate(coord, fromSystem string) (cartconvert.Coordinate, string, error) {
+	if strings.EqualFold(fromSystem, Auto) {
+		return parse.ParseCoordinate(coord)
+	}
+
+	sys, err := lookup(fromSystem)
+	if err != nil {
+		return nil, "", err
+	}
+	c, err := sys.parse(strings.TrimSpace(coord))
+	if err != nil {
+		return nil, "", ParseError{System: fromSystem, Input: coord, Err: err}
+	}
+	return c, fromSystem, nil
+}
+
+// LatLong converts the literal coord of the coordinate system fromSystem to WGS84 latitude / longitude. The
+// coordinate system "auto" detects the coordinate system of the literal.
+//
+// returns UnknownSystemError if fromSystem is not registered
+// returns ParseError if coord is not a coordinate of fromSystem or can not be converted to WGS84, or the
+// parse.NoMatchError for "auto"
+func LatLong(coord, fromSystem string) (*cartconvert.PolarCoord, error) {
+	c, system, err := parseCoordinate(coord, fromSystem)
+	if err != nil {
+		return nil, err
+	}
+	gc, err := c.ToWGS84()
+	if err != nil {
+		return nil, ParseError{System: system, Input: coord, Err: err}
+	}
+	return gc, nil
+}
+
+// Format converts WGS84 latitude / longitude to the literal of the coordinate system toSystem.
+//
+// returns UnknownSystemError if toSystem is not registered
+// returns FormatError if gc can not be converted to toSystem
+func Format(gc *cartconvert.PolarCoord, toSystem string) (string, error) {
+	sys, err := lookup(toSystem)
+	if err != nil {
+		return "", err
+	}
+	out, err := sys.from(gc)
+	if err != nil {
+		return "", FormatError{System: toSystem, Coord: gc, Err: err}
+	}
+	return out, nil
+}
+
+// Convert converts the literal input of the coordinate system fromSystem to the literal of the coordinate system
+// toSystem, pivoting through WGS84 latitude / longitude. Coordinate systems are given by their names, case
+// insensitive. The source coordinate system "auto" detects the coordinate system of the literal.
+//
+// returns UnknownSystemError if fromSystem or toSystem is not registered
+// returns ParseError if input is not a coordinate of fromSystem, and FormatError if it can not be converted to
+// toSystem
+func Convert(input, fromSystem, toSystem string) (string, error) {
+	if _, err := lookup(toSystem); err != nil {
+		return "", err
+	}
+	gc, err := LatLong(input, fromSystem)
+	if err != nil {
+		return "", err
+	}
+	return Format(gc, toSystem)
+}
+
+// parsed returns coord as a cartconvert.Coordinate, or nil on error, so the nil pointer of a failed parser is not
+// returned as a non-nil interface
+func parsed(coord cartconvert.Coordinate, err error) (cartconvert.Coordinate, error) {
+	if err != nil {
+		return nil, err
+	}
+	return coord, nil
+}
+
+// stringer returns the literal of the result of a conversion
+func stringer(s fmt.Stringer, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	return s.String(), nil
+}
+
+// The built-in coordinate systems
+func init() {
+	builtin := []struct {
+		name   string
+		parser parse.Parser
+		from   FromWGS84
+	}{
+		{"latlong",
+			func(coord string) (cartconvert.Coordinate, error) {
+				return parsed(cartconvert.ADegMinSecToPolar(coord, false))
+			},
+			func(gc *cartconvert.PolarCoord) (string, error) {
+				return strconv.FormatFloat(gc.Latitude, 'f', -1, 64) + ", " + strconv.FormatFloat(gc.Longitude, 'f', -1, 64), nil
+			}},
+		{"dms",
+			func(coord string) (cartconvert.Coordinate, error) {
+				return parsed(cartconvert.ADegMinSecToPolar(coord, false))
+			},
+			func(gc *cartconvert.PolarCoord) (string, error) {
+				lat, long := cartconvert.LatLongToString(gc, cartconvert.LLFdms)
+				return lat + " " + long, nil
+			}},
+		{"utm",
+			func(coord string) (cartconvert.Coordinate, error) {
+				return parsed(cartconvert.AUTMToStruct(coord, cartconvert.WGS84Ellipsoid))
+			},
+			func(gc *cartconvert.PolarCoord) (string, error) {
+				utm, err := cartconvert.LatLongToUTMChecked(gc)
+				if err != nil {
+					return "", err
+				}
+				return utm.String(), nil
+			}},
+		// UTM, or UPS in the polar regions
+		{"ups",
+			func(coord string) (cartconvert.Coordinate, error) {
+				return parsed(cartconvert.AUTMToStruct(coord, cartconvert.WGS84Ellipsoid))
+			},
+			func(gc *cartconvert.PolarCoord) (string, error) {
+				return cartconvert.LatLongToUTMUPS(gc).String(), nil
+			}},
+		// a geohash has no coordinate struct of its own, it parses into the middle of its cell
+		{"geohash",
+			func(coord string) (cartconvert.Coordinate, error) {
+				return parsed(cartconvert.GeoHashToLatLong(coord, cartconvert.WGS84Ellipsoid))
+			},
+			func(gc *cartconvert.PolarCoord) (string, error) {
+				return cartconvert.LatLongToGeoHashChecked(gc)
+			}},
+		{"bmn",
+			func(coord string) (cartconvert.Coordinate, error) {
+				return parsed(bmn.ABMNToStruct(coord))
+			},
+			func(gc *cartconvert.PolarCoord) (string, error) {
+				return stringer(bmn.WGS84LatLongToBMN(gc, bmn.BMNZoneDet))
+			}},
+		{"osgb36",
+			func(coord string) (cartconvert.Coordinate, error) {
+				return parsed(osgb36.AOSGB36ToStruct(coord, osgb36.OSGB36Leave))
+			},
+			func(gc *cartconvert.PolarCoord) (string, error) {
+				return stringer(osgb36.WGS84LatLongToOSGB36(gc))
+			}},
+		{"gk",
+			func(coord string) (cartconvert.Coordinate, error) {
+				return parsed(gausskrueger.AGKToStruct(coord))
+			},
+			func(gc *cartconvert.PolarCoord) (string, error) {
+				return stringer(gausskrueger.WGS84LatLongToGK(gc, gausskrueger.GKZoneDet))
+			}},
+		{"irishgrid",
+			func(coord string) (cartconvert.Coordinate, error) {
+				return parsed(irishgrid.AIrishGridToStruct(coord))
+			},
+			func(gc *cartconvert.PolarCoord) (string, error) {
+				return stringer(irishgrid.WGS84LatLongToIrishGrid(gc))
+			}},
+		{"itm",
+			func(coord string) (cartconvert.Coordinate, error) {
+				return parsed(irishgrid.AITMToStruct(coord))
+			},
+			func(gc *cartconvert.PolarCoord) (string, error) {
+				return irishgrid.WGS84LatLongToITM(gc).String(), nil
+			}},
+		// LV03 and LV95 are told apart by the magnitude of the coordinate, so both read either of them
+		{"lv03",
+			func(coord string) (cartconvert.Coordinate, error) {
+				return parsed(lv03p.ASwissCoordToStruct(coord))
+			},
+			func(gc *cartconvert.PolarCoord) (string, error) {
+				return stringer(lv03p.WGS84LatLongToSwissCoord(gc, lv03p.LV03))
+			}},
+		{"lv95",
+			func(coord string) (cartconvert.Coordinate, error) {
+				return parsed(lv03p.ASwissCoordToStruct(coord))
+			},
+			func(gc *cartconvert.PolarCoord) (string, error) {
+				return stringer(lv03p.WGS84LatLongToSwissCoord(gc, lv03p.LV95))
+			}},
+		{"rd",
+			func(coord string) (cartconvert.Coordinate, error) {
+				return parsed(dutchrd.ARDToStruct(coord))
+			},
+			func(gc *cartconvert.PolarCoord) (string, error) {
+				return stringer(dutchrd.WGS84LatLongToRD(gc))
+			}},
+		{"lambert93",
+			func(coord string) (cartconvert.Coordinate, error) {
+				return parsed(lambert93.AL93ToStruct(coord))
+			},
+			func(gc *cartconvert.PolarCoord) (string, error) {
+				return lambert93.WGS84LatLongToL93(gc).String(), nil
+			}},
+		{"austrialambert",
+			func(coord string) (cartconvert.Coordinate, error) {
+				return parsed(austrialambert.AALToStruct(coord))
+			},
+			func(gc *cartconvert.PolarCoord) (string, error) {
+				return stringer(austrialambert.WGS84LatLongToAL(gc))
+			}},
+		{"mgrs",
+			func(coord string) (cartconvert.Coordinate, error) {
+				return parsed(mgrs.AMGRSToStruct(coord))
+			},
+			func(gc *cartconvert.PolarCoord) (string, error) {
+				return stringer(mgrs.WGS84LatLongToMGRS(gc, mgrs.MGRS_1m))
+			}},
+		{"maidenhead",
+			func(coord string) (cartconvert.Coordinate, error) {
+				return parsed(maidenhead.AMaidenheadToStruct(coord))
+			},
+			func(gc *cartconvert.PolarCoord) (string, error) {
+				return stringer(maidenhead.WGS84LatLongToMaidenhead(gc, maidenhead.MaidenheadExtendedSquare))
+			}},
+		{"olc",
+			func(coord string) (cartconvert.Coordinate, error) {
+				return parsed(olc.AOLCToStruct(coord))
+			},
+			func(gc *cartconvert.PolarCoord) (string, error) {
+				return stringer(olc.WGS84LatLongToOLC(gc, olc.OLCPairLength))
+			}},
+	}
+
+	for _, b := range builtin {
+		if err := Register(b.name, b.parser, b.from); err != nil {
+			panic(err)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+package convert
+
+import (
+	"errors"
+	"github.com/the42/cartconvert/cartconvert"
+	"github.com/the42/cartconvert/cartconvert/parse"
+	"testing"
+)
+
+// ## Convert
+type convertTest struct {
+	in, from, to, out string
+}
+
+var convertTests = []convertTest{
+	{"M34 592269 272290", "bmn", "latlong", "47.57030382968155, 14.236146128316541"},
+	{"M34 592269 272290", "BMN", "utm", "33T 442549 5268826"},
+	{"33T 442549 5268826", "utm", "mgrs", "33T VN 42548 68826"},
+	{"47.5703038, 14.2361461", "latlong", "dms", "N 47°34'13.09'' E 14°14'10.13''"},
+	{"N 47°34'13.09'' E 14°14'10.13''", "dms", "geohash", "u26negymn70v217ssec71bcz"},
+	{"NN 166 712", "osgb36", "maidenhead", "IO76lt91"},
+	// the approximate formulas of swisstopo are accurate to about 1 meter
+	{"Y:600000 X:200000", "lv03", "lv95", "E:2600000.346334 N:1199999.830821"},
+	{"87, -62.3", "latlong", "ups", "Y 1705036 1845140"},
+	{"625844.87 483218.81", "austrialambert", "bmn", "M34 753020 341124"},
+	// the source coordinate system is detected
+	{"M34 592269 272290", "auto", "utm", "33T 442549 5268826"},
+	{"8FWH4HX8+QR", "auto", "latlong", "48.1494375, 11.5670625"},
+}
+
+func TestConvert(t *testing.T) {
+	for cnt, test := range convertTests {
+		out, err := Convert(test.in, test.from, test.to)
+		if err != nil {
+			t.Errorf("Convert [%d]: %s", cnt, err)
+			continue
+		}
+		if out != test.out {
+			t.Errorf("Convert [%d]: expected %s, got %s", cnt, test.out, out)
+		}
+	}
+}
+
+// every coordinate system reads the literals it writes
+func TestConvertRoundTrip(t *testing.T) {
+	gc := &cartconvert.PolarCoord{Latitude: 46.95, Longitude: 7.44, El: cartconvert.WGS84Ellipsoid}
+	for _, system := range Systems() {
+		out, err := Format(gc, system)
+		// Bern lies outside of the national grids of the other countries
+		if errors.Is(err, cartconvert.ErrRange) {
+			continue
+		}
+		if err != nil {
+			t.Errorf("Format %s: %s", system, err)
+			continue
+		}
+		if _, err = LatLong(out, system); err != nil {
+			t.Errorf("LatLong %s: %s", system, err)
+		}
+	}
+}
+
+func TestConvertErrors(t *testing.T) {
+	if _, err := Convert("M34 592269 272290", "bmn", "wgs72"); err != (UnknownSystemError{System: "wgs72"}) {
+		t.Errorf("Convert: expected UnknownSystemError for the target, got %v", err)
+	}
+	if _, err := Convert("M34 592269 272290", "bmm", "utm"); err != (UnknownSystemError{System: "bmm"}) {
+		t.Errorf("Convert: expected UnknownSystemError for the source, got %v", err)
+	}
+	if _, err := Convert("M99 592269 272290", "bmn", "utm"); !errors.As(err, new(ParseError)) || !errors.Is(err, cartconvert.ErrSyntax) {
+		t.Errorf("Convert: expected ParseError with ErrSyntax, got %v", err)
+	}
+	if _, err := Convert("M34 592269 272290", "bmn", "osgb36"); !errors.As(err, new(FormatError)) || !errors.Is(err, cartconvert.ErrRange) {
+		t.Errorf("Convert: expected FormatError with ErrRange, got %v", err)
+	}
+	if _, err := Convert("600000 200000", "auto", "utm"); !errors.As(err, new(parse.NoMatchError)) {
+		t.Errorf("Convert: expected NoMatchError, got %v", err)
+	}
+}
+
+// ## Parse
+func TestParse(t *testing.T) {
+	coord, err := Parse("M34 592269 272290", "bmn")
+	if err != nil || coord.System() != "bmn" || coord.String() != "M34 592269 272290" {
+		t.Errorf("Parse: expected M34 592269 272290 on bmn, got %v (%v)", coord, err)
+	}
+	// auto returns the coordinate detected by package parse
+	if coord, err = Parse("33UXP0123456789", Auto); err != nil || coord.System() != "mgrs" {
+		t.Errorf("Parse: expected a coordinate of mgrs, got %v (%v)", coord, err)
+	}
+	if coord, err = Parse("M99 592269 272290", "bmn"); coord != nil || !errors.As(err, new(ParseError)) {
+		t.Errorf("Parse: expected ParseError, got %v (%v)", coord, err)
+	}
+}
+
+// ## Register
+func TestRegister(t *testing.T) {
+	parser := func(coord string) (cartconvert.Coordinate, error) { return nil, cartconvert.ErrSyntax }
+	from := func(gc *cartconvert.PolarCoord) (string, error) { return "", nil }
+
+	if err := Register("utm", parser, from); err != cartconvert.ErrDuplicate {
+		t.Errorf("Register: expected ErrDuplicate, got %v", err)
+	}
+	for _, name := range []string{"", Auto} {
+		if err := Register(name, parser, from); err != cartconvert.ErrSyntax {
+			t.Errorf("Register %q: expected ErrSyntax, got %v", name, err)
+		}
+	}
+	if err := Register("test", parser, nil); err != cartconvert.ErrSyntax {
+		t.Errorf("Register: expected ErrSyntax for a missing conversion, got %v", err)
+	}
+}
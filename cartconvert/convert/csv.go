@@ -0,0 +1,178 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+package convert
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/the42/cartconvert/cartconvert"
+	"io"
+	"strings"
+)
+
+// A CSVMapping maps the columns of a CSV table, named by its header, to the coordinate of a row and names the
+// coordinate systems the coordinate is converted to.
+type CSVMapping struct {
+	// The source coordinate system of the coordinate, "auto" to detect it row by row
+	From string
+	// The names of the columns holding the coordinate, eg. "lat" and "long", or "zone", "easting" and "northing".
+	// Their values are joined by blanks in the given order to the literal of the coordinate.
+	Columns []string
+	// The target coordinate systems, each appended as a column named like the coordinate system
+	To []string
+	// The name of a column appended to hold the error of a row, which failed to convert. Without a name, the row is
+	// written with empty result columns only.
+	ErrorColumn string
+	// The decimal mark of the numbers of the results, the decimal point if not set. The columns read are written
+	// unchanged.
+	DecimalMark cartconvert.DecimalMark
+}
+
+// The error of a row of a CSV table, which failed to convert. Line is the line of the row within the table, the
+// header being line 1.
+type RowError struct {
+	Line int
+	Err  error
+}
+
+func (re RowError) Error() string {
+	return fmt.Sprintf("line %d: %s", re.Line, re.Err)
+}
+
+// Unwrap returns the error of the conversion
+func (re RowError) Unwrap() error {
+	return re.Err
+}
+
+// ConvertCSV reads a CSV table with a header from r, converts the coordinate of every row as given by mapping and
+// writes the table with the results appended as columns to w. The other columns are written unchanged. Column
+// names are compared case insensitive and without surrounding blanks.
+//
+// A row which fails to convert, or which can not be read, is written with empty result columns and reported to
+// report, if not nil, and the remaining rows keep being converted. Returns the number of rows which failed to
+// convert.
+//
+// returns cartconvert.ErrSyntax if mapping names no columns or no target coordinate systems
+// returns UnknownSystemError if a coordinate system of mapping is not registered
+// returns an error wrapping cartconvert.ErrNotFound, if a column of mapping is missing from the header
+func ConvertCSV(r *csv.Reader, w *csv.Writer, mapping CSVMapping, report func(RowError)) (failed int, err error) {
+	if len(mapping.Columns) == 0 || len(mapping.To) == 0 {
+		return 0, cartconvert.ErrSyntax
+	}
+	if !strings.EqualFold(mapping.From, Auto) {
+		if _, err := lookup(mapping.From); err != nil {
+			return 0, err
+		}
+	}
+	for _, system := range mapping.To {
+		if _, err := lookup(system); err != nil {
+			return 0, err
+		}
+	}
+
+	// rows may differ in their number of columns, a short row lacks the coordinate only if it lacks a mapped column
+	r.FieldsPerRecord = -1
+	header, err := r.Read()
+	if err != nil {
+		return 0, err
+	}
+
+	indexes := make([]int, len(mapping.Columns))
+	for i, name := range mapping.Columns {
+		indexes[i] = -1
+		for j, column := range header {
+			if strings.EqualFold(strings.TrimSpace(column), strings.TrimSpace(name)) {
+				indexes[i] = j
+				break
+			}
+		}
+		if indexes[i] < 0 {
+			return 0, fmt.Errorf("%w: column \"%s\"", cartconvert.ErrNotFound, name)
+		}
+	}
+
+	columns := len(header)
+	header = append(header, mapping.To...)
+	if mapping.ErrorColumn != "" {
+		header = append(header, mapping.ErrorColumn)
+	}
+	if err := w.Write(header); err != nil {
+		return 0, err
+	}
+
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+
+		var line int
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			line = parseErr.Line
+		} else if err != nil {
+			return failed, err
+		} else {
+			line, _ = r.FieldPos(0)
+		}
+
+		results := make([]string, len(mapping.To))
+		if err == nil {
+			err = convertRow(record, indexes, mapping, results)
+		}
+		if err != nil {
+			failed++
+			if report != nil {
+				report(RowError{Line: line, Err: err})
+			}
+			for i := range results {
+				results[i] = ""
+			}
+		}
+
+		// the results are appended after the columns of the header, even to a short row
+		for len(record) < columns {
+			record = append(record, "")
+		}
+		record = append(record, results...)
+		if mapping.ErrorColumn != "" {
+			message := ""
+			if err != nil {
+				message = err.Error()
+			}
+			record = append(record, message)
+		}
+		if err := w.Write(record); err != nil {
+			return failed, err
+		}
+	}
+	w.Flush()
+	return failed, w.Error()
+}
+
+// convertRow converts the coordinate in the columns indexes of record to the target coordinate systems of mapping
+// and sets their literals in results
+func convertRow(record []string, indexes []int, mapping CSVMapping, results []string) error {
+	values := make([]string, len(indexes))
+	for i, index := range indexes {
+		if index >= len(record) {
+			return fmt.Errorf("%w: column \"%s\"", cartconvert.ErrNotFound, mapping.Columns[i])
+		}
+		values[i] = strings.TrimSpace(record[index])
+	}
+
+	gc, err := LatLong(strings.Join(values, " "), mapping.From)
+	if err != nil {
+		return err
+	}
+	for i, system := range mapping.To {
+		if results[i], err = Format(gc, system); err != nil {
+			return err
+		}
+		results[i] = mapping.DecimalMark.Localize(results[i])
+	}
+	return nil
+}
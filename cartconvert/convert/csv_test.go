@@ -0,0 +1,101 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+package convert
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"github.com/the42/cartconvert/cartconvert"
+	"strings"
+	"testing"
+)
+
+// ## ConvertCSV
+type convertCSVTest struct {
+	in      string
+	mapping CSVMapping
+	out     string
+	failed  []int
+}
+
+var convertCSVTests = []convertCSVTest{
+	// other columns are kept, the mapped columns are found regardless of case and blanks
+	{"name, Lat ,LONG\nStephansdom,48.208493,16.373118\n",
+		CSVMapping{From: "latlong", Columns: []string{"lat", "long"}, To: []string{"utm"}},
+		"name,\" Lat \",LONG,utm\nStephansdom,48.208493,16.373118,33U 602014 5340385\n", nil},
+	// the columns are joined in the order of the mapping, not of the table
+	{"northing;easting;zone\n5268826;442549;33T\n",
+		CSVMapping{From: "utm", Columns: []string{"zone", "easting", "northing"}, To: []string{"bmn", "geohash"}},
+		"northing;easting;zone;bmn;geohash\n5268826;442549;33T;M31 517966 270555;u26negymn7u9052v06jh0ggz\n", nil},
+	// the results take the decimal mark, the columns read are kept
+	{"name,lat,long\nStephansdom,48.208493,16.373118\n",
+		CSVMapping{From: "latlong", Columns: []string{"lat", "long"}, To: []string{"latlong", "utm"}, DecimalMark: cartconvert.DecimalComma},
+		"name,lat,long,latlong,utm\nStephansdom,48.208493,16.373118,\"48,208493, 16,373118\",33U 602014 5340385\n", nil},
+	// a failed row is written with empty results and the error, the conversion goes on
+	{"id,coord\n1,M34 592269 272290\n2,M99 592269 272290\n3\n4,M31 500761 270346\n",
+		CSVMapping{From: "auto", Columns: []string{"coord"}, To: []string{"utm"}, ErrorColumn: "error"},
+		"", []int{3, 4}},
+}
+
+func TestConvertCSV(t *testing.T) {
+	for cnt, test := range convertCSVTests {
+		r := csv.NewReader(strings.NewReader(test.in))
+		if strings.HasPrefix(test.in, "northing;") {
+			r.Comma = ';'
+		}
+		buf := new(bytes.Buffer)
+		w := csv.NewWriter(buf)
+		w.Comma = r.Comma
+
+		var lines []int
+		failed, err := ConvertCSV(r, w, test.mapping, func(re RowError) { lines = append(lines, re.Line) })
+		if err != nil {
+			t.Errorf("ConvertCSV [%d]: %s", cnt, err)
+			continue
+		}
+		if failed != len(test.failed) || len(lines) != len(test.failed) {
+			t.Errorf("ConvertCSV [%d]: expected failed lines %v, got %d failed lines %v", cnt, test.failed, failed, lines)
+			continue
+		}
+		for i := range lines {
+			if lines[i] != test.failed[i] {
+				t.Errorf("ConvertCSV [%d]: expected failed lines %v, got %v", cnt, test.failed, lines)
+				break
+			}
+		}
+		if test.out != "" && buf.String() != test.out {
+			t.Errorf("ConvertCSV [%d]: expected\n%s\ngot\n%s", cnt, test.out, buf.String())
+		}
+		if test.mapping.ErrorColumn != "" {
+			rows, _ := csv.NewReader(buf).ReadAll()
+			if len(rows) != 5 || rows[0][3] != "error" || rows[1][3] != "" || rows[2][2] != "" || rows[2][3] == "" ||
+				rows[3][1] != "" || rows[3][3] == "" || rows[4][2] == "" {
+				t.Errorf("ConvertCSV [%d]: expected the errors of lines 3 and 4 in the error column, got %v", cnt, rows)
+			}
+		}
+	}
+}
+
+func TestConvertCSVMapping(t *testing.T) {
+	table := "lat,long\n48.2,16.37\n"
+	convertCSV := func(mapping CSVMapping) error {
+		_, err := ConvertCSV(csv.NewReader(strings.NewReader(table)), csv.NewWriter(new(bytes.Buffer)), mapping, nil)
+		return err
+	}
+
+	if err := convertCSV(CSVMapping{From: "latlong", To: []string{"utm"}}); err != cartconvert.ErrSyntax {
+		t.Errorf("ConvertCSV: expected ErrSyntax without columns, got %v", err)
+	}
+	if err := convertCSV(CSVMapping{From: "latlong", Columns: []string{"lat", "long"}}); err != cartconvert.ErrSyntax {
+		t.Errorf("ConvertCSV: expected ErrSyntax without target coordinate systems, got %v", err)
+	}
+	if _, ok := convertCSV(CSVMapping{From: "latlong", Columns: []string{"lat", "long"}, To: []string{"nowhere"}}).(UnknownSystemError); !ok {
+		t.Error("ConvertCSV: expected UnknownSystemError for an unknown target coordinate system")
+	}
+	if err := convertCSV(CSVMapping{From: "latlong", Columns: []string{"lat", "lon"}, To: []string{"utm"}}); !errors.Is(err, cartconvert.ErrNotFound) {
+		t.Errorf("ConvertCSV: expected ErrNotFound for a missing column, got %v", err)
+	}
+}
@@ -0,0 +1,522 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// This package provides a series of functions to deal with
+// conversion and transformations of coordinates in the Danish coordinate systems.
+//
+// The official Danish grid is UTM on ETRS89. Unlike the regular UTM zone rule, all of Denmark
+// except Bornholm is mapped in zone 32, Bornholm is mapped in zone 33.
+//
+// The legacy System 34 is not an analytic projection but the result of an adjustment of the
+// Danish triangulation. Transformations from and to System 34 are therefore performed by interpolating
+// in a correction grid, which maps ETRS89 / UTM zone 32 coordinates to System 34 coordinates.
+// The package does not ship the correction grids, they have to be loaded by LoadCorrectionGrid
+// or supplied by implementing System34Grid.
+//
+// For more information see:
+//
+// [EN]: http://en.wikipedia.org/wiki/System_34
+package dk
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"github.com/the42/cartconvert/cartconvert"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ## Danish UTM on ETRS89
+
+// Bounding boxes of Denmark and of Bornholm in decimal degrees
+const (
+	denmarkMinLat, denmarkMaxLat     = 54.5, 57.8
+	denmarkMinLong, denmarkMaxLong   = 8.0, 15.2
+	bornholmMinLat, bornholmMaxLat   = 54.95, 55.35
+	bornholmMinLong, bornholmMaxLong = 14.6, 15.2
+)
+
+// UTM zones of the Danish grid
+const (
+	zoneDenmark  = 32
+	zoneBornholm = 33
+)
+
+// Returns the UTM meridian zone used by the Danish national cartography for a latitude / longitude
+// coordinate: zone 33 for Bornholm and zone 32 for the rest of Denmark, although Zealand east of 12°
+// would regularly fall into zone 33.
+//
+// Returns cartconvert.ErrRange if the coordinate lies outside of Denmark.
+func DanishUTMZone(gc *cartconvert.PolarCoord) (uint, error) {
+
+	if err := gc.CheckFinite(); err != nil {
+		return 0, err
+	}
+
+	switch {
+	case gc.Latitude >= bornholmMinLat && gc.Latitude <= bornholmMaxLat &&
+		gc.Longitude >= bornholmMinLong && gc.Longitude <= bornholmMaxLong:
+		return zoneBornholm, nil
+	case gc.Latitude >= denmarkMinLat && gc.Latitude <= denmarkMaxLat &&
+		gc.Longitude >= denmarkMinLong && gc.Longitude <= denmarkMaxLong:
+		return zoneDenmark, nil
+	}
+	return 0, cartconvert.ErrRange
+}
+
+// Transform a latitude / longitude coordinate into an UTM coordinate of the Danish national
+// cartography, selecting the zone by DanishUTMZone. Function returns cartconvert.ErrRange, if the
+// coordinate lies outside of Denmark.
+//
+// ETRS89 is treated as identical to WGS84. No datum shift is applied, the reference ellipsoid of
+// the resulting UTM coordinate is the GRS80Ellipsoid.
+func LatLongToDanishUTM(gc *cartconvert.PolarCoord) (*cartconvert.UTMCoord, error) {
+
+	zonenumber, err := DanishUTMZone(gc)
+	if err != nil {
+		return nil, err
+	}
+
+	etrs := *gc
+	etrs.El = cartconvert.GRS80Ellipsoid
+
+	return cartconvert.LatLongToUTMZone(&etrs, zonenumber)
+}
+
+// Transform an UTM coordinate of the Danish national cartography into latitude and longitude.
+// Function returns cartconvert.ErrRange, if the zone of the UTM coordinate is not one of the zones
+// 32 or 33 used in Denmark.
+//
+// The reference ellipsoid is always the GRS80Ellipsoid, regardless of the actually set reference ellipsoid.
+func DanishUTMToLatLong(coord *cartconvert.UTMCoord) (*cartconvert.PolarCoord, error) {
+
+	zonelength := len(coord.Zone)
+	if zonelength < 2 {
+		return nil, cartconvert.ErrSyntax
+	}
+
+	zonenumber, err := strconv.ParseUint(coord.Zone[:zonelength-1], 10, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if zonenumber != zoneDenmark && zonenumber != zoneBornholm {
+		return nil, cartconvert.ErrRange
+	}
+
+	etrs := *coord
+	etrs.El = cartconvert.GRS80Ellipsoid
+
+	return cartconvert.UTMToLatLong(&etrs)
+}
+
+// ## System 34
+
+// Region of System 34. Jutland and Zealand are separate grids with separate origins.
+type System34Region byte
+
+const (
+	S34J System34Region = iota + 1 // Jutland and Funen
+	S34S                           // Zealand, Lolland and Falster
+)
+
+func (region System34Region) String() (rep string) {
+	switch region {
+	case S34J:
+		rep = "S34J"
+	case S34S:
+		rep = "S34S"
+	default:
+		rep = "#unknown"
+	}
+	return
+}
+
+// A System 34 coordinate is specified by easting (y), northing (x) and the region
+type System34Coord struct {
+	Easting  float64        `json:"easting" xml:"easting"`
+	Northing float64        `json:"northing" xml:"northing"`
+	Region   System34Region `json:"region" xml:"region"`
+}
+
+// Canonical representation of a System 34 coordinate
+func (coord *System34Coord) String() string {
+	return fmt.Sprintf("%s %.2f %.2f", coord.Region, coord.Easting, coord.Northing)
+}
+
+// Implements cartconvert.Coordinate, the system of a System 34 coordinate is "system34"
+func (coord *System34Coord) System() string {
+	return "system34"
+}
+
+// Implements cartconvert.Coordinate, System 34 has no EPSG code, so it returns 0
+func (coord *System34Coord) EPSG() int {
+	return 0
+}
+
+// Implements cartconvert.Coordinate. Returns ErrUnsupported, as System 34 is converted by a correction grid, which
+// has to be passed to System34ToWGS84LatLong.
+func (coord *System34Coord) ToWGS84() (*cartconvert.PolarCoord, error) {
+	return nil, cartconvert.ErrUnsupported
+}
+
+// Representation of a System 34 coordinate with precision decimal places of easting and northing, see
+// cartconvert.FormatFixed
+func (coord *System34Coord) Format(precision int) string {
+	return fmt.Sprintf("%s %s %s", coord.Region, cartconvert.FormatFixed(coord.Easting, precision), cartconvert.FormatFixed(coord.Northing, precision))
+}
+
+// Returns a copy of the System 34 coordinate with easting and northing snapped to the nearest multiple of
+// spacingMeters, see cartconvert.SnapToGrid. The region is kept.
+func (coord *System34Coord) Snap(spacingMeters float64) *System34Coord {
+	snapped := *coord
+	snapped.Easting, snapped.Northing = cartconvert.SnapToGrid(coord.Easting, spacingMeters), cartconvert.SnapToGrid(coord.Northing, spacingMeters)
+	return &snapped
+}
+
+// Implements json.Marshaler, writing the coordinates in fixed-point notation
+func (coord *System34Coord) MarshalJSON() ([]byte, error) {
+	return cartconvert.MarshalFixedJSON(coord)
+}
+
+// Implements xml.Marshaler, writing the coordinates in fixed-point notation
+func (coord *System34Coord) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return cartconvert.MarshalFixedXML(e, start, coord)
+}
+
+// Parses a string representation of a System 34 coordinate of the format
+//
+//	"REGION EASTING NORTHING"
+//
+// where region is either S34J or S34S and easting and northing are specified as decimal meters.
+func AS34ToStruct(coord string) (*System34Coord, error) {
+
+	fields := strings.Fields(strings.ToUpper(coord))
+	if len(fields) != 3 {
+		return nil, cartconvert.ErrSyntax
+	}
+
+	var region System34Region
+	switch fields[0] {
+	case "S34J":
+		region = S34J
+	case "S34S":
+		region = S34S
+	default:
+		return nil, cartconvert.ErrSyntax
+	}
+
+	easting, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return nil, err
+	}
+
+	northing, err := strconv.ParseFloat(fields[2], 64)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = cartconvert.CheckFinite(easting, northing); err != nil {
+		return nil, err
+	}
+
+	return &System34Coord{Easting: easting, Northing: northing, Region: region}, nil
+}
+
+// A System34Grid maps ETRS89 / UTM zone 32 coordinates of a region to System 34 coordinates.
+// Shift returns the difference of the System 34 coordinate to the UTM coordinate, which is
+// added to the UTM coordinate. Shift returns cartconvert.ErrRange if the UTM coordinate
+// lies outside of the grid.
+type System34Grid interface {
+	Region() System34Region
+	Shift(easting, northing float64) (de, dn float64, err error)
+}
+
+// Methods of interpolating between the nodes of a CorrectionGrid
+type Interpolation int
+
+const (
+	// Bilinear interpolation between the four surrounding nodes, as used by the official grid definitions
+	Bilinear Interpolation = iota
+	// Bicubic convolution (Catmull-Rom) of the sixteen surrounding nodes, which yields a smooth gradient of the corrections
+	Bicubic
+	// The correction of the nearest node, a fast approximation
+	NearestNeighbor
+)
+
+func (method Interpolation) String() string {
+	switch method {
+	case Bilinear:
+		return "bilinear"
+	case Bicubic:
+		return "bicubic"
+	case NearestNeighbor:
+		return "nearest neighbor"
+	}
+	return fmt.Sprintf("Interpolation(%d)", int(method))
+}
+
+// A CorrectionGrid is a regular grid of coordinate corrections. Interpolation sets the method
+// used by Shift and defaults to Bilinear.
+type CorrectionGrid struct {
+	Interpolation Interpolation
+
+	region                  System34Region
+	originE, originN        float64 // south-west corner of the grid
+	spacing                 float64
+	columns, rows           int
+	corrections             [][2]float64 // row by row from south to north, west to east
+	maxEasting, maxNorthing float64      // north-east corner of the grid
+}
+
+// Loads a correction grid from r. The grid file is a text file of the format
+//
+//	# comment
+//	region S34J
+//	origin <easting> <northing>
+//	spacing <meters>
+//	size <columns> <rows>
+//	<de> <dn>
+//	...
+//
+// Origin is the ETRS89 / UTM zone 32 coordinate of the south-west corner of the grid and spacing the
+// distance of two grid nodes in meters. Size is followed by one correction per line for each of the
+// columns times rows grid nodes, row by row from south to north and from west to east within a row.
+// Empty lines and lines starting with # are ignored.
+//
+// Returns cartconvert.ErrSyntax if the grid file is malformed.
+func LoadCorrectionGrid(r io.Reader) (*CorrectionGrid, error) {
+
+	grid := &CorrectionGrid{}
+	scanner := bufio.NewScanner(r)
+
+	var values []float64
+	parse := func(fields []string, n int) bool {
+		if len(fields) != n {
+			return false
+		}
+		values = values[:0]
+		for _, field := range fields {
+			val, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				return false
+			}
+			values = append(values, val)
+		}
+		return true
+	}
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		fields := strings.Fields(line)
+
+		switch fields[0] {
+		case "region":
+			if len(fields) != 2 {
+				return nil, cartconvert.ErrSyntax
+			}
+			switch fields[1] {
+			case "S34J":
+				grid.region = S34J
+			case "S34S":
+				grid.region = S34S
+			default:
+				return nil, cartconvert.ErrSyntax
+			}
+		case "origin":
+			if !parse(fields[1:], 2) {
+				return nil, cartconvert.ErrSyntax
+			}
+			grid.originE, grid.originN = values[0], values[1]
+		case "spacing":
+			if !parse(fields[1:], 1) || !(values[0] > 0) {
+				return nil, cartconvert.ErrSyntax
+			}
+			grid.spacing = values[0]
+		case "size":
+			if !parse(fields[1:], 2) || values[0] < 2 || values[1] < 2 || values[0] != math.Trunc(values[0]) || values[1] != math.Trunc(values[1]) {
+				return nil, cartconvert.ErrSyntax
+			}
+			grid.columns, grid.rows = int(values[0]), int(values[1])
+		default:
+			if grid.columns == 0 || len(grid.corrections) == grid.columns*grid.rows || !parse(fields, 2) {
+				return nil, cartconvert.ErrSyntax
+			}
+			grid.corrections = append(grid.corrections, [2]float64{values[0], values[1]})
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if grid.region == 0 || grid.spacing == 0 || grid.columns == 0 || len(grid.corrections) != grid.columns*grid.rows {
+		return nil, cartconvert.ErrSyntax
+	}
+
+	grid.maxEasting = grid.originE + float64(grid.columns-1)*grid.spacing
+	grid.maxNorthing = grid.originN + float64(grid.rows-1)*grid.spacing
+
+	return grid, nil
+}
+
+// Returns the System 34 region of the correction grid
+func (grid *CorrectionGrid) Region() System34Region {
+	return grid.region
+}
+
+// Returns the correction at the UTM coordinate, interpolated by the Interpolation method of the grid.
+// Returns cartconvert.ErrRange if the coordinate lies outside of the grid.
+func (grid *CorrectionGrid) Shift(easting, northing float64) (de, dn float64, err error) {
+	return grid.ShiftInterpolated(easting, northing, grid.Interpolation)
+}
+
+// Returns the correction at the UTM coordinate, interpolated by method. Returns cartconvert.ErrRange
+// if the coordinate lies outside of the grid and cartconvert.ErrUnsupported for an unknown method.
+func (grid *CorrectionGrid) ShiftInterpolated(easting, northing float64, method Interpolation) (de, dn float64, err error) {
+
+	if !(easting >= grid.originE && easting <= grid.maxEasting && northing >= grid.originN && northing <= grid.maxNorthing) {
+		return 0, 0, cartconvert.ErrRange
+	}
+
+	x := (easting - grid.originE) / grid.spacing
+	y := (northing - grid.originN) / grid.spacing
+
+	col, row := int(x), int(y)
+	// the eastern and northern edge belong to the last cell
+	if col == grid.columns-1 {
+		col--
+	}
+	if row == grid.rows-1 {
+		row--
+	}
+	fx, fy := x-float64(col), y-float64(row)
+
+	for i, d := range []*float64{&de, &dn} {
+		switch method {
+		case Bilinear:
+			*d = (1-fy)*((1-fx)*grid.node(col, row, i)+fx*grid.node(col+1, row, i)) +
+				fy*((1-fx)*grid.node(col, row+1, i)+fx*grid.node(col+1, row+1, i))
+		case Bicubic:
+			var p [4]float64
+			for r := range p {
+				p[r] = catmullRom(grid.node(col-1, row+r-1, i), grid.node(col, row+r-1, i), grid.node(col+1, row+r-1, i), grid.node(col+2, row+r-1, i), fx)
+			}
+			*d = catmullRom(p[0], p[1], p[2], p[3], fy)
+		case NearestNeighbor:
+			*d = grid.node(col+int(fx+0.5), row+int(fy+0.5), i)
+		default:
+			return 0, 0, cartconvert.ErrUnsupported
+		}
+	}
+	return
+}
+
+// Returns component i of the correction at a node of the grid. Nodes beyond the edges of the grid, as needed
+// by bicubic interpolation, are extrapolated from the three nodes next to the edge, so corrections varying
+// quadratically are reproduced exactly. Grids of two nodes per row or column are extrapolated linearly.
+func (grid *CorrectionGrid) node(col, row, i int) float64 {
+	switch {
+	case col < 0:
+		return extrapolate(grid.columns, func(c int) float64 { return grid.node(c, row, i) })
+	case col >= grid.columns:
+		return extrapolate(grid.columns, func(c int) float64 { return grid.node(grid.columns-1-c, row, i) })
+	case row < 0:
+		return extrapolate(grid.rows, func(r int) float64 { return grid.node(col, r, i) })
+	case row >= grid.rows:
+		return extrapolate(grid.rows, func(r int) float64 { return grid.node(col, grid.rows-1-r, i) })
+	}
+	return grid.corrections[row*grid.columns+col][i]
+}
+
+// Returns the value preceding the n values v(0), v(1), ..., extrapolated by the parabola through the first three values
+func extrapolate(n int, v func(int) float64) float64 {
+	if n < 3 {
+		return 2*v(0) - v(1)
+	}
+	return 3*v(0) - 3*v(1) + v(2)
+}
+
+// Interpolates between p1 and p2 at t in [0, 1] by the Catmull-Rom spline through p0 to p3
+func catmullRom(p0, p1, p2, p3, t float64) float64 {
+	return 0.5 * (2*p1 + (p2-p0)*t + (2*p0-5*p1+4*p2-p3)*t*t + (3*(p1-p2)+p3-p0)*t*t*t)
+}
+
+// Maximum number of iterations and precision in meters of the inversion of a System34Grid
+const (
+	inverseIterations = 20
+	inversePrecision  = 1e-4
+)
+
+// Transform a latitude / longitude coordinate into a System 34 coordinate of the region of the grid.
+// Function returns cartconvert.ErrRange, if the coordinate lies outside of the grid.
+//
+// ETRS89 is treated as identical to WGS84, the reference ellipsoid of the latitude / longitude coordinate
+// is assumed to be the GRS80Ellipsoid, regardless of the actually set reference ellipsoid.
+func WGS84LatLongToSystem34(gc *cartconvert.PolarCoord, grid System34Grid) (*System34Coord, error) {
+
+	etrs := *gc
+	etrs.El = cartconvert.GRS80Ellipsoid
+
+	utm, err := cartconvert.LatLongToUTMZone(&etrs, zoneDenmark)
+	if err != nil {
+		return nil, err
+	}
+
+	de, dn, err := grid.Shift(utm.Easting, utm.Northing)
+	if err != nil {
+		return nil, err
+	}
+
+	return &System34Coord{Easting: utm.Easting + de, Northing: utm.Northing + dn, Region: grid.Region()}, nil
+}
+
+// Transform a System 34 coordinate into latitude and longitude on ETRS89, using the correction grid of the
+// region of the coordinate. As the grid maps UTM to System 34 coordinates, the UTM coordinate is found by
+// iteration. Function returns cartconvert.ErrRange, if the region of the coordinate differs from the region
+// of the grid, the coordinate lies outside of the grid or the iteration does not converge.
+//
+// The reference ellipsoid of the result is the GRS80Ellipsoid.
+func System34ToWGS84LatLong(coord *System34Coord, grid System34Grid) (*cartconvert.PolarCoord, error) {
+
+	if coord.Region != grid.Region() {
+		return nil, cartconvert.ErrRange
+	}
+	if err := cartconvert.CheckFinite(coord.Easting, coord.Northing); err != nil {
+		return nil, err
+	}
+
+	easting, northing := coord.Easting, coord.Northing
+	for i := 0; ; i++ {
+		if i == inverseIterations {
+			return nil, cartconvert.ErrRange
+		}
+
+		de, dn, err := grid.Shift(easting, northing)
+		if err != nil {
+			return nil, err
+		}
+
+		nextE, nextN := coord.Easting-de, coord.Northing-dn
+		converged := math.Abs(nextE-easting) < inversePrecision && math.Abs(nextN-northing) < inversePrecision
+		easting, northing = nextE, nextN
+		if converged {
+			break
+		}
+	}
+
+	return cartconvert.InverseTransverseMercator(
+		&cartconvert.GeoPoint{X: easting, Y: northing, El: cartconvert.GRS80Ellipsoid},
+		0,
+		(zoneDenmark-1)*6-180+3,
+		0.9996,
+		500000,
+		0), nil
+}
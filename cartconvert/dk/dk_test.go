@@ -0,0 +1,274 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// Automated tests for the cartconvert/dk (Danish coordinate systems) package
+package dk
+
+import (
+	"fmt"
+	"github.com/the42/cartconvert/cartconvert"
+	"math"
+	"strings"
+	"testing"
+)
+
+func latlongequal(pcp1, pcp2 *cartconvert.PolarCoord) bool {
+	pp1s := fmt.Sprintf("%.6f %.6f", pcp1.Latitude, pcp1.Longitude)
+	pp2s := fmt.Sprintf("%.6f %.6f", pcp2.Latitude, pcp2.Longitude)
+
+	return pp1s == pp2s
+}
+
+// ## LatLongToDanishUTM
+type latLongToDanishUTMTest struct {
+	in   *cartconvert.PolarCoord
+	zone string
+	err  error
+}
+
+var latLongToDanishUTMTests = []latLongToDanishUTMTest{
+	// Copenhagen, regularly in zone 33
+	{&cartconvert.PolarCoord{Latitude: 55.676, Longitude: 12.568}, "32U", nil},
+	// Aarhus
+	{&cartconvert.PolarCoord{Latitude: 56.157, Longitude: 10.211}, "32V", nil},
+	// Rønne, Bornholm
+	{&cartconvert.PolarCoord{Latitude: 55.1, Longitude: 14.7}, "33U", nil},
+	// Hamburg
+	{&cartconvert.PolarCoord{Latitude: 53.55, Longitude: 10.0}, "", cartconvert.ErrRange},
+}
+
+func TestLatLongToDanishUTM(t *testing.T) {
+	for index, test := range latLongToDanishUTMTests {
+		out, err := LatLongToDanishUTM(test.in)
+
+		if err != test.err {
+			t.Errorf("LatLongToDanishUTM [%d]: expected error %v, got %v", index, test.err, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		if out.Zone != test.zone || out.El != cartconvert.GRS80Ellipsoid {
+			t.Errorf("LatLongToDanishUTM [%d]: expected zone %s on GRS80, got %s on %s", index, test.zone, out, out.El.CommonName)
+		}
+
+		latlong, err := DanishUTMToLatLong(out)
+		if err != nil || !latlongequal(test.in, latlong) {
+			t.Errorf("DanishUTMToLatLong [%d]: expected %s, got %s (%v)", index, test.in, latlong, err)
+		}
+	}
+
+	if _, err := DanishUTMToLatLong(&cartconvert.UTMCoord{Zone: "33T", Easting: 425351, Northing: 5268987}); err != nil {
+		t.Errorf("DanishUTMToLatLong: expected zone 33 to be accepted, got %v", err)
+	}
+	if _, err := DanishUTMToLatLong(&cartconvert.UTMCoord{Zone: "31U", Easting: 425351, Northing: 5268987}); err != cartconvert.ErrRange {
+		t.Errorf("DanishUTMToLatLong: expected ErrRange for zone 31, got %v", err)
+	}
+}
+
+// ## AS34ToStruct
+func TestAS34ToStruct(t *testing.T) {
+	out, err := AS34ToStruct("s34j 416015.5 6113030")
+	if err != nil {
+		t.Fatalf("AS34ToStruct: %s", err)
+	}
+
+	if out.String() != "S34J 416015.50 6113030.00" {
+		t.Errorf("AS34ToStruct: expected S34J 416015.50 6113030.00, got %s", out)
+	}
+
+	for _, in := range []string{"S45B 1 2", "S34S 1", "S34S 1 x"} {
+		if _, err := AS34ToStruct(in); err == nil {
+			t.Errorf("AS34ToStruct: expected an error for '%s'", in)
+		}
+	}
+}
+
+// ## System 34
+
+// a 4 by 4 grid with the linear corrections de = 1000 + 0.001 (E - 400000), dn = -2000 + 0.002 (N - 6100000)
+func testGrid() string {
+	grid := "# test grid\nregion S34J\norigin 400000 6100000\nspacing 10000\nsize 4 4\n"
+	for row := 0; row < 4; row++ {
+		for col := 0; col < 4; col++ {
+			grid += fmt.Sprintf("%f %f\n", 1000+0.001*float64(col*10000), -2000+0.002*float64(row*10000))
+		}
+	}
+	return grid
+}
+
+func TestSystem34(t *testing.T) {
+	grid, err := LoadCorrectionGrid(strings.NewReader(testGrid()))
+	if err != nil {
+		t.Fatalf("LoadCorrectionGrid: %s", err)
+	}
+
+	latlong := cartconvert.InverseTransverseMercator(&cartconvert.GeoPoint{X: 415000, Y: 6115000, El: cartconvert.GRS80Ellipsoid}, 0, 9, 0.9996, 500000, 0)
+
+	out, err := WGS84LatLongToSystem34(latlong, grid)
+	if err != nil {
+		t.Fatalf("WGS84LatLongToSystem34: %s", err)
+	}
+
+	if expected := "S34J 416015.00 6113030.00"; out.String() != expected {
+		t.Errorf("WGS84LatLongToSystem34: expected %s, got %s", expected, out)
+	}
+
+	back, err := System34ToWGS84LatLong(out, grid)
+	if err != nil || !latlongequal(latlong, back) {
+		t.Errorf("System34ToWGS84LatLong: expected %s, got %s (%v)", latlong, back, err)
+	}
+
+	if _, err := System34ToWGS84LatLong(&System34Coord{Easting: out.Easting, Northing: out.Northing, Region: S34S}, grid); err != cartconvert.ErrRange {
+		t.Errorf("System34ToWGS84LatLong: expected ErrRange for a coordinate of another region, got %v", err)
+	}
+
+	outside := &cartconvert.PolarCoord{Latitude: 57.5, Longitude: 10.5}
+	if _, err := WGS84LatLongToSystem34(outside, grid); err != cartconvert.ErrRange {
+		t.Errorf("WGS84LatLongToSystem34: expected ErrRange outside of the grid, got %v", err)
+	}
+}
+
+var malformedGrids = []string{
+	"",
+	"region S45B\norigin 0 0\nspacing 1\nsize 2 2\n0 0\n0 0\n0 0\n0 0\n",
+	"region S34J\norigin 0 0\nspacing 0\nsize 2 2\n0 0\n0 0\n0 0\n0 0\n",
+	"region S34J\norigin 0 0\nspacing 1\nsize 2 2\n0 0\n0 0\n0 0\n",
+	"region S34J\norigin 0 0\nspacing 1\nsize 2 2\n0 0\n0 0\n0 0\n0 0\n0 0\n",
+	"region S34J\norigin 0 0\nspacing 1\nsize 2.5 2\n0 0\n0 0\n0 0\n0 0\n",
+	"region S34J\n0 0\n",
+}
+
+func TestLoadCorrectionGrid(t *testing.T) {
+	for index, grid := range malformedGrids {
+		if _, err := LoadCorrectionGrid(strings.NewReader(grid)); err != cartconvert.ErrSyntax {
+			t.Errorf("LoadCorrectionGrid [%d]: expected ErrSyntax, got %v", index, err)
+		}
+	}
+}
+
+// ## Interpolation
+
+// a 5 by 5 grid with the quadratic corrections de = 1e-8 (E - 400000)², dn = 0.001 (N - 6100000)
+func quadraticGrid() string {
+	grid := "region S34J\norigin 400000 6100000\nspacing 10000\nsize 5 5\n"
+	for row := 0; row < 5; row++ {
+		for col := 0; col < 5; col++ {
+			x := float64(col * 10000)
+			grid += fmt.Sprintf("%f %f\n", 1e-8*x*x, 0.001*float64(row*10000))
+		}
+	}
+	return grid
+}
+
+type interpolationTest struct {
+	easting, northing float64
+	method            Interpolation
+	de, dn            float64
+	err               error
+}
+
+var interpolationTests = []interpolationTest{
+	{415000, 6125000, Bilinear, 2.5, 25, nil},
+	// bicubic convolution reproduces quadratic corrections, also in the cells at the edges of the grid
+	{415000, 6125000, Bicubic, 2.25, 25, nil},
+	{435000, 6105000, Bicubic, 12.25, 5, nil},
+	{401000, 6139000, Bicubic, 0.01, 39, nil},
+	{415000, 6124000, NearestNeighbor, 4, 20, nil},
+	{414000, 6126000, NearestNeighbor, 1, 30, nil},
+	{440000, 6140000, NearestNeighbor, 16, 40, nil},
+	{415000, 6125000, Interpolation(7), 0, 0, cartconvert.ErrUnsupported},
+	{445000, 6125000, Bicubic, 0, 0, cartconvert.ErrRange},
+}
+
+func TestShiftInterpolated(t *testing.T) {
+	grid, err := LoadCorrectionGrid(strings.NewReader(quadraticGrid()))
+	if err != nil {
+		t.Fatalf("LoadCorrectionGrid: %s", err)
+	}
+
+	for index, test := range interpolationTests {
+		de, dn, err := grid.ShiftInterpolated(test.easting, test.northing, test.method)
+		if err != test.err || math.Abs(de-test.de) > 1e-9 || math.Abs(dn-test.dn) > 1e-9 {
+			t.Errorf("ShiftInterpolated [%d] %s: expected %f %f (%v), got %f %f (%v)", index, test.method, test.de, test.dn, test.err, de, dn, err)
+		}
+	}
+
+	// Shift uses the interpolation method of the grid
+	grid.Interpolation = Bicubic
+	if de, _, _ := grid.Shift(415000, 6125000); math.Abs(de-2.25) > 1e-9 {
+		t.Errorf("Shift: expected the bicubic correction 2.25, got %f", de)
+	}
+}
+
+// ## RoundTrip
+// The positional error in meters of a latitude / longitude coordinate converted into a Danish UTM coordinate and back
+type roundTripTest struct {
+	gc        *cartconvert.PolarCoord
+	threshold float64
+}
+
+// UTM is inverted to the millimeter
+var roundTripTests = []roundTripTest{
+	{&cartconvert.PolarCoord{Latitude: 55.6761, Longitude: 12.5683, El: cartconvert.WGS84Ellipsoid}, 0.001}, // Copenhagen, forced into zone 32
+	{&cartconvert.PolarCoord{Latitude: 55.1, Longitude: 14.9, El: cartconvert.WGS84Ellipsoid}, 0.001},       // Bornholm
+	{&cartconvert.PolarCoord{Latitude: 57.05, Longitude: 9.92, El: cartconvert.WGS84Ellipsoid}, 0.001},
+}
+
+func TestRoundTrip(t *testing.T) {
+	for index, test := range roundTripTests {
+		utm, err := LatLongToDanishUTM(test.gc)
+		if err != nil {
+			t.Fatalf("LatLongToDanishUTM [%d]: %s", index, err)
+		}
+		out, err := DanishUTMToLatLong(utm)
+		if err != nil {
+			t.Fatalf("DanishUTMToLatLong [%d]: %s", index, err)
+		}
+
+		if d := cartconvert.Haversine(test.gc, out, 0); d > test.threshold {
+			t.Errorf("RoundTrip [%d]: expected a positional error below %g m, got %.3f m", index, test.threshold, d)
+		}
+	}
+}
+
+// ## NaN and infinity
+func TestNonFinite(t *testing.T) {
+	grid, err := LoadCorrectionGrid(strings.NewReader(testGrid()))
+	if err != nil {
+		t.Fatalf("LoadCorrectionGrid: %s", err)
+	}
+
+	for _, value := range []float64{math.NaN(), math.Inf(1)} {
+		if _, err := DanishUTMZone(&cartconvert.PolarCoord{Latitude: 55.7, Longitude: value}); err != cartconvert.ErrNotFinite {
+			t.Errorf("DanishUTMZone (%v): expected ErrNotFinite, got %v", value, err)
+		}
+		if _, err := AS34ToStruct(fmt.Sprintf("S34J %v 6113030", value)); err != cartconvert.ErrNotFinite {
+			t.Errorf("AS34ToStruct (%v): expected ErrNotFinite, got %v", value, err)
+		}
+		if _, err := WGS84LatLongToSystem34(&cartconvert.PolarCoord{Latitude: value, Longitude: 9}, grid); err != cartconvert.ErrNotFinite {
+			t.Errorf("WGS84LatLongToSystem34 (%v): expected ErrNotFinite, got %v", value, err)
+		}
+		if _, err := System34ToWGS84LatLong(&System34Coord{Easting: value, Northing: 6113030, Region: grid.Region()}, grid); err != cartconvert.ErrNotFinite {
+			t.Errorf("System34ToWGS84LatLong (%v): expected ErrNotFinite, got %v", value, err)
+		}
+	}
+}
+
+// ## Snap
+func TestSnap(t *testing.T) {
+	out := (&System34Coord{Easting: 195000.5, Northing: -1234.5, Region: S34S}).Snap(1)
+	if out.Easting != 195000 || out.Northing != -1234 || out.Region != S34S {
+		t.Errorf("System34Coord.Snap: expected s 195000.00 -1234.00, got %s", out)
+	}
+}
+
+// ## ToWGS84
+func TestToWGS84(t *testing.T) {
+	coord := &System34Coord{Easting: 416015.5, Northing: 6113030, Region: S34J}
+	if _, err := coord.ToWGS84(); err != cartconvert.ErrUnsupported || coord.System() != "system34" || coord.EPSG() != 0 {
+		t.Errorf("ToWGS84: expected ErrUnsupported on system34 without EPSG code, got %v on %s, EPSG %d", err, coord.System(), coord.EPSG())
+	}
+}
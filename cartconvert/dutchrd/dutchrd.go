@@ -0,0 +1,234 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// This package provides functions to deal with conversion of coordinates of the Dutch Rijksdriehoeksmeting (RD),
+// the national grid of the Netherlands on the Amersfoort datum.
+//
+// RD coordinates are given by x (easting) and y (northing) of an oblique stereographic double projection of the
+// Bessel ellipsoid, centered at the tower of the Onze Lieve Vrouwe church of Amersfoort at x = 155000 m and
+// y = 463000 m. Instead of the rigorous projection and datum shift, the package uses the approximating polynomials
+// published by the Dutch cadastre (Schreutelkamp and Strang van Hees, 2001), which transform between RD and
+// WGS84 directly. Within the Netherlands they deviate from the rigorous transformation by less than a meter.
+//
+// For further info see http://nl.wikipedia.org/wiki/Rijksdriehoeksco%C3%B6rdinaten
+package dutchrd
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/the42/cartconvert/cartconvert"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Geographic extent of RD, the territory of the Netherlands
+var Extent = &cartconvert.LatLongExtent{MinLat: 50.7, MaxLat: 53.7, MinLong: 3.2, MaxLong: 7.3}
+
+// The range of RD coordinates in meters, which covers the Netherlands
+const (
+	RDMinX = -7000
+	RDMaxX = 300000
+	RDMinY = 289000
+	RDMaxY = 629000
+)
+
+// RD and WGS84 coordinates of the origin, the church tower of Amersfoort
+const (
+	rdX0    = 155000
+	rdY0    = 463000
+	rdLat0  = 52.15517440
+	rdLong0 = 5.38720621
+)
+
+// A term of an approximating polynomial, coefficient * a^p * b^q
+type rdTerm struct {
+	p, q        int
+	coefficient float64
+}
+
+// Polynomials from RD to WGS84, giving latitude and longitude in seconds of arc relative to the origin.
+// a and b are x and y relative to the origin in units of 100 km.
+var (
+	rdToLat = []rdTerm{
+		{0, 1, 3235.65389}, {2, 0, -32.58297}, {0, 2, -0.24750}, {2, 1, -0.84978},
+		{0, 3, -0.06550}, {2, 2, -0.01709}, {1, 0, -0.00738}, {4, 0, 0.00530},
+		{2, 3, -0.00039}, {4, 1, 0.00033}, {1, 1, -0.00012}}
+	rdToLong = []rdTerm{
+		{1, 0, 5260.52916}, {1, 1, 105.94684}, {1, 2, 2.45656}, {3, 0, -0.81885},
+		{1, 3, 0.05594}, {3, 1, -0.05607}, {0, 1, 0.01199}, {3, 2, -0.00256},
+		{1, 4, 0.00128}, {0, 2, 0.00022}, {2, 0, -0.00022}, {5, 0, 0.00026}}
+)
+
+// Polynomials from WGS84 to RD, giving x and y in meters relative to the origin.
+// a and b are latitude and longitude relative to the origin in units of 10000".
+var (
+	latLongToX = []rdTerm{
+		{0, 1, 190094.945}, {1, 1, -11832.228}, {2, 1, -114.221}, {0, 3, -32.391},
+		{1, 0, -0.705}, {3, 1, -2.340}, {1, 3, -0.608}, {0, 2, -0.008},
+		{2, 3, 0.148}}
+	latLongToY = []rdTerm{
+		{1, 0, 309056.544}, {0, 2, 3638.893}, {2, 0, 73.077}, {1, 2, -157.984},
+		{3, 0, 59.788}, {0, 1, 0.433}, {2, 2, -6.439}, {1, 1, -0.032},
+		{0, 4, 0.092}, {1, 4, -0.054}}
+)
+
+func polynomial(terms []rdTerm, a, b float64) (sum float64) {
+	for _, term := range terms {
+		sum += term.coefficient * math.Pow(a, float64(term.p)) * math.Pow(b, float64(term.q))
+	}
+	return
+}
+
+// An RD coordinate is specified by x (easting) and y (northing) in meters
+type RDCoord struct {
+	X         float64                `json:"x" xml:"x"`
+	Y         float64                `json:"y" xml:"y"`
+	RelHeight float64                `json:"relheight" xml:"relheight"`
+	El        *cartconvert.Ellipsoid `json:"ellipsoid,omitempty" xml:"ellipsoid,omitempty"`
+}
+
+// Canonical representation of an RD coordinate, x and y to the meter, like "155000 463000"
+func (coord *RDCoord) String() string {
+	return fmt.Sprintf("%.0f %.0f", coord.X, coord.Y)
+}
+
+// Implements cartconvert.Coordinate, the system of an RD coordinate is "rd"
+func (coord *RDCoord) System() string {
+	return "rd"
+}
+
+// Implements cartconvert.Coordinate, see RDToWGS84LatLong
+func (coord *RDCoord) ToWGS84() (*cartconvert.PolarCoord, error) {
+	return RDToWGS84LatLong(coord)
+}
+
+// Representation of an RD coordinate with precision decimal places of x and y, see cartconvert.FormatFixed
+func (coord *RDCoord) Format(precision int) string {
+	return cartconvert.FormatFixed(coord.X, precision) + " " + cartconvert.FormatFixed(coord.Y, precision)
+}
+
+// Returns a copy of the RD coordinate with x and y snapped to the nearest multiple of spacingMeters, see
+// cartconvert.SnapToGrid
+func (coord *RDCoord) Snap(spacingMeters float64) *RDCoord {
+	snapped := *coord
+	snapped.X, snapped.Y = cartconvert.SnapToGrid(coord.X, spacingMeters), cartconvert.SnapToGrid(coord.Y, spacingMeters)
+	return &snapped
+}
+
+// Implements json.Marshaler, writing the coordinates in fixed-point notation
+func (coord *RDCoord) MarshalJSON() ([]byte, error) {
+	return cartconvert.MarshalFixedJSON(coord)
+}
+
+// Implements xml.Marshaler, writing the coordinates in fixed-point notation
+func (coord *RDCoord) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return cartconvert.MarshalFixedXML(e, start, coord)
+}
+
+// Validates that the RD coordinate lies within Extent, the territory of the Netherlands. Returns the error of
+// RDToWGS84LatLong, if x and y lie outside of the domain of the polynomials, or a cartconvert.ExtentError, if
+// they transform to a location outside of Extent.
+func (coord *RDCoord) Valid() error {
+	gc, err := RDToWGS84LatLong(coord)
+	if err != nil {
+		return err
+	}
+	return Extent.Validate(gc, "RD", coord.String())
+}
+
+// EPSG code of the Rijksdriehoeksmeting, Amersfoort / RD New. The double stereographic projection of the RD is
+// not implemented by cartconvert.CRS, so the system is not registered.
+const EPSG = 28992
+
+// Returns the EPSG code of the Rijksdriehoeksmeting
+func (coord *RDCoord) EPSG() int {
+	return EPSG
+}
+
+// Parses a string representation of an RD coordinate into an RD coordinate struct. The literal is given as
+// x and y in meters separated by blanks, like
+//
+//	155000 463000
+//	121687.25 487484.75
+//
+// The reference ellipsoid of an RD coordinate will always be set to the Bessel ellipsoid.
+//
+// returns cartconvert.ErrSyntax if the literal doesn't consist of x and y
+// returns cartconvert.ErrRange if the coordinate lies outside of RDMinX to RDMaxX or RDMinY to RDMaxY
+func ARDToStruct(rdcoord string) (*RDCoord, error) {
+
+	fields := strings.Fields(rdcoord)
+	if len(fields) != 2 {
+		return nil, cartconvert.ErrSyntax
+	}
+
+	x, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return nil, err
+	}
+	y, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = cartconvert.CheckFinite(x, y); err != nil {
+		return nil, err
+	}
+	if x < RDMinX || x > RDMaxX || y < RDMinY || y > RDMaxY {
+		return nil, cartconvert.ErrRange
+	}
+	return NewRDCoord(x, y, 0), nil
+}
+
+// Transform an RD coordinate value to a WGS84 based latitude and longitude coordinate by the approximating
+// polynomials. The height above sea level RelHeight is not converted. Function returns cartconvert.ErrRange,
+// if the coordinate lies outside of RDMinX to RDMaxX or RDMinY to RDMaxY, where the polynomials diverge.
+func RDToWGS84LatLong(coord *RDCoord) (*cartconvert.PolarCoord, error) {
+
+	if err := cartconvert.CheckFinite(coord.X, coord.Y); err != nil {
+		return nil, err
+	}
+	if coord.X < RDMinX || coord.X > RDMaxX || coord.Y < RDMinY || coord.Y > RDMaxY {
+		return nil, cartconvert.ErrRange
+	}
+
+	a := (coord.X - rdX0) / 100000
+	b := (coord.Y - rdY0) / 100000
+
+	return &cartconvert.PolarCoord{
+		Latitude:  rdLat0 + polynomial(rdToLat, a, b)/3600,
+		Longitude: rdLong0 + polynomial(rdToLong, a, b)/3600,
+		El:        cartconvert.WGS84Ellipsoid}, nil
+}
+
+// Transform a WGS84 latitude / longitude coordinate into an RD coordinate by the approximating polynomials.
+// The height of gc is not converted. Function returns cartconvert.ErrRange, if gc lies outside of Extent,
+// where the polynomials diverge.
+func WGS84LatLongToRD(gc *cartconvert.PolarCoord) (*RDCoord, error) {
+
+	if err := gc.CheckFinite(); err != nil {
+		return nil, err
+	}
+	if !Extent.Contains(gc) {
+		return nil, cartconvert.ErrRange
+	}
+
+	a := (gc.Latitude - rdLat0) * 0.36
+	b := (gc.Longitude - rdLong0) * 0.36
+
+	return NewRDCoord(rdX0+polynomial(latLongToX, a, b), rdY0+polynomial(latLongToY, a, b), 0), nil
+}
+
+func NewRDCoord(X, Y, RelHeight float64) *RDCoord {
+	return &RDCoord{X: X, Y: Y, RelHeight: RelHeight, El: cartconvert.Bessel1841Ellipsoid}
+}
+
+// Returns reference points of the Rijksdriehoeksmeting, for tests and documentation: the origin of the grid at the
+// church tower of Amersfoort, as published with RDNAPTRANS.
+func Examples() []cartconvert.Example {
+	return []cartconvert.Example{
+		{Name: "Onze Lieve Vrouwetoren, Amersfoort", Input: "155000 463000", LatLong: &cartconvert.PolarCoord{Latitude: 52.15517440, Longitude: 5.38720621, El: cartconvert.WGS84Ellipsoid}, Tolerance: 0.5},
+	}
+}
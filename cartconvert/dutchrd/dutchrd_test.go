@@ -0,0 +1,198 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// Automated tests for the cartconvert/dutchrd package
+package dutchrd
+
+import (
+	"fmt"
+	"github.com/the42/cartconvert/cartconvert"
+	"math"
+	"testing"
+)
+
+// ## ARDToStruct
+type aRDToStructTest struct {
+	in  string
+	out *RDCoord
+	err error
+}
+
+var aRDToStructTests = []aRDToStructTest{
+	{"155000 463000", NewRDCoord(155000, 463000, 0), nil},
+	{" 121687.25  487484.75 ", NewRDCoord(121687.25, 487484.75, 0), nil},
+	{"-7000 289000", NewRDCoord(-7000, 289000, 0), nil},
+	{"155000", nil, cartconvert.ErrSyntax},
+	{"155000 463000 12", nil, cartconvert.ErrSyntax},
+	{"300001 463000", nil, cartconvert.ErrRange},
+	{"155000 288999", nil, cartconvert.ErrRange},
+}
+
+func rdequal(rd1, rd2 *RDCoord) bool {
+	p1 := fmt.Sprintf("%.2f %.2f", rd1.X, rd1.Y)
+	p2 := fmt.Sprintf("%.2f %.2f", rd2.X, rd2.Y)
+
+	return p1 == p2
+}
+
+func TestARDToStruct(t *testing.T) {
+	for index, test := range aRDToStructTests {
+		out, err := ARDToStruct(test.in)
+
+		if err != test.err {
+			t.Errorf("ARDToStruct [%d]: expected error %v, got %v", index, test.err, err)
+			continue
+		}
+		if test.out != nil && !rdequal(test.out, out) {
+			t.Errorf("ARDToStruct [%d]: expected %v, got %v", index, test.out, out)
+		}
+	}
+
+	if _, err := ARDToStruct("x155000 463000"); err == nil {
+		t.Error("ARDToStruct: expected error on malformed x")
+	}
+}
+
+// ## String
+func TestRDCoordString(t *testing.T) {
+	if s := NewRDCoord(121687.4, 487484.6, 0).String(); s != "121687 487485" {
+		t.Errorf("RDCoord.String: expected 121687 487485, got %s", s)
+	}
+}
+
+// ## RDToWGS84LatLong
+type rDTest struct {
+	rd *RDCoord
+	gc *cartconvert.PolarCoord
+}
+
+var rDTests = []rDTest{
+	// the origin, the church tower of Amersfoort
+	{NewRDCoord(155000, 463000, 0), &cartconvert.PolarCoord{Latitude: 52.15517440, Longitude: 5.38720621}},
+	// Amsterdam, Dam
+	{NewRDCoord(121687, 487484, 0), &cartconvert.PolarCoord{Latitude: 52.37422027, Longitude: 4.89801257}},
+	// Utrecht, Dom tower
+	{NewRDCoord(136013, 455953, 0), &cartconvert.PolarCoord{Latitude: 52.09151084, Longitude: 5.11015158}},
+	{NewRDCoord(280000, 620000, 0), &cartconvert.PolarCoord{Latitude: 53.55130055, Longitude: 7.27322500}},
+}
+
+func TestRDToWGS84LatLong(t *testing.T) {
+	for index, test := range rDTests {
+		out, err := RDToWGS84LatLong(test.rd)
+		if err != nil {
+			t.Errorf("RDToWGS84LatLong [%d]: %s", index, err)
+			continue
+		}
+		if math.Abs(out.Latitude-test.gc.Latitude) > 1e-8 || math.Abs(out.Longitude-test.gc.Longitude) > 1e-8 {
+			t.Errorf("RDToWGS84LatLong [%d]: expected %.8f %.8f, got %.8f %.8f", index, test.gc.Latitude, test.gc.Longitude, out.Latitude, out.Longitude)
+		}
+		if out.El != cartconvert.WGS84Ellipsoid {
+			t.Errorf("RDToWGS84LatLong [%d]: expected the WGS84 ellipsoid, got %s", index, out.El.CommonName)
+		}
+	}
+
+	if _, err := RDToWGS84LatLong(NewRDCoord(155000, 700000, 0)); err != cartconvert.ErrRange {
+		t.Errorf("RDToWGS84LatLong: expected ErrRange outside of the RD range, got %v", err)
+	}
+}
+
+// ## WGS84LatLongToRD
+func TestWGS84LatLongToRD(t *testing.T) {
+	for index, test := range rDTests {
+		out, err := WGS84LatLongToRD(test.gc)
+		if err != nil {
+			t.Errorf("WGS84LatLongToRD [%d]: %s", index, err)
+			continue
+		}
+		// the polynomials are no exact inverses, but agree to the millimeter
+		if math.Hypot(out.X-test.rd.X, out.Y-test.rd.Y) > 0.01 {
+			t.Errorf("WGS84LatLongToRD [%d]: expected %.3f %.3f, got %.3f %.3f", index, test.rd.X, test.rd.Y, out.X, out.Y)
+		}
+	}
+
+	if _, err := WGS84LatLongToRD(&cartconvert.PolarCoord{Latitude: 48.2, Longitude: 16.37}); err != cartconvert.ErrRange {
+		t.Errorf("WGS84LatLongToRD: expected ErrRange outside of the Netherlands, got %v", err)
+	}
+}
+
+// ## RDRoundTrip
+func TestRDRoundTrip(t *testing.T) {
+	for lat := Extent.MinLat; lat <= Extent.MaxLat; lat += 0.1 {
+		for long := Extent.MinLong; long <= Extent.MaxLong; long += 0.1 {
+			gc := &cartconvert.PolarCoord{Latitude: lat, Longitude: long}
+			rd, err := WGS84LatLongToRD(gc)
+			if err != nil {
+				t.Fatalf("RDRoundTrip: %.1f %.1f: %s", lat, long, err)
+			}
+			if rd.X < RDMinX || rd.X > RDMaxX || rd.Y < RDMinY || rd.Y > RDMaxY {
+				continue
+			}
+
+			back, _ := RDToWGS84LatLong(rd)
+			rdback, err := WGS84LatLongToRD(back)
+			if err != nil {
+				// back lies just beyond the border of Extent
+				continue
+			}
+			if d := math.Hypot(rdback.X-rd.X, rdback.Y-rd.Y); d > 0.01 {
+				t.Errorf("RDRoundTrip: %.1f %.1f: expected a round trip within 1 cm, got %.3f m", lat, long, d)
+			}
+		}
+	}
+}
+
+type validTest struct {
+	in    *RDCoord
+	valid bool
+}
+
+var validTests = []validTest{
+	{NewRDCoord(155000, 463000, 0), true},
+	{NewRDCoord(121687.25, 487484.75, 0), true},
+	// the English Channel, within the domain of the polynomials
+	{NewRDCoord(-7000, 289000, 0), false},
+}
+
+func TestValid(t *testing.T) {
+	for index, test := range validTests {
+		err := test.in.Valid()
+		if _, ok := err.(cartconvert.ExtentError); (err == nil) != test.valid || (err != nil && !ok) {
+			t.Errorf("Valid [%d]: expected valid %t for %s, got %v", index, test.valid, test.in, err)
+		}
+	}
+
+	if err := NewRDCoord(400000, 463000, 0).Valid(); err != cartconvert.ErrRange {
+		t.Errorf("Valid: expected ErrRange outside the domain of the polynomials, got %v", err)
+	}
+}
+
+// ## EPSG
+func TestEPSG(t *testing.T) {
+	if out := NewRDCoord(155000, 463000, 0).EPSG(); out != 28992 {
+		t.Errorf("EPSG: expected 28992, got %d", out)
+	}
+}
+
+// ## NaN and infinity
+func TestNonFinite(t *testing.T) {
+	for _, value := range []float64{math.NaN(), math.Inf(1)} {
+		if _, err := ARDToStruct(fmt.Sprintf("%v 463000", value)); err != cartconvert.ErrNotFinite {
+			t.Errorf("ARDToStruct (%v): expected ErrNotFinite, got %v", value, err)
+		}
+		if _, err := RDToWGS84LatLong(NewRDCoord(155000, value, 0)); err != cartconvert.ErrNotFinite {
+			t.Errorf("RDToWGS84LatLong (%v): expected ErrNotFinite, got %v", value, err)
+		}
+		if _, err := WGS84LatLongToRD(&cartconvert.PolarCoord{Latitude: 52.15, Longitude: value}); err != cartconvert.ErrNotFinite {
+			t.Errorf("WGS84LatLongToRD (%v): expected ErrNotFinite, got %v", value, err)
+		}
+	}
+}
+
+// ## Snap
+func TestSnap(t *testing.T) {
+	out := (&RDCoord{X: 155049.99, Y: 463150, El: cartconvert.Bessel1841Ellipsoid}).Snap(100)
+	if out.X != 155000 || out.Y != 463200 || out.El != cartconvert.Bessel1841Ellipsoid {
+		t.Errorf("RDCoord.Snap: expected 155000 463200, got %s", out)
+	}
+}
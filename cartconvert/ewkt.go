@@ -0,0 +1,83 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// This file provides the extended well-known text and binary representation of points of PostGIS.
+package cartconvert
+
+import (
+	"encoding/binary"
+	"math"
+	"strconv"
+)
+
+// ## EWKT and EWKB
+
+// Flag of the geometry type of EWKB, set if the geometry is followed by its SRID
+const ewkbSRIDFlag = 0x20000000
+
+// Geometry type of a point in WKB and EWKB
+const wkbPoint = 1
+
+// The quiet NaN by which the coordinates of an empty point are written in WKB and EWKB
+const wkbEmptyBits = 0x7FF8000000000000
+
+// Returns the point x, y of the coordinate reference system denoted by the EPSG code srid as EWKT, the extended
+// well-known text of PostGIS, like SRID=4326;POINT(16.3 48.2). x and y are the easting and northing of projected
+// coordinates, like EWKT(utm.Easting, utm.Northing, utm.EPSG()), see LatLongToEWKT for geographic coordinates.
+// The prefix of the SRID is left out for srid 0, the unknown coordinate reference system. A point with a NaN
+// coordinate is written as POINT EMPTY.
+func EWKT(x, y float64, srid int) string {
+
+	var ewkt string
+	if srid != 0 {
+		ewkt = "SRID=" + strconv.Itoa(srid) + ";"
+	}
+	if math.IsNaN(x) || math.IsNaN(y) {
+		return ewkt + "POINT EMPTY"
+	}
+	return ewkt + "POINT(" + strconv.FormatFloat(x, 'f', -1, 64) + " " + strconv.FormatFloat(y, 'f', -1, 64) + ")"
+}
+
+// Returns the point x, y of the coordinate reference system denoted by the EPSG code srid as little endian EWKB,
+// the extended well-known binary of PostGIS. Like EWKT, the SRID is left out for srid 0. The layout is
+//
+//	offset   size     content
+//	0        1        byte order, 1 for little endian
+//	1        4        geometry type, 1 for a point, or'ed with 0x20000000 if the SRID follows
+//	5        4        SRID, if not 0
+//	5 or 9   8        x as IEEE 754 float64
+//	13 or 17 8        y as IEEE 754 float64
+//
+// A point with a NaN coordinate is written as the empty point, both coordinates NaN. The hexadecimal encoding of
+// the EWKB is accepted as text input of a geometry column by PostGIS.
+func EWKB(x, y float64, srid int) []byte {
+
+	ewkb := make([]byte, 0, 25)
+	ewkb = append(ewkb, 1)
+	if srid != 0 {
+		ewkb = binary.LittleEndian.AppendUint32(ewkb, wkbPoint|ewkbSRIDFlag)
+		ewkb = binary.LittleEndian.AppendUint32(ewkb, uint32(srid))
+	} else {
+		ewkb = binary.LittleEndian.AppendUint32(ewkb, wkbPoint)
+	}
+	if math.IsNaN(x) || math.IsNaN(y) {
+		ewkb = binary.LittleEndian.AppendUint64(ewkb, wkbEmptyBits)
+		return binary.LittleEndian.AppendUint64(ewkb, wkbEmptyBits)
+	}
+	ewkb = binary.LittleEndian.AppendUint64(ewkb, math.Float64bits(x))
+	return binary.LittleEndian.AppendUint64(ewkb, math.Float64bits(y))
+}
+
+// Returns the geographic coordinate pc of the coordinate reference system denoted by the EPSG code srid, like 4326
+// for WGS84, as EWKT. Following PostGIS, the longitude is x and written first, regardless of the axis order of the
+// coordinate reference system.
+func LatLongToEWKT(pc *PolarCoord, srid int) string {
+	return EWKT(pc.Longitude, pc.Latitude, srid)
+}
+
+// Returns the geographic coordinate pc of the coordinate reference system denoted by the EPSG code srid as EWKB,
+// the longitude as x like LatLongToEWKT.
+func LatLongToEWKB(pc *PolarCoord, srid int) []byte {
+	return EWKB(pc.Longitude, pc.Latitude, srid)
+}
@@ -0,0 +1,52 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// Automated tests for the EWKT and EWKB representation of the cartconvert package
+package cartconvert
+
+import (
+	"encoding/hex"
+	"math"
+	"strings"
+	"testing"
+)
+
+// ## EWKT and EWKB
+type ewktTest struct {
+	x, y float64
+	srid int
+	ewkt string
+	ewkb string // hexadecimal, as written by ST_AsEWKB of PostGIS
+}
+
+var ewktTests = []ewktTest{
+	{1, 2, 4326, "SRID=4326;POINT(1 2)", "0101000020E6100000000000000000F03F0000000000000040"},
+	{16.3, 48.2, 4326, "SRID=4326;POINT(16.3 48.2)", "0101000020E6100000CDCCCCCCCC4C30409A99999999194840"},
+	{425351.5, 5268987.25, 32633, "SRID=32633;POINT(425351.5 5268987.25)", "0101000020797F0000000000001EF61941000000D07E195441"},
+	{-1.5, 0, 0, "POINT(-1.5 0)", "0101000000000000000000F8BF0000000000000000"},
+	{math.NaN(), math.NaN(), 4326, "SRID=4326;POINT EMPTY", "0101000020E6100000000000000000F87F000000000000F87F"},
+	{1, math.NaN(), 0, "POINT EMPTY", "0101000000000000000000F87F000000000000F87F"},
+}
+
+func TestEWKT(t *testing.T) {
+	for _, test := range ewktTests {
+		if ewkt := EWKT(test.x, test.y, test.srid); ewkt != test.ewkt {
+			t.Errorf("EWKT (%f, %f, %d): expected %s, got %s", test.x, test.y, test.srid, test.ewkt, ewkt)
+		}
+		if ewkb := strings.ToUpper(hex.EncodeToString(EWKB(test.x, test.y, test.srid))); ewkb != test.ewkb {
+			t.Errorf("EWKB (%f, %f, %d): expected %s, got %s", test.x, test.y, test.srid, test.ewkb, ewkb)
+		}
+	}
+}
+
+func TestLatLongToEWKT(t *testing.T) {
+	pc := &PolarCoord{Latitude: 48.2, Longitude: 16.3, El: WGS84Ellipsoid}
+
+	if ewkt := LatLongToEWKT(pc, 4326); ewkt != "SRID=4326;POINT(16.3 48.2)" {
+		t.Errorf("LatLongToEWKT: expected SRID=4326;POINT(16.3 48.2), got %s", ewkt)
+	}
+	if ewkb := strings.ToUpper(hex.EncodeToString(LatLongToEWKB(pc, 4326))); ewkb != ewktTests[1].ewkb {
+		t.Errorf("LatLongToEWKB: expected %s, got %s", ewktTests[1].ewkb, ewkb)
+	}
+}
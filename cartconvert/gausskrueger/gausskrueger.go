@@ -0,0 +1,302 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// This package provides functions to deal with conversion and transformations of Gauss-Krüger
+// coordinates of the German datum DHDN (Deutsches Hauptdreiecksnetz), as found in German cadastral data.
+//
+// Gauss-Krüger coordinates are transverse Mercator coordinates on the Bessel ellipsoid in zones of 3° of
+// longitude, with the central meridians at multiples of 3°. The leading digit of the easting (Rechtswert)
+// gives the zone, the central meridian being three times the zone number, followed by the easting relative
+// to a false easting of 500000 m on the central meridian. Germany is covered by the zones 2 to 5.
+//
+// The conversion between WGS84 and DHDN uses a helmert transformation with an accuracy of about 3 m.
+// For higher accuracy, the transformation grids of the German states (BeTA2007) have to be applied.
+//
+// For further info see http://de.wikipedia.org/wiki/Gauß-Krüger-Koordinatensystem
+package gausskrueger
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/the42/cartconvert/cartconvert"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Geographic extent of DHDN, the territory of Germany
+var Extent = &cartconvert.LatLongExtent{MinLat: 47.2, MaxLat: 55.1, MinLong: 5.8, MaxLong: 15.1}
+
+// Zone number which lets the zone be determined from the longitude of the coordinate
+const GKZoneDet = 0
+
+// The range of zone numbers. Zone 119 has its central meridian at 357°, that is 3° W.
+const (
+	GKMinZone = 1
+	GKMaxZone = 119
+)
+
+// A Gauss-Krüger coordinate is specified by zone, easting (Rechtswert) and northing (Hochwert).
+// Easting is the easting within the zone, including the false easting of 500000 m but not the zone prefix.
+type GKCoord struct {
+	Zone      uint                   `json:"zone" xml:"zone"`
+	Easting   float64                `json:"easting" xml:"easting"`
+	Northing  float64                `json:"northing" xml:"northing"`
+	RelHeight float64                `json:"relheight" xml:"relheight"`
+	El        *cartconvert.Ellipsoid `json:"ellipsoid,omitempty" xml:"ellipsoid,omitempty"`
+	// Approximate positional error in meters introduced by the datum shift of the conversion from WGS84, the
+	// accuracy of the helmert parameter set used. Zero for coordinates not converted from WGS84.
+	Accuracy float64 `json:"accuracy,omitempty" xml:"accuracy,omitempty"`
+}
+
+// Canonical representation of a Gauss-Krüger coordinate, the easting prefixed by the zone and the northing,
+// each to the meter, like "3477000 5530000"
+func (coord *GKCoord) String() string {
+	return fmt.Sprintf("%.0f %.0f", coord.PrefixedEasting(), coord.Northing)
+}
+
+// Implements cartconvert.Coordinate, the system of a Gauss-Krüger coordinate is "gk"
+func (coord *GKCoord) System() string {
+	return "gk"
+}
+
+// Implements cartconvert.Coordinate, see GKToWGS84LatLong
+func (coord *GKCoord) ToWGS84() (*cartconvert.PolarCoord, error) {
+	return GKToWGS84LatLong(coord)
+}
+
+// Representation of a Gauss-Krüger coordinate with precision decimal places of the prefixed easting and the northing,
+// see cartconvert.FormatFixed
+func (coord *GKCoord) Format(precision int) string {
+	return cartconvert.FormatFixed(coord.PrefixedEasting(), precision) + " " + cartconvert.FormatFixed(coord.Northing, precision)
+}
+
+// Returns a copy of the Gauss-Krüger coordinate with easting and northing snapped to the nearest multiple of
+// spacingMeters, see cartconvert.SnapToGrid. The zone is kept.
+func (coord *GKCoord) Snap(spacingMeters float64) *GKCoord {
+	snapped := *coord
+	snapped.Easting, snapped.Northing = cartconvert.SnapToGrid(coord.Easting, spacingMeters), cartconvert.SnapToGrid(coord.Northing, spacingMeters)
+	return &snapped
+}
+
+// Returns the easting prefixed by the zone number, as given in Gauss-Krüger coordinate literals
+func (coord *GKCoord) PrefixedEasting() float64 {
+	return float64(coord.Zone)*1000000 + coord.Easting
+}
+
+// Implements json.Marshaler, writing the coordinates in fixed-point notation
+func (coord *GKCoord) MarshalJSON() ([]byte, error) {
+	return cartconvert.MarshalFixedJSON(coord)
+}
+
+// Implements xml.Marshaler, writing the coordinates in fixed-point notation
+func (coord *GKCoord) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return cartconvert.MarshalFixedXML(e, start, coord)
+}
+
+// Validates that the Gauss-Krüger coordinate lies within Extent, the territory of Germany. Returns the error of
+// GKToWGS84LatLong, if the zone is invalid, or a cartconvert.ExtentError, if easting and northing transform to a
+// location outside of Extent.
+func (coord *GKCoord) Valid() error {
+	gc, err := GKToWGS84LatLong(coord)
+	if err != nil {
+		return err
+	}
+	return Extent.Validate(gc, "Gauss-Krüger", coord.String())
+}
+
+// EPSG codes of the zones 2 to 5 of DHDN, DHDN / 3-degree Gauss-Kruger zone 2 to 5, the zones covering Germany
+const (
+	EPSGZone2 = 31466
+	EPSGZone5 = 31469
+)
+
+// Returns the EPSG code of the zone of the Gauss-Krüger coordinate, 0 if the zone is not one of Germany
+func (coord *GKCoord) EPSG() int {
+	if coord.Zone < 2 || coord.Zone > 5 {
+		return 0
+	}
+	return EPSGZone2 + int(coord.Zone) - 2
+}
+
+// The zones of Germany are registered as coordinate reference systems of DHDN. As defined by EPSG, the false
+// easting includes the zone number, which GKCoord.Easting doesn't.
+func init() {
+	for zone := 2; zone <= 5; zone++ {
+		crs := &cartconvert.CRS{EPSG: EPSGZone2 + zone - 2, Name: fmt.Sprintf("DHDN / 3-degree Gauss-Kruger zone %d", zone),
+			Datum: cartconvert.DatumDHDN, El: cartconvert.Bessel1841Ellipsoid, Method: cartconvert.MethodTransverseMercator,
+			LongO: float64(3 * zone), Scale: 1, FalseEasting: float64(zone)*1000000 + 500000}
+		if err := cartconvert.RegisterCRS(crs); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Parses a string representation of a Gauss-Krüger coordinate into a Gauss-Krüger coordinate struct.
+// The literal is given as easting and northing in meters separated by blanks, like
+//
+//	3477000 5530000
+//	3477000.25 5530000.75
+//
+// with the easting prefixed by the zone number. The zone is derived from the leading digits of the easting,
+// which has 7 digits for the zones 1 to 9 and 8 digits from zone 10 on.
+//
+// The reference ellipsoid of a Gauss-Krüger coordinate will always be set to the Bessel ellipsoid.
+//
+// returns cartconvert.ErrSyntax if the literal doesn't consist of easting and northing
+// returns cartconvert.ErrRange if the zone is not within GKMinZone to GKMaxZone
+func AGKToStruct(gkcoord string) (*GKCoord, error) {
+
+	fields := strings.Fields(gkcoord)
+	if len(fields) != 2 {
+		return nil, cartconvert.ErrSyntax
+	}
+
+	easting, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return nil, err
+	}
+	northing, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = cartconvert.CheckFinite(easting, northing); err != nil {
+		return nil, err
+	}
+
+	zone := math.Floor(easting / 1000000)
+	if zone < GKMinZone || zone > GKMaxZone {
+		return nil, cartconvert.ErrRange
+	}
+
+	return NewGKCoord(uint(zone), easting-zone*1000000, northing, 0), nil
+}
+
+// Returns the longitude of the central meridian of a zone. Returns cartconvert.ErrRange,
+// if the zone is not within GKMinZone to GKMaxZone.
+func centralMeridian(zone uint) (float64, error) {
+	if zone < GKMinZone || zone > GKMaxZone {
+		return 0, cartconvert.ErrRange
+	}
+	long0 := 3 * float64(zone)
+	if long0 > 180 {
+		long0 -= 360
+	}
+	return long0, nil
+}
+
+// Returns the helmert parameter set used for the datum shift between WGS84 and DHDN. If set is nil,
+// the default parameter set for WGS84 to DHDN is returned. Returns cartconvert.ErrRange if the
+// parameter set does not transform from WGS84 to DHDN.
+func helmertParameterSet(set *cartconvert.HelmertParameterSet) (*cartconvert.HelmertParameterSet, error) {
+	if set == nil {
+		return cartconvert.DefaultHelmertParameterSet(cartconvert.DatumWGS84, cartconvert.DatumDHDN)
+	}
+	if set.From != cartconvert.DatumWGS84 || set.To != cartconvert.DatumDHDN {
+		return nil, cartconvert.ErrRange
+	}
+	return set, nil
+}
+
+// Transform a Gauss-Krüger coordinate value to a WGS84 based latitude and longitude coordinate. Function returns
+// cartconvert.ErrRange, if the zone of the Gauss-Krüger coordinate is invalid.
+func GKToWGS84LatLong(coord *GKCoord) (*cartconvert.PolarCoord, error) {
+	return GKToWGS84LatLongHelmert(coord, nil)
+}
+
+// Transform a Gauss-Krüger coordinate value to a WGS84 based latitude and longitude coordinate using the helmert
+// parameter set for the datum shift. If set is nil, the default parameter set from WGS84 to DHDN is used.
+// Function returns cartconvert.ErrRange, if the zone of the Gauss-Krüger coordinate is invalid or
+// the parameter set does not transform from WGS84 to DHDN.
+func GKToWGS84LatLongHelmert(coord *GKCoord, set *cartconvert.HelmertParameterSet) (*cartconvert.PolarCoord, error) {
+
+	hp, err := helmertParameterSet(set)
+	if err != nil {
+		return nil, err
+	}
+
+	long0, err := centralMeridian(coord.Zone)
+	if err != nil {
+		return nil, err
+	}
+	if err = cartconvert.CheckFinite(coord.Easting, coord.Northing); err != nil {
+		return nil, err
+	}
+
+	el := coord.El
+	if el == nil {
+		el = cartconvert.Bessel1841Ellipsoid
+	}
+
+	gc := cartconvert.InverseTransverseMercator(
+		&cartconvert.GeoPoint{Y: coord.Northing, X: coord.Easting, El: el},
+		0,
+		long0,
+		1,
+		500000,
+		0)
+
+	cart := cartconvert.PolarToCartesian(gc)
+	pt := hp.InverseTransform(&cartconvert.Point3D{X: cart.X, Y: cart.Y, Z: cart.Z})
+
+	return cartconvert.CartesianToPolar(&cartconvert.CartPoint{X: pt.X, Y: pt.Y, Z: pt.Z, El: cartconvert.WGS84Ellipsoid}), nil
+}
+
+// Transform a latitude / longitude coordinate datum into a Gauss-Krüger coordinate of the zone. If zone is GKZoneDet,
+// the zone of the nearest central meridian is determined. Function returns cartconvert.ErrRange, if the zone is invalid.
+//
+// Important: The reference ellipsoid of the originating coordinate system will be assumed
+// to be the WGS84Ellipsoid and will be set thereupon, regardless of the actually set reference ellipsoid.
+func WGS84LatLongToGK(gc *cartconvert.PolarCoord, zone uint) (*GKCoord, error) {
+	return WGS84LatLongToGKHelmert(gc, zone, nil)
+}
+
+// Transform a latitude / longitude coordinate datum into a Gauss-Krüger coordinate of the zone using the helmert
+// parameter set for the datum shift. If set is nil, the default parameter set from WGS84 to DHDN is used.
+// If zone is GKZoneDet, the zone of the nearest central meridian is determined. Function returns
+// cartconvert.ErrRange, if the zone is invalid or the parameter set does not transform from WGS84 to DHDN.
+func WGS84LatLongToGKHelmert(gc *cartconvert.PolarCoord, zone uint, set *cartconvert.HelmertParameterSet) (*GKCoord, error) {
+
+	hp, err := helmertParameterSet(set)
+	if err != nil {
+		return nil, err
+	}
+	if err = gc.CheckFinite(); err != nil {
+		return nil, err
+	}
+
+	// This sets the Ellipsoid to WGS84, regardless of the actual value set
+	gc.El = cartconvert.WGS84Ellipsoid
+
+	cart := cartconvert.PolarToCartesian(gc)
+	pt := hp.Transform(&cartconvert.Point3D{X: cart.X, Y: cart.Y, Z: cart.Z})
+	polar := cartconvert.CartesianToPolar(&cartconvert.CartPoint{X: pt.X, Y: pt.Y, Z: pt.Z, El: cartconvert.Bessel1841Ellipsoid})
+
+	if zone == GKZoneDet {
+		long := math.Mod(polar.Longitude+360, 360)
+		zone = uint(math.Floor(long/3+0.5)) % 120
+	}
+
+	long0, err := centralMeridian(zone)
+	if err != nil {
+		return nil, err
+	}
+
+	gp := cartconvert.DirectTransverseMercator(
+		polar,
+		0,
+		long0,
+		1,
+		500000,
+		0)
+
+	coord := NewGKCoord(zone, gp.X, gp.Y, 0)
+	coord.Accuracy = hp.Accuracy
+	return coord, nil
+}
+
+func NewGKCoord(Zone uint, Easting, Northing, RelHeight float64) *GKCoord {
+	return &GKCoord{Zone: Zone, Easting: Easting, Northing: Northing, RelHeight: RelHeight, El: cartconvert.Bessel1841Ellipsoid}
+}
@@ -0,0 +1,280 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// Automated tests for the cartconvert/gausskrueger package
+package gausskrueger
+
+import (
+	"fmt"
+	"github.com/the42/cartconvert/cartconvert"
+	"math"
+	"testing"
+)
+
+// ## AGKToStruct
+type aGKToStructTest struct {
+	in  string
+	out *GKCoord
+	err error
+}
+
+var aGKToStructTests = []aGKToStructTest{
+	{"3477000 5530000", NewGKCoord(3, 477000, 5530000, 0), nil},
+	{" 4593627.4  5821242.6 ", NewGKCoord(4, 593627.4, 5821242.6, 0), nil},
+	{"12500000 5000000", NewGKCoord(12, 500000, 5000000, 0), nil},
+	{"3477000", nil, cartconvert.ErrSyntax},
+	{"3477000 5530000 12", nil, cartconvert.ErrSyntax},
+	{"477000 5530000", nil, cartconvert.ErrRange},
+	{"120500000 5530000", nil, cartconvert.ErrRange},
+}
+
+func gkequal(gk1, gk2 *GKCoord) bool {
+	p1 := fmt.Sprintf("%d %.1f %.1f", gk1.Zone, gk1.Easting, gk1.Northing)
+	p2 := fmt.Sprintf("%d %.1f %.1f", gk2.Zone, gk2.Easting, gk2.Northing)
+
+	return p1 == p2
+}
+
+func TestAGKToStruct(t *testing.T) {
+	for index, test := range aGKToStructTests {
+		out, err := AGKToStruct(test.in)
+
+		if err != test.err {
+			t.Errorf("AGKToStruct [%d]: expected error %v, got %v", index, test.err, err)
+			continue
+		}
+		if test.out != nil && !gkequal(test.out, out) {
+			t.Errorf("AGKToStruct [%d]: expected %v, got %v", index, test.out, out)
+		}
+	}
+
+	if _, err := AGKToStruct("R3477000 5530000"); err == nil {
+		t.Error("AGKToStruct: expected error on malformed easting")
+	}
+}
+
+// ## String
+func TestGKCoordString(t *testing.T) {
+	if s := NewGKCoord(3, 477000.4, 5530000.6, 0).String(); s != "3477000 5530001" {
+		t.Errorf("GKCoord.String: expected 3477000 5530001, got %s", s)
+	}
+}
+
+// ## WGS84LatLongToGK
+type wGS84LatLongToGKParam struct {
+	gc   *cartconvert.PolarCoord
+	zone uint
+}
+
+type wGS84LatLongToGKTest struct {
+	in  wGS84LatLongToGKParam
+	out *GKCoord
+}
+
+var wGS84LatLongToGKTests = []wGS84LatLongToGKTest{
+	{ // Brandenburger Tor, Berlin
+		wGS84LatLongToGKParam{
+			gc:   &cartconvert.PolarCoord{Latitude: 52.516275, Longitude: 13.377704},
+			zone: GKZoneDet},
+		NewGKCoord(4, 593627, 5821242, 0),
+	},
+	{ // Marienplatz, München
+		wGS84LatLongToGKParam{
+			gc:   &cartconvert.PolarCoord{Latitude: 48.137154, Longitude: 11.576124},
+			zone: GKZoneDet},
+		NewGKCoord(4, 468560, 5333323, 0),
+	},
+	{ // Marienplatz, München, in the adjacent zone
+		wGS84LatLongToGKParam{
+			gc:   &cartconvert.PolarCoord{Latitude: 48.137154, Longitude: 11.576124},
+			zone: 5},
+		NewGKCoord(5, 245325, 5338904, 0),
+	},
+}
+
+func gkequalmeter(gk1, gk2 *GKCoord) bool {
+	return gk1.String() == gk2.String()
+}
+
+func TestWGS84LatLongToGK(t *testing.T) {
+	for index, test := range wGS84LatLongToGKTests {
+		out, err := WGS84LatLongToGK(test.in.gc, test.in.zone)
+		if err != nil {
+			t.Errorf("WGS84LatLongToGK [%d]: %s", index, err)
+			continue
+		}
+		if !gkequalmeter(test.out, out) {
+			t.Errorf("WGS84LatLongToGK [%d]: expected %s, got %s", index, test.out, out)
+		}
+	}
+
+	if _, err := WGS84LatLongToGK(&cartconvert.PolarCoord{Latitude: 50, Longitude: 9}, 120); err != cartconvert.ErrRange {
+		t.Errorf("WGS84LatLongToGK: expected ErrRange on invalid zone, got %v", err)
+	}
+}
+
+// ## WGS84LatLongToGKHelmert
+func TestWGS84LatLongToGKHelmert(t *testing.T) {
+	set, err := cartconvert.HelmertParameterSetByName("DHDN_7param_EPSG1777")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for index, test := range wGS84LatLongToGKTests {
+		out, err := WGS84LatLongToGKHelmert(test.in.gc, test.in.zone, set)
+		if err != nil {
+			t.Errorf("WGS84LatLongToGKHelmert [%d]: %s", index, err)
+			continue
+		}
+		if !gkequalmeter(test.out, out) {
+			t.Errorf("WGS84LatLongToGKHelmert [%d]: expected %s, got %s", index, test.out, out)
+		}
+		if out.Accuracy != set.Accuracy {
+			t.Errorf("WGS84LatLongToGKHelmert [%d]: expected the accuracy %g of the parameter set, got %g", index, set.Accuracy, out.Accuracy)
+		}
+	}
+
+	mgi, err := cartconvert.DefaultHelmertParameterSet(cartconvert.DatumWGS84, cartconvert.DatumMGI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := WGS84LatLongToGKHelmert(&cartconvert.PolarCoord{Latitude: 50, Longitude: 9}, GKZoneDet, mgi); err != cartconvert.ErrRange {
+		t.Errorf("WGS84LatLongToGKHelmert: expected ErrRange on parameter set of MGI, got %v", err)
+	}
+}
+
+// ## GKToWGS84LatLong
+func latlongequal(pcp1, pcp2 *cartconvert.PolarCoord) bool {
+	pp1s := fmt.Sprintf("%.6f %.6f", pcp1.Latitude, pcp1.Longitude)
+	pp2s := fmt.Sprintf("%.6f %.6f", pcp2.Latitude, pcp2.Longitude)
+
+	return pp1s == pp2s
+}
+
+func TestGKToWGS84LatLong(t *testing.T) {
+	for index, test := range wGS84LatLongToGKTests {
+		in, err := WGS84LatLongToGK(&cartconvert.PolarCoord{Latitude: test.in.gc.Latitude, Longitude: test.in.gc.Longitude}, test.in.zone)
+		if err != nil {
+			t.Errorf("GKToWGS84LatLong [%d]: %s", index, err)
+			continue
+		}
+
+		out, err := GKToWGS84LatLong(in)
+		if err != nil {
+			t.Errorf("GKToWGS84LatLong [%d]: %s", index, err)
+			continue
+		}
+		if !latlongequal(test.in.gc, out) {
+			t.Errorf("GKToWGS84LatLong [%d]: expected %.6f %.6f, got %.6f %.6f", index, test.in.gc.Latitude, test.in.gc.Longitude, out.Latitude, out.Longitude)
+		}
+	}
+
+	if _, err := GKToWGS84LatLong(NewGKCoord(0, 500000, 5000000, 0)); err != cartconvert.ErrRange {
+		t.Errorf("GKToWGS84LatLong: expected ErrRange on invalid zone, got %v", err)
+	}
+}
+
+type validTest struct {
+	in    *GKCoord
+	valid bool
+}
+
+var validTests = []validTest{
+	{NewGKCoord(3, 477000, 5530000, 0), true},
+	{NewGKCoord(4, 593627.4, 5821242.6, 0), true},
+	// the mediterranean sea
+	{NewGKCoord(3, 477000, 4500000, 0), false},
+}
+
+func TestValid(t *testing.T) {
+	for index, test := range validTests {
+		err := test.in.Valid()
+		if _, ok := err.(cartconvert.ExtentError); (err == nil) != test.valid || (err != nil && !ok) {
+			t.Errorf("Valid [%d]: expected valid %t for %s, got %v", index, test.valid, test.in, err)
+		}
+	}
+
+	if err := NewGKCoord(0, 477000, 5530000, 0).Valid(); err != cartconvert.ErrRange {
+		t.Errorf("Valid: expected ErrRange for an invalid zone, got %v", err)
+	}
+}
+
+// ## RoundTrip
+// The positional error in meters of a latitude / longitude coordinate converted into a Gauss-Krüger coordinate and back
+type roundTripTest struct {
+	gc        *cartconvert.PolarCoord
+	threshold float64
+}
+
+// The helmert transformation is inverted exactly, the round trip is limited by the transverse mercator projection
+var roundTripTests = []roundTripTest{
+	{&cartconvert.PolarCoord{Latitude: 50.1109, Longitude: 8.6821, El: cartconvert.WGS84Ellipsoid}, 0.01},
+	{&cartconvert.PolarCoord{Latitude: 52.52, Longitude: 13.405, El: cartconvert.WGS84Ellipsoid}, 0.01},
+	{&cartconvert.PolarCoord{Latitude: 48.137, Longitude: 11.575, El: cartconvert.WGS84Ellipsoid}, 0.01},
+}
+
+func TestRoundTrip(t *testing.T) {
+	for index, test := range roundTripTests {
+		gk, err := WGS84LatLongToGK(test.gc, 0)
+		if err != nil {
+			t.Fatalf("WGS84LatLongToGK [%d]: %s", index, err)
+		}
+		out, err := GKToWGS84LatLong(gk)
+		if err != nil {
+			t.Fatalf("GKToWGS84LatLong [%d]: %s", index, err)
+		}
+
+		if d := cartconvert.Haversine(test.gc, out, 0); d > test.threshold {
+			t.Errorf("RoundTrip [%d]: expected a positional error below %g m, got %.3f m", index, test.threshold, d)
+		}
+	}
+}
+
+// ## EPSG
+func TestEPSG(t *testing.T) {
+	gc := &cartconvert.PolarCoord{Latitude: 50.1109, Longitude: 8.6821, El: cartconvert.WGS84Ellipsoid}
+	for zone := uint(2); zone <= 5; zone++ {
+		gk, err := WGS84LatLongToGK(gc, zone)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the false easting of the registered coordinate reference system includes the zone number
+		crs, err := cartconvert.ByEPSG(gk.EPSG())
+		if err != nil {
+			t.Fatalf("ByEPSG: expected the CRS of zone %d, got %v", zone, err)
+		}
+		out, err := crs.InverseProject(&cartconvert.GeoPoint{X: float64(zone)*1000000 + gk.Easting, Y: gk.Northing})
+		if err != nil || math.Abs(out.Latitude-gc.Latitude) > 0.01 || math.Abs(out.Longitude-gc.Longitude) > 0.01 {
+			t.Errorf("CRS.InverseProject: expected %s of %s near %s, got %v (%v)", crs.Name, gk, gc, out, err)
+		}
+	}
+
+	if out := NewGKCoord(6, 500000, 5000000, 0).EPSG(); out != 0 {
+		t.Errorf("EPSG: expected 0 for a zone beyond Germany, got %d", out)
+	}
+}
+
+// ## NaN and infinity
+func TestNonFinite(t *testing.T) {
+	for _, value := range []float64{math.NaN(), math.Inf(1)} {
+		if _, err := AGKToStruct(fmt.Sprintf("%v 5530000", value)); err != cartconvert.ErrNotFinite {
+			t.Errorf("AGKToStruct (%v): expected ErrNotFinite, got %v", value, err)
+		}
+		if _, err := GKToWGS84LatLong(NewGKCoord(3, 477000, value, 0)); err != cartconvert.ErrNotFinite {
+			t.Errorf("GKToWGS84LatLong (%v): expected ErrNotFinite, got %v", value, err)
+		}
+		if _, err := WGS84LatLongToGK(&cartconvert.PolarCoord{Latitude: value, Longitude: 9}, GKZoneDet); err != cartconvert.ErrNotFinite {
+			t.Errorf("WGS84LatLongToGK (%v): expected ErrNotFinite, got %v", value, err)
+		}
+	}
+}
+
+// ## Snap
+func TestSnap(t *testing.T) {
+	out := (&GKCoord{Zone: 3, Easting: 477050, Northing: 5530049.9}).Snap(100)
+	if out.PrefixedEasting() != 3477000 || out.Northing != 5530000 {
+		t.Errorf("GKCoord.Snap: expected 3477000 5530000, got %s", out)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// This file provides the coarse EGM96 geoid embedded in the package.
+package geoid
+
+import (
+	"math"
+)
+
+// The spacing in degrees of the embedded EGM96 grid
+const egm96Spacing = 5
+
+// Degree and order, up to which the EGM96 geopotential model is evaluated
+const egm96Degree = 8
+
+// Fully normalized coefficients of the EGM96 geopotential model, egm96C[n][m] and egm96S[n][m] of degree n and
+// order m, up to egm96Degree
+var egm96C = [egm96Degree + 1][egm96Degree + 1]float64{
+	2: {-4.84165371736e-04, -1.86987635955e-10, 2.43914352398e-06},
+	3: {9.57254173792e-07, 2.03046201047e-06, 9.04787894809e-07, 7.21321757121e-07},
+	4: {5.39873863789e-07, -5.36157389388e-07, 3.50694105785e-07, 9.90856766672e-07, -1.88560802735e-07},
+	5: {6.86702913736e-08, -6.29211923042e-08, 6.52078043176e-07, -4.51847152328e-07, -2.95328761175e-07, 1.74811795496e-07},
+	6: {-1.49953927978e-07, -7.59210081892e-08, 4.86488924604e-08, 5.72451611175e-08, -8.60237937191e-08, -2.67166423703e-07,
+		9.47068749610e-09},
+	7: {9.05120844521e-08, 2.80887555776e-07, 3.30407220406e-07, 2.50499579740e-07, -2.74983225033e-07, 1.64169786479e-09,
+		-3.58838070165e-07, 1.50614464985e-09},
+	8: {4.94756003005e-08, 2.31607991248e-08, 8.00143297599e-08, -1.93745255566e-08, -2.44601323074e-07, -2.57364986052e-08,
+		-6.59649644013e-08, 6.72628420473e-08, -1.24091926968e-07},
+}
+
+var egm96S = [egm96Degree + 1][egm96Degree + 1]float64{
+	2: {0, 1.19528012031e-09, -1.40016683654e-06},
+	3: {0, 2.48200415856e-07, -6.19005475177e-07, 1.41434926192e-06},
+	4: {0, -4.73567346518e-07, 6.62671572540e-07, -2.00928369177e-07, 3.08853169333e-07},
+	5: {0, -9.43698073395e-08, -3.23353192540e-07, -2.14955408306e-07, 4.98070550102e-08, -6.69379935180e-07},
+	6: {0, 2.65122593213e-08, -3.73789324523e-07, 8.95201130268e-09, -4.71425573429e-07, -5.36493151500e-07,
+		-2.37382353351e-07},
+	7: {0, 9.51259362590e-08, 9.29969290402e-08, -2.17198608738e-07, -1.24058637483e-07, 1.79467769985e-08,
+		1.51789817739e-07, 2.41146513766e-08},
+	8: {0, 5.90094462177e-08, 6.54140819424e-08, -8.63770188914e-08, 7.00378852051e-08, 8.92384298706e-08,
+		3.09160192866e-07, 7.48811299147e-08, 1.20552993860e-07},
+}
+
+// The even zonal coefficients of the normal gravity field of the WGS84 ellipsoid, which are subtracted from the
+// coefficients of the model to yield the disturbing potential
+var wgs84NormalC = [egm96Degree + 1]float64{2: -0.484166774985e-3, 4: 0.790303733511e-6, 6: -0.168724961151e-8, 8: 0.346052468394e-11}
+
+const (
+	wgs84A  = 6378137.0
+	wgs84F  = 1 / 298.257223563
+	wgs84GM = 3.986004418e14
+	// normal gravity at the equator and Somigliana's constant
+	wgs84GammaE = 9.7803253359
+	wgs84K      = 0.00193185265241
+	// the zero degree undulation of EGM96 with respect to WGS84
+	egm96N0 = -0.53
+)
+
+// normalizedLegendre returns the fully normalized associated Legendre functions p[n][m] of t up to egm96Degree
+func normalizedLegendre(t float64) (p [egm96Degree + 1][egm96Degree + 1]float64) {
+
+	u := math.Sqrt(1 - t*t)
+	p[0][0] = 1
+	p[1][1] = math.Sqrt(3) * u
+	for n := 1; n <= egm96Degree; n++ {
+		fn := float64(n)
+		if n > 1 {
+			p[n][n] = math.Sqrt((2*fn+1)/(2*fn)) * u * p[n-1][n-1]
+		}
+		p[n][n-1] = math.Sqrt(2*fn+1) * t * p[n-1][n-1]
+		for m := 0; m < n-1; m++ {
+			fm := float64(m)
+			a := math.Sqrt((2*fn - 1) * (2*fn + 1) / ((fn - fm) * (fn + fm)))
+			b := math.Sqrt((2*fn + 1) * (fn + fm - 1) * (fn - fm - 1) / ((fn - fm) * (fn + fm) * (2*fn - 3)))
+			p[n][m] = a*t*p[n-1][m] - b*p[n-2][m]
+		}
+	}
+	return
+}
+
+// egm96Undulation evaluates the geoid undulation of the EGM96 geopotential model up to egm96Degree at the
+// latitude and longitude in degrees, by Bruns' formula from the disturbing potential on the WGS84 ellipsoid.
+func egm96Undulation(lat, long float64) float64 {
+
+	phi := lat * math.Pi / 180
+	lambda := long * math.Pi / 180
+
+	e2 := wgs84F * (2 - wgs84F)
+	sinphi := math.Sin(phi)
+	w := math.Sqrt(1 - e2*sinphi*sinphi)
+
+	// geocentric radius and latitude of the point on the ellipsoid
+	nu := wgs84A / w
+	x, z := nu*math.Cos(phi), nu*(1-e2)*sinphi
+	r := math.Hypot(x, z)
+	p := normalizedLegendre(z / r)
+
+	var t float64
+	for n := 2; n <= egm96Degree; n++ {
+		var sum float64
+		for m := 0; m <= n; m++ {
+			c := egm96C[n][m]
+			if m == 0 {
+				c -= wgs84NormalC[n]
+			}
+			fm := float64(m)
+			sum += (c*math.Cos(fm*lambda) + egm96S[n][m]*math.Sin(fm*lambda)) * p[n][m]
+		}
+		t += math.Pow(wgs84A/r, float64(n)) * sum
+	}
+
+	gamma := wgs84GammaE * (1 + wgs84K*sinphi*sinphi) / w
+	return wgs84GM/(r*gamma)*t + egm96N0
+}
+
+// newEGM96Grid samples the EGM96 geopotential model on a global grid of egm96Spacing
+func newEGM96Grid() *Grid {
+
+	rows, cols := 180/egm96Spacing+1, 360/egm96Spacing+1
+	values := make([]float64, 0, rows*cols)
+	for row := 0; row < rows; row++ {
+		lat := float64(90 - row*egm96Spacing)
+		for col := 0; col < cols; col++ {
+			values = append(values, egm96Undulation(lat, float64(col*egm96Spacing)))
+		}
+	}
+
+	grid, err := NewGrid(-90, 90, 0, 360, egm96Spacing, egm96Spacing, values)
+	if err != nil {
+		panic(err)
+	}
+	return grid
+}
@@ -0,0 +1,137 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// This package provides the geoid undulation of WGS84 based coordinates, the height of the geoid above the WGS84
+// ellipsoid, to convert between ellipsoidal heights as measured by GPS and orthometric heights above sea level:
+//
+//	orthometric height = ellipsoidal height - undulation
+//
+// The package embeds a coarse global grid with a spacing of 5°, which is sampled from the coefficients of the EGM96
+// geopotential model up to degree and order 8 and interpolated bilinearly. It is not the EGM96 geoid of the NGA,
+// which evaluates the model up to degree and order 360: The grid reproduces the large-scale shape of the geoid only;
+// compared to the full EGM96 model the undulation deviates by about 10 m on average and by up to some 30 m in the
+// mountains and at the deep sea trenches. Where a better accuracy is needed, the 15' grid WW15MGH.GRD of EGM96
+// distributed by the NGA, or any other grid in that format, may be read by ReadGrid; it reproduces the reference
+// undulations of the NGA within a meter.
+//
+// A Grid implements cartconvert.GeoidModel and may be set as geoid of a cartconvert.VerticalDatum.
+//
+// For further info see http://en.wikipedia.org/wiki/EGM96
+package geoid
+
+import (
+	"bufio"
+	"github.com/the42/cartconvert/cartconvert"
+	"io"
+	"math"
+	"strconv"
+)
+
+// A Grid holds geoid undulations in meters at the nodes of a regular latitude / longitude grid of the spacing
+// dLat, dLong in degrees, bounded by minLat, maxLat, minLong and maxLong.
+type Grid struct {
+	minLat, maxLat, minLong, maxLong float64
+	dLat, dLong                      float64
+	rows, cols                       int
+	// row by row from maxLat to minLat, each row from minLong to maxLong
+	values []float64
+}
+
+// Returns a grid of the undulations in values, given row by row from the northern boundary maxLat to the southern
+// boundary minLat, each row from the western boundary minLong to the eastern boundary maxLong, as by the grids of
+// the NGA. Function returns cartconvert.ErrRange if the boundaries or spacing are invalid or yield less than two
+// rows or columns, and cartconvert.ErrSyntax if the number of values does not match the number of nodes of the grid.
+func NewGrid(minLat, maxLat, minLong, maxLong, dLat, dLong float64, values []float64) (*Grid, error) {
+
+	if !(minLat < maxLat) || minLat < -90 || maxLat > 90 || !(minLong < maxLong) || maxLong-minLong > 360 || !(dLat > 0) || !(dLong > 0) {
+		return nil, cartconvert.ErrRange
+	}
+
+	rows := int(math.Floor((maxLat-minLat)/dLat+0.5)) + 1
+	cols := int(math.Floor((maxLong-minLong)/dLong+0.5)) + 1
+	if rows < 2 || cols < 2 {
+		return nil, cartconvert.ErrRange
+	}
+	if len(values) != rows*cols {
+		return nil, cartconvert.ErrSyntax
+	}
+
+	return &Grid{minLat: minLat, maxLat: maxLat, minLong: minLong, maxLong: maxLong, dLat: dLat, dLong: dLong,
+		rows: rows, cols: cols, values: values}, nil
+}
+
+// Reads a grid in the ASCII format of the geoid grids of the NGA, like WW15MGH.GRD of EGM96: A header of
+// the southern, northern, western and eastern boundary and the spacing of latitude and longitude in degrees,
+// followed by the undulations separated by white space, as given to NewGrid.
+// Function returns cartconvert.ErrSyntax if the grid can not be parsed.
+func ReadGrid(r io.Reader) (*Grid, error) {
+
+	scanner := bufio.NewScanner(r)
+	scanner.Split(bufio.ScanWords)
+
+	var values []float64
+	for scanner.Scan() {
+		value, err := strconv.ParseFloat(scanner.Text(), 64)
+		if err != nil {
+			return nil, cartconvert.ErrSyntax
+		}
+		values = append(values, value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(values) < 6 {
+		return nil, cartconvert.ErrSyntax
+	}
+	return NewGrid(values[0], values[1], values[2], values[3], values[4], values[5], values[6:])
+}
+
+// Returns the extent of the grid
+func (g *Grid) Extent() *cartconvert.LatLongExtent {
+	return &cartconvert.LatLongExtent{MinLat: g.minLat, MaxLat: g.maxLat, MinLong: g.minLong, MaxLong: g.maxLong}
+}
+
+// Returns the undulation at gc in meters, interpolated bilinearly between the surrounding nodes of the grid. The
+// longitude of a grid spanning the globe is wrapped into the grid. Method returns cartconvert.ErrRange, if gc is
+// outside of the grid.
+func (g *Grid) Undulation(gc *cartconvert.PolarCoord) (float64, error) {
+
+	lat, long := gc.Latitude, gc.Longitude
+	if math.IsNaN(lat) || math.IsNaN(long) || math.IsInf(long, 0) {
+		return 0, cartconvert.ErrRange
+	}
+	if g.maxLong-g.minLong >= 360 {
+		long = g.minLong + math.Mod(math.Mod(long-g.minLong, 360)+360, 360)
+	}
+	if lat < g.minLat || lat > g.maxLat || long < g.minLong || long > g.maxLong {
+		return 0, cartconvert.ErrRange
+	}
+
+	y := (g.maxLat - lat) / g.dLat
+	x := (long - g.minLong) / g.dLong
+	row := int(math.Min(math.Floor(y), float64(g.rows-2)))
+	col := int(math.Min(math.Floor(x), float64(g.cols-2)))
+	fy, fx := y-float64(row), x-float64(col)
+
+	value := func(row, col int) float64 {
+		return g.values[row*g.cols+col]
+	}
+	north := value(row, col)*(1-fx) + value(row, col+1)*fx
+	south := value(row+1, col)*(1-fx) + value(row+1, col+1)*fx
+	return north*(1-fy) + south*fy, nil
+}
+
+// The coarse grid embedded in the package, sampled from the EGM96 geopotential model truncated to degree and order 8.
+// It is not the grid of the NGA, see ReadGrid for that.
+var EGM96 = newEGM96Grid()
+
+// Orthometric heights above the coarse EGM96 geoid embedded in the package
+var VerticalDatumEGM96 = &cartconvert.VerticalDatum{Name: "EGM96 degree 8", Geoid: EGM96}
+
+// Returns the undulation of the EGM96 geoid in meters at the WGS84 based coordinate gc, interpolated from the
+// coarse embedded grid. Function returns cartconvert.ErrRange if the latitude is beyond the poles.
+func Undulation(gc *cartconvert.PolarCoord) (float64, error) {
+	return EGM96.Undulation(gc)
+}
@@ -0,0 +1,162 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// Automated tests for the cartconvert/geoid package
+package geoid
+
+import (
+	"github.com/the42/cartconvert/cartconvert"
+	"math"
+	"os"
+	"strings"
+	"testing"
+)
+
+// ## ReadGrid
+type readGridTest struct {
+	in  string
+	err error
+}
+
+var readGridTests = []readGridTest{
+	{"0 1 0 1 1 1\n1 2\n3 4\n", nil},
+	{"0.000 1.000 .000 1.000 .500 1.000 1 2 1.5 2.5 3 4", nil},
+	{"0 1 0 1 1 1\n1 2\n3\n", cartconvert.ErrSyntax},
+	{"0 1 0 1 1 1\n1 2\n3 x\n", cartconvert.ErrSyntax},
+	{"0 1 0 1", cartconvert.ErrSyntax},
+	{"1 0 0 1 1 1\n1 2\n3 4\n", cartconvert.ErrRange},
+	{"0 1 0 1 3 1\n1 2\n", cartconvert.ErrRange},
+}
+
+func TestReadGrid(t *testing.T) {
+	for index, test := range readGridTests {
+		_, err := ReadGrid(strings.NewReader(test.in))
+		if err != test.err {
+			t.Errorf("ReadGrid [%d]: expected error %v, got %v", index, test.err, err)
+		}
+	}
+}
+
+// ## Undulation
+type undulationTest struct {
+	gc  *cartconvert.PolarCoord
+	out float64
+	err error
+}
+
+// a grid of 2 x 3 nodes, from 10°N to 12°N and from 20°E to 23°E
+var testGrid, _ = ReadGrid(strings.NewReader("10 12 20 23 2 1.5\n1 2 3\n5 6 7\n"))
+
+var gridUndulationTests = []undulationTest{
+	{&cartconvert.PolarCoord{Latitude: 12, Longitude: 20}, 1, nil},
+	{&cartconvert.PolarCoord{Latitude: 10, Longitude: 23}, 7, nil},
+	{&cartconvert.PolarCoord{Latitude: 11, Longitude: 20.75}, 3.5, nil},
+	{&cartconvert.PolarCoord{Latitude: 11.5, Longitude: 22.25}, 3.5, nil},
+	{&cartconvert.PolarCoord{Latitude: 9.99, Longitude: 21}, 0, cartconvert.ErrRange},
+	{&cartconvert.PolarCoord{Latitude: 11, Longitude: 23.5}, 0, cartconvert.ErrRange},
+	{&cartconvert.PolarCoord{Latitude: math.NaN(), Longitude: 21}, 0, cartconvert.ErrRange},
+}
+
+func TestGridUndulation(t *testing.T) {
+	for index, test := range gridUndulationTests {
+		out, err := testGrid.Undulation(test.gc)
+		if err != test.err {
+			t.Errorf("Undulation [%d]: expected error %v, got %v", index, test.err, err)
+			continue
+		}
+		if math.Abs(out-test.out) > 1e-9 {
+			t.Errorf("Undulation [%d]: expected %g, got %g", index, test.out, out)
+		}
+	}
+}
+
+// The reference undulations of the full EGM96 model published by the NGA with its interpolation program INTPT
+// (readme of F477.F, http://earth-info.nga.mil/GandG/wgs84/gravitymod/egm96/egm96.html)
+var ngaUndulationTests = []undulationTest{
+	{&cartconvert.PolarCoord{Latitude: 38.6281550, Longitude: 269.7791550}, -31.628, nil},
+	{&cartconvert.PolarCoord{Latitude: -14.6212170, Longitude: 305.0211140}, -2.969, nil},
+	{&cartconvert.PolarCoord{Latitude: 46.8743190, Longitude: 102.4487290}, -43.575, nil},
+	{&cartconvert.PolarCoord{Latitude: -23.6174460, Longitude: 133.8747120}, 15.871, nil},
+	{&cartconvert.PolarCoord{Latitude: 38.6254730, Longitude: 359.9995000}, 50.066, nil},
+	{&cartconvert.PolarCoord{Latitude: -0.4667440, Longitude: 0.0023000}, 17.329, nil},
+}
+
+// Undulations of the full EGM96 model, the tolerance is the accuracy of the embedded grid
+var undulationTests = []undulationTest{
+	{&cartconvert.PolarCoord{Latitude: 0, Longitude: 0}, 17.16, nil},
+	{&cartconvert.PolarCoord{Latitude: 90, Longitude: 0}, 13.61, nil},
+	{&cartconvert.PolarCoord{Latitude: -90, Longitude: 0}, -29.53, nil},
+	// the minimum of the geoid south of India and its maximum at New Guinea
+	{&cartconvert.PolarCoord{Latitude: 4.7, Longitude: 78.8}, -106.99, nil},
+	{&cartconvert.PolarCoord{Latitude: -8.1, Longitude: 147.3}, 85.39, nil},
+	{&cartconvert.PolarCoord{Latitude: 90.5, Longitude: 0}, 0, cartconvert.ErrRange},
+}
+
+func TestUndulation(t *testing.T) {
+	for index, test := range undulationTests {
+		out, err := Undulation(test.gc)
+		if err != test.err {
+			t.Errorf("Undulation [%d]: expected error %v, got %v", index, test.err, err)
+			continue
+		}
+		if math.Abs(out-test.out) > 16 {
+			t.Errorf("Undulation [%d]: expected %.2f, got %.2f", index, test.out, out)
+		}
+	}
+	// the embedded grid reproduces the reference undulations of the NGA within its accuracy only
+	for index, test := range ngaUndulationTests {
+		if out, err := Undulation(test.gc); err != nil || math.Abs(out-test.out) > 16 {
+			t.Errorf("Undulation NGA [%d]: expected %.3f, got %.3f (%v)", index, test.out, out, err)
+		}
+	}
+
+	// the grid wraps around the globe
+	east, _ := Undulation(&cartconvert.PolarCoord{Latitude: 47.5, Longitude: 182.5})
+	west, _ := Undulation(&cartconvert.PolarCoord{Latitude: 47.5, Longitude: -177.5})
+	if east != west {
+		t.Errorf("Undulation: expected the same undulation east and west of the antimeridian, got %g and %g", east, west)
+	}
+
+	// the nodes of the grid are the undulations of the model
+	if out, _ := Undulation(&cartconvert.PolarCoord{Latitude: 45, Longitude: 15}); out != egm96Undulation(45, 15) {
+		t.Errorf("Undulation: expected %g at the node of the grid, got %g", egm96Undulation(45, 15), out)
+	}
+}
+
+// The 15' grid WW15MGH.GRD of the NGA reproduces the reference undulations within a meter. The grid of about 9 MB
+// is not part of the package, the test reads it from the file named by the environment variable WW15MGH.
+func TestReadGridWW15MGH(t *testing.T) {
+	filename := os.Getenv("WW15MGH")
+	if filename == "" {
+		t.Skip("WW15MGH: the file of the EGM96 grid of the NGA is not given")
+	}
+	file, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	grid, err := ReadGrid(file)
+	if err != nil {
+		t.Fatalf("ReadGrid %s: %s", filename, err)
+	}
+	for index, test := range ngaUndulationTests {
+		if out, err := grid.Undulation(test.gc); err != nil || math.Abs(out-test.out) > 1 {
+			t.Errorf("Undulation [%d]: expected %.3f, got %.3f (%v)", index, test.out, out, err)
+		}
+	}
+}
+
+func TestVerticalDatumEGM96(t *testing.T) {
+	gc := &cartconvert.PolarCoord{Latitude: 48.2082, Longitude: 16.3738, Height: 220, El: cartconvert.WGS84Ellipsoid}
+	undulation, _ := Undulation(gc)
+
+	out, err := cartconvert.Convert3D(gc, cartconvert.EllipsoidalHeights, nil, VerticalDatumEGM96)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(out.Height-(220-undulation)) > 1e-9 {
+		t.Errorf("Convert3D: expected orthometric height %g, got %g", 220-undulation, out.Height)
+	}
+}
@@ -0,0 +1,479 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// This package provides functions to deal with conversion and transformations of coordinates
+// of the island of Ireland, the Irish Grid of the datum Ireland 1975 (TM75) and the Irish Transverse Mercator (ITM).
+//
+// The Irish Grid is a transverse Mercator projection on the modified Airy ellipsoid. Grid references
+// are given by a single letter denoting a 100 km square, followed by easting and northing within the square.
+// The conversion between WGS84 and TM75 uses a helmert transformation with an accuracy of about 1m.
+//
+// The Irish Transverse Mercator is defined on ETRS89, which is taken to be identical to WGS84.
+//
+// For further info see http://www.osi.ie/resources/reference-information-software/coordinate-converter/
+package irishgrid
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/the42/cartconvert/cartconvert"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Geographic extent of the Irish Grid and the Irish Transverse Mercator, the island of Ireland
+var Extent = &cartconvert.LatLongExtent{MinLat: 51.3, MaxLat: 55.5, MinLong: -10.7, MaxLong: -5.3}
+
+// The maximum number of digits of easting and northing of an Irish Grid reference, to the accuracy of a meter
+const IrishGridMaxPrec = 5
+
+// The location within the square denoted by a grid reference, a coordinate gets converted to.
+//
+//    IrishGridAnchorDefault - the middle of squares of reduced precision, the south-west corner of the bare 100 km square.
+//       This is the location returned by IrishGridZoneToRefCoords and IrishGridToWGS84LatLong.
+//    IrishGridCenter - the middle of the square at any precision, including the bare 100 km square
+//    IrishGridSouthWest - the south-west corner of the square at any precision, the origin of the square
+//
+// A grid reference to the accuracy of a meter denotes the same location for all anchors.
+type IrishGridAnchor byte
+
+const (
+	IrishGridAnchorDefault IrishGridAnchor = iota
+	IrishGridCenter
+	IrishGridSouthWest
+)
+
+// A Irish Grid coordinate is specified by the letter of the 100 km square, easting and northing within the square.
+type IrishGridCoord struct {
+	Easting   uint                   `json:"easting" xml:"easting"`
+	Northing  uint                   `json:"northing" xml:"northing"`
+	RelHeight float64                `json:"relheight" xml:"relheight"`
+	Zone      string                 `json:"zone" xml:"zone"`
+	El        *cartconvert.Ellipsoid `json:"ellipsoid,omitempty" xml:"ellipsoid,omitempty"`
+	// Approximate positional error in meters introduced by the datum shift of the conversion from WGS84, the
+	// accuracy of the helmert parameter set used. Zero for coordinates not converted from WGS84.
+	Accuracy float64 `json:"accuracy,omitempty" xml:"accuracy,omitempty"`
+	gridLen  byte
+}
+
+// Canonical representation of an Irish Grid reference, like O1593434595
+func (coord *IrishGridCoord) String() string {
+	if coord.gridLen > 0 {
+		return fmt.Sprintf("%s%0*d%0*d", coord.Zone, int(coord.gridLen), coord.Easting, int(coord.gridLen), coord.Northing)
+	}
+	return coord.Zone
+}
+
+// Implements cartconvert.Coordinate, the system of an Irish Grid reference is "irishgrid"
+func (coord *IrishGridCoord) System() string {
+	return "irishgrid"
+}
+
+// Implements cartconvert.Coordinate, see IrishGridToWGS84LatLongChecked
+func (coord *IrishGridCoord) ToWGS84() (*cartconvert.PolarCoord, error) {
+	return IrishGridToWGS84LatLongChecked(coord)
+}
+
+// Implements json.Marshaler, writing the coordinates in fixed-point notation
+func (coord *IrishGridCoord) MarshalJSON() ([]byte, error) {
+	return cartconvert.MarshalFixedJSON(coord)
+}
+
+// Implements xml.Marshaler, writing the coordinates in fixed-point notation
+func (coord *IrishGridCoord) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return cartconvert.MarshalFixedXML(e, start, coord)
+}
+
+// Validates that the Irish Grid coordinate lies within Extent, the island of Ireland. Returns a
+// cartconvert.ExtentError, if zone, easting and northing transform to a location outside of Extent.
+func (coord *IrishGridCoord) Valid() error {
+	gc, err := IrishGridToWGS84LatLongChecked(coord)
+	if err != nil {
+		return err
+	}
+	return Extent.Validate(gc, "Irish Grid", coord.String())
+}
+
+// EPSG codes of the Irish Grid, TM75 / Irish Grid, and of the Irish Transverse Mercator, IRENET95 / Irish
+// Transverse Mercator
+const (
+	EPSGIrishGrid = 29903
+	EPSGITM       = 2157
+)
+
+// Returns the EPSG code of the Irish Grid
+func (coord *IrishGridCoord) EPSG() int {
+	return EPSGIrishGrid
+}
+
+// The Irish Grid and the Irish Transverse Mercator are registered as coordinate reference systems
+func init() {
+	for _, crs := range []*cartconvert.CRS{
+		{EPSG: EPSGIrishGrid, Name: "TM75 / Irish Grid", Datum: cartconvert.DatumTM75, El: cartconvert.Airy1830ModEllipsoid,
+			Method: cartconvert.MethodTransverseMercator, LatO: 53.5, LongO: -8, Scale: 1.000035, FalseEasting: 200000, FalseNorthing: 250000},
+		{EPSG: EPSGITM, Name: "IRENET95 / Irish Transverse Mercator", El: cartconvert.GRS80Ellipsoid,
+			Method: cartconvert.MethodTransverseMercator, LatO: 53.5, LongO: -8, Scale: 0.99982, FalseEasting: 600000, FalseNorthing: 750000},
+	} {
+		if err := cartconvert.RegisterCRS(crs); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Parses a string representation of an Irish Grid reference into an Irish Grid coordinate struct. The literal
+// can be specified as follows:
+//
+//	Z EA NO
+//	Z EANO
+//	ZEANO
+//
+// with Z the letter of the 100 km square, EA easting and NO northing of 1 up to 5 digits each, the latter to
+// the accuracy of a meter. The bare letter denotes the south-west corner of the square.
+//
+// The reference ellipsoid of an Irish Grid coordinate will always be set to the modified Airy ellipsoid.
+//
+// returns cartconvert.ErrSyntax if format is not understood
+// returns cartconvert.ErrRange if easting and northing differ in length or exceed 5 digits
+func AIrishGridToStruct(igcoord string) (*IrishGridCoord, error) {
+
+	compact := strings.ToUpper(strings.Replace(igcoord, " ", "", -1))
+	if len(compact) == 0 || compact[0] < 'A' || compact[0] > 'Z' || compact[0] == 'I' {
+		return nil, cartconvert.ErrSyntax
+	}
+
+	zone, enn := compact[:1], compact[1:]
+	for _, item := range enn {
+		if item < '0' || item > '9' {
+			return nil, cartconvert.ErrSyntax
+		}
+	}
+
+	ennlen := len(enn)
+	if ennlen%2 > 0 || ennlen/2 > IrishGridMaxPrec {
+		return nil, cartconvert.ErrRange
+	}
+
+	var east, north int
+	var err error
+	if ennlen > 0 {
+		if east, err = strconv.Atoi(enn[:ennlen/2]); err != nil {
+			return nil, err
+		}
+		if north, err = strconv.Atoi(enn[ennlen/2:]); err != nil {
+			return nil, err
+		}
+	}
+	return NewIrishGridCoord(zone, uint(east), uint(north), 0, byte(ennlen/2)), nil
+}
+
+// Returns easting and northing of an Irish Grid reference relative to the false origin of the grid at square V.
+// Easting and northing of reduced precision are located at the middle of their square, the bare letter at the
+// south-west corner of the 100 km square.
+func IrishGridZoneToRefCoords(coord *IrishGridCoord) (easting, northing uint) {
+	easting, northing, _ = IrishGridZoneToRefCoordsAnchor(coord, IrishGridAnchorDefault)
+	return
+}
+
+// Returns easting and northing of an Irish Grid reference relative to the false origin of the grid at square V,
+// located within the square denoted by the grid reference according to anchor. Function returns
+// cartconvert.ErrRange, if anchor is unknown, and cartconvert.ErrSyntax, if the zone is not a single letter
+// other than I, as accepted by AIrishGridToStruct.
+func IrishGridZoneToRefCoordsAnchor(coord *IrishGridCoord, anchor IrishGridAnchor) (easting, northing uint, err error) {
+
+	if len(coord.Zone) != 1 || coord.Zone[0] < 'A' || coord.Zone[0] > 'Z' || coord.Zone[0] == 'I' {
+		return 0, 0, cartconvert.ErrSyntax
+	}
+
+	// get the numeric value of the letter, mapping A->0, B->1, C->2, etc. and skipping 'I'
+	l := uint(coord.Zone[0] - 'A')
+	if l > 7 {
+		l--
+	}
+
+	// the squares are lettered row by row from the north-west, square V is the south-western one
+	easting = (l%5)*100000 + coord.Easting*pow10(IrishGridMaxPrec-coord.gridLen)
+	northing = (4-l/5)*100000 + coord.Northing*pow10(IrishGridMaxPrec-coord.gridLen)
+
+	half := pow10(IrishGridMaxPrec-coord.gridLen) / 2
+	switch anchor {
+	case IrishGridAnchorDefault:
+		if coord.gridLen > 0 {
+			easting += half
+			northing += half
+		}
+	case IrishGridCenter:
+		easting += half
+		northing += half
+	case IrishGridSouthWest:
+	default:
+		return 0, 0, cartconvert.ErrRange
+	}
+	return
+}
+
+func pow10(n byte) uint {
+	return uint(math.Pow(10, float64(n)))
+}
+
+// Build an Irish Grid coordinate to the accuracy of a meter from easting and northing relative to the false origin.
+//
+// The function will return cartconvert.ErrRange if easting or northing are not within the Irish Grid.
+func GridRefNumToLet(easting, northing uint, height float64) (*IrishGridCoord, error) {
+	easting100k := easting / 100000
+	northing100k := northing / 100000
+
+	if easting100k > 4 || northing100k > 4 {
+		return nil, cartconvert.ErrRange
+	}
+
+	l := byte((4-northing100k)*5 + easting100k)
+	// compensate for the skipped 'I'
+	if l > 7 {
+		l++
+	}
+
+	return NewIrishGridCoord(string(l+'A'), easting%100000, northing%100000, height, IrishGridMaxPrec), nil
+}
+
+// Returns the helmert parameter set used for the datum shift between WGS84 and TM75. If set is nil,
+// the default parameter set for WGS84 to TM75 is returned. Returns cartconvert.ErrRange if the
+// parameter set does not transform from WGS84 to TM75.
+func helmertParameterSet(set *cartconvert.HelmertParameterSet) (*cartconvert.HelmertParameterSet, error) {
+	if set == nil {
+		return cartconvert.DefaultHelmertParameterSet(cartconvert.DatumWGS84, cartconvert.DatumTM75)
+	}
+	if set.From != cartconvert.DatumWGS84 || set.To != cartconvert.DatumTM75 {
+		return nil, cartconvert.ErrRange
+	}
+	return set, nil
+}
+
+// Convert an Irish Grid coordinate value to a WGS84 based latitude and longitude coordinate. Returns nil, if the
+// grid reference can't be converted, eg. of an unknown letter, see IrishGridToWGS84LatLongChecked.
+func IrishGridToWGS84LatLong(coord *IrishGridCoord) *cartconvert.PolarCoord {
+	gc, _ := IrishGridToWGS84LatLongChecked(coord)
+	return gc
+}
+
+// Convert an Irish Grid coordinate value to a WGS84 based latitude and longitude coordinate like
+// IrishGridToWGS84LatLong. Function returns the error of the conversion, eg. cartconvert.ErrSyntax for a zone
+// which is not a letter of the grid.
+func IrishGridToWGS84LatLongChecked(coord *IrishGridCoord) (*cartconvert.PolarCoord, error) {
+	return IrishGridToWGS84LatLongHelmert(coord, nil)
+}
+
+// Convert an Irish Grid coordinate value to a WGS84 based latitude and longitude coordinate using the
+// helmert parameter set for the datum shift. If set is nil, the default parameter set from WGS84 to
+// TM75 is used. Function returns cartconvert.ErrRange, if the parameter set does not transform from
+// WGS84 to TM75.
+func IrishGridToWGS84LatLongHelmert(coord *IrishGridCoord, set *cartconvert.HelmertParameterSet) (*cartconvert.PolarCoord, error) {
+	return IrishGridToWGS84LatLongAnchor(coord, IrishGridAnchorDefault, set)
+}
+
+// Convert an Irish Grid coordinate value to a WGS84 based latitude and longitude coordinate located within the
+// square denoted by the grid reference according to anchor, using the helmert parameter set for the datum
+// shift as by IrishGridToWGS84LatLongHelmert. Function returns cartconvert.ErrRange, if anchor is unknown or
+// if the parameter set does not transform from WGS84 to TM75.
+func IrishGridToWGS84LatLongAnchor(coord *IrishGridCoord, anchor IrishGridAnchor, set *cartconvert.HelmertParameterSet) (*cartconvert.PolarCoord, error) {
+
+	hp, err := helmertParameterSet(set)
+	if err != nil {
+		return nil, err
+	}
+
+	easting, northing, err := IrishGridZoneToRefCoordsAnchor(coord, anchor)
+	if err != nil {
+		return nil, err
+	}
+
+	gc := cartconvert.InverseTransverseMercator(
+		&cartconvert.GeoPoint{Y: float64(northing), X: float64(easting), El: cartconvert.Airy1830ModEllipsoid},
+		53.5,
+		-8,
+		1.000035,
+		200000,
+		250000)
+
+	cart := cartconvert.PolarToCartesian(gc)
+	pt := hp.InverseTransform(&cartconvert.Point3D{X: cart.X, Y: cart.Y, Z: cart.Z})
+
+	return cartconvert.CartesianToPolar(&cartconvert.CartPoint{X: pt.X, Y: pt.Y, Z: pt.Z, El: cartconvert.WGS84Ellipsoid}), nil
+}
+
+// Transform a latitude / longitude coordinate datum into an Irish Grid coordinate.
+//
+// Important: The reference ellipsoid of the originating coordinate system will be assumed
+// to be the WGS84Ellipsoid and will be set thereupon, regardless of the actually set reference ellipsoid.
+func WGS84LatLongToIrishGrid(gc *cartconvert.PolarCoord) (*IrishGridCoord, error) {
+	return WGS84LatLongToIrishGridHelmert(gc, nil)
+}
+
+// Transform a latitude / longitude coordinate datum into an Irish Grid coordinate using the helmert parameter
+// set for the datum shift. If set is nil, the default parameter set from WGS84 to TM75 is used.
+// Function returns cartconvert.ErrRange, if the parameter set does not transform from WGS84 to TM75
+// or the coordinate is not within the Irish Grid.
+//
+// Important: The reference ellipsoid of the originating coordinate system will be assumed
+// to be the WGS84Ellipsoid and will be set thereupon, regardless of the actually set reference ellipsoid.
+func WGS84LatLongToIrishGridHelmert(gc *cartconvert.PolarCoord, set *cartconvert.HelmertParameterSet) (*IrishGridCoord, error) {
+
+	hp, err := helmertParameterSet(set)
+	if err != nil {
+		return nil, err
+	}
+	if err = gc.CheckFinite(); err != nil {
+		return nil, err
+	}
+
+	// This sets the Ellipsoid to WGS84, regardless of the actual value set
+	gc.El = cartconvert.WGS84Ellipsoid
+
+	cart := cartconvert.PolarToCartesian(gc)
+	pt := hp.Transform(&cartconvert.Point3D{X: cart.X, Y: cart.Y, Z: cart.Z})
+	polar := cartconvert.CartesianToPolar(&cartconvert.CartPoint{X: pt.X, Y: pt.Y, Z: pt.Z, El: cartconvert.Airy1830ModEllipsoid})
+
+	gp := cartconvert.DirectTransverseMercator(
+		polar,
+		53.5,
+		-8,
+		1.000035,
+		200000,
+		250000)
+
+	if gp.X < 0 || gp.Y < 0 {
+		return nil, cartconvert.ErrRange
+	}
+	coord, err := GridRefNumToLet(uint(gp.X+0.5), uint(gp.Y+0.5), 0)
+	if err != nil {
+		return nil, err
+	}
+	coord.Accuracy = hp.Accuracy
+	return coord, nil
+}
+
+// Create a new Irish Grid coordinate from literals. Easting and northing are given by prec digits,
+// prec ranging from 0 for the bare 100 km square to IrishGridMaxPrec for the accuracy of a meter.
+func NewIrishGridCoord(Zone string, easting, northing uint, relheight float64, prec byte) *IrishGridCoord {
+	return &IrishGridCoord{Easting: easting, Northing: northing, RelHeight: relheight, Zone: Zone, gridLen: prec, El: cartconvert.Airy1830ModEllipsoid}
+}
+
+// --------------------------------------------------------------------
+// Irish Transverse Mercator
+
+// An ITM coordinate is specified by easting and northing in meters
+type ITMCoord struct {
+	Easting   float64                `json:"easting" xml:"easting"`
+	Northing  float64                `json:"northing" xml:"northing"`
+	RelHeight float64                `json:"relheight" xml:"relheight"`
+	El        *cartconvert.Ellipsoid `json:"ellipsoid,omitempty" xml:"ellipsoid,omitempty"`
+}
+
+// Canonical representation of an ITM coordinate, easting and northing to the meter, like "715830 734697"
+func (coord *ITMCoord) String() string {
+	return fmt.Sprintf("%.0f %.0f", coord.Easting, coord.Northing)
+}
+
+// Implements cartconvert.Coordinate, the system of an ITM coordinate is "itm"
+func (coord *ITMCoord) System() string {
+	return "itm"
+}
+
+// Implements cartconvert.Coordinate, see ITMToWGS84LatLong
+func (coord *ITMCoord) ToWGS84() (*cartconvert.PolarCoord, error) {
+	return ITMToWGS84LatLong(coord), nil
+}
+
+// Representation of an ITM coordinate with precision decimal places of easting and northing, see
+// cartconvert.FormatFixed
+func (coord *ITMCoord) Format(precision int) string {
+	return cartconvert.FormatFixed(coord.Easting, precision) + " " + cartconvert.FormatFixed(coord.Northing, precision)
+}
+
+// Returns a copy of the ITM coordinate with easting and northing snapped to the nearest multiple of spacingMeters, see
+// cartconvert.SnapToGrid
+func (coord *ITMCoord) Snap(spacingMeters float64) *ITMCoord {
+	snapped := *coord
+	snapped.Easting, snapped.Northing = cartconvert.SnapToGrid(coord.Easting, spacingMeters), cartconvert.SnapToGrid(coord.Northing, spacingMeters)
+	return &snapped
+}
+
+// Implements json.Marshaler, writing the coordinates in fixed-point notation
+func (coord *ITMCoord) MarshalJSON() ([]byte, error) {
+	return cartconvert.MarshalFixedJSON(coord)
+}
+
+// Implements xml.Marshaler, writing the coordinates in fixed-point notation
+func (coord *ITMCoord) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return cartconvert.MarshalFixedXML(e, start, coord)
+}
+
+// Validates that the ITM coordinate lies within Extent, the island of Ireland. Returns a cartconvert.ExtentError,
+// if easting and northing transform to a location outside of Extent.
+func (coord *ITMCoord) Valid() error {
+	gc := ITMToWGS84LatLong(coord)
+	return Extent.Validate(gc, "ITM", coord.String())
+}
+
+// Returns the EPSG code of the Irish Transverse Mercator
+func (coord *ITMCoord) EPSG() int {
+	return EPSGITM
+}
+
+// Parses a string representation of an ITM coordinate, easting and northing in meters separated by blanks.
+// The reference ellipsoid of an ITM coordinate will always be set to the GRS80 ellipsoid.
+//
+// returns cartconvert.ErrSyntax if the literal doesn't consist of easting and northing
+func AITMToStruct(itmcoord string) (*ITMCoord, error) {
+
+	fields := strings.Fields(itmcoord)
+	if len(fields) != 2 {
+		return nil, cartconvert.ErrSyntax
+	}
+
+	easting, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return nil, err
+	}
+	northing, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return nil, err
+	}
+	if err = cartconvert.CheckFinite(easting, northing); err != nil {
+		return nil, err
+	}
+	return NewITMCoord(easting, northing, 0), nil
+}
+
+// Convert an ITM coordinate value to a WGS84 based latitude and longitude coordinate.
+func ITMToWGS84LatLong(coord *ITMCoord) *cartconvert.PolarCoord {
+	gc := cartconvert.InverseTransverseMercator(
+		&cartconvert.GeoPoint{Y: coord.Northing, X: coord.Easting, El: cartconvert.GRS80Ellipsoid},
+		53.5,
+		-8,
+		0.99982,
+		600000,
+		750000)
+	gc.El = cartconvert.WGS84Ellipsoid
+	return gc
+}
+
+// Transform a latitude / longitude coordinate datum into an ITM coordinate.
+//
+// Important: The reference ellipsoid of the originating coordinate system will be assumed
+// to be the WGS84Ellipsoid, regardless of the actually set reference ellipsoid.
+func WGS84LatLongToITM(gc *cartconvert.PolarCoord) *ITMCoord {
+	gp := cartconvert.DirectTransverseMercator(
+		&cartconvert.PolarCoord{Latitude: gc.Latitude, Longitude: gc.Longitude, El: cartconvert.GRS80Ellipsoid},
+		53.5,
+		-8,
+		0.99982,
+		600000,
+		750000)
+	return NewITMCoord(gp.X, gp.Y, 0)
+}
+
+func NewITMCoord(easting, northing, relheight float64) *ITMCoord {
+	return &ITMCoord{Easting: easting, Northing: northing, RelHeight: relheight, El: cartconvert.GRS80Ellipsoid}
+}
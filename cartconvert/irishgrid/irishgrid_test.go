@@ -0,0 +1,352 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// Automated tests for the cartconvert/irishgrid package
+package irishgrid
+
+import (
+	"fmt"
+	"github.com/the42/cartconvert/cartconvert"
+	"math"
+	"testing"
+)
+
+// ## AIrishGridToStruct
+type aIrishGridToStructTest struct {
+	in  string
+	out *IrishGridCoord
+	err error
+}
+
+var aIrishGridToStructTests = []aIrishGridToStructTest{
+	{"O 15901 34671", NewIrishGridCoord("O", 15901, 34671, 0, 5), nil},
+	{"o1590134671", NewIrishGridCoord("O", 15901, 34671, 0, 5), nil},
+	{"O 159 346", NewIrishGridCoord("O", 159, 346, 0, 3), nil},
+	{"V", NewIrishGridCoord("V", 0, 0, 0, 0), nil},
+	{"I 12345 67890", nil, cartconvert.ErrSyntax},
+	{"OO 12345 67890", nil, cartconvert.ErrSyntax},
+	{"12345 67890", nil, cartconvert.ErrSyntax},
+	{"", nil, cartconvert.ErrSyntax},
+	{"O 1234 567", nil, cartconvert.ErrRange},
+	{"O 123456 123456", nil, cartconvert.ErrRange},
+}
+
+func TestAIrishGridToStruct(t *testing.T) {
+	for index, test := range aIrishGridToStructTests {
+		out, err := AIrishGridToStruct(test.in)
+
+		if err != test.err {
+			t.Errorf("AIrishGridToStruct [%d]: expected error %v, got %v", index, test.err, err)
+			continue
+		}
+		if test.out != nil && test.out.String() != out.String() {
+			t.Errorf("AIrishGridToStruct [%d]: expected %s, got %s", index, test.out, out)
+		}
+	}
+}
+
+// ## IrishGridZoneToRefCoords
+type irishGridZoneToRefCoordsTest struct {
+	in                string
+	easting, northing uint
+}
+
+var irishGridZoneToRefCoordsTests = []irishGridZoneToRefCoordsTest{
+	{"V", 0, 0},
+	{"A", 0, 400000},
+	{"Z 00001 00002", 400001, 2},
+	{"O 15901 34671", 315901, 234671},
+	// a reference of reduced precision is located at the middle of its square
+	{"O 159 346", 315950, 234650},
+}
+
+func TestIrishGridZoneToRefCoords(t *testing.T) {
+	for index, test := range irishGridZoneToRefCoordsTests {
+		coord, err := AIrishGridToStruct(test.in)
+		if err != nil {
+			t.Fatalf("AIrishGridToStruct [%d]: %s", index, err)
+		}
+		if easting, northing := IrishGridZoneToRefCoords(coord); easting != test.easting || northing != test.northing {
+			t.Errorf("IrishGridZoneToRefCoords [%d]: expected %d %d, got %d %d", index, test.easting, test.northing, easting, northing)
+		}
+	}
+}
+
+// ## IrishGridZoneToRefCoordsAnchor
+type irishGridZoneToRefCoordsAnchorTest struct {
+	in                string
+	anchor            IrishGridAnchor
+	easting, northing uint
+	err               error
+}
+
+var irishGridZoneToRefCoordsAnchorTests = []irishGridZoneToRefCoordsAnchorTest{
+	{"O 159 346", IrishGridAnchorDefault, 315950, 234650, nil},
+	{"O 159 346", IrishGridCenter, 315950, 234650, nil},
+	{"O 159 346", IrishGridSouthWest, 315900, 234600, nil},
+	{"O", IrishGridAnchorDefault, 300000, 200000, nil},
+	{"O", IrishGridCenter, 350000, 250000, nil},
+	{"O", IrishGridSouthWest, 300000, 200000, nil},
+	// a reference to the accuracy of a meter is the same for all anchors
+	{"O 15901 34671", IrishGridCenter, 315901, 234671, nil},
+	{"O 15901 34671", IrishGridSouthWest, 315901, 234671, nil},
+	{"O", IrishGridAnchor(99), 0, 0, cartconvert.ErrRange},
+}
+
+func TestIrishGridZoneToRefCoordsAnchor(t *testing.T) {
+	for index, test := range irishGridZoneToRefCoordsAnchorTests {
+		coord, err := AIrishGridToStruct(test.in)
+		if err != nil {
+			t.Fatalf("AIrishGridToStruct [%d]: %s", index, err)
+		}
+		easting, northing, err := IrishGridZoneToRefCoordsAnchor(coord, test.anchor)
+		if err != test.err {
+			t.Errorf("IrishGridZoneToRefCoordsAnchor [%d]: expected error %v, got %v", index, test.err, err)
+			continue
+		}
+		if easting != test.easting || northing != test.northing {
+			t.Errorf("IrishGridZoneToRefCoordsAnchor [%d]: expected %d %d, got %d %d", index, test.easting, test.northing, easting, northing)
+		}
+	}
+
+	square, _ := AIrishGridToStruct("O 159 346")
+	corner, _ := AIrishGridToStruct("O 15900 34600")
+	out, err := IrishGridToWGS84LatLongAnchor(square, IrishGridSouthWest, nil)
+	if err != nil {
+		t.Fatalf("IrishGridToWGS84LatLongAnchor: %s", err)
+	}
+	if expected := IrishGridToWGS84LatLong(corner); *out != *expected {
+		t.Errorf("IrishGridToWGS84LatLongAnchor: expected the south-west corner %s, got %s", expected, out)
+	}
+}
+
+// ## WGS84LatLongToIrishGrid
+type wGS84LatLongToIrishGridTest struct {
+	gc  *cartconvert.PolarCoord
+	ig  string
+	itm string
+}
+
+var wGS84LatLongToIrishGridTests = []wGS84LatLongToIrishGridTest{
+	// The Spire, Dublin
+	{&cartconvert.PolarCoord{Latitude: 53.3498, Longitude: -6.2603}, "O1590134671", "715827 734698"},
+	{&cartconvert.PolarCoord{Latitude: 52.6636, Longitude: -8.6304}, "R5739757078", "557356 657122"},
+	{&cartconvert.PolarCoord{Latitude: 54.5973, Longitude: -5.9301}, "J3382874088", "733751 874084"},
+	{&cartconvert.PolarCoord{Latitude: 51.8985, Longitude: -8.4756}, "W6731271861", "567269 571923"},
+}
+
+func latlongequal(pcp1, pcp2 *cartconvert.PolarCoord) bool {
+	// the helmert transformation between TM75 and WGS84 is exact to about 1m
+	return math.Hypot(pcp1.Latitude-pcp2.Latitude, pcp1.Longitude-pcp2.Longitude) < 0.00001
+}
+
+func TestWGS84LatLongToIrishGrid(t *testing.T) {
+	for index, test := range wGS84LatLongToIrishGridTests {
+		out, err := WGS84LatLongToIrishGrid(&cartconvert.PolarCoord{Latitude: test.gc.Latitude, Longitude: test.gc.Longitude})
+		if err != nil {
+			t.Errorf("WGS84LatLongToIrishGrid [%d]: %s", index, err)
+			continue
+		}
+		if out.String() != test.ig {
+			t.Errorf("WGS84LatLongToIrishGrid [%d]: expected %s, got %s", index, test.ig, out)
+		}
+	}
+
+	if _, err := WGS84LatLongToIrishGrid(&cartconvert.PolarCoord{Latitude: 47.57, Longitude: 14.0075}); err != cartconvert.ErrRange {
+		t.Errorf("WGS84LatLongToIrishGrid: expected ErrRange outside of the Irish Grid, got %v", err)
+	}
+}
+
+// ## WGS84LatLongToIrishGridHelmert
+func TestWGS84LatLongToIrishGridHelmert(t *testing.T) {
+	gc := &cartconvert.PolarCoord{Latitude: 53.3498, Longitude: -6.2603}
+
+	set, err := cartconvert.HelmertParameterSetByName("TM75_7param_OSi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out, err := WGS84LatLongToIrishGridHelmert(gc, set); err != nil || out.String() != "O1590134671" || out.Accuracy != set.Accuracy {
+		t.Errorf("WGS84LatLongToIrishGridHelmert: expected O1590134671 accurate to %g m, got %s (%v)", set.Accuracy, out, err)
+	}
+
+	mgi, _ := cartconvert.DefaultHelmertParameterSet(cartconvert.DatumWGS84, cartconvert.DatumMGI)
+	if _, err = WGS84LatLongToIrishGridHelmert(gc, mgi); err != cartconvert.ErrRange {
+		t.Errorf("WGS84LatLongToIrishGridHelmert: expected ErrRange for a parameter set of another datum, got %v", err)
+	}
+	if _, err = IrishGridToWGS84LatLongHelmert(NewIrishGridCoord("O", 15901, 34671, 0, 5), mgi); err != cartconvert.ErrRange {
+		t.Errorf("IrishGridToWGS84LatLongHelmert: expected ErrRange for a parameter set of another datum, got %v", err)
+	}
+}
+
+// ## IrishGridToWGS84LatLong
+func TestIrishGridToWGS84LatLong(t *testing.T) {
+	for index, test := range wGS84LatLongToIrishGridTests {
+		coord, err := AIrishGridToStruct(test.ig)
+		if err != nil {
+			t.Fatalf("AIrishGridToStruct [%d]: %s", index, err)
+		}
+		if out := IrishGridToWGS84LatLong(coord); !latlongequal(test.gc, out) {
+			t.Errorf("IrishGridToWGS84LatLong [%d]: expected %.6f %.6f, got %.6f %.6f", index, test.gc.Latitude, test.gc.Longitude, out.Latitude, out.Longitude)
+		}
+	}
+}
+
+// ## IrishGridToWGS84LatLongChecked
+func TestIrishGridToWGS84LatLongChecked(t *testing.T) {
+	for index, test := range wGS84LatLongToIrishGridTests {
+		coord, err := AIrishGridToStruct(test.ig)
+		if err != nil {
+			t.Fatalf("AIrishGridToStruct [%d]: %s", index, err)
+		}
+		if out, err := IrishGridToWGS84LatLongChecked(coord); err != nil || !latlongequal(test.gc, out) {
+			t.Errorf("IrishGridToWGS84LatLongChecked [%d]: expected %.6f %.6f, got %v (%v)", index, test.gc.Latitude, test.gc.Longitude, out, err)
+		}
+	}
+
+	// the letter I is not used by the grid
+	for _, zone := range []string{"I", "1", "", "OO"} {
+		coord := NewIrishGridCoord(zone, 15901, 34671, 0, 5)
+		if out, err := IrishGridToWGS84LatLongChecked(coord); err != cartconvert.ErrSyntax || out != nil {
+			t.Errorf("IrishGridToWGS84LatLongChecked [%s]: expected %v, got %v (%v)", zone, cartconvert.ErrSyntax, out, err)
+		}
+		if out := IrishGridToWGS84LatLong(coord); out != nil {
+			t.Errorf("IrishGridToWGS84LatLong [%s]: expected nil, got %v", zone, out)
+		}
+	}
+}
+
+// ## AITMToStruct
+func TestAITMToStruct(t *testing.T) {
+	out, err := AITMToStruct(" 715827.4  734697.6 ")
+	if err != nil || fmt.Sprintf("%.1f %.1f", out.Easting, out.Northing) != "715827.4 734697.6" {
+		t.Errorf("AITMToStruct: expected 715827.4 734697.6, got %v (%v)", out, err)
+	}
+	if _, err = AITMToStruct("715827"); err != cartconvert.ErrSyntax {
+		t.Errorf("AITMToStruct: expected ErrSyntax, got %v", err)
+	}
+	if _, err = AITMToStruct("E715827 734697"); err == nil {
+		t.Error("AITMToStruct: expected error on malformed easting")
+	}
+}
+
+// ## WGS84LatLongToITM
+func TestWGS84LatLongToITM(t *testing.T) {
+	for index, test := range wGS84LatLongToIrishGridTests {
+		out := WGS84LatLongToITM(test.gc)
+		if out.String() != test.itm {
+			t.Errorf("WGS84LatLongToITM [%d]: expected %s, got %s", index, test.itm, out)
+		}
+
+		if back := ITMToWGS84LatLong(out); math.Hypot(back.Latitude-test.gc.Latitude, back.Longitude-test.gc.Longitude) > 1e-9 {
+			t.Errorf("ITMToWGS84LatLong [%d]: expected %.9f %.9f, got %.9f %.9f", index, test.gc.Latitude, test.gc.Longitude, back.Latitude, back.Longitude)
+		}
+	}
+}
+
+type validTest struct {
+	in    *IrishGridCoord
+	valid bool
+}
+
+var validTests = []validTest{
+	{NewIrishGridCoord("O", 15901, 34671, 0, 5), true},
+	// Wales
+	{NewIrishGridCoord("Z", 50000, 50000, 0, 5), false},
+}
+
+func TestValid(t *testing.T) {
+	for index, test := range validTests {
+		err := test.in.Valid()
+		if _, ok := err.(cartconvert.ExtentError); (err == nil) != test.valid || (err != nil && !ok) {
+			t.Errorf("Valid [%d]: expected valid %t for %s, got %v", index, test.valid, test.in, err)
+		}
+	}
+
+	if err := NewITMCoord(715830, 734697, 0).Valid(); err != nil {
+		t.Errorf("Valid: expected a valid ITM coordinate, got %v", err)
+	}
+	if _, ok := NewITMCoord(715830, 1500000, 0).Valid().(cartconvert.ExtentError); !ok {
+		t.Error("Valid: expected an ExtentError for an ITM coordinate north of Ireland")
+	}
+}
+
+// ## RoundTrip
+// The positional error in meters of a latitude / longitude coordinate converted into an Irish Grid reference and into an ITM coordinate and back
+type roundTripTest struct {
+	gc        *cartconvert.PolarCoord
+	threshold float64
+}
+
+// The Irish Grid reference is rounded to the meter, which limits the round trip to 0.71 m, ITM is kept exact
+var roundTripTests = []roundTripTest{
+	{&cartconvert.PolarCoord{Latitude: 53.3498, Longitude: -6.2603, El: cartconvert.WGS84Ellipsoid}, 1}, // Dublin
+	{&cartconvert.PolarCoord{Latitude: 51.8985, Longitude: -8.4756, El: cartconvert.WGS84Ellipsoid}, 1},
+	{&cartconvert.PolarCoord{Latitude: 54.5973, Longitude: -5.9301, El: cartconvert.WGS84Ellipsoid}, 1},
+}
+
+func TestRoundTrip(t *testing.T) {
+	for index, test := range roundTripTests {
+		ig, err := WGS84LatLongToIrishGrid(test.gc)
+		if err != nil {
+			t.Fatalf("WGS84LatLongToIrishGrid [%d]: %s", index, err)
+		}
+		if d := cartconvert.Haversine(test.gc, IrishGridToWGS84LatLong(ig), 0); d > test.threshold {
+			t.Errorf("RoundTrip [%d]: expected a positional error below %g m, got %.3f m", index, test.threshold, d)
+		}
+
+		if d := cartconvert.Haversine(test.gc, ITMToWGS84LatLong(WGS84LatLongToITM(test.gc)), 0); d > 0.001 {
+			t.Errorf("RoundTrip [%d]: expected a positional error of ITM below 0.001 m, got %.3f m", index, d)
+		}
+	}
+}
+
+// ## EPSG
+func TestEPSG(t *testing.T) {
+	gc := &cartconvert.PolarCoord{Latitude: 53.3498, Longitude: -6.2603, El: cartconvert.WGS84Ellipsoid}
+
+	ig, err := WGS84LatLongToIrishGrid(gc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	easting, northing := IrishGridZoneToRefCoords(ig)
+	itm := WGS84LatLongToITM(gc)
+
+	// the registered coordinate reference systems project on TM75 and IRENET95, which differ from WGS84 by less than 0.01°
+	for _, test := range []struct {
+		epsg int
+		gp   *cartconvert.GeoPoint
+	}{
+		{ig.EPSG(), &cartconvert.GeoPoint{X: float64(easting), Y: float64(northing)}},
+		{itm.EPSG(), &cartconvert.GeoPoint{X: itm.Easting, Y: itm.Northing}},
+	} {
+		crs, err := cartconvert.ByEPSG(test.epsg)
+		if err != nil {
+			t.Fatalf("ByEPSG: expected the CRS of EPSG:%d, got %v", test.epsg, err)
+		}
+		out, err := crs.InverseProject(test.gp)
+		if err != nil || math.Abs(out.Latitude-gc.Latitude) > 0.01 || math.Abs(out.Longitude-gc.Longitude) > 0.01 {
+			t.Errorf("CRS.InverseProject: expected %s near %s, got %v (%v)", crs.Name, gc, out, err)
+		}
+	}
+}
+
+// ## NaN and infinity
+func TestNonFinite(t *testing.T) {
+	for _, value := range []float64{math.NaN(), math.Inf(1)} {
+		if _, err := AITMToStruct(fmt.Sprintf("%v 734698", value)); err != cartconvert.ErrNotFinite {
+			t.Errorf("AITMToStruct (%v): expected ErrNotFinite, got %v", value, err)
+		}
+		if _, err := WGS84LatLongToIrishGrid(&cartconvert.PolarCoord{Latitude: 53.35, Longitude: value}); err != cartconvert.ErrNotFinite {
+			t.Errorf("WGS84LatLongToIrishGrid (%v): expected ErrNotFinite, got %v", value, err)
+		}
+	}
+}
+
+// ## Snap
+func TestSnap(t *testing.T) {
+	out := (&ITMCoord{Easting: 715830.7, Northing: 734697.2}).Snap(10)
+	if out.Easting != 715830 || out.Northing != 734700 {
+		t.Errorf("ITMCoord.Snap: expected 715830 734700, got %s", out)
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// This package provides functions to deal with conversion of coordinates of Lambert-93, the national grid of
+// metropolitan France of the RGF93 datum.
+//
+// Lambert-93 is a Lambert Conformal Conic projection of the GRS80 ellipsoid with the standard parallels 44° and 49°
+// and the origin at 3°E 46.5°N, which is mapped to easting 700000 m and northing 6600000 m. RGF93 is taken to be
+// identical to WGS84, the two differ by some centimeters, so no datum shift is applied.
+//
+// For further info see https://geodesie.ign.fr/contenu/fichiers/documentation/rgf93/Lambert-93.pdf
+package lambert93
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/the42/cartconvert/cartconvert"
+	"strconv"
+	"strings"
+)
+
+// Geographic extent of Lambert-93, metropolitan France including Corsica
+var Extent = &cartconvert.LatLongExtent{MinLat: 41.2, MaxLat: 51.2, MinLong: -5.5, MaxLong: 10}
+
+// Parameters of the projection: standard parallels, latitude and longitude of the origin in decimal degrees,
+// false easting and northing in meters
+const (
+	lat1, lat2    = 44, 49
+	latO, longO   = 46.5, 3
+	falseEasting  = 700000
+	falseNorthing = 6600000
+)
+
+// The EPSG code of RGF93 / Lambert-93. The Lambert Conformal Conic projection is not implemented by
+// cartconvert.CRS, so the system is not registered.
+const EPSG = 2154
+
+// A Lambert-93 coordinate is specified by easting and northing in meters
+type L93Coord struct {
+	Easting   float64                `json:"easting" xml:"easting"`
+	Northing  float64                `json:"northing" xml:"northing"`
+	RelHeight float64                `json:"relheight" xml:"relheight"`
+	El        *cartconvert.Ellipsoid `json:"ellipsoid,omitempty" xml:"ellipsoid,omitempty"`
+}
+
+// Canonical representation of a Lambert-93 coordinate, easting and northing to the centimeter, like
+// "648235.9 6862268.37"
+func (coord *L93Coord) String() string {
+	return cartconvert.FormatTrimmed(coord.Easting, 2) + " " + cartconvert.FormatTrimmed(coord.Northing, 2)
+}
+
+// Implements cartconvert.Coordinate, the system of a Lambert-93 coordinate is "lambert93"
+func (coord *L93Coord) System() string {
+	return "lambert93"
+}
+
+// Implements cartconvert.Coordinate, see L93ToWGS84LatLong
+func (coord *L93Coord) ToWGS84() (*cartconvert.PolarCoord, error) {
+	return L93ToWGS84LatLong(coord), nil
+}
+
+// Representation of a Lambert-93 coordinate with precision decimal places of easting and northing, see
+// cartconvert.FormatFixed
+func (coord *L93Coord) Format(precision int) string {
+	return fmt.Sprintf("%s %s", cartconvert.FormatFixed(coord.Easting, precision), cartconvert.FormatFixed(coord.Northing, precision))
+}
+
+// Returns a copy of the Lambert 93 coordinate with easting and northing snapped to the nearest multiple of
+// spacingMeters, see cartconvert.SnapToGrid
+func (coord *L93Coord) Snap(spacingMeters float64) *L93Coord {
+	snapped := *coord
+	snapped.Easting, snapped.Northing = cartconvert.SnapToGrid(coord.Easting, spacingMeters), cartconvert.SnapToGrid(coord.Northing, spacingMeters)
+	return &snapped
+}
+
+// Implements json.Marshaler, writing the coordinates in fixed-point notation
+func (coord *L93Coord) MarshalJSON() ([]byte, error) {
+	return cartconvert.MarshalFixedJSON(coord)
+}
+
+// Implements xml.Marshaler, writing the coordinates in fixed-point notation
+func (coord *L93Coord) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return cartconvert.MarshalFixedXML(e, start, coord)
+}
+
+// Validates that the Lambert-93 coordinate lies within Extent, metropolitan France. Returns a
+// cartconvert.ExtentError, if easting and northing transform to a location outside of Extent.
+func (coord *L93Coord) Valid() error {
+	gc := L93ToWGS84LatLong(coord)
+	return Extent.Validate(gc, "Lambert-93", coord.String())
+}
+
+// Returns the EPSG code of Lambert-93
+func (coord *L93Coord) EPSG() int {
+	return EPSG
+}
+
+// Parses a string representation of a Lambert-93 coordinate, easting and northing in meters separated by blanks,
+// like
+//
+//	648235.9 6862268.37
+//
+// The reference ellipsoid of a Lambert-93 coordinate will always be set to the GRS80 ellipsoid.
+//
+// returns cartconvert.ErrSyntax if the literal doesn't consist of easting and northing
+func AL93ToStruct(l93coord string) (*L93Coord, error) {
+
+	fields := strings.Fields(l93coord)
+	if len(fields) != 2 {
+		return nil, cartconvert.ErrSyntax
+	}
+
+	easting, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return nil, err
+	}
+	northing, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return nil, err
+	}
+	if err = cartconvert.CheckFinite(easting, northing); err != nil {
+		return nil, err
+	}
+	return NewL93Coord(easting, northing, 0), nil
+}
+
+// Convert a Lambert-93 coordinate value to a WGS84 based latitude and longitude coordinate. RGF93 is taken as
+// WGS84. The height above sea level RelHeight is not converted.
+func L93ToWGS84LatLong(coord *L93Coord) *cartconvert.PolarCoord {
+	gc := cartconvert.InverseLambertConformalConic(
+		&cartconvert.GeoPoint{X: coord.Easting, Y: coord.Northing, El: cartconvert.GRS80Ellipsoid},
+		lat1, lat2, latO, longO, falseEasting, falseNorthing)
+	gc.El = cartconvert.WGS84Ellipsoid
+	return gc
+}
+
+// Transform a latitude / longitude coordinate into a Lambert-93 coordinate. WGS84 is taken as RGF93. The height
+// of gc is not converted.
+//
+// Important: The reference ellipsoid of the originating coordinate system will be assumed
+// to be the WGS84Ellipsoid, regardless of the actually set reference ellipsoid.
+func WGS84LatLongToL93(gc *cartconvert.PolarCoord) *L93Coord {
+	gp := cartconvert.DirectLambertConformalConic(
+		&cartconvert.PolarCoord{Latitude: gc.Latitude, Longitude: gc.Longitude, El: cartconvert.GRS80Ellipsoid},
+		lat1, lat2, latO, longO, falseEasting, falseNorthing)
+	return NewL93Coord(gp.X, gp.Y, 0)
+}
+
+func NewL93Coord(easting, northing, relheight float64) *L93Coord {
+	return &L93Coord{Easting: easting, Northing: northing, RelHeight: relheight, El: cartconvert.GRS80Ellipsoid}
+}
+
+// Returns reference points of Lambert-93, for tests and documentation, among them the origin of the projection
+func Examples() []cartconvert.Example {
+	return []cartconvert.Example{
+		{Name: "Eiffel Tower, Paris", Input: "648235.90 6862268.37", LatLong: &cartconvert.PolarCoord{Latitude: 48.8583701, Longitude: 2.2944813, El: cartconvert.WGS84Ellipsoid}, Tolerance: 0.01},
+		{Name: "Origin of the projection", Input: "700000 6600000", LatLong: &cartconvert.PolarCoord{Latitude: 46.5, Longitude: 3, El: cartconvert.WGS84Ellipsoid}, Tolerance: 0.01},
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// Automated tests for the cartconvert/lambert93 package
+package lambert93
+
+import (
+	"fmt"
+	"github.com/the42/cartconvert/cartconvert"
+	"math"
+	"testing"
+)
+
+// ## AL93ToStruct
+func TestAL93ToStruct(t *testing.T) {
+	out, err := AL93ToStruct(" 648235.9  6862268.37 ")
+	if err != nil || fmt.Sprintf("%.2f %.2f", out.Easting, out.Northing) != "648235.90 6862268.37" {
+		t.Errorf("AL93ToStruct: expected 648235.90 6862268.37, got %v (%v)", out, err)
+	}
+	if _, err = AL93ToStruct("648235.9"); err != cartconvert.ErrSyntax {
+		t.Errorf("AL93ToStruct: expected ErrSyntax, got %v", err)
+	}
+	if _, err = AL93ToStruct("E648235 6862268"); err == nil {
+		t.Error("AL93ToStruct: expected error on malformed easting")
+	}
+}
+
+// ## String, Format
+func TestL93CoordString(t *testing.T) {
+	coord := NewL93Coord(648235.9, 6862268.3749, 0)
+	if s := coord.String(); s != "648235.9 6862268.37" {
+		t.Errorf("L93Coord.String: expected 648235.9 6862268.37, got %s", s)
+	}
+	if s := coord.Format(3); s != "648235.900 6862268.375" {
+		t.Errorf("L93Coord.Format: expected 648235.900 6862268.375, got %s", s)
+	}
+}
+
+// ## WGS84LatLongToL93, L93ToWGS84LatLong
+type wGS84LatLongToL93Test struct {
+	gc                *cartconvert.PolarCoord
+	easting, northing float64
+}
+
+// Computed by the closed formulas of IGN (NT/G 71) with the constants of Lambert-93 published by IGN,
+// n = 0.7256077650532670, c = 11754255.426096, Xs = 700000, Ys = 12655612.049876
+var wGS84LatLongToL93Tests = []wGS84LatLongToL93Test{
+	// the origin
+	{&cartconvert.PolarCoord{Latitude: 46.5, Longitude: 3, El: cartconvert.WGS84Ellipsoid}, 700000, 6600000},
+	// Eiffel tower, Paris
+	{&cartconvert.PolarCoord{Latitude: 48.8583701, Longitude: 2.2944813, El: cartconvert.WGS84Ellipsoid}, 648235.900, 6862268.369},
+	// Toulouse
+	{&cartconvert.PolarCoord{Latitude: 43.6045, Longitude: 1.444, El: cartconvert.WGS84Ellipsoid}, 574341.233, 6279621.067},
+	// Dunkirk
+	{&cartconvert.PolarCoord{Latitude: 51, Longitude: 2, El: cartconvert.WGS84Ellipsoid}, 629650.134, 7100910.055},
+	// Corsica
+	{&cartconvert.PolarCoord{Latitude: 42.5, Longitude: 9, El: cartconvert.WGS84Ellipsoid}, 1193433.198, 6174330.974},
+}
+
+func TestWGS84LatLongToL93(t *testing.T) {
+	for index, test := range wGS84LatLongToL93Tests {
+		out := WGS84LatLongToL93(test.gc)
+		if math.Abs(out.Easting-test.easting) > 0.01 || math.Abs(out.Northing-test.northing) > 0.01 {
+			t.Errorf("WGS84LatLongToL93 [%d]: expected %.3f %.3f, got %.3f %.3f", index, test.easting, test.northing, out.Easting, out.Northing)
+		}
+
+		back := L93ToWGS84LatLong(out)
+		if math.Hypot(back.Latitude-test.gc.Latitude, back.Longitude-test.gc.Longitude) > 1e-9 {
+			t.Errorf("L93ToWGS84LatLong [%d]: expected %.9f %.9f, got %.9f %.9f", index, test.gc.Latitude, test.gc.Longitude, back.Latitude, back.Longitude)
+		}
+		if back.El != cartconvert.WGS84Ellipsoid {
+			t.Errorf("L93ToWGS84LatLong [%d]: expected the WGS84 ellipsoid, got %v", index, back.El)
+		}
+	}
+}
+
+// ## DirectLambertConformalConic, InverseLambertConformalConic
+//
+// The test values of the algorithms ALG0003 and ALG0004 published by IGN in NT/G 71, "Projection cartographique
+// conique conforme de Lambert", for Lambert I of NTF on the Clarke 1880 IGN ellipsoid: e = 0.0824832568,
+// n = 0.760405966, C = 11603796.9767, λc = 0.04079234433 rad, Xs = 600000 m and Ys = 5657616.674 m. The cone is
+// given by the standard parallels of Lambert I, 48°35'54.682" and 50°23'45.282", which IGN publishes to the
+// thousandth of a second of arc, the latitude of origin 49.5° and the false northing 200000 m. The rounding of the
+// parallels shifts the projection by some millimeters.
+type nTG71Test struct {
+	name      string
+	lat, long float64 // radians
+	x, y      float64
+}
+
+var nTG71Tests = []nTG71Test{
+	{"ALG0003", 0.872664626, 0.145512099, 1029705.0818, 272723.8510},
+	{"ALG0004", 0.87266462567, 0.14551209925, 1029705.083, 272723.849},
+}
+
+func TestNTG71(t *testing.T) {
+	clarke1880IGN := cartconvert.NewEllipsoid(6378249.2, 6356515.0, "Clarke1880IGN")
+	dms := func(d, m, s float64) float64 { return d + m/60 + s/3600 }
+	lat1, lat2, longO := dms(48, 35, 54.682), dms(50, 23, 45.282), 0.04079234433*180/math.Pi
+
+	for _, test := range nTG71Tests {
+		gc := &cartconvert.PolarCoord{Latitude: test.lat * 180 / math.Pi, Longitude: test.long * 180 / math.Pi, El: clarke1880IGN}
+		out := cartconvert.DirectLambertConformalConic(gc, lat1, lat2, 49.5, longO, 600000, 200000)
+		if math.Abs(out.X-test.x) > 0.005 || math.Abs(out.Y-test.y) > 0.005 {
+			t.Errorf("DirectLambertConformalConic %s: expected %.4f %.4f, got %.4f %.4f", test.name, test.x, test.y, out.X, out.Y)
+		}
+
+		back := cartconvert.InverseLambertConformalConic(&cartconvert.GeoPoint{X: test.x, Y: test.y, El: clarke1880IGN}, lat1, lat2, 49.5, longO, 600000, 200000)
+		// 1e-9 radians are about 6 millimeters
+		if math.Abs(back.Latitude*math.Pi/180-test.lat) > 1e-9 || math.Abs(back.Longitude*math.Pi/180-test.long) > 1e-9 {
+			t.Errorf("InverseLambertConformalConic %s: expected %.11f %.11f, got %.11f %.11f", test.name, test.lat, test.long, back.Latitude*math.Pi/180, back.Longitude*math.Pi/180)
+		}
+	}
+}
+
+// ## Valid
+func TestValid(t *testing.T) {
+	if err := NewL93Coord(648236, 6862268, 0).Valid(); err != nil {
+		t.Errorf("Valid: expected a valid Lambert-93 coordinate, got %v", err)
+	}
+	if _, ok := NewL93Coord(648236, 7800000, 0).Valid().(cartconvert.ExtentError); !ok {
+		t.Error("Valid: expected an ExtentError for a Lambert-93 coordinate north of France")
+	}
+}
+
+// ## EPSG
+func TestEPSG(t *testing.T) {
+	if code := NewL93Coord(700000, 6600000, 0).EPSG(); code != 2154 {
+		t.Errorf("EPSG: expected 2154, got %d", code)
+	}
+}
+
+// ## NaN and infinity
+func TestNonFinite(t *testing.T) {
+	for _, value := range []float64{math.NaN(), math.Inf(1)} {
+		if _, err := AL93ToStruct(fmt.Sprintf("648235.9 %v", value)); err != cartconvert.ErrNotFinite {
+			t.Errorf("AL93ToStruct (%v): expected ErrNotFinite, got %v", value, err)
+		}
+	}
+}
+
+// ## Snap
+func TestSnap(t *testing.T) {
+	out := (&L93Coord{Easting: 648235.9, Northing: 6862250}).Snap(500)
+	if out.Easting != 648000 || out.Northing != 6862000 {
+		t.Errorf("L93Coord.Snap: expected 648000 6862000, got %s", out)
+	}
+}
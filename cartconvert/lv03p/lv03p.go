@@ -9,13 +9,17 @@
 // generall below one meter and ignored by this package. For accuracy within 1cm the
 //  FINELTRA-Transformation has to be applied.
 //
+// Besides the rigorous transformation by projection and datum shift, the package provides the approximate
+// formulas published by swisstopo, which transform between WGS84 and the Swiss coordinates directly with
+// an accuracy of about one meter.
+//
 // References:
 //
 // [DE]: http://www.swisstopo.admin.ch/internet/swisstopo/de/home/topics/survey/sys/refsys/switzerland.parsysrelated1.24280.downloadList.32633.DownloadFile.tmp/refsysd.pdf
 package lv03p
 
 import (
-	"fmt"
+	"encoding/xml"
 	"github.com/the42/cartconvert/cartconvert"
 	"strconv"
 	"strings"
@@ -29,43 +33,102 @@ const (
 	LV95
 )
 
+// Geographic extent of the Swiss coordinate system, the territory of Switzerland and Liechtenstein
+var Extent = &cartconvert.LatLongExtent{MinLat: 45.8, MaxLat: 47.9, MinLong: 5.9, MaxLong: 10.6}
+
 // A coordinate in Switzerland is specified by easting (right-value, x), and northing (height-value, y)
 type SwissCoord struct {
-	Easting, Northing, RelHeight float64
-	CoordType                    SwissCoordType
-	El                           *cartconvert.Ellipsoid
+	Easting   float64                `json:"easting" xml:"easting"`
+	Northing  float64                `json:"northing" xml:"northing"`
+	RelHeight float64                `json:"relheight" xml:"relheight"`
+	CoordType SwissCoordType         `json:"coordtype" xml:"coordtype"`
+	El        *cartconvert.Ellipsoid `json:"ellipsoid,omitempty" xml:"ellipsoid,omitempty"`
+	// Approximate positional error in meters introduced by the conversion from WGS84 by the approximate
+	// formulas of swisstopo. Zero for coordinates not converted from WGS84.
+	Accuracy float64 `json:"accuracy,omitempty" xml:"accuracy,omitempty"`
 }
 
+// The accuracy of the approximate formulas of swisstopo converting between WGS84 and the Swiss coordinates
+const approxAccuracy = 1
+
 var coordliterals = [][]string{{"y:", " x:"}, {"E:", " N:"}}
 
 // Canonical representation of a SwissCoord-value
 func (bc *SwissCoord) String() (fs string) {
 
-	var next float64
+	if bc == nil {
+		return
+	}
+	literals := coordliterals[bc.CoordType]
+	return literals[0] + cartconvert.FormatTrimmed(bc.Easting, 6) + literals[1] + cartconvert.FormatTrimmed(bc.Northing, 6)
+}
+
+// Implements cartconvert.Coordinate, the system of a SwissCoord-value is "lv03" or "lv95", by its type
+func (bc *SwissCoord) System() string {
+	if bc.CoordType == LV95 {
+		return "lv95"
+	}
+	return "lv03"
+}
+
+// Implements cartconvert.Coordinate, see SwissCoordToWGS84LatLong
+func (bc *SwissCoord) ToWGS84() (*cartconvert.PolarCoord, error) {
+	return SwissCoordToWGS84LatLong(bc)
+}
+
+// Representation of a SwissCoord-value with precision decimal places of easting and northing, see
+// cartconvert.FormatFixed
+func (bc *SwissCoord) Format(precision int) (fs string) {
 
 	if bc == nil {
 		return
 	}
-	for i := 0; i < 2; i++ {
-		fs += coordliterals[bc.CoordType][i]
-		switch i {
-		case 0:
-			next = bc.Easting
-		case 1:
-			next = bc.Northing
-		}
+	literals := coordliterals[bc.CoordType]
+	return literals[0] + cartconvert.FormatFixed(bc.Easting, precision) + literals[1] + cartconvert.FormatFixed(bc.Northing, precision)
+}
 
-		tmp := fmt.Sprintf("%f", next)
-		n := len(tmp)
-		for n > 0 && tmp[n-1] == '0' {
-			n--
-		}
-		if n > 0 && tmp[n-1] == '.' {
-			n--
-		}
-		fs = fs + tmp[:n]
+// Returns a copy of the Swiss coordinate with easting and northing snapped to the nearest multiple of
+// spacingMeters, see cartconvert.SnapToGrid. The type of the coordinate, LV03 or LV95, is kept.
+func (bc *SwissCoord) Snap(spacingMeters float64) *SwissCoord {
+	snapped := *bc
+	snapped.Easting, snapped.Northing = cartconvert.SnapToGrid(bc.Easting, spacingMeters), cartconvert.SnapToGrid(bc.Northing, spacingMeters)
+	return &snapped
+}
+
+// Implements json.Marshaler, writing the coordinates in fixed-point notation
+func (bc *SwissCoord) MarshalJSON() ([]byte, error) {
+	return cartconvert.MarshalFixedJSON(bc)
+}
+
+// Implements xml.Marshaler, writing the coordinates in fixed-point notation
+func (bc *SwissCoord) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return cartconvert.MarshalFixedXML(e, start, bc)
+}
+
+// Validates that the Swiss coordinate lies within Extent, the territory of Switzerland and Liechtenstein. Returns the
+// error of SwissCoordToWGS84LatLong, if the coordinate type is invalid, or a cartconvert.ExtentError, if easting and
+// northing transform to a location outside of Extent.
+func (bc *SwissCoord) Valid() error {
+	gc, err := SwissCoordToWGS84LatLong(bc)
+	if err != nil {
+		return err
+	}
+	return Extent.Validate(gc, "Swiss", bc.String())
+}
+
+// EPSG codes of the Swiss coordinate systems, CH1903 / LV03 and CH1903+ / LV95. The oblique Mercator projection
+// of Switzerland is not implemented by cartconvert.CRS, so the systems are not registered.
+const (
+	EPSGLV03 = 21781
+	EPSGLV95 = 2056
+)
+
+// Returns the EPSG code of the coordinate type of the Swiss coordinate
+func (bc *SwissCoord) EPSG() int {
+	if bc.CoordType == LV95 {
+		return EPSGLV95
 	}
-	return
+	return EPSGLV03
 }
 
 // Parses a string representation of a LV++ coordinate into a struct holding a SwissCoord coordinate value.
@@ -85,6 +148,11 @@ L1:
 			index = len(compact)
 		}
 
+		if index < 2 {
+			err = cartconvert.ErrSyntax
+			break L1
+		}
+
 		switch compact[:2] {
 		case "X:":
 			coordType = LV03
@@ -103,7 +171,8 @@ L1:
 			break L1
 		}
 
-		if oldcoordType != coordType {
+		// both axes have to be labeled of the same coordinate type
+		if i == 1 && oldcoordType != coordType {
 			err = cartconvert.ErrSyntax
 			break L1
 		}
@@ -111,6 +180,12 @@ L1:
 		if i == 1 {
 			break L1
 		}
+
+		// the second axis is missing
+		if index == len(compact) {
+			err = cartconvert.ErrSyntax
+			break L1
+		}
 		compact = compact[index+len(" "):]
 		compact = strings.TrimLeft(compact, " ")
 		oldcoordType = coordType
@@ -123,7 +198,9 @@ L1:
 
 			height, err = strconv.ParseFloat(heights, 64)
 			if err == nil {
-				return &SwissCoord{Easting: right, Northing: height, CoordType: coordType, El: cartconvert.Bessel1841Ellipsoid}, nil
+				if err = cartconvert.CheckFinite(right, height); err == nil {
+					return &SwissCoord{Easting: right, Northing: height, CoordType: coordType, El: cartconvert.Bessel1841Ellipsoid}, nil
+				}
 			}
 		}
 	}
@@ -147,6 +224,9 @@ func SwissCoordToGRS80LatLong(coord *SwissCoord) (*cartconvert.PolarCoord, error
 	default:
 		return nil, cartconvert.ErrRange
 	}
+	if err := cartconvert.CheckFinite(coord.Easting, coord.Northing); err != nil {
+		return nil, err
+	}
 
 	gc := cartconvert.InverseTransverseMercator(
 		&cartconvert.GeoPoint{Y: coord.Northing, X: coord.Easting, El: coord.El},
@@ -175,6 +255,10 @@ func GRS80LatLongToSwissCoord(gc *cartconvert.PolarCoord, coordType SwissCoordTy
 
 	var fn, fe float64
 
+	if err := gc.CheckFinite(); err != nil {
+		return nil, err
+	}
+
 	// This sets the Ellipsoid to GRS80, regardless of the actual value set
 	gc.El = cartconvert.GRS80Ellipsoid
 
@@ -208,6 +292,80 @@ func GRS80LatLongToSwissCoord(gc *cartconvert.PolarCoord, coordType SwissCoordTy
 	return &SwissCoord{CoordType: coordType, Northing: gp.Y, Easting: gp.X, El: gp.El}, nil
 }
 
+// Transform a WGS84 latitude / longitude coordinate into a Swiss coordinate by the approximate formulas
+// published by swisstopo. The accuracy is about one meter. The ellipsoidal height of gc is converted into the height
+// above sea level RelHeight with an accuracy of some decimeters. Function returns cartconvert.ErrRange,
+// if the coordinate type is not one of LV03 or LV95.
+func WGS84LatLongToSwissCoord(gc *cartconvert.PolarCoord, coordType SwissCoordType) (*SwissCoord, error) {
+
+	var fn, fe float64
+
+	switch coordType {
+	case LV03:
+	case LV95:
+		fe = 2000000
+		fn = 1000000
+	default:
+		return nil, cartconvert.ErrRange
+	}
+	if err := gc.CheckFinite(); err != nil {
+		return nil, err
+	}
+
+	// auxiliary values relative to the old observatory of Bern in units of 10000"
+	phi := (gc.Latitude*3600 - 169028.66) / 10000
+	lambda := (gc.Longitude*3600 - 26782.5) / 10000
+
+	y := 600072.37 + 211455.93*lambda - 10938.51*lambda*phi - 0.36*lambda*phi*phi - 44.54*lambda*lambda*lambda
+	x := 200147.07 + 308807.95*phi + 3745.25*lambda*lambda + 76.63*phi*phi - 194.56*lambda*lambda*phi + 119.79*phi*phi*phi
+	h := gc.Height - 49.55 + 2.73*lambda + 6.94*phi
+
+	return &SwissCoord{CoordType: coordType, Easting: y + fe, Northing: x + fn, RelHeight: h, El: cartconvert.Bessel1841Ellipsoid, Accuracy: approxAccuracy}, nil
+}
+
+// Transform a Swiss coordinate into a WGS84 latitude / longitude coordinate by the approximate formulas
+// published by swisstopo. The accuracy is about one meter. The height above sea level RelHeight is converted
+// into the ellipsoidal height. Function returns cartconvert.ErrRange, if the swiss coordinate type is not one of LV03 or LV95.
+func SwissCoordToWGS84LatLong(coord *SwissCoord) (*cartconvert.PolarCoord, error) {
+
+	var fn, fe float64
+
+	switch coord.CoordType {
+	case LV03:
+		fe = 600000
+		fn = 200000
+	case LV95:
+		fe = 2600000
+		fn = 1200000
+	default:
+		return nil, cartconvert.ErrRange
+	}
+	if err := cartconvert.CheckFinite(coord.Easting, coord.Northing); err != nil {
+		return nil, err
+	}
+
+	// auxiliary values relative to the projection center in units of 1000 km
+	y := (coord.Easting - fe) / 1000000
+	x := (coord.Northing - fn) / 1000000
+
+	// in units of 10000"
+	lambda := 2.6779094 + 4.728982*y + 0.791484*y*x + 0.1306*y*x*x - 0.0436*y*y*y
+	phi := 16.9023892 + 3.238272*x - 0.270978*y*y - 0.002528*x*x - 0.0447*y*y*x - 0.0140*x*x*x
+	h := coord.RelHeight + 49.55 - 12.60*y - 22.64*x
+
+	return &cartconvert.PolarCoord{Latitude: phi * 100 / 36, Longitude: lambda * 100 / 36, Height: h, El: cartconvert.WGS84Ellipsoid}, nil
+}
+
 func NewSwissCoord(CoordType SwissCoordType, Easting, Northing, RelHeight float64) *SwissCoord {
 	return &SwissCoord{Easting: Easting, Northing: Northing, RelHeight: RelHeight, CoordType: CoordType, El: cartconvert.Bessel1841Ellipsoid}
 }
+
+// Returns reference points of LV03 and LV95, for tests and documentation: the origin of the grid at the old
+// observatory of Bern and the worked example of swisstopo, whose tolerance covers the approximate formulas.
+func Examples() []cartconvert.Example {
+	return []cartconvert.Example{
+		{Name: "Old observatory, Bern", Input: "y:600000 x:200000", LatLong: &cartconvert.PolarCoord{Latitude: 46.9510811, Longitude: 7.4386372, El: cartconvert.WGS84Ellipsoid}, Tolerance: 1},
+		{Name: "Example of swisstopo, LV03", Input: "y:700000 x:100000", LatLong: &cartconvert.PolarCoord{Latitude: 46 + 2.0/60 + 38.86/3600, Longitude: 8 + 43.0/60 + 49.80/3600, El: cartconvert.WGS84Ellipsoid}, Tolerance: 1},
+		{Name: "Example of swisstopo, LV95", Input: "E:2700000 N:1100000", LatLong: &cartconvert.PolarCoord{Latitude: 46 + 2.0/60 + 38.86/3600, Longitude: 8 + 43.0/60 + 49.80/3600, El: cartconvert.WGS84Ellipsoid}, Tolerance: 1},
+	}
+}
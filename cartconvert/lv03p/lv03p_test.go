@@ -21,6 +21,12 @@ var swissCoordRepresentationTest = []swissCoordRepresentation{
 	{
 		SwissCoord{Easting: 235.5, Northing: 20.0, CoordType: LV03}, "y:235.5 x:20",
 	},
+	{
+		SwissCoord{Easting: 2600000, Northing: 1199999.9999996, CoordType: LV95}, "E:2600000 N:1200000",
+	},
+	{
+		SwissCoord{Easting: -12.0000004, Northing: -0.0000001, CoordType: LV03}, "y:-12 x:0",
+	},
 }
 
 func TestSwissCoordRepresentation(t *testing.T) {
@@ -33,6 +39,13 @@ func TestSwissCoordRepresentation(t *testing.T) {
 	}
 }
 
+func TestSwissCoordFormat(t *testing.T) {
+	coord := &SwissCoord{Easting: 600000.125, Northing: 199999.996, CoordType: LV03}
+	if out := coord.Format(2); out != "y:600000.12 x:200000.00" {
+		t.Errorf("SwissCoord.Format: expected y:600000.12 x:200000.00, got %s", out)
+	}
+}
+
 // ## ASwissCoordToStruct
 type aSwissCoordToStructretparam struct {
 	coord *SwissCoord
@@ -60,9 +73,26 @@ var aSwissCoordToStructTests = []aSwissCoordToStruct{
 	{
 		in: "x:25 y:34.3", out: aSwissCoordToStructretparam{coord: &SwissCoord{Easting: 34.3, Northing: 25, CoordType: LV03}, err: nil},
 	},
+	{
+		in: "E:2600000 N:1200000", out: aSwissCoordToStructretparam{coord: &SwissCoord{Easting: 2600000, Northing: 1200000, CoordType: LV95}, err: nil},
+	},
 	{
 		in: "x:25.0 N:34.3", out: aSwissCoordToStructretparam{coord: nil, err: cartconvert.ErrSyntax},
 	},
+	{
+		in: "N:34.3 x:25.0", out: aSwissCoordToStructretparam{coord: nil, err: cartconvert.ErrSyntax},
+	},
+	// literals too short to carry the labels of the axes
+	{
+		in: "x", out: aSwissCoordToStructretparam{coord: nil, err: cartconvert.ErrSyntax},
+	},
+	{
+		in: "x:25 y", out: aSwissCoordToStructretparam{coord: nil, err: cartconvert.ErrSyntax},
+	},
+	// a single labeled axis
+	{
+		in: "E:2600000", out: aSwissCoordToStructretparam{coord: nil, err: cartconvert.ErrSyntax},
+	},
 }
 
 func aswisscoordtostructequal(coord1, coord2 aSwissCoordToStructretparam) bool {
@@ -148,3 +178,164 @@ func TestGRS80LatLongToSwissCoord(t *testing.T) {
 		}
 	}
 }
+
+// ## WGS84LatLongToSwissCoord
+type wGS84LatLongToSwissCoordTest struct {
+	in        *cartconvert.PolarCoord
+	coordType SwissCoordType
+	out       *SwissCoord
+}
+
+// Examples of the approximate formulas by swisstopo, http://www.swisstopo.admin.ch/internet/swisstopo/en/home/topics/survey/sys/refsys/switzerland.html
+var wGS84LatLongToSwissCoordTests = []wGS84LatLongToSwissCoordTest{
+	{
+		&cartconvert.PolarCoord{Latitude: 46 + 2.0/60 + 38.87/3600, Longitude: 8 + 43.0/60 + 49.79/3600, Height: 650.60, El: cartconvert.WGS84Ellipsoid},
+		LV03,
+		NewSwissCoord(LV03, 699999.76, 99999.97, 600.05),
+	},
+	{
+		&cartconvert.PolarCoord{Latitude: 46 + 2.0/60 + 38.87/3600, Longitude: 8 + 43.0/60 + 49.79/3600, Height: 650.60, El: cartconvert.WGS84Ellipsoid},
+		LV95,
+		NewSwissCoord(LV95, 2699999.76, 1099999.97, 600.05),
+	},
+	{
+		&cartconvert.PolarCoord{Latitude: 47.518605, Longitude: 9.437422, El: cartconvert.WGS84Ellipsoid},
+		LV03,
+		NewSwissCoord(LV03, 750536, 265013, -46.17),
+	},
+}
+
+func TestWGS84LatLongToSwissCoord(t *testing.T) {
+	for cnt, test := range wGS84LatLongToSwissCoordTests {
+		out, err := WGS84LatLongToSwissCoord(test.in, test.coordType)
+		if err != nil || out.CoordType != test.out.CoordType ||
+			math.Abs(out.Easting-test.out.Easting) > 0.1 || math.Abs(out.Northing-test.out.Northing) > 0.1 ||
+			math.Abs(out.RelHeight-test.out.RelHeight) > 0.01 {
+			t.Errorf("WGS84LatLongToSwissCoord [%d]: Expected %s %.2f, got %s %v", cnt, test.out, test.out.RelHeight, out, err)
+			continue
+		}
+		if out.Accuracy != 1 {
+			t.Errorf("WGS84LatLongToSwissCoord [%d]: Expected the accuracy of 1 m of the approximate formulas, got %g", cnt, out.Accuracy)
+		}
+	}
+
+	if _, err := WGS84LatLongToSwissCoord(&cartconvert.PolarCoord{Latitude: 47, Longitude: 8}, SwissCoordType(2)); err != cartconvert.ErrRange {
+		t.Errorf("WGS84LatLongToSwissCoord: Expected %v for an unknown coordinate type, got %v", cartconvert.ErrRange, err)
+	}
+}
+
+// ## SwissCoordToWGS84LatLong
+var swissCoordToWGS84LatLongTests = []swissCoordToGRS80LatLongTest{
+	{
+		NewSwissCoord(LV03, 700000, 100000, 600),
+		&cartconvert.PolarCoord{Latitude: 46 + 2.0/60 + 38.86/3600, Longitude: 8 + 43.0/60 + 49.80/3600, Height: 650.55},
+	},
+	{
+		NewSwissCoord(LV95, 2700000, 1100000, 600),
+		&cartconvert.PolarCoord{Latitude: 46 + 2.0/60 + 38.86/3600, Longitude: 8 + 43.0/60 + 49.80/3600, Height: 650.55},
+	},
+}
+
+func TestSwissCoordToWGS84LatLong(t *testing.T) {
+	for cnt, test := range swissCoordToWGS84LatLongTests {
+		out, err := SwissCoordToWGS84LatLong(test.in)
+		// 0.01" of arc are about 0.3 m
+		if err != nil || math.Abs(out.Latitude-test.out.Latitude) > 0.01/3600 || math.Abs(out.Longitude-test.out.Longitude) > 0.01/3600 ||
+			math.Abs(out.Height-test.out.Height) > 0.01 || out.El != cartconvert.WGS84Ellipsoid {
+			t.Errorf("SwissCoordToWGS84LatLong [%d]: Expected %s, got %s %v", cnt, test.out, out, err)
+		}
+	}
+}
+
+type validTest struct {
+	in    *SwissCoord
+	valid bool
+}
+
+var validTests = []validTest{
+	{NewSwissCoord(LV03, 600000, 200000, 0), true},
+	{NewSwissCoord(LV95, 2683000, 1248000, 0), true},
+	// southern Germany
+	{NewSwissCoord(LV03, 600000, 500000, 0), false},
+}
+
+func TestValid(t *testing.T) {
+	for index, test := range validTests {
+		err := test.in.Valid()
+		if _, ok := err.(cartconvert.ExtentError); (err == nil) != test.valid || (err != nil && !ok) {
+			t.Errorf("Valid [%d]: expected valid %t for %s, got %v", index, test.valid, test.in, err)
+		}
+	}
+}
+
+// ## RoundTrip
+// The positional error in meters of a latitude / longitude coordinate converted into a Swiss coordinate and back
+type roundTripTest struct {
+	gc        *cartconvert.PolarCoord
+	threshold float64
+}
+
+// The approximate formulas of swisstopo are no exact inverses and documented with an accuracy of about one meter
+var roundTripTests = []roundTripTest{
+	{&cartconvert.PolarCoord{Latitude: 46.95108, Longitude: 7.43864, El: cartconvert.WGS84Ellipsoid}, 1}, // Bern
+	{&cartconvert.PolarCoord{Latitude: 47.3769, Longitude: 8.5417, El: cartconvert.WGS84Ellipsoid}, 1},
+	{&cartconvert.PolarCoord{Latitude: 46.0037, Longitude: 8.9511, El: cartconvert.WGS84Ellipsoid}, 1},
+}
+
+func TestRoundTrip(t *testing.T) {
+	for index, test := range roundTripTests {
+		for _, coordType := range []SwissCoordType{LV03, LV95} {
+			coord, err := WGS84LatLongToSwissCoord(test.gc, coordType)
+			if err != nil {
+				t.Fatalf("WGS84LatLongToSwissCoord [%d]: %s", index, err)
+			}
+			out, err := SwissCoordToWGS84LatLong(coord)
+			if err != nil {
+				t.Fatalf("SwissCoordToWGS84LatLong [%d]: %s", index, err)
+			}
+			if d := cartconvert.Haversine(test.gc, out, 0); d > test.threshold {
+				t.Errorf("RoundTrip [%d]: expected a positional error below %g m, got %.3f m", index, test.threshold, d)
+			}
+		}
+	}
+}
+
+// ## EPSG
+func TestEPSG(t *testing.T) {
+	if out := NewSwissCoord(LV03, 600000, 200000, 0).EPSG(); out != EPSGLV03 {
+		t.Errorf("EPSG: expected %d, got %d", EPSGLV03, out)
+	}
+	if out := NewSwissCoord(LV95, 2600000, 1200000, 0).EPSG(); out != EPSGLV95 {
+		t.Errorf("EPSG: expected %d, got %d", EPSGLV95, out)
+	}
+}
+
+// ## NaN and infinity
+func TestNonFinite(t *testing.T) {
+	for _, value := range []float64{math.NaN(), math.Inf(1)} {
+		if _, err := ASwissCoordToStruct(fmt.Sprintf("x:%v y:600000", value)); err != cartconvert.ErrNotFinite {
+			t.Errorf("ASwissCoordToStruct (%v): expected ErrNotFinite, got %v", value, err)
+		}
+		if _, err := SwissCoordToGRS80LatLong(NewSwissCoord(LV03, value, 200000, 0)); err != cartconvert.ErrNotFinite {
+			t.Errorf("SwissCoordToGRS80LatLong (%v): expected ErrNotFinite, got %v", value, err)
+		}
+		if _, err := SwissCoordToWGS84LatLong(NewSwissCoord(LV95, 2600000, value, 0)); err != cartconvert.ErrNotFinite {
+			t.Errorf("SwissCoordToWGS84LatLong (%v): expected ErrNotFinite, got %v", value, err)
+		}
+		gc := &cartconvert.PolarCoord{Latitude: value, Longitude: 7.44}
+		if _, err := GRS80LatLongToSwissCoord(gc, LV03); err != cartconvert.ErrNotFinite {
+			t.Errorf("GRS80LatLongToSwissCoord (%v): expected ErrNotFinite, got %v", value, err)
+		}
+		if _, err := WGS84LatLongToSwissCoord(gc, LV95); err != cartconvert.ErrNotFinite {
+			t.Errorf("WGS84LatLongToSwissCoord (%v): expected ErrNotFinite, got %v", value, err)
+		}
+	}
+}
+
+// ## Snap
+func TestSnap(t *testing.T) {
+	out := (&SwissCoord{Easting: 2600000.346, Northing: 1199999.831, CoordType: LV95}).Snap(1)
+	if out.Easting != 2600000 || out.Northing != 1200000 || out.CoordType != LV95 {
+		t.Errorf("SwissCoord.Snap: expected E:2600000 N:1200000, got %s", out)
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// This package provides functions to deal with conversion of Maidenhead locators, the grid squares
+// used by amateur radio operators to denote their position.
+//
+// A Maidenhead locator like JN68rt consists of pairs of characters, each pair giving longitude first and
+// latitude second. The first pair, the field, is given by letters A to R and divides the earth into 20° of
+// longitude by 10° of latitude. The square, given by digits, divides the field into 2° by 1°, the subsquare,
+// given by letters a to x, divides the square into 5' by 2.5' and the extended square, given by digits,
+// divides the subsquare into 30" by 15". The length of the locator sets the precision.
+//
+// Locators refer to WGS84.
+//
+// For further info see http://en.wikipedia.org/wiki/Maidenhead_Locator_System
+package maidenhead
+
+import (
+	"github.com/the42/cartconvert/cartconvert"
+	"math"
+	"strings"
+)
+
+// The precision of a Maidenhead locator as the number of its characters
+type MaidenheadPrec byte
+
+const (
+	MaidenheadField          MaidenheadPrec = 2
+	MaidenheadSquare         MaidenheadPrec = 4
+	MaidenheadSubsquare      MaidenheadPrec = 6
+	MaidenheadExtendedSquare MaidenheadPrec = 8
+)
+
+// The location within the area addressed by a locator, a coordinate gets converted to
+type MaidenheadAnchor byte
+
+const (
+	MaidenheadCenter MaidenheadAnchor = iota
+	MaidenheadSouthWest
+)
+
+// A Maidenhead locator in its canonical notation, with field letters in upper case and subsquare letters
+// in lower case
+type MaidenheadCoord struct {
+	Locator string                 `json:"locator" xml:"locator"`
+	El      *cartconvert.Ellipsoid `json:"ellipsoid,omitempty" xml:"ellipsoid,omitempty"`
+}
+
+// Canonical representation of a Maidenhead locator
+func (coord *MaidenheadCoord) String() string {
+	return coord.Locator
+}
+
+// Implements cartconvert.Coordinate, the system of a Maidenhead locator is "maidenhead"
+func (coord *MaidenheadCoord) System() string {
+	return "maidenhead"
+}
+
+// Implements cartconvert.Coordinate, a locator addresses a square of WGS84 latitude / longitude
+func (coord *MaidenheadCoord) EPSG() int {
+	return cartconvert.EPSGWGS84
+}
+
+// Implements cartconvert.Coordinate, the center of the square of the locator, see MaidenheadToWGS84LatLong
+func (coord *MaidenheadCoord) ToWGS84() (*cartconvert.PolarCoord, error) {
+	return MaidenheadToWGS84LatLong(coord, MaidenheadCenter)
+}
+
+// Returns the precision of the locator
+func (coord *MaidenheadCoord) Prec() MaidenheadPrec {
+	return MaidenheadPrec(len(coord.Locator))
+}
+
+// Longitude and latitude are computed in units of the extended square, 30" of longitude and 15" of latitude.
+// Every pair of characters of a locator is a digit of the given radix and counts the given number of units.
+var pairs = [4]struct {
+	first, last byte
+	units       int
+}{
+	{'A', 'R', 2400},
+	{'0', '9', 240},
+	{'a', 'x', 10},
+	{'0', '9', 1},
+}
+
+// The number of units per degree of longitude and latitude
+const (
+	unitsPerDegreeLong = 120
+	unitsPerDegreeLat  = 240
+)
+
+// Returns whether prec is one of the supported lengths of a locator
+func validPrec(prec MaidenheadPrec) bool {
+	return prec == MaidenheadField || prec == MaidenheadSquare || prec == MaidenheadSubsquare || prec == MaidenheadExtendedSquare
+}
+
+// Parses a string representation of a Maidenhead locator of 2, 4, 6 or 8 characters into a Maidenhead
+// coordinate struct. Letters may be given in upper or lower case.
+//
+// The reference ellipsoid of a Maidenhead locator will always be set to the WGS84 ellipsoid.
+//
+// returns cartconvert.ErrSyntax if the length of the locator is not supported or a letter is given in place
+// of a digit or vice versa
+// returns cartconvert.ErrRange if a field letter is beyond R or a subsquare letter beyond X
+func AMaidenheadToStruct(locator string) (*MaidenheadCoord, error) {
+
+	compact := strings.TrimSpace(locator)
+	if !validPrec(MaidenheadPrec(len(compact))) {
+		return nil, cartconvert.ErrSyntax
+	}
+
+	canonical := make([]byte, len(compact))
+	for i := range compact {
+		pair := pairs[i/2]
+		char := compact[i]
+
+		switch pair.first {
+		case 'A':
+			char = strings.ToUpper(string(char))[0]
+		case 'a':
+			char = strings.ToLower(string(char))[0]
+		}
+
+		switch {
+		case pair.first == '0' && (char < '0' || char > '9'):
+			return nil, cartconvert.ErrSyntax
+		case pair.first != '0' && (char < pair.first || char > pair.first+25):
+			return nil, cartconvert.ErrSyntax
+		case char > pair.last:
+			return nil, cartconvert.ErrRange
+		}
+		canonical[i] = char
+	}
+
+	return &MaidenheadCoord{Locator: string(canonical), El: cartconvert.WGS84Ellipsoid}, nil
+}
+
+// Transform a latitude / longitude coordinate on WGS84 into a Maidenhead locator of the given precision.
+// The locator addresses the area containing the coordinate. Function returns cartconvert.ErrRange,
+// if the coordinate is not a valid latitude / longitude or the precision is not supported.
+func WGS84LatLongToMaidenhead(gc *cartconvert.PolarCoord, prec MaidenheadPrec) (*MaidenheadCoord, error) {
+
+	if err := gc.CheckFinite(); err != nil {
+		return nil, err
+	}
+	if !validPrec(prec) || !cartconvert.WorldExtent.Contains(gc) {
+		return nil, cartconvert.ErrRange
+	}
+
+	// the north pole and the antimeridian in the east belong to the last locator
+	const maxUnits = 18*2400 - 1
+	long := int(math.Min(math.Floor((gc.Longitude+180)*unitsPerDegreeLong), maxUnits))
+	lat := int(math.Min(math.Floor((gc.Latitude+90)*unitsPerDegreeLat), maxUnits))
+
+	locator := make([]byte, prec)
+	for i := 0; i < int(prec); i += 2 {
+		pair := pairs[i/2]
+		radix := int(pair.last-pair.first) + 1
+		locator[i] = pair.first + byte(long/pair.units%radix)
+		locator[i+1] = pair.first + byte(lat/pair.units%radix)
+	}
+
+	return &MaidenheadCoord{Locator: string(locator), El: cartconvert.WGS84Ellipsoid}, nil
+}
+
+// Transform a Maidenhead locator into a latitude / longitude coordinate on WGS84, placed at the center or
+// the south-west corner of the area addressed by the locator, according to anchor. Function returns the error
+// of AMaidenheadToStruct, if the locator is malformed.
+func MaidenheadToWGS84LatLong(coord *MaidenheadCoord, anchor MaidenheadAnchor) (*cartconvert.PolarCoord, error) {
+
+	// validates the locator, which may have been set directly
+	canonical, err := AMaidenheadToStruct(coord.Locator)
+	if err != nil {
+		return nil, err
+	}
+	locator := canonical.Locator
+
+	var long, lat float64
+	for i := 0; i < len(locator); i += 2 {
+		pair := pairs[i/2]
+		long += float64(int(locator[i]-pair.first) * pair.units)
+		lat += float64(int(locator[i+1]-pair.first) * pair.units)
+	}
+
+	if anchor == MaidenheadCenter {
+		size := float64(pairs[len(locator)/2-1].units)
+		long += size / 2
+		lat += size / 2
+	}
+
+	return &cartconvert.PolarCoord{
+		Latitude:  lat/unitsPerDegreeLat - 90,
+		Longitude: long/unitsPerDegreeLong - 180,
+		El:        cartconvert.WGS84Ellipsoid}, nil
+}
+
+// Returns reference points of the Maidenhead locator system, for tests and documentation. A locator denotes a cell,
+// so the tolerance is the distance of the reference point to the center of its cell.
+func Examples() []cartconvert.Example {
+	return []cartconvert.Example{
+		{Name: "Stephansdom, Vienna", Input: "JN88ee49", LatLong: &cartconvert.PolarCoord{Latitude: 48.2082, Longitude: 16.3738, El: cartconvert.WGS84Ellipsoid}, Tolerance: 500},
+		{Name: "ARRL headquarters, Newington", Input: "FN31pr", LatLong: &cartconvert.PolarCoord{Latitude: 41.714775, Longitude: -72.727260, El: cartconvert.WGS84Ellipsoid}, Tolerance: 2500},
+		{Name: "Sydney Opera House", Input: "QF56od", LatLong: &cartconvert.PolarCoord{Latitude: -33.8568, Longitude: 151.2153, El: cartconvert.WGS84Ellipsoid}, Tolerance: 1000},
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// Automated tests for the cartconvert/maidenhead package
+package maidenhead
+
+import (
+	"github.com/the42/cartconvert/cartconvert"
+	"math"
+	"testing"
+)
+
+// ## AMaidenheadToStruct
+type aMaidenheadToStructTest struct {
+	in  string
+	out string
+	err error
+}
+
+var aMaidenheadToStructTests = []aMaidenheadToStructTest{
+	{"JN68rt", "JN68rt", nil},
+	{"jn68RT", "JN68rt", nil},
+	{" FN31pr ", "FN31pr", nil},
+	{"JN88ee49", "JN88ee49", nil},
+	{"JN", "JN", nil},
+	{"RR99xx99", "RR99xx99", nil},
+	{"JN6", "", cartconvert.ErrSyntax},
+	{"JN68rt4", "", cartconvert.ErrSyntax},
+	{"JN68rt4950", "", cartconvert.ErrSyntax},
+	{"J968rt", "", cartconvert.ErrSyntax},
+	{"JNx8rt", "", cartconvert.ErrSyntax},
+	{"JN6842", "", cartconvert.ErrSyntax},
+	{"JN68rtax", "", cartconvert.ErrSyntax},
+	{"SN68rt", "", cartconvert.ErrRange},
+	{"JZ68", "", cartconvert.ErrRange},
+	{"JN68yt", "", cartconvert.ErrRange},
+}
+
+func TestAMaidenheadToStruct(t *testing.T) {
+	for index, test := range aMaidenheadToStructTests {
+		out, err := AMaidenheadToStruct(test.in)
+
+		if err != test.err {
+			t.Errorf("AMaidenheadToStruct [%d] '%s': expected error %v, got %v", index, test.in, test.err, err)
+			continue
+		}
+		if err == nil && (out.String() != test.out || out.El != cartconvert.WGS84Ellipsoid) {
+			t.Errorf("AMaidenheadToStruct [%d]: expected %s, got %s", index, test.out, out)
+		}
+	}
+}
+
+// ## WGS84LatLongToMaidenhead
+type wgs84LatLongToMaidenheadTest struct {
+	in   *cartconvert.PolarCoord
+	prec MaidenheadPrec
+	out  string
+	err  error
+}
+
+var wgs84LatLongToMaidenheadTests = []wgs84LatLongToMaidenheadTest{
+	// Vienna
+	{&cartconvert.PolarCoord{Latitude: 48.2082, Longitude: 16.3738}, MaidenheadExtendedSquare, "JN88ee49", nil},
+	{&cartconvert.PolarCoord{Latitude: 48.2082, Longitude: 16.3738}, MaidenheadSubsquare, "JN88ee", nil},
+	{&cartconvert.PolarCoord{Latitude: 48.2082, Longitude: 16.3738}, MaidenheadSquare, "JN88", nil},
+	{&cartconvert.PolarCoord{Latitude: 48.2082, Longitude: 16.3738}, MaidenheadField, "JN", nil},
+	// ARRL headquarters, W1AW
+	{&cartconvert.PolarCoord{Latitude: 41.714775, Longitude: -72.727260}, MaidenheadSubsquare, "FN31pr", nil},
+	// Sydney
+	{&cartconvert.PolarCoord{Latitude: -33.8568, Longitude: 151.2153}, MaidenheadSubsquare, "QF56od", nil},
+	// the corners of the world
+	{&cartconvert.PolarCoord{Latitude: -90, Longitude: -180}, MaidenheadExtendedSquare, "AA00aa00", nil},
+	{&cartconvert.PolarCoord{Latitude: 90, Longitude: 180}, MaidenheadExtendedSquare, "RR99xx99", nil},
+	{&cartconvert.PolarCoord{Latitude: 91, Longitude: 0}, MaidenheadSubsquare, "", cartconvert.ErrRange},
+	{&cartconvert.PolarCoord{Latitude: 48.2082, Longitude: 16.3738}, MaidenheadPrec(5), "", cartconvert.ErrRange},
+}
+
+func TestWGS84LatLongToMaidenhead(t *testing.T) {
+	for index, test := range wgs84LatLongToMaidenheadTests {
+		out, err := WGS84LatLongToMaidenhead(test.in, test.prec)
+		if err != test.err || (err == nil && out.String() != test.out) {
+			t.Errorf("WGS84LatLongToMaidenhead [%d]: expected %s (%v), got %s (%v)", index, test.out, test.err, out, err)
+		}
+	}
+}
+
+// ## MaidenheadToWGS84LatLong
+type maidenheadToWGS84LatLongTest struct {
+	in        string
+	anchor    MaidenheadAnchor
+	lat, long float64
+}
+
+var maidenheadToWGS84LatLongTests = []maidenheadToWGS84LatLongTest{
+	{"JN68rt", MaidenheadCenter, 48 + 48.75/60, 13 + 27.5/60},
+	{"JN68rt", MaidenheadSouthWest, 48 + 47.5/60, 13 + 25.0/60},
+	{"JN68", MaidenheadCenter, 48.5, 13},
+	{"JN", MaidenheadSouthWest, 40, 0},
+	{"JN88ee49", MaidenheadSouthWest, 48 + 12.0/60 + 15.0/3600, 16 + 22.0/60},
+	{"JN88ee49", MaidenheadCenter, 48 + 12.0/60 + 22.5/3600, 16 + 22.0/60 + 15.0/3600},
+}
+
+func TestMaidenheadToWGS84LatLong(t *testing.T) {
+	for index, test := range maidenheadToWGS84LatLongTests {
+		coord, _ := AMaidenheadToStruct(test.in)
+		out, err := MaidenheadToWGS84LatLong(coord, test.anchor)
+		if err != nil || math.Abs(out.Latitude-test.lat) > 1e-9 || math.Abs(out.Longitude-test.long) > 1e-9 {
+			t.Errorf("MaidenheadToWGS84LatLong [%d]: expected %f %f, got %s (%v)", index, test.lat, test.long, out, err)
+		}
+	}
+
+	if _, err := MaidenheadToWGS84LatLong(&MaidenheadCoord{Locator: "ZZ"}, MaidenheadCenter); err != cartconvert.ErrRange {
+		t.Errorf("MaidenheadToWGS84LatLong: expected %v for an invalid locator, got %v", cartconvert.ErrRange, err)
+	}
+}
+
+// The center of the area of a locator maps back to the locator
+func TestMaidenheadRoundTrip(t *testing.T) {
+	for _, in := range []string{"JN68rt", "FN31pr", "QF56od", "AA00aa00", "RR99xx99", "KI07"} {
+		coord, _ := AMaidenheadToStruct(in)
+		latlong, _ := MaidenheadToWGS84LatLong(coord, MaidenheadCenter)
+		if out, err := WGS84LatLongToMaidenhead(latlong, coord.Prec()); err != nil || out.String() != in {
+			t.Errorf("TestMaidenheadRoundTrip: expected %s, got %s (%v)", in, out, err)
+		}
+	}
+}
+
+// ## NaN and infinity
+func TestNonFinite(t *testing.T) {
+	for _, value := range []float64{math.NaN(), math.Inf(1)} {
+		if _, err := WGS84LatLongToMaidenhead(&cartconvert.PolarCoord{Latitude: value, Longitude: 16.37}, MaidenheadSubsquare); err != cartconvert.ErrNotFinite {
+			t.Errorf("WGS84LatLongToMaidenhead (%v): expected ErrNotFinite, got %v", value, err)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// This package provides functions to deal with conversion and transformations of coordinates
+// of the Military Grid Reference System (MGRS), as used by NATO.
+//
+// MGRS is an alternative notation of UTM coordinates on WGS84. A coordinate is given by the UTM zone,
+// the latitude band, a two letter identifier of a square of 100 km and easting and northing within
+// the square, like 32U MV 12345 67890. The number of digits of easting and northing sets the precision,
+// from 1 m with five digits to the bare 100 km square.
+//
+// The polar regions, which MGRS maps in the Universal Polar Stereographic system, are not supported.
+//
+// For further info see http://en.wikipedia.org/wiki/Military_grid_reference_system
+package mgrs
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/the42/cartconvert/cartconvert"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// The precision of an MGRS coordinate as the number of digits of easting and northing.
+// MGRS_100km denotes the bare 100 km square, MGRS_1m a resolution of one meter.
+type MGRSprec byte
+
+const (
+	MGRS_100km MGRSprec = iota
+	MGRS_10km
+	MGRS_1km
+	MGRS_100m
+	MGRS_10m
+	MGRS_1m
+)
+
+// An MGRS coordinate is specified by UTM zone and latitude band, the 100 km square and easting and northing
+// within the square. Easting and northing are given in units of the precision, eg. in 10 m for MGRS_10m,
+// and denote the south-west corner of a cell of that size.
+type MGRSCoord struct {
+	Zone     uint                   `json:"zone" xml:"zone"`
+	Band     byte                   `json:"band" xml:"band"`
+	Square   string                 `json:"square" xml:"square"`
+	Easting  uint                   `json:"easting" xml:"easting"`
+	Northing uint                   `json:"northing" xml:"northing"`
+	Prec     MGRSprec               `json:"prec" xml:"prec"`
+	El       *cartconvert.Ellipsoid `json:"ellipsoid,omitempty" xml:"ellipsoid,omitempty"`
+}
+
+// Latitude bands from 80° S to 84° N. All bands span 8°, except band X, which spans 12°.
+const bands = "CDEFGHJKLMNPQRSTUVWX"
+
+// Column letters of the 100 km squares, repeating every three zones, and row letters, repeating every 2000 km
+var (
+	columnLetters = [3]string{"ABCDEFGH", "JKLMNPQR", "STUVWXYZ"}
+	rowLetters    = "ABCDEFGHJKLMNPQRSTUV"
+)
+
+// Canonical representation of an MGRS coordinate, with zone and band, square, easting and northing separated by blanks
+func (coord *MGRSCoord) String() string {
+	if coord.Prec == MGRS_100km {
+		return fmt.Sprintf("%d%c %s", coord.Zone, coord.Band, coord.Square)
+	}
+	return fmt.Sprintf("%d%c %s %0*d %0*d", coord.Zone, coord.Band, coord.Square, int(coord.Prec), coord.Easting, int(coord.Prec), coord.Northing)
+}
+
+// Implements cartconvert.Coordinate, the system of an MGRS coordinate is "mgrs"
+func (coord *MGRSCoord) System() string {
+	return "mgrs"
+}
+
+// Implements cartconvert.Coordinate, the EPSG code of the UTM zone on WGS84 the grid square lies in, on the northern
+// hemisphere for the bands N to X, 0 if the zone is invalid
+func (coord *MGRSCoord) EPSG() int {
+	if coord.Zone < 1 || coord.Zone > 60 {
+		return 0
+	}
+	if coord.Band >= 'N' {
+		return cartconvert.EPSGWGS84UTMNorth + int(coord.Zone)
+	}
+	return cartconvert.EPSGWGS84UTMSouth + int(coord.Zone)
+}
+
+// Implements cartconvert.Coordinate, see MGRSToWGS84LatLong
+func (coord *MGRSCoord) ToWGS84() (*cartconvert.PolarCoord, error) {
+	return MGRSToWGS84LatLong(coord)
+}
+
+// Implements json.Marshaler, writing the coordinates in fixed-point notation
+func (coord *MGRSCoord) MarshalJSON() ([]byte, error) {
+	return cartconvert.MarshalFixedJSON(coord)
+}
+
+// Implements xml.Marshaler, writing the coordinates in fixed-point notation
+func (coord *MGRSCoord) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return cartconvert.MarshalFixedXML(e, start, coord)
+}
+
+// Returns the southern and northern latitude of a latitude band in decimal degrees
+func bandLatitudes(band byte) (south, north float64, err error) {
+	index := strings.IndexByte(bands, band)
+	if index < 0 {
+		return 0, 0, cartconvert.ErrRange
+	}
+	south = -80 + 8*float64(index)
+	north = south + 8
+	if band == 'X' {
+		north += 4
+	}
+	return
+}
+
+// Parses a string representation of an MGRS coordinate into an MGRS coordinate struct. The literal
+// can be specified as follows:
+//
+//	ZZB SQ EA NO
+//	ZZB SQ EANO
+//	ZZBSQEANO
+//
+// with ZZ the UTM zone number, B the latitude band, SQ the two letter identifier of the 100 km square and
+// EA easting and NO northing, each of them having the same number of up to five digits.
+//
+// The reference ellipsoid of an MGRS coordinate will always be set to the WGS84 ellipsoid.
+//
+// returns cartconvert.ErrSyntax if format is not understood
+// returns cartconvert.ErrRange if values are outside the defined parameters for an MGRS coordinate, including
+// 100 km squares which do not exist in the latitude band
+func AMGRSToStruct(mgrscoord string) (*MGRSCoord, error) {
+
+	compact := strings.Replace(strings.ToUpper(strings.TrimSpace(mgrscoord)), " ", "", -1)
+
+	zonelen := 0
+	for zonelen < len(compact) && compact[zonelen] >= '0' && compact[zonelen] <= '9' {
+		zonelen++
+	}
+	if zonelen == 0 || zonelen > 2 || len(compact) < zonelen+3 {
+		return nil, cartconvert.ErrSyntax
+	}
+
+	zone, err := strconv.ParseUint(compact[:zonelen], 10, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	coord := &MGRSCoord{Zone: uint(zone), Band: compact[zonelen], Square: compact[zonelen+1 : zonelen+3], El: cartconvert.WGS84Ellipsoid}
+
+	enn := compact[zonelen+3:]
+	for _, item := range enn {
+		if item < '0' || item > '9' {
+			return nil, cartconvert.ErrSyntax
+		}
+	}
+	if len(enn)%2 > 0 || len(enn)/2 > int(MGRS_1m) {
+		return nil, cartconvert.ErrRange
+	}
+
+	if coord.Prec = MGRSprec(len(enn) / 2); coord.Prec > MGRS_100km {
+		east, _ := strconv.Atoi(enn[:coord.Prec])
+		north, _ := strconv.Atoi(enn[coord.Prec:])
+		coord.Easting, coord.Northing = uint(east), uint(north)
+	}
+
+	// validates zone, band and square
+	if _, err = MGRSToUTM(coord); err != nil {
+		return nil, err
+	}
+	return coord, nil
+}
+
+// Returns the size in meters of the cells of an MGRS coordinate of precision prec
+func cellSize(prec MGRSprec) float64 {
+	return math.Pow(10, float64(MGRS_1m-prec))
+}
+
+// Transform an MGRS coordinate into the UTM coordinate of the south-west corner of its cell. Easting and
+// northing are determined by the letters of the 100 km square, the row letters being resolved within the
+// latitude band. Function returns cartconvert.ErrRange, if zone, band or square are invalid or
+// the square does not exist in the latitude band.
+func MGRSToUTM(coord *MGRSCoord) (*cartconvert.UTMCoord, error) {
+
+	south, north, err := bandLatitudes(coord.Band)
+	if err != nil || coord.Zone < 1 || coord.Zone > 60 || len(coord.Square) != 2 || coord.Prec > MGRS_1m {
+		return nil, cartconvert.ErrRange
+	}
+
+	column := strings.IndexByte(columnLetters[(coord.Zone-1)%3], coord.Square[0])
+	row := strings.IndexByte(rowLetters, coord.Square[1])
+	if column < 0 || row < 0 {
+		return nil, cartconvert.ErrRange
+	}
+	// the row letters of even zones are offset by five rows
+	if coord.Zone%2 == 0 {
+		row = (row + 15) % 20
+	}
+
+	size := cellSize(coord.Prec)
+	limit := uint(math.Pow(10, float64(coord.Prec)))
+	if coord.Easting >= limit || coord.Northing >= limit {
+		return nil, cartconvert.ErrRange
+	}
+
+	long0 := float64(coord.Zone-1)*6 - 180 + 3
+	falseNorthing := cartconvert.UTMBandHemisphere(coord.Band).UTMFalseNorthing()
+
+	// the northing of the southern boundary of the band on the central meridian
+	// picks the repetition of the row letters of 2000 km
+	southNorthing := cartconvert.DirectTransverseMercator(
+		&cartconvert.PolarCoord{Latitude: south, Longitude: long0, El: cartconvert.WGS84Ellipsoid},
+		0, long0, 0.9996, 500000, falseNorthing).Y
+
+	// the squares cut by the southern boundary start up to 100 km south of it
+	northing := float64(row) * 100000
+	northing += math.Ceil((southNorthing-100000-northing)/2000000) * 2000000
+
+	easting := float64(column+1)*100000 + float64(coord.Easting)*size
+	northing += float64(coord.Northing) * size
+
+	// the cell has to overlap the band. The latitude of its center may exceed the band by half the diagonal of the cell,
+	// a degree of latitude spans at least 110.5 km
+	center := cartconvert.InverseTransverseMercator(
+		&cartconvert.GeoPoint{X: easting + size/2, Y: northing + size/2, El: cartconvert.WGS84Ellipsoid},
+		0, long0, 0.9996, 500000, falseNorthing)
+	margin := size * math.Sqrt2 / 2 / 110500
+	if center.Latitude < south-margin || center.Latitude > north+margin {
+		return nil, cartconvert.ErrRange
+	}
+
+	return &cartconvert.UTMCoord{
+		Zone:     fmt.Sprintf("%d%c", coord.Zone, coord.Band),
+		Easting:  easting,
+		Northing: northing,
+		El:       cartconvert.WGS84Ellipsoid}, nil
+}
+
+// Transform an UTM coordinate on WGS84 into an MGRS coordinate of the given precision. Easting and northing
+// are truncated to the precision, so the MGRS coordinate denotes the cell containing the UTM coordinate.
+// Function returns cartconvert.ErrRange, if the zone of the UTM coordinate is invalid or lies in the polar regions.
+func UTMToMGRS(utm *cartconvert.UTMCoord, prec MGRSprec) (*MGRSCoord, error) {
+
+	zonelength := len(utm.Zone)
+	if zonelength < 2 || prec > MGRS_1m {
+		return nil, cartconvert.ErrRange
+	}
+	if err := cartconvert.CheckFinite(utm.Easting, utm.Northing); err != nil {
+		return nil, err
+	}
+
+	zone, err := strconv.ParseUint(utm.Zone[:zonelength-1], 10, 0)
+	if err != nil || zone < 1 || zone > 60 {
+		return nil, cartconvert.ErrRange
+	}
+	band := utm.Zone[zonelength-1]
+	if _, _, err = bandLatitudes(band); err != nil {
+		return nil, err
+	}
+
+	column := int(math.Floor(utm.Easting/100000)) - 1
+	if column < 0 || column > 7 {
+		return nil, cartconvert.ErrRange
+	}
+	row := int(math.Floor(math.Mod(utm.Northing, 2000000) / 100000))
+	if zone%2 == 0 {
+		row = (row + 5) % 20
+	}
+
+	size := cellSize(prec)
+	return &MGRSCoord{
+		Zone:     uint(zone),
+		Band:     band,
+		Square:   string([]byte{columnLetters[(zone-1)%3][column], rowLetters[row]}),
+		Easting:  uint(math.Floor(math.Mod(utm.Easting, 100000) / size)),
+		Northing: uint(math.Floor(math.Mod(utm.Northing, 100000) / size)),
+		Prec:     prec,
+		El:       cartconvert.WGS84Ellipsoid}, nil
+}
+
+// Transform a latitude / longitude coordinate on WGS84 into an MGRS coordinate of the given precision.
+// Function returns cartconvert.ErrRange, if the coordinate lies in the polar regions not covered by UTM.
+func WGS84LatLongToMGRS(gc *cartconvert.PolarCoord, prec MGRSprec) (*MGRSCoord, error) {
+
+	if err := gc.CheckFinite(); err != nil {
+		return nil, err
+	}
+	if !cartconvert.UTMExtent.Contains(gc) {
+		return nil, cartconvert.ErrRange
+	}
+
+	wgs84 := *gc
+	wgs84.El = cartconvert.WGS84Ellipsoid
+	return UTMToMGRS(cartconvert.LatLongToUTM(&wgs84), prec)
+}
+
+// Transform an MGRS coordinate into a latitude / longitude coordinate on WGS84. The coordinate points
+// to the south-west corner of the cell of the MGRS coordinate, like a fully qualified coordinate of a
+// resolution of 1 m does. Function returns cartconvert.ErrRange, if the MGRS coordinate is invalid.
+func MGRSToWGS84LatLong(coord *MGRSCoord) (*cartconvert.PolarCoord, error) {
+
+	utm, err := MGRSToUTM(coord)
+	if err != nil {
+		return nil, err
+	}
+
+	pt := &cartconvert.GeoPoint{X: utm.Easting, Y: utm.Northing, El: cartconvert.WGS84Ellipsoid}
+	falseNorthing := cartconvert.UTMBandHemisphere(coord.Band).UTMFalseNorthing()
+	return cartconvert.InverseTransverseMercator(pt, 0, float64(coord.Zone-1)*6-180+3, 0.9996, 500000, falseNorthing), nil
+}
@@ -0,0 +1,167 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// Automated tests for the cartconvert/mgrs package
+package mgrs
+
+import (
+	"github.com/the42/cartconvert/cartconvert"
+	"math"
+	"testing"
+)
+
+// ## AMGRSToStruct
+type aMGRSToStructTest struct {
+	in  string
+	out string
+	err error
+}
+
+var aMGRSToStructTests = []aMGRSToStructTest{
+	{"33U XP 02065 40353", "33U XP 02065 40353", nil},
+	{"33uxp0206540353", "33U XP 02065 40353", nil},
+	{"33U XP 0240", "33U XP 02 40", nil},
+	{"33UXP", "33U XP", nil},
+	{"4QFJ1234567890", "4Q FJ 12345 67890", nil},
+	{"56H LH 34900 52288", "56H LH 34900 52288", nil},
+	{"33U XP 123", "", cartconvert.ErrRange},
+	{"33U XP 123456123456", "", cartconvert.ErrRange},
+	{"33U XP 12a4", "", cartconvert.ErrSyntax},
+	{"UXP", "", cartconvert.ErrSyntax},
+	{"33U", "", cartconvert.ErrSyntax},
+	{"61U XP", "", cartconvert.ErrRange},
+	{"33Y XP", "", cartconvert.ErrRange},
+	// column letter A is used by zones 1, 4, ..., not by zone 33
+	{"33U AP", "", cartconvert.ErrRange},
+	{"33U XI", "", cartconvert.ErrRange},
+	// row letter G lies south of band U in zone 33
+	{"33U XG", "", cartconvert.ErrRange},
+}
+
+func TestAMGRSToStruct(t *testing.T) {
+	for index, test := range aMGRSToStructTests {
+		out, err := AMGRSToStruct(test.in)
+
+		if err != test.err {
+			t.Errorf("AMGRSToStruct [%d] '%s': expected error %v, got %v", index, test.in, test.err, err)
+			continue
+		}
+		if err == nil && out.String() != test.out {
+			t.Errorf("AMGRSToStruct [%d]: expected %s, got %s", index, test.out, out)
+		}
+	}
+}
+
+// ## WGS84LatLongToMGRS
+type wgs84LatLongToMGRSTest struct {
+	in  *cartconvert.PolarCoord
+	out string
+}
+
+var wgs84LatLongToMGRSTests = []wgs84LatLongToMGRSTest{
+	// Washington Monument
+	{&cartconvert.PolarCoord{Latitude: 38.8895, Longitude: -77.0353}, "18S UJ 23478 06483"},
+	// Vienna
+	{&cartconvert.PolarCoord{Latitude: 48.2082, Longitude: 16.3738}, "33U XP 02065 40353"},
+	// Sydney
+	{&cartconvert.PolarCoord{Latitude: -33.8568, Longitude: 151.2153}, "56H LH 34900 52288"},
+	// Honolulu
+	{&cartconvert.PolarCoord{Latitude: 21.3069, Longitude: -157.8583}, "4Q FJ 18417 56542"},
+	// Bergen, in the widened zone 32V
+	{&cartconvert.PolarCoord{Latitude: 60.3913, Longitude: 5.3221}, "32V KN 97353 00648"},
+}
+
+func TestWGS84LatLongToMGRS(t *testing.T) {
+	for index, test := range wgs84LatLongToMGRSTests {
+		out, err := WGS84LatLongToMGRS(test.in, MGRS_1m)
+		if err != nil || out.String() != test.out {
+			t.Errorf("WGS84LatLongToMGRS [%d]: expected %s, got %s (%v)", index, test.out, out, err)
+			continue
+		}
+
+		latlong, err := MGRSToWGS84LatLong(out)
+		if err != nil || math.Abs(latlong.Latitude-test.in.Latitude) > 2e-5 || math.Abs(latlong.Longitude-test.in.Longitude) > 2e-5 {
+			t.Errorf("MGRSToWGS84LatLong [%d]: expected %s, got %s (%v)", index, test.in, latlong, err)
+		}
+	}
+
+	if out, err := WGS84LatLongToMGRS(wgs84LatLongToMGRSTests[1].in, MGRS_1km); err != nil || out.String() != "33U XP 02 40" {
+		t.Errorf("WGS84LatLongToMGRS: expected 33U XP 02 40, got %s (%v)", out, err)
+	}
+	if _, err := WGS84LatLongToMGRS(&cartconvert.PolarCoord{Latitude: 85, Longitude: 10}, MGRS_1m); err != cartconvert.ErrRange {
+		t.Errorf("WGS84LatLongToMGRS: expected ErrRange in the polar region, got %v", err)
+	}
+}
+
+// Squares cut by the boundaries of latitude bands or the equator have to be accepted in both bands
+func TestMGRSBandBoundaries(t *testing.T) {
+	for _, gc := range []*cartconvert.PolarCoord{
+		{Latitude: 48.0001, Longitude: 16.0},
+		{Latitude: 47.9999, Longitude: 16.0},
+		{Latitude: 0.0001, Longitude: 10.0},
+		{Latitude: -0.0001, Longitude: 10.0},
+		{Latitude: 72.0001, Longitude: 20.0},
+		{Latitude: 83.9, Longitude: 20.0},
+		{Latitude: -79.9, Longitude: -60.0},
+	} {
+		for prec := MGRS_100km; prec <= MGRS_1m; prec++ {
+			coord, err := WGS84LatLongToMGRS(gc, prec)
+			if err != nil {
+				t.Errorf("WGS84LatLongToMGRS %s: %s", gc, err)
+				continue
+			}
+			if parsed, err := AMGRSToStruct(coord.String()); err != nil || parsed.String() != coord.String() {
+				t.Errorf("AMGRSToStruct %s: expected %s, got %s (%v)", gc, coord, parsed, err)
+			}
+		}
+	}
+}
+
+// ## RoundTrip
+// The positional error in meters of a latitude / longitude coordinate converted into an MGRS coordinate and back
+type roundTripTest struct {
+	gc        *cartconvert.PolarCoord
+	threshold float64
+}
+
+type roundTripPrecTest struct {
+	roundTripTest
+	prec MGRSprec
+}
+
+// An MGRS coordinate denotes the south-west corner of its cell, so the round trip is limited by the diagonal of the cell
+var roundTripTests = []roundTripPrecTest{
+	{roundTripTest{&cartconvert.PolarCoord{Latitude: 47.570299, Longitude: 14.236188, El: cartconvert.WGS84Ellipsoid}, 1.42}, MGRS_1m},
+	{roundTripTest{&cartconvert.PolarCoord{Latitude: 47.570299, Longitude: 14.236188, El: cartconvert.WGS84Ellipsoid}, 14.2}, MGRS_10m},
+	{roundTripTest{&cartconvert.PolarCoord{Latitude: -33.8688, Longitude: 151.2093, El: cartconvert.WGS84Ellipsoid}, 1.42}, MGRS_1m},
+	{roundTripTest{&cartconvert.PolarCoord{Latitude: 64.1466, Longitude: -21.9426, El: cartconvert.WGS84Ellipsoid}, 142}, MGRS_100m},
+}
+
+func TestRoundTrip(t *testing.T) {
+	for index, test := range roundTripTests {
+		coord, err := WGS84LatLongToMGRS(test.gc, test.prec)
+		if err != nil {
+			t.Fatalf("WGS84LatLongToMGRS [%d]: %s", index, err)
+		}
+		out, err := MGRSToWGS84LatLong(coord)
+		if err != nil {
+			t.Fatalf("MGRSToWGS84LatLong [%d]: %s", index, err)
+		}
+		if d := cartconvert.Haversine(test.gc, out, 0); d > test.threshold {
+			t.Errorf("RoundTrip [%d]: expected a positional error below %g m, got %.3f m", index, test.threshold, d)
+		}
+	}
+}
+
+// ## NaN and infinity
+func TestNonFinite(t *testing.T) {
+	for _, value := range []float64{math.NaN(), math.Inf(1)} {
+		if _, err := WGS84LatLongToMGRS(&cartconvert.PolarCoord{Latitude: 47.57, Longitude: value}, MGRS_1m); err != cartconvert.ErrNotFinite {
+			t.Errorf("WGS84LatLongToMGRS (%v): expected ErrNotFinite, got %v", value, err)
+		}
+		if _, err := UTMToMGRS(&cartconvert.UTMCoord{Zone: "33T", Easting: value, Northing: 5268987}, MGRS_1m); err != cartconvert.ErrNotFinite {
+			t.Errorf("UTMToMGRS (%v): expected ErrNotFinite, got %v", value, err)
+		}
+	}
+}
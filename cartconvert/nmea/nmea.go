@@ -0,0 +1,187 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// This package provides functions to extract the position from NMEA 0183 sentences, as written by GNSS receivers
+// to their serial port.
+//
+// The sentences GGA (fix data) and RMC (recommended minimum data) are supported, of any talker like GP for GPS or
+// GN for combined systems. A sentence has to carry its checksum, like
+//
+//	$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47
+//
+// Latitude and longitude are given in degrees and decimal minutes, ddmm.mmmm and dddmm.mmmm, followed by the
+// hemisphere N, S, E or W. Positions refer to WGS84.
+//
+// For further info see http://en.wikipedia.org/wiki/NMEA_0183
+package nmea
+
+import (
+	"errors"
+	"github.com/the42/cartconvert/cartconvert"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Returned if the checksum of a sentence doesn't match its contents
+var ErrChecksum = errors.New("checksum mismatch")
+
+// Returned if a sentence states that the receiver has no valid position
+var ErrNoFix = errors.New("no position fix")
+
+// Returns the checksum of the contents of a sentence, the bytes between $ and *
+func checksum(contents string) (sum byte) {
+	for i := 0; i < len(contents); i++ {
+		sum ^= contents[i]
+	}
+	return
+}
+
+// Splits a sentence into its fields after verifying the checksum. The first field is the address of the sentence,
+// the talker followed by the sentence type.
+func fields(sentence string) ([]string, error) {
+
+	sentence = strings.TrimSpace(sentence)
+	if len(sentence) == 0 || sentence[0] != '$' {
+		return nil, cartconvert.ErrSyntax
+	}
+
+	star := strings.LastIndexByte(sentence, '*')
+	if star < 0 || len(sentence)-star != 3 {
+		return nil, cartconvert.ErrSyntax
+	}
+
+	sum, err := strconv.ParseUint(sentence[star+1:], 16, 8)
+	if err != nil {
+		return nil, cartconvert.ErrSyntax
+	}
+
+	contents := sentence[1:star]
+	if checksum(contents) != byte(sum) {
+		return nil, ErrChecksum
+	}
+	return strings.Split(contents, ","), nil
+}
+
+// Parses a bearing of degrees and decimal minutes, with degdigits digits of the degrees, in the hemisphere
+// positive or negative into decimal degrees. Returns ErrNoFix, if the bearing is empty.
+func parseBearing(value, hemisphere string, degdigits int, positive, negative string, max float64) (float64, error) {
+
+	if value == "" && hemisphere == "" {
+		return 0, ErrNoFix
+	}
+	if len(value) < degdigits+2 {
+		return 0, cartconvert.ErrSyntax
+	}
+
+	deg, err := strconv.ParseUint(value[:degdigits], 10, 16)
+	if err != nil {
+		return 0, cartconvert.ErrSyntax
+	}
+	// the minutes consist of digits and the decimal point only, no sign, exponent, NaN or Inf
+	if strings.Trim(value[degdigits:], "0123456789.") != "" {
+		return 0, cartconvert.ErrSyntax
+	}
+	min, err := strconv.ParseFloat(value[degdigits:], 64)
+	if err != nil {
+		return 0, cartconvert.ErrSyntax
+	}
+	if min >= 60 {
+		return 0, cartconvert.ErrRange
+	}
+
+	bearing := float64(deg) + min/60
+	if bearing > max {
+		return 0, cartconvert.ErrRange
+	}
+
+	switch hemisphere {
+	case positive:
+	case negative:
+		bearing = -bearing
+	default:
+		return 0, cartconvert.ErrSyntax
+	}
+	return bearing, nil
+}
+
+// Parses latitude and longitude given by the fields lat, N/S, long, E/W of a sentence
+func parsePosition(f []string) (*cartconvert.PolarCoord, error) {
+
+	lat, err := parseBearing(f[0], f[1], 2, "N", "S", 90)
+	if err != nil {
+		return nil, err
+	}
+	long, err := parseBearing(f[2], f[3], 3, "E", "W", 180)
+	if err != nil {
+		return nil, err
+	}
+	return &cartconvert.PolarCoord{Latitude: lat, Longitude: long, El: cartconvert.WGS84Ellipsoid}, nil
+}
+
+// Parses an NMEA 0183 sentence of type GGA or RMC into the position it reports on the WGS84 ellipsoid. The height
+// of a GGA sentence is the ellipsoidal height, the altitude above mean sea level plus the geoid separation, or the
+// altitude alone, if the sentence doesn't state the separation. RMC sentences carry no height.
+//
+// returns ErrChecksum if the checksum doesn't match the sentence
+// returns ErrNoFix if the receiver states that it has no valid position
+// returns cartconvert.ErrUnsupported if the sentence is neither GGA nor RMC
+// returns cartconvert.ErrSyntax if the sentence is malformed
+// returns cartconvert.ErrRange if latitude or longitude exceed their range
+func ParseSentence(sentence string) (*cartconvert.PolarCoord, error) {
+
+	f, err := fields(sentence)
+	if err != nil {
+		return nil, err
+	}
+	if len(f[0]) != 5 {
+		return nil, cartconvert.ErrSyntax
+	}
+
+	switch f[0][2:] {
+	case "GGA":
+		// address, time, lat, N/S, long, E/W, quality, satellites, HDOP, altitude, M, separation, M, ...
+		if len(f) < 13 {
+			return nil, cartconvert.ErrSyntax
+		}
+		if f[6] == "0" {
+			return nil, ErrNoFix
+		}
+
+		gc, err := parsePosition(f[2:6])
+		if err != nil {
+			return nil, err
+		}
+		for _, field := range [][2]string{{f[9], f[10]}, {f[11], f[12]}} {
+			if field[0] == "" {
+				continue
+			}
+			h, err := strconv.ParseFloat(field[0], 64)
+			if err != nil || math.IsInf(h, 0) || math.IsNaN(h) || field[1] != "M" {
+				return nil, cartconvert.ErrSyntax
+			}
+			gc.Height += h
+		}
+		return gc, nil
+
+	case "RMC":
+		// address, time, status, lat, N/S, long, E/W, speed, course, date, ...
+		if len(f) < 10 {
+			return nil, cartconvert.ErrSyntax
+		}
+		if f[2] != "A" {
+			return nil, ErrNoFix
+		}
+		return parsePosition(f[3:7])
+	}
+	return nil, cartconvert.ErrUnsupported
+}
+
+// Returns example sentences, for tests and documentation, among them the GGA sentence of the NMEA 0183 reference
+func Examples() []cartconvert.Example {
+	return []cartconvert.Example{
+		{Name: "GGA fix", Input: "$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47", LatLong: &cartconvert.PolarCoord{Latitude: 48.1173, Longitude: 11.516667, El: cartconvert.WGS84Ellipsoid}, Tolerance: 0.1},
+		{Name: "RMC fix", Input: "$GNRMC,001031.00,A,3352.50000,S,15112.80000,E,0.0,,010120,,,A*7E", LatLong: &cartconvert.PolarCoord{Latitude: -33.875, Longitude: 151.213333, El: cartconvert.WGS84Ellipsoid}, Tolerance: 0.1},
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// Automated tests for the cartconvert/nmea package
+package nmea
+
+import (
+	"github.com/the42/cartconvert/cartconvert"
+	"math"
+	"testing"
+)
+
+// ## ParseSentence
+type parseSentenceTest struct {
+	in                string
+	lat, long, height float64
+	err               error
+}
+
+var parseSentenceTests = []parseSentenceTest{
+	{"$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47", 48.1173, 11.516667, 592.3, nil},
+	{"$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W*6A", 48.1173, 11.516667, 0, nil},
+	// southern and western hemisphere, other talkers and a negative geoid separation
+	{"$GNRMC,001031.00,A,3352.50000,S,15112.80000,E,0.0,,010120,,,A*7E\r\n", -33.875, 151.213333, 0, nil},
+	{" $GPGGA,002153.000,3342.6618,S,15119.3124,W,1,10,1.2,27.0,M,-34.2,M,,0000*4F", -33.71103, -151.321873, -7.2, nil},
+	// no position fix
+	{"$GPGGA,123519,,,,,0,00,,,M,,M,,*6B", 0, 0, 0, ErrNoFix},
+	{"$GPRMC,123519,V,,,,,,,230394,,,N*51", 0, 0, 0, ErrNoFix},
+	// checksum mismatch and missing checksum
+	{"$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*48", 0, 0, 0, ErrChecksum},
+	{"$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,", 0, 0, 0, cartconvert.ErrSyntax},
+	{"GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47", 0, 0, 0, cartconvert.ErrSyntax},
+	// unsupported sentence type
+	{"$GPGSV,3,1,11,03,03,111,00,04,15,270,00,06,01,010,00,13,06,292,00*74", 0, 0, 0, cartconvert.ErrUnsupported},
+	// malformed and out of range bearings
+	{"$GPRMC,123519,A,4807.038,X,01131.000,E,022.4,084.4,230394,003.1,W*7C", 0, 0, 0, cartconvert.ErrSyntax},
+	// minutes of other than digits and the decimal point, which strconv.ParseFloat would accept
+	{"$GPRMC,123519,A,48NaN,N,01131.000,E,022.4,084.4,230394,003.1,W*19", 0, 0, 0, cartconvert.ErrSyntax},
+	{"$GPGGA,123519,4807.038,N,011Inf,E,1,08,0.9,545.4,M,46.9,M,,*1A", 0, 0, 0, cartconvert.ErrSyntax},
+	{"$GPRMC,123519,A,4807e1,N,01131.000,E,022.4,084.4,230394,003.1,W*2B", 0, 0, 0, cartconvert.ErrSyntax},
+	{"$GPRMC,123519,A,4860.000,N,01131.000,E,022.4,084.4,230394,003.1,W*60", 0, 0, 0, cartconvert.ErrRange},
+	{"$GPRMC,123519,A,4807.038,N,18131.000,E,022.4,084.4,230394,003.1,W*62", 0, 0, 0, cartconvert.ErrRange},
+}
+
+func TestParseSentence(t *testing.T) {
+	for index, test := range parseSentenceTests {
+		out, err := ParseSentence(test.in)
+
+		if err != test.err {
+			t.Errorf("ParseSentence [%d]: expected error %v, got %v", index, test.err, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		if math.Abs(out.Latitude-test.lat) > 1e-6 || math.Abs(out.Longitude-test.long) > 1e-6 || math.Abs(out.Height-test.height) > 1e-9 {
+			t.Errorf("ParseSentence [%d]: expected %f %f %f, got %f %f %f", index, test.lat, test.long, test.height,
+				out.Latitude, out.Longitude, out.Height)
+		}
+		if out.El != cartconvert.WGS84Ellipsoid {
+			t.Errorf("ParseSentence [%d]: expected the WGS84 ellipsoid, got %v", index, out.El)
+		}
+	}
+}
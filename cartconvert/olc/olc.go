@@ -0,0 +1,365 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// This package provides functions to deal with conversion of Open Location Codes, also known as Plus Codes,
+// which serve as addresses in places without street names.
+//
+// An Open Location Code like 8FVC9G8F+6X consists of up to five pairs of digits from a base 20 alphabet,
+// each pair giving latitude first and longitude second. The first pair divides the earth into 20° by 20°,
+// every following pair divides the area of the preceding pair into 20 by 20. The separator '+' follows the
+// eighth digit. Codes of less than eight digits are padded by '0' up to the separator. Digits beyond the
+// tenth divide the area into a grid of 5 rows by 4 columns each, up to 15 digits.
+//
+// A short code like 9G8F+6X omits leading digits, which are recovered from a nearby reference location.
+//
+// Codes refer to WGS84.
+//
+// For further info see https://github.com/google/open-location-code/blob/master/docs/specification.md
+package olc
+
+import (
+	"github.com/the42/cartconvert/cartconvert"
+	"math"
+	"strings"
+)
+
+// The digits of an Open Location Code
+const olcAlphabet = "23456789CFGHJMPQRVWX"
+
+const (
+	olcSeparator         = '+'
+	olcSeparatorPosition = 8
+	olcPadding           = '0'
+)
+
+// Code lengths; the pair code consists of the five pairs, the grid refines it up to the maximum number of digits
+const (
+	OLCMinLength     = 2
+	OLCPairLength    = 10
+	OLCMaxLength     = 15
+	olcGridRows      = 5
+	olcGridColumns   = 4
+	olcEncodingBase  = 20
+	olcLatitudeMax   = 90
+	olcLongitudeMax  = 180
+	olcMinTrimLength = 6
+)
+
+// Latitude and longitude are computed as integers in units of the last pair and of the last grid digit
+const (
+	olcPairPrecision     = 8000
+	olcPairFirstPlace    = olcEncodingBase * olcEncodingBase * olcEncodingBase * olcEncodingBase
+	olcGridLatFirstPlace = olcGridRows * olcGridRows * olcGridRows * olcGridRows
+	olcGridLngFirstPlace = olcGridColumns * olcGridColumns * olcGridColumns * olcGridColumns
+	olcFinalLatPrecision = olcPairPrecision * olcGridLatFirstPlace * olcGridRows
+	olcFinalLngPrecision = olcPairPrecision * olcGridLngFirstPlace * olcGridColumns
+)
+
+// An Open Location Code in its canonical notation, with upper case digits
+type OLCCoord struct {
+	Code string                 `json:"code" xml:"code"`
+	El   *cartconvert.Ellipsoid `json:"ellipsoid,omitempty" xml:"ellipsoid,omitempty"`
+}
+
+// Canonical representation of an Open Location Code
+func (coord *OLCCoord) String() string {
+	return coord.Code
+}
+
+// Implements cartconvert.Coordinate, the system of an Open Location Code is "olc"
+func (coord *OLCCoord) System() string {
+	return "olc"
+}
+
+// Implements cartconvert.Coordinate, a code addresses an area of WGS84 latitude / longitude
+func (coord *OLCCoord) EPSG() int {
+	return cartconvert.EPSGWGS84
+}
+
+// Implements cartconvert.Coordinate, the center of the area addressed by the code, see OLCToWGS84LatLong
+func (coord *OLCCoord) ToWGS84() (*cartconvert.PolarCoord, error) {
+	gc, _, err := OLCToWGS84LatLong(coord)
+	return gc, err
+}
+
+// The area addressed by an Open Location Code, given by its south-west and north-east corner, and the number of digits
+// of the code
+type CodeArea struct {
+	SouthWest, NorthEast *cartconvert.PolarCoord
+	CodeLength           int
+}
+
+// Returns the center of the area. The center of an area at the north pole is clipped to latitude 90°.
+func (area *CodeArea) Center() *cartconvert.PolarCoord {
+	return &cartconvert.PolarCoord{
+		Latitude:  math.Min((area.SouthWest.Latitude+area.NorthEast.Latitude)/2, olcLatitudeMax),
+		Longitude: math.Min((area.SouthWest.Longitude+area.NorthEast.Longitude)/2, olcLongitudeMax),
+		El:        cartconvert.WGS84Ellipsoid}
+}
+
+// Returns whether code is a valid full or short code, given in upper case
+func validCode(code string) bool {
+
+	sep := strings.IndexRune(code, olcSeparator)
+	if sep < 0 || sep != strings.LastIndex(code, string(olcSeparator)) || sep > olcSeparatorPosition || sep%2 == 1 {
+		return false
+	}
+
+	// padding may only follow a pair up to the separator, which then ends the code
+	if pad := strings.IndexRune(code, olcPadding); pad >= 0 {
+		if sep < olcSeparatorPosition || pad == 0 || pad > sep || pad%2 == 1 || code[pad:sep] != strings.Repeat(string(olcPadding), sep-pad) || sep != len(code)-1 {
+			return false
+		}
+	}
+
+	// a single digit after the separator is not allowed
+	if len(code)-sep-1 == 1 {
+		return false
+	}
+
+	for i := range code {
+		if i != sep && code[i] != olcPadding && strings.IndexByte(olcAlphabet, code[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns whether code is a valid full code. The first pair must not exceed latitude 90° and longitude 180°.
+func fullCode(code string) bool {
+	if !validCode(code) || strings.IndexRune(code, olcSeparator) < olcSeparatorPosition {
+		return false
+	}
+	return strings.IndexByte(olcAlphabet, code[0])*olcEncodingBase < 2*olcLatitudeMax &&
+		strings.IndexByte(olcAlphabet, code[1])*olcEncodingBase < 2*olcLongitudeMax
+}
+
+// Parses a full Open Location Code into an Open Location Code struct. Digits may be given in upper or lower case.
+//
+// The reference ellipsoid of an Open Location Code will always be set to the WGS84 ellipsoid.
+//
+// returns cartconvert.ErrSyntax if the code is malformed
+// returns cartconvert.ErrRange if the code is a short code, which has to be recovered by RecoverOLC, or its
+// first pair exceeds latitude 90° or longitude 180°
+func AOLCToStruct(code string) (*OLCCoord, error) {
+
+	canonical := strings.ToUpper(strings.TrimSpace(code))
+	switch {
+	case !validCode(canonical):
+		return nil, cartconvert.ErrSyntax
+	case !fullCode(canonical):
+		return nil, cartconvert.ErrRange
+	}
+	return &OLCCoord{Code: canonical, El: cartconvert.WGS84Ellipsoid}, nil
+}
+
+// Transform a latitude / longitude coordinate on WGS84 into an Open Location Code of codeLength digits.
+// Latitudes beyond ±90° are clipped, longitudes are normalized to [-180°, 180°). The code addresses the area
+// containing the coordinate. Function returns cartconvert.ErrRange, if codeLength is less than 2 or odd
+// and less than 10. Code lengths beyond 15 are set to 15.
+func WGS84LatLongToOLC(gc *cartconvert.PolarCoord, codeLength int) (*OLCCoord, error) {
+
+	if codeLength < OLCMinLength || (codeLength < OLCPairLength && codeLength%2 == 1) {
+		return nil, cartconvert.ErrRange
+	}
+	if err := gc.CheckFinite(); err != nil {
+		return nil, err
+	}
+	if codeLength > OLCMaxLength {
+		codeLength = OLCMaxLength
+	}
+
+	// round to 6 decimal places of the integer units first, as floating point arithmetic may be just below a cell border
+	latVal := int64(math.Floor(math.Round(gc.Latitude*olcFinalLatPrecision*1e6) / 1e6))
+	latVal += olcLatitudeMax * olcFinalLatPrecision
+	if latVal < 0 {
+		latVal = 0
+	} else if latVal >= 2*olcLatitudeMax*olcFinalLatPrecision {
+		latVal = 2*olcLatitudeMax*olcFinalLatPrecision - 1
+	}
+
+	const lngRange = 2 * olcLongitudeMax * olcFinalLngPrecision
+	lngVal := int64(math.Floor(math.Round(gc.Longitude*olcFinalLngPrecision*1e6) / 1e6))
+	lngVal += olcLongitudeMax * olcFinalLngPrecision
+	lngVal = (lngVal%lngRange + lngRange) % lngRange
+
+	code := make([]byte, OLCMaxLength)
+	if codeLength > OLCPairLength {
+		for i := OLCMaxLength - 1; i >= OLCPairLength; i-- {
+			code[i] = olcAlphabet[latVal%olcGridRows*olcGridColumns+lngVal%olcGridColumns]
+			latVal /= olcGridRows
+			lngVal /= olcGridColumns
+		}
+	} else {
+		latVal /= olcGridLatFirstPlace * olcGridRows
+		lngVal /= olcGridLngFirstPlace * olcGridColumns
+	}
+	for i := OLCPairLength - 2; i >= 0; i -= 2 {
+		code[i] = olcAlphabet[latVal%olcEncodingBase]
+		code[i+1] = olcAlphabet[lngVal%olcEncodingBase]
+		latVal /= olcEncodingBase
+		lngVal /= olcEncodingBase
+	}
+
+	var olc string
+	if codeLength >= olcSeparatorPosition {
+		olc = string(code[:olcSeparatorPosition]) + string(olcSeparator) + string(code[olcSeparatorPosition:codeLength])
+	} else {
+		olc = string(code[:codeLength]) + strings.Repeat(string(olcPadding), olcSeparatorPosition-codeLength) + string(olcSeparator)
+	}
+	return &OLCCoord{Code: olc, El: cartconvert.WGS84Ellipsoid}, nil
+}
+
+// Transform an Open Location Code into a latitude / longitude coordinate on WGS84 at the center of the area
+// addressed by the code, together with the area. Function returns the error of AOLCToStruct, if the code is
+// not a valid full code.
+func OLCToWGS84LatLong(coord *OLCCoord) (*cartconvert.PolarCoord, *CodeArea, error) {
+
+	// validates the code, which may have been set directly
+	canonical, err := AOLCToStruct(coord.Code)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	digits := strings.Replace(strings.Replace(canonical.Code, string(olcSeparator), "", 1), string(olcPadding), "", -1)
+	if len(digits) > OLCMaxLength {
+		digits = digits[:OLCMaxLength]
+	}
+
+	normalLat, normalLng := int64(-olcLatitudeMax*olcPairPrecision), int64(-olcLongitudeMax*olcPairPrecision)
+	pv := int64(olcPairFirstPlace)
+	pairs := int(math.Min(float64(len(digits)), OLCPairLength))
+	for i := 0; i < pairs; i += 2 {
+		normalLat += int64(strings.IndexByte(olcAlphabet, digits[i])) * pv
+		normalLng += int64(strings.IndexByte(olcAlphabet, digits[i+1])) * pv
+		if i < pairs-2 {
+			pv /= olcEncodingBase
+		}
+	}
+	latSize, lngSize := float64(pv)/olcPairPrecision, float64(pv)/olcPairPrecision
+
+	var gridLat, gridLng int64
+	if len(digits) > OLCPairLength {
+		rowpv, colpv := int64(olcGridLatFirstPlace), int64(olcGridLngFirstPlace)
+		for i := OLCPairLength; i < len(digits); i++ {
+			digit := int64(strings.IndexByte(olcAlphabet, digits[i]))
+			gridLat += digit / olcGridColumns * rowpv
+			gridLng += digit % olcGridColumns * colpv
+			if i < len(digits)-1 {
+				rowpv /= olcGridRows
+				colpv /= olcGridColumns
+			}
+		}
+		latSize, lngSize = float64(rowpv)/olcFinalLatPrecision, float64(colpv)/olcFinalLngPrecision
+	}
+
+	lat := float64(normalLat)/olcPairPrecision + float64(gridLat)/olcFinalLatPrecision
+	lng := float64(normalLng)/olcPairPrecision + float64(gridLng)/olcFinalLngPrecision
+
+	area := &CodeArea{
+		SouthWest:  &cartconvert.PolarCoord{Latitude: lat, Longitude: lng, El: cartconvert.WGS84Ellipsoid},
+		NorthEast:  &cartconvert.PolarCoord{Latitude: lat + latSize, Longitude: lng + lngSize, El: cartconvert.WGS84Ellipsoid},
+		CodeLength: len(digits)}
+	return area.Center(), area, nil
+}
+
+// Returns the size in degrees of the area of a code, whose first padding digits are omitted
+func olcResolution(padding int) float64 {
+	return math.Pow(olcEncodingBase, 2-float64(padding)/2)
+}
+
+// Shorten an Open Location Code by omitting 4, 6 or 8 leading digits, which can be recovered by RecoverOLC from the
+// reference location, if it lies close enough to the center of the code. The code is returned unaltered, if it
+// can not be shortened. Function returns the error of AOLCToStruct, if the code is not a valid full code, and
+// cartconvert.ErrRange, if the code is padded or has less than 6 digits.
+func ShortenOLC(coord *OLCCoord, ref *cartconvert.PolarCoord) (string, error) {
+
+	canonical, err := AOLCToStruct(coord.Code)
+	if err != nil {
+		return "", err
+	}
+	center, area, err := OLCToWGS84LatLong(canonical)
+	if err != nil {
+		return "", err
+	}
+	if strings.IndexRune(canonical.Code, olcPadding) >= 0 || area.CodeLength < olcMinTrimLength {
+		return "", cartconvert.ErrRange
+	}
+
+	lat := math.Max(-olcLatitudeMax, math.Min(olcLatitudeMax, ref.Latitude))
+	lng := normalizeLongitude(ref.Longitude)
+	distance := math.Max(math.Abs(center.Latitude-lat), math.Abs(center.Longitude-lng))
+
+	// the reference has to be within half the size of the recovered area; 0.3 leaves a margin of safety
+	for omit := olcSeparatorPosition; omit >= 4; omit -= 2 {
+		if distance < olcResolution(omit)*0.3 {
+			return canonical.Code[omit:], nil
+		}
+	}
+	return canonical.Code, nil
+}
+
+// Returns longitude normalized to [-180°, 180°)
+func normalizeLongitude(long float64) float64 {
+	long = math.Mod(long+olcLongitudeMax, 2*olcLongitudeMax)
+	if long < 0 {
+		long += 2 * olcLongitudeMax
+	}
+	return long - olcLongitudeMax
+}
+
+// Recover the full Open Location Code of a short code, which is the code of the area nearest to the reference
+// location. A full code is returned in its canonical notation. Function returns cartconvert.ErrSyntax, if the code
+// is neither a valid full nor short code.
+func RecoverOLC(code string, ref *cartconvert.PolarCoord) (*OLCCoord, error) {
+
+	short := strings.ToUpper(strings.TrimSpace(code))
+	if fullCode(short) {
+		return &OLCCoord{Code: short, El: cartconvert.WGS84Ellipsoid}, nil
+	}
+	if !validCode(short) || strings.IndexRune(short, olcPadding) >= 0 {
+		return nil, cartconvert.ErrSyntax
+	}
+
+	lat := math.Max(-olcLatitudeMax, math.Min(olcLatitudeMax, ref.Latitude))
+	lng := normalizeLongitude(ref.Longitude)
+
+	// the omitted digits are taken from the code of the reference location
+	padding := olcSeparatorPosition - strings.IndexRune(short, olcSeparator)
+	refcode, err := WGS84LatLongToOLC(&cartconvert.PolarCoord{Latitude: lat, Longitude: lng}, OLCPairLength)
+	if err != nil {
+		return nil, err
+	}
+	center, area, err := OLCToWGS84LatLong(&OLCCoord{Code: refcode.Code[:padding] + short})
+	if err != nil {
+		return nil, err
+	}
+
+	// move the area by its size towards the reference, if the reference is nearer to the neighbouring area
+	resolution := olcResolution(padding)
+	switch {
+	case lat+resolution/2 < center.Latitude && center.Latitude-resolution >= -olcLatitudeMax:
+		center.Latitude -= resolution
+	case lat-resolution/2 > center.Latitude && center.Latitude+resolution <= olcLatitudeMax:
+		center.Latitude += resolution
+	}
+	switch {
+	case lng+resolution/2 < center.Longitude:
+		center.Longitude -= resolution
+	case lng-resolution/2 > center.Longitude:
+		center.Longitude += resolution
+	}
+
+	return WGS84LatLongToOLC(center, area.CodeLength)
+}
+
+// Returns reference points of Open Location Codes, for tests and documentation, taken from the test data of the
+// reference implementation. The locations are the centers of the code areas.
+func Examples() []cartconvert.Example {
+	return []cartconvert.Example{
+		{Name: "Algeria", Input: "7FG49QCJ+2V", LatLong: &cartconvert.PolarCoord{Latitude: 20.3700625, Longitude: 2.7821875, El: cartconvert.WGS84Ellipsoid}, Tolerance: 0.01},
+		{Name: "Switzerland", Input: "8FVC2222+22", LatLong: &cartconvert.PolarCoord{Latitude: 47.0000625, Longitude: 8.0000625, El: cartconvert.WGS84Ellipsoid}, Tolerance: 0.01},
+		{Name: "Wellington", Input: "4VCPPQGP+Q9", LatLong: &cartconvert.PolarCoord{Latitude: -41.2730625, Longitude: 174.7859375, El: cartconvert.WGS84Ellipsoid}, Tolerance: 0.01},
+	}
+}
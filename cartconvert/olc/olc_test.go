@@ -0,0 +1,263 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// Automated tests for the cartconvert/olc (Open Location Code) package
+package olc
+
+import (
+	"github.com/the42/cartconvert/cartconvert"
+	"math"
+	"testing"
+)
+
+// ## AOLCToStruct
+type aOLCToStructTest struct {
+	in  string
+	out string
+	err error
+}
+
+var aOLCToStructTests = []aOLCToStructTest{
+	{"8FVC9G8F+6X", "8FVC9G8F+6X", nil},
+	{" 8fvc9g8f+6xq ", "8FVC9G8F+6XQ", nil},
+	{"8FVC0000+", "8FVC0000+", nil},
+	{"8FVC9G8F+", "8FVC9G8F+", nil},
+	// short codes have to be recovered
+	{"9G8F+6X", "", cartconvert.ErrRange},
+	// the first pair exceeds latitude 90° resp. longitude 180°
+	{"XFVC9G8F+6X", "", cartconvert.ErrRange},
+	{"8XVC9G8F+6X", "", cartconvert.ErrRange},
+	{"8FVC9G8F6X", "", cartconvert.ErrSyntax},
+	{"8FVC9G8F++6X", "", cartconvert.ErrSyntax},
+	{"8FVC9G8F+6", "", cartconvert.ErrSyntax},
+	{"8FVC9G8FA+", "", cartconvert.ErrSyntax},
+	{"8FVC9G8+6X", "", cartconvert.ErrSyntax},
+	{"8FVC9GAF+6X", "", cartconvert.ErrSyntax},
+	{"8FV00000+", "", cartconvert.ErrSyntax},
+	{"8F0C0000+", "", cartconvert.ErrSyntax},
+	{"00000000+", "", cartconvert.ErrSyntax},
+	{"8FVC0000+6X", "", cartconvert.ErrSyntax},
+	{"8FVC9G8F+60", "", cartconvert.ErrSyntax},
+	{"", "", cartconvert.ErrSyntax},
+}
+
+func TestAOLCToStruct(t *testing.T) {
+	for index, test := range aOLCToStructTests {
+		out, err := AOLCToStruct(test.in)
+
+		if err != test.err {
+			t.Errorf("AOLCToStruct [%d]: expected error %v, got %v", index, test.err, err)
+			continue
+		}
+		if test.err == nil && out.String() != test.out {
+			t.Errorf("AOLCToStruct [%d]: expected %s, got %s", index, test.out, out)
+		}
+	}
+}
+
+// ## WGS84LatLongToOLC
+type wGS84LatLongToOLCTest struct {
+	lat, long  float64
+	codeLength int
+	out        string
+}
+
+// Values taken from the test data of the reference implementation
+var wGS84LatLongToOLCTests = []wGS84LatLongToOLCTest{
+	{20.375, 2.775, 6, "7FG49Q00+"},
+	{20.3700625, 2.7821875, 10, "7FG49QCJ+2V"},
+	{20.3701125, 2.782234375, 11, "7FG49QCJ+2VX"},
+	{20.3701135, 2.78223535156, 13, "7FG49QCJ+2VXGJ"},
+	{47.0000625, 8.0000625, 10, "8FVC2222+22"},
+	{-41.2730625, 174.7859375, 10, "4VCPPQGP+Q9"},
+	{0.5, -179.5, 4, "62G20000+"},
+	{-89.5, -179.5, 4, "22220000+"},
+	{20.5, 2.5, 4, "7FG40000+"},
+	{-89.9999375, -179.9999375, 10, "22222222+22"},
+	{0.5, 179.5, 4, "6VGX0000+"},
+	{1, 1, 11, "6FH32222+222"},
+	// the north pole belongs to the northernmost area, latitudes beyond are clipped
+	{90, 1, 4, "CFX30000+"},
+	{92, 1, 4, "CFX30000+"},
+	{90, 1, 10, "CFX3X2X2+X2"},
+	// longitudes are normalized
+	{1, 180, 4, "62H20000+"},
+	{1, 181, 4, "62H30000+"},
+	// code lengths beyond 15 are set to 15
+	{20.3701135, 2.78223535156, 16, "7FG49QCJ+2VXGJFH"},
+}
+
+func TestWGS84LatLongToOLC(t *testing.T) {
+	for index, test := range wGS84LatLongToOLCTests {
+		out, err := WGS84LatLongToOLC(&cartconvert.PolarCoord{Latitude: test.lat, Longitude: test.long}, test.codeLength)
+		if err != nil {
+			t.Errorf("WGS84LatLongToOLC [%d]: %s", index, err)
+			continue
+		}
+		if out.String() != test.out {
+			t.Errorf("WGS84LatLongToOLC [%d]: expected %s, got %s", index, test.out, out)
+		}
+	}
+
+	for _, codeLength := range []int{0, 1, 3, 5, 7, 9} {
+		if _, err := WGS84LatLongToOLC(&cartconvert.PolarCoord{Latitude: 47, Longitude: 8}, codeLength); err != cartconvert.ErrRange {
+			t.Errorf("WGS84LatLongToOLC: expected ErrRange for code length %d, got %v", codeLength, err)
+		}
+	}
+}
+
+// ## OLCToWGS84LatLong
+type oLCToWGS84LatLongTest struct {
+	in                           string
+	codeLength                   int
+	latLo, longLo, latHi, longHi float64
+}
+
+// Values taken from the test data of the reference implementation
+var oLCToWGS84LatLongTests = []oLCToWGS84LatLongTest{
+	{"7FG49Q00+", 6, 20.35, 2.75, 20.4, 2.8},
+	{"7FG49QCJ+2V", 10, 20.37, 2.782125, 20.370125, 2.78225},
+	{"7FG49QCJ+2VX", 11, 20.3701, 2.78221875, 20.370125, 2.78225},
+	{"7FG49QCJ+2VXGJ", 13, 20.370113, 2.782234375, 20.370114, 2.78223632813},
+	{"8FVC2222+22", 10, 47.0, 8.0, 47.000125, 8.000125},
+	{"4VCPPQGP+Q9", 10, -41.273125, 174.785875, -41.273, 174.786},
+	{"62G20000+", 4, 0.0, -180.0, 1, -179},
+	{"22220000+", 4, -90, -180, -89, -179},
+	{"CFX30000+", 4, 89, 1, 90, 2},
+}
+
+func TestOLCToWGS84LatLong(t *testing.T) {
+	for index, test := range oLCToWGS84LatLongTests {
+		center, area, err := OLCToWGS84LatLong(&OLCCoord{Code: test.in})
+		if err != nil {
+			t.Errorf("OLCToWGS84LatLong [%d]: %s", index, err)
+			continue
+		}
+
+		if area.CodeLength != test.codeLength ||
+			math.Abs(area.SouthWest.Latitude-test.latLo) > 1e-10 || math.Abs(area.SouthWest.Longitude-test.longLo) > 1e-10 ||
+			math.Abs(area.NorthEast.Latitude-test.latHi) > 1e-10 || math.Abs(area.NorthEast.Longitude-test.longHi) > 1e-10 {
+			t.Errorf("OLCToWGS84LatLong [%d]: expected %d digits (%f, %f) (%f, %f), got %d digits (%f, %f) (%f, %f)", index,
+				test.codeLength, test.latLo, test.longLo, test.latHi, test.longHi,
+				area.CodeLength, area.SouthWest.Latitude, area.SouthWest.Longitude, area.NorthEast.Latitude, area.NorthEast.Longitude)
+		}
+
+		if center.Latitude != (area.SouthWest.Latitude+area.NorthEast.Latitude)/2 || center.Longitude != (area.SouthWest.Longitude+area.NorthEast.Longitude)/2 {
+			t.Errorf("OLCToWGS84LatLong [%d]: expected the center of the area, got (%f, %f)", index, center.Latitude, center.Longitude)
+		}
+	}
+
+	if _, _, err := OLCToWGS84LatLong(&OLCCoord{Code: "9G8F+6X"}); err != cartconvert.ErrRange {
+		t.Errorf("OLCToWGS84LatLong: expected ErrRange for a short code, got %v", err)
+	}
+}
+
+// ## ShortenOLC
+type shortenOLCTest struct {
+	code      string
+	lat, long float64
+	short     string
+}
+
+// Values taken from the test data of the reference implementation
+var shortenOLCTests = []shortenOLCTest{
+	{"9C3W9QCJ+2VX", 51.3701125, -1.217765625, "+2VX"},
+	{"9C3W9QCJ+2VX", 51.3708675, -1.217765625, "CJ+2VX"},
+	{"9C3W9QCJ+2VX", 51.3693575, -1.217765625, "CJ+2VX"},
+	{"9C3W9QCJ+2VX", 51.3701125, -1.218520625, "CJ+2VX"},
+	{"9C3W9QCJ+2VX", 51.3701125, -1.217010625, "CJ+2VX"},
+	{"9C3W9QCJ+2VX", 51.3852125, -1.217765625, "9QCJ+2VX"},
+	{"9C3W9QCJ+2VX", 51.3550125, -1.217765625, "9QCJ+2VX"},
+	{"9C3W9QCJ+2VX", 51.3701125, -1.232865625, "9QCJ+2VX"},
+	{"9C3W9QCJ+2VX", 51.3701125, -1.202665625, "9QCJ+2VX"},
+	// too far away to be shortened
+	{"9C3W9QCJ+2VX", 48.3701125, -1.217765625, "9C3W9QCJ+2VX"},
+	// distances are not measured across the antimeridian
+	{"8VPX2X2R+22", 44.0, -179.99, "8VPX2X2R+22"},
+}
+
+func TestShortenOLC(t *testing.T) {
+	for index, test := range shortenOLCTests {
+		short, err := ShortenOLC(&OLCCoord{Code: test.code}, &cartconvert.PolarCoord{Latitude: test.lat, Longitude: test.long})
+		if err != nil {
+			t.Errorf("ShortenOLC [%d]: %s", index, err)
+			continue
+		}
+		if short != test.short {
+			t.Errorf("ShortenOLC [%d]: expected %s, got %s", index, test.short, short)
+		}
+	}
+
+	for _, code := range []string{"9C3W0000+", "9C3W9Q00+", "9C3W+"} {
+		if _, err := ShortenOLC(&OLCCoord{Code: code}, &cartconvert.PolarCoord{Latitude: 51.3701125, Longitude: -1.217765625}); err == nil {
+			t.Errorf("ShortenOLC: expected error for %s", code)
+		}
+	}
+}
+
+// ## RecoverOLC
+type recoverOLCTest struct {
+	short     string
+	lat, long float64
+	code      string
+}
+
+var recoverOLCTests = []recoverOLCTest{
+	// the reverse of shortening
+	{"+2VX", 51.3701125, -1.217765625, "9C3W9QCJ+2VX"},
+	{"cj+2vx", 51.3708675, -1.217765625, "9C3W9QCJ+2VX"},
+	{"9QCJ+2VX", 51.3550125, -1.217765625, "9C3W9QCJ+2VX"},
+	{"2222+22", 20.0, 0.0, "7FG22222+22"},
+	// the nearest area lies south-west of the area of the reference
+	{"XXXX+XX", 20.0, 0.0, "7CFXXXXX+XX"},
+	// the nearest area lies across the antimeridian
+	{"2X2R+22", 44.0, -179.99, "8VPX2X2R+22"},
+	// full codes are returned in canonical notation
+	{"8fvc9g8f+6x", 0, 0, "8FVC9G8F+6X"},
+}
+
+func TestRecoverOLC(t *testing.T) {
+	for index, test := range recoverOLCTests {
+		out, err := RecoverOLC(test.short, &cartconvert.PolarCoord{Latitude: test.lat, Longitude: test.long})
+		if err != nil {
+			t.Errorf("RecoverOLC [%d]: %s", index, err)
+			continue
+		}
+		if out.String() != test.code {
+			t.Errorf("RecoverOLC [%d]: expected %s, got %s", index, test.code, out)
+		}
+	}
+
+	for _, code := range []string{"9G8F6X", "9G00+", "9G8F+6"} {
+		if _, err := RecoverOLC(code, &cartconvert.PolarCoord{Latitude: 47, Longitude: 8}); err != cartconvert.ErrSyntax {
+			t.Errorf("RecoverOLC: expected ErrSyntax for %s, got %v", code, err)
+		}
+	}
+}
+
+// ## OLCRoundTrip
+func TestOLCRoundTrip(t *testing.T) {
+	for index, test := range wGS84LatLongToOLCTests {
+		code, _ := WGS84LatLongToOLC(&cartconvert.PolarCoord{Latitude: test.lat, Longitude: test.long}, test.codeLength)
+		center, _, err := OLCToWGS84LatLong(code)
+		if err != nil {
+			t.Errorf("OLCRoundTrip [%d]: %s", index, err)
+			continue
+		}
+
+		back, _ := WGS84LatLongToOLC(center, test.codeLength)
+		if back.String() != code.String() {
+			t.Errorf("OLCRoundTrip [%d]: expected %s, got %s", index, code, back)
+		}
+	}
+}
+
+// ## NaN and infinity
+func TestNonFinite(t *testing.T) {
+	for _, value := range []float64{math.NaN(), math.Inf(1)} {
+		if _, err := WGS84LatLongToOLC(&cartconvert.PolarCoord{Latitude: 47.57, Longitude: value}, 10); err != cartconvert.ErrNotFinite {
+			t.Errorf("WGS84LatLongToOLC (%v): expected ErrNotFinite, got %v", value, err)
+		}
+	}
+}
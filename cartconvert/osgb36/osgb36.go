@@ -15,6 +15,7 @@
 package osgb36
 
 import (
+	"encoding/xml"
 	"fmt"
 	"github.com/the42/cartconvert/cartconvert"
 	"math"
@@ -22,13 +23,20 @@ import (
 	"strings"
 )
 
+// Geographic extent of the Ordnance Survey National Grid, Great Britain including the offshore islands
+var Extent = &cartconvert.LatLongExtent{MinLat: 49.75, MaxLat: 61.01, MinLong: -9.01, MaxLong: 2.01}
+
 // A OSGB36 coordinate is specified by zone, easting and northing.
 type OSGB36Coord struct {
-	Easting, Northing uint
-	RelHeight         float64
-	Zone              string
-	El                *cartconvert.Ellipsoid
-	gridLen           byte
+	Easting   uint                   `json:"easting" xml:"easting"`
+	Northing  uint                   `json:"northing" xml:"northing"`
+	RelHeight float64                `json:"relheight" xml:"relheight"`
+	Zone      string                 `json:"zone" xml:"zone"`
+	El        *cartconvert.Ellipsoid `json:"ellipsoid,omitempty" xml:"ellipsoid,omitempty"`
+	// Approximate positional error in meters introduced by the datum shift of the conversion from WGS84, the
+	// accuracy of the helmert parameter set used. Zero for coordinates not converted from WGS84.
+	Accuracy float64 `json:"accuracy,omitempty" xml:"accuracy,omitempty"`
+	gridLen  byte
 }
 
 // Controls formatting of an OSGB36 coordinate.
@@ -55,6 +63,22 @@ const (
 	OSGB36Auto  = OSGB36Leave + 1
 )
 
+// The location within the square denoted by a grid reference, a coordinate gets converted to.
+//
+//    OSGB36AnchorDefault - the middle of squares of 1 km down to 10 m, the south-west corner of the 10 km and 100 km squares.
+//       This is the location returned by OSGB36ZoneToRefCoords and OSGB36ToWGS84LatLong.
+//    OSGB36Center - the middle of the square at any precision, including the bare 100 km zone
+//    OSGB36SouthWest - the south-west corner of the square at any precision, the origin of the square
+//
+// A grid reference to the accuracy of a meter denotes the same location for all anchors.
+type OSGB36Anchor byte
+
+const (
+	OSGB36AnchorDefault OSGB36Anchor = iota
+	OSGB36Center
+	OSGB36SouthWest
+)
+
 // Canonical representation of a OSGB36 datum.
 func (coord *OSGB36Coord) String() string {
 	if coord.gridLen > 0 {
@@ -63,6 +87,80 @@ func (coord *OSGB36Coord) String() string {
 	return coord.Zone
 }
 
+// Implements cartconvert.Coordinate, the system of an OSGB36 grid reference is "osgb36"
+func (coord *OSGB36Coord) System() string {
+	return "osgb36"
+}
+
+// Implements cartconvert.Coordinate, see OSGB36ToWGS84LatLongChecked
+func (coord *OSGB36Coord) ToWGS84() (*cartconvert.PolarCoord, error) {
+	return OSGB36ToWGS84LatLongChecked(coord)
+}
+
+// Formats the grid reference with figures digits of easting and northing together, 0, 2, 4, 6, 8 or 10, grouped
+// by blanks: "TQ" for the 100 km square, "TQ 1 6" to the accuracy of 10 km, "TQ 12 67" of 1 km, down to
+// "TQ 12345 67890" of a meter. Following the convention of the Ordnance Survey, the digits are truncated, not
+// rounded, so the grid reference denotes the square containing the coordinate by its south-west corner. A
+// coordinate of lower precision than requested is padded with zeros, denoting the south-west corner of its square.
+// Like OSGB36ZoneToRefCoords, easting and northing are taken as meters within the 100 km square, as parsed by
+// AOSGB36ToStruct with OSGB36Leave and converted by WGS84LatLongToOSGB36.
+//
+// returns cartconvert.ErrRange if figures is not one of 0, 2, 4, 6, 8 or 10
+func (coord *OSGB36Coord) FormatFigures(figures int) (string, error) {
+
+	if figures < 0 || figures > 2*int(OSGB36_Max) || figures%2 != 0 {
+		return "", cartconvert.ErrRange
+	}
+	if figures == 0 {
+		return coord.Zone, nil
+	}
+
+	// easting and northing are the meters within the 100 km square, truncated to the size of the requested square
+	digits := figures / 2
+	fact := uint(math.Pow(10, float64(int(OSGB36_Max)-digits)))
+
+	return fmt.Sprintf("%s %0*d %0*d", coord.Zone, digits, coord.Easting/fact, digits, coord.Northing/fact), nil
+}
+
+// Implements json.Marshaler, writing the coordinates in fixed-point notation
+func (coord *OSGB36Coord) MarshalJSON() ([]byte, error) {
+	return cartconvert.MarshalFixedJSON(coord)
+}
+
+// Implements xml.Marshaler, writing the coordinates in fixed-point notation
+func (coord *OSGB36Coord) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return cartconvert.MarshalFixedXML(e, start, coord)
+}
+
+// Validates that the OSGB36 coordinate lies within Extent, Great Britain. Returns a cartconvert.ExtentError, if
+// zone, easting and northing transform to a location outside of Extent, or the error of OSGB36ZoneToRefCoordsAnchor,
+// if the zone is no square of the grid.
+func (coord *OSGB36Coord) Valid() error {
+	gc, err := OSGB36ToWGS84LatLongHelmert(coord, nil)
+	if err != nil {
+		return err
+	}
+	return Extent.Validate(gc, "OSGB36", coord.String())
+}
+
+// EPSG code of the National Grid, OSGB 1936 / British National Grid
+const EPSG = 27700
+
+// Returns the EPSG code of the National Grid
+func (coord *OSGB36Coord) EPSG() int {
+	return EPSG
+}
+
+// The National Grid is registered as coordinate reference system of OSGB36
+func init() {
+	crs := &cartconvert.CRS{EPSG: EPSG, Name: "OSGB 1936 / British National Grid", Datum: cartconvert.DatumOSGB36,
+		El: cartconvert.Airy1830Ellipsoid, Method: cartconvert.MethodTransverseMercator,
+		LatO: 49, LongO: -2, Scale: 0.9996012717, FalseEasting: 400000, FalseNorthing: -100000}
+	if err := cartconvert.RegisterCRS(crs); err != nil {
+		panic(err)
+	}
+}
+
 // Parses a string representation of an OSGB36 coordinate datum into a OSGB36 coordinate struct. The literal
 // can be specified as follows:
 //    ZO EA NO
@@ -75,7 +173,8 @@ func (coord *OSGB36Coord) String() string {
 // The reference ellipsoid of an OSGB36 will always be set to the Airy1830 ellipsoid.
 //
 // returns cartconvert.ErrSyntax if format is not understood
-// returns cartconvert.ErrRange if values are outside the defined parameters for an OSGB36 bearing
+// returns cartconvert.ErrRange if values are outside the defined parameters for an OSGB36 bearing, like a zone with
+// the unused letter 'I' or a zone west or south of the false origin in square SV
 func AOSGB36ToStruct(osgb36coord string, prec OSGB36prec) (*OSGB36Coord, error) {
 
 	compact := strings.ToUpper(strings.TrimSpace(osgb36coord))
@@ -99,6 +198,9 @@ L1:
 	if zl == 0 || zl > 2 {
 		return nil, cartconvert.ErrSyntax
 	}
+	if _, _, err := zoneToGridIndexes(zone); err != nil {
+		return nil, err
+	}
 
 	ennlen := byte(len(enn))
 	if ennlen > 0 {
@@ -123,38 +225,46 @@ L1:
 	return NewOSGB36Coord(zone, uint(east), uint(north), 0, ennlen, prec), nil
 }
 
-// Returns northing and easting based on OSGB36 zone specifier relative to false northing and easting
+// Returns northing and easting based on OSGB36 zone specifier relative to false northing and easting. Both are 0
+// for a zone which is not a square of the National Grid, see OSGB36ZoneToRefCoordsAnchor.
 func OSGB36ZoneToRefCoords(coord *OSGB36Coord) (easting, northing uint) {
-	var l1, l2 uint
+	easting, northing, _ = OSGB36ZoneToRefCoordsAnchor(coord, OSGB36AnchorDefault)
+	return
+}
 
-	// get numeric values of letter references, mapping A->0, B->1, C->2, etc:
-	l1 = uint(coord.Zone[0] - 'A')
-	if len(coord.Zone) > 1 {
-		l2 = uint(coord.Zone[1] - 'A')
+// Returns northing and easting based on OSGB36 zone specifier relative to false northing and easting, located
+// within the square denoted by the grid reference according to anchor. Function returns cartconvert.ErrRange,
+// if anchor is unknown or if the zone is not a square east and north of the false origin, and cartconvert.ErrSyntax,
+// if the zone is not made of letters.
+func OSGB36ZoneToRefCoordsAnchor(coord *OSGB36Coord, anchor OSGB36Anchor) (easting, northing uint, err error) {
+	easting100k, northing100k, err := zoneToGridIndexes(coord.Zone)
+	if err != nil {
+		return 0, 0, err
 	}
-
-	// shuffle down letters after 'I' since 'I' is not used in grid:
-	if l1 > 7 {
-		l1--
-	}
-	if l2 > 7 {
-		l2--
-	}
-
-	// convert grid letters into 100km-square indexes from false origin (grid square SV):
-	easting = (((l1-2)%5)*5 + l2%5) * 100000
-	northing = ((19 - l1/5*5) - l2/5) * 100000
+	easting = easting100k * 100000
+	northing = northing100k * 100000
 
 	// append numeric part of references to grid index:
 	easting += coord.Easting
 	northing += coord.Northing
 
-	// if only the grid zone was specified, return the location at point.
-	// for all other specified vales return location at middle of square
+	// the size of the square denoted by the grid reference
 	fact := uint(math.Pow(10, float64(byte(OSGB36_Max)-coord.gridLen)))
-	if fact < 10000 {
-		easting += 5 * (fact / 10)
-		northing += 5 * (fact / 10)
+
+	switch anchor {
+	case OSGB36AnchorDefault:
+		// if only the grid zone was specified, return the location at point.
+		// for all other specified vales return location at middle of square
+		if fact < 10000 {
+			easting += 5 * (fact / 10)
+			northing += 5 * (fact / 10)
+		}
+	case OSGB36Center:
+		easting += fact / 2
+		northing += fact / 2
+	case OSGB36SouthWest:
+	default:
+		return 0, 0, cartconvert.ErrRange
 	}
 	return
 }
@@ -165,9 +275,56 @@ func OSGB36ZoneToRefCoords(coord *OSGB36Coord) (easting, northing uint) {
 // For the point at NN1000010000 it is necessary to fully qualify northing and easting.
 //
 // Plain grid zone specifiers will NOT be shifted towards the middle of the square.
+//
+// Easting and northing of a grid reference are integral and can't be NaN or infinite. Returns nil, if the grid
+// reference can't be converted, eg. of an unknown zone, see OSGB36ToWGS84LatLongChecked.
 func OSGB36ToWGS84LatLong(coord *OSGB36Coord) *cartconvert.PolarCoord {
+	gc, _ := OSGB36ToWGS84LatLongChecked(coord)
+	return gc
+}
 
-	easting, northing := OSGB36ZoneToRefCoords(coord)
+// Convert an OSGB36 coordinate value to a WGS84 based latitude and longitude coordinate like OSGB36ToWGS84LatLong.
+// Function returns the error of the conversion, eg. cartconvert.ErrRange for a zone of no grid square.
+func OSGB36ToWGS84LatLongChecked(coord *OSGB36Coord) (*cartconvert.PolarCoord, error) {
+	return OSGB36ToWGS84LatLongHelmert(coord, nil)
+}
+
+// Returns the helmert parameter set used for the datum shift between WGS84 and OSGB36. If set is nil,
+// the default parameter set for WGS84 to OSGB36 is returned. Returns cartconvert.ErrRange if the
+// parameter set does not transform from WGS84 to OSGB36.
+func helmertParameterSet(set *cartconvert.HelmertParameterSet) (*cartconvert.HelmertParameterSet, error) {
+	if set == nil {
+		return cartconvert.DefaultHelmertParameterSet(cartconvert.DatumWGS84, cartconvert.DatumOSGB36)
+	}
+	if set.From != cartconvert.DatumWGS84 || set.To != cartconvert.DatumOSGB36 {
+		return nil, cartconvert.ErrRange
+	}
+	return set, nil
+}
+
+// Convert an OSGB36 coordinate value to a WGS84 based latitude and longitude coordinate using the
+// helmert parameter set for the datum shift. If set is nil, the default parameter set from WGS84 to
+// OSGB36 is used. Function returns cartconvert.ErrRange, if the parameter set does not transform from
+// WGS84 to OSGB36.
+func OSGB36ToWGS84LatLongHelmert(coord *OSGB36Coord, set *cartconvert.HelmertParameterSet) (*cartconvert.PolarCoord, error) {
+	return OSGB36ToWGS84LatLongAnchor(coord, OSGB36AnchorDefault, set)
+}
+
+// Convert an OSGB36 coordinate value to a WGS84 based latitude and longitude coordinate located within the
+// square denoted by the grid reference according to anchor, using the helmert parameter set for the datum
+// shift as by OSGB36ToWGS84LatLongHelmert. Function returns cartconvert.ErrRange, if anchor is unknown or if
+// the parameter set does not transform from WGS84 to OSGB36.
+func OSGB36ToWGS84LatLongAnchor(coord *OSGB36Coord, anchor OSGB36Anchor, set *cartconvert.HelmertParameterSet) (*cartconvert.PolarCoord, error) {
+
+	hp, err := helmertParameterSet(set)
+	if err != nil {
+		return nil, err
+	}
+
+	easting, northing, err := OSGB36ZoneToRefCoordsAnchor(coord, anchor)
+	if err != nil {
+		return nil, err
+	}
 
 	gc := cartconvert.InverseTransverseMercator(
 		&cartconvert.GeoPoint{Y: float64(northing), X: float64(easting), El: coord.El},
@@ -178,9 +335,9 @@ func OSGB36ToWGS84LatLong(coord *OSGB36Coord) *cartconvert.PolarCoord {
 		-100000)
 
 	cart := cartconvert.PolarToCartesian(gc)
-	pt := cartconvert.HelmertWGS84ToOSGB36.InverseTransform(&cartconvert.Point3D{X: cart.X, Y: cart.Y, Z: cart.Z})
+	pt := hp.InverseTransform(&cartconvert.Point3D{X: cart.X, Y: cart.Y, Z: cart.Z})
 
-	return cartconvert.CartesianToPolar(&cartconvert.CartPoint{X: pt.X, Y: pt.Y, Z: pt.Z, El: cartconvert.WGS84Ellipsoid})
+	return cartconvert.CartesianToPolar(&cartconvert.CartPoint{X: pt.X, Y: pt.Y, Z: pt.Z, El: cartconvert.WGS84Ellipsoid}), nil
 }
 
 // Perform formating on an OSGB36 datum. For formatting see OSGB36prec.
@@ -228,32 +385,100 @@ func SanitizeOSGB36CoordToPrec(easting, northing *uint, inputprec byte, desiredp
 	return byte(desiredprec)
 }
 
+// The National Grid extends over 7 x 13 squares of 100km from the false origin, square SV in the south-west to
+// square JM in the north-east
+const (
+	gridSquaresEast  = 7
+	gridSquaresNorth = 13
+)
+
+// gridLetterIndex returns the index of a grid letter within the 5 x 5 block of the letters A to Z without 'I',
+// counted row by row from A in the north-west to Z in the south-east. The block lays out the 500km squares, as
+// the first letter of a zone, and the 100km squares within them, as the second letter.
+//
+// returns cartconvert.ErrSyntax if letter is not a letter and cartconvert.ErrRange for 'I'
+func gridLetterIndex(letter byte) (int, error) {
+	switch {
+	case letter < 'A' || letter > 'Z':
+		return 0, cartconvert.ErrSyntax
+	case letter == 'I':
+		return 0, cartconvert.ErrRange
+	case letter > 'I':
+		return int(letter-'A') - 1, nil
+	}
+	return int(letter - 'A'), nil
+}
+
+// gridLetter returns the grid letter of an index of gridLetterIndex, skipping 'I'
+//
+// returns cartconvert.ErrRange if index lies outside of the 5 x 5 block
+func gridLetter(index int) (byte, error) {
+	if index < 0 || index >= 25 {
+		return 0, cartconvert.ErrRange
+	}
+	if index >= 'I'-'A' {
+		index++
+	}
+	return byte('A' + index), nil
+}
+
+// zoneToGridIndexes returns the indexes of the 100km square of zone, eastwards and northwards from the false origin
+// in square SV. The indexes are computed signed, so a square west or south of the false origin is detected instead of
+// wrapping around. A zone of a single letter denotes the north-west 100km square of the 500km square.
+//
+// returns cartconvert.ErrSyntax if zone is not made of one or two letters and cartconvert.ErrRange if it is not a
+// square of the grid east and north of the false origin
+func zoneToGridIndexes(zone string) (easting100k, northing100k uint, err error) {
+	if len(zone) == 0 || len(zone) > 2 {
+		return 0, 0, cartconvert.ErrSyntax
+	}
+
+	l1, err := gridLetterIndex(zone[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	var l2 int
+	if len(zone) > 1 {
+		if l2, err = gridLetterIndex(zone[1]); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	// the false origin lies in the 500km square S, the fourth row and third column of the block
+	east := (l1%5-2)*5 + l2%5
+	north := (3-l1/5)*5 + 4 - l2/5
+	if east < 0 || north < 0 {
+		return 0, 0, cartconvert.ErrRange
+	}
+	return uint(east), uint(north), nil
+}
+
 // Build OSGB36 coordinate from easting and northing relative to Grid. The parameter prec controls
 // how the resulting OSGB36 coordinates are formated. See OSGB36prec.
 //
-// The function will return cartconvert.ErrRange if lat/long are not within the OSGB36 datum area.
+// The function will return cartconvert.ErrRange if easting and northing are not within the National Grid, which
+// extends 700km eastwards and 1300km northwards from the false origin.
 func GridRefNumToLet(easting, northing uint, height float64, prec OSGB36prec) (*OSGB36Coord, error) {
 	// get the 100km-grid indices
 	easting100k := easting / 100000
 	northing100k := northing / 100000
 
-	if easting100k < 0 || easting100k > 6 || northing100k < 0 || northing100k > 12 {
+	if easting100k >= gridSquaresEast || northing100k >= gridSquaresNorth {
 		return nil, cartconvert.ErrRange
 	}
 
-	// translate those into numeric equivalents of the grid letters
-	l1 := byte((19 - northing100k) - (19-northing100k)%5 + (easting100k+10)/5)
-	l2 := byte((19-northing100k)*5%25 + easting100k%5)
-
-	// compensate for skipped 'I' and build grid letter-pairs
-	if l1 > 7 {
-		l1++
+	// translate those into the indexes of the grid letters: the 500km square, counted from the false origin in
+	// square S, and the 100km square within it, counted from its north-west corner
+	l1, err := gridLetter(int(3-northing100k/5)*5 + int(easting100k/5) + 2)
+	if err != nil {
+		return nil, err
 	}
-	if l2 > 7 {
-		l2++
+	l2, err := gridLetter(int(4-northing100k%5)*5 + int(easting100k%5))
+	if err != nil {
+		return nil, err
 	}
 
-	zone := string(l1+'A') + string(l2+'A')
+	zone := string(l1) + string(l2)
 	easting %= 100000
 	northing %= 100000
 
@@ -265,11 +490,30 @@ func GridRefNumToLet(easting, northing uint, height float64, prec OSGB36prec) (*
 // Important: The reference ellipsoid of the originating coordinate system will be assumed
 // to be the WGS84Ellipsoid and will be set thereupon, regardless of the actually set reference ellipsoid.
 func WGS84LatLongToOSGB36(gc *cartconvert.PolarCoord) (*OSGB36Coord, error) {
+	return WGS84LatLongToOSGB36Helmert(gc, nil)
+}
+
+// Transform a latitude / longitude coordinate datum into a OSGB36 coordinate using the helmert parameter
+// set for the datum shift. If set is nil, the default parameter set from WGS84 to OSGB36 is used.
+// Function returns cartconvert.ErrRange, if the parameter set does not transform from WGS84 to OSGB36.
+//
+// Important: The reference ellipsoid of the originating coordinate system will be assumed
+// to be the WGS84Ellipsoid and will be set thereupon, regardless of the actually set reference ellipsoid.
+func WGS84LatLongToOSGB36Helmert(gc *cartconvert.PolarCoord, set *cartconvert.HelmertParameterSet) (*OSGB36Coord, error) {
+
+	hp, err := helmertParameterSet(set)
+	if err != nil {
+		return nil, err
+	}
+	if err = gc.CheckFinite(); err != nil {
+		return nil, err
+	}
+
 	// This sets the Ellipsoid to WGS84, regardless of the actual value set
 	gc.El = cartconvert.WGS84Ellipsoid
 
 	cart := cartconvert.PolarToCartesian(gc)
-	pt := cartconvert.HelmertWGS84ToOSGB36.Transform(&cartconvert.Point3D{X: cart.X, Y: cart.Y, Z: cart.Z})
+	pt := hp.Transform(&cartconvert.Point3D{X: cart.X, Y: cart.Y, Z: cart.Z})
 	polar := cartconvert.CartesianToPolar(&cartconvert.CartPoint{X: pt.X, Y: pt.Y, Z: pt.Z, El: cartconvert.Airy1830Ellipsoid})
 
 	gp := cartconvert.DirectTransverseMercator(
@@ -280,7 +524,19 @@ func WGS84LatLongToOSGB36(gc *cartconvert.PolarCoord) (*OSGB36Coord, error) {
 		400000,
 		-100000)
 
-	return GridRefNumToLet(uint(gp.X+0.5), uint(gp.Y+0.5), 0, OSGB36_Max)
+	// a location west or south of the false origin has no grid reference, and converting its negative easting or
+	// northing to uint would wrap around
+	x, y := math.Floor(gp.X+0.5), math.Floor(gp.Y+0.5)
+	if x < 0 || y < 0 || x >= gridSquaresEast*100000 || y >= gridSquaresNorth*100000 {
+		return nil, cartconvert.ErrRange
+	}
+
+	coord, err := GridRefNumToLet(uint(x), uint(y), 0, OSGB36_Max)
+	if err != nil {
+		return nil, err
+	}
+	coord.Accuracy = hp.Accuracy
+	return coord, nil
 }
 
 func max(x, y int) int {
@@ -302,3 +558,60 @@ func NewOSGB36Coord(Zone string, easting, northing uint, relheight float64, inpu
 	effbytes := SanitizeOSGB36CoordToPrec(&easting, &northing, inputprec, desiredprec)
 	return &OSGB36Coord{Easting: easting, Northing: northing, RelHeight: relheight, Zone: Zone, gridLen: effbytes, El: cartconvert.Airy1830Ellipsoid}
 }
+
+// Returns the grid cells of size resolution x resolution meters adjacent to the cell containing the coordinate,
+// crossing the boundaries of the 100 km squares as necessary. With connectivity 4, the cells to the north, east,
+// south and west are returned in this order, with connectivity 8 the diagonal cells are included, starting
+// with north and proceeding clockwise. Cells outside of the National Grid are omitted.
+//
+// Every cell is denoted by the OSGB36 coordinate of its south-west corner to the accuracy of a meter, or by the bare
+// zone for the south-west corner of a 100 km square. A coordinate of reduced precision like NN1671 is located at
+// the middle of its square, as by OSGB36ZoneToRefCoords.
+//
+// The function returns cartconvert.ErrRange, if connectivity is neither 4 nor 8 or if resolution is not a
+// whole number of meters dividing the 100 km squares into cells of equal size.
+func (coord *OSGB36Coord) Neighbors(resolution float64, connectivity int) ([]*OSGB36Coord, error) {
+
+	if connectivity != 4 && connectivity != 8 {
+		return nil, cartconvert.ErrRange
+	}
+	if resolution < 1 || resolution != math.Floor(resolution) || 100000%uint(resolution) != 0 {
+		return nil, cartconvert.ErrRange
+	}
+
+	size := uint(resolution)
+	easting, northing := OSGB36ZoneToRefCoords(coord)
+	easting, northing = easting/size*size, northing/size*size
+
+	// offsets of the adjacent cells clockwise from north; the odd ones are the diagonals
+	offsets := [][2]int{{0, 1}, {1, 1}, {1, 0}, {1, -1}, {0, -1}, {-1, -1}, {-1, 0}, {-1, 1}}
+
+	var neighbors []*OSGB36Coord
+	for i, offset := range offsets {
+		if connectivity == 4 && i%2 == 1 {
+			continue
+		}
+
+		e, n := int(easting)+offset[0]*int(size), int(northing)+offset[1]*int(size)
+		if e < 0 || n < 0 {
+			continue
+		}
+
+		neighbor, err := GridRefNumToLet(uint(e), uint(n), coord.RelHeight, OSGB36Leave)
+		if err != nil {
+			continue
+		}
+		neighbor.RelHeight = coord.RelHeight
+		neighbors = append(neighbors, neighbor)
+	}
+	return neighbors, nil
+}
+
+// Returns reference points of the British National Grid, for tests and documentation. The WGS84 locations are taken
+// from http://gridreferencefinder.com/, the tolerance covers the accuracy of the helmert transformation.
+func Examples() []cartconvert.Example {
+	return []cartconvert.Example{
+		{Name: "Leeds", Input: "SE 29793 33798", LatLong: &cartconvert.PolarCoord{Latitude: 53.799638, Longitude: -1.5491515, El: cartconvert.WGS84Ellipsoid}, Tolerance: 5},
+		{Name: "Ben Nevis", Input: "NN 166 712", LatLong: &cartconvert.PolarCoord{Latitude: 56.796557, Longitude: -5.0039304, El: cartconvert.WGS84Ellipsoid}, Tolerance: 5},
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"github.com/the42/cartconvert/cartconvert"
 	"math"
+	"strings"
 	"testing"
 )
 
@@ -151,6 +152,108 @@ func TestOSGB36ToWGS84LatLong(t *testing.T) {
 	}
 }
 
+// ## OSGB36ToWGS84LatLongChecked
+var oSGB36ToWGS84LatLongCheckedErrors = []struct {
+	zone string
+	err  error
+}{
+	// a zone south west of the false origin
+	{"AA", cartconvert.ErrRange},
+	{"A1", cartconvert.ErrSyntax},
+}
+
+func TestOSGB36ToWGS84LatLongChecked(t *testing.T) {
+	for cnt, test := range oSGB36ToWGS84LatLongTests {
+		if out, err := OSGB36ToWGS84LatLongChecked(test.in); err != nil || !latlongequal(test.out, out) {
+			t.Errorf("OSGB36ToWGS84LatLongChecked:%d [%s]: Expected %s, got %s (%v)", cnt, test.in, test.out, out, err)
+		}
+	}
+
+	for _, test := range oSGB36ToWGS84LatLongCheckedErrors {
+		coord := NewOSGB36Coord(test.zone, 10000, 10000, 0, 5, OSGB36Leave)
+		if out, err := OSGB36ToWGS84LatLongChecked(coord); err != test.err || out != nil {
+			t.Errorf("OSGB36ToWGS84LatLongChecked [%s]: expected %v, got %s (%v)", test.zone, test.err, out, err)
+		}
+		if out := OSGB36ToWGS84LatLong(coord); out != nil {
+			t.Errorf("OSGB36ToWGS84LatLong [%s]: expected nil, got %s", test.zone, out)
+		}
+	}
+}
+
+// ## OSGB36ZoneToRefCoordsAnchor
+type oSGB36ZoneToRefCoordsAnchorTest struct {
+	in                *OSGB36Coord
+	anchor            OSGB36Anchor
+	easting, northing uint
+	err               error
+}
+
+var oSGB36ZoneToRefCoordsAnchorTests = []oSGB36ZoneToRefCoordsAnchorTest{
+	{&OSGB36Coord{Zone: "NN", Easting: 16600, Northing: 71200, gridLen: 3}, OSGB36AnchorDefault, 216650, 771250, nil},
+	{&OSGB36Coord{Zone: "NN", Easting: 16600, Northing: 71200, gridLen: 3}, OSGB36Center, 216650, 771250, nil},
+	{&OSGB36Coord{Zone: "NN", Easting: 16600, Northing: 71200, gridLen: 3}, OSGB36SouthWest, 216600, 771200, nil},
+	// the 10 km squares are located at their south-west corner by default
+	{&OSGB36Coord{Zone: "NN", Easting: 10000, Northing: 60000, gridLen: 1}, OSGB36AnchorDefault, 210000, 760000, nil},
+	{&OSGB36Coord{Zone: "NN", Easting: 10000, Northing: 60000, gridLen: 1}, OSGB36Center, 215000, 765000, nil},
+	{&OSGB36Coord{Zone: "NN", Easting: 10000, Northing: 60000, gridLen: 1}, OSGB36SouthWest, 210000, 760000, nil},
+	{&OSGB36Coord{Zone: "NN", gridLen: 0}, OSGB36AnchorDefault, 200000, 700000, nil},
+	{&OSGB36Coord{Zone: "NN", gridLen: 0}, OSGB36Center, 250000, 750000, nil},
+	{&OSGB36Coord{Zone: "NN", gridLen: 0}, OSGB36SouthWest, 200000, 700000, nil},
+	// a reference to the accuracy of a meter is the same for all anchors
+	{&OSGB36Coord{Zone: "SE", Easting: 29793, Northing: 33798, gridLen: 5}, OSGB36Center, 429793, 433798, nil},
+	{&OSGB36Coord{Zone: "SE", Easting: 29793, Northing: 33798, gridLen: 5}, OSGB36SouthWest, 429793, 433798, nil},
+	{&OSGB36Coord{Zone: "NN", gridLen: 0}, OSGB36Anchor(99), 0, 0, cartconvert.ErrRange},
+}
+
+func TestOSGB36ZoneToRefCoordsAnchor(t *testing.T) {
+	for cnt, test := range oSGB36ZoneToRefCoordsAnchorTests {
+		easting, northing, err := OSGB36ZoneToRefCoordsAnchor(test.in, test.anchor)
+		if err != test.err {
+			t.Errorf("OSGB36ZoneToRefCoordsAnchor:%d: expected error %v, got %v", cnt, test.err, err)
+			continue
+		}
+		if easting != test.easting || northing != test.northing {
+			t.Errorf("OSGB36ZoneToRefCoordsAnchor:%d: expected %d %d, got %d %d", cnt, test.easting, test.northing, easting, northing)
+		}
+	}
+
+	// the default anchor is the location of OSGB36ZoneToRefCoords
+	for cnt, test := range oSGB36ZoneToRefCoordsAnchorTests {
+		if test.anchor != OSGB36AnchorDefault {
+			continue
+		}
+		if easting, northing := OSGB36ZoneToRefCoords(test.in); easting != test.easting || northing != test.northing {
+			t.Errorf("OSGB36ZoneToRefCoords:%d: expected %d %d, got %d %d", cnt, test.easting, test.northing, easting, northing)
+		}
+	}
+}
+
+// ## OSGB36ToWGS84LatLongAnchor
+func TestOSGB36ToWGS84LatLongAnchor(t *testing.T) {
+	square := &OSGB36Coord{Zone: "NN", Easting: 16600, Northing: 71200, gridLen: 3, El: cartconvert.Airy1830Ellipsoid}
+	corner := &OSGB36Coord{Zone: "NN", Easting: 16600, Northing: 71200, gridLen: 5, El: cartconvert.Airy1830Ellipsoid}
+
+	out, err := OSGB36ToWGS84LatLongAnchor(square, OSGB36SouthWest, nil)
+	if err != nil {
+		t.Fatalf("OSGB36ToWGS84LatLongAnchor: %s", err)
+	}
+	if expected := OSGB36ToWGS84LatLong(corner); *out != *expected {
+		t.Errorf("OSGB36ToWGS84LatLongAnchor: expected the south-west corner %s, got %s", expected, out)
+	}
+
+	out, err = OSGB36ToWGS84LatLongAnchor(square, OSGB36Center, nil)
+	if err != nil {
+		t.Fatalf("OSGB36ToWGS84LatLongAnchor: %s", err)
+	}
+	if expected := OSGB36ToWGS84LatLong(square); *out != *expected {
+		t.Errorf("OSGB36ToWGS84LatLongAnchor: expected the middle %s, got %s", expected, out)
+	}
+
+	if _, err = OSGB36ToWGS84LatLongAnchor(square, OSGB36Anchor(99), nil); err != cartconvert.ErrRange {
+		t.Errorf("OSGB36ToWGS84LatLongAnchor: expected %s for an unknown anchor, got %v", cartconvert.ErrRange, err)
+	}
+}
+
 // ## WGS84LatLongToOSGB36
 type wGS84LatLongToOSGB36Test struct {
 	in  *cartconvert.PolarCoord
@@ -190,3 +293,350 @@ func TestWGS84LatLongToOSGB36(t *testing.T) {
 		}
 	}
 }
+
+// ## WGS84LatLongToOSGB36Helmert
+func TestWGS84LatLongToOSGB36Helmert(t *testing.T) {
+	gc := &cartconvert.PolarCoord{Latitude: 52.658007, Longitude: 1.716073, El: cartconvert.WGS84Ellipsoid}
+
+	def, _ := WGS84LatLongToOSGB36(gc)
+	out, err := WGS84LatLongToOSGB36Helmert(gc, nil)
+	if err != nil || out.String() != def.String() {
+		t.Errorf("WGS84LatLongToOSGB36Helmert: expected the default parameter set to yield %s, got %s (%v)", def, out, err)
+	}
+
+	set, _ := cartconvert.HelmertParameterSetByName("OSGB36_3param_NIMA")
+	out, err = WGS84LatLongToOSGB36Helmert(gc, set)
+	if err != nil {
+		t.Fatalf("WGS84LatLongToOSGB36Helmert: %s", err)
+	}
+
+	if out.String() == def.String() {
+		t.Errorf("WGS84LatLongToOSGB36Helmert: expected parameter set %s to yield a different result than the default", set.Name)
+	}
+	if def.Accuracy != 5 || out.Accuracy != 20 {
+		t.Errorf("WGS84LatLongToOSGB36Helmert: expected the accuracies 5 and 20 of the parameter sets, got %g and %g", def.Accuracy, out.Accuracy)
+	}
+
+	back, err := OSGB36ToWGS84LatLongHelmert(out, set)
+	if err != nil {
+		t.Fatalf("OSGB36ToWGS84LatLongHelmert: %s", err)
+	}
+
+	if dist := math.Hypot(back.Latitude-gc.Latitude, back.Longitude-gc.Longitude); dist > 0.0001 {
+		t.Errorf("OSGB36ToWGS84LatLongHelmert: expected %s, got %s", gc, back)
+	}
+
+	mgi, _ := cartconvert.DefaultHelmertParameterSet(cartconvert.DatumWGS84, cartconvert.DatumMGI)
+	if _, err = WGS84LatLongToOSGB36Helmert(gc, mgi); err != cartconvert.ErrRange {
+		t.Errorf("WGS84LatLongToOSGB36Helmert: expected ErrRange for a parameter set of another datum, got %v", err)
+	}
+}
+
+// ## FormatFigures
+type formatFiguresTest struct {
+	in      string
+	figures int
+	out     string
+	err     error
+}
+
+var formatFiguresTests = []formatFiguresTest{
+	{"TQ 12345 67890", 0, "TQ", nil},
+	{"TQ 12345 67890", 2, "TQ 1 6", nil},
+	{"TQ 12345 67890", 4, "TQ 12 67", nil},
+	{"TQ 12345 67890", 6, "TQ 123 678", nil},
+	{"TQ 12345 67890", 8, "TQ 1234 6789", nil},
+	{"TQ 12345 67890", 10, "TQ 12345 67890", nil},
+	// truncated to the south-west corner of the square, not rounded
+	{"TQ 12999 67999", 4, "TQ 12 67", nil},
+	{"TQ 12999 67999", 8, "TQ 1299 6799", nil},
+	// leading zeros are kept
+	{"SV 00512 00048", 6, "SV 005 000", nil},
+	// a coordinate of lower precision is padded with zeros
+	{"NN 16 71", 10, "NN 16000 71000", nil},
+	{"NN 16 71", 4, "NN 16 71", nil},
+	{"NN", 6, "NN 000 000", nil},
+	{"TQ 12345 67890", -2, "", cartconvert.ErrRange},
+	{"TQ 12345 67890", 3, "", cartconvert.ErrRange},
+	{"TQ 12345 67890", 12, "", cartconvert.ErrRange},
+}
+
+func TestFormatFigures(t *testing.T) {
+	for _, test := range formatFiguresTests {
+		coord, err := AOSGB36ToStruct(test.in, OSGB36Leave)
+		if err != nil {
+			t.Fatalf("AOSGB36ToStruct %q: %s", test.in, err)
+		}
+
+		out, err := coord.FormatFigures(test.figures)
+		if err != test.err || out != test.out {
+			t.Errorf("FormatFigures %q, %d: expected %q, %v, got %q, %v", test.in, test.figures, test.out, test.err, out, err)
+		}
+	}
+}
+
+// ## Neighbors
+type neighborsTest struct {
+	in           string
+	resolution   float64
+	connectivity int
+	out          []string
+	err          error
+}
+
+var neighborsTests = []neighborsTest{
+	// the cell in the south-east corner of NN borders on NO, NS and NT. The corner of a square is denoted by the bare zone
+	{"NN 950 050", 10000, 8, []string{"NN9000010000", "NO0000010000", "NO", "NT0000090000", "NS9000090000", "NS8000090000", "NN8000000000", "NN8000010000"}, nil},
+	{"NN 950 050", 10000, 4, []string{"NN9000010000", "NO", "NS9000090000", "NN8000000000"}, nil},
+	{"NN1666071255", 1, 4, []string{"NN1666071256", "NN1666171255", "NN1666071254", "NN1665971255"}, nil},
+	// the south-western corner of the National Grid
+	{"SV 005 005", 1000, 8, []string{"SV0000001000", "SV0100001000", "SV0100000000"}, nil},
+	{"NN 950 050", 3000, 8, nil, cartconvert.ErrRange},
+	{"NN 950 050", 10000, 6, nil, cartconvert.ErrRange},
+}
+
+func TestNeighbors(t *testing.T) {
+	for index, test := range neighborsTests {
+		coord, err := AOSGB36ToStruct(test.in, OSGB36Leave)
+		if err != nil {
+			t.Fatalf("AOSGB36ToStruct [%d]: %s", index, err)
+		}
+
+		neighbors, err := coord.Neighbors(test.resolution, test.connectivity)
+		if err != test.err {
+			t.Errorf("Neighbors [%d]: expected error %v, got %v", index, test.err, err)
+			continue
+		}
+
+		var out []string
+		for _, neighbor := range neighbors {
+			out = append(out, neighbor.String())
+		}
+		if fmt.Sprint(out) != fmt.Sprint(test.out) {
+			t.Errorf("Neighbors [%d]: expected %v, got %v", index, test.out, out)
+		}
+	}
+}
+
+type validTest struct {
+	in    *OSGB36Coord
+	valid bool
+}
+
+var validTests = []validTest{
+	{NewOSGB36Coord("NN", 16600, 71200, 0, 5, OSGB36Auto), true},
+	{NewOSGB36Coord("TQ", 30000, 80000, 0, 5, OSGB36Auto), true},
+	// north-west of the Shetland Islands
+	{NewOSGB36Coord("HA", 50000, 50000, 0, 5, OSGB36Auto), false},
+}
+
+func TestValid(t *testing.T) {
+	for index, test := range validTests {
+		err := test.in.Valid()
+		if _, ok := err.(cartconvert.ExtentError); (err == nil) != test.valid || (err != nil && !ok) {
+			t.Errorf("Valid [%d]: expected valid %t for %s, got %v", index, test.valid, test.in, err)
+		}
+	}
+}
+
+// ## Grid letters
+type gridRefNumToLetTest struct {
+	easting, northing uint
+	out               string
+	err               error
+}
+
+// The corners of the National Grid and the squares just beyond them
+var gridRefNumToLetTests = []gridRefNumToLetTest{
+	{0, 0, "SV", nil},
+	{699999, 0, "TW9999900000", nil},
+	{0, 1299999, "HL0000099999", nil},
+	{699999, 1299999, "JM9999999999", nil},
+	{700000, 0, "", cartconvert.ErrRange},
+	{0, 1300000, "", cartconvert.ErrRange},
+	{math.MaxUint64, 0, "", cartconvert.ErrRange},
+	{0, math.MaxUint64, "", cartconvert.ErrRange},
+}
+
+func TestGridRefNumToLet(t *testing.T) {
+	for index, test := range gridRefNumToLetTests {
+		out, err := GridRefNumToLet(test.easting, test.northing, 0, OSGB36Leave)
+		if err != test.err {
+			t.Errorf("GridRefNumToLet [%d]: expected error %v, got %v", index, test.err, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if out.String() != test.out {
+			t.Errorf("GridRefNumToLet [%d]: expected %s, got %s", index, test.out, out)
+		}
+		easting, northing, err := OSGB36ZoneToRefCoordsAnchor(out, OSGB36SouthWest)
+		if err != nil || easting != test.easting || northing != test.northing {
+			t.Errorf("OSGB36ZoneToRefCoordsAnchor [%d]: expected %d %d, got %d %d (%v)", index, test.easting, test.northing, easting, northing, err)
+		}
+	}
+}
+
+// Every 100km square of the grid is named by a distinct pair of letters without 'I', which reads back the same square
+func TestGridLetters(t *testing.T) {
+	zones := make(map[string]bool)
+	for easting := uint(0); easting < 7; easting++ {
+		for northing := uint(0); northing < 13; northing++ {
+			out, err := GridRefNumToLet(easting*100000, northing*100000, 0, OSGB36Leave)
+			if err != nil {
+				t.Errorf("GridRefNumToLet %d %d: %s", easting, northing, err)
+				continue
+			}
+			if strings.Contains(out.Zone, "I") || zones[out.Zone] {
+				t.Errorf("GridRefNumToLet %d %d: unexpected zone %s", easting, northing, out.Zone)
+			}
+			zones[out.Zone] = true
+
+			in, err := AOSGB36ToStruct(out.Zone, OSGB36Leave)
+			if err != nil {
+				t.Errorf("AOSGB36ToStruct %s: %s", out.Zone, err)
+				continue
+			}
+			if e, n := OSGB36ZoneToRefCoords(in); e != easting*100000 || n != northing*100000 {
+				t.Errorf("OSGB36ZoneToRefCoords %s: expected %d %d, got %d %d", out.Zone, easting*100000, northing*100000, e, n)
+			}
+		}
+	}
+}
+
+type aOSGB36ToStructErrorTest struct {
+	in  string
+	err error
+}
+
+var aOSGB36ToStructErrorTests = []aOSGB36ToStructErrorTest{
+	// 'I' is not a grid letter
+	{"SI 123 456", cartconvert.ErrRange},
+	{"IS 123 456", cartconvert.ErrRange},
+	// west and south of the false origin in square SV
+	{"AA 123 456", cartconvert.ErrRange},
+	{"VV 123 456", cartconvert.ErrRange},
+	{"ZZ", cartconvert.ErrRange},
+	{"QV 123 456", cartconvert.ErrRange},
+	{"S- 123 456", cartconvert.ErrSyntax},
+	{"Ä 123 456", cartconvert.ErrSyntax},
+}
+
+func TestAOSGB36ToStructZone(t *testing.T) {
+	for index, test := range aOSGB36ToStructErrorTests {
+		if out, err := AOSGB36ToStruct(test.in, OSGB36Leave); err != test.err {
+			t.Errorf("AOSGB36ToStruct [%d] '%s': expected error %v, got %v (%v)", index, test.in, test.err, err, out)
+		}
+	}
+}
+
+func TestWGS84LatLongToOSGB36OutsideGrid(t *testing.T) {
+	// west of the grid in the Atlantic and south of it in France, where easting resp. northing are negative
+	for _, gc := range []*cartconvert.PolarCoord{{Latitude: 55, Longitude: -20}, {Latitude: 45, Longitude: -3}} {
+		if out, err := WGS84LatLongToOSGB36(gc); err != cartconvert.ErrRange {
+			t.Errorf("WGS84LatLongToOSGB36 %s: expected ErrRange, got %v (%v)", gc, out, err)
+		}
+	}
+}
+
+// ## RoundTrip
+// The positional error in meters of a latitude / longitude coordinate converted into an OSGB36 grid reference and back
+type roundTripTest struct {
+	gc        *cartconvert.PolarCoord
+	threshold float64
+}
+
+// The grid reference is rounded to the meter, which limits the round trip to 0.71 m
+var roundTripTests = []roundTripTest{
+	{&cartconvert.PolarCoord{Latitude: 56.796557, Longitude: -5.003525, El: cartconvert.WGS84Ellipsoid}, 1}, // Ben Nevis
+	{&cartconvert.PolarCoord{Latitude: 51.5007, Longitude: -0.1246, El: cartconvert.WGS84Ellipsoid}, 1},
+	{&cartconvert.PolarCoord{Latitude: 52.657977, Longitude: 1.716073, El: cartconvert.WGS84Ellipsoid}, 1},
+}
+
+func TestRoundTrip(t *testing.T) {
+	for index, test := range roundTripTests {
+		osgb, err := WGS84LatLongToOSGB36(test.gc)
+		if err != nil {
+			t.Fatalf("WGS84LatLongToOSGB36 [%d]: %s", index, err)
+		}
+		out := OSGB36ToWGS84LatLong(osgb)
+
+		if d := cartconvert.Haversine(test.gc, out, 0); d > test.threshold {
+			t.Errorf("RoundTrip [%d]: expected a positional error below %g m, got %.3f m", index, test.threshold, d)
+		}
+	}
+}
+
+// The round trip inverts the helmert transformation exactly and hides its inaccuracy towards the national grid, which
+// is defined by the OSTN15 transformation. Against the worked example of the Ordnance Survey, Caister Water Tower, the
+// helmert transformation stays within the documented accuracy of 5 m.
+func TestHelmertAccuracy(t *testing.T) {
+	gc := &cartconvert.PolarCoord{Latitude: 52 + 39.0/60 + 28.8282/3600, Longitude: 1 + 42.0/60 + 57.8663/3600, El: cartconvert.WGS84Ellipsoid}
+	osgb, err := WGS84LatLongToOSGB36(gc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	easting, northing := OSGB36ZoneToRefCoords(osgb)
+	if d := math.Hypot(float64(easting)-651409.903, float64(northing)-313177.270); d > 5 {
+		t.Errorf("WGS84LatLongToOSGB36: expected 651409.903 313177.270 within 5 m, got %d %d, %.1f m off", easting, northing, d)
+	}
+}
+
+// ## EPSG
+func TestEPSG(t *testing.T) {
+	coord, err := AOSGB36ToStruct("NN 166 712", OSGB36Leave)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gc := OSGB36ToWGS84LatLong(coord)
+
+	// the registered coordinate reference system projects on OSGB36, which differs from WGS84 by less than 0.01°
+	crs, err := cartconvert.ByEPSG(coord.EPSG())
+	if err != nil {
+		t.Fatalf("ByEPSG: expected the CRS of the National Grid, got %v", err)
+	}
+	easting, northing := OSGB36ZoneToRefCoords(coord)
+	out, err := crs.InverseProject(&cartconvert.GeoPoint{X: float64(easting), Y: float64(northing)})
+	if err != nil || math.Abs(out.Latitude-gc.Latitude) > 0.01 || math.Abs(out.Longitude-gc.Longitude) > 0.01 {
+		t.Errorf("CRS.InverseProject: expected %s near %s, got %v (%v)", crs.Name, gc, out, err)
+	}
+}
+
+// ## WKT
+func TestWKT(t *testing.T) {
+	crs, err := cartconvert.ByEPSG(EPSG)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `PROJCS["OSGB 1936 / British National Grid",GEOGCS["OSGB 1936",DATUM["OSGB36",` +
+		`SPHEROID["Airy1830",6377563.396,299.324961266,AUTHORITY["EPSG","7001"]]],PRIMEM["Greenwich",0],` +
+		`UNIT["degree",0.0174532925199433],AUTHORITY["EPSG","4277"]],PROJECTION["Transverse_Mercator"],` +
+		`PARAMETER["latitude_of_origin",49],PARAMETER["central_meridian",-2],PARAMETER["scale_factor",0.9996012717],` +
+		`PARAMETER["false_easting",400000],PARAMETER["false_northing",-100000],UNIT["metre",1],AUTHORITY["EPSG","27700"]]`
+	if out := crs.WKT(); out != expected {
+		t.Errorf("CRS.WKT: expected %s, got %s", expected, out)
+	}
+}
+
+// ## Proj4
+func TestProj4(t *testing.T) {
+	crs, err := cartconvert.ByEPSG(EPSG)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "+proj=tmerc +lat_0=49 +lon_0=-2 +k=0.9996012717 +x_0=400000 +y_0=-100000 +ellps=airy" +
+		" +towgs84=446.448,-125.157,542.06,0.1502,0.247,0.8421,-20.4894 +units=m +no_defs"
+	if out := crs.Proj4(); out != expected {
+		t.Errorf("CRS.Proj4: expected %s, got %s", expected, out)
+	}
+}
+
+// ## NaN and infinity
+func TestNonFinite(t *testing.T) {
+	for _, value := range []float64{math.NaN(), math.Inf(1)} {
+		if _, err := WGS84LatLongToOSGB36(&cartconvert.PolarCoord{Latitude: value, Longitude: -1.5}); err != cartconvert.ErrNotFinite {
+			t.Errorf("WGS84LatLongToOSGB36 (%v): expected ErrNotFinite, got %v", value, err)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// This package parses coordinate literals of any of the coordinate systems supported by cartconvert and its
+// subpackages, without the caller specifying the coordinate system. The parsers of the coordinate systems are
+// registered in an order of precedence and tried in turn, the first one accepting the literal determines the
+// coordinate system.
+//
+// The built-in coordinate systems are registered in the order
+//
+//	utm, mgrs, bmn, lv03, irishgrid, osgb36, olc, maidenhead, geohash, latlong, gk, rd, itm, stateplane
+//
+// Notations starting with a zone, a grid letter or the labels of the axes are distinctive and therefore tried
+// first. A single grid letter denotes a square of the Irish Grid rather than of the National Grid of Great
+// Britain. A bare pair of numbers is tried as latitude / longitude first, and as a pair of projected coordinates
+// afterwards, each accepted only if it lies within the range of the projection. A State Plane coordinate is
+// prefixed by the FIPS code of its zone.
+package parse
+
+import (
+	"fmt"
+	"github.com/the42/cartconvert/cartconvert"
+	"github.com/the42/cartconvert/cartconvert/bmn"
+	"github.com/the42/cartconvert/cartconvert/dutchrd"
+	"github.com/the42/cartconvert/cartconvert/gausskrueger"
+	"github.com/the42/cartconvert/cartconvert/irishgrid"
+	"github.com/the42/cartconvert/cartconvert/lv03p"
+	"github.com/the42/cartconvert/cartconvert/maidenhead"
+	"github.com/the42/cartconvert/cartconvert/mgrs"
+	"github.com/the42/cartconvert/cartconvert/olc"
+	"github.com/the42/cartconvert/cartconvert/osgb36"
+	"github.com/the42/cartconvert/cartconvert/stateplane"
+	"strings"
+	"sync"
+)
+
+// A parser parses the literal of a coordinate of its coordinate system into the coordinate of the coordinate
+// system, like a *cartconvert.UTMCoord or a *bmn.BMNCoord. Returns an error, if the literal is not a coordinate of
+// the coordinate system.
+type Parser func(coord string) (cartconvert.Coordinate, error)
+
+// The error returned by ParseCoordinate, if no registered parser accepts the literal. Tried lists the
+// coordinate systems in the order tried, Errs the error of the respective parser.
+type NoMatchError struct {
+	Coord string
+	Tried []string
+	Errs  []error
+}
+
+func (nme NoMatchError) Error() string {
+	reasons := make([]string, len(nme.Tried))
+	for i, system := range nme.Tried {
+		reasons[i] = fmt.Sprintf("%s: %s", system, nme.Errs[i])
+	}
+	return fmt.Sprintf("\"%s\" is not a coordinate of any coordinate system, tried %s", nme.Coord, strings.Join(reasons, "; "))
+}
+
+var (
+	registryMu sync.RWMutex
+	systems    []string
+	parsers    = make(map[string]Parser)
+)
+
+// Register adds the parser of a coordinate system of the given name, which is tried after the parsers
+// registered before. Returns cartconvert.ErrSyntax if the name is empty or the parser is nil and
+// cartconvert.ErrDuplicate if a parser of the name is already registered.
+func Register(system string, parser Parser) error {
+	if system == "" || parser == nil {
+		return cartconvert.ErrSyntax
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, ok := parsers[system]; ok {
+		return cartconvert.ErrDuplicate
+	}
+	parsers[system] = parser
+	systems = append(systems, system)
+	return nil
+}
+
+// Systems returns the names of the registered coordinate systems in the order their parsers are tried.
+func Systems() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	return append([]string(nil), systems...)
+}
+
+// ParseCoordinate parses the literal coord by the registered parsers in their order of precedence and returns
+// the coordinate of the first parser accepting the literal, together with the name of its coordinate system.
+// If no parser accepts the literal, a NoMatchError is returned, listing the coordinate systems tried. An empty
+// literal returns cartconvert.ErrSyntax.
+func ParseCoordinate(coord string) (cartconvert.Coordinate, string, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	literal := strings.TrimSpace(coord)
+	if literal == "" {
+		return nil, "", cartconvert.ErrSyntax
+	}
+
+	nme := NoMatchError{Coord: coord}
+	for _, system := range systems {
+		parsed, err := parsers[system](literal)
+		if err == nil {
+			return parsed, system, nil
+		}
+		nme.Tried = append(nme.Tried, system)
+		nme.Errs = append(nme.Errs, err)
+	}
+	return nil, "", nme
+}
+
+// ParseSystem parses the literal coord by the parser of the registered coordinate system system. Returns
+// cartconvert.ErrNotFound, if no parser of the name is registered, and the error of the parser, if it doesn't
+// accept the literal.
+func ParseSystem(coord, system string) (cartconvert.Coordinate, error) {
+	registryMu.RLock()
+	parser, ok := parsers[system]
+	registryMu.RUnlock()
+
+	if !ok {
+		return nil, cartconvert.ErrNotFound
+	}
+	return parser(strings.TrimSpace(coord))
+}
+
+// validator is implemented by the coordinates of the projections, which may be parsed but lie outside of the
+// range of the projection
+type validator interface {
+	cartconvert.Coordinate
+	Valid() error
+}
+
+// valid returns coord, if it lies within the range of its projection, and the error of the validation otherwise
+func valid(coord validator, err error) (cartconvert.Coordinate, error) {
+	if err != nil {
+		return nil, err
+	}
+	if err = coord.Valid(); err != nil {
+		return nil, err
+	}
+	return coord, nil
+}
+
+// parsed returns coord as a cartconvert.Coordinate, or nil on error, so the nil pointer of a failed parser is not
+// returned as a non-nil interface
+func parsed(coord cartconvert.Coordinate, err error) (cartconvert.Coordinate, error) {
+	if err != nil {
+		return nil, err
+	}
+	return coord, nil
+}
+
+// The built-in coordinate systems, in their order of precedence
+func init() {
+	builtin := []struct {
+		system string
+		parser Parser
+	}{
+		{"utm", func(coord string) (cartconvert.Coordinate, error) {
+			return valid(cartconvert.AUTMToStruct(coord, cartconvert.WGS84Ellipsoid))
+		}},
+		{"mgrs", func(coord string) (cartconvert.Coordinate, error) {
+			return parsed(mgrs.AMGRSToStruct(coord))
+		}},
+		{"bmn", func(coord string) (cartconvert.Coordinate, error) {
+			return valid(bmn.ABMNToStruct(coord))
+		}},
+		{"lv03", func(coord string) (cartconvert.Coordinate, error) {
+			return valid(lv03p.ASwissCoordToStruct(coord))
+		}},
+		{"irishgrid", func(coord string) (cartconvert.Coordinate, error) {
+			return valid(irishgrid.AIrishGridToStruct(coord))
+		}},
+		{"osgb36", func(coord string) (cartconvert.Coordinate, error) {
+			return valid(osgb36.AOSGB36ToStruct(coord, osgb36.OSGB36Leave))
+		}},
+		{"olc", func(coord string) (cartconvert.Coordinate, error) {
+			return parsed(olc.AOLCToStruct(coord))
+		}},
+		{"maidenhead", func(coord string) (cartconvert.Coordinate, error) {
+			return parsed(maidenhead.AMaidenheadToStruct(coord))
+		}},
+		// a geohash has no coordinate struct of its own, it parses into the middle of its cell
+		{"geohash", func(coord string) (cartconvert.Coordinate, error) {
+			return parsed(cartconvert.GeoHashToLatLong(coord, cartconvert.WGS84Ellipsoid))
+		}},
+		{"latlong", func(coord string) (cartconvert.Coordinate, error) {
+			return parsed(cartconvert.ADegMinSecToPolar(coord, false))
+		}},
+		{"gk", func(coord string) (cartconvert.Coordinate, error) {
+			return valid(gausskrueger.AGKToStruct(coord))
+		}},
+		{"rd", func(coord string) (cartconvert.Coordinate, error) {
+			return valid(dutchrd.ARDToStruct(coord))
+		}},
+		{"itm", func(coord string) (cartconvert.Coordinate, error) {
+			return valid(irishgrid.AITMToStruct(coord))
+		}},
+		{"stateplane", func(coord string) (cartconvert.Coordinate, error) {
+			return valid(stateplane.ASPToStruct(coord))
+		}},
+	}
+
+	for _, b := range builtin {
+		if err := Register(b.system, b.parser); err != nil {
+			panic(err)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+package parse
+
+import (
+	"errors"
+	"fmt"
+	"github.com/the42/cartconvert/cartconvert"
+	"github.com/the42/cartconvert/cartconvert/dutchrd"
+	"github.com/the42/cartconvert/cartconvert/lambert93"
+	"github.com/the42/cartconvert/cartconvert/lv03p"
+	"github.com/the42/cartconvert/cartconvert/maidenhead"
+	"github.com/the42/cartconvert/cartconvert/nmea"
+	"github.com/the42/cartconvert/cartconvert/olc"
+	"github.com/the42/cartconvert/cartconvert/osgb36"
+	"strings"
+	"testing"
+)
+
+// ## ParseCoordinate
+type parseCoordinateTest struct {
+	in, system, out string
+}
+
+var parseCoordinateTests = []parseCoordinateTest{
+	{"33T 596598 5339347", "utm", "33T 596598 5339347"},
+	{"33UXP0123456789", "mgrs", "33U XP 01234 56789"},
+	{"M34 592269 272290", "bmn", "M34 592269 272290"},
+	{"Y:600000 X:200000", "lv03", "y:600000 x:200000"},
+	{"O 159 346", "irishgrid", "O159346"},
+	{"NN 166 712", "osgb36", "NN1660071200"},
+	{"8FWH4HX8+QR", "olc", "8FWH4HX8+QR"},
+	{"JN88ee", "maidenhead", "JN88ee"},
+	{"u2edk8", "geohash", "lat: 48.21°, long: 16.4°"},
+	{"47.5, 16.3", "latlong", "lat: 47.5°, long: 16.3°"},
+	{"48°12'N 16°22'E", "latlong", "lat: 48.2°, long: 16.366667°"},
+	{"N47 30.25 E016 20.17", "latlong", "lat: 47.504167°, long: 16.336167°"},
+	{"3477000 5530000", "gk", "3477000 5530000"},
+	{"155000 463000", "rd", "155000 463000"},
+	{"  715830 734697 ", "itm", "715830 734697"},
+	{"0405 1983028 563075", "stateplane", "0405 1983028 563075"},
+}
+
+func TestParseCoordinate(t *testing.T) {
+	for cnt, test := range parseCoordinateTests {
+		out, system, err := ParseCoordinate(test.in)
+		if err != nil {
+			t.Errorf("ParseCoordinate [%d]: %s", cnt, err)
+			continue
+		}
+		if system != test.system || fmt.Sprint(out) != test.out {
+			t.Errorf("ParseCoordinate [%d]: expected %s %s, got %s %s", cnt, test.system, test.out, system, out)
+		}
+	}
+}
+
+func TestParseCoordinateNoMatch(t *testing.T) {
+	_, _, err := ParseCoordinate("600000 200000")
+	nme, ok := err.(NoMatchError)
+	if !ok {
+		t.Fatalf("ParseCoordinate: expected NoMatchError, got %v", err)
+	}
+
+	// every registered coordinate system was tried, in its order of precedence
+	if strings.Join(nme.Tried, " ") != strings.Join(Systems(), " ") || len(nme.Errs) != len(nme.Tried) {
+		t.Errorf("ParseCoordinate: expected to try %v, tried %v", Systems(), nme.Tried)
+	}
+	for _, system := range nme.Tried {
+		if !strings.Contains(nme.Error(), system+": ") {
+			t.Errorf("ParseCoordinate: expected the error to list %s, got %s", system, nme.Error())
+		}
+	}
+
+	if _, _, err = ParseCoordinate(" "); err != cartconvert.ErrSyntax {
+		t.Errorf("ParseCoordinate: expected ErrSyntax for an empty literal, got %v", err)
+	}
+}
+
+// ## ParseSystem
+func TestParseSystem(t *testing.T) {
+	// the parser of the coordinate system is applied alone, regardless of its precedence
+	if coord, err := ParseSystem(" 48 16 ", "latlong"); err != nil || coord.String() != "lat: 48°, long: 16°" {
+		t.Errorf("ParseSystem: expected lat: 48°, long: 16°, got %v (%v)", coord, err)
+	}
+	if coord, err := ParseSystem("3477000 5530000", "gk"); err != nil || coord.System() != "gk" {
+		t.Errorf("ParseSystem: expected a coordinate of gk, got %v (%v)", coord, err)
+	}
+	if _, err := ParseSystem("3477000 5530000", "bmn"); err == nil {
+		t.Error("ParseSystem: expected an error of the parser of bmn")
+	}
+	// the coordinates of the projections are accepted only within their range
+	if _, err := ParseSystem("M34 3477000 5530000", "bmn"); !errors.As(err, new(cartconvert.ExtentError)) {
+		t.Errorf("ParseSystem: expected ExtentError for a BMN coordinate outside of Austria, got %v", err)
+	}
+	if _, err := ParseSystem("3477000 5530000", "wgs72"); err != cartconvert.ErrNotFound {
+		t.Errorf("ParseSystem: expected ErrNotFound for an unknown system, got %v", err)
+	}
+}
+
+// ## Coordinate
+
+// The parsed coordinates implement cartconvert.Coordinate: the system of the coordinate, which differs from the
+// parser for a geohash, the EPSG code of its coordinate reference system and the WGS84 latitude / longitude
+type coordinateTest struct {
+	in, system string
+	epsg       int
+	gc         *cartconvert.PolarCoord
+}
+
+var coordinateTests = []coordinateTest{
+	{"33T 596598 5339347", "utm", 32633, &cartconvert.PolarCoord{Latitude: 48.200002, Longitude: 16.300002}},
+	{"33UXP0123456789", "mgrs", 32633, &cartconvert.PolarCoord{Latitude: 48.356156, Longitude: 16.366553}},
+	{"M34 592269 272290", "bmn", 31259, &cartconvert.PolarCoord{Latitude: 47.570304, Longitude: 14.236146}},
+	// the origin of LV03 in Bern and of RD in Amersfoort
+	{"Y:600000 X:200000", "lv03", 21781, &cartconvert.PolarCoord{Latitude: 46.951081, Longitude: 7.438637}},
+	{"155000 463000", "rd", 28992, &cartconvert.PolarCoord{Latitude: 52.155174, Longitude: 5.387206}},
+	{"O 159 346", "irishgrid", 29903, &cartconvert.PolarCoord{Latitude: 53.349597, Longitude: -6.259566}},
+	{"715830 734697", "itm", 2157, &cartconvert.PolarCoord{Latitude: 53.349794, Longitude: -6.260248}},
+	{"NN 166 712", "osgb36", 27700, &cartconvert.PolarCoord{Latitude: 56.796556, Longitude: -5.003930}},
+	{"8FWH4HX8+QR", "olc", 4326, &cartconvert.PolarCoord{Latitude: 48.149437, Longitude: 11.567063}},
+	{"JN88ee", "maidenhead", 4326, &cartconvert.PolarCoord{Latitude: 48.1875, Longitude: 16.375}},
+	{"u2edk8", "latlong", 4326, &cartconvert.PolarCoord{Latitude: 48.21, Longitude: 16.4}},
+	{"47.5, 16.3", "latlong", 4326, &cartconvert.PolarCoord{Latitude: 47.5, Longitude: 16.3}},
+	{"3477000 5530000", "gk", 31467, &cartconvert.PolarCoord{Latitude: 49.905992, Longitude: 8.678785}},
+}
+
+func TestCoordinate(t *testing.T) {
+	for _, test := range coordinateTests {
+		coord, _, err := ParseCoordinate(test.in)
+		if err != nil {
+			t.Errorf("Coordinate %s: %s", test.in, err)
+			continue
+		}
+		gc, err := coord.ToWGS84()
+		if err != nil || !gc.Equal(test.gc, 0.5) || coord.System() != test.system || coord.EPSG() != test.epsg {
+			t.Errorf("Coordinate %s: expected %s on %s, EPSG %d, got %s on %s, EPSG %d (%v)", test.in, test.gc, test.system, test.epsg, gc, coord.System(), coord.EPSG(), err)
+		}
+	}
+}
+
+// ## Examples
+// The reference points of the subpackages and the parsers of their literals
+var examplesTests = map[string]struct {
+	examples func() []cartconvert.Example
+	parse    Parser
+}{
+	"dutchrd":   {dutchrd.Examples, func(coord string) (cartconvert.Coordinate, error) { return parsed(dutchrd.ARDToStruct(coord)) }},
+	"lambert93": {lambert93.Examples, func(coord string) (cartconvert.Coordinate, error) { return parsed(lambert93.AL93ToStruct(coord)) }},
+	"lv03p":     {lv03p.Examples, func(coord string) (cartconvert.Coordinate, error) { return parsed(lv03p.ASwissCoordToStruct(coord)) }},
+	"maidenhead": {maidenhead.Examples, func(coord string) (cartconvert.Coordinate, error) {
+		return parsed(maidenhead.AMaidenheadToStruct(coord))
+	}},
+	"nmea": {nmea.Examples, func(coord string) (cartconvert.Coordinate, error) { return parsed(nmea.ParseSentence(coord)) }},
+	"olc":  {olc.Examples, func(coord string) (cartconvert.Coordinate, error) { return parsed(olc.AOLCToStruct(coord)) }},
+	"osgb36": {osgb36.Examples, func(coord string) (cartconvert.Coordinate, error) {
+		return parsed(osgb36.AOSGB36ToStruct(coord, osgb36.OSGB36Leave))
+	}},
+}
+
+func TestExamples(t *testing.T) {
+	for name, test := range examplesTests {
+		for _, ex := range test.examples() {
+			coord, err := test.parse(ex.Input)
+			if err != nil {
+				t.Errorf("Examples %s %s: %s", name, ex.Name, err)
+				continue
+			}
+			gc, err := coord.ToWGS84()
+			if err != nil || !ex.Matches(gc) {
+				t.Errorf("Examples %s %s: expected %s within %g m, got %s (%v)", name, ex.Name, ex.LatLong, ex.Tolerance, gc, err)
+			}
+		}
+	}
+}
+
+// ## Register
+func TestRegister(t *testing.T) {
+	parser := func(coord string) (cartconvert.Coordinate, error) {
+		if coord != "@home" {
+			return nil, cartconvert.ErrSyntax
+		}
+		return &cartconvert.PolarCoord{Latitude: 47, Longitude: 16, El: cartconvert.WGS84Ellipsoid}, nil
+	}
+
+	if err := Register("test", parser); err != nil {
+		t.Fatalf("Register: %s", err)
+	}
+	if err := Register("test", parser); err != cartconvert.ErrDuplicate {
+		t.Errorf("Register: expected ErrDuplicate, got %v", err)
+	}
+	if err := Register("", parser); err != cartconvert.ErrSyntax {
+		t.Errorf("Register: expected ErrSyntax for an empty name, got %v", err)
+	}
+	if err := Register("nil", nil); err != cartconvert.ErrSyntax {
+		t.Errorf("Register: expected ErrSyntax for a nil parser, got %v", err)
+	}
+
+	if systems := Systems(); systems[len(systems)-1] != "test" {
+		t.Errorf("Systems: expected the registered system last, got %v", systems)
+	}
+	if _, system, err := ParseCoordinate("@home"); err != nil || system != "test" {
+		t.Errorf("ParseCoordinate: expected test, got %s (%v)", system, err)
+	}
+}
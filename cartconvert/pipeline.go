@@ -0,0 +1,399 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// This file provides the parsing and application of PROJ pipelines and the proj4 definitions of coordinate
+// reference systems.
+package cartconvert
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ## PROJ pipelines
+
+// A PipelineError is yielded when a PROJ pipeline definition can not be parsed.
+type PipelineError struct {
+	Step  int    // Number of the step containing the error, 0 for the global parameters
+	Token string // The offending token of the pipeline definition
+	Err   error  // ErrSyntax for malformed definitions, ErrUnsupported for operations not implemented by this package
+}
+
+func (pe PipelineError) Error() string {
+	return fmt.Sprintf("pipeline step %d, token \"%s\": %s", pe.Step, pe.Token, pe.Err.Error())
+}
+
+// Reference ellipsoids known by their PROJ name
+var projEllipsoids = map[string]*Ellipsoid{
+	"WGS84":    WGS84Ellipsoid,
+	"GRS80":    GRS80Ellipsoid,
+	"bessel":   Bessel1841Ellipsoid,
+	"airy":     Airy1830Ellipsoid,
+	"mod_airy": Airy1830ModEllipsoid,
+	"clrk66":   Clarke1866Ellipsoid,
+	"intl":     International1924Ellipsoid,
+}
+
+// A single step of a pipeline, transforming a point in place
+type pipelineStep func(pt *Point3D)
+
+// A Pipeline chains the steps of a PROJ pipeline definition. Create instances by calling ParsePipeline.
+type Pipeline struct {
+	definition string
+	steps      []pipelineStep
+}
+
+// Parses a PROJ pipeline definition of the form
+//
+//	+proj=pipeline [global parameters] +step +proj=<operation> [parameters] [+inv] +step ...
+//
+// into a Pipeline. Global parameters apply to every step, unless the step sets the parameter itself.
+// The supported operations are the ones implemented by this package:
+//
+//	cart: geodetic to geocentric Cartesian coordinates; +ellps
+//	tmerc: transverse mercator projection; +lat_0, +lon_0, +k or +k_0, +x_0, +y_0, +ellps
+//	utm: UTM projection; +zone, +south, +ellps
+//	helmert: 3 or 7 parameter helmert transformation on geocentric Cartesian coordinates;
+//	         +x, +y, +z in meters, +rx, +ry, +rz in arc seconds, +s in ppm, +convention
+//	axisswap: reorders the axes; +order
+//	noop: does nothing
+//
+// Supported ellipsoids are WGS84, GRS80, bessel, airy, mod_airy, clrk66 and intl, GRS80 being the default as in PROJ.
+// Every operation may be inverted by +inv. The +convention of a helmert step must be either
+// position_vector or coordinate_frame if rotations are given.
+//
+// Geodetic coordinates are passed as X: longitude, Y: latitude in decimal degrees and Z: ellipsoidal height,
+// projected coordinates as X: easting, Y: northing.
+//
+// Returns a PipelineError, which carries ErrUnsupported for operations not implemented by this
+// package and ErrSyntax for malformed definitions.
+func ParsePipeline(definition string) (*Pipeline, error) {
+
+	tokens := strings.Fields(definition)
+	if len(tokens) == 0 || tokens[0] != "+proj=pipeline" {
+		token := ""
+		if len(tokens) > 0 {
+			token = tokens[0]
+		}
+		return nil, PipelineError{Token: token, Err: ErrSyntax}
+	}
+
+	// split the definition into the global parameters and the parameters of every step
+	var steps []map[string]string
+	global := make(map[string]string)
+	params := global
+
+	for _, token := range tokens[1:] {
+		if !strings.HasPrefix(token, "+") || len(token) == 1 {
+			return nil, PipelineError{Step: len(steps), Token: token, Err: ErrSyntax}
+		}
+		if token == "+step" {
+			params = make(map[string]string)
+			steps = append(steps, params)
+			continue
+		}
+
+		key, value := token[1:], ""
+		if index := strings.Index(key, "="); index != -1 {
+			key, value = key[:index], key[index+1:]
+		}
+		if _, ok := params[key]; ok {
+			return nil, PipelineError{Step: len(steps), Token: token, Err: ErrSyntax}
+		}
+		params[key] = value
+	}
+
+	if _, ok := global["proj"]; ok || len(steps) == 0 {
+		return nil, PipelineError{Token: definition, Err: ErrSyntax}
+	}
+
+	pipeline := &Pipeline{definition: definition}
+
+	for index, params := range steps {
+		for key, value := range global {
+			if _, ok := params[key]; !ok {
+				params[key] = value
+			}
+		}
+
+		step, err := newPipelineStep(params)
+		if err != nil {
+			if pe, ok := err.(PipelineError); ok {
+				pe.Step = index + 1
+				return nil, pe
+			}
+			return nil, err
+		}
+		pipeline.steps = append(pipeline.steps, step)
+	}
+
+	return pipeline, nil
+}
+
+// Applies all steps of the pipeline to a point and returns the resulting point
+func (p *Pipeline) Transform(pt *Point3D) *Point3D {
+
+	tp := *pt
+	for _, step := range p.steps {
+		step(&tp)
+	}
+	return &tp
+}
+
+// Returns the PROJ definition of the pipeline
+func (p *Pipeline) String() string {
+	return p.definition
+}
+
+// Returns the floating point value of parameter key, or def if the parameter is not set
+func pipelineFloat(params map[string]string, key string, def float64) (float64, error) {
+	value, ok := params[key]
+	if !ok {
+		return def, nil
+	}
+	val, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, PipelineError{Token: "+" + key + "=" + value, Err: ErrSyntax}
+	}
+	if err = CheckFinite(val); err != nil {
+		return 0, PipelineError{Token: "+" + key + "=" + value, Err: err}
+	}
+	return val, nil
+}
+
+// Returns the reference ellipsoid set by parameter ellps, or the GRS80Ellipsoid if the parameter is not set
+func pipelineEllipsoid(params map[string]string) (*Ellipsoid, error) {
+	name, ok := params["ellps"]
+	if !ok {
+		return GRS80Ellipsoid, nil
+	}
+	el, ok := projEllipsoids[name]
+	if !ok {
+		return nil, PipelineError{Token: "+ellps=" + name, Err: ErrUnsupported}
+	}
+	return el, nil
+}
+
+// Creates the step of a single operation
+func newPipelineStep(params map[string]string) (pipelineStep, error) {
+
+	_, inv := params["inv"]
+
+	switch op := params["proj"]; op {
+	case "noop":
+		return func(pt *Point3D) {}, nil
+	case "cart":
+		return newCartStep(params, inv)
+	case "tmerc":
+		return newTMercStep(params, inv)
+	case "utm":
+		return newUTMStep(params, inv)
+	case "helmert":
+		return newHelmertStep(params, inv)
+	case "axisswap":
+		return newAxisSwapStep(params, inv)
+	case "":
+		return nil, PipelineError{Token: "+proj", Err: ErrSyntax}
+	default:
+		return nil, PipelineError{Token: "+proj=" + op, Err: ErrUnsupported}
+	}
+}
+
+func newCartStep(params map[string]string, inv bool) (pipelineStep, error) {
+
+	el, err := pipelineEllipsoid(params)
+	if err != nil {
+		return nil, err
+	}
+
+	if inv {
+		return func(pt *Point3D) {
+			gc := CartesianToPolar(&CartPoint{X: pt.X, Y: pt.Y, Z: pt.Z, El: el})
+			pt.X, pt.Y, pt.Z = gc.Longitude, gc.Latitude, gc.Height
+		}, nil
+	}
+	return func(pt *Point3D) {
+		cart := PolarToCartesian(&PolarCoord{Latitude: pt.Y, Longitude: pt.X, Height: pt.Z, El: el})
+		pt.X, pt.Y, pt.Z = cart.X, cart.Y, cart.Z
+	}, nil
+}
+
+// Creates a transverse mercator projection step
+func newTransverseMercatorStep(el *Ellipsoid, latO, longO, scale, fe, fn float64, inv bool) pipelineStep {
+	if inv {
+		return func(pt *Point3D) {
+			gc := InverseTransverseMercator(&GeoPoint{X: pt.X, Y: pt.Y, El: el}, latO, longO, scale, fe, fn)
+			pt.X, pt.Y = gc.Longitude, gc.Latitude
+		}
+	}
+	return func(pt *Point3D) {
+		gp := DirectTransverseMercator(&PolarCoord{Latitude: pt.Y, Longitude: pt.X, El: el}, latO, longO, scale, fe, fn)
+		pt.X, pt.Y = gp.X, gp.Y
+	}
+}
+
+func newTMercStep(params map[string]string, inv bool) (pipelineStep, error) {
+
+	el, err := pipelineEllipsoid(params)
+	if err != nil {
+		return nil, err
+	}
+
+	key := "k_0"
+	if _, ok := params["k"]; ok {
+		key = "k"
+	}
+	scale, err := pipelineFloat(params, key, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	var values [4]float64
+	for i, key := range []string{"lat_0", "lon_0", "x_0", "y_0"} {
+		if values[i], err = pipelineFloat(params, key, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	return newTransverseMercatorStep(el, values[0], values[1], scale, values[2], values[3], inv), nil
+}
+
+func newUTMStep(params map[string]string, inv bool) (pipelineStep, error) {
+
+	el, err := pipelineEllipsoid(params)
+	if err != nil {
+		return nil, err
+	}
+
+	zone, err := strconv.ParseUint(params["zone"], 10, 8)
+	if err != nil || zone < 1 || zone > 60 {
+		return nil, PipelineError{Token: "+zone=" + params["zone"], Err: ErrSyntax}
+	}
+
+	hemisphere := HemisphereNorth
+	if _, ok := params["south"]; ok {
+		hemisphere = HemisphereSouth
+	}
+
+	return newTransverseMercatorStep(el, 0, utmCentralMeridian(uint(zone)), 0.9996, utmFalseEasting, hemisphere.UTMFalseNorthing(), inv), nil
+}
+
+func newHelmertStep(params map[string]string, inv bool) (pipelineStep, error) {
+
+	var values [7]float64
+	var err error
+	for i, key := range []string{"x", "y", "z", "s", "rx", "ry", "rz"} {
+		if values[i], err = pipelineFloat(params, key, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	if values[4] != 0 || values[5] != 0 || values[6] != 0 {
+		switch params["convention"] {
+		case "position_vector":
+		case "coordinate_frame":
+			// the HelmertTransform implements the position vector convention
+			values[4], values[5], values[6] = -values[4], -values[5], -values[6]
+		default:
+			return nil, PipelineError{Token: "+convention=" + params["convention"], Err: ErrSyntax}
+		}
+	}
+
+	hp := NewHelmertTransformer(values[0], values[1], values[2], values[3], values[4], values[5], values[6], "pipeline")
+
+	if inv {
+		return func(pt *Point3D) { *pt = *hp.InverseTransform(pt) }, nil
+	}
+	return func(pt *Point3D) { *pt = *hp.Transform(pt) }, nil
+}
+
+func newAxisSwapStep(params map[string]string, inv bool) (pipelineStep, error) {
+
+	// order lists the input axis for every output axis, 1 based
+	order := [3]int{1, 2, 3}
+	axes := strings.Split(params["order"], ",")
+	if len(axes) < 2 || len(axes) > 3 {
+		return nil, PipelineError{Token: "+order=" + params["order"], Err: ErrSyntax}
+	}
+
+	var seen [4]bool
+	for i, axis := range axes {
+		n, err := strconv.Atoi(axis)
+		if err != nil || n < 1 || n > len(axes) || seen[n] {
+			return nil, PipelineError{Token: "+order=" + params["order"], Err: ErrSyntax}
+		}
+		seen[n] = true
+		order[i] = n
+	}
+
+	if inv {
+		var inverse [3]int
+		for i, n := range order {
+			inverse[n-1] = i + 1
+		}
+		order = inverse
+	}
+
+	return func(pt *Point3D) {
+		in := [3]float64{pt.X, pt.Y, pt.Z}
+		pt.X, pt.Y, pt.Z = in[order[0]-1], in[order[1]-1], in[order[2]-1]
+	}, nil
+}
+
+// ## proj4 definitions
+
+// Returns the +ellps or +a and +b parameters of a reference ellipsoid
+func proj4Ellipsoid(el *Ellipsoid) string {
+	for name, pel := range projEllipsoids {
+		if pel == el {
+			return "+ellps=" + name
+		}
+	}
+	return "+a=" + strconv.FormatFloat(el.a, 'f', -1, 64) + " +b=" + strconv.FormatFloat(el.b, 'f', -1, 64)
+}
+
+// Returns the +towgs84 parameter of the default helmert parameter set between datum and WGS84, or an empty
+// string if there is none. The parameters of a set transforming from WGS84 are negated, which approximates the
+// inverse transformation to a few millimeters.
+func proj4ToWGS84(datum string) string {
+
+	if datum == "" || datum == DatumWGS84 {
+		return ""
+	}
+
+	sign := 1.0
+	set, err := DefaultHelmertParameterSet(datum, DatumWGS84)
+	if err != nil {
+		if set, err = DefaultHelmertParameterSet(DatumWGS84, datum); err != nil {
+			return ""
+		}
+		sign = -1
+	}
+
+	dx, dy, dz, dM, drx, dry, drz := set.Parameters()
+	params := make([]string, 0, 7)
+	for _, p := range []float64{dx, dy, dz, drx, dry, drz, dM} {
+		params = append(params, FormatTrimmed(sign*p, 6))
+	}
+	return " +towgs84=" + strings.Join(params, ",")
+}
+
+// Returns the proj4 definition of the coordinate reference system, like
+//
+//	+proj=tmerc +lat_0=49 +lon_0=-2 +k=0.9996012717 +x_0=400000 +y_0=-100000 +ellps=airy
+//	    +towgs84=446.448,-125.157,542.06,0.1502,0.247,0.8421,-20.4894 +units=m +no_defs
+//
+// for the British National Grid. Geographic systems are defined by +proj=longlat. The datum shift +towgs84 is
+// taken from the default helmert parameter set between the datum of the system and WGS84, if there is one.
+// The definition may be passed to cs2cs to cross-check the transformations of this package.
+func (crs *CRS) Proj4() string {
+
+	if !crs.Projected() {
+		return "+proj=longlat " + proj4Ellipsoid(crs.El) + proj4ToWGS84(crs.Datum) + " +no_defs"
+	}
+	return fmt.Sprintf("+proj=tmerc +lat_0=%s +lon_0=%s +k=%s +x_0=%s +y_0=%s %s%s +units=m +no_defs",
+		wkt1Number(crs.LatO), wkt1Number(crs.LongO), wkt1Number(crs.Scale), wkt1Number(crs.FalseEasting),
+		wkt1Number(crs.FalseNorthing), proj4Ellipsoid(crs.El), proj4ToWGS84(crs.Datum))
+}
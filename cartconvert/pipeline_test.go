@@ -0,0 +1,138 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// Automated tests for the PROJ pipelines of the cartconvert package
+package cartconvert
+
+import (
+	"testing"
+)
+
+// ## ParsePipeline
+func TestPipelineUTMReprojection(t *testing.T) {
+	pipeline, err := ParsePipeline("+proj=pipeline +ellps=WGS84 +step +inv +proj=utm +zone=33 +step +proj=utm +zone=34")
+	if err != nil {
+		t.Fatalf("ParsePipeline: %s", err)
+	}
+
+	gc := &PolarCoord{Latitude: 47.57, Longitude: 18.1, El: WGS84Ellipsoid}
+	in := latLongToUTMZone(gc, 33)
+	expected := latLongToUTMZone(gc, 34)
+
+	out := pipeline.Transform(&Point3D{X: in.Easting, Y: in.Northing})
+	if !utmabrequal(expected, &UTMCoord{Zone: expected.Zone, Easting: out.X, Northing: out.Y}) {
+		t.Errorf("Pipeline.Transform: expected %s, got %f %f", expected, out.X, out.Y)
+	}
+}
+
+func TestPipelineHelmert(t *testing.T) {
+	pipeline, err := ParsePipeline("+proj=pipeline " +
+		"+step +proj=cart +ellps=WGS84 " +
+		"+step +proj=helmert +x=-577.326 +y=-90.129 +z=-463.919 +rx=5.1366 +ry=1.4742 +rz=5.2970 +s=-2.4232 +convention=position_vector " +
+		"+step +inv +proj=cart +ellps=bessel " +
+		"+step +proj=axisswap +order=2,1")
+	if err != nil {
+		t.Fatalf("ParsePipeline: %s", err)
+	}
+
+	gc := &PolarCoord{Latitude: 48.507001, Longitude: 15.698748, El: WGS84Ellipsoid}
+	cart := PolarToCartesian(gc)
+	pt := HelmertWGS84ToMGI.Transform(&Point3D{X: cart.X, Y: cart.Y, Z: cart.Z})
+	expected := CartesianToPolar(&CartPoint{X: pt.X, Y: pt.Y, Z: pt.Z, El: Bessel1841Ellipsoid})
+
+	out := pipeline.Transform(&Point3D{X: gc.Longitude, Y: gc.Latitude})
+	if !latlongequal(expected, &PolarCoord{Latitude: out.X, Longitude: out.Y}) {
+		t.Errorf("Pipeline.Transform: expected %s, got %f %f", expected, out.X, out.Y)
+	}
+
+	// the coordinate frame convention inverts the sign of the rotations
+	cf, err := ParsePipeline("+proj=pipeline +step +proj=helmert +x=-577.326 +y=-90.129 +z=-463.919 +rx=-5.1366 +ry=-1.4742 +rz=-5.2970 +s=-2.4232 +convention=coordinate_frame")
+	if err != nil {
+		t.Fatalf("ParsePipeline: %s", err)
+	}
+	if out := cf.Transform(&Point3D{X: cart.X, Y: cart.Y, Z: cart.Z}); *out != *pt {
+		t.Errorf("Pipeline.Transform: expected %v, got %v", pt, out)
+	}
+}
+
+func TestPipelineInverse(t *testing.T) {
+	pipeline, err := ParsePipeline("+proj=pipeline +step +proj=tmerc +lon_0=13.333333 +k=1 +x_0=450000 +y_0=-5000000 +ellps=bessel +step +proj=axisswap +order=2,1 +step +proj=axisswap +order=2,1 +inv")
+	if err != nil {
+		t.Fatalf("ParsePipeline: %s", err)
+	}
+
+	inverse, err := ParsePipeline("+proj=pipeline +step +inv +proj=tmerc +lon_0=13.333333 +k=1 +x_0=450000 +y_0=-5000000 +ellps=bessel +step +proj=noop")
+	if err != nil {
+		t.Fatalf("ParsePipeline: %s", err)
+	}
+
+	in := &Point3D{X: 14.236188, Y: 47.570299}
+	out := inverse.Transform(pipeline.Transform(in))
+	if !latlongequal(&PolarCoord{Latitude: in.Y, Longitude: in.X}, &PolarCoord{Latitude: out.Y, Longitude: out.X}) {
+		t.Errorf("Pipeline.Transform: expected %v, got %v", in, out)
+	}
+}
+
+type parsePipelineErrorTest struct {
+	definition string
+	step       int
+	err        error
+}
+
+var parsePipelineErrorTests = []parsePipelineErrorTest{
+	{"", 0, ErrSyntax},
+	{"+proj=utm +zone=33", 0, ErrSyntax},
+	{"+proj=pipeline", 0, ErrSyntax},
+	{"+proj=pipeline +step +proj=utm zone=33", 1, ErrSyntax},
+	{"+proj=pipeline +step +proj=utm +zone=61", 1, ErrSyntax},
+	{"+proj=pipeline +step +proj=noop +step +proj=lcc +lat_1=46", 2, ErrUnsupported},
+	{"+proj=pipeline +step +proj=cart +ellps=krass", 1, ErrUnsupported},
+	{"+proj=pipeline +step +proj=helmert +x=1 +rx=1", 1, ErrSyntax},
+	{"+proj=pipeline +step +proj=axisswap +order=1,1", 1, ErrSyntax},
+	{"+proj=pipeline +step +proj=tmerc +k=one", 1, ErrSyntax},
+	{"+proj=pipeline +step +proj=tmerc +k=NaN", 1, ErrNotFinite},
+	{"+proj=pipeline +step +proj=tmerc +x_0=-Inf", 1, ErrNotFinite},
+}
+
+func TestParsePipelineErrors(t *testing.T) {
+	for index, test := range parsePipelineErrorTests {
+		_, err := ParsePipeline(test.definition)
+		pe, ok := err.(PipelineError)
+		if !ok || pe.Err != test.err || pe.Step != test.step {
+			t.Errorf("ParsePipeline [%d]: expected %v in step %d, got %v", index, test.err, test.step, err)
+		}
+	}
+}
+
+// ## Proj4
+type proj4Test struct {
+	epsg  int
+	proj4 string
+}
+
+var proj4Tests = []proj4Test{
+	{4326, "+proj=longlat +ellps=WGS84 +no_defs"},
+	// a helmert parameter set transforming to WGS84
+	{4149, "+proj=longlat +ellps=bessel +towgs84=674.374,15.056,405.346,0,0,0,0 +no_defs"},
+	{32733, "+proj=tmerc +lat_0=0 +lon_0=15 +k=0.9996 +x_0=500000 +y_0=10000000 +ellps=WGS84 +units=m +no_defs"},
+	{25832, "+proj=tmerc +lat_0=0 +lon_0=9 +k=0.9996 +x_0=500000 +y_0=0 +ellps=GRS80 +units=m +no_defs"},
+}
+
+func TestCRSProj4(t *testing.T) {
+	for index, test := range proj4Tests {
+		crs, err := ByEPSG(test.epsg)
+		if err != nil {
+			t.Fatalf("ByEPSG [%d]: %s", index, err)
+		}
+		if out := crs.Proj4(); out != test.proj4 {
+			t.Errorf("CRS.Proj4 [%d]: expected %s, got %s", index, test.proj4, out)
+		}
+	}
+
+	// an ellipsoid without a PROJ name is given by its axes
+	crs := &CRS{El: NewEllipsoid(6371000, 6371000, "Sphere")}
+	if out, expected := crs.Proj4(), "+proj=longlat +a=6371000 +b=6371000 +no_defs"; out != expected {
+		t.Errorf("CRS.Proj4: expected %s, got %s", expected, out)
+	}
+}
@@ -0,0 +1,325 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// This package provides functions to deal with conversion of coordinates of the State Plane Coordinate System of
+// 1983 (SPCS83) of the United States.
+//
+// Every state is divided into one or more zones, identified by their FIPS code, like 0405 for California zone V.
+// A zone is defined either by a Lambert Conformal Conic or by a Transverse Mercator projection of the GRS80
+// ellipsoid of the North American Datum of 1983 (NAD83). The coordinates are given in meters. NAD83 and WGS84
+// differ by about a meter within the conterminous United States, which is ignored by this package.
+//
+// The package defines a selection of the zones of the conterminous United States, see Zones.
+//
+// For further info see http://en.wikipedia.org/wiki/State_Plane_Coordinate_System
+package stateplane
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/the42/cartconvert/cartconvert"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Geographic extent of the zones of the package, the conterminous United States
+var Extent = &cartconvert.LatLongExtent{MinLat: 24, MaxLat: 50, MinLong: -125, MaxLong: -66}
+
+// The projection a zone is defined by
+type Projection byte
+
+const (
+	LambertConformalConic Projection = iota
+	TransverseMercator
+)
+
+func (p Projection) String() string {
+	switch p {
+	case LambertConformalConic:
+		return "Lambert Conformal Conic"
+	case TransverseMercator:
+		return "Transverse Mercator"
+	}
+	return "#unknown"
+}
+
+// A zone of SPCS83. Latitudes and longitudes are given in decimal degrees, false easting and northing in meters.
+//
+//	Lat1, Lat2: standard parallels of a Lambert Conformal Conic zone
+//	LatO, LongO: latitude and longitude of the origin, for Transverse Mercator the central meridian
+//	Scale: scale factor at the central meridian of a Transverse Mercator zone
+type Zone struct {
+	FIPS       uint
+	Name       string
+	Projection Projection
+
+	Lat1, Lat2, LatO, LongO     float64
+	Scale                       float64
+	FalseEasting, FalseNorthing float64
+}
+
+// Returns decimal degrees of degree and minute
+func dm(deg, min float64) float64 {
+	return deg + min/60
+}
+
+func lambertZone(fips uint, name string, lat1, lat2, latO, longO, fe, fn float64) *Zone {
+	return &Zone{FIPS: fips, Name: name, Projection: LambertConformalConic, Lat1: lat1, Lat2: lat2, LatO: latO, LongO: longO,
+		FalseEasting: fe, FalseNorthing: fn}
+}
+
+func tmZone(fips uint, name string, latO, longO, scale, fe, fn float64) *Zone {
+	return &Zone{FIPS: fips, Name: name, Projection: TransverseMercator, LatO: latO, LongO: longO, Scale: scale,
+		FalseEasting: fe, FalseNorthing: fn}
+}
+
+// Returns easting and northing in meters of latitude lat and longitude long on the ellipsoid el by the projection
+// of the zone
+func (zone *Zone) project(lat, long float64, el *cartconvert.Ellipsoid) (easting, northing float64) {
+	var pt *cartconvert.GeoPoint
+	switch zone.Projection {
+	case LambertConformalConic:
+		pt = cartconvert.DirectLambertConformalConic(&cartconvert.PolarCoord{Latitude: lat, Longitude: long, El: el},
+			zone.Lat1, zone.Lat2, zone.LatO, zone.LongO, zone.FalseEasting, zone.FalseNorthing)
+	case TransverseMercator:
+		pt = cartconvert.DirectTransverseMercator(&cartconvert.PolarCoord{Latitude: lat, Longitude: long, El: el},
+			zone.LatO, zone.LongO, zone.Scale, zone.FalseEasting, zone.FalseNorthing)
+	}
+	return pt.X, pt.Y
+}
+
+// Returns latitude and longitude on the ellipsoid el of easting and northing in meters of the zone, the inverse of
+// project
+func (zone *Zone) unproject(easting, northing float64, el *cartconvert.Ellipsoid) (lat, long float64) {
+	var gc *cartconvert.PolarCoord
+	switch zone.Projection {
+	case LambertConformalConic:
+		gc = cartconvert.InverseLambertConformalConic(&cartconvert.GeoPoint{X: easting, Y: northing, El: el},
+			zone.Lat1, zone.Lat2, zone.LatO, zone.LongO, zone.FalseEasting, zone.FalseNorthing)
+	case TransverseMercator:
+		gc = cartconvert.InverseTransverseMercator(&cartconvert.GeoPoint{X: easting, Y: northing, El: el},
+			zone.LatO, zone.LongO, zone.Scale, zone.FalseEasting, zone.FalseNorthing)
+	}
+	return gc.Latitude, gc.Longitude
+}
+
+// The zones by FIPS code
+var zones = map[uint]*Zone{}
+
+func init() {
+	for _, zone := range []*Zone{
+		tmZone(101, "Alabama East", dm(30, 30), -dm(85, 50), 1-1.0/25000, 200000, 0),
+		tmZone(102, "Alabama West", dm(30, 0), -dm(87, 30), 1-1.0/15000, 600000, 0),
+		tmZone(201, "Arizona East", dm(31, 0), -dm(110, 10), 1-1.0/10000, 213360, 0),
+		tmZone(202, "Arizona Central", dm(31, 0), -dm(111, 55), 1-1.0/10000, 213360, 0),
+		tmZone(203, "Arizona West", dm(31, 0), -dm(113, 45), 1-1.0/15000, 213360, 0),
+		lambertZone(401, "California I", dm(40, 0), dm(41, 40), dm(39, 20), -dm(122, 0), 2000000, 500000),
+		lambertZone(402, "California II", dm(38, 20), dm(39, 50), dm(37, 40), -dm(122, 0), 2000000, 500000),
+		lambertZone(403, "California III", dm(37, 4), dm(38, 26), dm(36, 30), -dm(120, 30), 2000000, 500000),
+		lambertZone(404, "California IV", dm(36, 0), dm(37, 15), dm(35, 20), -dm(119, 0), 2000000, 500000),
+		lambertZone(405, "California V", dm(34, 2), dm(35, 28), dm(33, 30), -dm(118, 0), 2000000, 500000),
+		lambertZone(406, "California VI", dm(32, 47), dm(33, 53), dm(32, 10), -dm(116, 15), 2000000, 500000),
+		lambertZone(501, "Colorado North", dm(39, 43), dm(40, 47), dm(39, 20), -dm(105, 30), 914401.8289, 304800.6096),
+		lambertZone(502, "Colorado Central", dm(38, 27), dm(39, 45), dm(37, 50), -dm(105, 30), 914401.8289, 304800.6096),
+		lambertZone(503, "Colorado South", dm(37, 14), dm(38, 26), dm(36, 40), -dm(105, 30), 914401.8289, 304800.6096),
+		tmZone(901, "Florida East", dm(24, 20), -dm(81, 0), 1-1.0/17000, 200000, 0),
+		tmZone(902, "Florida West", dm(24, 20), -dm(82, 0), 1-1.0/17000, 200000, 0),
+		lambertZone(903, "Florida North", dm(29, 35), dm(30, 45), dm(29, 0), -dm(84, 30), 600000, 0),
+		tmZone(1001, "Georgia East", dm(30, 0), -dm(82, 10), 1-1.0/10000, 200000, 0),
+		tmZone(1002, "Georgia West", dm(30, 0), -dm(84, 10), 1-1.0/10000, 700000, 0),
+		tmZone(1201, "Illinois East", dm(36, 40), -dm(88, 20), 1-1.0/40000, 300000, 0),
+		tmZone(1202, "Illinois West", dm(36, 40), -dm(90, 10), 1-1.0/17000, 700000, 0),
+		tmZone(1301, "Indiana East", dm(37, 30), -dm(85, 40), 1-1.0/30000, 100000, 250000),
+		tmZone(1302, "Indiana West", dm(37, 30), -dm(87, 5), 1-1.0/30000, 900000, 250000),
+		lambertZone(1900, "Maryland", dm(38, 18), dm(39, 27), dm(37, 40), -dm(77, 0), 400000, 0),
+		tmZone(2701, "Nevada East", dm(34, 45), -dm(115, 35), 1-1.0/10000, 200000, 8000000),
+		tmZone(2702, "Nevada Central", dm(34, 45), -dm(116, 40), 1-1.0/10000, 500000, 6000000),
+		tmZone(2703, "Nevada West", dm(34, 45), -dm(118, 35), 1-1.0/10000, 800000, 4000000),
+		tmZone(2900, "New Jersey", dm(38, 50), -dm(74, 30), 1-1.0/10000, 150000, 0),
+		tmZone(3101, "New York East", dm(38, 50), -dm(74, 30), 1-1.0/10000, 150000, 0),
+		tmZone(3102, "New York Central", dm(40, 0), -dm(76, 35), 1-1.0/16000, 250000, 0),
+		tmZone(3103, "New York West", dm(40, 0), -dm(78, 35), 1-1.0/16000, 350000, 0),
+		lambertZone(3104, "New York Long Island", dm(40, 40), dm(41, 2), dm(40, 10), -dm(74, 0), 300000, 0),
+		lambertZone(3301, "Ohio North", dm(40, 26), dm(41, 42), dm(39, 40), -dm(82, 30), 600000, 0),
+		lambertZone(3302, "Ohio South", dm(38, 44), dm(40, 2), dm(38, 0), -dm(82, 30), 600000, 0),
+		lambertZone(3601, "Oregon North", dm(44, 20), dm(46, 0), dm(43, 40), -dm(120, 30), 2500000, 0),
+		lambertZone(3602, "Oregon South", dm(42, 20), dm(44, 0), dm(41, 40), -dm(120, 30), 1500000, 0),
+		lambertZone(3701, "Pennsylvania North", dm(40, 53), dm(41, 57), dm(40, 10), -dm(77, 45), 600000, 0),
+		lambertZone(3702, "Pennsylvania South", dm(39, 56), dm(40, 58), dm(39, 20), -dm(77, 45), 600000, 0),
+		lambertZone(4201, "Texas North", dm(34, 39), dm(36, 11), dm(34, 0), -dm(101, 30), 200000, 1000000),
+		lambertZone(4202, "Texas North Central", dm(32, 8), dm(33, 58), dm(31, 40), -dm(98, 30), 600000, 2000000),
+		lambertZone(4203, "Texas Central", dm(30, 7), dm(31, 53), dm(29, 40), -dm(100, 20), 700000, 3000000),
+		lambertZone(4204, "Texas South Central", dm(28, 23), dm(30, 17), dm(27, 50), -dm(99, 0), 600000, 4000000),
+		lambertZone(4205, "Texas South", dm(26, 10), dm(27, 50), dm(25, 40), -dm(98, 30), 300000, 5000000),
+		lambertZone(4501, "Virginia North", dm(38, 2), dm(39, 12), dm(37, 40), -dm(78, 30), 3500000, 2000000),
+		lambertZone(4502, "Virginia South", dm(36, 46), dm(37, 58), dm(36, 20), -dm(78, 30), 3500000, 1000000),
+		lambertZone(4601, "Washington North", dm(47, 30), dm(48, 44), dm(47, 0), -dm(120, 50), 500000, 0),
+		lambertZone(4602, "Washington South", dm(45, 50), dm(47, 20), dm(45, 20), -dm(120, 30), 500000, 0),
+	} {
+		zones[zone.FIPS] = zone
+	}
+}
+
+// Returns the zone of the FIPS code fips. Returns cartconvert.ErrNotFound, if the package doesn't define the zone.
+func LookupZone(fips uint) (*Zone, error) {
+	zone, ok := zones[fips]
+	if !ok {
+		return nil, cartconvert.ErrNotFound
+	}
+	return zone, nil
+}
+
+// Returns the zones defined by the package, ordered by FIPS code
+func Zones() []*Zone {
+	list := make([]*Zone, 0, len(zones))
+	for _, zone := range zones {
+		list = append(list, zone)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].FIPS < list[j].FIPS })
+	return list
+}
+
+// A State Plane coordinate is specified by the FIPS code of the zone, easting and northing in meters
+type SPCoord struct {
+	Zone      uint                   `json:"zone" xml:"zone"`
+	Easting   float64                `json:"easting" xml:"easting"`
+	Northing  float64                `json:"northing" xml:"northing"`
+	RelHeight float64                `json:"relheight" xml:"relheight"`
+	El        *cartconvert.Ellipsoid `json:"ellipsoid,omitempty" xml:"ellipsoid,omitempty"`
+}
+
+// Canonical representation of a State Plane coordinate, the FIPS code of the zone followed by easting and northing
+// to the meter, like "0405 1983028 563075"
+func (coord *SPCoord) String() string {
+	return fmt.Sprintf("%04d %.0f %.0f", coord.Zone, coord.Easting, coord.Northing)
+}
+
+// Implements cartconvert.Coordinate, the system of a State Plane coordinate is "stateplane"
+func (coord *SPCoord) System() string {
+	return "stateplane"
+}
+
+// Implements cartconvert.Coordinate. Returns 0, as the EPSG codes of the zones do not follow their FIPS codes and are
+// not tabulated by this package.
+func (coord *SPCoord) EPSG() int {
+	return 0
+}
+
+// Implements cartconvert.Coordinate, see SPToWGS84LatLong
+func (coord *SPCoord) ToWGS84() (*cartconvert.PolarCoord, error) {
+	return SPToWGS84LatLong(coord)
+}
+
+// Representation of a State Plane coordinate with precision decimal places of easting and northing, see
+// cartconvert.FormatFixed
+func (coord *SPCoord) Format(precision int) string {
+	return fmt.Sprintf("%04d %s %s", coord.Zone, cartconvert.FormatFixed(coord.Easting, precision), cartconvert.FormatFixed(coord.Northing, precision))
+}
+
+// Returns a copy of the State Plane coordinate with easting and northing snapped to the nearest multiple of
+// spacingMeters, see cartconvert.SnapToGrid. The zone is kept.
+func (coord *SPCoord) Snap(spacingMeters float64) *SPCoord {
+	snapped := *coord
+	snapped.Easting, snapped.Northing = cartconvert.SnapToGrid(coord.Easting, spacingMeters), cartconvert.SnapToGrid(coord.Northing, spacingMeters)
+	return &snapped
+}
+
+// Implements json.Marshaler, writing the coordinates in fixed-point notation
+func (coord *SPCoord) MarshalJSON() ([]byte, error) {
+	return cartconvert.MarshalFixedJSON(coord)
+}
+
+// Implements xml.Marshaler, writing the coordinates in fixed-point notation
+func (coord *SPCoord) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return cartconvert.MarshalFixedXML(e, start, coord)
+}
+
+// Validates that the State Plane coordinate lies within Extent, the conterminous United States. Returns the error
+// of SPToWGS84LatLong, if the zone is unknown, or a cartconvert.ExtentError, if easting and northing transform to a
+// location outside of Extent.
+func (coord *SPCoord) Valid() error {
+	gc, err := SPToWGS84LatLong(coord)
+	if err != nil {
+		return err
+	}
+	return Extent.Validate(gc, "State Plane", coord.String())
+}
+
+// Parses a string representation of a State Plane coordinate into a State Plane coordinate struct. The literal is
+// given as the FIPS code of the zone, easting and northing in meters separated by blanks, like
+//
+//	0405 1983028 563075
+//	4204 949800.25 4198000.75
+//
+// The reference ellipsoid of a State Plane coordinate will always be set to the GRS80 ellipsoid.
+//
+// returns cartconvert.ErrSyntax if the literal doesn't consist of zone, easting and northing
+// returns cartconvert.ErrNotFound if the package doesn't define the zone
+func ASPToStruct(spcoord string) (*SPCoord, error) {
+
+	fields := strings.Fields(spcoord)
+	if len(fields) != 3 {
+		return nil, cartconvert.ErrSyntax
+	}
+
+	fips, err := strconv.ParseUint(fields[0], 10, 16)
+	if err != nil {
+		return nil, cartconvert.ErrSyntax
+	}
+	if _, err = LookupZone(uint(fips)); err != nil {
+		return nil, err
+	}
+
+	easting, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return nil, err
+	}
+	northing, err := strconv.ParseFloat(fields[2], 64)
+	if err != nil {
+		return nil, err
+	}
+	if err = cartconvert.CheckFinite(easting, northing); err != nil {
+		return nil, err
+	}
+	return NewSPCoord(uint(fips), easting, northing, 0), nil
+}
+
+// Transform a State Plane coordinate value to a WGS84 based latitude and longitude coordinate. NAD83 is taken as
+// WGS84. The height above sea level RelHeight is not converted. Function returns cartconvert.ErrNotFound, if the
+// package doesn't define the zone of the coordinate.
+func SPToWGS84LatLong(coord *SPCoord) (*cartconvert.PolarCoord, error) {
+
+	zone, err := LookupZone(coord.Zone)
+	if err != nil {
+		return nil, err
+	}
+	if err = cartconvert.CheckFinite(coord.Easting, coord.Northing); err != nil {
+		return nil, err
+	}
+
+	lat, long := zone.unproject(coord.Easting, coord.Northing, cartconvert.GRS80Ellipsoid)
+	return &cartconvert.PolarCoord{Latitude: lat, Longitude: long, El: cartconvert.WGS84Ellipsoid}, nil
+}
+
+// Transform a WGS84 latitude / longitude coordinate into a State Plane coordinate of the zone with the FIPS code
+// fips. WGS84 is taken as NAD83. The height of gc is not converted. Function returns cartconvert.ErrNotFound, if
+// the package doesn't define the zone.
+func WGS84LatLongToSP(gc *cartconvert.PolarCoord, fips uint) (*SPCoord, error) {
+
+	zone, err := LookupZone(fips)
+	if err != nil {
+		return nil, err
+	}
+	if err = gc.CheckFinite(); err != nil {
+		return nil, err
+	}
+
+	easting, northing := zone.project(gc.Latitude, gc.Longitude, cartconvert.GRS80Ellipsoid)
+	return NewSPCoord(fips, easting, northing, 0), nil
+}
+
+func NewSPCoord(Zone uint, Easting, Northing, RelHeight float64) *SPCoord {
+	return &SPCoord{Zone: Zone, Easting: Easting, Northing: Northing, RelHeight: RelHeight, El: cartconvert.GRS80Ellipsoid}
+}
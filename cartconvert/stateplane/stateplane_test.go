@@ -0,0 +1,228 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// Automated tests for the cartconvert/stateplane package
+package stateplane
+
+import (
+	"fmt"
+	"github.com/the42/cartconvert/cartconvert"
+	"math"
+	"testing"
+)
+
+// ## ASPToStruct
+type aSPToStructTest struct {
+	in  string
+	out *SPCoord
+	err error
+}
+
+var aSPToStructTests = []aSPToStructTest{
+	{"0405 1983028 563075", NewSPCoord(405, 1983028, 563075, 0), nil},
+	{" 4204  949800.25 4198000.75 ", NewSPCoord(4204, 949800.25, 4198000.75, 0), nil},
+	{"405 1983028 563075", NewSPCoord(405, 1983028, 563075, 0), nil},
+	{"0405 1983028", nil, cartconvert.ErrSyntax},
+	{"CA5 1983028 563075", nil, cartconvert.ErrSyntax},
+	{"0499 1983028 563075", nil, cartconvert.ErrNotFound},
+}
+
+func spequal(sp1, sp2 *SPCoord) bool {
+	p1 := fmt.Sprintf("%d %.2f %.2f", sp1.Zone, sp1.Easting, sp1.Northing)
+	p2 := fmt.Sprintf("%d %.2f %.2f", sp2.Zone, sp2.Easting, sp2.Northing)
+
+	return p1 == p2
+}
+
+func TestASPToStruct(t *testing.T) {
+	for index, test := range aSPToStructTests {
+		out, err := ASPToStruct(test.in)
+
+		if err != test.err {
+			t.Errorf("ASPToStruct [%d]: expected error %v, got %v", index, test.err, err)
+			continue
+		}
+		if test.out != nil && !spequal(test.out, out) {
+			t.Errorf("ASPToStruct [%d]: expected %v, got %v", index, test.out, out)
+		}
+	}
+}
+
+// ## String
+func TestSPCoordString(t *testing.T) {
+	if s := NewSPCoord(405, 1983028.4, 563074.6, 0).String(); s != "0405 1983028 563075" {
+		t.Errorf("SPCoord.String: expected 0405 1983028 563075, got %s", s)
+	}
+}
+
+// ## WGS84LatLongToSP, SPToWGS84LatLong
+func TestZones(t *testing.T) {
+	for _, zone := range Zones() {
+		// the origin of the zone yields false easting and northing
+		sp, err := WGS84LatLongToSP(&cartconvert.PolarCoord{Latitude: zone.LatO, Longitude: zone.LongO, El: cartconvert.WGS84Ellipsoid}, zone.FIPS)
+		if err != nil || math.Abs(sp.Easting-zone.FalseEasting) > 1e-3 || math.Abs(sp.Northing-zone.FalseNorthing) > 1e-3 {
+			t.Errorf("WGS84LatLongToSP %04d %s: expected the false origin %.3f %.3f, got %v (%v)", zone.FIPS, zone.Name,
+				zone.FalseEasting, zone.FalseNorthing, sp, err)
+			continue
+		}
+
+		gc := &cartconvert.PolarCoord{Latitude: zone.LatO + 1.5, Longitude: zone.LongO + 0.75, El: cartconvert.WGS84Ellipsoid}
+		if sp, err = WGS84LatLongToSP(gc, zone.FIPS); err != nil {
+			t.Errorf("WGS84LatLongToSP %04d %s: %s", zone.FIPS, zone.Name, err)
+			continue
+		}
+		back, err := SPToWGS84LatLong(sp)
+		if err != nil || math.Abs(back.Latitude-gc.Latitude) > 1e-8 || math.Abs(back.Longitude-gc.Longitude) > 1e-8 {
+			t.Errorf("SPToWGS84LatLong %04d %s: expected %s, got %s (%v)", zone.FIPS, zone.Name, gc, back, err)
+		}
+		if back.El != cartconvert.WGS84Ellipsoid {
+			t.Errorf("SPToWGS84LatLong %04d %s: expected the WGS84 ellipsoid, got %v", zone.FIPS, zone.Name, back.El)
+		}
+	}
+
+	if _, err := WGS84LatLongToSP(&cartconvert.PolarCoord{Latitude: 34, Longitude: -118}, 499); err != cartconvert.ErrNotFound {
+		t.Errorf("WGS84LatLongToSP: expected ErrNotFound for an unknown zone, got %v", err)
+	}
+	if _, err := SPToWGS84LatLong(NewSPCoord(499, 2000000, 500000, 0)); err != cartconvert.ErrNotFound {
+		t.Errorf("SPToWGS84LatLong: expected ErrNotFound for an unknown zone, got %v", err)
+	}
+}
+
+// ## project, unproject
+// The worked examples of the projections of the zones from the EPSG guidance note 7-2 (IOGP Publication 373-7-2,
+// Coordinate Conversions and Transformations including Formulas), which apply the formulas of Manual NOS NGS 5.
+// The Lambert Conformal Conic example is the zone Texas South Central of SPCS27, on the Clarke 1866 ellipsoid of
+// NAD27 in US survey feet, the Transverse Mercator example the British National Grid on the Airy 1830 ellipsoid.
+const usSurveyFoot = 1200.0 / 3937
+
+type projectTest struct {
+	zone              *Zone
+	el                *cartconvert.Ellipsoid
+	lat, long         float64
+	easting, northing float64 // in meters
+	tolerance         float64 // of easting and northing in meters
+}
+
+var projectTests = []projectTest{
+	{lambertZone(4204, "Texas South Central, SPCS27", dm(28, 23), dm(30, 17), dm(27, 50), -dm(99, 0), 2000000*usSurveyFoot, 0),
+		cartconvert.Clarke1866Ellipsoid, dm(28, 30), -96, 2963503.91 * usSurveyFoot, 254759.80 * usSurveyFoot, 0.01 * usSurveyFoot},
+	{tmZone(0, "British National Grid", 49, -2, 0.9996012717, 400000, -100000),
+		cartconvert.Airy1830Ellipsoid, dm(50, 30), dm(0, 30), 577274.99, 69740.49, 0.01},
+}
+
+func TestProject(t *testing.T) {
+	for _, test := range projectTests {
+		easting, northing := test.zone.project(test.lat, test.long, test.el)
+		if math.Abs(easting-test.easting) > test.tolerance || math.Abs(northing-test.northing) > test.tolerance {
+			t.Errorf("project %s: expected %.3f %.3f, got %.3f %.3f", test.zone.Name, test.easting, test.northing, easting, northing)
+		}
+
+		// the coordinates are published to the centimeter, about 1e-7 degrees
+		lat, long := test.zone.unproject(test.easting, test.northing, test.el)
+		if math.Abs(lat-test.lat) > 1e-6 || math.Abs(long-test.long) > 1e-6 {
+			t.Errorf("unproject %s: expected %.8f %.8f, got %.8f %.8f", test.zone.Name, test.lat, test.long, lat, long)
+		}
+	}
+}
+
+func TestLookupZone(t *testing.T) {
+	zone, err := LookupZone(4204)
+	if err != nil || zone.Name != "Texas South Central" || zone.Projection != LambertConformalConic {
+		t.Errorf("LookupZone: expected Texas South Central, got %v (%v)", zone, err)
+	}
+	zone, err = LookupZone(3101)
+	if err != nil || zone.Name != "New York East" || zone.Projection != TransverseMercator {
+		t.Errorf("LookupZone: expected New York East, got %v (%v)", zone, err)
+	}
+
+	zones := Zones()
+	for i := 1; i < len(zones); i++ {
+		if zones[i-1].FIPS >= zones[i].FIPS {
+			t.Errorf("Zones: expected the zones ordered by FIPS code, got %04d before %04d", zones[i-1].FIPS, zones[i].FIPS)
+		}
+	}
+}
+
+type validTest struct {
+	in    *SPCoord
+	valid bool
+}
+
+var validTests = []validTest{
+	{NewSPCoord(405, 1983028, 563075, 0), true},
+	{NewSPCoord(3101, 150000, 200000, 0), true},
+	// the Pacific Ocean
+	{NewSPCoord(405, -1000000, 563075, 0), false},
+}
+
+func TestValid(t *testing.T) {
+	for index, test := range validTests {
+		err := test.in.Valid()
+		if _, ok := err.(cartconvert.ExtentError); (err == nil) != test.valid || (err != nil && !ok) {
+			t.Errorf("Valid [%d]: expected valid %t for %s, got %v", index, test.valid, test.in, err)
+		}
+	}
+
+	if err := NewSPCoord(499, 2000000, 500000, 0).Valid(); err != cartconvert.ErrNotFound {
+		t.Errorf("Valid: expected ErrNotFound for an unknown zone, got %v", err)
+	}
+}
+
+// ## RoundTrip
+// The positional error in meters of a latitude / longitude coordinate converted into a State Plane coordinate and back
+type roundTripTest struct {
+	gc        *cartconvert.PolarCoord
+	threshold float64
+}
+
+type roundTripZoneTest struct {
+	roundTripTest
+	fips uint
+}
+
+// Both projections are inverted to the millimeter
+var roundTripTests = []roundTripZoneTest{
+	{roundTripTest{&cartconvert.PolarCoord{Latitude: 34.0522, Longitude: -118.2437, El: cartconvert.WGS84Ellipsoid}, 0.001}, 405},
+	{roundTripTest{&cartconvert.PolarCoord{Latitude: 40.7128, Longitude: -74.006, El: cartconvert.WGS84Ellipsoid}, 0.001}, 3104},
+	{roundTripTest{&cartconvert.PolarCoord{Latitude: 29.7604, Longitude: -95.3698, El: cartconvert.WGS84Ellipsoid}, 0.001}, 4204},
+}
+
+func TestRoundTrip(t *testing.T) {
+	for index, test := range roundTripTests {
+		coord, err := WGS84LatLongToSP(test.gc, test.fips)
+		if err != nil {
+			t.Fatalf("WGS84LatLongToSP [%d]: %s", index, err)
+		}
+		out, err := SPToWGS84LatLong(coord)
+		if err != nil {
+			t.Fatalf("SPToWGS84LatLong [%d]: %s", index, err)
+		}
+		if d := cartconvert.Haversine(test.gc, out, 0); d > test.threshold {
+			t.Errorf("RoundTrip [%d]: expected a positional error below %g m, got %.3f m", index, test.threshold, d)
+		}
+	}
+}
+
+// ## NaN and infinity
+func TestNonFinite(t *testing.T) {
+	for _, value := range []float64{math.NaN(), math.Inf(1)} {
+		if _, err := ASPToStruct(fmt.Sprintf("0405 %v 563075", value)); err != cartconvert.ErrNotFinite {
+			t.Errorf("ASPToStruct (%v): expected ErrNotFinite, got %v", value, err)
+		}
+		if _, err := SPToWGS84LatLong(NewSPCoord(405, 1983028, value, 0)); err != cartconvert.ErrNotFinite {
+			t.Errorf("SPToWGS84LatLong (%v): expected ErrNotFinite, got %v", value, err)
+		}
+		if _, err := WGS84LatLongToSP(&cartconvert.PolarCoord{Latitude: value, Longitude: -122}, 405); err != cartconvert.ErrNotFinite {
+			t.Errorf("WGS84LatLongToSP (%v): expected ErrNotFinite, got %v", value, err)
+		}
+	}
+}
+
+// ## Snap
+func TestSnap(t *testing.T) {
+	out := (&SPCoord{Zone: 405, Easting: -150.5, Northing: 64350}).Snap(100)
+	if out.Easting != -200 || out.Northing != 64400 || out.Zone != 405 {
+		t.Errorf("SPCoord.Snap: expected 0405 -200 64400, got %s", out)
+	}
+}
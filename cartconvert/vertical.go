@@ -0,0 +1,96 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// This file provides the conversion of heights between vertical datums.
+package cartconvert
+
+import (
+	"fmt"
+)
+
+// ## Vertical datums
+
+// A GeoidModel yields the geoid undulation, the height of the geoid above the reference ellipsoid of a location,
+// such as an implementation of EGM2008 or a national geoid grid. The package does not provide a model, as the
+// grids are large and distributed by the national mapping agencies. The subpackage geoid provides a coarse grid
+// of EGM96 and reads the grids of the NGA.
+type GeoidModel interface {
+	// Returns the geoid undulation in meters at gc. The coordinate refers to the datum of the model.
+	// Returns ErrRange if the location is outside of the extent of the model.
+	Undulation(gc *PolarCoord) (float64, error)
+}
+
+// A VerticalDatum defines the reference surface heights are measured from. Ellipsoidal heights are measured from
+// the reference ellipsoid, orthometric heights from a geoid given by a GeoidModel. Offset is the height of the zero
+// level of the datum above the geoid in meters, as for local datums defined by a tide gauge which differ from the
+// geoid model by a constant.
+type VerticalDatum struct {
+	Name        string
+	Ellipsoidal bool
+	Geoid       GeoidModel
+	Offset      float64
+}
+
+// A VerticalDatumError is yielded when heights can not be converted from or into a vertical datum.
+type VerticalDatumError struct {
+	Datum string // Name of the vertical datum
+	Err   error  // ErrUnsupported if no geoid model is configured for the datum, or the error of the geoid model
+}
+
+func (ve VerticalDatumError) Error() string {
+	return fmt.Sprintf("vertical datum \"%s\": %s", ve.Datum, ve.Err.Error())
+}
+
+var (
+	// Heights above the reference ellipsoid, as delivered by GNSS receivers
+	EllipsoidalHeights = &VerticalDatum{Name: "ellipsoidal", Ellipsoidal: true}
+	// Austrian heights above the Adriatic Sea (Gebrauchshöhen über Adria), referring to the tide gauge of Trieste.
+	// No geoid model is configured by default; set Geoid to a model of the Austrian geoid on MGI.
+	VerticalDatumAdriatic = &VerticalDatum{Name: "AT:Adria"}
+)
+
+// Returns the height of the zero level of vd above the reference ellipsoid at gc
+func (vd *VerticalDatum) separation(gc *PolarCoord) (float64, error) {
+
+	if vd.Ellipsoidal {
+		return 0, nil
+	}
+	if vd.Geoid == nil {
+		return 0, VerticalDatumError{Datum: vd.Name, Err: ErrUnsupported}
+	}
+
+	undulation, err := vd.Geoid.Undulation(gc)
+	if err != nil {
+		return 0, VerticalDatumError{Datum: vd.Name, Err: err}
+	}
+	return undulation + vd.Offset, nil
+}
+
+// Converts gc, whose height refers to the vertical datum srcVDatum, by the horizontal transformation transform
+// and returns the result with its height referring to targetVDatum. The horizontal transformation, such as a datum
+// shift by a helmert transformation, is applied to the ellipsoidal height, so the height follows the change of the
+// reference ellipsoid. The geoid of srcVDatum is evaluated at gc, the geoid of targetVDatum at the transformed coordinate.
+// If transform is nil, only the height is converted.
+//
+// Returns a VerticalDatumError if either vertical datum lacks a geoid model or the geoid model fails, or the error of transform.
+func Convert3D(gc *PolarCoord, srcVDatum *VerticalDatum, transform func(*PolarCoord) (*PolarCoord, error), targetVDatum *VerticalDatum) (*PolarCoord, error) {
+
+	separation, err := srcVDatum.separation(gc)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &PolarCoord{Latitude: gc.Latitude, Longitude: gc.Longitude, Height: gc.Height + separation, El: gc.El}
+	if transform != nil {
+		if out, err = transform(out); err != nil {
+			return nil, err
+		}
+	}
+
+	if separation, err = targetVDatum.separation(out); err != nil {
+		return nil, err
+	}
+	out.Height -= separation
+	return out, nil
+}
@@ -0,0 +1,92 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// Automated tests for the vertical datums of the cartconvert package
+package cartconvert
+
+import (
+	"math"
+	"testing"
+)
+
+// a geoid model of constant undulation within Austria
+type constantUndulation float64
+
+func (cu constantUndulation) Undulation(gc *PolarCoord) (float64, error) {
+	if gc.Latitude < 46 || gc.Latitude > 49.5 {
+		return 0, ErrRange
+	}
+	return float64(cu), nil
+}
+
+// helmert transformations between MGI and WGS84, which carry the ellipsoidal height
+func mgiToWGS84(gc *PolarCoord) (*PolarCoord, error) {
+	cart := PolarToCartesian(gc)
+	pt := HelmertWGS84ToMGI.InverseTransform(&Point3D{X: cart.X, Y: cart.Y, Z: cart.Z})
+	return CartesianToPolar(&CartPoint{X: pt.X, Y: pt.Y, Z: pt.Z, El: WGS84Ellipsoid}), nil
+}
+
+func wgs84ToMGI(gc *PolarCoord) (*PolarCoord, error) {
+	cart := PolarToCartesian(gc)
+	pt := HelmertWGS84ToMGI.Transform(&Point3D{X: cart.X, Y: cart.Y, Z: cart.Z})
+	return CartesianToPolar(&CartPoint{X: pt.X, Y: pt.Y, Z: pt.Z, El: Bessel1841MGIEllipsoid}), nil
+}
+
+// ## Convert3D
+func TestConvert3D(t *testing.T) {
+	adriatic := &VerticalDatum{Name: "test", Geoid: constantUndulation(48.5), Offset: -0.3}
+	egm := &VerticalDatum{Name: "egm", Geoid: constantUndulation(47.9)}
+
+	// Graz, 353 m above the Adriatic Sea
+	graz := &PolarCoord{Latitude: 47.071, Longitude: 15.439, Height: 353, El: Bessel1841MGIEllipsoid}
+
+	out, err := Convert3D(graz, adriatic, nil, EllipsoidalHeights)
+	if err != nil || !floatequal(out.Height, 401.2) || out.Latitude != graz.Latitude || out.El != graz.El {
+		t.Errorf("Convert3D: expected an ellipsoidal height of 401.2, got %s (%v)", out, err)
+	}
+
+	if out, err = Convert3D(graz, adriatic, nil, egm); err != nil || !floatequal(out.Height, 353.3) {
+		t.Errorf("Convert3D: expected an orthometric height of 353.3, got %s (%v)", out, err)
+	}
+
+	wgs84, err := Convert3D(graz, adriatic, mgiToWGS84, EllipsoidalHeights)
+	if err != nil {
+		t.Fatalf("Convert3D: %s", err)
+	}
+	// the ellipsoidal height changes with the reference ellipsoid
+	if wgs84.El != WGS84Ellipsoid || math.Abs(wgs84.Height-447.43) > 0.1 {
+		t.Errorf("Convert3D: expected a WGS84 ellipsoidal height of 447.43, got %s, %f", wgs84, wgs84.Height)
+	}
+
+	back, err := Convert3D(wgs84, EllipsoidalHeights, wgs84ToMGI, adriatic)
+	// the inverse helmert transformation is an approximation
+	if err != nil || math.Abs(back.Height-graz.Height) > 0.01 || !latlongequal(back, graz) {
+		t.Errorf("Convert3D: expected %s in the round trip, got %s, %f (%v)", graz, back, back.Height, err)
+	}
+}
+
+func TestConvert3DErrors(t *testing.T) {
+	graz := &PolarCoord{Latitude: 47.071, Longitude: 15.439, Height: 353, El: Bessel1841MGIEllipsoid}
+	berlin := &PolarCoord{Latitude: 52.52, Longitude: 13.405, Height: 34, El: Bessel1841MGIEllipsoid}
+	adriatic := &VerticalDatum{Name: "test", Geoid: constantUndulation(48.5)}
+
+	for index, test := range []struct {
+		gc                      *PolarCoord
+		srcVDatum, targetVDatum *VerticalDatum
+		err                     error
+	}{
+		{graz, VerticalDatumAdriatic, EllipsoidalHeights, VerticalDatumError{Datum: "AT:Adria", Err: ErrUnsupported}},
+		{graz, EllipsoidalHeights, VerticalDatumAdriatic, VerticalDatumError{Datum: "AT:Adria", Err: ErrUnsupported}},
+		{berlin, adriatic, EllipsoidalHeights, VerticalDatumError{Datum: "test", Err: ErrRange}},
+	} {
+		if _, err := Convert3D(test.gc, test.srcVDatum, mgiToWGS84, test.targetVDatum); err != test.err {
+			t.Errorf("Convert3D [%d]: expected %v, got %v", index, test.err, err)
+		}
+	}
+
+	failing := func(gc *PolarCoord) (*PolarCoord, error) { return nil, ErrRange }
+	if _, err := Convert3D(graz, EllipsoidalHeights, failing, EllipsoidalHeights); err != ErrRange {
+		t.Errorf("Convert3D: expected the error of the transformation, got %v", err)
+	}
+}
@@ -0,0 +1,501 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// This file provides the parsing of coordinate reference systems defined as WKT2 strings and their WKT1
+// representation.
+package cartconvert
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ## WKT2 coordinate reference systems
+
+// A WKTError is yielded when a WKT2 definition of a coordinate reference system can not be parsed
+// or describes a coordinate reference system not supported by this package.
+type WKTError struct {
+	Keyword string // The keyword of the offending WKT element, or the offending token for syntax errors
+	Err     error  // ErrSyntax for malformed definitions, ErrUnsupported for methods or elements not implemented by this package
+}
+
+func (we WKTError) Error() string {
+	return fmt.Sprintf("WKT element \"%s\": %s", we.Keyword, we.Err.Error())
+}
+
+// The name of the Transverse Mercator projection method
+const MethodTransverseMercator = "Transverse Mercator"
+
+// A coordinate reference system as defined by a WKT2 string or registered by its EPSG code. Create instances by
+// calling ParseWKT2CRS or look them up by ByEPSG. For a geographic coordinate reference system, Method is empty
+// and the projection parameters are zero.
+type CRS struct {
+	EPSG   int        // EPSG code of the coordinate reference system, 0 if it has none
+	Name   string     // Name of the coordinate reference system
+	Datum  string     // Name of the datum as used by the helmert parameter sets, empty if unknown
+	El     *Ellipsoid // Reference ellipsoid of the (base) datum
+	Method string     // Name of the projection method as given by the definition
+
+	// The parameters of the projection. Angles are given in decimal degrees, lengths in meters
+	LatO, LongO, Scale, FalseEasting, FalseNorthing float64
+}
+
+// Returns true, if the coordinate reference system is a projected one
+func (crs *CRS) Projected() bool {
+	return len(crs.Method) > 0
+}
+
+// Projects a latitude / longitude coordinate into the coordinate reference system.
+// Returns ErrUnsupported for geographic coordinate reference systems.
+func (crs *CRS) Project(gc *PolarCoord) (*GeoPoint, error) {
+	if !crs.Projected() {
+		return nil, ErrUnsupported
+	}
+	pc := *gc
+	pc.El = crs.El
+	return DirectTransverseMercator(&pc, crs.LatO, crs.LongO, crs.Scale, crs.FalseEasting, crs.FalseNorthing), nil
+}
+
+// Returns the latitude / longitude coordinate of a projected coordinate of the coordinate reference system.
+// Returns ErrUnsupported for geographic coordinate reference systems.
+func (crs *CRS) InverseProject(gp *GeoPoint) (*PolarCoord, error) {
+	if !crs.Projected() {
+		return nil, ErrUnsupported
+	}
+	pt := *gp
+	pt.El = crs.El
+	return InverseTransverseMercator(&pt, crs.LatO, crs.LongO, crs.Scale, crs.FalseEasting, crs.FalseNorthing), nil
+}
+
+// A WKT element KEYWORD[value, ...]. Values are either quoted strings, numbers, enumerations or nested elements.
+type wktNode struct {
+	keyword string
+	values  []interface{}
+}
+
+// Returns the first nested element having one of the keywords, nil if there is none
+func (node *wktNode) child(keywords ...string) *wktNode {
+	for _, value := range node.values {
+		if child, ok := value.(*wktNode); ok {
+			for _, keyword := range keywords {
+				if child.keyword == keyword {
+					return child
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Returns the quoted string value at index
+func (node *wktNode) text(index int) (string, error) {
+	if index < len(node.values) {
+		if text, ok := node.values[index].(wktText); ok {
+			return string(text), nil
+		}
+	}
+	return "", WKTError{Keyword: node.keyword, Err: ErrSyntax}
+}
+
+// Returns the numeric value at index
+func (node *wktNode) number(index int) (float64, error) {
+	if index < len(node.values) {
+		if number, ok := node.values[index].(wktNumber); ok {
+			return float64(number), nil
+		}
+	}
+	return 0, WKTError{Keyword: node.keyword, Err: ErrSyntax}
+}
+
+// Returns the EPSG code of the ID element of node, 0 if there is none
+func (node *wktNode) epsg() int {
+	if id := node.child("ID", "AUTHORITY"); id != nil {
+		if authority, err := id.text(0); err == nil && strings.EqualFold(authority, "EPSG") {
+			if code, err := id.number(1); err == nil {
+				return int(code)
+			}
+			if code, err := id.text(1); err == nil {
+				n, _ := strconv.Atoi(code)
+				return n
+			}
+		}
+	}
+	return 0
+}
+
+// Returns the conversion factor of the unit of node to the SI unit, or def if node has no unit
+func (node *wktNode) unit(def float64, keywords ...string) (float64, error) {
+	unit := node.child(keywords...)
+	if unit == nil {
+		return def, nil
+	}
+	return unit.number(1)
+}
+
+type (
+	wktText   string
+	wktNumber float64
+	wktEnum   string
+)
+
+// A lexer of WKT definitions
+type wktLexer struct {
+	definition string
+	pos        int
+}
+
+// Returns the next token, which is one of the delimiters "[", "]", ",", a quoted string including the quotes,
+// a number or a keyword. Returns an empty token at the end of the definition.
+func (lex *wktLexer) next() (string, error) {
+
+	for lex.pos < len(lex.definition) && unicode.IsSpace(rune(lex.definition[lex.pos])) {
+		lex.pos++
+	}
+	if lex.pos == len(lex.definition) {
+		return "", nil
+	}
+
+	start := lex.pos
+	switch c := lex.definition[lex.pos]; {
+	case c == '[' || c == '(':
+		lex.pos++
+		return "[", nil
+	case c == ']' || c == ')':
+		lex.pos++
+		return "]", nil
+	case c == ',':
+		lex.pos++
+		return ",", nil
+	case c == '"':
+		// quotes within a quoted text are escaped by doubling them
+		for lex.pos++; lex.pos < len(lex.definition); lex.pos++ {
+			if lex.definition[lex.pos] == '"' {
+				if lex.pos+1 < len(lex.definition) && lex.definition[lex.pos+1] == '"' {
+					lex.pos++
+					continue
+				}
+				lex.pos++
+				return lex.definition[start:lex.pos], nil
+			}
+		}
+		return "", WKTError{Keyword: lex.definition[start:], Err: ErrSyntax}
+	default:
+		for lex.pos < len(lex.definition) && !strings.ContainsRune("[](),\"", rune(lex.definition[lex.pos])) &&
+			!unicode.IsSpace(rune(lex.definition[lex.pos])) {
+			lex.pos++
+		}
+		return lex.definition[start:lex.pos], nil
+	}
+}
+
+// Parses the element starting with keyword, the opening bracket not yet consumed
+func (lex *wktLexer) parseNode(keyword string) (*wktNode, error) {
+
+	if token, err := lex.next(); err != nil || token != "[" {
+		return nil, WKTError{Keyword: keyword, Err: ErrSyntax}
+	}
+
+	node := &wktNode{keyword: strings.ToUpper(keyword)}
+	for {
+		token, err := lex.next()
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case token == "" || token == "[" || token == "]" || token == ",":
+			return nil, WKTError{Keyword: keyword, Err: ErrSyntax}
+		case token[0] == '"':
+			node.values = append(node.values, wktText(strings.Replace(token[1:len(token)-1], `""`, `"`, -1)))
+		case token[0] == '-' || token[0] == '+' || token[0] == '.' || (token[0] >= '0' && token[0] <= '9'):
+			number, err := strconv.ParseFloat(token, 64)
+			if err != nil {
+				return nil, WKTError{Keyword: token, Err: ErrSyntax}
+			}
+			node.values = append(node.values, wktNumber(number))
+		default:
+			// a keyword followed by an opening bracket starts a nested element, otherwise it is an enumeration
+			pos := lex.pos
+			if next, err := lex.next(); err == nil && next == "[" {
+				lex.pos = pos
+				child, err := lex.parseNode(token)
+				if err != nil {
+					return nil, err
+				}
+				node.values = append(node.values, child)
+			} else {
+				lex.pos = pos
+				node.values = append(node.values, wktEnum(token))
+			}
+		}
+
+		token, err = lex.next()
+		if err != nil {
+			return nil, err
+		}
+		switch token {
+		case "]":
+			return node, nil
+		case ",":
+		default:
+			return nil, WKTError{Keyword: keyword, Err: ErrSyntax}
+		}
+	}
+}
+
+// Parses a WKT2 (ISO 19162) definition of a coordinate reference system, eg. the contents of a .prj file,
+// for the subset supported by this package. Supported are geographic coordinate reference systems (GEOGCRS)
+// and projected coordinate reference systems (PROJCRS) using the Transverse Mercator projection method,
+// which include the UTM zones. The reference ellipsoid is set to the predefined ellipsoid of the package
+// if the definition matches one, otherwise a new ellipsoid is created.
+//
+// Returns a WKTError, which carries ErrSyntax for malformed definitions and ErrUnsupported for other
+// types of coordinate reference systems or other projection methods.
+func ParseWKT2CRS(definition string) (*CRS, error) {
+
+	lex := &wktLexer{definition: definition}
+	keyword, err := lex.next()
+	if err != nil {
+		return nil, err
+	}
+	if len(keyword) == 0 || strings.ContainsAny(keyword[:1], "[],\"") {
+		return nil, WKTError{Keyword: keyword, Err: ErrSyntax}
+	}
+
+	root, err := lex.parseNode(keyword)
+	if err != nil {
+		return nil, err
+	}
+	if token, _ := lex.next(); token != "" {
+		return nil, WKTError{Keyword: token, Err: ErrSyntax}
+	}
+
+	crs := &CRS{EPSG: root.epsg()}
+	if crs.Name, err = root.text(0); err != nil {
+		return nil, err
+	}
+
+	geodetic := root
+	switch root.keyword {
+	case "GEOGCRS", "GEOGRAPHICCRS":
+	case "PROJCRS", "PROJECTEDCRS":
+		if geodetic = root.child("BASEGEOGCRS", "BASEGEODCRS"); geodetic == nil {
+			return nil, WKTError{Keyword: root.keyword, Err: ErrSyntax}
+		}
+		conversion := root.child("CONVERSION")
+		if conversion == nil {
+			return nil, WKTError{Keyword: root.keyword, Err: ErrSyntax}
+		}
+		if err = crs.parseConversion(conversion); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, WKTError{Keyword: root.keyword, Err: ErrUnsupported}
+	}
+
+	datum := geodetic.child("DATUM", "GEODETICDATUM", "TRF")
+	if datum == nil {
+		return nil, WKTError{Keyword: geodetic.keyword, Err: ErrSyntax}
+	}
+	ellipsoid := datum.child("ELLIPSOID", "SPHEROID")
+	if ellipsoid == nil {
+		return nil, WKTError{Keyword: datum.keyword, Err: ErrSyntax}
+	}
+	if crs.El, err = parseWKTEllipsoid(ellipsoid); err != nil {
+		return nil, err
+	}
+
+	return crs, nil
+}
+
+// Returns the ellipsoid ELLIPSOID["name", semi-major axis, inverse flattening, LENGTHUNIT[...]]
+func parseWKTEllipsoid(node *wktNode) (*Ellipsoid, error) {
+
+	name, err := node.text(0)
+	if err != nil {
+		return nil, err
+	}
+	a, err := node.number(1)
+	if err != nil {
+		return nil, err
+	}
+	rf, err := node.number(2)
+	if err != nil {
+		return nil, err
+	}
+	factor, err := node.unit(1, "LENGTHUNIT")
+	if err != nil {
+		return nil, err
+	}
+
+	a *= factor
+	b := a
+	// an inverse flattening of 0 denotes a sphere
+	if rf != 0 {
+		b = a * (1 - 1/rf)
+	}
+	if !(a > 0) || !(b > 0) {
+		return nil, WKTError{Keyword: node.keyword, Err: ErrSyntax}
+	}
+
+	// the semi-minor axes of WGS84 and GRS80 differ by 0.1 mm only, choose the closest match
+	var match *Ellipsoid
+	deviation := 1e-3
+	for _, el := range []*Ellipsoid{WGS84Ellipsoid, GRS80Ellipsoid, Bessel1841Ellipsoid, Bessel1841MGIEllipsoid, Airy1830Ellipsoid, Airy1830ModEllipsoid, Clarke1866Ellipsoid, International1924Ellipsoid} {
+		if d := math.Max(math.Abs(el.a-a), math.Abs(el.b-b)); d < deviation {
+			match, deviation = el, d
+		}
+	}
+	if match == nil {
+		match = NewEllipsoid(a, b, name)
+	}
+	return match, nil
+}
+
+// EPSG codes and names of the parameters of the Transverse Mercator projection
+var wktTransverseMercatorParameters = []struct {
+	epsg int
+	name string
+}{
+	{8801, "latitude of natural origin"},
+	{8802, "longitude of natural origin"},
+	{8805, "scale factor at natural origin"},
+	{8806, "false easting"},
+	{8807, "false northing"},
+}
+
+// Sets the projection method and parameters of the CONVERSION element
+func (crs *CRS) parseConversion(conversion *wktNode) error {
+
+	method := conversion.child("METHOD", "PROJECTION")
+	if method == nil {
+		return WKTError{Keyword: conversion.keyword, Err: ErrSyntax}
+	}
+	name, err := method.text(0)
+	if err != nil {
+		return err
+	}
+	if epsg := method.epsg(); epsg != 9807 && (epsg != 0 || !strings.EqualFold(name, MethodTransverseMercator)) {
+		return WKTError{Keyword: method.keyword + ": " + name, Err: ErrUnsupported}
+	}
+	crs.Method = name
+
+	crs.Scale = 1
+	targets := []*float64{&crs.LatO, &crs.LongO, &crs.Scale, &crs.FalseEasting, &crs.FalseNorthing}
+
+	for _, value := range conversion.values {
+		parameter, ok := value.(*wktNode)
+		if !ok || parameter.keyword != "PARAMETER" {
+			continue
+		}
+		pname, err := parameter.text(0)
+		if err != nil {
+			return err
+		}
+		pvalue, err := parameter.number(1)
+		if err != nil {
+			return err
+		}
+
+		for i, tmp := range wktTransverseMercatorParameters {
+			if epsg := parameter.epsg(); epsg != tmp.epsg && (epsg != 0 || !strings.EqualFold(pname, tmp.name)) {
+				continue
+			}
+
+			// angles are converted from radians to degrees, the other units to their SI unit
+			factor := 1.0
+			if unit := parameter.child("ANGLEUNIT"); unit != nil {
+				if factor, err = unit.number(1); err != nil {
+					return err
+				}
+				factor = radtodeg(factor)
+			} else if factor, err = parameter.unit(1, "LENGTHUNIT", "SCALEUNIT"); err != nil {
+				return err
+			}
+			*targets[i] = pvalue * factor
+		}
+	}
+	return nil
+}
+
+// ## WKT1 representation
+
+// Formats a number of a WKT1 definition with up to 10 decimal places
+func wkt1Number(val float64) string {
+	return FormatTrimmed(val, 10)
+}
+
+// Returns the WKT1 AUTHORITY element of an EPSG code, or an empty string if code is 0
+func wkt1Authority(code int) string {
+	if code == 0 {
+		return ""
+	}
+	return fmt.Sprintf(`,AUTHORITY["EPSG","%d"]`, code)
+}
+
+// Returns the WKT1 representation of the ellipsoid, SPHEROID["name",semi-major axis,inverse flattening]. The inverse
+// flattening of a sphere is 0.
+func (el *Ellipsoid) WKT() string {
+	rf := 0.0
+	if el.a != el.b {
+		rf = el.a / (el.a - el.b)
+	}
+	return fmt.Sprintf(`SPHEROID["%s",%s,%s%s]`, el.CommonName, strconv.FormatFloat(el.a, 'f', -1, 64), FormatTrimmed(rf, 9), wkt1Authority(el.epsg))
+}
+
+// Returns the registered geographic coordinate reference system of the datum and reference ellipsoid of crs
+// with the lowest EPSG code, nil if there is none
+func (crs *CRS) geographic() *CRS {
+
+	crsRegistryLock.RLock()
+	defer crsRegistryLock.RUnlock()
+
+	var match *CRS
+	for _, other := range crss {
+		if !other.Projected() && other.Datum == crs.Datum && other.El == crs.El && (match == nil || other.EPSG < match.EPSG) {
+			match = other
+		}
+	}
+	return match
+}
+
+// Returns the WKT1 representation of the coordinate reference system, a GEOGCS element for geographic systems
+// and a PROJCS element for projected ones, like
+//
+//	PROJCS["OSGB 1936 / British National Grid",GEOGCS["OSGB 1936",DATUM["OSGB36",SPHEROID["Airy1830",...]],...],
+//	    PROJECTION["Transverse_Mercator"],PARAMETER["latitude_of_origin",49],...,AUTHORITY["EPSG","27700"]]
+//
+// The base geographic system of a projected system is the registered one of the same datum and reference
+// ellipsoid. Angles are given in degrees, lengths in meters. Datum shifts (TOWGS84) are not included.
+func (crs *CRS) WKT() string {
+
+	base := crs
+	if crs.Projected() {
+		if base = crs.geographic(); base == nil {
+			base = &CRS{Name: crs.Datum, Datum: crs.Datum, El: crs.El}
+			if base.Name == "" {
+				base.Name = crs.El.CommonName
+			}
+		}
+	}
+
+	datum := base.Datum
+	if datum == "" {
+		datum = base.Name
+	}
+	geogcs := fmt.Sprintf(`GEOGCS["%s",DATUM["%s",%s],PRIMEM["Greenwich",0],UNIT["degree",0.0174532925199433]%s]`,
+		base.Name, datum, base.El.WKT(), wkt1Authority(base.EPSG))
+	if !crs.Projected() {
+		return geogcs
+	}
+
+	return fmt.Sprintf(`PROJCS["%s",%s,PROJECTION["Transverse_Mercator"],PARAMETER["latitude_of_origin",%s],`+
+		`PARAMETER["central_meridian",%s],PARAMETER["scale_factor",%s],PARAMETER["false_easting",%s],`+
+		`PARAMETER["false_northing",%s],UNIT["metre",1]%s]`,
+		crs.Name, geogcs, wkt1Number(crs.LatO), wkt1Number(crs.LongO), wkt1Number(crs.Scale),
+		wkt1Number(crs.FalseEasting), wkt1Number(crs.FalseNorthing), wkt1Authority(crs.EPSG))
+}
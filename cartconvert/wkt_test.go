@@ -0,0 +1,182 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// Automated tests for the WKT2 parsing of the cartconvert package
+package cartconvert
+
+import (
+	"testing"
+)
+
+const wktETRS89UTM32 = `PROJCRS["ETRS89 / UTM zone 32N",
+    BASEGEOGCRS["ETRS89",
+        DATUM["European Terrestrial Reference System 1989",
+            ELLIPSOID["GRS 1980",6378137,298.257222101,
+                LENGTHUNIT["metre",1]]],
+        PRIMEM["Greenwich",0,
+            ANGLEUNIT["degree",0.0174532925199433]],
+        ID["EPSG",4258]],
+    CONVERSION["UTM zone 32N",
+        METHOD["Transverse Mercator",
+            ID["EPSG",9807]],
+        PARAMETER["Latitude of natural origin",0,
+            ANGLEUNIT["degree",0.0174532925199433],
+            ID["EPSG",8801]],
+        PARAMETER["Longitude of natural origin",9,
+            ANGLEUNIT["degree",0.0174532925199433],
+            ID["EPSG",8802]],
+        PARAMETER["Scale factor at natural origin",0.9996,
+            SCALEUNIT["unity",1],
+            ID["EPSG",8805]],
+        PARAMETER["False easting",500000,
+            LENGTHUNIT["metre",1],
+            ID["EPSG",8806]],
+        PARAMETER["False northing",0,
+            LENGTHUNIT["metre",1],
+            ID["EPSG",8807]]],
+    CS[Cartesian,2],
+        AXIS["(E)",east,
+            ORDER[1],
+            LENGTHUNIT["metre",1]],
+        AXIS["(N)",north,
+            ORDER[2],
+            LENGTHUNIT["metre",1]],
+    ID["EPSG",25832]]`
+
+// British National Grid, parameters identified by name only, angles in grads
+const wktBritishNationalGrid = `PROJCRS["OSGB36 / British National Grid",
+    BASEGEOGCRS["OSGB36",DATUM["Ordnance Survey of Great Britain 1936",ELLIPSOID["Airy 1830",6377563.396,299.3249646]]],
+    CONVERSION["British National Grid",METHOD["Transverse Mercator"],
+        PARAMETER["Latitude of natural origin",54,ANGLEUNIT["grad",0.015707963267949]],
+        PARAMETER["Longitude of natural origin",-2,ANGLEUNIT["degree",0.0174532925199433]],
+        PARAMETER["Scale factor at natural origin",0.9996012717],
+        PARAMETER["False easting",400],
+        PARAMETER["False northing",-100,LENGTHUNIT["kilometre",1000]],
+        LENGTHUNIT["metre",1]],
+    CS[Cartesian,2],AXIS["easting (E)",east],AXIS["northing (N)",north]]`
+
+const wktWGS84 = `GEOGCRS["WGS 84",
+    DATUM["World Geodetic System 1984",
+        ELLIPSOID["WGS 84",6378137,298.257223563,LENGTHUNIT["metre",1]]],
+    CS[ellipsoidal,2],
+        AXIS["geodetic latitude (Lat)",north],
+        AXIS["geodetic longitude (Lon)",east],
+        ANGLEUNIT["degree",0.0174532925199433],
+    ID["EPSG",4326]]`
+
+type parseWKT2CRSTest struct {
+	definition                                      string
+	name                                            string
+	el                                              *Ellipsoid
+	latO, longO, scale, falseEasting, falseNorthing float64
+}
+
+var parseWKT2CRSTests = []parseWKT2CRSTest{
+	{wktETRS89UTM32, "ETRS89 / UTM zone 32N", GRS80Ellipsoid, 0, 9, 0.9996, 500000, 0},
+	{wktBritishNationalGrid, "OSGB36 / British National Grid", Airy1830Ellipsoid, 48.6, -2, 0.9996012717, 400, -100000},
+	{wktWGS84, "WGS 84", WGS84Ellipsoid, 0, 0, 0, 0, 0},
+}
+
+func TestParseWKT2CRS(t *testing.T) {
+	for index, test := range parseWKT2CRSTests {
+		crs, err := ParseWKT2CRS(test.definition)
+		if err != nil {
+			t.Errorf("ParseWKT2CRS [%d]: %s", index, err)
+			continue
+		}
+
+		if crs.Name != test.name || crs.El != test.el || !floatequal(crs.LatO, test.latO) || !floatequal(crs.LongO, test.longO) ||
+			!floatequal(crs.Scale, test.scale) || !floatequal(crs.FalseEasting, test.falseEasting) || !floatequal(crs.FalseNorthing, test.falseNorthing) {
+			t.Errorf("ParseWKT2CRS [%d]: expected %v, got %v", index, test, crs)
+		}
+	}
+}
+
+func TestCRSProject(t *testing.T) {
+	crs, err := ParseWKT2CRS(wktETRS89UTM32)
+	if err != nil {
+		t.Fatalf("ParseWKT2CRS: %s", err)
+	}
+
+	gc := &PolarCoord{Latitude: 55.676, Longitude: 12.568, El: GRS80Ellipsoid}
+	expected := latLongToUTMZone(gc, 32)
+
+	gp, err := crs.Project(gc)
+	if err != nil || !utmabrequal(expected, &UTMCoord{Zone: expected.Zone, Easting: gp.X, Northing: gp.Y}) {
+		t.Errorf("CRS.Project: expected %s, got %v (%v)", expected, gp, err)
+	}
+
+	back, err := crs.InverseProject(gp)
+	if err != nil || !latlongequal(gc, back) {
+		t.Errorf("CRS.InverseProject: expected %s, got %s (%v)", gc, back, err)
+	}
+
+	geographic, _ := ParseWKT2CRS(wktWGS84)
+	if _, err := geographic.Project(gc); err != ErrUnsupported {
+		t.Errorf("CRS.Project: expected ErrUnsupported for a geographic CRS, got %v", err)
+	}
+}
+
+type parseWKT2CRSErrorTest struct {
+	definition string
+	err        error
+}
+
+var parseWKT2CRSErrorTests = []parseWKT2CRSErrorTest{
+	{"", ErrSyntax},
+	{`GEOGCRS["WGS 84"`, ErrSyntax},
+	{`GEOGCRS["WGS 84]`, ErrSyntax},
+	{`GEOGCRS["WGS 84",DATUM["WGS 84",ELLIPSOID["WGS 84",6378137,298.257223563]]]]`, ErrSyntax},
+	{`GEOGCRS["WGS 84",DATUM["WGS 84",ELLIPSOID["WGS 84",equator,298.257223563]]]`, ErrSyntax},
+	{`GEOGCRS["WGS 84",DATUM["WGS 84"]]`, ErrSyntax},
+	{`PROJCRS["ETRS89 / no conversion",BASEGEOGCRS["ETRS89",DATUM["ETRS89",ELLIPSOID["GRS 1980",6378137,298.257222101]]]]`, ErrSyntax},
+	{`VERTCRS["EGM2008 height",VDATUM["EGM2008 geoid"]]`, ErrUnsupported},
+	{`PROJCRS["MGI / Austria Lambert",BASEGEOGCRS["MGI",DATUM["Militaer-Geographische Institut",ELLIPSOID["Bessel 1841",6377397.155,299.1528128]]],
+	  CONVERSION["Austria Lambert",METHOD["Lambert Conic Conformal (2SP)",ID["EPSG",9802]]]]`, ErrUnsupported},
+}
+
+func TestParseWKT2CRSErrors(t *testing.T) {
+	for index, test := range parseWKT2CRSErrorTests {
+		_, err := ParseWKT2CRS(test.definition)
+		if we, ok := err.(WKTError); !ok || we.Err != test.err {
+			t.Errorf("ParseWKT2CRS [%d]: expected %v, got %v", index, test.err, err)
+		}
+	}
+}
+
+type wktTest struct {
+	epsg int
+	wkt  string
+}
+
+var wktTests = []wktTest{
+	{4326, `GEOGCS["WGS 84",DATUM["WGS84",SPHEROID["WGS84",6378137,298.25722363,AUTHORITY["EPSG","7030"]]],` +
+		`PRIMEM["Greenwich",0],UNIT["degree",0.0174532925199433],AUTHORITY["EPSG","4326"]]`},
+	{25832, `PROJCS["ETRS89 / UTM zone 32N",GEOGCS["ETRS89",DATUM["ETRS89",SPHEROID["GRS80",6378137,298.257222096,AUTHORITY["EPSG","7019"]]],` +
+		`PRIMEM["Greenwich",0],UNIT["degree",0.0174532925199433],AUTHORITY["EPSG","4258"]],PROJECTION["Transverse_Mercator"],` +
+		`PARAMETER["latitude_of_origin",0],PARAMETER["central_meridian",9],PARAMETER["scale_factor",0.9996],` +
+		`PARAMETER["false_easting",500000],PARAMETER["false_northing",0],UNIT["metre",1],AUTHORITY["EPSG","25832"]]`},
+}
+
+func TestCRSWKT(t *testing.T) {
+	for index, test := range wktTests {
+		crs, err := ByEPSG(test.epsg)
+		if err != nil {
+			t.Fatalf("ByEPSG [%d]: %s", index, err)
+		}
+		if out := crs.WKT(); out != test.wkt {
+			t.Errorf("CRS.WKT [%d]: expected %s, got %s", index, test.wkt, out)
+		}
+	}
+
+	// a system without a registered geographic system is based on its ellipsoid
+	crs := &CRS{Name: "Sphere / TM", El: NewEllipsoid(6371000, 6371000, "Sphere"), Method: MethodTransverseMercator, Scale: 1}
+	expected := `PROJCS["Sphere / TM",GEOGCS["Sphere",DATUM["Sphere",SPHEROID["Sphere",6371000,0]],PRIMEM["Greenwich",0],` +
+		`UNIT["degree",0.0174532925199433]],PROJECTION["Transverse_Mercator"],PARAMETER["latitude_of_origin",0],` +
+		`PARAMETER["central_meridian",0],PARAMETER["scale_factor",1],PARAMETER["false_easting",0],` +
+		`PARAMETER["false_northing",0],UNIT["metre",1]]`
+	if out := crs.WKT(); out != expected {
+		t.Errorf("CRS.WKT: expected %s, got %s", expected, out)
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// RESTFul interface for coordinate transformations - authentication of clients by API key
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// The request header and the URL parameter carrying the API key of a client
+const (
+	APIKeyHeader = "X-API-Key"
+	APIKeySpec   = "apikey"
+)
+
+// validAPIKey reports whether key is one of keys. The keys are compared in constant time, so the time taken doesn't
+// reveal how much of a key was guessed right.
+func validAPIKey(key string, keys []string) bool {
+	valid := false
+	for _, k := range keys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(k)) == 1 {
+			valid = true
+		}
+	}
+	return key != "" && valid
+}
+
+// apiKeyHandler passes a request to the API on to h, if it carries one of the configured API keys in the header
+// X-API-Key or the parameter apikey, which is removed from the request. Otherwise the request is responded with
+// http status 401. Requests outside of the API, like the documentation, are passed on regardless. Without
+// configured keys, every request is passed on.
+func apiKeyHandler(h http.Handler) http.Handler {
+	keys := conf_apikeys()
+	if len(keys) == 0 {
+		return h
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !strings.HasPrefix(req.URL.Path, apirootLink+"/") {
+			h.ServeHTTP(w, req)
+			return
+		}
+
+		key := req.Header.Get(APIKeyHeader)
+		if query := req.URL.Query(); query.Get(APIKeySpec) != "" {
+			if key == "" {
+				key = query.Get(APIKeySpec)
+			}
+			// the key is not a parameter of the conversion, which would echo it in the response
+			query.Del(APIKeySpec)
+			req.URL.RawQuery = query.Encode()
+		}
+		if !validAPIKey(key, keys) {
+			http.Error(w, "Missing or invalid API key", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, req)
+	})
+}
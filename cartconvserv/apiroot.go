@@ -0,0 +1,57 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// RESTFul interface for coordinate transformations - normalization of the roots of the API and the documentation
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// The paths served by the server itself, which the API and the documentation must not be rooted at
+var reservedRoots = []string{"/static", "/metrics", "/healthz", "/version"}
+
+// normalizeRoot returns root as path with a leading and without a trailing slash, with repeated slashes and dot
+// segments removed, like "/api" for "api/", "/api/" or "//api". Returns "/" for an empty root.
+func normalizeRoot(root string) string {
+	return path.Clean("/" + strings.TrimSpace(root))
+}
+
+// validateRoots returns an error if the normalized roots of the API and the documentation can not be served: if
+// either is the root of the server, they share a path, or either is a path served by the server itself.
+func validateRoots(apiroot, docroot string) error {
+	switch {
+	case apiroot == "/":
+		return fmt.Errorf("APIRoot must not be the root of the server")
+	case docroot == "/":
+		return fmt.Errorf("DocRoot must not be the root of the server")
+	case apiroot == docroot:
+		return fmt.Errorf("APIRoot and DocRoot are both '%s'", apiroot)
+	}
+	for _, reserved := range reservedRoots {
+		if apiroot == reserved || docroot == reserved {
+			return fmt.Errorf("'%s' is served by the server and can not be the root of the API or documentation", reserved)
+		}
+	}
+	return nil
+}
+
+// apiPathHandler removes repeated slashes and dot segments from the path of requests to the API, keeping a trailing
+// slash, so they are routed to their method instead of being redirected, which would turn a POST into a GET. Every
+// other request is passed on unchanged to h.
+func apiPathHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		cleaned := path.Clean(req.URL.Path)
+		if cleaned != req.URL.Path && (cleaned == apirootLink || strings.HasPrefix(cleaned, apirootLink+"/")) {
+			if strings.HasSuffix(req.URL.Path, "/") && cleaned != "/" {
+				cleaned += "/"
+			}
+			req.URL.Path, req.URL.RawPath = cleaned, ""
+		}
+		h.ServeHTTP(w, req)
+	})
+}
@@ -0,0 +1,220 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// RESTFul interface for coordinate transformations - batch conversion
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/the42/cartconvert/cartconvert"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	BatchMethod  = "/batch"
+	GridCellSpec = "gridcell" // edge length in meters of the UTM grid cells by which the results of a batch are grouped
+)
+
+// --------------------------------------------------------------------
+// Serialization struct definitions
+type (
+	// A single conversion of a batch, given as the restful method, eg. "utm", its input value and the output format.
+	// Parameters are passed to the method like URL parameters, eg. "lat" and "long" for the method "latlong".
+	// Name and Description are passed through to the waypoint of the conversion, if the batch is serialized as GPX.
+	BatchItem struct {
+		Method       string
+		Value        string
+		OutputFormat string
+		Parameters   map[string]string
+		Name         string
+		Description  string
+	}
+
+	// The result of a single conversion of a batch. Index is the position of the conversion within the batch.
+	// A failed conversion carries the reason in Error and the http status it would be responded with as a
+	// single request in Code. It does not fail the batch.
+	BatchResult struct {
+		Index        int
+		Method       string
+		Value        string
+		Payload      interface{} `json:",omitempty" xml:",omitempty"`
+		Provenance   *Provenance `json:",omitempty" xml:",omitempty"`
+		GridAddress  string      `json:",omitempty" xml:",omitempty"`
+		PlaceName    string      `json:",omitempty" xml:",omitempty"`
+		Warning      string      `json:",omitempty" xml:",omitempty"`
+		Altitude     *float64    `json:",omitempty" xml:",omitempty"` // height above sea level of the result, if given by the input
+		Accuracy     float64     `json:",omitempty" xml:",omitempty"` // approximate positional error in meters of the datum shifts
+		ParameterSet []string    `json:",omitempty" xml:",omitempty"` // names of the helmert parameter sets of the datum shifts
+		AxisOrder    string      `json:",omitempty" xml:",omitempty"` // order of latitude and longitude in the output, if requested
+		Error        string      `json:",omitempty" xml:",omitempty"`
+		Code         int         `json:",omitempty" xml:",omitempty"`
+
+		location    *cartconvert.PolarCoord // WGS84 location of the result
+		name        string                  // name and description of the conversion, given by the batch
+		description string
+	}
+
+	// The results of a batch whose WGS84 location lies within the same UTM grid cell, as identified by
+	// cartconvert.UTMGridCell. Index are the positions of the results within the batch.
+	GridCell struct {
+		Cell  string
+		Index []int
+	}
+
+	// The results of a batch. GridCells groups the results by UTM grid cell, if requested by GridCellSpec.
+	Batch struct {
+		BatchResult []BatchResult
+		GridCells   []GridCell `json:",omitempty" xml:",omitempty"`
+	}
+)
+
+// dispatch resolves the restful method of a conversion of a batch. It is set at init, as referring
+// to httphandlerfuncs from batchHandler would be an initialization cycle.
+var dispatch func(method string) (httphandlerfunc, bool)
+
+func init() {
+	dispatch = func(method string) (httphandlerfunc, bool) {
+		fn, ok := httphandlerfuncs[method]
+		return fn, ok
+	}
+}
+
+// readBatchBody reads the body of a POST request, which carries the conversions of a batch. The body is limited to
+// the configured MaxBodySize, so an oversized request is rejected with a *http.MaxBytesError before it gets parsed.
+func readBatchBody(w http.ResponseWriter, req *http.Request) ([]byte, error) {
+	defer req.Body.Close()
+	return ioutil.ReadAll(http.MaxBytesReader(w, req.Body, conf_maxbodysize()))
+}
+
+// batchHandler performs the conversions of a batch, a JSON array of BatchItem sent as body of a POST request,
+// and returns their results in the order of the batch. The parameters of the request, eg. helmert or provenance,
+// apply to every conversion, unless the conversion sets the parameter itself.
+func batchHandler(req *GEOConvertRequest, value, oformat string) (interface{}, error) {
+
+	if req.body == nil {
+		return nil, newRequestError(http.StatusMethodNotAllowed, "", "Batch conversion requires a POST request with a JSON array of conversions")
+	}
+
+	var items []BatchItem
+	if err := json.Unmarshal(req.body, &items); err != nil {
+		return nil, badRequest("", "Unable to parse batch: %s", err)
+	}
+	if max := conf_maxbatchsize(); len(items) > max {
+		return nil, newRequestError(http.StatusRequestEntityTooLarge, "", "Batch of %d conversions exceeds the maximum batch size of %d", len(items), max)
+	}
+
+	var resolution float64
+	if sresolution := getfirstValueFromURLParameters(req.Parameters, GridCellSpec); len(sresolution) > 0 {
+		var err error
+		if resolution, err = strconv.ParseFloat(sresolution, 64); err != nil || !(resolution > 0) {
+			return nil, badRequest(sresolution, "Not a grid cell size in meters: '%s'", sresolution)
+		}
+	}
+
+	// the conversions are performed concurrently and stop, once the request is canceled or its deadline has passed;
+	// the response has been given up on then, so the results processed so far are discarded
+	batch := &Batch{BatchResult: make([]BatchResult, len(items))}
+	if _, err := cartconvert.ProcessBatch(req.ctx, len(items), func(index int) {
+		batch.BatchResult[index] = convertBatchItem(req, index, &items[index], oformat)
+	}); err != nil {
+		return nil, err
+	}
+
+	if resolution > 0 {
+		batch.GridCells = groupByGridCell(batch.BatchResult, resolution)
+	}
+	return batch, nil
+}
+
+// groupByGridCell groups the results of a batch by the UTM grid cell of resolution meters edge length their WGS84
+// location lies within, ordered by the identifier of the cell. Failed conversions and results which can not be
+// represented in UTM, like those in the polar regions, are left out.
+func groupByGridCell(results []BatchResult, resolution float64) []GridCell {
+	coords := make([]*cartconvert.UTMCoord, len(results))
+	index := make(map[*cartconvert.UTMCoord]int, len(results))
+	for i := range results {
+		if results[i].location == nil {
+			continue
+		}
+		if utm, err := cartconvert.LatLongToUTMChecked(results[i].location); err == nil && !utm.IsUPS() {
+			coords[i], index[utm] = utm, i
+		}
+	}
+
+	cells, err := cartconvert.GroupUTMByGridCell(coords, resolution)
+	if err != nil {
+		return nil
+	}
+
+	gridcells := make([]GridCell, 0, len(cells))
+	for cell, group := range cells {
+		gridcell := GridCell{Cell: cell, Index: make([]int, 0, len(group))}
+		for _, utm := range group {
+			gridcell.Index = append(gridcell.Index, index[utm])
+		}
+		gridcells = append(gridcells, gridcell)
+	}
+	sort.Slice(gridcells, func(i, j int) bool { return gridcells[i].Cell < gridcells[j].Cell })
+	return gridcells
+}
+
+// convertBatchItem performs a single conversion of a batch by the handler of its restful method
+func convertBatchItem(batchrequest *GEOConvertRequest, index int, item *BatchItem, oformat string) (result BatchResult) {
+
+	result = BatchResult{Index: index, Method: item.Method, Value: item.Value, name: item.Name, description: item.Description}
+
+	// a panic of the handler, eg. on malformed input, fails the single conversion only
+	defer func() {
+		if err := recover(); err != nil {
+			result = BatchResult{Index: index, Method: item.Method, Value: item.Value, Error: fmt.Sprint(err), Code: http.StatusInternalServerError}
+		}
+	}()
+
+	method := "/" + strings.Trim(item.Method, "/")
+	fn, ok := dispatch(method)
+	if !ok || method == BatchMethod {
+		result.Error = fmt.Sprintf("Unsupported method: '%s'", item.Method)
+		result.Code = http.StatusNotFound
+		return result
+	}
+
+	// the parameters of the conversion precede the parameters of the batch request
+	request := &GEOConvertRequest{Method: method, Value: item.Value, requestID: batchrequest.requestID, ctx: batchrequest.ctx}
+	for key, value := range item.Parameters {
+		request.Parameters = append(request.Parameters, URLParameter{Key: key, Values: []string{value}})
+	}
+	request.Parameters = append(request.Parameters, batchrequest.Parameters...)
+
+	if getfirstValueFromURLParameters(request.Parameters, ProvenanceSpec) == "true" {
+		request.provenance = &Provenance{Source: strings.Trim(method, "/")}
+	}
+	if len(item.OutputFormat) > 0 {
+		oformat = item.OutputFormat
+	}
+
+	payload, err := convertCached(fn.restHandler, request, item.Value, oformat)
+	if err != nil {
+		re := asRequestError(err)
+		result.Error, result.Code = re.Message, re.Code
+		return result
+	}
+
+	result.Payload = payload
+	result.Provenance = request.provenance
+	result.Warning = request.warning
+	if request.location != nil {
+		result.GridAddress = gridAddress(request)
+		result.PlaceName = placeName(request)
+		result.location, result.Altitude = request.location, request.altitude
+		result.Accuracy = request.accuracy
+		result.ParameterSet = request.sets
+		result.AxisOrder = request.axisorder
+	}
+	return result
+}
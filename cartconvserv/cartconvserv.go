@@ -7,19 +7,23 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"github.com/the42/cartconvert/cartconvert"
 	"github.com/the42/cartconvert/cartconvert/bmn"
 	"github.com/the42/cartconvert/cartconvert/osgb36"
 	"html/template"
-	"log"
+	"io/ioutil"
+	"math"
 	"net/http"
 	"net/url"
 	"path"
 	"runtime/debug"
 	"strconv"
+	"strings"
 )
 
 // supported serialization formats
@@ -28,21 +32,39 @@ const (
 	XMLFormatSpec  = ".xml"
 )
 
+// XML may also be requested by the parameter FormatSpec=xml or by the Accept header XMLMediaType
+const XMLMediaType = "application/xml"
+
 const apitemplateroot = templateroot + "api/"
 const apiTemplate = "index.tpl"
 
 // supported representation/transformation formats
 const (
 	OutputFormatSpec = "outputformat"
+	HelmertSpec      = "helmert"         // name of the helmert parameter set to use for datum shifts
+	ProvenanceSpec   = "provenance"      // if "true", the response carries the provenance of the result
+	SwapSpec         = "autocorrectswap" // if "true", latitude and longitude given in the wrong order get swapped
+	LatLongSpec      = "latlong"         // latitude and longitude in one parameter, in the common notations
+	LongFirstSpec    = "longfirst"       // if "true", the longitude is given first in the parameter latlong
+	AnchorSpec       = "anchor"          // "center" or "southwest", the location within the square of a grid reference
+	AltitudeSpec     = "altitude"        // height above sea level in meters of the input, carried to the output
+	AxisOrderSpec    = "axisorder"       // "lat,long" or "long,lat", the order of latitude and longitude in the output
+	CRSSpec          = "crs"             // identifier of the geographic coordinate reference system of the parameter coords
+	EllipsoidSpec    = "ellipsoid"       // name of the reference ellipsoid of latitude and longitude, like GRS80
 
 	OFlatlongdeg   = "latlongdeg"
 	OFlatlongcomma = "latlongcomma"
 	OFgeohash      = "geohash"
 	OFUTM          = "utm"
+	OFUPS          = "ups" // UTM, or UPS in the polar regions
 	OFBMN          = "bmn"
 	OFOSGB         = "osgb"
 )
 
+// Version of the wire format of the responses, raised with every incompatible change of the names or the shape of
+// their fields. Version 2 names the fields of the coordinates in lower case and the ellipsoid by its common name.
+const APIVersion = 2
+
 // Interface type for transparent XML / JSON Encoding
 type Encoder interface {
 	Encode(v interface{}) error
@@ -60,14 +82,49 @@ type (
 		Method     string
 		Value      string
 		Parameters []URLParameter
+		provenance *Provenance             // nil, unless the provenance was requested
+		location   *cartconvert.PolarCoord // WGS84 location of the result, set by serialize
+		warning    string                  // a warning about the input, which did not prevent the conversion
+		altitude   *float64                // height of the location above sea level, nil unless given by the input
+		body       []byte                  // body of a POST request, nil for every other request
+		requestID  string                  // ID of the http request, see requestIDHandler
+		accuracy   float64                 // approximate positional error of the datum shifts, see addProvenanceStep
+		sets       []string                // names of the helmert parameter sets of the datum shifts, see addProvenanceStep
+		axisorder  string                  // axis order of the output, empty unless requested by AxisOrderSpec
+		ctx        context.Context         // done, when the deadline of the conversion has passed, see requestContext
 	}
 
 	GEOConvertResponse struct {
 		Status            string
 		Code              int
 		Error             bool
+		APIVersion        int                // version of the wire format of the response, see APIVersion
 		GEOConvertRequest *GEOConvertRequest // MIND: GEOConvertRequest is named, because XML and JSON serialization behave differently. An unnamed struct element will NOT be serialized by the XML encoder
 		Payload           interface{}
+		Provenance        *Provenance `json:",omitempty" xml:",omitempty"`
+		GridAddress       string      `json:",omitempty" xml:",omitempty"` // address of the result, if a grid address provider is configured
+		PlaceName         string      `json:",omitempty" xml:",omitempty"` // name of the place of the result, if a geocoder is configured
+		Warning           string      `json:",omitempty" xml:",omitempty"`
+		Altitude          *float64    `json:",omitempty" xml:",omitempty"` // height above sea level of the result, if given by the input
+		Accuracy          float64     `json:",omitempty" xml:",omitempty"` // approximate positional error in meters of the datum shifts of the conversion
+		ParameterSet      []string    `json:",omitempty" xml:",omitempty"` // names of the helmert parameter sets of the datum shifts of the conversion
+		AxisOrder         string      `json:",omitempty" xml:",omitempty"` // order of latitude and longitude in the output, if requested
+		Input             string      `json:",omitempty" xml:",omitempty"` // the part of the request which caused the error
+	}
+
+	// A single step of the derivation of a result. ParameterSet and Accuracy are set for datum shifts.
+	ProvenanceStep struct {
+		Operation    string
+		ParameterSet string  `json:",omitempty" xml:",omitempty"`
+		Accuracy     float64 `json:",omitempty" xml:",omitempty"`
+	}
+
+	// The derivation of a result from the source system through every step of the transformation
+	// to the target system
+	Provenance struct {
+		Source         string
+		ProvenanceStep []ProvenanceStep
+		Target         string
 	}
 
 	LatLong struct {
@@ -93,39 +150,188 @@ type (
 		OSGB36Coord  *osgb36.OSGB36Coord // MIND: OSGB36Coord is named, because XML and JSON serialization behave differently. An unnamed struct element will NOT be serialized by the XML encoder
 		OSGB36String string
 	}
+
+	HelmertParameterSet struct {
+		Name, From, To string
+		Accuracy       float64
+		Source         string
+		Default        bool
+		Parameters     string
+		Extent         *cartconvert.LatLongExtent `json:",omitempty" xml:",omitempty"` // area the parameters apply to, nil if they apply everywhere
+	}
+
+	HelmertParameterSets struct {
+		HelmertParameterSet []HelmertParameterSet
+	}
 )
 
+// helmertParameterSet returns the helmert parameter set requested by HelmertSpec, if it applies to the
+// datum shift between from and to. If no parameter set is requested or the requested parameter set
+// applies to another pair of datums, the parameter set between from and to best suited for location is
+// returned, see cartconvert.SelectHelmertParameterSet.
+func helmertParameterSet(request *GEOConvertRequest, from, to string, location *cartconvert.PolarCoord) (*cartconvert.HelmertParameterSet, error) {
+	if name := getfirstValueFromURLParameters(request.Parameters, HelmertSpec); len(name) > 0 {
+		set, err := cartconvert.HelmertParameterSetByName(name)
+		if err != nil {
+			return nil, notFound(name, "Unknown helmert parameter set: '%s'", name)
+		}
+
+		if set.From == from && set.To == to {
+			return set, nil
+		}
+	}
+	return cartconvert.SelectHelmertParameterSet(from, to, location)
+}
+
+// requestEllipsoid returns the reference ellipsoid requested by EllipsoidSpec, see cartconvert.EllipsoidByName.
+// If no ellipsoid is requested, the cartconvert.DefaultEllipsoid is returned.
+func requestEllipsoid(request *GEOConvertRequest) (*cartconvert.Ellipsoid, error) {
+	name := getfirstValueFromURLParameters(request.Parameters, EllipsoidSpec)
+	if len(name) == 0 {
+		return cartconvert.DefaultEllipsoid, nil
+	}
+
+	el, err := cartconvert.EllipsoidByName(name)
+	if err != nil {
+		return nil, notFound(name, "Unknown ellipsoid: '%s'", name)
+	}
+	return el, nil
+}
+
+// addProvenanceStep records a step of the transformation, if the provenance was requested.
+// set is the helmert parameter set of a datum shift and nil for every other operation. The accuracies of the
+// datum shifts of a conversion add up to the accuracy of the result as independent errors, by root sum square.
+func (request *GEOConvertRequest) addProvenanceStep(operation string, set *cartconvert.HelmertParameterSet) {
+	if set != nil {
+		request.accuracy = math.Hypot(request.accuracy, set.Accuracy)
+		request.sets = append(request.sets, set.Name)
+	}
+	if request.provenance == nil {
+		return
+	}
+
+	step := ProvenanceStep{Operation: operation}
+	if set != nil {
+		step.ParameterSet = set.Name
+		step.Accuracy = set.Accuracy
+	}
+	request.provenance.ProvenanceStep = append(request.provenance.ProvenanceStep, step)
+}
+
+// Output formats of the target systems given by EPSG code, like EPSG:27700. The systems of zones or meridian
+// stripes are not listed, as serialize determines the zone from the location.
+var epsgOutputFormats = map[int]string{
+	4326:        OFlatlongcomma,
+	osgb36.EPSG: OFOSGB,
+}
+
+// epsgOutputFormat returns the output format of oformat given as EPSG code of the target system
+func epsgOutputFormat(oformat string) (string, bool) {
+	if len(oformat) < 5 || !strings.EqualFold(oformat[:5], "EPSG:") {
+		return "", false
+	}
+	code, err := strconv.Atoi(oformat[5:])
+	if err != nil {
+		return "", false
+	}
+	format, ok := epsgOutputFormats[code]
+	return format, ok
+}
+
 // serialize gets called by the respective handler methods to perform the serialization in the requested output representation
-func serialize(latlong *cartconvert.PolarCoord, oformat string) (interface{}, error) {
+func serialize(request *GEOConvertRequest, latlong *cartconvert.PolarCoord, oformat string) (interface{}, error) {
 	var serializestruct interface{}
 	var err error
 
+	if format, ok := epsgOutputFormat(oformat); ok {
+		oformat = format
+	}
+
+	// the conversions reject non-finite input, this catches a conversion yielding NaN nevertheless
+	if err = latlong.CheckFinite(); err != nil {
+		return nil, badRequest(request.Value, "Not a finite coordinate: %s", err)
+	}
+
+	// a height given by the parameter applies, unless the input coordinate carries one
+	if value := getfirstValueFromURLParameters(request.Parameters, AltitudeSpec); value != "" && request.altitude == nil {
+		altitude, perr := strconv.ParseFloat(value, 64)
+		if perr != nil || cartconvert.CheckFinite(altitude) != nil {
+			return nil, badRequest(value, "Altitude is not a number: '%s'", value)
+		}
+		request.altitude = &altitude
+	}
+
+	// latitude and longitude are output in this order, where they are not named or ordered by a specification
+	order := cartconvert.AxisLatLong
+	if value := getfirstValueFromURLParameters(request.Parameters, AxisOrderSpec); value != "" {
+		if order, err = cartconvert.ParseAxisOrder(value); err != nil {
+			return nil, badRequest(value, "Unknown axis order '%s', expected lat,long or long,lat", value)
+		}
+		request.axisorder = order.String()
+	}
+
 	switch oformat {
 	case OFlatlongdeg:
+		request.addProvenanceStep("formatting as degrees, minutes and seconds", nil)
 		lat, long := cartconvert.LatLongToString(latlong, cartconvert.LLFdms)
-		serializestruct = &LatLong{Lat: lat, Long: long, Fmt: cartconvert.LLFdms.String(), LatLongString: latlong.String()}
+		serializestruct = &LatLong{Lat: lat, Long: long, Fmt: cartconvert.LLFdms.String(), LatLongString: latlong.StringOrder(order)}
 	case OFlatlongcomma:
+		request.addProvenanceStep("formatting as decimal degrees", nil)
 		lat, long := cartconvert.LatLongToString(latlong, cartconvert.LLFdeg)
-		serializestruct = &LatLong{Lat: lat, Long: long, Fmt: cartconvert.LLFdeg.String(), LatLongString: latlong.String()}
+		serializestruct = &LatLong{Lat: lat, Long: long, Fmt: cartconvert.LLFdeg.String(), LatLongString: latlong.StringOrder(order)}
 	case OFgeohash:
-		serializestruct = &GeoHash{GeoHash: cartconvert.LatLongToGeoHash(latlong)}
+		var geohash string
+		if geohash, err = cartconvert.LatLongToGeoHashChecked(latlong); err != nil {
+			break
+		}
+		request.addProvenanceStep("geohash encoding", nil)
+		serializestruct = &GeoHash{GeoHash: geohash}
 	case OFUTM:
-		utm := cartconvert.LatLongToUTM(latlong)
+		var utm *cartconvert.UTMCoord
+		if utm, err = cartconvert.LatLongToUTMChecked(latlong); err != nil {
+			break
+		}
+		request.addProvenanceStep("UTM projection, zone "+utm.Zone, nil)
+		serializestruct = &UTMCoord{UTMCoord: utm, UTMString: utm.String()}
+	case OFUPS:
+		utm := cartconvert.LatLongToUTMUPS(latlong)
+		request.addProvenanceStep(utmSystem(utm)+" projection, zone "+utm.Zone, nil)
 		serializestruct = &UTMCoord{UTMCoord: utm, UTMString: utm.String()}
 	case OFBMN:
+		var set *cartconvert.HelmertParameterSet
+		if set, err = helmertParameterSet(request, cartconvert.DatumWGS84, cartconvert.DatumMGI, latlong); err != nil {
+			break
+		}
+
 		var bmnval *bmn.BMNCoord
-		bmnval, err = bmn.WGS84LatLongToBMN(latlong, bmn.BMNZoneDet)
+		bmnval, err = bmn.WGS84LatLongToBMNHelmert(latlong, bmn.BMNZoneDet, set)
 		if err == nil {
+			request.addProvenanceStep("helmert transformation WGS84 to MGI", set)
+			request.addProvenanceStep("transverse mercator projection, meridian "+bmnval.Meridian.String(), nil)
 			serializestruct = &BMN{BMNCoord: bmnval, BMNString: bmnval.String()}
 		}
 	case OFOSGB:
+		var set *cartconvert.HelmertParameterSet
+		if set, err = helmertParameterSet(request, cartconvert.DatumWGS84, cartconvert.DatumOSGB36, latlong); err != nil {
+			break
+		}
+
 		var osgb36val *osgb36.OSGB36Coord
-		osgb36val, err = osgb36.WGS84LatLongToOSGB36(latlong)
+		osgb36val, err = osgb36.WGS84LatLongToOSGB36Helmert(latlong, set)
 		if err == nil {
+			request.addProvenanceStep("helmert transformation WGS84 to OSGB36", set)
+			request.addProvenanceStep("transverse mercator projection, National Grid", nil)
 			serializestruct = &OSGB36{OSGB36Coord: osgb36val, OSGB36String: osgb36val.String()}
 		}
 	default:
-		err = fmt.Errorf("Unsupported output format: '%s'", oformat)
+		err = notFound(oformat, "Unsupported output format: '%s'", oformat)
+	}
+
+	if err == nil {
+		request.location = latlong
+		if request.provenance != nil {
+			request.provenance.Target = oformat
+		}
 	}
 	return serializestruct, err
 }
@@ -145,79 +351,286 @@ func getfirstValueFromURLParameters(params []URLParameter, key string) (retval s
 func latlongHandler(request *GEOConvertRequest, latlongstrval, oformat string) (interface{}, error) {
 
 	if len(latlongstrval) > 0 {
-		return nil, fmt.Errorf("Latlong doesn't accept an input value. Use the parameters 'lat' and 'long' instead")
+		return nil, badRequest(latlongstrval, "Latlong doesn't accept an input value. Use the parameters 'lat' and 'long' instead")
 	}
 
-	slat := getfirstValueFromURLParameters(request.Parameters, "lat")
-	slong := getfirstValueFromURLParameters(request.Parameters, "long")
+	if sepsg := getfirstValueFromURLParameters(request.Parameters, "epsg"); len(sepsg) > 0 {
+		return crsLatLong(request, "EPSG:"+sepsg, oformat)
+	}
+	if scrs := getfirstValueFromURLParameters(request.Parameters, CRSSpec); len(scrs) > 0 {
+		return crsLatLong(request, scrs, oformat)
+	}
 
 	var lat, long float64
 	var err error
 
-	lat, err = cartconvert.ADegMMSSToNum(slat)
-	if err != nil {
-		lat, err = cartconvert.ADegCommaToNum(slat)
-		if err != nil {
-			return nil, fmt.Errorf("Not a bearing: '%s'", slat)
+	if slatlong := getfirstValueFromURLParameters(request.Parameters, LatLongSpec); len(slatlong) > 0 {
+		longfirst := getfirstValueFromURLParameters(request.Parameters, LongFirstSpec) == "true"
+
+		var latlong *cartconvert.PolarCoord
+		if latlong, err = cartconvert.ADegMinSecToPolar(slatlong, longfirst); err != nil {
+			return nil, badRequest(slatlong, "Not a latitude / longitude: %s", err)
+		}
+		lat, long = latlong.Latitude, latlong.Longitude
+	} else {
+		slat := getfirstValueFromURLParameters(request.Parameters, "lat")
+		slong := getfirstValueFromURLParameters(request.Parameters, "long")
+
+		if lat, err = parseBearing(slat); err != nil {
+			return nil, badRequest(slat, "Not a bearing: '%s'", slat)
+		}
+		if long, err = parseBearing(slong); err != nil {
+			return nil, badRequest(slong, "Not a bearing: '%s'", slong)
 		}
 	}
 
-	long, err = cartconvert.ADegMMSSToNum(slong)
+	if getfirstValueFromURLParameters(request.Parameters, SwapSpec) == "true" {
+		lat, long = correctSwap(request, lat, long, oformat)
+	}
+
+	el, err := requestEllipsoid(request)
 	if err != nil {
-		long, err = cartconvert.ADegCommaToNum(slong)
+		return nil, err
+	}
+
+	latlong := &cartconvert.PolarCoord{Latitude: lat, Longitude: long, El: el}
+	return serialize(request, latlong, oformat)
+}
+
+// parseBearing parses a bearing as Deg°MM'SS'', as decimal degrees or in one of the notations of ADegMinSecToNum
+func parseBearing(bearing string) (float64, error) {
+	if value, err := cartconvert.ADegMMSSToNum(bearing); err == nil {
+		return value, nil
+	}
+	if value, err := cartconvert.ADegCommaToNum(bearing); err == nil {
+		return value, nil
+	}
+	return cartconvert.ADegMinSecToNum(bearing)
+}
+
+// extents of the coordinate systems of the output formats, if they are not defined globally
+var outputextents = map[string]*cartconvert.LatLongExtent{
+	OFUTM:  cartconvert.UTMExtent,
+	OFBMN:  bmn.Extent,
+	OFOSGB: osgb36.Extent,
+}
+
+// correctSwap swaps latitude and longitude, if only the swapped coordinate lies within the extent of the
+// output format. If both orderings are plausible, the coordinate is left unchanged and a warning is set.
+func correctSwap(request *GEOConvertRequest, lat, long float64, oformat string) (float64, float64) {
+	extent, ok := outputextents[oformat]
+	if !ok {
+		extent = cartconvert.WorldExtent
+	}
+
+	clat, clong, swapped, err := cartconvert.AutoCorrectSwap(lat, long, extent)
+	switch {
+	case swapped:
+		request.warning = "Latitude and longitude were given in the wrong order and got swapped"
+		request.addProvenanceStep("swap of latitude and longitude", nil)
+	case err == cartconvert.ErrAmbiguous:
+		request.warning = "Latitude and longitude might be given in the wrong order, both orderings are valid"
+	}
+	return clat, clong
+}
+
+// crsLatLong parses the parameter 'coords', two decimal values separated by blanks, in the axis order of the
+// geographic coordinate reference system of WGS84 denoted by crs, like EPSG:4326 or OGC:CRS84
+func crsLatLong(request *GEOConvertRequest, crs, oformat string) (interface{}, error) {
+	var err error
+
+	scoords := getfirstValueFromURLParameters(request.Parameters, "coords")
+	fields := strings.Fields(scoords)
+	if len(fields) != 2 {
+		return nil, badRequest(scoords, "Expected two values separated by blanks as parameter 'coords', got: '%s'", scoords)
+	}
+
+	var values [2]float64
+	for i, field := range fields {
+		values[i], err = strconv.ParseFloat(field, 64)
 		if err != nil {
-			return nil, fmt.Errorf("Not a bearing: '%s'", slong)
+			return nil, badRequest(field, "Not a decimal value: '%s'", field)
 		}
 	}
 
+	lat, long, err := cartconvert.NormalizeAxisOrder(values, crs)
+	if err != nil {
+		return nil, badRequest(crs, "Unsupported coordinate reference system: '%s', only the geographic systems of WGS84 are supported", crs)
+	}
+	request.addProvenanceStep(fmt.Sprintf("axis order of %s", crs), nil)
+
 	latlong := &cartconvert.PolarCoord{Latitude: lat, Longitude: long, El: cartconvert.DefaultEllipsoid}
-	return serialize(latlong, oformat)
+	return serialize(request, latlong, oformat)
 }
 
 func geohashHandler(request *GEOConvertRequest, geohashstrval, oformat string) (interface{}, error) {
 	var latlong *cartconvert.PolarCoord
 	var err error
 	if latlong, err = cartconvert.GeoHashToLatLong(geohashstrval, nil); err != nil {
-		return nil, err
+		return nil, badRequest(geohashstrval, "Not a geohash: %s", err)
 	}
-	return serialize(latlong, oformat)
+	request.addProvenanceStep("geohash decoding", nil)
+	return serialize(request, latlong, oformat)
 }
 
+// utmSystem returns the name of the system of utm, UTM or UPS
+func utmSystem(utm *cartconvert.UTMCoord) string {
+	if utm.IsUPS() {
+		return "UPS"
+	}
+	return "UTM"
+}
+
+// utmHandler converts coordinates of UTM and, by the zones A, B, Y and Z, of UPS in the polar regions
 func utmHandler(req *GEOConvertRequest, utmstrval, oformat string) (interface{}, error) {
 	var utmval *cartconvert.UTMCoord
 	var err error
 	if utmval, err = cartconvert.AUTMToStruct(utmstrval, nil); err != nil {
-		return nil, err
+		return nil, badRequest(utmstrval, "Not a UTM or UPS coordinate: %s", err)
+	}
+	system := utmSystem(utmval)
+	if err = utmval.Valid(); err != nil {
+		return nil, badRequest(utmstrval, "Invalid %s coordinate: %s", system, err)
 	}
 
 	var latlong *cartconvert.PolarCoord
 	if latlong, err = cartconvert.UTMToLatLong(utmval); err != nil {
-		return nil, err
+		return nil, badRequest(utmstrval, "Unable to convert %s coordinate: %s", system, err)
 	}
-	return serialize(latlong, oformat)
+	req.addProvenanceStep("inverse "+system+" projection, zone "+utmval.Zone, nil)
+	return serialize(req, latlong, oformat)
 }
 
 func bmnHandler(req *GEOConvertRequest, bmnstrval, oformat string) (interface{}, error) {
 	var bmnval *bmn.BMNCoord
 	var err error
 	if bmnval, err = bmn.ABMNToStruct(bmnstrval); err != nil {
+		return nil, badRequest(bmnstrval, "Not a BMN coordinate: %s", err)
+	}
+	if err = bmnval.Valid(); err != nil {
+		return nil, badRequest(bmnstrval, "Invalid BMN coordinate: %s", err)
+	}
+
+	// the parameter set is selected by the location of the coordinate as shifted by the default parameter set,
+	// which is accurate enough to tell the extents of parameter sets apart
+	location, err := bmn.BMNToWGS84LatLong(bmnval)
+	if err != nil {
+		return nil, badRequest(bmnstrval, "Unable to convert BMN coordinate: %s", err)
+	}
+	var set *cartconvert.HelmertParameterSet
+	if set, err = helmertParameterSet(req, cartconvert.DatumWGS84, cartconvert.DatumMGI, location); err != nil {
 		return nil, err
 	}
 
 	var latlong *cartconvert.PolarCoord
-	if latlong, err = bmn.BMNToWGS84LatLong(bmnval); err != nil {
-		return nil, err
+	if latlong, err = bmn.BMNToWGS84LatLongHelmert(bmnval, set); err != nil {
+		return nil, badRequest(bmnstrval, "Unable to convert BMN coordinate: %s", err)
 	}
-	return serialize(latlong, oformat)
+	req.addProvenanceStep("inverse transverse mercator projection, meridian "+bmnval.Meridian.String(), nil)
+	req.addProvenanceStep("inverse helmert transformation MGI to WGS84", set)
+	if bmnval.RelHeight != 0 {
+		req.altitude = &bmnval.RelHeight
+	}
+	return serialize(req, latlong, oformat)
 }
 
 func osgbHandler(req *GEOConvertRequest, osgb36strval, oformat string) (interface{}, error) {
 	var osgb36val *osgb36.OSGB36Coord
 	var err error
 	if osgb36val, err = osgb36.AOSGB36ToStruct(osgb36strval, osgb36.OSGB36Leave); err != nil {
+		return nil, badRequest(osgb36strval, "Not an OSGB36 grid reference: %s", err)
+	}
+	if err = osgb36val.Valid(); err != nil {
+		return nil, badRequest(osgb36strval, "Invalid OSGB36 grid reference: %s", err)
+	}
+
+	// the parameter set is selected by the location of the grid reference as shifted by the default parameter set
+	var approx *cartconvert.PolarCoord
+	if approx, err = osgb36.OSGB36ToWGS84LatLongChecked(osgb36val); err != nil {
+		return nil, badRequest(osgb36strval, "Unable to convert OSGB36 grid reference: %s", err)
+	}
+	var set *cartconvert.HelmertParameterSet
+	if set, err = helmertParameterSet(req, cartconvert.DatumWGS84, cartconvert.DatumOSGB36, approx); err != nil {
 		return nil, err
 	}
-	return serialize(osgb36.OSGB36ToWGS84LatLong(osgb36val), oformat)
+
+	anchor := osgb36.OSGB36AnchorDefault
+	switch value := getfirstValueFromURLParameters(req.Parameters, AnchorSpec); value {
+	case "":
+	case "center":
+		anchor = osgb36.OSGB36Center
+	case "southwest":
+		anchor = osgb36.OSGB36SouthWest
+	default:
+		return nil, badRequest(value, "Unknown anchor '%s', expected center or southwest", value)
+	}
+
+	var latlong *cartconvert.PolarCoord
+	if latlong, err = osgb36.OSGB36ToWGS84LatLongAnchor(osgb36val, anchor, set); err != nil {
+		return nil, badRequest(osgb36strval, "Unable to convert OSGB36 grid reference: %s", err)
+	}
+	req.addProvenanceStep("inverse transverse mercator projection, National Grid", nil)
+	req.addProvenanceStep("inverse helmert transformation OSGB36 to WGS84", set)
+	return serialize(req, latlong, oformat)
+}
+
+func addressHandler(req *GEOConvertRequest, address, oformat string) (interface{}, error) {
+	provider := cartconvert.DefaultGridAddressProvider
+	if provider == nil {
+		return nil, newRequestError(http.StatusNotImplemented, address, "No grid address provider configured")
+	}
+
+	latlong, err := provider.Decode(address)
+	if err != nil {
+		return nil, badRequest(address, "Unable to decode grid address: %s", err)
+	}
+	req.addProvenanceStep("grid address decoding", nil)
+	return serialize(req, latlong, oformat)
+}
+
+// helmertHandler lists the registered helmert parameter sets. The parameters "from" and "to" restrict
+// the listing to the parameter sets between the respective datums.
+func helmertHandler(req *GEOConvertRequest, value, oformat string) (interface{}, error) {
+	from := getfirstValueFromURLParameters(req.Parameters, "from")
+	to := getfirstValueFromURLParameters(req.Parameters, "to")
+
+	sets := &HelmertParameterSets{}
+	for _, set := range cartconvert.HelmertParameterSets(from, to) {
+		sets.HelmertParameterSet = append(sets.HelmertParameterSet, HelmertParameterSet{
+			Name: set.Name, From: set.From, To: set.To, Accuracy: set.Accuracy, Source: set.Source,
+			Default: set.IsDefault(), Parameters: set.WellKnownString(), Extent: set.Extent})
+	}
+	return sets, nil
+}
+
+// gridAddress returns the address of the location of a conversion, if a grid address provider is configured.
+// The address complements the result, failing to determine it doesn't fail the conversion.
+func gridAddress(request *GEOConvertRequest) string {
+	if cartconvert.DefaultGridAddressProvider == nil {
+		return ""
+	}
+
+	address, err := cartconvert.DefaultGridAddressProvider.Encode(request.location)
+	if err != nil {
+		logger.PrintfRequest(request.requestID, "Unable to determine grid address: %s", err)
+		return ""
+	}
+	return address
+}
+
+// placeName returns the name of the place of the location of a conversion, determined by the configured geocoder.
+// Like the grid address, the name complements the result, failing to determine it doesn't fail the conversion.
+func placeName(request *GEOConvertRequest) string {
+	if cartconvert.DefaultGeocoder == nil {
+		return ""
+	}
+
+	name, err := cartconvert.DefaultGeocoder.ReverseGeocode(request.location)
+	if err != nil {
+		logger.PrintfRequest(request.requestID, "Unable to determine place name: %s", err)
+		return ""
+	}
+	return name
 }
 
 // closure of the restful methods
@@ -236,14 +649,31 @@ func (fn httphandlerfunc) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	defer func() {
 		if err := recover(); err != nil {
 			buf := fmt.Sprintf(httperrorstr, err)
-			log.Printf("%s", buf)
-			log.Printf("%s", debug.Stack())
-			http.Error(w, buf, http.StatusInternalServerError)
+			logger.PrintfRequest(requestID(req), "%s", buf)
+			logger.PrintfRequest(requestID(req), "%s", debug.Stack())
+			respondError(w, req, nil, &RequestError{Code: http.StatusInternalServerError, Message: buf})
 		}
 	}()
 
-	if req.ParseForm() != nil {
-		panic("Cannot parse request parameters")
+	// the body has to be read before the form gets parsed, which otherwise consumes the body of a POST request
+	var body []byte
+	if req.Method == "POST" {
+		var err error
+		if body, err = readBatchBody(w, req); err != nil {
+			code := http.StatusBadRequest
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				code = http.StatusRequestEntityTooLarge
+			}
+			respondError(w, req, nil, &RequestError{Code: code, Message: fmt.Sprintf("Cannot read request body: %s", err)})
+			return
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(nil))
+	}
+
+	if err := req.ParseForm(); err != nil {
+		respondError(w, req, nil, &RequestError{Code: http.StatusBadRequest, Message: fmt.Sprintf("Cannot parse request parameters: %s", err)})
+		return
 	}
 
 	// val: coordinate value
@@ -254,16 +684,40 @@ func (fn httphandlerfunc) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	val = val[:len(val)-len(serialformat)]
 	oformat := req.URL.Query().Get(OutputFormatSpec)
 
-	request := &GEOConvertRequest{Method: fn.method, Value: val}
+	ctx, cancel := requestContext(req.Context())
+	defer cancel()
+
+	request := &GEOConvertRequest{Method: fn.method, Value: val, body: body, requestID: requestID(req), ctx: ctx}
 	for key, value := range req.Form {
 		request.Parameters = append(request.Parameters, URLParameter{Key: key, Values: value})
 	}
 
+	if getfirstValueFromURLParameters(request.Parameters, ProvenanceSpec) == "true" {
+		request.provenance = &Provenance{Source: strings.Trim(fn.method, "/")}
+	}
+
 	// enc keeps the requested encoding scheme as requested by content negotiation
 	var enc Encoder
 	// allocate buffer to which the http stream is written, until it gets responded. By doing so we keep the chance to trap errors and respond them to the caller
 	buf := new(bytes.Buffer)
 
+	if serialformat == "" {
+		switch {
+		case wantsFormat(req, request, GeoJSONFormatSpec, GeoJSONMediaType):
+			serialformat = GeoJSONFormatSpec
+		case wantsFormat(req, request, KMLFormatSpec, KMLMediaType):
+			serialformat = KMLFormatSpec
+		case wantsFormat(req, request, GPXFormatSpec, GPXMediaType):
+			serialformat = GPXFormatSpec
+		case wantsFormat(req, request, WKBFormatSpec, WKBMediaType):
+			serialformat = WKBFormatSpec
+		case wantsFormat(req, request, ColumnarFormatSpec, ColumnarMediaType):
+			serialformat = ColumnarFormatSpec
+		case wantsFormat(req, request, XMLFormatSpec, XMLMediaType):
+			serialformat = XMLFormatSpec
+		}
+	}
+
 	switch serialformat {
 	case JSONFormatSpec, "":
 		w.Header().Set("Content-Type", "application/json; charset=utf-8")
@@ -271,21 +725,83 @@ func (fn httphandlerfunc) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	case XMLFormatSpec:
 		w.Header().Set("Content-Type", "text/xml")
 		enc = xml.NewEncoder(buf)
+	case TopoJSONFormatSpec:
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		topojson, err := newTopoJSONEncoder(buf, request)
+		if err != nil {
+			respondError(w, req, request, asRequestError(err))
+			return
+		}
+		enc = topojson
+	case GeoJSONFormatSpec:
+		w.Header().Set("Content-Type", GeoJSONMediaType)
+		enc = &geoJSONEncoder{w: buf}
+	case KMLFormatSpec:
+		w.Header().Set("Content-Type", KMLMediaType)
+		enc = &kmlEncoder{w: buf}
+	case GPXFormatSpec:
+		w.Header().Set("Content-Type", GPXMediaType)
+		enc = &gpxEncoder{w: buf}
+	case WKBFormatSpec:
+		w.Header().Set("Content-Type", WKBMediaType)
+		enc = &wkbEncoder{w: buf, header: w.Header()}
+	case ColumnarFormatSpec:
+		w.Header().Set("Content-Type", ColumnarMediaType)
+		enc = &columnarEncoder{w: buf, header: w.Header()}
 	default:
-		panic(fmt.Sprintf("Unsupported serialization format: '%s'", serialformat))
+		respondError(w, req, request, asRequestError(badRequest(serialformat, "Unsupported serialization format: '%s'", serialformat)))
+		return
 	}
 
-	response := &GEOConvertResponse{GEOConvertRequest: request}
+	// a requested GetFeatureInfo format takes precedence over the serialization format
+	switch infoformat := getfirstValueFromURLParametersFold(request.Parameters, InfoFormatSpec); infoformat {
+	case "":
+	case InfoFormatText:
+		mark, err := decimalMark(getfirstValueFromURLParameters(request.Parameters, DecimalMarkSpec))
+		if err != nil {
+			respondError(w, req, request, asRequestError(err))
+			return
+		}
+		w.Header().Set("Content-Type", InfoFormatText+"; charset=utf-8")
+		enc = &featureInfoTextEncoder{w: buf, mark: mark}
+	case InfoFormatGML:
+		w.Header().Set("Content-Type", InfoFormatGML)
+		enc = &featureInfoGMLEncoder{w: buf}
+	default:
+		respondError(w, req, request, asRequestError(badRequest(infoformat, "Unsupported info format: '%s'", infoformat)))
+		return
+	}
+
+	response := &GEOConvertResponse{APIVersion: APIVersion, GEOConvertRequest: request}
 
-	serial, err := fn.restHandler(request, val, oformat)
+	serial, err := convertCached(fn.restHandler, request, val, oformat)
+	if timedOut(err) {
+		// the conversion goes on in the background and may still change request
+		respondError(w, req, nil, asRequestError(err))
+		return
+	}
 	response.Payload = serial
+	response.Provenance = request.provenance
+	response.Warning = request.warning
+	if err == nil && request.location != nil {
+		response.GridAddress = gridAddress(request)
+		response.PlaceName = placeName(request)
+		response.Altitude = request.altitude
+		response.Accuracy = request.accuracy
+		response.ParameterSet = request.sets
+		response.AxisOrder = request.axisorder
+	}
+	status := http.StatusOK
 	if err != nil {
 
 		// might as well panic(err) but we add some more info
 		// we  serialize the error here in the chosen encoding
+		re := asRequestError(err)
 		response.Error = true
-		response.Status = fmt.Sprint(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		response.Status = re.Message
+		response.Code = re.Code
+		response.Input = re.Input
+		status = re.Code
 	}
 
 	err = enc.Encode(response)
@@ -294,13 +810,11 @@ func (fn httphandlerfunc) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		panic(fmt.Sprintf("Unable to encode response: %s", err))
 	}
 
-	// Enable CORS and set the allowed domain to those requested by Origin
-	if origin := req.Header.Get("Origin"); origin != "" {
-		w.Header().Set("Access-Control-Allow-Origin", origin)
-	}
+	allowOrigin(w, req)
 
 	// prevent chunking by explicitely set the content-length
 	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(status)
 	buf.WriteTo(w)
 }
 
@@ -325,6 +839,13 @@ type apidocpageLayout struct {
 }
 
 func apiHandler(w http.ResponseWriter, req *http.Request) {
+	// every path below the API root, which is not handled by a restful method, denotes an unknown method
+	if apipath := strings.TrimSuffix(req.URL.Path, "/"); apipath != apirootLink {
+		method := strings.SplitN(strings.TrimPrefix(apipath, apirootLink+"/"), "/", 2)[0]
+		respondError(w, req, nil, asRequestError(notFound(method, "Unknown coordinate system or method: '%s'", method)))
+		return
+	}
+
 	tpl := template.Must(template.ParseFiles(apitemplateroot + apiTemplate))
 	apipage := &apidocpageLayout{APIRoot: apirootLink, DOCRoot: docrootLink}
 	for _, val := range httphandlerfuncs {
@@ -348,21 +869,53 @@ type httphandlerfunc struct {
 	method string
 	restHandler
 	docstring string
+	system    string // coordinate system of package convert the method converts from, empty for the other methods
 }
 
 var httphandlerfuncs = map[string]httphandlerfunc{
-	"/latlong": {"/latlong", latlongHandler, "Latitude, Longitude"},
-	"/geohash": {"/geohash", geohashHandler, "Geohash"},
-	"/utm":     {"/utm", utmHandler, "UTM"},
-	"/bmn":     {"/bmn", bmnHandler, "AT:Bundesmeldenetz"},
-	"/osgb":    {"/osgb", osgbHandler, "UK:OSGB36"},
+	"/latlong":   {"/latlong", latlongHandler, "Latitude, Longitude", "latlong"},
+	"/geohash":   {"/geohash", geohashHandler, "Geohash", "geohash"},
+	"/utm":       {"/utm", utmHandler, "UTM", "utm"},
+	"/ups":       {"/ups", utmHandler, "UTM/UPS", "ups"},
+	"/bmn":       {"/bmn", bmnHandler, "AT:Bundesmeldenetz", "bmn"},
+	"/osgb":      {"/osgb", osgbHandler, "UK:OSGB36", "osgb36"},
+	"/address":   {"/address", addressHandler, "Grid addresses", ""},
+	"/helmert":   {"/helmert", helmertHandler, "Helmert parameter sets", ""},
+	"/systems":   {"/systems", systemsHandler, "Coordinate systems", ""},
+	"/detect":    {"/detect", detectHandler, "Detection of the coordinate system", ""},
+	"/selftest":  {"/selftest", selftestHandler, "Self test", ""},
+	"/roundtrip": {"/roundtrip", roundtripHandler, "Round trip of a conversion", ""},
+	"/batch":     {"/batch", batchHandler, "Batch conversion", ""},
 }
 
+// The methods converting from the coordinate systems of package convert, by the name of the system. It is filled at
+// init, as referring to httphandlerfuncs from detectHandler would be an initialization cycle.
+var systemMethods = make(map[string]httphandlerfunc)
+
 func init() {
+	for _, fn := range httphandlerfuncs {
+		if fn.system != "" {
+			systemMethods[fn.system] = fn
+		}
+	}
+}
+
+// registerAPI registers the handlers of the API at the configured API root. It is called by main once the command
+// line and the configuration file are read.
+func registerAPI() {
 	apirootLink = conf_apiroot()
+	cartconvert.JSONPrecision = conf_precision()
+	// the API root is served with and without a trailing slash
+	http.HandleFunc(apirootLink, apiHandler)
 	http.HandleFunc(apirootLink+"/", apiHandler)
 
 	for _, handle := range httphandlerfuncs {
 		http.Handle(apirootLink+handle.method+"/", handle)
 	}
+
+	// streams and CSV tables are not buffered and responded like the restful methods
+	http.HandleFunc(apirootLink+StreamMethod, streamHandler)
+	http.HandleFunc(apirootLink+StreamMethod+"/", streamHandler)
+	http.HandleFunc(apirootLink+CSVMethod, csvHandler)
+	http.HandleFunc(apirootLink+CSVMethod+"/", csvHandler)
 }
@@ -0,0 +1,43 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// Automated tests for the restful methods of the server
+package main
+
+import (
+	"testing"
+)
+
+// ## latlongHandler
+type latlongHandlerTest struct {
+	lat, long string
+	out       string // latitude and longitude of the location, empty if the request is bad
+}
+
+var latlongHandlerTests = []latlongHandlerTest{
+	{"70", "14", "lat: 70°, long: 14°"},
+	{"-70", "-14", "lat: -70°, long: -14°"},
+	{"47.57", "14.0075", "lat: 47.57°, long: 14.0075°"},
+	{"47.57°", "14°0'27''", "lat: 47.57°, long: 14.0075°"},
+	{"N 47°34'12''", "E 14°0'27''", "lat: 47.57°, long: 14.0075°"},
+	{"north", "14", ""},
+}
+
+func TestLatLongHandler(t *testing.T) {
+	for index, test := range latlongHandlerTests {
+		request := &GEOConvertRequest{Method: "/latlong", Parameters: []URLParameter{
+			{Key: "lat", Values: []string{test.lat}}, {Key: "long", Values: []string{test.long}}}}
+		_, err := latlongHandler(request, "", OFlatlongcomma)
+
+		if test.out == "" {
+			if err == nil {
+				t.Errorf("latlongHandler [%d]: expected a bad request for %s %s", index, test.lat, test.long)
+			}
+			continue
+		}
+		if err != nil || request.location == nil || request.location.String() != test.out {
+			t.Errorf("latlongHandler [%d]: expected %s, got %v (%v)", index, test.out, request.location, err)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// RESTFul interface for coordinate transformations - columnar output of batches
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/the42/cartconvert/cartconvert"
+	"io"
+	"net/http"
+)
+
+// The serialization format ColumnarFormatSpec encodes the results of a batch in the compact binary columnar format of
+// the package cartconvert, see cartconvert.WriteColumnar. It may also be requested by the parameter format=ccol
+// or by the Accept header ColumnarMediaType.
+const (
+	ColumnarFormatSpec = ".ccol"
+	ColumnarMediaType  = "application/vnd.cartconvert.columnar"
+)
+
+// columnarEncoder writes the results of a batch as columns of eastings and northings. Responses other than batches,
+// like single conversions, errors or listings, are written as JSON, and the content type of header is set
+// accordingly.
+type columnarEncoder struct {
+	w      io.Writer
+	header http.Header
+}
+
+func (enc *columnarEncoder) Encode(v interface{}) error {
+	response, ok := v.(*GEOConvertResponse)
+	if !ok {
+		return fmt.Errorf("Unable to encode %T as columnar format", v)
+	}
+
+	batch, ok := response.Payload.(*Batch)
+	if response.Error || !ok {
+		enc.header.Set("Content-Type", "application/json; charset=utf-8")
+		return json.NewEncoder(enc.w).Encode(response)
+	}
+
+	eastingNorthing := make([]float64, 0, 2*len(batch.BatchResult))
+	var errs []error
+	for index, result := range batch.BatchResult {
+		easting, northing, ok := columnarEastingNorthing(&result)
+		if !ok {
+			if errs == nil {
+				errs = make([]error, len(batch.BatchResult))
+			}
+			errs[index] = fmt.Errorf("%s", result.Error)
+		}
+		eastingNorthing = append(eastingNorthing, easting, northing)
+	}
+	return cartconvert.WriteColumnar(enc.w, eastingNorthing, errs)
+}
+
+// columnarEastingNorthing returns easting and northing of the projected coordinate of a result, or longitude and
+// latitude of its location, if the output format is no projection, like latitude / longitude or geohash. ok is false
+// for a failed conversion.
+func columnarEastingNorthing(result *BatchResult) (easting, northing float64, ok bool) {
+	switch payload := result.Payload.(type) {
+	case *UTMCoord:
+		return payload.UTMCoord.Easting, payload.UTMCoord.Northing, true
+	case *BMN:
+		return payload.BMNCoord.Right, payload.BMNCoord.Height, true
+	case *OSGB36:
+		return float64(payload.OSGB36Coord.Easting), float64(payload.OSGB36Coord.Northing), true
+	}
+	if result.Error != "" || result.location == nil {
+		return 0, 0, false
+	}
+	return result.location.Longitude, result.location.Latitude, true
+}
@@ -11,68 +11,267 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"github.com/the42/cartconvert/cartconvert"
 	"io/ioutil"
 	"os"
+	"strings"
+	"sync"
+	"time"
 )
 
 var configFileName = flag.String("config", "config.json", "location of JSON configuration file")
 
+// The addresses the server listens at, a port like "5000" or a host and port like "localhost:5001". In the
+// configuration file a single binding may be given as string, several as list of strings.
+type bindings []string
+
+func (b *bindings) UnmarshalJSON(data []byte) error {
+	var binding string
+	if err := json.Unmarshal(data, &binding); err == nil {
+		*b = bindings{binding}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*b = bindings(list)
+	return nil
+}
+
 type config struct {
 	APIRoot string
 	DocRoot string
-	Binding string
+	Binding bindings
 	TimeOut int
+	// decimal places of coordinates in JSON output, -1 for the smallest number necessary
+	Precision int
+	// maximum number of conversions of a batch
+	MaxBatchSize int
+	// maximum bytes of the body of a request
+	MaxBodySize int64
+	// seconds granted to a conversion, 0 for no limit
+	RequestTimeout int
+	// number of recent conversion results kept in memory, 0 to disable the cache of conversions
+	ConversionCacheSize int
+	// origins allowed to call the API from browsers, "*" for any origin
+	AllowedOrigins []string
+	// seconds to read a request, to write a response and to keep an idle connection open
+	ReadTimeout, WriteTimeout, IdleTimeout int
+	// requests per second and requests at once granted to a client, 0 to disable rate limiting
+	RateLimit float64
+	RateBurst int
+	// seconds responses of the API may be cached, 0 to disable entity tags and caching
+	CacheMaxAge int
+	// certificate and private key in PEM format, the server uses HTTPS if both are set
+	CertFile, KeyFile string
+	// keys granting access to the API, every request is granted access if there are none
+	APIKeys []string
+	// file the log is appended to, standard error if empty, and the format of the log, "plain" or "json"
+	LogFile, LogFormat string
+	// decimal mark of numbers in CSV tables and text/plain output, "." or ","
+	DecimalMark string
+	// JSON file of helmert parameter sets registered at startup in addition to the shipped ones, none if empty
+	HelmertParameterFile string
 }
 
-var conf *config
+// The configuration, read once by loadedConfig
+var (
+	conf     *config
+	confOnce sync.Once
+)
+
+// The environment variable holding API keys, separated by commas
+const apiKeysEnv = "CARTCONVSERV_API_KEYS"
+
+// The error reading the configuration file, if any
+var confErr error
+
+// createconfig returns the configuration of the defaults, replaced by the values of the configuration file and
+// completed by the API keys of the environment
+func createconfig() *config {
+	conf := &config{APIRoot: "/api", DocRoot: "/doc", Binding: bindings{os.Getenv("PORT")}, TimeOut: 3600, Precision: -1, MaxBatchSize: 1000,
+		MaxBodySize: 1 << 20, RequestTimeout: 10, ReadTimeout: 10, WriteTimeout: 30, IdleTimeout: 120, LogFormat: LogFormatPlain,
+		DecimalMark: "."}
+	if err := readConfig(*configFileName, conf); err != nil {
+		confErr = err
+	}
 
-func createorreturnconfig(conf *config) *config {
-	if conf == nil {
-		conf = &config{APIRoot: "/api", DocRoot: "/doc", Binding: os.Getenv("PORT"), TimeOut: 3600}
+	// roots which can not be served are reported and replaced by the defaults
+	conf.APIRoot, conf.DocRoot = normalizeRoot(conf.APIRoot), normalizeRoot(conf.DocRoot)
+	if err := validateRoots(conf.APIRoot, conf.DocRoot); err != nil {
+		if confErr == nil {
+			confErr = err
+		}
+		conf.APIRoot, conf.DocRoot = "/api", "/doc"
 	}
-	flag.Parse()
-	readConfig(*configFileName, conf)
-	if conf.Binding == "" {
-		conf.Binding = "5000"
+
+	if _, err := cartconvert.ParseDecimalMark(conf.DecimalMark); err != nil {
+		if confErr == nil {
+			confErr = fmt.Errorf("DecimalMark has to be '.' or ',', got: '%s'", conf.DecimalMark)
+		}
+		conf.DecimalMark = "."
+	}
+
+	var binding bindings
+	for _, b := range conf.Binding {
+		if b != "" {
+			binding = append(binding, b)
+		}
+	}
+	if len(binding) == 0 {
+		binding = bindings{"5000"}
+	}
+	conf.Binding = binding
+
+	// keys given by the environment are granted access in addition to the configured ones
+	for _, key := range strings.Split(os.Getenv(apiKeysEnv), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			conf.APIKeys = append(conf.APIKeys, key)
+		}
 	}
 	return conf
 }
 
-func readConfig(filename string, conf *config) {
+// readConfig sets the values of conf given by the JSON configuration file filename. A missing file leaves conf
+// unchanged. If the file can not be read or parsed, conf is left unchanged as well and an error is returned, stating
+// line and column of a syntax error.
+func readConfig(filename string, conf *config) error {
 	b, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		logger.Printf("%s", err)
+		return nil
+	}
 	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		return
+		return err
 	}
 
-	if conf == nil {
-		conf = &config{}
+	// the file is decoded into a copy, so a type error halfway through doesn't leave a partial configuration
+	parsed := *conf
+	if err = json.Unmarshal(b, &parsed); err != nil {
+		var offset int64
+		switch err := err.(type) {
+		case *json.SyntaxError:
+			offset = err.Offset
+		case *json.UnmarshalTypeError:
+			offset = err.Offset
+		default:
+			return fmt.Errorf("%s: %s", filename, err)
+		}
+		// the offset is past the offending byte
+		line, column := textPosition(b, offset-1)
+		return fmt.Errorf("%s:%d:%d: %s", filename, line, column, err)
 	}
+	*conf = parsed
+	return nil
+}
 
-	err = json.Unmarshal(b, &conf)
-	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		panic("Unable to parse json configuration file")
+// textPosition returns line and column, both counted from 1, of the byte at offset into text
+func textPosition(text []byte, offset int64) (line, column int) {
+	if offset < 0 {
+		offset = 0
+	} else if offset > int64(len(text)) {
+		offset = int64(len(text))
+	}
+	line, column = 1, 1
+	for _, c := range text[:offset] {
+		if c == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
+		}
 	}
 	return
 }
 
+// loadedConfig returns the configuration, which is created by the first call only. It is never changed afterwards, so
+// the accessors below are safe for concurrent use by the handlers. The command line has to be parsed before the
+// first call, so main reads the configuration rather than the initialization of the package.
+func loadedConfig() *config {
+	confOnce.Do(func() {
+		conf = createconfig()
+	})
+	return conf
+}
+
+// conf_err returns the error reading the configuration file, nil if it was read or doesn't exist
+func conf_err() error {
+	loadedConfig()
+	return confErr
+}
+
 func conf_apiroot() string {
-	conf = createorreturnconfig(conf)
-	return conf.APIRoot
+	return loadedConfig().APIRoot
 }
 
 func conf_docroot() string {
-	conf = createorreturnconfig(conf)
-	return conf.DocRoot
+	return loadedConfig().DocRoot
 }
 
-func conf_binding() string {
-	conf = createorreturnconfig(conf)
-	return conf.Binding
+func conf_bindings() []string {
+	return loadedConfig().Binding
 }
 
 func conf_statictimeout() int {
-	conf = createorreturnconfig(conf)
-	return conf.TimeOut
+	return loadedConfig().TimeOut
+}
+
+func conf_precision() int {
+	return loadedConfig().Precision
+}
+
+func conf_maxbatchsize() int {
+	return loadedConfig().MaxBatchSize
+}
+
+func conf_maxbodysize() int64 {
+	return loadedConfig().MaxBodySize
+}
+
+func conf_requesttimeout() time.Duration {
+	return time.Duration(loadedConfig().RequestTimeout) * time.Second
+}
+
+func conf_conversioncachesize() int {
+	return loadedConfig().ConversionCacheSize
+}
+
+func conf_allowedorigins() []string {
+	return loadedConfig().AllowedOrigins
+}
+
+func conf_timeouts() (read, write, idle time.Duration) {
+	conf := loadedConfig()
+	return time.Duration(conf.ReadTimeout) * time.Second, time.Duration(conf.WriteTimeout) * time.Second, time.Duration(conf.IdleTimeout) * time.Second
+}
+
+func conf_ratelimit() (rate float64, burst int) {
+	return loadedConfig().RateLimit, conf.RateBurst
+}
+
+func conf_cachemaxage() int {
+	return loadedConfig().CacheMaxAge
+}
+
+func conf_tls() (certFile, keyFile string) {
+	return loadedConfig().CertFile, conf.KeyFile
+}
+
+func conf_apikeys() []string {
+	return loadedConfig().APIKeys
+}
+
+func conf_log() (file, format string) {
+	return loadedConfig().LogFile, conf.LogFormat
+}
+
+func conf_decimalmark() cartconvert.DecimalMark {
+	mark, _ := cartconvert.ParseDecimalMark(loadedConfig().DecimalMark)
+	return mark
+}
+
+func conf_helmertparameterfile() string {
+	return loadedConfig().HelmertParameterFile
 }
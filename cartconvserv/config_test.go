@@ -0,0 +1,87 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// +build !appengine
+
+// Automated tests for the configuration of the stand alone server
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// ## readConfig
+type readConfigTest struct {
+	name, content string
+	err           string // prefix of the error, empty if the file is read
+	apiroot       string
+	precision     int
+}
+
+var readConfigTests = []readConfigTest{
+	{"valid", `{"APIRoot": "/myapi", "Precision": 6}`, "", "/myapi", 6},
+	{"partial", `{"Precision": 3}`, "", "/api", 3},
+	{"trailing brace", "{\n\"APIRoot\": \"/myapi\",\n}", "config.json:3:1: invalid character '}'", "/api", -1},
+	{"unterminated", `{"APIRoot": "/myapi"`, "config.json:1:20: unexpected end of JSON input", "/api", -1},
+	{"type mismatch", "{\"APIRoot\": \"/myapi\",\n \"Precision\": \"six\"}", "config.json:2:19: json: cannot unmarshal string", "/api", -1},
+	{"no object", `[1, 2]`, "config.json:1:1: json: cannot unmarshal array", "/api", -1},
+}
+
+func TestReadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cartconvserv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "config.json")
+
+	for _, test := range readConfigTests {
+		if err := ioutil.WriteFile(filename, []byte(test.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		conf := &config{APIRoot: "/api", Precision: -1}
+		err := readConfig(filename, conf)
+
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("readConfig %s: expected no error, got %s", test.name, err)
+		case test.err != "" && (err == nil || !strings.HasPrefix(err.Error(), filepath.Join(dir, test.err))):
+			t.Errorf("readConfig %s: expected the error %s, got %v", test.name, test.err, err)
+		}
+		// a file which can not be parsed leaves the defaults unchanged
+		if conf.APIRoot != test.apiroot || conf.Precision != test.precision {
+			t.Errorf("readConfig %s: expected APIRoot %s and Precision %d, got %s and %d", test.name, test.apiroot, test.precision, conf.APIRoot, conf.Precision)
+		}
+	}
+
+	// a missing file is not an error
+	conf := &config{APIRoot: "/api"}
+	if err := readConfig(filepath.Join(dir, "missing.json"), conf); err != nil || conf.APIRoot != "/api" {
+		t.Errorf("readConfig: expected the defaults for a missing file, got %s (%v)", conf.APIRoot, err)
+	}
+}
+
+// ## textPosition
+func TestTextPosition(t *testing.T) {
+	text := []byte("{\n  \"a\": 1,\n}")
+	for _, test := range []struct {
+		offset       int64
+		line, column int
+	}{
+		{0, 1, 1},
+		{1, 1, 2},
+		{4, 2, 3},
+		{12, 3, 1},
+		{-1, 1, 1},
+		{100, 3, 2},
+	} {
+		if line, column := textPosition(text, test.offset); line != test.line || column != test.column {
+			t.Errorf("textPosition %d: expected %d:%d, got %d:%d", test.offset, test.line, test.column, line, column)
+		}
+	}
+}
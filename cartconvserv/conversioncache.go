@@ -0,0 +1,120 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// RESTFul interface for coordinate transformations - cache of recent conversion results
+package main
+
+import (
+	"container/list"
+	"github.com/the42/cartconvert/cartconvert"
+	"net/url"
+	"sync"
+)
+
+// A conversion result kept by the cache: the payload of the restful method and the state it leaves in the request
+type cachedConversion struct {
+	key        string
+	payload    interface{}
+	provenance *Provenance
+	location   *cartconvert.PolarCoord
+	warning    string
+	altitude   *float64
+	accuracy   float64
+	sets       []string
+	axisorder  string
+}
+
+// conversionCache keeps the results of the most recent conversions, the least recently used result is evicted
+// first. It is safe for concurrent use.
+type conversionCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	lru     *list.List // front is the most recently used result
+}
+
+func newConversionCache(size int) *conversionCache {
+	return &conversionCache{size: size, entries: make(map[string]*list.Element), lru: list.New()}
+}
+
+// The cache of conversions of the API, nil if disabled by a ConversionCacheSize of 0
+var conversions *conversionCache
+
+// setupConversionCache creates the cache of conversions of the configured size, called by main
+func setupConversionCache() {
+	if size := conf_conversioncachesize(); size > 0 {
+		conversions = newConversionCache(size)
+	}
+}
+
+func (c *conversionCache) get(key string) (*cachedConversion, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return elem.Value.(*cachedConversion), true
+}
+
+func (c *conversionCache) add(entry *cachedConversion) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[entry.key]; ok {
+		elem.Value = entry
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[entry.key] = c.lru.PushFront(entry)
+	if c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cachedConversion).key)
+	}
+}
+
+// conversionKey returns the key of a conversion, the normalized request: the restful method, the input value, the
+// output format and the parameters sorted by key. The key is empty, if the conversion must not be cached, like a self test, or a
+// request with a body, which is not part of the key.
+func conversionKey(request *GEOConvertRequest, value, oformat string) string {
+	if request.body != nil || request.Method == "/selftest" {
+		return ""
+	}
+	params := url.Values{}
+	for _, param := range request.Parameters {
+		params[param.Key] = append(params[param.Key], param.Values...)
+	}
+	return request.Method + "\n" + value + "\n" + oformat + "\n" + params.Encode()
+}
+
+// convertCached converts like convertWithDeadline, but responds a recent result of the same conversion from the cache
+// instead of converting again. Only successful conversions are cached. The cached payload is shared by the responses,
+// which must not modify it.
+func convertCached(fn restHandler, request *GEOConvertRequest, value, oformat string) (interface{}, error) {
+	if conversions == nil {
+		return convertWithDeadline(fn, request, value, oformat)
+	}
+	key := conversionKey(request, value, oformat)
+	if key == "" {
+		return convertWithDeadline(fn, request, value, oformat)
+	}
+
+	if entry, ok := conversions.get(key); ok {
+		apimetrics.observeCache(true)
+		request.provenance, request.location, request.warning = entry.provenance, entry.location, entry.warning
+		request.altitude, request.accuracy, request.sets, request.axisorder = entry.altitude, entry.accuracy, entry.sets, entry.axisorder
+		return entry.payload, nil
+	}
+	apimetrics.observeCache(false)
+
+	payload, err := convertWithDeadline(fn, request, value, oformat)
+	if err == nil {
+		conversions.add(&cachedConversion{key: key, payload: payload, provenance: request.provenance, location: request.location,
+			warning: request.warning, altitude: request.altitude, accuracy: request.accuracy, sets: request.sets, axisorder: request.axisorder})
+	}
+	return payload, err
+}
@@ -0,0 +1,37 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// Automated tests for the cache of conversion results
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+// ## convertCached
+func TestConvertCachedOutputFormat(t *testing.T) {
+	defer func(cache *conversionCache) { conversions = cache }(conversions)
+	conversions = newConversionCache(16)
+
+	newRequest := func() *GEOConvertRequest {
+		return &GEOConvertRequest{Method: "/latlong", ctx: context.Background(),
+			Parameters: []URLParameter{{Key: "lat", Values: []string{"47.57"}}, {Key: "long", Values: []string{"14.0075"}}}}
+	}
+
+	// the same input converted to two output formats, like the items of a batch, is cached per output format
+	for round := 0; round < 2; round++ {
+		payload, err := convertCached(latlongHandler, newRequest(), "", OFUTM)
+		if _, ok := payload.(*UTMCoord); err != nil || !ok {
+			t.Errorf("convertCached [%d]: expected a UTM coordinate, got %T (%v)", round, payload, err)
+		}
+		payload, err = convertCached(latlongHandler, newRequest(), "", OFgeohash)
+		if _, ok := payload.(*GeoHash); err != nil || !ok {
+			t.Errorf("convertCached [%d]: expected a geohash, got %T (%v)", round, payload, err)
+		}
+	}
+	if n := conversions.lru.Len(); n != 2 {
+		t.Errorf("convertCached: expected 2 cached conversions, got %d", n)
+	}
+}
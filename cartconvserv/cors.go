@@ -0,0 +1,70 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// RESTFul interface for coordinate transformations - cross-origin resource sharing
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// The methods and request headers browsers may use when calling the API from an allowed origin, and the response
+// headers they may read
+const (
+	corsAllowedMethods = "GET, POST, OPTIONS"
+	corsAllowedHeaders = "Accept, Content-Type, " + APIKeyHeader + ", " + RequestIDHeader
+	corsExposedHeaders = RequestIDHeader
+)
+
+// originAllowed reports whether origin is one of the allowed origins, or any origin is allowed by "*"
+func originAllowed(origin string, allowed []string) bool {
+	for _, allow := range allowed {
+		if allow == "*" || strings.EqualFold(allow, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// Enable CORS for the origin of the request. Without configured allowed origins, the domain requested by Origin
+// is allowed, but no preflight requests are answered. Otherwise only the allowed origins are granted access.
+func allowOrigin(w http.ResponseWriter, req *http.Request) {
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return
+	}
+
+	allowed := conf_allowedorigins()
+	if len(allowed) == 0 {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Expose-Headers", corsExposedHeaders)
+		return
+	}
+
+	// the response depends on the origin, so caches must not serve it to other origins
+	w.Header().Add("Vary", "Origin")
+	if !originAllowed(origin, allowed) {
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	w.Header().Set("Access-Control-Expose-Headers", corsExposedHeaders)
+	w.Header().Set("Access-Control-Allow-Methods", corsAllowedMethods)
+	w.Header().Set("Access-Control-Allow-Headers", corsAllowedHeaders)
+}
+
+// corsHandler answers the preflight requests of browsers, if allowed origins are configured. Every other request
+// is passed on to h.
+func corsHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != "OPTIONS" || req.Header.Get("Access-Control-Request-Method") == "" || len(conf_allowedorigins()) == 0 {
+			h.ServeHTTP(w, req)
+			return
+		}
+
+		allowOrigin(w, req)
+		w.Header().Set("Content-Length", "0")
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,134 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// RESTFul interface for coordinate transformations - conversion of CSV tables
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/the42/cartconvert/cartconvert"
+	"github.com/the42/cartconvert/cartconvert/convert"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+const CSVMethod = "/csv"
+
+// The media type of CSV tables
+const CSVMediaType = "text/csv; charset=utf-8"
+
+// The name of the column appended to a CSV table to hold the error of a row
+const csvErrorColumn = "Error"
+
+// The parameter DecimalMarkSpec sets the decimal mark of numbers in CSV tables and text/plain output, "." or "," or
+// their names "point" and "comma", by default the configured DecimalMark. JSON and XML always use the decimal point.
+const DecimalMarkSpec = "decimal"
+
+// decimalMark returns the decimal mark given by spec, the configured one if spec is empty
+func decimalMark(spec string) (cartconvert.DecimalMark, error) {
+	if spec == "" {
+		return conf_decimalmark(), nil
+	}
+	mark, err := cartconvert.ParseDecimalMark(spec)
+	if err != nil {
+		return mark, badRequest(spec, "The decimal mark has to be '.' or ',', got: '%s'", spec)
+	}
+	return mark, nil
+}
+
+// writeTracker records whether anything has been written, after which an error can no longer be responded
+type writeTracker struct {
+	io.Writer
+	written bool
+}
+
+func (w *writeTracker) Write(b []byte) (int, error) {
+	w.written = true
+	return w.Writer.Write(b)
+}
+
+// csvHandler converts a CSV table with a header, sent as body of a POST request, by convert.ConvertCSV and responds
+// the table with the results appended as columns, row by row as they are converted. The parameter from names the
+// source coordinate system, "auto" by default, columns the columns holding the coordinate, to the target coordinate
+// systems, both separated by commas, delimiter the column delimiter, "," by default or "tab", and DecimalMarkSpec the
+// decimal mark of the results. A row which fails to convert keeps empty results and carries the error in the appended
+// column Error.
+func csvHandler(w http.ResponseWriter, req *http.Request) {
+
+	if req.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "Conversion of a CSV table requires a POST request with the table as body", http.StatusMethodNotAllowed)
+		return
+	}
+	defer req.Body.Close()
+
+	query := req.URL.Query()
+	mapping := convert.CSVMapping{From: query.Get("from"), ErrorColumn: csvErrorColumn}
+	if mapping.From == "" {
+		mapping.From = convert.Auto
+	}
+	if columns := query.Get("columns"); columns != "" {
+		mapping.Columns = strings.Split(columns, ",")
+	}
+	if to := query.Get("to"); to != "" {
+		mapping.To = strings.Split(to, ",")
+	}
+
+	var err error
+	if mapping.DecimalMark, err = decimalMark(query.Get(DecimalMarkSpec)); err != nil {
+		respondError(w, req, nil, asRequestError(err))
+		return
+	}
+
+	delimiter := ','
+	if spec := query.Get("delimiter"); spec != "" {
+		if spec == "tab" {
+			spec = "\t"
+		}
+		var size int
+		if delimiter, size = utf8.DecodeRuneInString(spec); size != len(spec) {
+			respondError(w, req, nil, asRequestError(badRequest(spec, "The delimiter has to be a single character, got: '%s'", spec)))
+			return
+		}
+	}
+
+	// the rows are converted while the table is read, so the request body has to be read while the response is
+	// written. A row is not limited in length, so the table is limited like the body of a batch.
+	http.NewResponseController(w).EnableFullDuplex()
+	r := csv.NewReader(http.MaxBytesReader(w, req.Body, conf_maxbodysize()))
+	r.Comma = delimiter
+
+	w.Header().Set("Content-Type", CSVMediaType)
+	tracker := &writeTracker{Writer: w}
+	cw := csv.NewWriter(tracker)
+	cw.Comma = delimiter
+
+	_, err = convert.ConvertCSV(r, cw, mapping, nil)
+	if err == nil {
+		return
+	}
+	if tracker.written {
+		// the table has been partly responded, the error can only be logged
+		logger.PrintfRequest(requestID(req), "Unable to convert CSV table: %s", err)
+		return
+	}
+
+	code := http.StatusBadRequest
+	var tooLarge *http.MaxBytesError
+	var unknown convert.UnknownSystemError
+	switch {
+	case errors.As(err, &tooLarge):
+		code = http.StatusRequestEntityTooLarge
+	case errors.As(err, &unknown), errors.Is(err, cartconvert.ErrNotFound):
+		code = http.StatusNotFound
+	case err == cartconvert.ErrSyntax:
+		err = fmt.Errorf("parameters 'columns' and 'to' are required")
+	}
+	w.Header().Del("Content-Type")
+	respondError(w, req, nil, &RequestError{Code: code, Message: fmt.Sprintf("Unable to convert CSV table: %s", err)})
+}
@@ -0,0 +1,65 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// RESTFul interface for coordinate transformations - processing deadline of a conversion
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+// requestContext returns the context of a conversion, which is done after the configured RequestTimeout has passed
+// or when parent is done. A RequestTimeout of 0 sets no deadline.
+func requestContext(parent context.Context) (context.Context, context.CancelFunc) {
+	if timeout := conf_requesttimeout(); timeout > 0 {
+		return context.WithTimeout(parent, timeout)
+	}
+	return context.WithCancel(parent)
+}
+
+// convertWithDeadline calls the handler fn of a restful method and returns its result, or a RequestError responded
+// with http status 504, if the context of request is done before. A conversion can not be interrupted, so fn goes on
+// in the background and its result is discarded. request must not be used any further in that case, as fn may still
+// change it. A panic of fn is returned as error responded with http status 500.
+func convertWithDeadline(fn restHandler, request *GEOConvertRequest, value, oformat string) (interface{}, error) {
+
+	type result struct {
+		payload interface{}
+		err     error
+	}
+
+	// buffered, so the conversion can finish after the deadline has passed
+	done := make(chan result, 1)
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				buf := fmt.Sprintf(httperrorstr, err)
+				logger.PrintfRequest(request.requestID, "%s", buf)
+				logger.PrintfRequest(request.requestID, "%s", debug.Stack())
+				done <- result{err: &RequestError{Code: http.StatusInternalServerError, Message: buf}}
+			}
+		}()
+		payload, err := fn(request, value, oformat)
+		done <- result{payload, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.payload, r.err
+	case <-request.ctx.Done():
+		if request.ctx.Err() == context.DeadlineExceeded {
+			return nil, newRequestError(http.StatusGatewayTimeout, value, "Conversion exceeded the time limit of %s", conf_requesttimeout())
+		}
+		return nil, newRequestError(http.StatusGatewayTimeout, value, "Conversion canceled: %s", request.ctx.Err())
+	}
+}
+
+// timedOut reports whether err is the error of convertWithDeadline, if the conversion did not finish in time
+func timedOut(err error) bool {
+	re, ok := err.(*RequestError)
+	return ok && re.Code == http.StatusGatewayTimeout
+}
@@ -0,0 +1,133 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// RESTFul interface for coordinate transformations - detection of the coordinate system of a coordinate literal
+package main
+
+import (
+	"github.com/the42/cartconvert/cartconvert/bmn"
+	"github.com/the42/cartconvert/cartconvert/parse"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// --------------------------------------------------------------------
+// Serialization struct definitions
+type (
+	// A coordinate system a literal may be given in. GEOConvertRequest is the request converting the literal in
+	// this coordinate system, Latitude and Longitude the WGS84 location it denotes. Confidence ranges from 0 to 1.
+	// Method and GEOConvertRequest are empty for a coordinate system, which the API has no method of.
+	DetectCandidate struct {
+		Name                string
+		Method              string `json:",omitempty" xml:",omitempty"`
+		Confidence          float64
+		GEOConvertRequest   *GEOConvertRequest `json:",omitempty" xml:",omitempty"`
+		Latitude, Longitude float64
+	}
+
+	Detection struct {
+		Candidate []DetectCandidate
+	}
+)
+
+// A guess of a coordinate system of package parse, which becomes a candidate if its parser accepts the literal.
+// The request converting the literal of a system with a method of the API is item, the literal may differ from
+// the one of the request by a reading of its own, like a meridian stripe prepended.
+type detectGuess struct {
+	system, literal string
+	item            BatchItem
+	confidence      float64
+}
+
+// guessItem returns the request converting the literal by the method of the coordinate system, see systemMethods
+func guessItem(system, literal string) BatchItem {
+	fn := systemMethods[system]
+	if system == "latlong" {
+		return BatchItem{Method: "latlong", Parameters: map[string]string{LatLongSpec: literal}}
+	}
+	return BatchItem{Method: strings.Trim(fn.method, "/"), Value: literal}
+}
+
+// detectGuesses returns the coordinate systems the literal might be given in: every coordinate system registered
+// with package parse, and the readings of a bare pair of numbers in the meridian stripes of the Bundesmeldenetz and
+// as longitude / latitude. The confidence expresses how distinctive the notation is for the coordinate system. It
+// decreases with the order of precedence of package parse, which tries the distinctive notations first.
+func detectGuesses(literal string) []detectGuess {
+
+	systems := parse.Systems()
+	guesses := make([]detectGuess, 0, len(systems)+4)
+	for index, system := range systems {
+		confidence := 0.9 - 0.6*float64(index)/float64(len(systems))
+		guesses = append(guesses, detectGuess{system, literal, guessItem(system, literal), confidence})
+	}
+
+	fields := strings.FieldsFunc(literal, func(r rune) bool { return unicode.IsSpace(r) || r == ',' || r == ';' })
+	if len(fields) != 2 || strings.IndexFunc(literal, unicode.IsLetter) >= 0 {
+		return guesses
+	}
+	// a bare pair of right- and height-value may lie in any of the meridian stripes of the Bundesmeldenetz
+	for _, meridian := range []bmn.BMNMeridian{bmn.BMNM28, bmn.BMNM31, bmn.BMNM34} {
+		value := meridian.String() + " " + fields[0] + " " + fields[1]
+		guesses = append(guesses, detectGuess{"bmn", value, guessItem("bmn", value), 0.5})
+	}
+	// a pair of decimals is given latitude first by convention
+	item := guessItem("latlong", literal)
+	item.Parameters[LongFirstSpec] = "true"
+	guesses = append(guesses, detectGuess{"latlong", fields[1] + " " + fields[0], item, 0.3})
+	return guesses
+}
+
+// candidateRequest returns the request converting the literal of a guess by its method
+func candidateRequest(req *GEOConvertRequest, method string, item *BatchItem) *GEOConvertRequest {
+	request := &GEOConvertRequest{Method: method, Value: item.Value, requestID: req.requestID}
+	for key, value := range item.Parameters {
+		request.Parameters = append(request.Parameters, URLParameter{Key: key, Values: []string{value}})
+	}
+	sort.Slice(request.Parameters, func(i, j int) bool { return request.Parameters[i].Key < request.Parameters[j].Key })
+	return request
+}
+
+// detectHandler ranks the coordinate systems, in which the coordinate literal value may be given. Every guess of
+// detectGuesses is parsed by the parser of its coordinate system in package parse, and becomes a candidate, if the
+// parser accepts the literal, that is if the literal parses and lies within the range of the coordinate system.
+// The first coordinate system accepting the literal unchanged is the one detected by package parse, it comes first
+// with a confidence of 1. The other candidates are ordered by decreasing confidence.
+func detectHandler(req *GEOConvertRequest, value, oformat string) (interface{}, error) {
+
+	literal := strings.TrimSpace(value)
+	if literal == "" {
+		literal = strings.TrimSpace(getfirstValueFromURLParameters(req.Parameters, "value"))
+	}
+	if literal == "" {
+		return nil, badRequest(value, "Detection requires a coordinate literal as input value or parameter 'value'")
+	}
+
+	detection := &Detection{Candidate: []DetectCandidate{}}
+	detected := false
+	for _, guess := range detectGuesses(literal) {
+		coord, err := parse.ParseSystem(guess.literal, guess.system)
+		if err != nil {
+			continue
+		}
+		gc, err := coord.ToWGS84()
+		if err != nil {
+			continue
+		}
+
+		candidate := DetectCandidate{Name: guess.system, Confidence: guess.confidence, Latitude: gc.Latitude, Longitude: gc.Longitude}
+		if guess.literal == literal && !detected {
+			candidate.Confidence, detected = 1, true
+		}
+		if fn, ok := systemMethods[guess.system]; ok {
+			candidate.Name, candidate.Method, candidate.GEOConvertRequest = fn.docstring, fn.method, candidateRequest(req, fn.method, &guess.item)
+		}
+		detection.Candidate = append(detection.Candidate, candidate)
+	}
+
+	sort.SliceStable(detection.Candidate, func(i, j int) bool {
+		return detection.Candidate[i].Confidence > detection.Candidate[j].Confidence
+	})
+	return detection, nil
+}
@@ -10,7 +10,6 @@ import (
 	"bytes"
 	"fmt"
 	"html/template"
-	"log"
 	"net/http"
 	"net/url"
 	"path"
@@ -32,8 +31,8 @@ type docPageLayout struct {
 	Navigation       []Link // Keeps an url and the text for a href
 }
 
-// user defined APIRoot and DocRoot are constant throughout program execution
-var docPage = docPageLayout{APIRoot: conf_apiroot(), DocRoot: conf_docroot()}
+// user defined APIRoot and DocRoot are constant throughout program execution, set by registerDocumentation
+var docPage docPageLayout
 
 func docHandler(w http.ResponseWriter, req *http.Request) {
 
@@ -41,8 +40,8 @@ func docHandler(w http.ResponseWriter, req *http.Request) {
 	defer func() {
 		if err := recover(); err != nil {
 			buf := fmt.Sprintf(httperrorstr, err)
-			log.Printf("%s", buf)
-			log.Printf("%s", debug.Stack())
+			logger.PrintfRequest(requestID(req), "%s", buf)
+			logger.PrintfRequest(requestID(req), "%s", debug.Stack())
 			http.Error(w, buf, http.StatusInternalServerError)
 		}
 	}()
@@ -75,7 +74,11 @@ func docHandler(w http.ResponseWriter, req *http.Request) {
 	buf.WriteTo(w)
 }
 
-func init() {
+// registerDocumentation registers the handler of the documentation at the configured documentation root, called by
+// main once the configuration is read
+func registerDocumentation() {
+	docPage.APIRoot, docPage.DocRoot = conf_apiroot(), conf_docroot()
+
 	// parse all REST handlers and create corresponding documentation links
 	for _, val := range httphandlerfuncs {
 		url, err := url.Parse(val.method + "/")
@@ -0,0 +1,80 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// RESTFul interface for coordinate transformations - errors of the restful methods
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/the42/cartconvert/cartconvert"
+	"net/http"
+	"strconv"
+)
+
+// A RequestError is an error of a restful method, which gets responded with the http status Code.
+// Input is the part of the request which caused the error, eg. the coordinate which failed to parse.
+type RequestError struct {
+	Code    int
+	Message string
+	Input   string
+}
+
+func (re *RequestError) Error() string {
+	return re.Message
+}
+
+// newRequestError returns a RequestError of the http status code. The message is formatted like fmt.Sprintf.
+func newRequestError(code int, input string, format string, a ...interface{}) error {
+	return &RequestError{Code: code, Message: fmt.Sprintf(format, a...), Input: input}
+}
+
+// badRequest returns the error of a malformed input, responded with http status 400
+func badRequest(input string, format string, a ...interface{}) error {
+	return newRequestError(http.StatusBadRequest, input, format, a...)
+}
+
+// notFound returns the error of an unknown coordinate system, output format or parameter set, responded with http status 404
+func notFound(input string, format string, a ...interface{}) error {
+	return newRequestError(http.StatusNotFound, input, format, a...)
+}
+
+// asRequestError returns err as RequestError. A NaN or infinite coordinate and a syntax error of the package are a
+// malformed input, responded with http status 400. Any other range error of the package, like a location outside of
+// the territory of the output format, is a well-formed input which can not be converted, responded with http status
+// 422. Errors of other types are internal errors, responded with http status 500.
+func asRequestError(err error) *RequestError {
+	if re, ok := err.(*RequestError); ok {
+		return re
+	}
+	switch {
+	case errors.Is(err, cartconvert.ErrNotFinite), errors.Is(err, cartconvert.ErrSyntax):
+		return &RequestError{Code: http.StatusBadRequest, Message: err.Error()}
+	case errors.Is(err, cartconvert.ErrRange):
+		return &RequestError{Code: http.StatusUnprocessableEntity, Message: err.Error()}
+	}
+	return &RequestError{Code: http.StatusInternalServerError, Message: err.Error()}
+}
+
+// respondError responds re as GEOConvertResponse encoded as JSON, regardless of the requested serialization
+// format. It is used for errors which prevent the response from being encoded in the requested format, like an
+// unsupported format or a panic. request is nil, if the error occurs before the request is parsed.
+func respondError(w http.ResponseWriter, req *http.Request, request *GEOConvertRequest, re *RequestError) {
+	response := &GEOConvertResponse{Status: re.Message, Code: re.Code, Error: true, APIVersion: APIVersion, GEOConvertRequest: request,
+		Input: re.Input}
+
+	buf, err := json.Marshal(response)
+	if err != nil {
+		logger.PrintfRequest(requestID(req), "Unable to encode error response: %s", err)
+		http.Error(w, re.Message, re.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	allowOrigin(w, req)
+	w.Header().Set("Content-Length", strconv.Itoa(len(buf)))
+	w.WriteHeader(re.Code)
+	w.Write(buf)
+}
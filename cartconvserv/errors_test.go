@@ -0,0 +1,40 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// Automated tests for the errors of the restful methods
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/the42/cartconvert/cartconvert"
+	"net/http"
+	"testing"
+)
+
+// ## asRequestError
+type asRequestErrorTest struct {
+	err  error
+	code int
+}
+
+var asRequestErrorTests = []asRequestErrorTest{
+	{badRequest("x", "bad"), http.StatusBadRequest},
+	{notFound("x", "unknown"), http.StatusNotFound},
+	{cartconvert.ErrSyntax, http.StatusBadRequest},
+	{cartconvert.ErrNotFinite, http.StatusBadRequest},
+	{cartconvert.CartographyError{Coord: "x", Err: cartconvert.ErrSyntax}, http.StatusBadRequest},
+	{cartconvert.ErrRange, http.StatusUnprocessableEntity},
+	{fmt.Errorf("outside of the grid: %w", cartconvert.ErrRange), http.StatusUnprocessableEntity},
+	{cartconvert.ErrNotFound, http.StatusInternalServerError},
+	{errors.New("internal"), http.StatusInternalServerError},
+}
+
+func TestAsRequestError(t *testing.T) {
+	for index, test := range asRequestErrorTests {
+		if re := asRequestError(test.err); re.Code != test.code || re.Message != test.err.Error() {
+			t.Errorf("asRequestError [%d]: expected status %d and message %s, got %d and %s", index, test.code, test.err, re.Code, re.Message)
+		}
+	}
+}
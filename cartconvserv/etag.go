@@ -0,0 +1,104 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// RESTFul interface for coordinate transformations - caching of responses by entity tags
+package main
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"github.com/the42/cartconvert/cartconvert"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// requestETag returns the entity tag of the response to req. Conversions are deterministic, so the tag is a hash of
+// the normalized request: the path, the query with its parameters sorted, and the content negotiated by Accept,
+// together with the configuration of the output, see outputConfiguration.
+func requestETag(req *http.Request) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n%s", req.URL.Path, req.URL.Query().Encode(), req.Header.Get("Accept"), outputConfiguration())
+	return `"` + hex.EncodeToString(h.Sum(nil)) + `"`
+}
+
+var (
+	outputConfigurationOnce sync.Once
+	outputConfigurationText string
+)
+
+// outputConfiguration returns the settings of the server which change the response to the same request: the API
+// version, the configured precision and decimal mark, and the registered helmert parameter sets, which are extended by
+// the configured helmert parameter file. The sets are registered by main before the first request, so the text is
+// built once.
+func outputConfiguration() string {
+	outputConfigurationOnce.Do(func() {
+		var buf bytes.Buffer
+		fmt.Fprintf(&buf, "%d\n%d\n%d\n", APIVersion, conf_precision(), conf_decimalmark())
+		for _, set := range cartconvert.HelmertParameterSets("", "") {
+			fmt.Fprintf(&buf, "%s %s %s %g %t %v", set.Name, set.From, set.To, set.Accuracy, set.IsDefault(), *set.HelmertTransform)
+			if set.Extent != nil {
+				fmt.Fprintf(&buf, " %v", *set.Extent)
+			}
+			buf.WriteByte('\n')
+		}
+		outputConfigurationText = buf.String()
+	})
+	return outputConfigurationText
+}
+
+// etagMatch reports whether the If-None-Match header value matches etag. The header may list several tags, which
+// are compared weakly, or be "*" for any tag.
+func etagMatch(ifNoneMatch, etag string) bool {
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// etagWriter drops the caching headers from responses other than 200 OK, so errors are never cached
+type etagWriter struct {
+	http.ResponseWriter
+}
+
+func (w etagWriter) WriteHeader(status int) {
+	if status != http.StatusOK {
+		w.Header().Del("ETag")
+		w.Header().Del("Cache-Control")
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// etagHandler tags the responses of GET and HEAD requests to the API with an entity tag and lets them be cached for
+// the configured seconds. A request whose If-None-Match matches the tag is responded with http status 304, without
+// passing it on to h. Without a configured cache duration, every request is passed on untagged.
+func etagHandler(h http.Handler) http.Handler {
+	maxage := conf_cachemaxage()
+	if maxage <= 0 {
+		return h
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if (req.Method != "GET" && req.Method != "HEAD") || !strings.HasPrefix(req.URL.Path, apirootLink) {
+			h.ServeHTTP(w, req)
+			return
+		}
+
+		etag := requestETag(req)
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d, public", maxage))
+		w.Header().Add("Vary", "Accept")
+
+		if match := req.Header.Get("If-None-Match"); match != "" && etagMatch(match, etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		h.ServeHTTP(etagWriter{w}, req)
+	})
+}
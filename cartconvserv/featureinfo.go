@@ -0,0 +1,158 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// RESTFul interface for coordinate transformations - WMS GetFeatureInfo style output
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/the42/cartconvert/cartconvert"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// The parameter InfoFormatSpec requests the response to be formatted like a WMS GetFeatureInfo response.
+// Like all WMS parameters, its name is case insensitive.
+const InfoFormatSpec = "info_format"
+
+// supported GetFeatureInfo formats
+const (
+	InfoFormatText = "text/plain"
+	InfoFormatGML  = "application/vnd.ogc.gml"
+)
+
+// A single attribute of a feature, named by the path of the field within the payload, eg. "UTMCoord.Easting".
+// Numeric is set for a floating point value.
+type featureAttribute struct {
+	Name, Value string
+	Numeric     bool
+}
+
+// getfirstValueFromURLParametersFold is like getfirstValueFromURLParameters but compares the key case insensitive
+func getfirstValueFromURLParametersFold(params []URLParameter, key string) (retval string) {
+	for _, parameter := range params {
+		if strings.EqualFold(parameter.Key, key) {
+			return parameter.Values[0]
+		}
+	}
+	return
+}
+
+// featureAttributes flattens the exported fields of v into a list of attributes
+func featureAttributes(name string, v reflect.Value, attrs []featureAttribute) []featureAttribute {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return attrs
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			fieldname := field.Name
+			if len(name) > 0 {
+				fieldname = name + "." + fieldname
+			}
+			attrs = featureAttributes(fieldname, v.Field(i), attrs)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			attrs = featureAttributes(fmt.Sprintf("%s.%d", name, i), v.Index(i), attrs)
+		}
+	case reflect.Float32, reflect.Float64:
+		attrs = append(attrs, featureAttribute{Name: name, Value: strconv.FormatFloat(v.Float(), 'f', -1, 64), Numeric: true})
+	default:
+		attrs = append(attrs, featureAttribute{Name: name, Value: fmt.Sprint(v.Interface())})
+	}
+	return attrs
+}
+
+// featureName returns the name of the feature type, which is the REST method without slashes
+func featureName(response *GEOConvertResponse) string {
+	if response.GEOConvertRequest == nil {
+		return "cartconvert"
+	}
+	return strings.Trim(response.GEOConvertRequest.Method, "/")
+}
+
+// featureInfoTextEncoder writes the response as a text/plain GetFeatureInfo response, the floating point values
+// with the decimal mark mark
+type featureInfoTextEncoder struct {
+	w    io.Writer
+	mark cartconvert.DecimalMark
+}
+
+func (enc *featureInfoTextEncoder) Encode(v interface{}) error {
+	response, ok := v.(*GEOConvertResponse)
+	if !ok {
+		return fmt.Errorf("Unable to encode %T as feature info", v)
+	}
+
+	if response.Error {
+		_, err := fmt.Fprintf(enc.w, "GetFeatureInfo error: %s\n", response.Status)
+		return err
+	}
+
+	const separator = "--------------------------------------------\n"
+	text := fmt.Sprintf("Results for FeatureType '%s':\n", featureName(response)) + separator
+	for _, attr := range featureAttributes("", reflect.ValueOf(response.Payload), nil) {
+		value := attr.Value
+		if attr.Numeric {
+			value = enc.mark.Localize(value)
+		}
+		text += attr.Name + " = " + value + "\n"
+	}
+	text += separator
+
+	_, err := io.WriteString(enc.w, text)
+	return err
+}
+
+// featureInfoGMLEncoder writes the response as an application/vnd.ogc.gml GetFeatureInfo response
+type featureInfoGMLEncoder struct {
+	w io.Writer
+}
+
+func xmlescape(val string) string {
+	var buf strings.Builder
+	xml.EscapeText(&buf, []byte(val))
+	return buf.String()
+}
+
+func (enc *featureInfoGMLEncoder) Encode(v interface{}) error {
+	response, ok := v.(*GEOConvertResponse)
+	if !ok {
+		return fmt.Errorf("Unable to encode %T as feature info", v)
+	}
+
+	gml := xml.Header
+	if response.Error {
+		gml += "<ServiceExceptionReport version=\"1.1.1\">\n" +
+			"  <ServiceException>" + xmlescape(response.Status) + "</ServiceException>\n" +
+			"</ServiceExceptionReport>\n"
+	} else {
+		name := featureName(response)
+		gml += "<msGMLOutput xmlns:gml=\"http://www.opengis.net/gml\">\n" +
+			"  <" + name + "_layer>\n" +
+			"    <" + name + "_feature>\n"
+		for _, attr := range featureAttributes("", reflect.ValueOf(response.Payload), nil) {
+			gml += "      <" + attr.Name + ">" + xmlescape(attr.Value) + "</" + attr.Name + ">\n"
+		}
+		gml += "    </" + name + "_feature>\n" +
+			"  </" + name + "_layer>\n" +
+			"</msGMLOutput>\n"
+	}
+
+	_, err := io.WriteString(enc.w, gml)
+	return err
+}
@@ -0,0 +1,119 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// RESTFul interface for coordinate transformations - GeoJSON output
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/the42/cartconvert/cartconvert"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// The serialization format GeoJSONFormatSpec encodes the result as GeoJSON Feature with the WGS84 location as Point
+const GeoJSONFormatSpec = ".geojson"
+
+// GeoJSON may also be requested by the parameter FormatSpec=geojson or by the Accept header GeoJSONMediaType.
+// The same holds for every serialization format with a media type, eg. KML.
+const (
+	FormatSpec       = "format"
+	GeoJSONMediaType = "application/geo+json"
+)
+
+type (
+	geoJSONGeometry struct {
+		Type        string    `json:"type"`
+		Coordinates []float64 `json:"coordinates"`
+	}
+
+	// Named coordinate reference system of the GeoJSON format of 2008. RFC 7946 dropped the member and
+	// fixed the coordinate reference system to WGS84, which some clients still expect to be stated.
+	geoJSONCRS struct {
+		Type       string            `json:"type"`
+		Properties map[string]string `json:"properties"`
+	}
+
+	// The properties of a feature carry the input of the conversion and its result
+	geoJSONProperties struct {
+		Method       string
+		Value        string
+		OutputFormat string      `json:",omitempty"`
+		Payload      interface{} `json:",omitempty"`
+		Provenance   *Provenance `json:",omitempty"`
+		GridAddress  string      `json:",omitempty"`
+		PlaceName    string      `json:",omitempty"`
+		Warning      string      `json:",omitempty"`
+		Accuracy     float64     `json:",omitempty"`
+		ParameterSet []string    `json:",omitempty"`
+	}
+
+	geoJSONFeature struct {
+		Type       string            `json:"type"`
+		CRS        geoJSONCRS        `json:"crs"`
+		Geometry   geoJSONGeometry   `json:"geometry"`
+		Properties geoJSONProperties `json:"properties"`
+	}
+)
+
+// wantsFormat reports whether the serialization format is requested by the parameter FormatSpec or its media type
+// by the Accept header, for requests without an explicit serialization format
+func wantsFormat(req *http.Request, request *GEOConvertRequest, serialformat, mediatype string) bool {
+	if strings.EqualFold(getfirstValueFromURLParameters(request.Parameters, FormatSpec), strings.TrimPrefix(serialformat, ".")) {
+		return true
+	}
+	for _, accept := range strings.Split(req.Header.Get("Accept"), ",") {
+		if accepted := strings.TrimSpace(strings.Split(accept, ";")[0]); strings.EqualFold(accepted, mediatype) {
+			return true
+		}
+	}
+	return false
+}
+
+// geoJSONPosition returns the position of a location, with the altitude as third element only if it is given
+func geoJSONPosition(location *cartconvert.PolarCoord, altitude *float64) []float64 {
+	if altitude != nil {
+		return []float64{location.Longitude, location.Latitude, *altitude}
+	}
+	return []float64{location.Longitude, location.Latitude}
+}
+
+// geoJSONEncoder writes the location of a conversion as GeoJSON Feature. Positions are given as [longitude, latitude]
+// as required by the GeoJSON specification. Responses without location, like errors or listings, are written as JSON.
+type geoJSONEncoder struct {
+	w io.Writer
+}
+
+func (enc *geoJSONEncoder) Encode(v interface{}) error {
+	response, ok := v.(*GEOConvertResponse)
+	if !ok {
+		return fmt.Errorf("Unable to encode %T as GeoJSON", v)
+	}
+
+	request := response.GEOConvertRequest
+	if response.Error || request == nil || request.location == nil {
+		return json.NewEncoder(enc.w).Encode(response)
+	}
+
+	feature := &geoJSONFeature{
+		Type:     "Feature",
+		CRS:      geoJSONCRS{Type: "name", Properties: map[string]string{"name": "urn:ogc:def:crs:OGC:1.3:CRS84"}},
+		Geometry: geoJSONGeometry{Type: "Point", Coordinates: geoJSONPosition(request.location, request.altitude)},
+		Properties: geoJSONProperties{
+			Method:       strings.Trim(request.Method, "/"),
+			Value:        request.Value,
+			OutputFormat: getfirstValueFromURLParameters(request.Parameters, OutputFormatSpec),
+			Payload:      response.Payload,
+			Provenance:   response.Provenance,
+			GridAddress:  response.GridAddress,
+			PlaceName:    response.PlaceName,
+			Warning:      response.Warning,
+			Accuracy:     response.Accuracy,
+			ParameterSet: response.ParameterSet,
+		},
+	}
+	return json.NewEncoder(enc.w).Encode(feature)
+}
@@ -0,0 +1,125 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// RESTFul interface for coordinate transformations - GPX output
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/the42/cartconvert/cartconvert"
+	"io"
+	"strings"
+)
+
+// The serialization format GPXFormatSpec encodes the WGS84 location of the result as GPX waypoint, to be read by
+// GPS devices. It may also be requested by the parameter FormatSpec=gpx or by the Accept header GPXMediaType.
+const (
+	GPXFormatSpec = ".gpx"
+	GPXMediaType  = "application/gpx+xml"
+)
+
+// The parameters GPXNameSpec and GPXDescriptionSpec set the name and description of the GPX document.
+// If GPXTrackSpec is "true", the locations are encoded as points of a single track instead of waypoints.
+const (
+	GPXNameSpec        = "name"
+	GPXDescriptionSpec = "desc"
+	GPXTrackSpec       = "track"
+)
+
+const (
+	gpxNamespace = "http://www.topografix.com/GPX/1/1"
+	gpxCreator   = "cartconvserv"
+)
+
+type (
+	gpxMetadata struct {
+		Name        string `xml:"name,omitempty"`
+		Description string `xml:"desc,omitempty"`
+	}
+
+	// A waypoint or a point of a track
+	gpxPoint struct {
+		Latitude    float64  `xml:"lat,attr"`
+		Longitude   float64  `xml:"lon,attr"`
+		Elevation   *float64 `xml:"ele,omitempty"`
+		Name        string   `xml:"name,omitempty"`
+		Description string   `xml:"desc,omitempty"`
+	}
+
+	gpxTrack struct {
+		Name   string     `xml:"name,omitempty"`
+		Points []gpxPoint `xml:"trkseg>trkpt"`
+	}
+
+	gpxDocument struct {
+		XMLName   xml.Name     `xml:"gpx"`
+		Version   string       `xml:"version,attr"`
+		Creator   string       `xml:"creator,attr"`
+		Namespace string       `xml:"xmlns,attr"`
+		Metadata  *gpxMetadata `xml:"metadata,omitempty"`
+		Waypoints []gpxPoint   `xml:"wpt"`
+		Track     *gpxTrack    `xml:"trk,omitempty"`
+	}
+)
+
+// newGPXPoint returns the point of a location. The name defaults to the method and the input value of the conversion.
+func newGPXPoint(location *cartconvert.PolarCoord, altitude *float64, method, value, name, description string) gpxPoint {
+	if name == "" {
+		name = strings.TrimSpace(strings.Trim(method, "/") + " " + value)
+	}
+	return gpxPoint{Latitude: location.Latitude, Longitude: location.Longitude, Elevation: altitude, Name: name, Description: description}
+}
+
+// gpxEncoder writes the location of a conversion as GPX waypoint, or the locations of a batch as one waypoint per
+// conversion, in the order of the batch. Failed conversions of a batch are left out. The elevation is the height
+// above sea level given by the input, eg. of a BMN coordinate. Responses without location, like errors or listings,
+// are written as XML.
+type gpxEncoder struct {
+	w io.Writer
+}
+
+func (enc *gpxEncoder) Encode(v interface{}) error {
+	response, ok := v.(*GEOConvertResponse)
+	if !ok {
+		return fmt.Errorf("Unable to encode %T as GPX", v)
+	}
+
+	request := response.GEOConvertRequest
+	if response.Error || request == nil {
+		return xml.NewEncoder(enc.w).Encode(response)
+	}
+
+	var points []gpxPoint
+	if batch, ok := response.Payload.(*Batch); ok {
+		for _, result := range batch.BatchResult {
+			if result.location != nil {
+				points = append(points, newGPXPoint(result.location, result.Altitude, result.Method, result.Value, result.name, result.description))
+			}
+		}
+	} else if request.location != nil {
+		points = append(points, newGPXPoint(request.location, request.altitude, request.Method, request.Value, "", ""))
+	} else {
+		return xml.NewEncoder(enc.w).Encode(response)
+	}
+
+	doc := &gpxDocument{Version: "1.1", Creator: gpxCreator, Namespace: gpxNamespace}
+	metadata := gpxMetadata{
+		Name:        getfirstValueFromURLParameters(request.Parameters, GPXNameSpec),
+		Description: getfirstValueFromURLParameters(request.Parameters, GPXDescriptionSpec),
+	}
+	if metadata != (gpxMetadata{}) {
+		doc.Metadata = &metadata
+	}
+	if getfirstValueFromURLParameters(request.Parameters, GPXTrackSpec) == "true" {
+		doc.Track = &gpxTrack{Name: metadata.Name, Points: points}
+	} else {
+		doc.Waypoints = points
+	}
+
+	if _, err := io.WriteString(enc.w, xml.Header); err != nil {
+		return err
+	}
+	return xml.NewEncoder(enc.w).Encode(doc)
+}
@@ -0,0 +1,181 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// RESTFul interface for coordinate transformations - gzip content encoding of responses
+package main
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Responses shorter than gzipMinLength bytes are sent uncompressed, as the gzip header and trailer alone take 18
+// bytes and compressing them gains next to nothing
+const gzipMinLength = 1024
+
+// gzipWriters holds the gzip writers of finished responses for reuse, as each allocates a sizable compressor
+var gzipWriters = sync.Pool{New: func() interface{} { return gzip.NewWriter(nil) }}
+
+// acceptsGzip reports whether the Accept-Encoding header value allows the gzip content coding, either by name or by
+// "*", and doesn't refuse it by a quality of 0. Naming gzip takes precedence over "*".
+func acceptsGzip(acceptEncoding string) bool {
+	accepted, named := false, false
+	for _, coding := range strings.Split(acceptEncoding, ",") {
+		params := strings.Split(coding, ";")
+		name := strings.ToLower(strings.TrimSpace(params[0]))
+		if (name != "gzip" && name != "*") || (name == "*" && named) {
+			continue
+		}
+		weight := 1.0
+		for _, param := range params[1:] {
+			if q := strings.TrimSpace(param); strings.HasPrefix(q, "q=") {
+				var err error
+				if weight, err = strconv.ParseFloat(q[2:], 64); err != nil {
+					weight = 0
+				}
+			}
+		}
+		accepted, named = weight > 0, name == "gzip"
+	}
+	return accepted
+}
+
+// gzipWriter holds back the body of a response until it is known to be long enough to be worth compressing, either
+// by its Content-Length or by gzipMinLength bytes written. Shorter responses are written unchanged by finish.
+type gzipWriter struct {
+	http.ResponseWriter
+	status  int
+	buf     []byte
+	gz      *gzip.Writer
+	decided bool
+}
+
+func (w *gzipWriter) WriteHeader(status int) {
+	if w.status != 0 {
+		return
+	}
+	w.status = status
+
+	h := w.Header()
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified ||
+		status == http.StatusPartialContent || h.Get("Content-Encoding") != "" {
+		w.pass()
+		return
+	}
+	if length := h.Get("Content-Length"); length != "" {
+		if n, err := strconv.Atoi(length); err != nil || n < gzipMinLength {
+			w.pass()
+		} else {
+			w.compress()
+		}
+	}
+}
+
+func (w *gzipWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	switch {
+	case w.gz != nil:
+		return w.gz.Write(b)
+	case w.decided:
+		return w.ResponseWriter.Write(b)
+	}
+
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= gzipMinLength {
+		w.compress()
+	}
+	return len(b), nil
+}
+
+// Flush sends what has been written so far. A response still held back is compressed, as a flushed response is
+// a stream of unknown length.
+func (w *gzipWriter) Flush() {
+	if w.status != 0 && !w.decided {
+		w.compress()
+	}
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, eg. to set the deadlines of a stream
+func (w *gzipWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// pass writes the header and what has been held back of the body uncompressed
+func (w *gzipWriter) pass() {
+	w.decided = true
+	w.ResponseWriter.WriteHeader(w.status)
+	if len(w.buf) > 0 {
+		w.ResponseWriter.Write(w.buf)
+		w.buf = nil
+	}
+}
+
+// compress writes the header of the gzip encoded response and compresses what has been held back of the body. The
+// content type is sniffed from the uncompressed body, if the handler didn't set one, and a strong entity tag is
+// weakened, as it is no longer the tag of the byte-identical uncompressed response.
+func (w *gzipWriter) compress() {
+	w.decided = true
+
+	h := w.Header()
+	if h.Get("Content-Type") == "" && len(w.buf) > 0 {
+		h.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		h.Set("ETag", "W/"+etag)
+	}
+	h.Del("Content-Length")
+	h.Set("Content-Encoding", "gzip")
+	w.ResponseWriter.WriteHeader(w.status)
+
+	w.gz = gzipWriters.Get().(*gzip.Writer)
+	w.gz.Reset(w.ResponseWriter)
+	if len(w.buf) > 0 {
+		w.gz.Write(w.buf)
+		w.buf = nil
+	}
+}
+
+// finish writes a response still held back uncompressed, or completes the gzip stream of a compressed one
+func (w *gzipWriter) finish() {
+	if w.gz != nil {
+		w.gz.Close()
+		gzipWriters.Put(w.gz)
+		w.gz = nil
+		return
+	}
+	if !w.decided && w.status != 0 {
+		w.pass()
+	}
+}
+
+// gzipHandler compresses the responses of the API with gzip, if the client accepts it by its Accept-Encoding header.
+// Responses shorter than gzipMinLength bytes, responses of HEAD requests and responses already content encoded are
+// sent unchanged. Every response of the API varies by Accept-Encoding, so caches keep both representations apart.
+func gzipHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !strings.HasPrefix(req.URL.Path, apirootLink) {
+			h.ServeHTTP(w, req)
+			return
+		}
+
+		w.Header().Add("Vary", "Accept-Encoding")
+		if req.Method == "HEAD" || !acceptsGzip(req.Header.Get("Accept-Encoding")) {
+			h.ServeHTTP(w, req)
+			return
+		}
+
+		gw := &gzipWriter{ResponseWriter: w}
+		defer gw.finish()
+		h.ServeHTTP(gw, req)
+	})
+}
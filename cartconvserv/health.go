@@ -0,0 +1,62 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// RESTFul interface for coordinate transformations - health check and version of the service
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"strconv"
+)
+
+// The version of the service, set at build time by
+//
+//	go build -ldflags "-X main.version=1.2.0"
+var version = "devel"
+
+type (
+	// A coordinate system supported by the service
+	VersionSystem struct {
+		Name, Method string
+	}
+
+	// The deployed version of the service and the version of the wire format of its responses
+	Version struct {
+		Version, GoVersion string
+		APIVersion         int
+		Systems            []VersionSystem
+	}
+)
+
+// healthzHandler tells load balancers and container orchestrators that the service is up
+func healthzHandler(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Content-Length", "3")
+	w.Write([]byte("ok\n"))
+}
+
+// versionHandler responds the version of the service and the coordinate systems it supports as JSON
+func versionHandler(w http.ResponseWriter, req *http.Request) {
+	v := &Version{Version: version, GoVersion: runtime.Version(), APIVersion: APIVersion}
+	for _, system := range systems.System {
+		v.Systems = append(v.Systems, VersionSystem{Name: system.Name, Method: system.Method})
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(len(b)))
+	w.Write(b)
+}
+
+func init() {
+	http.HandleFunc("/healthz", healthzHandler)
+	http.HandleFunc("/version", versionHandler)
+}
@@ -0,0 +1,102 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// RESTFul interface for coordinate transformations - KML output
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// The serialization format KMLFormatSpec encodes the result as KML Placemark with the WGS84 location as Point,
+// to be opened by Google Earth. It may also be requested by the parameter FormatSpec=kml or by the Accept header KMLMediaType.
+const (
+	KMLFormatSpec = ".kml"
+	KMLMediaType  = "application/vnd.google-earth.kml+xml"
+)
+
+const kmlNamespace = "http://www.opengis.net/kml/2.2"
+
+type (
+	kmlData struct {
+		Name  string `xml:"name,attr"`
+		Value string `xml:"value"`
+	}
+
+	kmlPoint struct {
+		Coordinates string `xml:"coordinates"`
+	}
+
+	kmlPlacemark struct {
+		Name         string    `xml:"name"`
+		ExtendedData []kmlData `xml:"ExtendedData>Data,omitempty"`
+		Point        kmlPoint  `xml:"Point"`
+	}
+
+	kmlDocument struct {
+		XMLName   xml.Name     `xml:"kml"`
+		Namespace string       `xml:"xmlns,attr"`
+		Placemark kmlPlacemark `xml:"Placemark"`
+	}
+)
+
+// kmlEncoder writes the location of a conversion as KML Placemark. The coordinates of the Point are given as
+// longitude, latitude and altitude, which is the height above sea level given by the input, eg. of a BMN coordinate,
+// and omitted otherwise. Responses without location, like errors or listings, are written as XML.
+type kmlEncoder struct {
+	w io.Writer
+}
+
+func (enc *kmlEncoder) Encode(v interface{}) error {
+	response, ok := v.(*GEOConvertResponse)
+	if !ok {
+		return fmt.Errorf("Unable to encode %T as KML", v)
+	}
+
+	request := response.GEOConvertRequest
+	if response.Error || request == nil || request.location == nil {
+		return xml.NewEncoder(enc.w).Encode(response)
+	}
+
+	coordinates := strconv.FormatFloat(request.location.Longitude, 'f', -1, 64) + "," +
+		strconv.FormatFloat(request.location.Latitude, 'f', -1, 64)
+	if request.altitude != nil {
+		coordinates += "," + strconv.FormatFloat(*request.altitude, 'f', -1, 64)
+	}
+
+	method := strings.Trim(request.Method, "/")
+	placemark := kmlPlacemark{
+		Name:  strings.TrimSpace(method + " " + request.Value),
+		Point: kmlPoint{Coordinates: coordinates},
+	}
+
+	var accuracy string
+	if response.Accuracy > 0 {
+		accuracy = strconv.FormatFloat(response.Accuracy, 'f', -1, 64)
+	}
+
+	for _, data := range []kmlData{
+		{"Method", method},
+		{"Value", request.Value},
+		{"OutputFormat", getfirstValueFromURLParameters(request.Parameters, OutputFormatSpec)},
+		{"GridAddress", response.GridAddress},
+		{"PlaceName", response.PlaceName},
+		{"Warning", response.Warning},
+		{"Accuracy", accuracy},
+		{"ParameterSet", strings.Join(response.ParameterSet, ", ")},
+	} {
+		if data.Value != "" {
+			placemark.ExtendedData = append(placemark.ExtendedData, data)
+		}
+	}
+
+	if _, err := io.WriteString(enc.w, xml.Header); err != nil {
+		return err
+	}
+	return xml.NewEncoder(enc.w).Encode(&kmlDocument{Namespace: kmlNamespace, Placemark: placemark})
+}
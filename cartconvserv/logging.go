@@ -0,0 +1,137 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// RESTFul interface for coordinate transformations - logging of messages and requests
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// The formats of the log
+const (
+	LogFormatPlain = "plain"
+	LogFormatJSON  = "json"
+)
+
+// requestRecord describes a request responded by the server
+type requestRecord struct {
+	Method  string
+	Path    string
+	Status  int
+	Latency time.Duration
+	IP      string
+	ID      string // ID of the request, see requestIDHandler
+}
+
+// serverLogger receives the messages and the requests of the server. Replacing logger routes the log elsewhere,
+// eg. to a file or to syslog.
+type serverLogger interface {
+	// Printf logs a message, formatted as by fmt.Printf
+	Printf(format string, v ...interface{})
+	// PrintfRequest logs a message concerning the request of id, formatted as by fmt.Printf
+	PrintfRequest(id, format string, v ...interface{})
+	// Request logs a responded request
+	Request(rec requestRecord)
+}
+
+// The logger of the server, writing plain lines to standard error until the configuration says otherwise
+var logger serverLogger = newLogger(os.Stderr, LogFormatPlain)
+
+// newLogger returns a logger writing to w in format, which is either LogFormatPlain or LogFormatJSON
+func newLogger(w io.Writer, format string) serverLogger {
+	if format == LogFormatJSON {
+		return &jsonLogger{w: w}
+	}
+	return &plainLogger{w: w}
+}
+
+// plainLogger writes a line of text per entry, prefixed by the time
+type plainLogger struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *plainLogger) println(line string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fmt.Fprintf(l.w, "%s %s\n", time.Now().UTC().Format(time.RFC3339), line)
+}
+
+func (l *plainLogger) Printf(format string, v ...interface{}) {
+	l.println(fmt.Sprintf(format, v...))
+}
+
+// The message is prefixed by the request ID in brackets
+func (l *plainLogger) PrintfRequest(id, format string, v ...interface{}) {
+	l.println("[" + id + "] " + fmt.Sprintf(format, v...))
+}
+
+func (l *plainLogger) Request(rec requestRecord) {
+	l.println(fmt.Sprintf("[%s] %s %s %s %d %s", rec.ID, rec.IP, rec.Method, rec.Path, rec.Status, rec.Latency))
+}
+
+// jsonLogger writes a JSON object per line
+type jsonLogger struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// jsonLogEntry is the JSON encoding of a log entry. A message carries Message, a request the other fields.
+type jsonLogEntry struct {
+	Time      string  `json:"time"`
+	Message   string  `json:"msg,omitempty"`
+	Method    string  `json:"method,omitempty"`
+	Path      string  `json:"path,omitempty"`
+	Status    int     `json:"status,omitempty"`
+	LatencyMS float64 `json:"latency_ms,omitempty"`
+	IP        string  `json:"ip,omitempty"`
+	RequestID string  `json:"request_id,omitempty"`
+}
+
+func (l *jsonLogger) encode(entry *jsonLogEntry) {
+	entry.Time = time.Now().UTC().Format(time.RFC3339Nano)
+	buf, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.w.Write(append(buf, '\n'))
+}
+
+func (l *jsonLogger) Printf(format string, v ...interface{}) {
+	l.encode(&jsonLogEntry{Message: fmt.Sprintf(format, v...)})
+}
+
+func (l *jsonLogger) PrintfRequest(id, format string, v ...interface{}) {
+	l.encode(&jsonLogEntry{Message: fmt.Sprintf(format, v...), RequestID: id})
+}
+
+func (l *jsonLogger) Request(rec requestRecord) {
+	l.encode(&jsonLogEntry{Method: rec.Method, Path: rec.Path, Status: rec.Status,
+		LatencyMS: float64(rec.Latency) / float64(time.Millisecond), IP: rec.IP, RequestID: rec.ID})
+}
+
+// requestLogHandler logs every request passed on to h, with its method, path, http status, the time taken to respond
+// it, the IP address of the client and the request ID
+func requestLogHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w}
+		h.ServeHTTP(sw, req)
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+		logger.Request(requestRecord{Method: req.Method, Path: req.URL.Path, Status: sw.status,
+			Latency: time.Since(start), IP: clientIP(req), ID: requestID(req)})
+	})
+}
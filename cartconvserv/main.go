@@ -8,39 +8,26 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"github.com/the42/cartconvert/cartconvert"
 	"html/template"
-	"log"
+	"io/ioutil"
 	"net/http"
+	"os"
+	"os/signal"
 	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 )
 
 const templateroot = "./template/"
 const mainTemplate = "index.tpl"
 
-type logRecord struct {
-	http.ResponseWriter
-
-	time                time.Time
-	ip, method, rawpath string
-	responseBytes       int64
-	responseStatus      int
-	userAgent, referer  string
-	proto               string // "HTTP/1.1"
-}
-
-// Wrapper arround DefaultServeMux, inspired by
-func Log(handler http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// TODO: add more information
-		logr := &logRecord{time: time.Now().UTC(), ip: r.RemoteAddr}
-		// TODO: make the logfile format compatible with eg. apache
-		log.Printf("%s %s", logr.time, logr.ip)
-		handler.ServeHTTP(w, r)
-	})
-}
-
 func rootHandler(w http.ResponseWriter, req *http.Request) {
 	tpl := template.Must(template.ParseFiles(templateroot + mainTemplate))
 	rootpage := &apidocpageLayout{APIRoot: apirootLink, DOCRoot: docrootLink}
@@ -63,9 +50,154 @@ func maxAgeHandler(seconds int, h http.Handler) http.Handler {
 	})
 }
 
+// Returns the address of the server listening at binding, which is either a port or a host and port
+func listenAddress(binding string) string {
+	if strings.Contains(binding, ":") {
+		return binding
+	}
+	return ":" + binding
+}
+
+// loadHelmertParameterFile registers the helmert parameter sets of the JSON file filename, see
+// cartconvert.LoadHelmertParameterSets. Returns an error naming the file, if it can not be read or is malformed,
+// stating line and column of a syntax error like readConfig.
+func loadHelmertParameterFile(filename string) error {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	sets, err := cartconvert.LoadHelmertParameterSets(bytes.NewReader(b))
+	if err != nil {
+		var offset int64
+		switch err := err.(type) {
+		case *json.SyntaxError:
+			offset = err.Offset
+		case *json.UnmarshalTypeError:
+			offset = err.Offset
+		default:
+			return fmt.Errorf("%s: %s", filename, err)
+		}
+		line, column := textPosition(b, offset-1)
+		return fmt.Errorf("%s:%d:%d: %s", filename, line, column, err)
+	}
+	for _, set := range sets {
+		logger.Printf("Registered helmert parameter set %s from %s to %s of %s", set.Name, set.From, set.To, filename)
+	}
+	return nil
+}
+
+// serverHandler wraps h by the handlers every request passes, from the outermost: the request ID, the log, the
+// normalization of the path, the metrics, the rate limit, CORS, the API key, gzip and entity tags. The handlers
+// read the configuration when wrapping.
+func serverHandler(h http.Handler) http.Handler {
+	return requestIDHandler(requestLogHandler(apiPathHandler(metricsHandler(rateLimitHandler(corsHandler(apiKeyHandler(gzipHandler(etagHandler(h)))))))))
+}
+
+// fatalf logs a message and exits with a non-zero status
+func fatalf(format string, v ...interface{}) {
+	logger.Printf(format, v...)
+	os.Exit(1)
+}
+
+// The time granted to requests in flight to complete, once the server is asked to shut down
+const shutdownTimeout = 30 * time.Second
+
 func main() {
 
+	flag.Parse()
+	if err := conf_err(); err != nil {
+		fatalf("Unable to read configuration: %s", err)
+	}
+
+	logFile, logFormat := conf_log()
+	if logFormat != LogFormatPlain && logFormat != LogFormatJSON {
+		fatalf("Unknown log format '%s', expected '%s' or '%s'", logFormat, LogFormatPlain, LogFormatJSON)
+	}
+	logWriter := os.Stderr
+	if logFile != "" {
+		f, err := os.OpenFile(logFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			fatalf("Unable to open log file: %s", err)
+		}
+		defer f.Close()
+		logWriter = f
+	}
+	logger = newLogger(logWriter, logFormat)
+
+	if filename := conf_helmertparameterfile(); filename != "" {
+		if err := loadHelmertParameterFile(filename); err != nil {
+			fatalf("Unable to load helmert parameter sets: %s", err)
+		}
+	}
+
+	registerAPI()
+	registerDocumentation()
+	setupConversionCache()
 	http.HandleFunc("/", rootHandler)
 	http.Handle("/static/", maxAgeHandler(conf_statictimeout(), http.StripPrefix("/static/", http.FileServer(http.Dir("static")))))
-	http.ListenAndServe(":"+conf_binding(), Log(http.DefaultServeMux))
+
+	// serve HTTPS only if both the certificate and its key are configured; one without the other is a mistake
+	certFile, keyFile := conf_tls()
+	if (certFile == "") != (keyFile == "") {
+		fatalf("TLS requires both CertFile and KeyFile to be configured")
+	}
+
+	// every binding is served by a server of its own, all sharing the same handlers
+	handler := serverHandler(http.DefaultServeMux)
+	read, write, idle := conf_timeouts()
+
+	var servers []*http.Server
+	for _, binding := range conf_bindings() {
+		servers = append(servers, &http.Server{
+			Addr:         listenAddress(binding),
+			Handler:      handler,
+			ReadTimeout:  read,
+			WriteTimeout: write,
+			IdleTimeout:  idle,
+		})
+	}
+
+	// on SIGINT or SIGTERM stop accepting connections and drain the requests in flight of every server
+	done := make(chan struct{})
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		logger.Printf("Received %s, shutting down", <-signals)
+
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		var wg sync.WaitGroup
+		for _, server := range servers {
+			wg.Add(1)
+			go func(server *http.Server) {
+				defer wg.Done()
+				if err := server.Shutdown(ctx); err != nil {
+					logger.Printf("Unable to shut down %s gracefully: %s", server.Addr, err)
+				}
+			}(server)
+		}
+		wg.Wait()
+		close(done)
+	}()
+
+	// a server failing to listen takes down the others, rather than leaving the service partially reachable
+	errs := make(chan error, len(servers))
+	for _, server := range servers {
+		go func(server *http.Server) {
+			logger.Printf("Listening at %s", server.Addr)
+			if certFile != "" {
+				errs <- server.ListenAndServeTLS(certFile, keyFile)
+			} else {
+				errs <- server.ListenAndServe()
+			}
+		}(server)
+	}
+	for range servers {
+		if err := <-errs; err != http.ErrServerClosed {
+			fatalf("%s", err)
+		}
+	}
+	<-done
 }
@@ -0,0 +1,249 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// +build !appengine
+
+// Automated tests of the handlers every request passes
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var registerTestAPI sync.Once
+
+// testServer returns the handlers of the server wrapped by serverHandler, configured like the defaults modified by
+// configure. The API is rooted at /api. The configuration is restored by the returned function.
+func testServer(configure func(*config)) (http.Handler, func()) {
+	confOnce.Do(func() {
+		conf = &config{APIRoot: "/api", DocRoot: "/doc", Precision: -1, MaxBatchSize: 1000, MaxBodySize: 1 << 20,
+			RequestTimeout: 10, LogFormat: LogFormatPlain, DecimalMark: "."}
+	})
+	registerTestAPI.Do(registerAPI)
+	logger = newLogger(ioutil.Discard, LogFormatPlain)
+
+	saved := *conf
+	configure(conf)
+	return serverHandler(http.DefaultServeMux), func() { *conf = saved }
+}
+
+// serve responds a request of method and target with the request headers header
+func serve(h http.Handler, method, target string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+// ## serverHandler
+type serverHandlerTest struct {
+	name   string
+	method string
+	target string
+	header map[string]string
+	status int
+}
+
+const latlongTarget = "/api/latlong/.json?lat=47.57&long=14.0075&outputformat=utm"
+
+var serverHandlerTests = []serverHandlerTest{
+	{"conversion", "GET", latlongTarget, nil, http.StatusOK},
+	{"repeated slashes", "GET", "/api//latlong/./.json?lat=47.57&long=14.0075&outputformat=utm", nil, http.StatusOK},
+	{"integer bearings", "GET", "/api/latlong/.json?lat=70&long=14&outputformat=utm", nil, http.StatusOK},
+	{"malformed bearing", "GET", "/api/latlong/.json?lat=north&long=14&outputformat=utm", nil, http.StatusBadRequest},
+	{"malformed quantization", "GET", "/api/latlong/.topojson?lat=47.57&long=14.0075&quantization=1", nil, http.StatusBadRequest},
+	{"outside of the grid", "GET", "/api/latlong/.json?lat=47.57&long=14.0075&outputformat=osgb", nil, http.StatusUnprocessableEntity},
+	{"unknown output format", "GET", "/api/latlong/.json?lat=47.57&long=14.0075&outputformat=none", nil, http.StatusNotFound},
+	{"unknown method", "GET", "/api/none/.json", nil, http.StatusNotFound},
+	{"unsupported serialization", "GET", "/api/latlong/.none?lat=47.57&long=14.0075", nil, http.StatusBadRequest},
+}
+
+func TestServerHandler(t *testing.T) {
+	h, restore := testServer(func(*config) {})
+	defer restore()
+
+	for _, test := range serverHandlerTests {
+		w := serve(h, test.method, test.target, test.header)
+		if w.Code != test.status {
+			t.Errorf("serverHandler %s: expected status %d, got %d: %s", test.name, test.status, w.Code, w.Body)
+		}
+		if w.Header().Get(RequestIDHeader) == "" {
+			t.Errorf("serverHandler %s: expected a request ID", test.name)
+		}
+
+		var response GEOConvertResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil && strings.HasSuffix(strings.SplitN(test.target, "?", 2)[0], ".json") {
+			t.Errorf("serverHandler %s: expected a JSON response, got %s", test.name, w.Body)
+		}
+		if w.Code != http.StatusOK && (!response.Error || response.Code != w.Code) {
+			t.Errorf("serverHandler %s: expected the error status %d in the response, got %d", test.name, w.Code, response.Code)
+		}
+	}
+
+	// a request ID of the client is kept
+	if w := serve(h, "GET", latlongTarget, map[string]string{RequestIDHeader: "trace-42"}); w.Header().Get(RequestIDHeader) != "trace-42" {
+		t.Errorf("serverHandler: expected the request ID trace-42, got %s", w.Header().Get(RequestIDHeader))
+	}
+}
+
+// ## apiKeyHandler
+func TestServerHandlerAPIKey(t *testing.T) {
+	h, restore := testServer(func(c *config) { c.APIKeys = []string{"secret"} })
+	defer restore()
+
+	for _, test := range []serverHandlerTest{
+		{"missing key", "GET", latlongTarget, nil, http.StatusUnauthorized},
+		{"wrong key", "GET", latlongTarget, map[string]string{APIKeyHeader: "guess"}, http.StatusUnauthorized},
+		{"key by header", "GET", latlongTarget, map[string]string{APIKeyHeader: "secret"}, http.StatusOK},
+		{"key by parameter", "GET", latlongTarget + "&apikey=secret", nil, http.StatusOK},
+		{"documentation", "GET", "/doc/none", nil, http.StatusNotFound},
+	} {
+		w := serve(h, test.method, test.target, test.header)
+		if w.Code != test.status {
+			t.Errorf("apiKeyHandler %s: expected status %d, got %d", test.name, test.status, w.Code)
+		}
+		if strings.Contains(w.Body.String(), "secret") {
+			t.Errorf("apiKeyHandler %s: expected the key not to be echoed, got %s", test.name, w.Body)
+		}
+	}
+}
+
+// ## etagHandler
+func TestServerHandlerETag(t *testing.T) {
+	h, restore := testServer(func(c *config) { c.CacheMaxAge = 60 })
+	defer restore()
+
+	w := serve(h, "GET", latlongTarget, nil)
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag == "" || w.Header().Get("Cache-Control") != "max-age=60, public" {
+		t.Fatalf("etagHandler: expected a tagged response, got status %d, ETag %s, Cache-Control %s", w.Code, etag, w.Header().Get("Cache-Control"))
+	}
+
+	// the tag depends on the request only
+	if other := serve(h, "GET", latlongTarget, nil).Header().Get("ETag"); other != etag {
+		t.Errorf("etagHandler: expected the same tag %s for the same request, got %s", etag, other)
+	}
+	if other := serve(h, "GET", latlongTarget+"&outputformat=geohash", nil).Header().Get("ETag"); other == etag {
+		t.Errorf("etagHandler: expected another tag for another request, got %s", other)
+	}
+
+	for _, test := range []struct {
+		match  string
+		status int
+	}{
+		{etag, http.StatusNotModified},
+		{"W/" + etag, http.StatusNotModified},
+		{`"other", ` + etag, http.StatusNotModified},
+		{"*", http.StatusNotModified},
+		{`"other"`, http.StatusOK},
+	} {
+		w := serve(h, "GET", latlongTarget, map[string]string{"If-None-Match": test.match})
+		if w.Code != test.status {
+			t.Errorf("etagHandler: expected status %d for If-None-Match %s, got %d", test.status, test.match, w.Code)
+		}
+		if test.status == http.StatusNotModified && w.Body.Len() != 0 {
+			t.Errorf("etagHandler: expected no body for If-None-Match %s, got %s", test.match, w.Body)
+		}
+	}
+
+	// errors are never cached
+	w = serve(h, "GET", "/api/latlong/.json?lat=north&long=14", nil)
+	if w.Code != http.StatusBadRequest || w.Header().Get("ETag") != "" || w.Header().Get("Cache-Control") != "" {
+		t.Errorf("etagHandler: expected an untagged error, got status %d, ETag %s", w.Code, w.Header().Get("ETag"))
+	}
+}
+
+// ## gzipHandler
+func TestServerHandlerGzip(t *testing.T) {
+	h, restore := testServer(func(c *config) { c.CacheMaxAge = 60 })
+	defer restore()
+
+	// the listing of the coordinate systems exceeds gzipMinLength, a single conversion does not
+	const listing = "/api/systems/.json"
+	plain := serve(h, "GET", listing, nil)
+	if plain.Code != http.StatusOK || plain.Body.Len() < gzipMinLength || plain.Header().Get("Content-Encoding") != "" {
+		t.Fatalf("gzipHandler: expected an uncompressed listing, got status %d, %d bytes, encoding %s", plain.Code, plain.Body.Len(), plain.Header().Get("Content-Encoding"))
+	}
+
+	w := serve(h, "GET", listing, map[string]string{"Accept-Encoding": "gzip"})
+	if w.Header().Get("Content-Encoding") != "gzip" || !strings.Contains(w.Header().Get("Vary"), "Accept-Encoding") {
+		t.Fatalf("gzipHandler: expected a gzip encoded response varying by Accept-Encoding, got encoding %s, Vary %s", w.Header().Get("Content-Encoding"), w.Header().Get("Vary"))
+	}
+	if etag := w.Header().Get("ETag"); etag != "W/"+plain.Header().Get("ETag") {
+		t.Errorf("gzipHandler: expected the weakened tag of the uncompressed response, got %s", etag)
+	}
+	gz, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("gzipHandler: %s", err)
+	}
+	body, err := ioutil.ReadAll(gz)
+	if err != nil || string(body) != plain.Body.String() {
+		t.Errorf("gzipHandler: expected the uncompressed listing, got %d bytes (%v)", len(body), err)
+	}
+
+	for _, test := range []struct {
+		target, encoding string
+	}{
+		{latlongTarget, "gzip"},
+		{listing, "gzip;q=0"},
+		{listing, "identity"},
+	} {
+		if w := serve(h, "GET", test.target, map[string]string{"Accept-Encoding": test.encoding}); w.Code != http.StatusOK || w.Header().Get("Content-Encoding") != "" {
+			t.Errorf("gzipHandler: expected an uncompressed response of %s for Accept-Encoding %s, got status %d, encoding %s",
+				test.target, test.encoding, w.Code, w.Header().Get("Content-Encoding"))
+		}
+	}
+}
+
+// ## rateLimitHandler
+func TestServerHandlerRateLimit(t *testing.T) {
+	h, restore := testServer(func(c *config) { c.RateLimit, c.RateBurst = 0.001, 2 })
+	defer restore()
+
+	// httptest requests come from 192.0.2.1, every client has a bucket of its own
+	for i, status := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		w := serve(h, "GET", latlongTarget, nil)
+		if w.Code != status {
+			t.Errorf("rateLimitHandler [%d]: expected status %d, got %d", i, status, w.Code)
+		}
+		if status == http.StatusTooManyRequests && w.Header().Get("Retry-After") == "" {
+			t.Errorf("rateLimitHandler [%d]: expected Retry-After", i)
+		}
+	}
+
+	req := httptest.NewRequest("GET", latlongTarget, nil)
+	req.RemoteAddr = "192.0.2.2:1234"
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("rateLimitHandler: expected status 200 for another client, got %d", w.Code)
+	}
+}
+
+// ## corsHandler
+func TestServerHandlerCORS(t *testing.T) {
+	h, restore := testServer(func(c *config) { c.AllowedOrigins = []string{"https://example.org"} })
+	defer restore()
+
+	preflight := map[string]string{"Origin": "https://example.org", "Access-Control-Request-Method": "POST"}
+	w := serve(h, "OPTIONS", "/api/batch/.json", preflight)
+	if w.Code != http.StatusNoContent || w.Header().Get("Access-Control-Allow-Origin") != "https://example.org" {
+		t.Errorf("corsHandler: expected the preflight request granted, got status %d, origin %s", w.Code, w.Header().Get("Access-Control-Allow-Origin"))
+	}
+
+	preflight["Origin"] = "https://example.com"
+	if w := serve(h, "OPTIONS", "/api/batch/.json", preflight); w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("corsHandler: expected the preflight request of another origin denied, got origin %s", w.Header().Get("Access-Control-Allow-Origin"))
+	}
+}
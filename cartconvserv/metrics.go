@@ -0,0 +1,238 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// RESTFul interface for coordinate transformations - metrics of the requests in the Prometheus text format
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The media type of the Prometheus text exposition format
+const MetricsMediaType = "text/plain; version=0.0.4; charset=utf-8"
+
+// The upper bounds in seconds of the buckets of the histogram of request durations
+var durationBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// The labels of a metric: the coordinate system or method requested, the output format and the http status
+type metricLabels struct {
+	source, target, status string
+}
+
+func (l metricLabels) String() string {
+	return fmt.Sprintf(`source="%s",target="%s",status="%s"`, escapeLabel(l.source), escapeLabel(l.target), escapeLabel(l.status))
+}
+
+// A histogram of durations, counted per bucket of durationBuckets and not cumulated
+type histogram struct {
+	buckets []uint64
+	sum     float64
+	count   uint64
+}
+
+// The metrics of the requests to the API, by the labels of the requests
+type metrics struct {
+	mu        sync.Mutex
+	requests  map[metricLabels]uint64
+	errors    map[metricLabels]uint64
+	durations map[metricLabels]*histogram
+	// conversions responded from the cache of conversions and converted anew
+	cacheHits, cacheMisses uint64
+}
+
+func newMetrics() *metrics {
+	return &metrics{requests: make(map[metricLabels]uint64), errors: make(map[metricLabels]uint64), durations: make(map[metricLabels]*histogram)}
+}
+
+var apimetrics = newMetrics()
+
+// observe counts a request of labels, which took duration to respond
+func (m *metrics) observe(labels metricLabels, status int, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests[labels]++
+	if status >= http.StatusBadRequest {
+		m.errors[labels]++
+	}
+
+	h, ok := m.durations[labels]
+	if !ok {
+		h = &histogram{buckets: make([]uint64, len(durationBuckets))}
+		m.durations[labels] = h
+	}
+	seconds := duration.Seconds()
+	if i := sort.SearchFloat64s(durationBuckets, seconds); i < len(durationBuckets) {
+		h.buckets[i]++
+	}
+	h.sum += seconds
+	h.count++
+}
+
+// observeCache counts a conversion looked up in the cache of conversions
+func (m *metrics) observeCache(hit bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if hit {
+		m.cacheHits++
+	} else {
+		m.cacheMisses++
+	}
+}
+
+// sortedLabels returns the labels of a metric in a stable order, so the exposition doesn't change between scrapes
+func sortedLabels(keys []metricLabels) []metricLabels {
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].source != keys[j].source {
+			return keys[i].source < keys[j].source
+		}
+		if keys[i].target != keys[j].target {
+			return keys[i].target < keys[j].target
+		}
+		return keys[i].status < keys[j].status
+	})
+	return keys
+}
+
+func writeCounter(w io.Writer, name, help string, counter map[metricLabels]uint64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+
+	var keys []metricLabels
+	for labels := range counter {
+		keys = append(keys, labels)
+	}
+	for _, labels := range sortedLabels(keys) {
+		fmt.Fprintf(w, "%s{%s} %d\n", name, labels, counter[labels])
+	}
+}
+
+// writeTo writes the metrics in the Prometheus text exposition format
+func (m *metrics) writeTo(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	writeCounter(w, "cartconvert_requests_total", "Requests to the API.", m.requests)
+	writeCounter(w, "cartconvert_request_errors_total", "Requests to the API responded with an http status of 400 or above.", m.errors)
+
+	if conversions != nil {
+		fmt.Fprintf(w, "# HELP cartconvert_conversion_cache_hits_total Conversions responded from the cache of conversions.\n# TYPE cartconvert_conversion_cache_hits_total counter\n")
+		fmt.Fprintf(w, "cartconvert_conversion_cache_hits_total %d\n", m.cacheHits)
+		fmt.Fprintf(w, "# HELP cartconvert_conversion_cache_misses_total Conversions not found in the cache of conversions.\n# TYPE cartconvert_conversion_cache_misses_total counter\n")
+		fmt.Fprintf(w, "cartconvert_conversion_cache_misses_total %d\n", m.cacheMisses)
+	}
+
+	const name = "cartconvert_request_duration_seconds"
+	fmt.Fprintf(w, "# HELP %s Time to respond to requests to the API.\n# TYPE %s histogram\n", name, name)
+
+	var keys []metricLabels
+	for labels := range m.durations {
+		keys = append(keys, labels)
+	}
+	for _, labels := range sortedLabels(keys) {
+		h := m.durations[labels]
+		var cumulative uint64
+		for i, bound := range durationBuckets {
+			cumulative += h.buckets[i]
+			fmt.Fprintf(w, "%s_bucket{%s,le=\"%s\"} %d\n", name, labels, strconv.FormatFloat(bound, 'f', -1, 64), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, h.count)
+		fmt.Fprintf(w, "%s_sum{%s} %s\n", name, labels, strconv.FormatFloat(h.sum, 'f', -1, 64))
+		fmt.Fprintf(w, "%s_count{%s} %d\n", name, labels, h.count)
+	}
+}
+
+// escapes label values as required by the exposition format
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+// requestLabels returns the labels of req. Only the known methods and output formats become label values, so
+// arbitrary requests can not blow up the number of metrics.
+func requestLabels(req *http.Request, status int) metricLabels {
+	source := "unknown"
+	method := "/" + strings.SplitN(strings.TrimPrefix(req.URL.Path, apirootLink+"/"), "/", 2)[0]
+	if _, ok := dispatch(method); ok || method == StreamMethod || method == CSVMethod {
+		source = strings.TrimPrefix(method, "/")
+	}
+
+	target := req.URL.Query().Get(OutputFormatSpec)
+	if format, ok := epsgOutputFormat(target); ok {
+		target = format
+	}
+	switch target {
+	case "", OFlatlongdeg, OFlatlongcomma, OFgeohash, OFUTM, OFUPS, OFBMN, OFOSGB:
+	default:
+		target = "unknown"
+	}
+	return metricLabels{source: source, target: target, status: strconv.Itoa(status)}
+}
+
+// statusWriter records the http status of the response
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, eg. to flush a stream
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// metricsHandler counts the requests to the API passed on to h and the time taken to respond them
+func metricsHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !strings.HasPrefix(req.URL.Path, apirootLink+"/") {
+			h.ServeHTTP(w, req)
+			return
+		}
+
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w}
+		h.ServeHTTP(sw, req)
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+		apimetrics.observe(requestLabels(req, sw.status), sw.status, time.Since(start))
+	})
+}
+
+// exposeMetricsHandler responds the metrics of the requests to the API in the Prometheus text exposition format
+func exposeMetricsHandler(w http.ResponseWriter, req *http.Request) {
+	buf := new(bytes.Buffer)
+	apimetrics.writeTo(buf)
+
+	w.Header().Set("Content-Type", MetricsMediaType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	buf.WriteTo(w)
+}
+
+func init() {
+	http.HandleFunc("/metrics", exposeMetricsHandler)
+}
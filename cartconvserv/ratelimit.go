@@ -0,0 +1,106 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// RESTFul interface for coordinate transformations - rate limiting of clients
+package main
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// The interval at which the buckets of clients which didn't send requests for a while are dropped
+const rateLimitSweepInterval = time.Minute
+
+// A token bucket of a client, holding up to burst tokens which are refilled at the rate of the limiter
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// A rateLimiter grants every client, identified by its IP address, rate requests per second on average and
+// up to burst requests at once.
+type rateLimiter struct {
+	rate  float64
+	burst float64
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = int(math.Max(1, math.Ceil(rate)))
+	}
+	return &rateLimiter{rate: rate, burst: float64(burst), buckets: make(map[string]*tokenBucket), lastSweep: time.Now()}
+}
+
+// allow takes a token from the bucket of client at time now. If the bucket is empty, it returns false and the
+// time until the next token is available.
+func (rl *rateLimiter) allow(client string, now time.Time) (bool, time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if now.Sub(rl.lastSweep) >= rateLimitSweepInterval {
+		rl.sweep(now)
+	}
+
+	bucket, ok := rl.buckets[client]
+	if !ok {
+		bucket = &tokenBucket{tokens: rl.burst, last: now}
+		rl.buckets[client] = bucket
+	}
+
+	bucket.tokens = math.Min(rl.burst, bucket.tokens+now.Sub(bucket.last).Seconds()*rl.rate)
+	bucket.last = now
+	if bucket.tokens < 1 {
+		return false, time.Duration((1 - bucket.tokens) / rl.rate * float64(time.Second))
+	}
+	bucket.tokens--
+	return true, 0
+}
+
+// sweep drops the buckets which would be refilled completely by now, as they make no difference to a new bucket
+func (rl *rateLimiter) sweep(now time.Time) {
+	for client, bucket := range rl.buckets {
+		if bucket.tokens+now.Sub(bucket.last).Seconds()*rl.rate >= rl.burst {
+			delete(rl.buckets, client)
+		}
+	}
+	rl.lastSweep = now
+}
+
+// clientIP returns the IP address of the client of req, without the port
+func clientIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+// rateLimitHandler passes a request on to h, if its client didn't exceed the configured rate limit. Otherwise the
+// request is responded with http status 429 and the seconds to wait in Retry-After. Without a configured rate,
+// every request is passed on.
+func rateLimitHandler(h http.Handler) http.Handler {
+	rate, burst := conf_ratelimit()
+	if rate <= 0 {
+		return h
+	}
+
+	limiter := newRateLimiter(rate, burst)
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if ok, wait := limiter.allow(clientIP(req), time.Now()); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+		h.ServeHTTP(w, req)
+	})
+}
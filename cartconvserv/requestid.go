@@ -0,0 +1,66 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// RESTFul interface for coordinate transformations - request IDs correlating responses and log lines
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// The request and response header carrying the ID of a request
+const RequestIDHeader = "X-Request-ID"
+
+// The longest request ID taken from a client, longer ones are replaced
+const maxRequestIDLength = 128
+
+// requestIDKey is the key of the request ID within the context of a request
+type requestIDKey struct{}
+
+// validRequestID reports whether id may be taken as request ID: not empty, not too long and consisting of printable
+// ASCII characters only, so it can't break up a log line
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID returns a random request ID of 32 hex digits
+func newRequestID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(buf)
+}
+
+// requestID returns the ID of req set by requestIDHandler, empty if there is none
+func requestID(req *http.Request) string {
+	id, _ := req.Context().Value(requestIDKey{}).(string)
+	return id
+}
+
+// requestIDHandler passes every request on to h with an ID, which is included in the response header X-Request-ID
+// and in the log lines of the request. The ID is taken from the request header X-Request-ID, so a request can be
+// traced across services, or generated, if the client doesn't send a valid one.
+func requestIDHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		id := req.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		h.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), requestIDKey{}, id)))
+	})
+}
@@ -0,0 +1,97 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// RESTFul interface for coordinate transformations - self-consistency check of a single conversion
+package main
+
+import (
+	"encoding/xml"
+	"github.com/the42/cartconvert/cartconvert"
+	"github.com/the42/cartconvert/cartconvert/bmn"
+	"github.com/the42/cartconvert/cartconvert/osgb36"
+	"net/http"
+)
+
+// --------------------------------------------------------------------
+// Serialization struct definitions
+type (
+	// The round trip of a WGS84 latitude / longitude into the target system of the output format and back.
+	// Residual is the ground distance in meters between the input and the location converted back. ParameterSet
+	// and Accuracy are the helmert parameter set of the datum shift and its advertised accuracy in meters, if the
+	// target system is based on another datum.
+	RoundTrip struct {
+		Target       string
+		Coord        string
+		LatLong      *cartconvert.PolarCoord
+		Residual     float64
+		ParameterSet string  `json:",omitempty" xml:",omitempty"`
+		Accuracy     float64 `json:",omitempty" xml:",omitempty"`
+	}
+)
+
+// Implements json.Marshaler, writing the residual in fixed-point notation
+func (result *RoundTrip) MarshalJSON() ([]byte, error) {
+	return cartconvert.MarshalFixedJSON(result)
+}
+
+// Implements xml.Marshaler, writing the residual in fixed-point notation
+func (result *RoundTrip) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return cartconvert.MarshalFixedXML(e, start, result)
+}
+
+// roundtripHandler converts the latitude / longitude given by the parameters of the method latlong into the target
+// system of the output format and back, using the same helmert parameter set in both directions. The target
+// coordinate is converted back at full precision, so the residual is the error of the transformation itself, not
+// the rounding of its literal.
+func roundtripHandler(req *GEOConvertRequest, value, oformat string) (interface{}, error) {
+
+	target, err := latlongHandler(req, value, oformat)
+	if err != nil {
+		return nil, err
+	}
+	latlong := req.location
+
+	result := &RoundTrip{Target: oformat}
+	var back *cartconvert.PolarCoord
+	var set *cartconvert.HelmertParameterSet
+
+	switch target := target.(type) {
+	case *LatLong:
+		// latitude and longitude are merely formatted, the location is the one converted
+		result.Coord = target.Lat + " " + target.Long
+		back = &cartconvert.PolarCoord{Latitude: latlong.Latitude, Longitude: latlong.Longitude}
+	case *GeoHash:
+		result.Coord = target.GeoHash
+		back, err = cartconvert.GeoHashToLatLong(target.GeoHash, cartconvert.DefaultEllipsoid)
+	case *UTMCoord:
+		result.Coord = target.UTMString
+		back, err = cartconvert.UTMToLatLong(target.UTMCoord)
+	case *BMN:
+		result.Coord = target.BMNString
+		if set, err = helmertParameterSet(req, cartconvert.DatumWGS84, cartconvert.DatumMGI, latlong); err == nil {
+			back, err = bmn.BMNToWGS84LatLongHelmert(target.BMNCoord, set)
+		}
+	case *OSGB36:
+		result.Coord = target.OSGB36String
+		if set, err = helmertParameterSet(req, cartconvert.DatumWGS84, cartconvert.DatumOSGB36, latlong); err == nil {
+			back, err = osgb36.OSGB36ToWGS84LatLongHelmert(target.OSGB36Coord, set)
+		}
+	default:
+		return nil, notFound(oformat, "No round trip for the output format: '%s'", oformat)
+	}
+	if err != nil {
+		return nil, newRequestError(http.StatusInternalServerError, result.Coord, "Unable to convert %s back to WGS84: %s", result.Coord, err)
+	}
+	req.addProvenanceStep("conversion back to WGS84", nil)
+
+	back.El = cartconvert.WGS84Ellipsoid
+	if result.Residual, _, _, err = cartconvert.Vincenty(&cartconvert.PolarCoord{Latitude: latlong.Latitude, Longitude: latlong.Longitude, El: cartconvert.WGS84Ellipsoid}, back); err != nil {
+		return nil, newRequestError(http.StatusInternalServerError, result.Coord, "Unable to compute the residual of the round trip: %s", err)
+	}
+	result.LatLong = back
+	if set != nil {
+		result.ParameterSet, result.Accuracy = set.Name, set.Accuracy
+	}
+	return result, nil
+}
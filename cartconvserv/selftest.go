@@ -0,0 +1,135 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// RESTFul interface for coordinate transformations - self test of the conversions
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/the42/cartconvert/cartconvert"
+	"github.com/the42/cartconvert/cartconvert/bmn"
+	"github.com/the42/cartconvert/cartconvert/osgb36"
+	"math"
+)
+
+// --------------------------------------------------------------------
+// Serialization struct definitions
+type (
+	// The result of the round trip of the reference points of a coordinate system.
+	// Deviations are given in meters.
+	SelfTestSystem struct {
+		Name         string
+		Points       int
+		MaxDeviation float64
+		Tolerance    float64
+		Passed       bool
+		Error        string `json:",omitempty" xml:",omitempty"`
+	}
+
+	SelfTest struct {
+		Passed         bool
+		SelfTestSystem []SelfTestSystem
+	}
+)
+
+// Implements json.Marshaler, writing the deviations in fixed-point notation
+func (result *SelfTestSystem) MarshalJSON() ([]byte, error) {
+	return cartconvert.MarshalFixedJSON(result)
+}
+
+// Implements xml.Marshaler, writing the deviations in fixed-point notation
+func (result *SelfTestSystem) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return cartconvert.MarshalFixedXML(e, start, result)
+}
+
+// A conversion from WGS84 latitude / longitude into a coordinate system and back
+type roundTrip func(latlong *cartconvert.PolarCoord) (*cartconvert.PolarCoord, error)
+
+// The reference points of every coordinate system, which are converted in a round trip.
+// The tolerance accounts for the resolution of the representation, eg. OSGB36 grid references are integral meters.
+var selftestsystems = []struct {
+	name      string
+	tolerance float64
+	points    []*cartconvert.PolarCoord
+	roundTrip
+}{
+	{"Geohash", 0.1, []*cartconvert.PolarCoord{
+		{Latitude: 47.57, Longitude: 14.0075},
+		{Latitude: -33.8568, Longitude: 151.2153},
+		{Latitude: 40.6892, Longitude: -74.0445},
+	}, func(latlong *cartconvert.PolarCoord) (*cartconvert.PolarCoord, error) {
+		return cartconvert.GeoHashToLatLong(cartconvert.LatLongToGeoHash(latlong), nil)
+	}},
+	{"UTM", 0.001, []*cartconvert.PolarCoord{
+		{Latitude: 47.57, Longitude: 14.0075},
+		{Latitude: -33.8568, Longitude: 151.2153},
+		{Latitude: 40.6892, Longitude: -74.0445},
+		{Latitude: 60.3913, Longitude: 5.3221},
+		{Latitude: 78.2232, Longitude: 15.6267},
+	}, func(latlong *cartconvert.PolarCoord) (*cartconvert.PolarCoord, error) {
+		return cartconvert.UTMToLatLong(cartconvert.LatLongToUTM(latlong))
+	}},
+	{"AT:Bundesmeldenetz", 0.01, []*cartconvert.PolarCoord{
+		{Latitude: 47.2608, Longitude: 11.3933},
+		{Latitude: 47.57, Longitude: 14.0075},
+		{Latitude: 48.2082, Longitude: 16.3738},
+	}, func(latlong *cartconvert.PolarCoord) (*cartconvert.PolarCoord, error) {
+		bmnval, err := bmn.WGS84LatLongToBMN(latlong, bmn.BMNZoneDet)
+		if err != nil {
+			return nil, err
+		}
+		return bmn.BMNToWGS84LatLong(bmnval)
+	}},
+	{"UK:OSGB36", 1, []*cartconvert.PolarCoord{
+		{Latitude: 56.7969, Longitude: -5.0036},
+		{Latitude: 51.5007, Longitude: -0.1246},
+		{Latitude: 50.0663, Longitude: -5.7147},
+	}, func(latlong *cartconvert.PolarCoord) (*cartconvert.PolarCoord, error) {
+		osgb36val, err := osgb36.WGS84LatLongToOSGB36(latlong)
+		if err != nil {
+			return nil, err
+		}
+		return osgb36.OSGB36ToWGS84LatLongChecked(osgb36val)
+	}},
+}
+
+// deviation returns the approximate distance in meters between two nearby coordinates
+func deviation(pc1, pc2 *cartconvert.PolarCoord) float64 {
+	const metersPerDegree = 6371008.8 * math.Pi / 180
+	dlat := pc2.Latitude - pc1.Latitude
+	dlong := (pc2.Longitude - pc1.Longitude) * math.Cos(pc1.Latitude*math.Pi/180)
+	return math.Hypot(dlat, dlong) * metersPerDegree
+}
+
+// selftestHandler converts the reference points of every supported coordinate system in a round trip and
+// reports the maximum deviation of every system. The parameters, eg. helmert, are not evaluated, the
+// self test always uses the default parameter sets.
+func selftestHandler(req *GEOConvertRequest, value, oformat string) (interface{}, error) {
+	selftest := &SelfTest{Passed: true}
+
+	for _, system := range selftestsystems {
+		result := SelfTestSystem{Name: system.name, Points: len(system.points), Tolerance: system.tolerance}
+
+		for _, point := range system.points {
+			latlong := &cartconvert.PolarCoord{Latitude: point.Latitude, Longitude: point.Longitude, El: cartconvert.DefaultEllipsoid}
+			back, err := system.roundTrip(latlong)
+			if err != nil {
+				result.Error = err.Error()
+				break
+			}
+			result.MaxDeviation = math.Max(result.MaxDeviation, deviation(latlong, back))
+		}
+
+		result.Passed = len(result.Error) == 0 && result.MaxDeviation <= result.Tolerance
+		selftest.Passed = selftest.Passed && result.Passed
+		selftest.SelfTestSystem = append(selftest.SelfTestSystem, result)
+	}
+
+	// a failed self test responds with an error, which lets monitoring detect it by the http status
+	if !selftest.Passed {
+		return selftest, fmt.Errorf("Self test failed")
+	}
+	return selftest, nil
+}
@@ -0,0 +1,107 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// RESTFul interface for coordinate transformations - streaming batch conversion of newline-delimited JSON
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const StreamMethod = "/stream"
+
+// The media type of newline-delimited JSON, by which conversions are streamed
+const NDJSONMediaType = "application/x-ndjson"
+
+// The maximum length of a line of a stream, a single conversion
+const maxStreamLineBytes = 64 * 1024
+
+// The number of results after which the response is flushed to the client
+const streamFlushInterval = 100
+
+// streamHandler performs the conversions of a stream, one BatchItem per line of the body of a POST request, and
+// writes one BatchResult per line as soon as the conversion is done. Neither the conversions nor their results are
+// held in memory, so the size of a stream is not limited by MaxBatchSize or MaxBodySize, but every line is limited to
+// maxStreamLineBytes. The RequestTimeout applies to every conversion. A line which can not be parsed yields a result
+// carrying the error, the stream goes on with the next line.
+func streamHandler(w http.ResponseWriter, req *http.Request) {
+
+	if req.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "Streaming conversion requires a POST request with one JSON encoded conversion per line", http.StatusMethodNotAllowed)
+		return
+	}
+	defer req.Body.Close()
+
+	// the request body is read while the results are written, and a stream may take longer than the timeouts
+	// granted to a single request, which are therefore extended line by line
+	rc := http.NewResponseController(w)
+	rc.EnableFullDuplex()
+	read, write, _ := conf_timeouts()
+	extendDeadlines := func() {
+		now := time.Now()
+		if read > 0 {
+			rc.SetReadDeadline(now.Add(read))
+		}
+		if write > 0 {
+			rc.SetWriteDeadline(now.Add(write))
+		}
+	}
+
+	// the parameters of the request apply to every conversion, as by a batch. The form is not parsed, which
+	// would consume the body.
+	request := &GEOConvertRequest{Method: StreamMethod, requestID: requestID(req)}
+	query := req.URL.Query()
+	for key, value := range query {
+		request.Parameters = append(request.Parameters, URLParameter{Key: key, Values: value})
+	}
+	oformat := query.Get(OutputFormatSpec)
+
+	w.Header().Set("Content-Type", NDJSONMediaType)
+	enc := json.NewEncoder(w)
+
+	scanner := bufio.NewScanner(req.Body)
+	scanner.Buffer(make([]byte, 0, 4096), maxStreamLineBytes)
+
+	index := 0
+	for extendDeadlines(); scanner.Scan(); extendDeadlines() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var result BatchResult
+		var item BatchItem
+		if err := json.Unmarshal([]byte(line), &item); err != nil {
+			result = BatchResult{Index: index, Error: fmt.Sprintf("Unable to parse conversion: %s", err), Code: http.StatusBadRequest}
+		} else {
+			var cancel context.CancelFunc
+			request.ctx, cancel = requestContext(req.Context())
+			result = convertBatchItem(request, index, &item, oformat)
+			cancel()
+		}
+		if err := enc.Encode(&result); err != nil {
+			// the client went away
+			return
+		}
+
+		if index++; index%streamFlushInterval == 0 {
+			rc.Flush()
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		code := http.StatusBadRequest
+		if err == bufio.ErrTooLong {
+			code = http.StatusRequestEntityTooLarge
+		}
+		enc.Encode(&BatchResult{Index: index, Error: fmt.Sprintf("Unable to read stream: %s", err), Code: code})
+	}
+}
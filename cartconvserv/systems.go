@@ -0,0 +1,146 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// RESTFul interface for coordinate transformations - discovery of the supported coordinate systems
+package main
+
+import (
+	"github.com/the42/cartconvert/cartconvert"
+	"github.com/the42/cartconvert/cartconvert/convert"
+	"github.com/the42/cartconvert/cartconvert/osgb36"
+	"github.com/the42/cartconvert/cartconvert/parse"
+	"sort"
+	"strings"
+)
+
+// --------------------------------------------------------------------
+// Serialization struct definitions
+type (
+	// A worked example of a conversion, consisting of the request and the output it yields
+	SystemExample struct {
+		GEOConvertRequest *GEOConvertRequest // MIND: GEOConvertRequest is named, because XML and JSON serialization behave differently. An unnamed struct element will NOT be serialized by the XML encoder
+		Output            interface{}
+	}
+
+	// A coordinate system supported by the restful method Method. The coordinate reference system is the one of
+	// Input, the literal of the example, as registered by the core package. For systems of zones or meridian
+	// stripes, like UTM, it is the one of the zone of the example. Extent is the area in which the system is defined.
+	System struct {
+		Name, Method string
+		Input        string // literal of the example, like "33T 425351 5268987"
+		EPSG         int    `json:",omitempty" xml:",omitempty"`
+		CRS          string `json:",omitempty" xml:",omitempty"` // name of the coordinate reference system
+		Ellipsoid    string `json:",omitempty" xml:",omitempty"`
+		Projection   string `json:",omitempty" xml:",omitempty"` // projection method, empty for geographic systems
+		Extent       *cartconvert.LatLongExtent
+		Example      *SystemExample        // nil, if the example could not be generated
+		Examples     []cartconvert.Example `json:",omitempty" xml:",omitempty"` // reference points of the subpackage of the system
+	}
+
+	Systems struct {
+		System []System
+	}
+)
+
+// The locations of the example conversions, in Styria, Austria, and on Ben Nevis. The example of every system
+// converts the first of them, which lies within the system, OSGB36 being only defined for Great Britain.
+var systemexamplelocations = []*cartconvert.PolarCoord{
+	{Latitude: 47.57, Longitude: 14.0075, El: cartconvert.WGS84Ellipsoid},
+	{Latitude: 56.796556, Longitude: -5.00393, El: cartconvert.WGS84Ellipsoid},
+}
+
+// systemexamplerequests returns the example conversion of every method converting a coordinate system of package
+// convert. The literal of the example is written by package convert, the conversion yields latitude / longitude,
+// for the method latlong UTM.
+func systemexamplerequests() []*GEOConvertRequest {
+	var requests []*GEOConvertRequest
+	for method, fn := range httphandlerfuncs {
+		if fn.system == "" {
+			continue
+		}
+
+		var literal string
+		var err error
+		for _, gc := range systemexamplelocations {
+			if literal, err = convert.Format(gc, fn.system); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			logger.Printf("Unable to generate example for '%s': %s", method, err)
+			continue
+		}
+
+		request := &GEOConvertRequest{Method: method, Value: literal, Parameters: []URLParameter{{OutputFormatSpec, []string{OFlatlongdeg}}}}
+		if fn.system == "latlong" {
+			request.Value = ""
+			request.Parameters = []URLParameter{{LatLongSpec, []string{literal}}, {OutputFormatSpec, []string{OFUTM}}}
+		}
+		requests = append(requests, request)
+	}
+	return requests
+}
+
+// The reference points of the subpackages, listed as further example inputs of the systems of their methods
+var systemreferencepoints = map[string]func() []cartconvert.Example{
+	"/osgb": osgb36.Examples,
+}
+
+// systems get filled by running the example requests through the conversions at server start
+var systems Systems
+
+// exampleCRS returns the registered coordinate reference system of the literal of an example. The literal is
+// parsed by the parse package, so a new coordinate system of a subpackage is described as soon as its literals
+// are parsed and its coordinate reference system is registered.
+func exampleCRS(literal string) (*cartconvert.CRS, error) {
+	coord, _, err := parse.ParseCoordinate(literal)
+	if err != nil {
+		return nil, err
+	}
+
+	return cartconvert.ByEPSG(coord.EPSG())
+}
+
+func init() {
+	for _, request := range systemexamplerequests() {
+		fn := httphandlerfuncs[request.Method]
+		system := System{Name: fn.docstring, Method: fn.method, Input: request.Value}
+		if examples, ok := systemreferencepoints[request.Method]; ok {
+			system.Examples = examples()
+		}
+		if system.Input == "" {
+			system.Input = getfirstValueFromURLParameters(request.Parameters, LatLongSpec)
+		}
+		// the methods of the projected systems are named like their output formats
+		system.Extent = cartconvert.WorldExtent
+		if extent, ok := outputextents[strings.Trim(fn.method, "/")]; ok {
+			system.Extent = extent
+		}
+
+		if crs, err := exampleCRS(system.Input); err != nil {
+			logger.Printf("Unable to determine the coordinate reference system of '%s': %s", request.Method, err)
+		} else {
+			system.EPSG, system.CRS, system.Projection = crs.EPSG, crs.Name, crs.Method
+			if crs.El != nil {
+				system.Ellipsoid = crs.El.CommonName
+			}
+		}
+
+		output, err := fn.restHandler(request, request.Value, getfirstValueFromURLParameters(request.Parameters, OutputFormatSpec))
+		if err != nil {
+			logger.Printf("Unable to generate example for '%s': %s", request.Method, err)
+		} else {
+			system.Example = &SystemExample{GEOConvertRequest: request, Output: output}
+		}
+		systems.System = append(systems.System, system)
+	}
+
+	sort.Slice(systems.System, func(i, j int) bool { return systems.System[i].Method < systems.System[j].Method })
+}
+
+// systemsHandler lists the supported coordinate systems together with their coordinate reference system, extent
+// and a worked example for every system
+func systemsHandler(req *GEOConvertRequest, value, oformat string) (interface{}, error) {
+	return &systems, nil
+}
@@ -0,0 +1,17 @@
+{{define "Back"}}..{{end}}{{define "Payload"}}
+  <header>
+    <h1><a href=".">Documentation for Grid addresses</a></h1>
+  </header>
+  <h2>Examples</h2>
+  <p>
+    Grid addresses are only available if the service is configured with a grid address provider, eg. for what3words.
+    <a href="{{.APIRoot}}/address/filled.count.soap.json?outputformat=utm">Three word address as UTM JSON-encoded</a>.
+  </p>
+  <h2>Reference</h2>
+  <p>
+    <a href="https://en.wikipedia.org/wiki/What3words">Wikipedia [EN]</a>
+  </p>
+  <h2>Grid address API Documentation</h2>
+  <p><a href="https://github.com/the42/cartconvert/tree/master/cartconvserv/README.md#grid-addresses-">Documentation on Github</a> (authorative developer source)
+  </p>
+  {{end}}
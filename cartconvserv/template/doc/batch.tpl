@@ -0,0 +1,14 @@
+{{define "Back"}}..{{end}}{{define "Payload"}}
+  <header>
+    <h1><a href=".">Documentation for the Batch conversion</a></h1>
+  </header>
+  <h2>Usage</h2>
+  <p>
+    Send a JSON array of conversions, each given by <code>Method</code>, <code>Value</code>, <code>OutputFormat</code>
+    and optional <code>Parameters</code>, as body of a POST request to <code>{{.APIRoot}}/batch/.json</code>.
+    The results are returned in the order of the batch, a failed conversion carries its reason in <code>Error</code>.
+  </p>
+  <h2>Batch conversion API Documentation</h2>
+  <p><a href="https://github.com/the42/cartconvert/tree/master/cartconvserv/README.md#batch-conversion-">Documentation on Github</a> (authorative developer source)
+  </p>
+  {{end}}
@@ -0,0 +1,13 @@
+{{define "Back"}}..{{end}}{{define "Payload"}}
+  <header>
+    <h1><a href=".">Documentation for the Detection of the coordinate system</a></h1>
+  </header>
+  <h2>Examples</h2>
+  <p>
+    <a href="{{.APIRoot}}/detect/592269 272290.json">Coordinate systems of the literal "592269 272290" JSON-encoded</a>,
+    <a href="{{.APIRoot}}/detect/592269 272290.xml">XML-encoded</a>.
+  </p>
+  <h2>Detection API Documentation</h2>
+  <p><a href="https://github.com/the42/cartconvert/tree/master/cartconvserv/README.md#detection-">Documentation on Github</a> (authorative developer source)
+  </p>
+  {{end}}
@@ -0,0 +1,18 @@
+{{define "Back"}}..{{end}}{{define "Payload"}}
+  <header>
+    <h1><a href=".">Documentation for Helmert parameter sets</a></h1>
+  </header>
+  <h2>Examples</h2>
+  <p>
+    <a href="{{.APIRoot}}/helmert/.json">All parameter sets JSON-encoded</a>,
+    <a href="{{.APIRoot}}/helmert/.xml?from=WGS84&amp;to=OSGB36">parameter sets from WGS84 to OSGB36, XML-encoded</a>,
+    <a href="{{.APIRoot}}/utm/31U%20365166%205684564.json?outputformat=osgb&amp;helmert=OSGB36_3param_NIMA">UTM as OSGB36 using the parameter set OSGB36_3param_NIMA</a>.
+  </p>
+  <h2>Reference</h2>
+  <p>
+    <a href="http://en.wikipedia.org/wiki/Helmert_transformation">Wikipedia [EN]</a>
+  </p>
+  <h2>Helmert API Documentation</h2>
+  <p><a href="https://github.com/the42/cartconvert/tree/master/cartconvserv/README.md#helmert-parameter-sets-">Documentation on Github</a> (authorative developer source)
+  </p>
+  {{end}}
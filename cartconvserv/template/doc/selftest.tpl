@@ -0,0 +1,13 @@
+{{define "Back"}}..{{end}}{{define "Payload"}}
+  <header>
+    <h1><a href=".">Documentation for the Self test</a></h1>
+  </header>
+  <h2>Examples</h2>
+  <p>
+    <a href="{{.APIRoot}}/selftest/.json">Self test JSON-encoded</a>,
+    <a href="{{.APIRoot}}/selftest/.xml">Self test XML-encoded</a>.
+  </p>
+  <h2>Self test API Documentation</h2>
+  <p><a href="https://github.com/the42/cartconvert/tree/master/cartconvserv/README.md#self-test-">Documentation on Github</a> (authorative developer source)
+  </p>
+  {{end}}
@@ -0,0 +1,13 @@
+{{define "Back"}}..{{end}}{{define "Payload"}}
+  <header>
+    <h1><a href=".">Documentation for Coordinate systems</a></h1>
+  </header>
+  <h2>Examples</h2>
+  <p>
+    <a href="{{.APIRoot}}/systems/.json">Supported coordinate systems with examples JSON-encoded</a>,
+    <a href="{{.APIRoot}}/systems/.xml">XML-encoded</a>.
+  </p>
+  <h2>Coordinate systems API Documentation</h2>
+  <p><a href="https://github.com/the42/cartconvert/tree/master/cartconvserv/README.md#coordinate-systems-">Documentation on Github</a> (authorative developer source)
+  </p>
+  {{end}}
@@ -0,0 +1,20 @@
+{{define "Back"}}..{{end}}{{define "Payload"}}
+  <header>
+    <h1><a href=".">Documentation for UTM/UPS</a></h1>
+  </header>
+  <h2>Examples</h2>
+  <p>
+    <a id="osm1" href="#">Y 1705036 1845140.44</a> as <a href="{{.APIRoot}}/ups/Y%201705036%201845140.44.xml?outputformat=latlongdeg">Lat / Long in degrees, XML-encoded</a>,
+    as <a href="{{.APIRoot}}/ups/Y%201705036%201845140.44.json?outputformat=geohash">Geohash, JSON-encoded</a>.
+  </p>
+  <h2>Reference</h2>
+  <p>
+    <a href="http://en.wikipedia.org/wiki/Universal_polar_stereographic_coordinate_system">Wikipedia [EN]</a>
+  </p>
+  <h2>UTM/UPS API Documentation</h2>
+  <p><a href="https://github.com/the42/cartconvert/blob/master/cartconvserv/README.md#utm---conversions-">Documentation on Github</a> (authorative developer source)
+  </p>
+  <script>
+    document.getElementById("osm1").addEventListener('click', function() {return osmload("{{.APIRoot}}/ups/Y%201705036%201845140.44.json?outputformat=latlongcomma")});
+  </script>
+  {{end}}
@@ -0,0 +1,142 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// RESTFul interface for coordinate transformations - TopoJSON output
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/the42/cartconvert/cartconvert"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// The serialization format TopoJSONFormatSpec encodes the WGS84 location of the result as a quantized TopoJSON topology
+const TopoJSONFormatSpec = ".topojson"
+
+// The parameter QuantizationSpec sets the number of distinguishable values per axis of a TopoJSON topology
+const QuantizationSpec = "quantization"
+
+const defaultQuantization = 1000000
+
+type (
+	topoJSONTransform struct {
+		Scale     [2]float64 `json:"scale"`
+		Translate [2]float64 `json:"translate"`
+	}
+
+	// A Point or MultiPoint geometry. Coordinates are quantized positions [x, y]
+	topoJSONGeometry struct {
+		Type        string            `json:"type"`
+		Coordinates interface{}       `json:"coordinates"`
+		Properties  map[string]string `json:"properties,omitempty"`
+	}
+
+	topoJSONGeometryCollection struct {
+		Type       string             `json:"type"`
+		Geometries []topoJSONGeometry `json:"geometries"`
+	}
+
+	topoJSONTopology struct {
+		Type      string                                `json:"type"`
+		Transform topoJSONTransform                     `json:"transform"`
+		Objects   map[string]topoJSONGeometryCollection `json:"objects"`
+		Arcs      [][][2]int64                          `json:"arcs"`
+	}
+
+	// A feature to be encoded as TopoJSON geometry. A single position becomes a Point, several positions a MultiPoint.
+	topoJSONFeature struct {
+		positions  []*cartconvert.PolarCoord
+		properties map[string]string
+	}
+)
+
+// newTopoJSONTopology builds a topology of a single geometry collection called name. The positions of all features are
+// quantized to quantization values per axis over the bounding box of all positions. Longitude is the x axis, latitude the y axis.
+func newTopoJSONTopology(name string, features []topoJSONFeature, quantization int64) *topoJSONTopology {
+
+	x0, y0, x1, y1 := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, feature := range features {
+		for _, pos := range feature.positions {
+			x0, x1 = math.Min(x0, pos.Longitude), math.Max(x1, pos.Longitude)
+			y0, y1 = math.Min(y0, pos.Latitude), math.Max(y1, pos.Latitude)
+		}
+	}
+
+	topology := &topoJSONTopology{Type: "Topology", Arcs: [][][2]int64{}}
+	if x0 > x1 {
+		// no positions at all
+		x0, y0, x1, y1 = 0, 0, 0, 0
+	}
+
+	// a degenerate extent quantizes every position to 0, any scale will do
+	kx, ky := 1.0, 1.0
+	if quantization > 1 && x1 > x0 {
+		kx = (x1 - x0) / float64(quantization-1)
+	}
+	if quantization > 1 && y1 > y0 {
+		ky = (y1 - y0) / float64(quantization-1)
+	}
+	topology.Transform = topoJSONTransform{Scale: [2]float64{kx, ky}, Translate: [2]float64{x0, y0}}
+
+	collection := topoJSONGeometryCollection{Type: "GeometryCollection", Geometries: []topoJSONGeometry{}}
+	for _, feature := range features {
+		positions := make([][2]int64, len(feature.positions))
+		for i, pos := range feature.positions {
+			positions[i] = [2]int64{int64(math.Floor((pos.Longitude-x0)/kx + 0.5)), int64(math.Floor((pos.Latitude-y0)/ky + 0.5))}
+		}
+
+		geometry := topoJSONGeometry{Type: "MultiPoint", Coordinates: positions, Properties: feature.properties}
+		if len(positions) == 1 {
+			geometry.Type, geometry.Coordinates = "Point", positions[0]
+		}
+		collection.Geometries = append(collection.Geometries, geometry)
+	}
+	topology.Objects = map[string]topoJSONGeometryCollection{name: collection}
+
+	return topology
+}
+
+// topoJSONEncoder writes the location of a conversion as TopoJSON topology, with the payload as properties
+// of the point. Responses without location, like errors or listings, are written as JSON.
+type topoJSONEncoder struct {
+	w            io.Writer
+	quantization int64
+}
+
+// newTopoJSONEncoder returns an encoder of the quantization requested by QuantizationSpec. Returns a bad request,
+// if the quantization is not an integer greater than 1.
+func newTopoJSONEncoder(w io.Writer, request *GEOConvertRequest) (*topoJSONEncoder, error) {
+	quantization := int64(defaultQuantization)
+	if squantization := getfirstValueFromURLParameters(request.Parameters, QuantizationSpec); len(squantization) > 0 {
+		q, err := strconv.ParseInt(squantization, 10, 64)
+		if err != nil || q < 2 {
+			return nil, badRequest(squantization, "Quantization has to be an integer greater than 1, got: '%s'", squantization)
+		}
+		quantization = q
+	}
+	return &topoJSONEncoder{w: w, quantization: quantization}, nil
+}
+
+func (enc *topoJSONEncoder) Encode(v interface{}) error {
+	response, ok := v.(*GEOConvertResponse)
+	if !ok {
+		return fmt.Errorf("Unable to encode %T as TopoJSON", v)
+	}
+
+	if response.Error || response.GEOConvertRequest == nil || response.GEOConvertRequest.location == nil {
+		return json.NewEncoder(enc.w).Encode(response)
+	}
+
+	properties := make(map[string]string)
+	for _, attr := range featureAttributes("", reflect.ValueOf(response.Payload), nil) {
+		properties[attr.Name] = attr.Value
+	}
+
+	feature := topoJSONFeature{positions: []*cartconvert.PolarCoord{response.GEOConvertRequest.location}, properties: properties}
+	return json.NewEncoder(enc.w).Encode(newTopoJSONTopology(featureName(response), []topoJSONFeature{feature}, enc.quantization))
+}
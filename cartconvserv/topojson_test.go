@@ -0,0 +1,154 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// Automated tests for the TopoJSON serialization
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/the42/cartconvert/cartconvert"
+	"math"
+	"net/http"
+	"testing"
+)
+
+// ## newTopoJSONTopology
+type topoJSONRoundTripTest struct {
+	name         string
+	features     [][]*cartconvert.PolarCoord
+	quantization int64
+}
+
+var topoJSONRoundTripTests = []topoJSONRoundTripTest{
+	{"point", [][]*cartconvert.PolarCoord{{{Latitude: 47.57, Longitude: 14.0075}}}, defaultQuantization},
+	{"points", [][]*cartconvert.PolarCoord{
+		{{Latitude: 47.57, Longitude: 14.0075}},
+		{{Latitude: 48.208333, Longitude: 16.373056}},
+		{{Latitude: -33.922667, Longitude: 18.416689}}}, defaultQuantization},
+	{"multipoint", [][]*cartconvert.PolarCoord{
+		{{Latitude: 51.5, Longitude: -0.12}, {Latitude: 53.35, Longitude: -6.26}},
+		{{Latitude: 46.95, Longitude: 7.44}}}, 1000},
+	{"wide extent", [][]*cartconvert.PolarCoord{{{Latitude: -17.7, Longitude: 178.4}, {Latitude: -14.3, Longitude: -170.7}}}, 10000},
+}
+
+// decodeTopoJSON reads the positions of the geometries of the collection name of a topology, as a TopoJSON reader
+// does: the quantized positions are scaled and translated by the transform of the topology
+func decodeTopoJSON(t *testing.T, data []byte, name string) [][][2]float64 {
+	var topology struct {
+		Type      string
+		Transform struct{ Scale, Translate [2]float64 }
+		Objects   map[string]struct {
+			Type       string
+			Geometries []struct {
+				Type        string
+				Coordinates json.RawMessage
+			}
+		}
+	}
+	if err := json.Unmarshal(data, &topology); err != nil {
+		t.Fatalf("decodeTopoJSON: %s", err)
+	}
+	if topology.Type != "Topology" || topology.Objects[name].Type != "GeometryCollection" {
+		t.Fatalf("decodeTopoJSON: expected a topology of the geometry collection %s, got %s", name, data)
+	}
+
+	var geometries [][][2]float64
+	for _, geometry := range topology.Objects[name].Geometries {
+		var quantized [][2]int64
+		switch geometry.Type {
+		case "Point":
+			var position [2]int64
+			if err := json.Unmarshal(geometry.Coordinates, &position); err != nil {
+				t.Fatalf("decodeTopoJSON: %s", err)
+			}
+			quantized = [][2]int64{position}
+		case "MultiPoint":
+			if err := json.Unmarshal(geometry.Coordinates, &quantized); err != nil {
+				t.Fatalf("decodeTopoJSON: %s", err)
+			}
+		default:
+			t.Fatalf("decodeTopoJSON: unexpected geometry %s", geometry.Type)
+		}
+
+		positions := make([][2]float64, len(quantized))
+		for i, q := range quantized {
+			for axis := range q {
+				positions[i][axis] = float64(q[axis])*topology.Transform.Scale[axis] + topology.Transform.Translate[axis]
+			}
+		}
+		geometries = append(geometries, positions)
+	}
+	return geometries
+}
+
+func TestTopoJSONRoundTrip(t *testing.T) {
+	for _, test := range topoJSONRoundTripTests {
+		features := make([]topoJSONFeature, len(test.features))
+		for i, positions := range test.features {
+			features[i] = topoJSONFeature{positions: positions}
+		}
+		topology := newTopoJSONTopology(test.name, features, test.quantization)
+
+		var buf bytes.Buffer
+		if err := json.NewEncoder(&buf).Encode(topology); err != nil {
+			t.Fatalf("TopoJSON %s: %s", test.name, err)
+		}
+		geometries := decodeTopoJSON(t, buf.Bytes(), test.name)
+
+		if len(geometries) != len(test.features) {
+			t.Fatalf("TopoJSON %s: expected %d geometries, got %d", test.name, len(test.features), len(geometries))
+		}
+		// a position is rounded to the nearest quantized value, which is off by at most half a step of the scale
+		tolerance := [2]float64{topology.Transform.Scale[0]/2 + 1e-9, topology.Transform.Scale[1]/2 + 1e-9}
+		for i, positions := range test.features {
+			if len(geometries[i]) != len(positions) {
+				t.Errorf("TopoJSON %s [%d]: expected %d positions, got %d", test.name, i, len(positions), len(geometries[i]))
+				continue
+			}
+			for j, pos := range positions {
+				out := geometries[i][j]
+				if math.Abs(out[0]-pos.Longitude) > tolerance[0] || math.Abs(out[1]-pos.Latitude) > tolerance[1] {
+					t.Errorf("TopoJSON %s [%d][%d]: expected %f %f, got %f %f", test.name, i, j, pos.Longitude, pos.Latitude, out[0], out[1])
+				}
+			}
+		}
+	}
+}
+
+// ## topoJSONEncoder
+func TestTopoJSONEncoder(t *testing.T) {
+	location := &cartconvert.PolarCoord{Latitude: 47.57, Longitude: 14.0075}
+	request := &GEOConvertRequest{Method: "/latlong", Parameters: []URLParameter{{Key: QuantizationSpec, Values: []string{"1000"}}}, location: location}
+	response := &GEOConvertResponse{GEOConvertRequest: request, Payload: &GeoHash{GeoHash: "u23ywezgq"}}
+
+	var buf bytes.Buffer
+	enc, err := newTopoJSONEncoder(&buf, request)
+	if err != nil {
+		t.Fatalf("newTopoJSONEncoder: %s", err)
+	}
+	if err := enc.Encode(response); err != nil {
+		t.Fatalf("topoJSONEncoder: %s", err)
+	}
+
+	geometries := decodeTopoJSON(t, buf.Bytes(), "latlong")
+	if len(geometries) != 1 || len(geometries[0]) != 1 {
+		t.Fatalf("topoJSONEncoder: expected a single point, got %v", geometries)
+	}
+	// the extent of a single position is degenerate, so the point is the translate of the transform
+	if out := geometries[0][0]; out[0] != location.Longitude || out[1] != location.Latitude {
+		t.Errorf("topoJSONEncoder: expected %f %f, got %f %f", location.Longitude, location.Latitude, out[0], out[1])
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`"properties":{"GeoHash":"u23ywezgq"}`)) {
+		t.Errorf("topoJSONEncoder: expected the payload as properties of the point, got %s", buf.Bytes())
+	}
+
+	// a quantization which is no integer greater than 1 is a bad request
+	for _, quantization := range []string{"1", "-5", "1e3", "many"} {
+		request := &GEOConvertRequest{Parameters: []URLParameter{{Key: QuantizationSpec, Values: []string{quantization}}}}
+		if _, err := newTopoJSONEncoder(&buf, request); err == nil || asRequestError(err).Code != http.StatusBadRequest {
+			t.Errorf("newTopoJSONEncoder: expected a bad request for the quantization %s, got %v", quantization, err)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright 2011,2012 Johann Höchtl. All rights reserved.
+// Use of this source code is governed by a Modified BSD License
+// that can be found in the LICENSE file.
+
+// RESTFul interface for coordinate transformations - WKB output
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/the42/cartconvert/cartconvert"
+	"io"
+	"net/http"
+)
+
+// The serialization format WKBFormatSpec encodes the WGS84 location of the result as little endian well-known binary
+// Point, as read by GeoPackage and spatial databases. It may also be requested by the parameter FormatSpec=wkb or by
+// the Accept header WKBMediaType. If the parameter WKBSRIDSpec is true, the SRID 4326 of WGS84 is embedded as EWKB.
+const (
+	WKBFormatSpec = ".wkb"
+	WKBMediaType  = "application/vnd.ogc.wkb"
+	WKBSRIDSpec   = "srid"
+)
+
+// wkbEncoder writes the location of a conversion as WKB point, longitude as x and latitude as y. Responses without
+// location, like errors or listings, are written as JSON, and the content type of header is set accordingly.
+type wkbEncoder struct {
+	w      io.Writer
+	header http.Header
+}
+
+func (enc *wkbEncoder) Encode(v interface{}) error {
+	response, ok := v.(*GEOConvertResponse)
+	if !ok {
+		return fmt.Errorf("Unable to encode %T as WKB", v)
+	}
+
+	request := response.GEOConvertRequest
+	if response.Error || request == nil || request.location == nil {
+		enc.header.Set("Content-Type", "application/json; charset=utf-8")
+		return json.NewEncoder(enc.w).Encode(response)
+	}
+
+	srid := 0
+	if getfirstValueFromURLParameters(request.Parameters, WKBSRIDSpec) == "true" {
+		srid = cartconvert.EPSGWGS84
+	}
+	_, err := enc.w.Write(cartconvert.LatLongToEWKB(request.location, srid))
+	return err
+}
@@ -117,7 +117,12 @@ func main() {
 				fmt.Fprintf(os.Stderr, "OSGB36: error on line %d: %s\n", lines, err)
 				continue
 			}
-			pc = osgb36.OSGB36ToWGS84LatLong(osgb36coord)
+			pc, err = osgb36.OSGB36ToWGS84LatLongChecked(osgb36coord)
+
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "OSGB36: error on line %d: %s\n", lines, err)
+				continue
+			}
 		}
 
 		switch of {